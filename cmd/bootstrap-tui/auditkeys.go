@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/authkeys"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+)
+
+// errUnknownKeys makes audit-keys exit non-zero when unrecognized keys grant access.
+var errUnknownKeys = errors.New("unknown keys grant access")
+
+// runAuditKeys implements `audit-keys <host>`: it lists authorized_keys
+// entries for the given accounts with fingerprints and comments and flags keys
+// that are not in the known set.
+func runAuditKeys(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("audit-keys", flag.ContinueOnError)
+	flags.SetOutput(out)
+	user := flags.String("user", os.Getenv("USER"), "SSH login user")
+	port := flags.Int("port", 22, "SSH port")
+	keyPath := flags.String("key", "", "private key for SSH authentication (default: ssh-agent)")
+	askPass := flags.Bool("ask-pass", false, "prompt for the SSH/sudo password")
+	users := flags.String("users", "root,ansible", "comma-separated accounts to audit")
+	known := flags.String("known", "", "authorized_keys-format file of expected keys (default: local ~/.ssh keys)")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui audit-keys [flags] <host>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("audit-keys requires exactly one host")
+	}
+	host := flags.Arg(0)
+
+	var password string
+	cred := sshconnection.Credential{KeyPath: *keyPath}
+	switch {
+	case *askPass:
+		fmt.Fprintf(os.Stderr, "Password for %s@%s: ", *user, host)
+		raw, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("read password: %w", err)
+		}
+		password = string(raw)
+		if *keyPath == "" {
+			cred = sshconnection.Credential{Password: password}
+		}
+	case *keyPath == "":
+		cred = sshconnection.Credential{Agent: true}
+	}
+
+	knownSet, err := knownFingerprints(*known)
+	if err != nil {
+		return err
+	}
+
+	client, err := sshconnection.Connect(host, *port, *user, cred)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	runner, err := auditClient(client, password)
+	if err != nil {
+		return fmt.Errorf("elevate: %w", err)
+	}
+	var results []*authkeys.UserKeys
+	for _, account := range strings.Split(*users, ",") {
+		if account = strings.TrimSpace(account); account == "" {
+			continue
+		}
+		res, err := authkeys.List(runner, account)
+		if err != nil {
+			return fmt.Errorf("audit %s: %w", account, err)
+		}
+		results = append(results, res)
+	}
+
+	if unknown := writeAudit(out, results, knownSet); unknown > 0 {
+		return fmt.Errorf("%w: %d", errUnknownKeys, unknown)
+	}
+	return nil
+}
+
+// writeAudit renders the audit table and returns how many entries were not
+// in the known set.
+func writeAudit(out io.Writer, results []*authkeys.UserKeys, known map[string]bool) int {
+	unknown := 0
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "USER\tLINE\tTYPE\tFINGERPRINT\tCOMMENT\tSTATUS")
+	for _, res := range results {
+		switch {
+		case !res.Exists:
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\tno such user\n", res.User)
+			continue
+		case len(res.Entries) == 0:
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\tno authorized keys\n", res.User)
+			continue
+		}
+		for _, entry := range res.Entries {
+			if entry.Invalid {
+				fmt.Fprintf(tw, "%s\t%d\t-\t-\t-\tunparseable\n", res.User, entry.Line)
+				continue
+			}
+			status := "known"
+			if !known[entry.Fingerprint] {
+				status = "UNKNOWN"
+				unknown++
+			}
+			if len(entry.Options) > 0 {
+				status += " (" + strings.Join(entry.Options, ",") + ")"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", res.User, entry.Line, entry.Type, entry.Fingerprint, entry.Comment, status)
+		}
+	}
+	_ = tw.Flush()
+	return unknown
+}
+
+// knownFingerprints loads the expected keys from path, or from the public keys
+// in ~/.ssh when path is empty.
+func knownFingerprints(path string) (map[string]bool, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read known keys: %w", err)
+		}
+		return authkeys.Fingerprints(string(data)), nil
+	}
+
+	known := make(map[string]bool)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return known, nil
+	}
+	pubs, _ := filepath.Glob(filepath.Join(home, ".ssh", "*.pub"))
+	for _, pub := range pubs {
+		data, err := os.ReadFile(pub)
+		if err != nil {
+			continue
+		}
+		for fp := range authkeys.Fingerprints(string(data)) {
+			known[fp] = true
+		}
+	}
+	for _, key := range sshconnection.DiscoverLocalKeys() {
+		if key.Fingerprint != "" {
+			known[key.Fingerprint] = true
+		}
+	}
+	return known, nil
+}
+
+// auditClient gains root for reading other users' authorized_keys. With a
+// password it uses the usual sudo/su flow, without installing anything;
+// otherwise the SSH user must be root or have passwordless sudo.
+func auditClient(client *ssh.Client, password string) (*privilege.ElevatedClient, error) {
+	if password == "" {
+		return privilege.PasswordlessClient(client)
+	}
+	return privilege.EnsureElevatedClient(client, privilege.Password{Value: password}, privilege.WithoutSudoInstall())
+}
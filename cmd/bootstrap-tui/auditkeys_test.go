@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/authkeys"
+)
+
+func TestWriteAuditFlagsUnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	results := []*authkeys.UserKeys{
+		{User: "root", Exists: true, Entries: []authkeys.Entry{
+			{Line: 1, Type: "ssh-ed25519", Fingerprint: "SHA256:known", Comment: "ops@laptop"},
+			{Line: 2, Type: "ssh-rsa", Fingerprint: "SHA256:stranger", Comment: "old-contractor"},
+			{Line: 3, Invalid: true},
+		}},
+		{User: "ansible", Exists: false},
+	}
+
+	var out bytes.Buffer
+	unknown := writeAudit(&out, results, map[string]bool{"SHA256:known": true})
+	require.Equal(t, 1, unknown)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	require.Contains(t, lines[1], "known")
+	require.Contains(t, lines[2], "UNKNOWN")
+	require.Contains(t, lines[2], "old-contractor")
+	require.Contains(t, lines[3], "unparseable")
+	require.Contains(t, lines[4], "no such user")
+}
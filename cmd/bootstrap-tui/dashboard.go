@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/config"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/terraform"
+)
+
+// dashboardNever is shown for hosts watch has not checked yet.
+const dashboardNever = "never"
+
+// dashboardRow is one prepared host with its last drift check.
+type dashboardRow struct {
+	Host       string
+	User       string
+	PreparedAt time.Time
+	Check      driftStatus
+	Checking   bool
+	attrs      map[string]string
+}
+
+// dashboardCheckedMsg carries the outcome of a re-check started from the
+// dashboard.
+type dashboardCheckedMsg struct {
+	result watchResult
+	at     time.Time
+}
+
+// dashboardPreppedMsg reports that a re-prep the dashboard handed the
+// terminal to has exited.
+type dashboardPreppedMsg struct {
+	host string
+	err  error
+}
+
+// dashboardModel is a read-only view of the hosts recorded by --terraform-out
+// and the last results of watch. It never changes a host itself: re-checks
+// run the drift bundle, and re-prep runs the regular prep TUI.
+type dashboardModel struct {
+	ctx        context.Context
+	hostsPath  string
+	statusPath string
+	check      driftChecker
+	reprep     func(host string) *exec.Cmd
+	now        func() time.Time
+
+	rows    []dashboardRow
+	cursor  int
+	message string
+}
+
+// runDashboard implements `dashboard`: a table of prepared hosts with their
+// last prep time and drift check, with keys to re-check or re-prep a host.
+func runDashboard(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	flags.SetOutput(out)
+	hostsPath := flags.String("hosts", "", "prepared hosts, as written by --terraform-out (required)")
+	statusPath := flags.String("status", "", "drift check results written by watch (default: the --hosts file with a .drift.json suffix)")
+	configPath := flags.String("config", "", "integration settings file passed to re-prep runs (default: "+config.DefaultPath()+")")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui dashboard --hosts FILE [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *hostsPath == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return errors.New("dashboard requires --hosts")
+	}
+	if *statusPath == "" {
+		*statusPath = driftStatusPath(*hostsPath)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	model := &dashboardModel{
+		ctx:        ctx,
+		hostsPath:  *hostsPath,
+		statusPath: *statusPath,
+		check:      checkDrift,
+		reprep:     reprepCommand(*hostsPath, *configPath),
+		now:        time.Now,
+	}
+	if err := model.reload(); err != nil {
+		return err
+	}
+	_, err := tea.NewProgram(model, tea.WithAltScreen()).Run()
+	return err
+}
+
+// reprepCommand runs this binary's prep TUI for a host, recording the result
+// in the same export so the dashboard sees the new prep time.
+func reprepCommand(hostsPath, configPath string) func(host string) *exec.Cmd {
+	return func(host string) *exec.Cmd {
+		exe, err := os.Executable()
+		if err != nil {
+			exe = os.Args[0]
+		}
+		args := []string{"--host", host, "--terraform-out", hostsPath}
+		if configPath != "" {
+			args = append(args, "--config", configPath)
+		}
+		return exec.Command(exe, args...)
+	}
+}
+
+// loadDashboardRows joins the export at hostsPath with the status file at
+// statusPath, sorted by host.
+func loadDashboardRows(hostsPath, statusPath string) ([]dashboardRow, error) {
+	doc, err := terraform.Load(hostsPath)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := loadDriftStatus(statusPath)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]dashboardRow, 0, len(doc.Hosts))
+	for name, attrs := range doc.Hosts {
+		row := dashboardRow{Host: name, User: attrs["ansible_user"], Check: statuses[name], attrs: attrs}
+		if at, err := time.Parse(time.RFC3339, attrs["prepared_at"]); err == nil {
+			row.PreparedAt = at
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Host < rows[j].Host })
+	return rows, nil
+}
+
+func (m *dashboardModel) reload() error {
+	rows, err := loadDashboardRows(m.hostsPath, m.statusPath)
+	if err != nil {
+		return err
+	}
+	// Keep the selection on the same host when hosts are added.
+	selected := ""
+	if m.cursor < len(m.rows) {
+		selected = m.rows[m.cursor].Host
+	}
+	m.rows = rows
+	m.cursor = 0
+	for i, row := range rows {
+		if row.Host == selected {
+			m.cursor = i
+		}
+	}
+	return nil
+}
+
+func (m *dashboardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m, m.handleKey(msg)
+	case dashboardCheckedMsg:
+		m.finishCheck(msg)
+	case dashboardPreppedMsg:
+		if err := m.reload(); err != nil {
+			m.message = err.Error()
+		} else if msg.err != nil {
+			m.message = fmt.Sprintf("re-prep of %s failed: %v", msg.host, msg.err)
+		} else {
+			m.message = "re-prep of " + msg.host + " finished"
+		}
+	}
+	return m, nil
+}
+
+func (m *dashboardModel) handleKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.rows)-1 {
+			m.cursor++
+		}
+	case "r":
+		m.message = "reloaded"
+		if err := m.reload(); err != nil {
+			m.message = err.Error()
+		}
+	case "c":
+		if m.cursor < len(m.rows) {
+			return m.startCheck(m.cursor)
+		}
+	case "a":
+		var cmds []tea.Cmd
+		for i := range m.rows {
+			cmds = append(cmds, m.startCheck(i))
+		}
+		return tea.Batch(cmds...)
+	case "p":
+		if m.cursor < len(m.rows) && !m.rows[m.cursor].Checking {
+			host := m.rows[m.cursor].Host
+			return tea.ExecProcess(m.reprep(host), func(err error) tea.Msg {
+				return dashboardPreppedMsg{host: host, err: err}
+			})
+		}
+	}
+	return nil
+}
+
+// startCheck re-checks the host in row i for drift in the background.
+func (m *dashboardModel) startCheck(i int) tea.Cmd {
+	row := &m.rows[i]
+	if row.Checking {
+		return nil
+	}
+	row.Checking = true
+	m.message = ""
+	host, attrs, check, ctx, now := row.Host, row.attrs, m.check, m.ctx, m.now
+	return func() tea.Msg {
+		status, detail := classifyDrift(check(ctx, attrs, nil))
+		return dashboardCheckedMsg{result: watchResult{Host: host, Status: status, Detail: detail}, at: now()}
+	}
+}
+
+// finishCheck shows a re-check's result and records it for watch and later
+// dashboard sessions.
+func (m *dashboardModel) finishCheck(msg dashboardCheckedMsg) {
+	for i := range m.rows {
+		if m.rows[i].Host == msg.result.Host {
+			m.rows[i].Checking = false
+			m.rows[i].Check = driftStatus{Status: msg.result.Status, Detail: msg.result.Detail, CheckedAt: msg.at.UTC()}
+		}
+	}
+	if err := recordDriftStatus(m.statusPath, msg.at, []watchResult{msg.result}); err != nil {
+		m.message = "record check: " + err.Error()
+	}
+}
+
+func (m *dashboardModel) View() string {
+	var b strings.Builder
+	b.WriteString(dashboardTitleStyle.Render("Prepared hosts"))
+	b.WriteString(dashboardMutedStyle.Render("  " + m.hostsPath))
+	b.WriteString("\n\n")
+	if len(m.rows) == 0 {
+		b.WriteString(dashboardMutedStyle.Render("No prepared hosts yet; prep runs with --terraform-out add them."))
+		b.WriteString("\n")
+	}
+
+	hostWidth, userWidth := len("HOST"), len("USER")
+	for _, row := range m.rows {
+		hostWidth = max(hostWidth, len(row.Host))
+		userWidth = max(userWidth, len(row.User))
+	}
+	header := fmt.Sprintf("  %-*s  %-*s  %-16s  %-16s  %-8s  %s", hostWidth, "HOST", userWidth, "USER", "PREPARED", "LAST CHECK", "STATUS", "DETAIL")
+	if len(m.rows) > 0 {
+		b.WriteString(dashboardHeaderStyle.Render(header))
+		b.WriteString("\n")
+	}
+	for i, row := range m.rows {
+		status, checked := row.Check.Status, dashboardTime(row.Check.CheckedAt)
+		if status == "" {
+			status = dashboardNever
+		}
+		if row.Checking {
+			status = "checking"
+		}
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+		line := fmt.Sprintf("%s%-*s  %-*s  %-16s  %-16s  ", cursor, hostWidth, row.Host, userWidth, row.User, dashboardTime(row.PreparedAt), checked)
+		if i == m.cursor {
+			line = dashboardSelectedStyle.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString(dashboardStatusStyle(status).Render(fmt.Sprintf("%-8s", status)))
+		b.WriteString("  " + row.Check.Detail)
+		b.WriteString("\n")
+	}
+
+	if m.message != "" {
+		b.WriteString("\n" + m.message + "\n")
+	}
+	b.WriteString(dashboardMutedStyle.Render("\n↑/↓ select • c re-check • a re-check all • p re-prep • r reload • q quit"))
+	return b.String()
+}
+
+func dashboardTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func dashboardStatusStyle(status string) lipgloss.Style {
+	switch status {
+	case watchOK:
+		return dashboardOKStyle
+	case watchDrift, watchError:
+		return dashboardBadStyle
+	default:
+		return dashboardMutedStyle
+	}
+}
+
+var (
+	dashboardTitleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#E0AAFF"))
+	dashboardHeaderStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A5B4FC"))
+	dashboardMutedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#94A3B8"))
+	dashboardSelectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FDE047"))
+	dashboardOKStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#34D399"))
+	dashboardBadStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#F87171")).Bold(true)
+)
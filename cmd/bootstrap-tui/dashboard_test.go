@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/driftcheck"
+)
+
+func TestDashboardRechecksHosts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hostsPath := filepath.Join(dir, "prepared-hosts.json")
+	require.NoError(t, os.WriteFile(hostsPath, []byte(`{"hosts": {
+		"10.0.0.6": {"ansible_host": "10.0.0.6", "ansible_user": "ansible", "prepared_at": "2026-03-01T09:00:00Z"},
+		"10.0.0.5": {"ansible_host": "10.0.0.5", "ansible_user": "ansible"}
+	}}`), 0o600))
+	statusPath := driftStatusPath(hostsPath)
+	require.Equal(t, filepath.Join(dir, "prepared-hosts.drift.json"), statusPath)
+	checkedAt := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+	require.NoError(t, recordDriftStatus(statusPath, checkedAt, []watchResult{{Host: "10.0.0.5", Status: watchOK}}))
+
+	var checked []string
+	model := &dashboardModel{
+		ctx:        context.Background(),
+		hostsPath:  hostsPath,
+		statusPath: statusPath,
+		check: func(_ context.Context, attrs map[string]string, _ []phases.Observer) error {
+			checked = append(checked, attrs["ansible_host"])
+			return driftcheck.DriftError{Host: attrs["ansible_host"], Findings: []driftcheck.Finding{{Check: driftcheck.CheckPython, Detail: "python3 is missing"}}}
+		},
+		now: func() time.Time { return checkedAt.Add(time.Hour) },
+	}
+	require.NoError(t, model.reload())
+	require.Len(t, model.rows, 2)
+	require.Equal(t, "10.0.0.5", model.rows[0].Host)
+	require.Equal(t, watchOK, model.rows[0].Check.Status)
+	require.True(t, model.rows[0].PreparedAt.IsZero())
+	require.Equal(t, time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), model.rows[1].PreparedAt)
+
+	view := model.View()
+	require.Contains(t, view, "10.0.0.5")
+	require.Contains(t, view, dashboardNever, "10.0.0.6 was never checked")
+
+	model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	require.NotNil(t, cmd)
+	require.True(t, model.rows[1].Checking)
+	require.Contains(t, model.View(), "checking")
+	_, again := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	require.Nil(t, again, "a host is checked once at a time")
+
+	model.Update(cmd())
+	require.Equal(t, []string{"10.0.0.6"}, checked)
+	require.False(t, model.rows[1].Checking)
+	require.Equal(t, watchDrift, model.rows[1].Check.Status)
+	require.Contains(t, model.View(), "python3 is missing")
+
+	statuses, err := loadDriftStatus(statusPath)
+	require.NoError(t, err)
+	require.Equal(t, driftStatus{Status: watchOK, CheckedAt: checkedAt}, statuses["10.0.0.5"])
+	require.Equal(t, driftStatus{Status: watchDrift, Detail: "python3 is missing", CheckedAt: checkedAt.Add(time.Hour)}, statuses["10.0.0.6"])
+
+	_, quit := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	require.NotNil(t, quit)
+}
+
+func TestReprepCommand(t *testing.T) {
+	t.Parallel()
+
+	cmd := reprepCommand("hosts.json", "settings.json")("10.0.0.5")
+	require.Equal(t, []string{"--host", "10.0.0.5", "--terraform-out", "hosts.json", "--config", "settings.json"}, cmd.Args[1:])
+	cmd = reprepCommand("hosts.json", "")("10.0.0.5")
+	require.Equal(t, []string{"--host", "10.0.0.5", "--terraform-out", "hosts.json"}, cmd.Args[1:])
+}
+
+func TestDashboardRequiresHosts(t *testing.T) {
+	t.Parallel()
+
+	var out strings.Builder
+	require.Error(t, runDashboard(nil, &out))
+	require.Error(t, runDashboard([]string{"--hosts", "x.json", "extra"}, &out))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/history"
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+)
+
+// runFleet implements `fleet export`, which summarizes the run history
+// recorded by --history or --store into one row per host for dashboards such as
+// Grafana's JSON or Infinity data sources.
+func runFleet(args []string, out io.Writer) error {
+	usage := func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui fleet export --history FILE|--store URL [--format json|csv] [--out FILE]")
+	}
+	if len(args) == 0 || args[0] != "export" {
+		usage()
+		return errors.New("fleet requires the export command")
+	}
+
+	flags := flag.NewFlagSet("fleet export", flag.ContinueOnError)
+	flags.SetOutput(out)
+	historyPath := flags.String("history", "", "run history, as written by --history")
+	storeURL := flags.String("store", "", "read the run history from this store, as written by --store or serve --store")
+	format := flags.String("format", "json", `dataset format: "json" or "csv"`)
+	outPath := flags.String("out", "", "write the dataset to this file instead of stdout")
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if (*historyPath == "") == (*storeURL == "") || flags.NArg() != 0 {
+		flags.Usage()
+		return errors.New("fleet export requires one of --history or --store")
+	}
+	write := history.WriteJSON
+	switch *format {
+	case "json":
+	case "csv":
+		write = history.WriteCSV
+	default:
+		return fmt.Errorf("invalid --format %q: expected json or csv", *format)
+	}
+
+	reports, err := loadHistory(*historyPath, *storeURL)
+	if err != nil {
+		return err
+	}
+	hosts := history.Fleet(reports)
+	if *outPath == "" {
+		return write(out, hosts)
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, hosts); err != nil {
+		return err
+	}
+	// Written atomically, so a dashboard polling the file never reads half of it.
+	if err := atomicfile.WriteFile(*outPath, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "exported %d host(s) to %s\n", len(hosts), *outPath)
+	return nil
+}
+
+func loadHistory(path, storeURL string) ([]report.Report, error) {
+	if path != "" {
+		return history.Load(path)
+	}
+	store, err := storage.Open(storeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	return history.LoadStore(context.Background(), store)
+}
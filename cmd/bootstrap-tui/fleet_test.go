@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/history"
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+func TestFleetExport(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	historyPath := filepath.Join(dir, "history.jsonl")
+	finished := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+	require.NoError(t, history.Append(historyPath, &report.Report{Host: "10.0.0.5", FinishedAt: finished, Success: true, Distro: "debian 12"}))
+	require.NoError(t, history.Append(historyPath, &report.Report{Host: "10.0.0.6", FinishedAt: finished, Phases: []report.PhaseResult{
+		{ID: "sudo_ensure", Status: report.StatusFailed},
+	}}))
+
+	var out bytes.Buffer
+	require.NoError(t, runFleet([]string{"export", "--history", historyPath}, &out))
+	require.Contains(t, out.String(), `"failing_phase": "sudo_ensure"`)
+
+	csvPath := filepath.Join(dir, "fleet.csv")
+	out.Reset()
+	require.NoError(t, runFleet([]string{"export", "--history", historyPath, "--format", "csv", "--out", csvPath}, &out))
+	require.Equal(t, "exported 2 host(s) to "+csvPath+"\n", out.String())
+	data, err := os.ReadFile(csvPath)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "10.0.0.5,succeeded,2026-03-10T14:30:00Z,2026-03-10T14:30:00Z,,,debian 12,,1\n")
+
+	require.Error(t, runFleet(nil, &out))
+	require.Error(t, runFleet([]string{"export"}, &out))
+	require.Error(t, runFleet([]string{"export", "--history", historyPath, "--format", "xml"}, &out))
+}
+
+func TestFleetExportFromStore(t *testing.T) {
+	t.Parallel()
+
+	storeURL := "sqlite://" + filepath.Join(t.TempDir(), "state.db")
+	store, err := storage.Open(storeURL)
+	require.NoError(t, err)
+	require.NoError(t, history.Save(context.Background(), store, &report.Report{Host: "10.0.0.5", FinishedAt: time.Now(), Success: true}))
+	require.NoError(t, store.Close())
+
+	var out bytes.Buffer
+	require.NoError(t, runFleet([]string{"export", "--store", storeURL, "--format", "csv"}, &out))
+	require.Contains(t, out.String(), "\n10.0.0.5,succeeded,")
+
+	require.Error(t, runFleet([]string{"export", "--store", storeURL, "--history", "history.jsonl"}, &out))
+}
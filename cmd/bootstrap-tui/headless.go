@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+// loadInputs reads pre-supplied answers for a headless run. The file is a JSON
+// object keyed by phase ID and then input ID; "-" reads from stdin.
+func loadInputs(path string) (phases.Inputs, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open inputs: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var inputs phases.Inputs
+	if err := json.NewDecoder(r).Decode(&inputs); err != nil {
+		return nil, fmt.Errorf("decode inputs %s: %w", path, err)
+	}
+	return inputs, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadInputs(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "inputs.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"ssh_connection":{"host":"10.0.0.5","port":2222}}`), 0o600))
+
+	inputs, err := loadInputs(path)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.5", inputs["ssh_connection"]["host"])
+	require.Equal(t, float64(2222), inputs["ssh_connection"]["port"])
+
+	require.NoError(t, os.WriteFile(path, []byte(`not json`), 0o600))
+	_, err = loadInputs(path)
+	require.Error(t, err)
+}
@@ -1,22 +1,93 @@
 package main
 
 import (
-	"context"
+	"io"
 	"log"
+	"os"
+	"time"
 
-	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
-	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/bundles/ansibleprep"
+	"github.com/BrianJOC/ansible-host-prep/pkg/config"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/email"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/webhook"
 )
 
+const (
+	webhookSecretEnv = "HOST_PREP_WEBHOOK_SECRET"
+	// httpTimeout bounds each request the CLI's integrations make.
+	httpTimeout = 10 * time.Second
+)
+
+// subcommands maps each subcommand to the function implementing it, one per
+// file. Anything else runs the prep pipeline (see runPrep).
+var subcommands = map[string]func(args []string, out io.Writer) error{
+	"audit-keys": runAuditKeys,
+	"dashboard":  runDashboard,
+	"fleet":      runFleet,
+	"profiles":   runProfiles,
+	"scan":       runScan,
+	"serve":      runServe,
+	"state":      runState,
+	"watch":      runWatch,
+}
+
 func main() {
-	app, err := phasedapp.New(
-		phasedapp.WithBundle(ansibleprep.Bundle),
-	)
-	if err != nil {
-		log.Fatalf("failed to initialize phased app: %v", err)
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+	if err := runPrep(os.Args[1:], os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// webhookOptions signs webhook deliveries when HOST_PREP_WEBHOOK_SECRET is
+// set, keeping the secret off the command line, and sends them through the
+// configured proxy.
+func webhookOptions(settings *config.Config) []webhook.Option {
+	return []webhook.Option{
+		webhook.WithHTTPClient(settings.HTTPClient(httpTimeout)),
+		webhook.WithSecret(os.Getenv(webhookSecretEnv)),
+		webhook.WithErrorHandler(func(err error) { log.Printf("webhook delivery failed: %v", err) }),
 	}
+}
 
-	if err := app.Start(context.Background()); err != nil {
-		log.Fatalf("tui exited with error: %v", err)
+// emailObserver mails run reports as configured in the settings file, or
+// returns nil when no email section is set. extra is applied last.
+func emailObserver(settings *config.Config, extra ...email.Option) *email.Observer {
+	mailCfg := settings.Email
+	if mailCfg == nil {
+		return nil
+	}
+	mailOpts := []email.Option{
+		email.WithAuth(mailCfg.Username, mailCfg.ResolvePassword()),
+		email.WithErrorHandler(func(err error) { log.Printf("email report failed: %v", err) }),
+	}
+	if mailCfg.FailuresOnly {
+		mailOpts = append(mailOpts, email.WithFailuresOnly())
+	}
+	return email.New(mailCfg.Host, mailCfg.ResolvePort(), mailCfg.From, mailCfg.To, append(mailOpts, extra...)...)
+}
+
+// loadSettings reads the integration settings file and exports its proxy to
+// the environment, so AWX requests and ansible-galaxy downloads use it. The
+// default location is optional; an explicitly named file must exist.
+func loadSettings(path string) (*config.Config, error) {
+	optional := path == ""
+	if optional {
+		if path = config.DefaultPath(); path == "" {
+			return &config.Config{}, nil
+		}
+	}
+	settings, err := config.Load(path, optional)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.ApplyProxyEnv(); err != nil {
+		return nil, err
 	}
+	return settings, nil
 }
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/bootstrapcleanup"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/pkg/config"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/bundles/ansibleprep"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/bundles/k8snode"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/history"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/metrics"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/netbox"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/terraform"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+	"github.com/BrianJOC/ansible-host-prep/utils/netscan"
+)
+
+// prepFlags holds the command line of the default command, which prepares a
+// host in the TUI or, with --inputs, headlessly.
+type prepFlags struct {
+	at, after        string
+	inputsPath       string
+	output           string
+	metricsAddr      string
+	webhookURL       string
+	transcript       string
+	terraformOut     string
+	historyPath      string
+	storeURL         string
+	configPath       string
+	reconnectTimeout time.Duration
+	check            bool
+	preset           string
+	scanCIDR         string
+	tags             string
+	agentKey         bool
+	host             string
+	revokeBootstrap  bool
+}
+
+func parsePrepFlags(args []string) (*prepFlags, error) {
+	f := &prepFlags{}
+	flags := flag.NewFlagSet("bootstrap-tui", flag.ExitOnError)
+	flags.StringVar(&f.at, "at", "", `start the pipeline at a wall-clock time (RFC3339, "2006-01-02 15:04", or "15:04")`)
+	flags.StringVar(&f.after, "after", "", "start the pipeline after a delay (e.g. 30m, 2h)")
+	flags.StringVar(&f.inputsPath, "inputs", "", `run without the TUI using answers from this JSON file ("-" for stdin)`)
+	flags.StringVar(&f.output, "output", "text", `headless progress format: "text", "json" (one JSON object per event), or "json-lines" (JSON events on stdout, progress text on stderr)`)
+	flags.StringVar(&f.metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address (e.g. :9100) at /metrics")
+	flags.StringVar(&f.webhookURL, "webhook", "", "POST JSON phase and pipeline events to this URL")
+	flags.StringVar(&f.transcript, "transcript", "", "write a redacted session transcript to this directory on exit")
+	flags.StringVar(&f.terraformOut, "terraform-out", "", "record prepared hosts in this Terraform-readable JSON file")
+	flags.StringVar(&f.historyPath, "history", "", "append a report of every run, failed ones included, to this JSON Lines file (see fleet export)")
+	flags.StringVar(&f.storeURL, "store", "", "save a report of every run to this store (directory, sqlite://, or s3:// URL), like --history")
+	flags.StringVar(&f.configPath, "config", "", "integration settings file (default: "+config.DefaultPath()+")")
+	flags.DurationVar(&f.reconnectTimeout, "reconnect-timeout", 5*time.Minute, "wait this long for a target that drops its SSH connection mid-phase (e.g. reboots) before failing; 0 disables")
+	flags.BoolVar(&f.check, "check", false, "show the sudoers and authorized_keys changes as diffs without applying them")
+	flags.StringVar(&f.preset, "preset", "", `tune the run for a kind of target: "pi" for Raspberry Pis and other small ARM devices (longer SSH retry, .local names via mDNS, ed25519 key, no optional phases)`)
+	flags.StringVar(&f.scanCIDR, "scan", "", "probe this network (e.g. 192.168.56.0/24) for SSH servers and offer them at the host prompt")
+	flags.StringVar(&f.tags, "tags", "", "after ansible prep, run the optional phases carrying any of these comma-separated tags (k8s, or one of swap, kernel-modules, sysctl, containerd)")
+	flags.BoolVar(&f.agentKey, "agent-key", false, "install a key loaded in ssh-agent for the ansible user instead of generating a key file")
+	flags.StringVar(&f.host, "host", "", "suggest this host at the host prompt, e.g. to prepare a known host again")
+	flags.BoolVar(&f.revokeBootstrap, "revoke-bootstrap", false, "once the ansible user is verified, disable the login password or remove the login key used to connect")
+	return f, flags.Parse(args)
+}
+
+// runPrep implements the default command: it builds the ansible prep
+// pipeline from the flags and runs it in the TUI, or headlessly with
+// --inputs.
+func runPrep(args []string, out io.Writer) error {
+	f, err := parsePrepFlags(args)
+	if err != nil {
+		return err
+	}
+	startAt, err := parseSchedule(f.at, f.after, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if f.inputsPath != "" && !startAt.IsZero() {
+		return errors.New("--inputs cannot be combined with --at/--after")
+	}
+	settings, err := loadSettings(f.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts, closeOpts, err := prepOptions(ctx, f, settings, startAt)
+	if err != nil {
+		return err
+	}
+	defer closeOpts()
+
+	app, err := phasedapp.New(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize phased app: %w", err)
+	}
+	if f.inputsPath != "" {
+		return runHeadless(ctx, app, f.inputsPath, out)
+	}
+	if err := app.Start(ctx); err != nil {
+		return fmt.Errorf("tui exited with error: %w", err)
+	}
+	return nil
+}
+
+// runHeadless runs app with the answers in the --inputs file.
+func runHeadless(ctx context.Context, app *phasedapp.App, inputsPath string, out io.Writer) error {
+	inputs, err := loadInputs(inputsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load inputs: %w", err)
+	}
+	if err := app.RunHeadless(ctx, inputs, out); err != nil {
+		return fmt.Errorf("headless run failed: %w", err)
+	}
+	return nil
+}
+
+// prepOptions turns the flags and settings into app options. closeOpts
+// releases what they hold open, such as a run store.
+func prepOptions(ctx context.Context, f *prepFlags, settings *config.Config, startAt time.Time) (opts []phasedapp.Option, closeOpts func(), err error) {
+	bundleOpts, err := bundleOptions(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputOpts, err := outputOptions(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	observerOpts, closeOpts, err := observerOptions(ctx, f, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append(bundleOpts, outputOpts...)
+	opts = append(opts, observerOpts...)
+	// Check mode runs a subset of the phases, which a full order would not match.
+	if len(settings.PhaseOrder) > 0 && !f.check {
+		opts = append(opts, phasedapp.WithPhaseOrder(settings.PhaseOrder...))
+	}
+	if f.transcript != "" {
+		opts = append(opts, phasedapp.WithTranscriptDir(f.transcript), phasedapp.WithTranscriptOnExit())
+	}
+	if !startAt.IsZero() {
+		opts = append(opts,
+			phasedapp.WithSchedule(startAt, ansibleprep.PreflightPhases()...),
+			phasedapp.WithPreflightPhases(ansibleprep.PreflightChecks()...),
+		)
+	}
+	return opts, closeOpts, nil
+}
+
+// bundleOptions picks the phases to run and how the summary describes them.
+func bundleOptions(f *prepFlags) ([]phasedapp.Option, error) {
+	bundle, reconnectMiddleware, err := baseBundle(f)
+	if err != nil {
+		return nil, err
+	}
+	summaryFields := ansibleprep.SummaryFields()
+	if f.tags != "" {
+		if bundle, err = withTaggedPhases(bundle, f); err != nil {
+			return nil, err
+		}
+		summaryFields = append(summaryFields, k8snode.SummaryFields()...)
+	}
+	if f.revokeBootstrap {
+		if f.check {
+			return nil, errors.New("--revoke-bootstrap cannot be combined with --check")
+		}
+		base := bundle
+		bundle = func() []phases.Phase { return append(base(), bootstrapcleanup.New()) }
+	}
+	if bundle, err = withPromptDefaults(bundle, f); err != nil {
+		return nil, err
+	}
+	opts := []phasedapp.Option{
+		phasedapp.WithBundle(bundle),
+		phasedapp.WithSummaryFields(summaryFields...),
+		phasedapp.WithLiveFields(ansibleprep.LiveFields()...),
+		phasedapp.WithNextSteps(ansibleprep.NextSteps()...),
+	}
+	if f.reconnectTimeout > 0 {
+		reconnect := reconnectMiddleware(sshconnect.WithReconnectTimeout(f.reconnectTimeout))
+		opts = append(opts, phasedapp.WithManagerOptions(phases.WithMiddleware(reconnect)))
+	}
+	return opts, nil
+}
+
+// baseBundle picks the ansible prep bundle for --check and --preset.
+func baseBundle(f *prepFlags) (func() []phases.Phase, func(...sshconnect.ReconnectOption) phases.PhaseMiddleware, error) {
+	bundle := ansibleprep.Bundle
+	reconnectMiddleware := ansibleprep.Reconnect
+	if f.check {
+		bundle = ansibleprep.CheckBundle
+	}
+	switch f.preset {
+	case "":
+	case "pi":
+		if f.check {
+			return nil, nil, errors.New("--preset pi cannot be combined with --check")
+		}
+		bundle = ansibleprep.DeviceBundle
+		reconnectMiddleware = ansibleprep.DeviceReconnect
+	default:
+		return nil, nil, fmt.Errorf("invalid --preset %q: expected pi", f.preset)
+	}
+	return bundle, reconnectMiddleware, nil
+}
+
+// withTaggedPhases appends the optional phases selected by --tags.
+func withTaggedPhases(bundle func() []phases.Phase, f *prepFlags) (func() []phases.Phase, error) {
+	if f.check {
+		return nil, errors.New("--tags cannot be combined with --check")
+	}
+	if f.preset != "" {
+		return nil, fmt.Errorf("--tags cannot be combined with --preset %s", f.preset)
+	}
+	optional := phasedapp.SelectPhases(k8snode.Bundle(), phasedapp.WithAnyTag(strings.Split(f.tags, ",")...))
+	if len(optional) == 0 {
+		return nil, fmt.Errorf("--tags %q matches no optional phases", f.tags)
+	}
+	return func() []phases.Phase { return append(bundle(), optional...) }, nil
+}
+
+// outputOptions selects the headless progress format.
+func outputOptions(f *prepFlags) ([]phasedapp.Option, error) {
+	switch f.output {
+	case "text":
+		return nil, nil
+	case "json", "json-lines":
+		if f.inputsPath == "" {
+			return nil, fmt.Errorf("--output %s requires --inputs", f.output)
+		}
+		if f.output == "json-lines" {
+			return []phasedapp.Option{phasedapp.WithJSONOutput(), phasedapp.WithLogOutput(os.Stderr)}, nil
+		}
+		return []phasedapp.Option{phasedapp.WithJSONOutput()}, nil
+	default:
+		return nil, fmt.Errorf("invalid --output %q: expected text, json, or json-lines", f.output)
+	}
+}
+
+// observerOptions registers the integrations that watch the run: webhooks,
+// NetBox, email, Terraform, run history, and metrics.
+func observerOptions(ctx context.Context, f *prepFlags, settings *config.Config) (opts []phasedapp.Option, closeOpts func(), err error) {
+	observers := settingsObservers(settings)
+	closeOpts = func() {}
+	if f.terraformOut != "" {
+		observers = append(observers, terraform.New(f.terraformOut, terraform.WithErrorHandler(func(err error) {
+			log.Printf("terraform export failed: %v", err)
+		})))
+	}
+	if f.historyPath != "" {
+		observers = append(observers, history.New(f.historyPath, history.WithErrorHandler(logHistoryError)))
+	}
+	if f.storeURL != "" {
+		store, err := storage.Open(f.storeURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid --store: %w", err)
+		}
+		closeOpts = func() { _ = store.Close() }
+		observers = append(observers, history.NewStore(store, history.WithErrorHandler(logHistoryError)))
+	}
+	if f.metricsAddr != "" {
+		observer := metrics.New()
+		observers = append(observers, observer)
+		go func() {
+			if err := observer.Serve(ctx, f.metricsAddr); err != nil {
+				log.Printf("metrics endpoint stopped: %v", err)
+			}
+		}()
+	}
+	if f.webhookURL != "" {
+		opts = append(opts, phasedapp.WithWebhook(f.webhookURL, webhookOptions(settings)...))
+	}
+	for _, observer := range observers {
+		opts = append(opts, phasedapp.WithManagerOptions(phases.WithObserver(observer)))
+	}
+	return opts, closeOpts, nil
+}
+
+// settingsObservers builds the observers configured in the settings file.
+func settingsObservers(settings *config.Config) []phases.Observer {
+	var observers []phases.Observer
+	if nb := settings.NetBox; nb != nil {
+		observers = append(observers, netbox.New(nb.URL, nb.ResolveToken(),
+			netbox.WithHTTPClient(settings.HTTPClient(httpTimeout)),
+			netbox.WithTag(nb.Tag),
+			netbox.WithCustomFields(nb.UserField, nb.FingerprintField),
+			netbox.WithErrorHandler(func(err error) { log.Printf("netbox sync failed: %v", err) }),
+		))
+	}
+	if mailer := emailObserver(settings); mailer != nil {
+		observers = append(observers, mailer)
+	}
+	return observers
+}
+
+func logHistoryError(err error) {
+	log.Printf("run history failed: %v", err)
+}
+
+// withPromptDefaults applies --scan, --host, and --agent-key, which shape
+// what the connection and ansible user phases offer at their prompts.
+func withPromptDefaults(bundle func() []phases.Phase, f *prepFlags) (func() []phases.Phase, error) {
+	if f.scanCIDR != "" {
+		if _, err := netscan.Hosts(f.scanCIDR, netscan.DefaultMaxHosts); err != nil {
+			return nil, fmt.Errorf("invalid --scan: %w", err)
+		}
+		bundle = withHostScan(bundle, f.scanCIDR)
+	}
+	if f.host != "" {
+		bundle = withDefaultHost(bundle, f.host)
+	}
+	if f.agentKey {
+		bundle = withAgentKey(bundle)
+	}
+	return bundle, nil
+}
+
+// withDefaultHost suggests host at the SSH connection phase's host prompt.
+func withDefaultHost(bundle func() []phases.Phase, host string) func() []phases.Phase {
+	return func() []phases.Phase {
+		list := bundle()
+		for _, phase := range list {
+			if connect, ok := phase.(*sshconnect.Phase); ok {
+				connect.WithDefaultHost(host)
+			}
+		}
+		return list
+	}
+}
+
+// withAgentKey makes the ansible user phase offer the ssh-agent's identities
+// instead of generating a key file.
+func withAgentKey(bundle func() []phases.Phase) func() []phases.Phase {
+	return func() []phases.Phase {
+		list := bundle()
+		for _, phase := range list {
+			if user, ok := phase.(*ansibleuser.Phase); ok {
+				user.WithKeySource(ansibleuser.KeySourceAgent)
+			}
+		}
+		return list
+	}
+}
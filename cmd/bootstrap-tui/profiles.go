@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/hostprofile"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+// errProfilesDiffer makes `profiles diff` exit non-zero, like diff(1), when
+// the hosts differ.
+var errProfilesDiffer = errors.New("profiles differ")
+
+// runProfiles implements `profiles diff <a> <b>`, which compares the prep
+// results of two hosts from their JSON reports (--output json, including
+// --check runs) or Terraform export documents.
+func runProfiles(args []string, out io.Writer) error {
+	usage := func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui profiles diff <report> <report>")
+		fmt.Fprintln(out, "a report is a --output json log or a --terraform-out file (append #<host> to pick a host)")
+	}
+	if len(args) == 0 || args[0] != "diff" {
+		usage()
+		return errors.New("profiles requires the diff command")
+	}
+
+	flags := flag.NewFlagSet("profiles diff", flag.ContinueOnError)
+	flags.SetOutput(out)
+	flags.Usage = usage
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		usage()
+		return errors.New("profiles diff requires exactly two reports")
+	}
+
+	a, err := hostprofile.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := hostprofile.Load(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	diffs := hostprofile.Diff(a, b)
+	writeProfileDiff(out, a, b, diffs)
+	if len(diffs) > 0 {
+		return errProfilesDiffer
+	}
+	return nil
+}
+
+// writeProfileDiff prints each differing attribute with both values, or a
+// unified diff for multi-line values such as planned sudoers files.
+func writeProfileDiff(out io.Writer, a, b *hostprofile.Profile, diffs []hostprofile.Difference) {
+	if len(diffs) == 0 {
+		fmt.Fprintf(out, "%s and %s match\n", a.Name, b.Name)
+		return
+	}
+	side := func(value string, missing bool) string {
+		if missing {
+			return "(absent)"
+		}
+		return value
+	}
+	for _, d := range diffs {
+		fmt.Fprintln(out, d.Key)
+		if strings.Contains(d.A, "\n") || strings.Contains(d.B, "\n") {
+			diff := systemuser.UnifiedDiff(a.Name, b.Name, d.A, d.B)
+			for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+				fmt.Fprintf(out, "  %s\n", line)
+			}
+			continue
+		}
+		fmt.Fprintf(out, "  - %s: %s\n", a.Name, side(d.A, d.MissingA))
+		fmt.Fprintf(out, "  + %s: %s\n", b.Name, side(d.B, d.MissingB))
+	}
+	fmt.Fprintf(out, "%d difference(s)\n", len(diffs))
+}
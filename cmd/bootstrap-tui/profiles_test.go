@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunProfilesDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	a := write("a.json", `{"event":"task","task":{"name":"/etc/sudoers.d/ansible","status":"changed","message":"--- /dev/null\n+++ /etc/sudoers.d/ansible\n@@ -0,0 +1,1 @@\n+ansible ALL=(ALL) NOPASSWD: ALL\n"}}
+{"event":"pipeline_finished","success":true,"summary":{"Python interpreter":"/usr/bin/python3"}}
+`)
+	b := write("b.json", `{"event":"task","task":{"name":"/etc/sudoers.d/ansible","status":"changed","message":"--- /dev/null\n+++ /etc/sudoers.d/ansible\n@@ -0,0 +1,1 @@\n+ansible ALL=(ALL) ALL\n"}}
+{"event":"pipeline_finished","success":true,"summary":{"Python interpreter":"/usr/libexec/platform-python"}}
+`)
+
+	var out bytes.Buffer
+	err := runProfiles([]string{"diff", a, b}, &out)
+	require.ErrorIs(t, err, errProfilesDiffer)
+	require.Contains(t, out.String(), "Python interpreter\n  - "+a+": /usr/bin/python3\n  + "+b+": /usr/libexec/platform-python\n")
+	require.Contains(t, out.String(), "planned /etc/sudoers.d/ansible\n")
+	require.Contains(t, out.String(), "  -ansible ALL=(ALL) NOPASSWD: ALL\n  +ansible ALL=(ALL) ALL\n")
+	require.Contains(t, out.String(), "2 difference(s)")
+
+	out.Reset()
+	require.NoError(t, runProfiles([]string{"diff", a, a}, &out))
+	require.Contains(t, out.String(), "match")
+
+	require.Error(t, runProfiles([]string{"diff", a}, &out))
+	require.Error(t, runProfiles(nil, &out))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/netscan"
+)
+
+// runScan implements `scan <cidr>`: it probes every address in the network
+// for an SSH server and lists those that answered, with their banners.
+func runScan(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.SetOutput(out)
+	port := flags.Int("port", 22, "TCP port to probe")
+	rate := flags.Int("rate", 100, "probes started per second")
+	timeout := flags.Duration("timeout", time.Second, "connect and banner timeout per address")
+	all := flags.Bool("all", false, "also list open ports that sent no SSH banner")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui scan [flags] <cidr>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("scan requires exactly one network, e.g. 192.168.56.0/24")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	candidates, err := netscan.Scan(ctx, flags.Arg(0), netscan.WithPort(*port), netscan.WithRate(*rate), netscan.WithTimeout(*timeout))
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ADDRESS\tPORT\tBANNER")
+	for _, c := range candidates {
+		if !c.IsSSH() && !*all {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", c.Addr, c.Port, c.Banner)
+	}
+	return tw.Flush()
+}
+
+// withHostScan makes the ssh_connection phase of bundle offer the SSH
+// servers found in cidr when it asks for the host.
+func withHostScan(bundle func() []phases.Phase, cidr string) func() []phases.Phase {
+	scan := func() []netscan.Candidate {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		candidates, err := netscan.Scan(ctx, cidr)
+		if err != nil {
+			log.Printf("host scan of %s stopped early: %v", cidr, err)
+		}
+		var ssh []netscan.Candidate
+		for _, c := range candidates {
+			if c.IsSSH() {
+				ssh = append(ssh, c)
+			}
+		}
+		return ssh
+	}
+	return func() []phases.Phase {
+		list := bundle()
+		for _, phase := range list {
+			if connect, ok := phase.(*sshconnect.Phase); ok {
+				connect.WithHostScanner(scan)
+			}
+		}
+		return list
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunScanListsSSHServers(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			_ = conn.Close()
+		}
+	}()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	var out bytes.Buffer
+	require.NoError(t, runScan([]string{"--port", port, "127.0.0.1/32"}, &out))
+	require.Contains(t, out.String(), "ADDRESS")
+	require.Regexp(t, `127\.0\.0\.1\s+`+port+`\s+SSH-2\.0-OpenSSH_9\.6`, out.String())
+
+	require.Error(t, runScan(nil, &out))
+	require.Error(t, runScan([]string{"10.0.0.0/8"}, &out))
+}
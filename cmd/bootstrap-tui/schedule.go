@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var scheduleLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+}
+
+// parseSchedule resolves the --at/--after flags into an absolute start time.
+// A zero time means the pipeline should start immediately.
+func parseSchedule(at, after string, now time.Time) (time.Time, error) {
+	at = strings.TrimSpace(at)
+	after = strings.TrimSpace(after)
+
+	switch {
+	case at != "" && after != "":
+		return time.Time{}, errors.New("--at and --after are mutually exclusive")
+	case after != "":
+		delay, err := time.ParseDuration(after)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --after value %q: %w", after, err)
+		}
+		if delay <= 0 {
+			return time.Time{}, fmt.Errorf("--after must be positive, got %s", after)
+		}
+		return now.Add(delay), nil
+	case at != "":
+		return parseAt(at, now)
+	default:
+		return time.Time{}, nil
+	}
+}
+
+func parseAt(value string, now time.Time) (time.Time, error) {
+	for _, layout := range scheduleLayouts {
+		if ts, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			if !ts.After(now) {
+				return time.Time{}, fmt.Errorf("--at %s is in the past", value)
+			}
+			return ts, nil
+		}
+	}
+
+	clock, err := time.ParseInLocation("15:04", value, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --at value %q: use RFC3339, \"2006-01-02 15:04\", or \"15:04\"", value)
+	}
+	ts := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !ts.After(now) {
+		ts = ts.AddDate(0, 0, 1)
+	}
+	return ts, nil
+}
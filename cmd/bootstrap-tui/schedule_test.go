@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		at      string
+		after   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "immediate"},
+		{name: "after duration", after: "90m", want: now.Add(90 * time.Minute)},
+		{name: "at clock later today", at: "23:15", want: time.Date(2026, 3, 10, 23, 15, 0, 0, time.UTC)},
+		{name: "at clock rolls to tomorrow", at: "02:00", want: time.Date(2026, 3, 11, 2, 0, 0, 0, time.UTC)},
+		{name: "at full timestamp", at: "2026-03-12 01:30", want: time.Date(2026, 3, 12, 1, 30, 0, 0, time.UTC)},
+		{name: "at rfc3339", at: "2026-03-12T01:30:00Z", want: time.Date(2026, 3, 12, 1, 30, 0, 0, time.UTC)},
+		{name: "at in the past", at: "2026-03-09 01:30", wantErr: true},
+		{name: "both flags", at: "23:15", after: "1h", wantErr: true},
+		{name: "negative delay", after: "-5m", wantErr: true},
+		{name: "garbage", at: "tomorrow-ish", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSchedule(tt.at, tt.after, now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.want.Equal(got), "want %s got %s", tt.want, got)
+		})
+	}
+}
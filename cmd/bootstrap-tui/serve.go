@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/pkg/config"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/bundles/ansibleprep"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/grpcapi"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+// runServe implements `serve`: it exposes the prep pipeline as an HTTP API
+// (and optionally gRPC) so a web frontend or another service can start runs,
+// stream their events, and answer input requests. Runs beyond
+// --max-concurrent wait in a queue, which makes it usable as a long-running
+// prep service shared by a team. With --tokens every request needs a bearer
+// token, and the principal it names is recorded on the run and in the
+// --audit-log trail. With --store finished runs and their reports outlive
+// the process in a directory, SQLite database, or S3-compatible bucket.
+func runServe(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(out)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to serve the HTTP API on")
+	grpcAddr := flags.String("grpc-addr", "", "also serve the gRPC API on this address (e.g. 127.0.0.1:9090)")
+	webhookURL := flags.String("webhook", "", "POST JSON phase and pipeline events to this URL")
+	configPath := flags.String("config", "", "integration settings file (default: "+config.DefaultPath()+")")
+	maxConcurrent := flags.Int("max-concurrent", 4, "run at most this many preps at once and queue the rest; 0 runs all immediately")
+	retain := flags.Int("retain", 100, "keep the status and events of this many finished runs; 0 keeps all")
+	tokensPath := flags.String("tokens", "", `require bearer tokens listed in this file, one "principal token" pair per line`)
+	storeURL := flags.String("store", "", "keep finished runs and the run history in this store (directory, sqlite://, or s3:// URL)")
+	auditPath := flags.String("audit-log", "", "append a JSON line per run start, answer, cancel, finish, and rejected token to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui serve [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		return err
+	}
+
+	serverOpts := []phasedapp.ServerOption{
+		phasedapp.WithHostInput(sshconnect.New().Metadata().ID, sshconnect.InputHost),
+		phasedapp.WithMaxConcurrentRuns(*maxConcurrent),
+		phasedapp.WithRunRetention(*retain),
+	}
+	if *tokensPath != "" {
+		tokens, err := loadTokens(*tokensPath)
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, phasedapp.WithAuthTokens(tokens))
+	}
+	if *auditPath != "" {
+		auditLog, err := os.OpenFile(*auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("open audit log: %w", err)
+		}
+		defer auditLog.Close()
+		serverOpts = append(serverOpts, phasedapp.WithAuditLog(auditLog))
+	}
+
+	if *storeURL != "" {
+		store, err := storage.Open(*storeURL)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		serverOpts = append(serverOpts, phasedapp.WithRunStore(store, func(err error) {
+			log.Printf("run store: %v", err)
+		}))
+	}
+
+	opts := []phasedapp.Option{
+		phasedapp.WithBundle(ansibleprep.Bundle),
+		phasedapp.WithSummaryFields(ansibleprep.SummaryFields()...),
+	}
+	if *webhookURL != "" {
+		opts = append(opts, phasedapp.WithWebhook(*webhookURL, webhookOptions(settings)...))
+	}
+	app, err := phasedapp.New(opts...)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := app.NewServer(serverOpts...)
+	if *grpcAddr != "" {
+		service := grpcapi.New(server)
+		go func() {
+			if err := service.Serve(ctx, *grpcAddr); err != nil {
+				log.Printf("grpc api stopped: %v", err)
+				stop()
+			}
+		}()
+		fmt.Fprintf(out, "serving gRPC API on %s\n", *grpcAddr)
+	}
+	fmt.Fprintf(out, "serving API on http://%s\n", *addr)
+	return server.Serve(ctx, *addr)
+}
+
+// loadTokens reads a tokens file: one "principal token" pair per line, with
+// blank lines and # comments ignored.
+func loadTokens(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokens: %w", err)
+	}
+	defer file.Close()
+
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"principal token\"", path, line)
+		}
+		if _, dup := tokens[fields[1]]; dup {
+			return nil, fmt.Errorf("%s:%d: token already assigned", path, line)
+		}
+		tokens[fields[1]] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s: no tokens", path)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTokens(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# CI and on-call\nci s3cr3t\n\nalice  hunter2\n"), 0o600))
+	tokens, err := loadTokens(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"s3cr3t": "ci", "hunter2": "alice"}, tokens)
+
+	require.NoError(t, os.WriteFile(path, []byte("ci s3cr3t\nbob s3cr3t\n"), 0o600))
+	_, err = loadTokens(path)
+	require.ErrorContains(t, err, ":2: token already assigned")
+
+	require.NoError(t, os.WriteFile(path, []byte("ci\n"), 0o600))
+	_, err = loadTokens(path)
+	require.ErrorContains(t, err, `:1: want "principal token"`)
+}
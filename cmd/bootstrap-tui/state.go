@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/config"
+	"github.com/BrianJOC/ansible-host-prep/pkg/state"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+// runState implements `state export <archive>` and `state import <archive>`,
+// which move settings profiles and other local state to a new workstation.
+// --history and --inputs bundle a run history and an answers file kept
+// elsewhere; on import they say where to restore them. Inline secrets are
+// left out of the archive, as are files that are not JSON. With --store the
+// archive is kept in a shared store under state/<archive> instead of a local
+// file.
+func runState(args []string, out io.Writer) error {
+	usage := func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui state export|import [flags] <archive>")
+	}
+	if len(args) == 0 {
+		usage()
+		return errors.New("state requires export or import")
+	}
+
+	flags := flag.NewFlagSet("state "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	dir := flags.String("dir", config.Dir(), "state directory")
+	force := flags.Bool("force", false, "import: overwrite files that already exist")
+	storeURL := flags.String("store", "", "keep the archive in this store (directory, sqlite://, or s3:// URL) instead of a local file")
+	historyPath := flags.String("history", "", "also move this run history file, as written by --history")
+	inputsPath := flags.String("inputs", "", "also move this answers file, as used with --inputs")
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("state requires exactly one archive path")
+	}
+	if *dir == "" {
+		return errors.New("no state directory on this platform; pass --dir")
+	}
+	archive := flags.Arg(0)
+	var opts []state.Option
+	if *historyPath != "" {
+		opts = append(opts, state.WithExtraFile("history.jsonl", *historyPath))
+	}
+	if *inputsPath != "" {
+		opts = append(opts, state.WithExtraFile("inputs.json", *inputsPath))
+	}
+	if *storeURL != "" {
+		return runStateInStore(args[0], *storeURL, *dir, archive, *force, out, opts)
+	}
+
+	switch args[0] {
+	case "export":
+		return exportState(*dir, archive, out, opts)
+	case "import":
+		return importState(*dir, archive, *force, out, opts)
+	default:
+		usage()
+		return fmt.Errorf("unknown state command %q", args[0])
+	}
+}
+
+func exportState(dir, archive string, out io.Writer, opts []state.Option) error {
+	file, err := os.OpenFile(archive, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	manifest, err := state.Export(dir, file, opts...)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(archive)
+		return err
+	}
+	fmt.Fprintf(out, "exported %d file(s) from %s to %s\n", len(manifest.Files)+len(manifest.Extra), dir, archive)
+	printRedacted(out, manifest)
+	return nil
+}
+
+func importState(dir, archive string, force bool, out io.Writer, opts []state.Option) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return importStateFrom(file, dir, force, out, opts)
+}
+
+// runStateInStore exports or imports the archive stored under
+// state/<archive> in the store at storeURL.
+func runStateInStore(command, storeURL, dir, archive string, force bool, out io.Writer, opts []state.Option) error {
+	store, err := storage.Open(storeURL)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	key := "state/" + archive
+	ctx := context.Background()
+
+	switch command {
+	case "export":
+		var buf bytes.Buffer
+		manifest, err := state.Export(dir, &buf, opts...)
+		if err != nil {
+			return err
+		}
+		if err := store.Put(ctx, key, buf.Bytes()); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "exported %d file(s) from %s to %s in %s\n", len(manifest.Files)+len(manifest.Extra), dir, key, storeURL)
+		printRedacted(out, manifest)
+		return nil
+	case "import":
+		data, err := store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		return importStateFrom(bytes.NewReader(data), dir, force, out, opts)
+	default:
+		return fmt.Errorf("unknown state command %q", command)
+	}
+}
+
+func importStateFrom(archive io.Reader, dir string, force bool, out io.Writer, opts []state.Option) error {
+	manifest, err := state.Import(archive, dir, force, opts...)
+	var conflict state.ConflictError
+	if errors.As(err, &conflict) {
+		return fmt.Errorf("%w (rerun with --force to overwrite)", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "imported %d file(s) into %s\n", len(manifest.Files)+len(manifest.Extra), dir)
+	printRedacted(out, manifest)
+	return nil
+}
+
+func printRedacted(out io.Writer, manifest *state.Manifest) {
+	if len(manifest.Skipped) > 0 {
+		fmt.Fprintln(out, "files not included (not JSON, so they could hold secrets; copy them yourself if needed):")
+		for _, name := range manifest.Skipped {
+			fmt.Fprintf(out, "  %s\n", name)
+		}
+	}
+	if len(manifest.Redacted) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "secrets not included (set them again or use the *_env fields):")
+	for _, field := range manifest.Redacted {
+		fmt.Fprintf(out, "  %s\n", field)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/driftcheck"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/pkg/config"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/bundles/ansibleprep"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/email"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/terraform"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/webhook"
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+)
+
+// Drift check outcomes printed by watch.
+const (
+	watchOK    = "ok"
+	watchDrift = "drift"
+	watchError = "error"
+)
+
+// watchResult is the outcome of one host's drift check.
+type watchResult struct {
+	Host   string
+	Status string
+	Detail string
+}
+
+// driftStatus is the last check of one host, as kept in the status file
+// next to the export for the dashboard.
+type driftStatus struct {
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// driftChecker runs the drift bundle for one host of the export.
+type driftChecker func(ctx context.Context, attrs map[string]string, observers []phases.Observer) error
+
+// runWatch implements `watch`: it re-checks every host recorded by
+// --terraform-out for drift on an interval and reports drifted hosts
+// through the configured notifiers.
+func runWatch(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	flags.SetOutput(out)
+	hostsPath := flags.String("hosts", "", "prepared hosts to check, as written by --terraform-out (required)")
+	interval := flags.Duration("interval", 24*time.Hour, "time between rounds of checks")
+	once := flags.Bool("once", false, "check every host once and exit, failing when any drifted")
+	webhookURL := flags.String("webhook", "", "POST JSON events of drifted or failed checks to this URL")
+	configPath := flags.String("config", "", "integration settings file for email reports (default: "+config.DefaultPath()+")")
+	statusPath := flags.String("status", "", "record each host's last check in this file (default: the --hosts file with a .drift.json suffix)")
+	flags.Usage = func() {
+		fmt.Fprintln(out, "usage: bootstrap-tui watch --hosts FILE [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *hostsPath == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return errors.New("watch requires --hosts")
+	}
+	if *interval < time.Minute {
+		return fmt.Errorf("--interval must be at least 1m, got %s", *interval)
+	}
+	if *statusPath == "" {
+		*statusPath = driftStatusPath(*hostsPath)
+	}
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	var observers []phases.Observer
+	if *webhookURL != "" {
+		observers = append(observers, &failuresOnly{Observer: webhook.New(*webhookURL, webhookOptions(settings)...)})
+	}
+	if mailer := emailObserver(settings, email.WithFailuresOnly()); mailer != nil {
+		observers = append(observers, mailer)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	for {
+		results, err := watchRound(ctx, *hostsPath, checkDrift, observers)
+		if err != nil {
+			return err
+		}
+		checkedAt := time.Now()
+		if err := recordDriftStatus(*statusPath, checkedAt, results); err != nil {
+			return err
+		}
+		if err := printWatchResults(out, checkedAt, results); err != nil {
+			return err
+		}
+		if *once {
+			if drifted := countDrifted(results); drifted > 0 {
+				return fmt.Errorf("%d of %d host(s) drifted", drifted, len(results))
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// watchRound checks every host in the export at path, re-read each round so
+// hosts prepared in the meantime are included.
+func watchRound(ctx context.Context, path string, check driftChecker, observers []phases.Observer) ([]watchResult, error) {
+	doc, err := terraform.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Hosts) == 0 {
+		return nil, fmt.Errorf("%s lists no prepared hosts", path)
+	}
+	names := make([]string, 0, len(doc.Hosts))
+	for name := range doc.Hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]watchResult, 0, len(names))
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		status, detail := classifyDrift(check(ctx, doc.Hosts[name], observers))
+		results = append(results, watchResult{Host: name, Status: status, Detail: detail})
+	}
+	return results, nil
+}
+
+// checkDrift logs in to the host as its ansible user with the recorded key
+// and runs ansibleprep.DriftBundle.
+func checkDrift(ctx context.Context, attrs map[string]string, observers []phases.Observer) error {
+	host, user, key := attrs["ansible_host"], attrs["ansible_user"], attrs["ansible_ssh_private_key_file"]
+	if host == "" || user == "" || key == "" {
+		return errors.New("export lacks ansible_host, ansible_user, or ansible_ssh_private_key_file")
+	}
+	if port := attrs["ansible_port"]; port != "" && port != "22" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	var opts []phases.ManagerOption
+	for _, obs := range observers {
+		opts = append(opts, phases.WithObserver(obs))
+	}
+	manager := phases.NewManager(opts...)
+	if err := manager.Register(ansibleprep.DriftBundle()...); err != nil {
+		return err
+	}
+	phaseCtx := phases.NewContext()
+	// Known up front so notifications name the host even when login fails.
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, attrs["ansible_host"])
+	sshID := sshconnect.New().Metadata().ID
+	phases.SetInput(phaseCtx, sshID, sshconnect.InputHost, host)
+	phases.SetInput(phaseCtx, sshID, sshconnect.InputUsername, user)
+	phases.SetInput(phaseCtx, sshID, sshconnect.InputAuthMethod, sshconnect.AuthMethodPrivateKey)
+	phases.SetInput(phaseCtx, sshID, sshconnect.InputKeyPath, key)
+	phases.SetInput(phaseCtx, ansibleuser.PhaseID, ansibleuser.InputKeyPath, key)
+	defer func() {
+		if client, ok := phases.GetContext(phaseCtx, sshconnect.KeySSHClient); ok && client != nil {
+			_ = client.Close()
+		}
+	}()
+	return manager.Run(ctx, phaseCtx)
+}
+
+// classifyDrift tells drift, including an ansible user that can no longer
+// log in or sudo without a password, apart from checks that could not run.
+func classifyDrift(err error) (status, detail string) {
+	var driftErr driftcheck.DriftError
+	var authErr sshconnection.AuthenticationError
+	var inputErr phases.InputRequestError
+	switch {
+	case err == nil:
+		return watchOK, ""
+	case errors.As(err, &driftErr):
+		detail := ""
+		for i, finding := range driftErr.Findings {
+			if i > 0 {
+				detail += "; "
+			}
+			detail += finding.Detail
+		}
+		return watchDrift, detail
+	case errors.As(err, &authErr):
+		return watchDrift, "the ansible user cannot log in with its key"
+	case errors.As(err, &inputErr) && inputErr.PhaseID == sudoensure.PhaseID:
+		return watchDrift, "sudo asks the ansible user for a password"
+	default:
+		return watchError, err.Error()
+	}
+}
+
+func countDrifted(results []watchResult) int {
+	n := 0
+	for _, result := range results {
+		if result.Status == watchDrift {
+			n++
+		}
+	}
+	return n
+}
+
+func printWatchResults(out io.Writer, at time.Time, results []watchResult) error {
+	fmt.Fprintf(out, "drift check at %s\n", at.Format(time.RFC3339))
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tSTATUS\tDETAIL")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Host, result.Status, result.Detail)
+	}
+	return tw.Flush()
+}
+
+// driftStatusPath is where watch records check results for an export at
+// hostsPath: prepared-hosts.json keeps them in prepared-hosts.drift.json.
+func driftStatusPath(hostsPath string) string {
+	return strings.TrimSuffix(hostsPath, ".json") + ".drift.json"
+}
+
+// loadDriftStatus reads the status file at path, keyed by host; a missing
+// file means no host has been checked yet.
+func loadDriftStatus(path string) (map[string]driftStatus, error) {
+	statuses := make(map[string]driftStatus)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return statuses, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return statuses, nil
+}
+
+// recordDriftStatus updates the checked hosts in the status file at path,
+// keeping the last result of hosts a round did not reach.
+func recordDriftStatus(path string, at time.Time, results []watchResult) error {
+	statuses, err := loadDriftStatus(path)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		statuses[result.Host] = driftStatus{Status: result.Status, Detail: result.Detail, CheckedAt: at.UTC()}
+	}
+	data, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// failuresOnly forwards only the pipeline outcome of failed checks, so a
+// webhook hears about drift instead of every phase of every round.
+type failuresOnly struct {
+	*webhook.Observer
+}
+
+func (f *failuresOnly) PhaseStarted(phases.PhaseMetadata)          {}
+func (f *failuresOnly) PhaseCompleted(phases.PhaseMetadata, error) {}
+
+func (f *failuresOnly) PipelineCompleted(phaseCtx *phases.Context, err error) {
+	if err != nil {
+		f.Observer.PipelineCompleted(phaseCtx, err)
+	}
+}
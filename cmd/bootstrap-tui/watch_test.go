@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/driftcheck"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+)
+
+func TestWatchRoundClassifiesHosts(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "prepared-hosts.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"hosts": {
+		"10.0.0.5": {"ansible_host": "10.0.0.5"},
+		"10.0.0.6": {"ansible_host": "10.0.0.6"},
+		"10.0.0.7": {"ansible_host": "10.0.0.7"},
+		"10.0.0.8": {"ansible_host": "10.0.0.8"},
+		"10.0.0.9": {"ansible_host": "10.0.0.9"}
+	}}`), 0o600))
+
+	outcomes := map[string]error{
+		"10.0.0.5": nil,
+		"10.0.0.6": phases.PhaseExecutionError{Err: driftcheck.DriftError{Host: "10.0.0.6", Findings: []driftcheck.Finding{
+			{Check: driftcheck.CheckFile, Detail: "/etc/sudoers.d/ansible was changed"},
+			{Check: driftcheck.CheckPython, Detail: "python3 is missing"},
+		}}},
+		"10.0.0.7": phases.PhaseExecutionError{Err: sshconnection.AuthenticationError{Username: "ansible", Err: errors.New("unable to authenticate")}},
+		"10.0.0.8": phases.PhaseExecutionError{Err: phases.InputRequestError{PhaseID: sudoensure.PhaseID, Reason: "password rejected"}},
+		"10.0.0.9": phases.PhaseExecutionError{Err: sshconnection.TimeoutError{Addr: "10.0.0.9:22", Err: errors.New("i/o timeout")}},
+	}
+	check := func(_ context.Context, attrs map[string]string, _ []phases.Observer) error {
+		return outcomes[attrs["ansible_host"]]
+	}
+
+	results, err := watchRound(context.Background(), path, check, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	require.Equal(t, watchResult{Host: "10.0.0.5", Status: watchOK}, results[0])
+	require.Equal(t, watchResult{Host: "10.0.0.6", Status: watchDrift, Detail: "/etc/sudoers.d/ansible was changed; python3 is missing"}, results[1])
+	require.Equal(t, watchDrift, results[2].Status)
+	require.Equal(t, watchDrift, results[3].Status)
+	require.Equal(t, watchError, results[4].Status)
+	require.Equal(t, 3, countDrifted(results))
+
+	var out bytes.Buffer
+	require.NoError(t, printWatchResults(&out, time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC), results))
+	require.Contains(t, out.String(), "drift check at 2026-03-10T14:30:00Z\n")
+	require.Contains(t, out.String(), "python3 is missing")
+
+	_, err = watchRound(context.Background(), filepath.Join(t.TempDir(), "missing.json"), check, nil)
+	require.Error(t, err, "an export without hosts is an error")
+}
+
+func TestWatchRequiresHosts(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	require.Error(t, runWatch(nil, &out))
+	require.Error(t, runWatch([]string{"--hosts", "x.json", "--interval", "10s"}, &out))
+}
@@ -2,30 +2,98 @@ package ansibleuser
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
 	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
 	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
 	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
 	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
 )
 
 const (
 	phaseID = "ansible_user"
+	// PhaseID identifies the phase, e.g. in other phases' Requires.
+	PhaseID = phaseID
 
 	// Input identifiers
 	InputKeyPath = "key_path"
+	// InputKeySource selects where the ansible user's key comes from:
+	// KeySourceFile (generate or reuse the pair at InputKeyPath) or
+	// KeySourceAgent (install a public key the local ssh-agent holds).
+	InputKeySource = "key_source"
+	// InputAgentKey is the SHA256 fingerprint of the ssh-agent identity to
+	// install with KeySourceAgent.
+	InputAgentKey = "agent_key"
+	// InputSudoGroup overrides the detected admin group (sudo or wheel).
+	InputSudoGroup = "sudo_group"
+	// InputHomeBase overrides the base directory for the new user's home.
+	InputHomeBase = "home_base"
+	// InputKeyWrite selects who writes authorized_keys: KeyWriteRoot or
+	// KeyWriteUser (for NFS homes exported with root_squash).
+	InputKeyWrite = "key_write"
+	// InputSudoLogFile is a file sudo logs the ansible user's commands to.
+	InputSudoLogFile = "sudo_logfile"
+	// InputSudoIOLog ("yes"/"no") turns on sudo I/O logging (log_output) for
+	// the ansible user.
+	InputSudoIOLog = "sudo_iolog"
+	// InputExtraKeys is a local file of more public keys to authorize for the
+	// ansible user, one per line, such as the team's keys.
+	InputExtraKeys = "extra_keys"
+	// InputPasswordHash is a crypt(3) hash to set as the ansible user's
+	// password.
+	InputPasswordHash = "password_hash"
+	// InputLockPassword ("yes"/"no") locks the ansible user's password so
+	// only key logins work.
+	InputLockPassword = "lock_password"
+	// InputSudoCommands is a comma-separated list of absolute command paths
+	// to limit the ansible user's passwordless sudo to, instead of ALL.
+	InputSudoCommands = "sudo_commands"
+
+	KeyWriteRoot = "root"
+	KeyWriteUser = "user"
+
+	KeySourceFile  = "file"
+	KeySourceAgent = "agent"
 
 	// Context keys
 	ContextKeyUserResult = "ansible:user_result"
 	ContextKeyKeyInfo    = "ansible:keypair_info"
+	// ContextKeyKeyFingerprint and ContextKeyKeyFingerprintMD5 hold the
+	// SHA256 and legacy MD5 fingerprints of the ansible user's key pair.
+	ContextKeyKeyFingerprint    = "ansible:key_fingerprint"
+	ContextKeyKeyFingerprintMD5 = "ansible:key_fingerprint_md5"
+	// ContextKeyAgentKey is the SHA256 fingerprint of the ssh-agent
+	// identity installed with KeySourceAgent; ContextKeyKeyInfo is unset
+	// then, as there is no key file.
+	ContextKeyAgentKey = "ansible:agent_key"
+	// ContextKeySudoVerified is true once the ansible user has logged in over
+	// a second SSH connection and run `sudo -n true`.
+	ContextKeySudoVerified = "ansible:sudo_verified"
+	// ContextKeyUserPlan holds the *systemuser.Plan computed in check mode.
+	ContextKeyUserPlan = "ansible:user_plan"
 
 	defaultUsername = "ansible"
 	defaultKeyName  = "ansible_id"
+	// agentKeyPrefix marks an ssh-agent identity in a SudoVerifier key path,
+	// as in sshconnect's key path input.
+	agentKeyPrefix = "agent:"
+)
+
+// Typed keys for context entries other phases read; see phases.Key.
+var (
+	KeyUserResult = phases.NewKey[*systemuser.Result](ContextKeyUserResult)
+	KeyKeyPair    = phases.NewKey[*sshkeypair.KeyPairInfo](ContextKeyKeyInfo)
 )
 
 // KeyPairEnsurer wraps sshkeypair.EnsureKeyPair.
@@ -34,11 +102,33 @@ type KeyPairEnsurer func(privatePath string, opts ...sshkeypair.Option) (*sshkey
 // UserEnsurer wraps systemuser.EnsureUser.
 type UserEnsurer func(r systemuser.Runner, username string, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error)
 
+// UserPlanner wraps systemuser.PlanUser.
+type UserPlanner func(r systemuser.Runner, username string, publicKey string, opts ...systemuser.Option) (*systemuser.Plan, error)
+
+// UserRemover wraps systemuser.RemoveUser.
+type UserRemover func(r systemuser.Runner, username string, opts ...systemuser.Option) error
+
+// AgentKeyLister lists the identities of the local ssh-agent.
+type AgentKeyLister func() ([]*agent.Key, error)
+
+// SudoVerifier logs in to host as username with the private key at keyPath
+// (or agent:<fingerprint> for an ssh-agent identity) and checks that sudo
+// works without a password, for commands when passwordless sudo was limited
+// to them.
+type SudoVerifier func(ctx context.Context, host string, port int, username, keyPath string, commands ...string) error
+
 // Phase creates the ansible user with passwordless sudo and SSH access.
 type Phase struct {
 	ensureKeyPair KeyPairEnsurer
 	ensureUser    UserEnsurer
+	planUser      UserPlanner
+	verifySudo    SudoVerifier
+	removeUser    UserRemover
+	listAgentKeys AgentKeyLister
+	keySource     string
 	username      string
+	checkMode     bool
+	keyPairOpts   []sshkeypair.Option
 }
 
 // New constructs the ansible user phase.
@@ -46,10 +136,53 @@ func New() *Phase {
 	return &Phase{
 		ensureKeyPair: sshkeypair.EnsureKeyPair,
 		ensureUser:    systemuser.EnsureUser,
+		planUser:      systemuser.PlanUser,
+		verifySudo:    verifySudo,
+		removeUser:    systemuser.RemoveUser,
+		listAgentKeys: listAgentKeys,
 		username:      defaultUsername,
 	}
 }
 
+// WithCheckMode makes the phase report the sudoers and authorized_keys
+// changes it would make, as unified diffs, instead of applying them. The
+// local key pair is still created so the planned key is the real one.
+func (p *Phase) WithCheckMode() *Phase {
+	p.checkMode = true
+	return p
+}
+
+// WithSudoVerifier overrides how passwordless sudo is verified (for tests).
+func (p *Phase) WithSudoVerifier(fn SudoVerifier) *Phase {
+	if fn != nil {
+		p.verifySudo = fn
+	}
+	return p
+}
+
+// WithUserRemover overrides how Undo removes the user (for tests).
+func (p *Phase) WithUserRemover(fn UserRemover) *Phase {
+	if fn != nil {
+		p.removeUser = fn
+	}
+	return p
+}
+
+// WithUserPlanner overrides the check-mode planning function (for tests).
+func (p *Phase) WithUserPlanner(fn UserPlanner) *Phase {
+	if fn != nil {
+		p.planUser = fn
+	}
+	return p
+}
+
+// WithKeyPairOptions configures how a missing key pair is generated, e.g.
+// sshkeypair.WithKeyType(sshkeypair.KeyTypeEd25519).
+func (p *Phase) WithKeyPairOptions(opts ...sshkeypair.Option) *Phase {
+	p.keyPairOpts = append(p.keyPairOpts, opts...)
+	return p
+}
+
 // WithKeyPairEnsurer overrides the key pair function (useful for testing).
 func (p *Phase) WithKeyPairEnsurer(fn KeyPairEnsurer) *Phase {
 	if fn != nil {
@@ -58,6 +191,26 @@ func (p *Phase) WithKeyPairEnsurer(fn KeyPairEnsurer) *Phase {
 	return p
 }
 
+// WithKeySource sets the key source used when InputKeySource is not
+// supplied, e.g. KeySourceAgent so the operator is offered the ssh-agent's
+// identities instead of a key path.
+func (p *Phase) WithKeySource(source string) *Phase {
+	switch source {
+	case KeySourceFile, KeySourceAgent:
+		p.keySource = source
+	}
+	return p
+}
+
+// WithAgentKeyLister overrides how ssh-agent identities are listed (for
+// tests).
+func (p *Phase) WithAgentKeyLister(fn AgentKeyLister) *Phase {
+	if fn != nil {
+		p.listAgentKeys = fn
+	}
+	return p
+}
+
 // WithUserEnsurer overrides the system user ensure function.
 func (p *Phase) WithUserEnsurer(fn UserEnsurer) *Phase {
 	if fn != nil {
@@ -68,11 +221,74 @@ func (p *Phase) WithUserEnsurer(fn UserEnsurer) *Phase {
 
 func (p *Phase) Metadata() phases.PhaseMetadata {
 	return phases.PhaseMetadata{
-		ID:          phaseID,
-		Title:       "Ensure Ansible User",
-		Description: fmt.Sprintf("Provision the %s user with passwordless sudo and SSH access.", p.username),
+		ID:                phaseID,
+		Title:             "Ensure Ansible User",
+		Requires:          []string{sudoensure.PhaseID},
+		Description:       fmt.Sprintf("Provision the %s user with passwordless sudo and SSH access.", p.username),
+		EstimatedDuration: 10 * time.Second,
 		Inputs: []phases.InputDefinition{
-			keyPathDefinition(),
+			keySourceDefinition(p.keySource),
+			keyPathDefinition(""),
+			agentKeyDefinition(nil),
+			{
+				ID:          InputSudoGroup,
+				Label:       "Sudo Group",
+				Description: "Admin group for the ansible user; leave empty to detect sudo or wheel on the target.",
+				Kind:        phases.InputKindText,
+			},
+			{
+				ID:          InputHomeBase,
+				Label:       "Home Base Directory",
+				Description: "Directory the ansible user's home is created in; leave empty to follow /etc/default/useradd.",
+				Kind:        phases.InputKindText,
+			},
+			keyWriteDefinition(),
+			{
+				ID:          InputSudoLogFile,
+				Label:       "sudo Log File",
+				Description: "Absolute path sudo logs the ansible user's commands to (Defaults logfile); leave empty for syslog only.",
+				Kind:        phases.InputKindText,
+			},
+			{
+				ID:          InputSudoIOLog,
+				Label:       "sudo I/O Logging",
+				Description: "Record the output of the ansible user's sudo sessions (Defaults log_output), replayable with sudoreplay.",
+				Kind:        phases.InputKindSelect,
+				Default:     "no",
+				Options: []phases.InputOption{
+					{Value: "no", Label: "Off"},
+					{Value: "yes", Label: "On"},
+				},
+			},
+			{
+				ID:          InputSudoCommands,
+				Label:       "sudo Commands",
+				Description: "Comma-separated absolute paths to allow without a password instead of ALL, e.g. /bin/sh,/usr/bin/apt-get; ansible's become needs /bin/sh. Leave empty for ALL.",
+				Kind:        phases.InputKindText,
+			},
+			{
+				ID:          InputExtraKeys,
+				Label:       "Extra Public Keys",
+				Description: "Local file of more public keys to authorize for the ansible user, one per line (e.g. the team's keys); leave empty for the generated key only.",
+				Kind:        phases.InputKindText,
+			},
+			{
+				ID:          InputPasswordHash,
+				Label:       "Password Hash",
+				Description: "crypt(3) hash to set as the ansible user's password, e.g. from `openssl passwd -6`; leave empty to leave the password alone.",
+				Kind:        phases.InputKindSecret,
+			},
+			{
+				ID:          InputLockPassword,
+				Label:       "Lock Password",
+				Description: "Lock the ansible user's password so only key logins work.",
+				Kind:        phases.InputKindSelect,
+				Default:     "no",
+				Options: []phases.InputOption{
+					{Value: "no", Label: "No"},
+					{Value: "yes", Label: "Yes"},
+				},
+			},
 		},
 	}
 }
@@ -89,96 +305,452 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 		p.ensureUser = systemuser.EnsureUser
 	}
 
-	keyPath, err := p.resolveKeyPath(phaseCtx)
+	key, err := p.resolveKey(phaseCtx)
 	if err != nil {
 		return err
 	}
+	publicKey := key.publicKey
+
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
+		return phases.ValidationError{Reason: "sudo phase must complete before creating ansible user"}
+	}
 
-	keyInfo, err := p.ensureKeyPair(keyPath)
+	runner := phases.NewTracedRunner(ctx, elevatedClient)
+	userOpts := []systemuser.Option{
+		systemuser.WithSudoAccess(),
+		systemuser.WithPasswordlessSudo(),
+	}
+	if group, _ := phases.GetInputString(phaseCtx, phaseID, InputSudoGroup); group != "" {
+		userOpts = append(userOpts, systemuser.WithSudoGroup(group))
+	}
+	if base, _ := phases.GetInputString(phaseCtx, phaseID, InputHomeBase); base != "" {
+		userOpts = append(userOpts, systemuser.WithBaseDir(base))
+	}
+	logFile, _ := phases.GetInputString(phaseCtx, phaseID, InputSudoLogFile)
+	if logFile != "" && !strings.HasPrefix(logFile, "/") {
+		return p.inputRequest(InputSudoLogFile, "sudo log file must be an absolute path")
+	}
+	ioLog, _, err := phases.GetInputBool(phaseCtx, phaseID, InputSudoIOLog)
 	if err != nil {
-		return err
+		return p.inputRequest(InputSudoIOLog, "sudo I/O logging must be yes or no")
+	}
+	if logFile != "" || ioLog {
+		userOpts = append(userOpts, systemuser.WithSudoLogging(systemuser.SudoLogging{LogFile: logFile, IOLog: ioLog}))
+	}
+	if list, _ := phases.GetInputString(phaseCtx, phaseID, InputSudoCommands); list != "" {
+		commands := strings.Split(list, ",")
+		for _, command := range commands {
+			if command = strings.TrimSpace(command); command != "" && !strings.HasPrefix(command, "/") {
+				return p.inputRequest(InputSudoCommands, fmt.Sprintf("sudo command %q must be an absolute path", command))
+			}
+		}
+		userOpts = append(userOpts, systemuser.WithSudoCommands(commands))
+	}
+	keyWrite, _ := phases.GetInputString(phaseCtx, phaseID, InputKeyWrite)
+	if keyWrite == KeyWriteUser {
+		userOpts = append(userOpts, systemuser.WithUserOwnedKeys())
+	}
+	lockPassword, _, err := phases.GetInputBool(phaseCtx, phaseID, InputLockPassword)
+	if err != nil {
+		return p.inputRequest(InputLockPassword, "lock password must be yes or no")
+	}
+	passwordHash, _ := phases.GetInputString(phaseCtx, phaseID, InputPasswordHash)
+	switch {
+	case passwordHash != "" && lockPassword:
+		return p.inputRequest(InputPasswordHash, "set a password hash or lock the password, not both")
+	case passwordHash != "":
+		userOpts = append(userOpts, systemuser.WithPasswordHash(passwordHash))
+	case lockPassword:
+		userOpts = append(userOpts, systemuser.WithLockedPassword())
+	}
+	if path, _ := phases.GetInputPath(phaseCtx, phaseID, InputExtraKeys); path != "" {
+		extra, err := os.ReadFile(path)
+		if err != nil {
+			return p.inputRequest(InputExtraKeys, fmt.Sprintf("read extra public keys: %v", err))
+		}
+		userOpts = append(userOpts, systemuser.WithAuthorizedKeys(strings.Split(string(extra), "\n")...))
 	}
 
-	publicKeyBytes, err := os.ReadFile(keyInfo.PublicPath)
+	if p.checkMode {
+		return p.runCheck(ctx, phaseCtx, runner, key, userOpts)
+	}
+
+	result, err := p.ensureUser(runner, p.username, publicKey, userOpts...)
+	var nfsErr systemuser.NFSHomeError
+	if errors.As(err, &nfsErr) && keyWrite != KeyWriteUser {
+		return phases.InputRequestError{
+			PhaseID: phaseID,
+			Input:   keyWriteDefinition(),
+			Reason:  fmt.Sprintf("%s is on NFS and root cannot write to it (root_squash); write authorized_keys as the user instead", nfsErr.HomeDir),
+		}
+	}
 	if err != nil {
 		return err
 	}
-	publicKey := strings.TrimSpace(string(publicKeyBytes))
-	if publicKey == "" {
-		return phases.ValidationError{Reason: "public key content empty"}
+
+	// Verification needs the target address, which embedders running
+	// without sshconnect may not record.
+	if host := targetHost(phaseCtx); host != "" && result.PasswordlessConfigured {
+		if p.verifySudo == nil {
+			p.verifySudo = verifySudo
+		}
+		if err := p.verifySudo(ctx, host, targetPort(phaseCtx), result.Username, key.loginPath(), result.SudoCommands...); err != nil {
+			return err
+		}
+		phaseCtx.Set(ContextKeySudoVerified, true)
 	}
 
-	elevatedVal, ok := phaseCtx.Get(sudoensure.ContextKeyElevatedClient)
-	if !ok {
-		return phases.ValidationError{Reason: "sudo phase must complete before creating ansible user"}
+	key.record(phaseCtx)
+	phases.SetContext(phaseCtx, KeyUserResult, result)
+
+	return nil
+}
+
+// Undo removes the ansible user, its home, and its sudoers drop-in, but only
+// when this phase created the user; a user that already existed is left
+// alone. The local key pair is kept.
+func (p *Phase) Undo(ctx context.Context, phaseCtx *phases.Context) error {
+	result, ok := phases.GetContext(phaseCtx, KeyUserResult)
+	if !ok || result == nil || !result.UserCreated {
+		return nil
 	}
-	elevatedClient, ok := elevatedVal.(*privilege.ElevatedClient)
+
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
 	if !ok || elevatedClient == nil {
-		return phases.ValidationError{Reason: "invalid elevated client in context"}
+		return phases.ValidationError{Reason: "elevated client required to remove the ansible user"}
+	}
+	if p.removeUser == nil {
+		p.removeUser = systemuser.RemoveUser
+	}
+	if err := p.removeUser(phases.NewTracedRunner(ctx, elevatedClient), result.Username); err != nil {
+		return err
 	}
 
-	runner := &sudoRunner{client: elevatedClient}
+	phaseCtx.Delete(ContextKeyUserResult)
+	phaseCtx.Delete(ContextKeySudoVerified)
+	return nil
+}
 
-	result, err := p.ensureUser(
-		runner,
-		p.username,
-		publicKey,
-		systemuser.WithSudoAccess(),
-		systemuser.WithPasswordlessSudo(),
-	)
+// runCheck plans the user and reports each file it would write as a task
+// whose message is the unified diff.
+func (p *Phase) runCheck(ctx context.Context, phaseCtx *phases.Context, runner systemuser.Runner, key ansibleKey, opts []systemuser.Option) error {
+	if p.planUser == nil {
+		p.planUser = systemuser.PlanUser
+	}
+	plan, err := p.planUser(runner, p.username, key.publicKey, opts...)
 	if err != nil {
 		return err
 	}
 
-	phaseCtx.Set(ContextKeyKeyInfo, keyInfo)
-	phaseCtx.Set(ContextKeyUserResult, result)
+	host := targetHost(phaseCtx)
+	if !plan.UserExists {
+		phases.ReportTask(ctx, phases.TaskEvent{Task: "create user " + plan.Username, Host: host, Status: phases.TaskChanged})
+	}
+	for _, file := range plan.Files {
+		status := phases.TaskOK
+		if file.Changed() {
+			status = phases.TaskChanged
+		}
+		phases.ReportTask(ctx, phases.TaskEvent{Task: file.Path, Host: host, Status: status, Message: file.Diff()})
+	}
 
+	key.record(phaseCtx)
+	phaseCtx.Set(ContextKeyUserPlan, plan)
 	return nil
 }
 
+// ansibleKey is the key installed for the ansible user: a local key pair, or
+// an identity held by the ssh-agent.
+type ansibleKey struct {
+	// info is the local key pair; nil for agent identities.
+	info *sshkeypair.KeyPairInfo
+	// agentFingerprint identifies the agent identity.
+	agentFingerprint string
+	publicKey        string
+	// fingerprints is nil when the public key could not be parsed.
+	fingerprints *sshkeypair.Fingerprints
+}
+
+// loginPath is the key verifySudo logs in with.
+func (k ansibleKey) loginPath() string {
+	if k.info == nil {
+		return agentKeyPrefix + k.agentFingerprint
+	}
+	return k.info.PrivatePath
+}
+
+// record publishes the key and its fingerprints, so operators can file them,
+// e.g. in a CMDB.
+func (k ansibleKey) record(phaseCtx *phases.Context) {
+	if k.info != nil {
+		phases.SetContext(phaseCtx, KeyKeyPair, k.info)
+	} else {
+		phaseCtx.Set(ContextKeyAgentKey, k.agentFingerprint)
+	}
+	if k.fingerprints != nil {
+		phaseCtx.Set(ContextKeyKeyFingerprint, k.fingerprints.SHA256)
+		phaseCtx.Set(ContextKeyKeyFingerprintMD5, k.fingerprints.MD5)
+	}
+}
+
+// resolveKey finds the public key to install according to InputKeySource.
+func (p *Phase) resolveKey(phaseCtx *phases.Context) (ansibleKey, error) {
+	source, _ := phases.GetInputString(phaseCtx, phaseID, InputKeySource)
+	if source == "" {
+		source = p.keySource
+	}
+	switch source {
+	case "", KeySourceFile:
+		return p.fileKey(phaseCtx)
+	case KeySourceAgent:
+		return p.agentKey(phaseCtx)
+	default:
+		return ansibleKey{}, p.inputRequest(InputKeySource, fmt.Sprintf("unknown key source %q", source))
+	}
+}
+
+// fileKey generates or reuses the key pair at InputKeyPath. A public key
+// that cannot be parsed leaves the fingerprints unset rather than failing.
+func (p *Phase) fileKey(phaseCtx *phases.Context) (ansibleKey, error) {
+	keyPath, err := p.resolveKeyPath(phaseCtx)
+	if err != nil {
+		return ansibleKey{}, err
+	}
+
+	keyInfo, err := p.ensureKeyPair(keyPath, p.keyPairOpts...)
+	if err != nil {
+		return ansibleKey{}, err
+	}
+
+	publicKeyBytes, err := os.ReadFile(keyInfo.PublicPath)
+	if err != nil {
+		return ansibleKey{}, err
+	}
+	key := ansibleKey{info: keyInfo, publicKey: strings.TrimSpace(string(publicKeyBytes))}
+	if key.publicKey == "" {
+		return ansibleKey{}, phases.ValidationError{Reason: "public key content empty"}
+	}
+	if fingerprints, err := sshkeypair.Fingerprint(keyInfo.PublicPath); err == nil {
+		key.fingerprints = &fingerprints
+	}
+	return key, nil
+}
+
+// agentKey installs the public half of an ssh-agent identity, e.g. a key on
+// a hardware token, so no private key file is written. The operator picks
+// the identity from the ones the agent lists.
+func (p *Phase) agentKey(phaseCtx *phases.Context) (ansibleKey, error) {
+	if p.listAgentKeys == nil {
+		p.listAgentKeys = listAgentKeys
+	}
+	identities, err := p.listAgentKeys()
+	if err != nil {
+		return ansibleKey{}, p.inputRequest(InputKeySource, fmt.Sprintf("list ssh-agent identities: %v", err))
+	}
+	if len(identities) == 0 {
+		return ansibleKey{}, p.inputRequest(InputKeySource, "the ssh-agent holds no identities; load one with ssh-add or use a key file")
+	}
+
+	fingerprint, _ := phases.GetInputString(phaseCtx, phaseID, InputAgentKey)
+	if fingerprint == "" {
+		return ansibleKey{}, phases.InputRequestError{
+			PhaseID: phaseID,
+			Input:   agentKeyDefinition(identities),
+			Reason:  "choose the ssh-agent identity to install for the ansible user",
+		}
+	}
+	for _, identity := range identities {
+		if ssh.FingerprintSHA256(identity) != fingerprint {
+			continue
+		}
+		line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(identity)))
+		if identity.Comment != "" {
+			line += " " + identity.Comment
+		}
+		fingerprints := sshkeypair.FingerprintKey(identity)
+		return ansibleKey{agentFingerprint: fingerprint, publicKey: line, fingerprints: &fingerprints}, nil
+	}
+	return ansibleKey{}, phases.InputRequestError{
+		PhaseID: phaseID,
+		Input:   agentKeyDefinition(identities),
+		Reason:  fmt.Sprintf("ssh-agent identity %s is not loaded", fingerprint),
+	}
+}
+
+func listAgentKeys() ([]*agent.Key, error) {
+	return sshconnection.AgentKeys(os.Getenv("SSH_AUTH_SOCK"))
+}
+
 func (p *Phase) resolveKeyPath(ctx *phases.Context) (string, error) {
-	val, ok := phases.GetInput(ctx, phaseID, InputKeyPath)
+	host := targetHost(ctx)
+	path, ok := phases.GetInputPath(ctx, phaseID, InputKeyPath)
 	if !ok {
 		return "", phases.InputRequestError{
 			PhaseID: phaseID,
-			Input:   keyPathDefinition(),
-			Reason:  fmt.Sprintf("key path required to create ansible SSH key pair (default %s)", defaultKeyPath()),
+			Input:   keyPathDefinition(host),
+			Reason:  fmt.Sprintf("key path required to create ansible SSH key pair (default %s)", defaultKeyPath(host)),
 		}
 	}
-	path := strings.TrimSpace(fmt.Sprint(val))
 	if path == "" {
 		return "", phases.InputRequestError{
 			PhaseID: phaseID,
-			Input:   keyPathDefinition(),
+			Input:   keyPathDefinition(host),
 			Reason:  "key path cannot be empty",
 		}
 	}
 	return path, nil
 }
 
-func keyPathDefinition() phases.InputDefinition {
+// keyPathDefinition describes the key path input, defaulting to a per-host
+// key when the target host is known so each host gets its own key pair.
+func keyPathDefinition(host string) phases.InputDefinition {
 	return phases.InputDefinition{
 		ID:          InputKeyPath,
 		Label:       "Ansible SSH Key Path",
-		Description: "Local path for the ansible user's SSH private key (e.g., ~/.ssh/ansible_id).",
+		Description: "Local path for the ansible user's SSH private key (defaults to ~/.ssh/ansible_<host>).",
 		Kind:        phases.InputKindText,
 		Required:    true,
-		Default:     defaultKeyPath(),
+		Default:     defaultKeyPath(host),
 	}
 }
 
-func defaultKeyPath() string {
-	home, err := os.UserHomeDir()
-	if err != nil || home == "" {
-		return filepath.Join("~", ".ssh", defaultKeyName)
+// inputRequest asks again for one of the phase's inputs.
+func (p *Phase) inputRequest(inputID, reason string) phases.InputRequestError {
+	req := phases.InputRequestError{PhaseID: phaseID, Reason: reason}
+	for _, def := range p.Metadata().Inputs {
+		if def.ID == inputID {
+			req.Input = def
+		}
+	}
+	return req
+}
+
+func keySourceDefinition(source string) phases.InputDefinition {
+	if source == "" {
+		source = KeySourceFile
+	}
+	return phases.InputDefinition{
+		ID:          InputKeySource,
+		Label:       "Ansible Key Source",
+		Description: "Generate (or reuse) a key file for the ansible user, or install a key already loaded in your ssh-agent.",
+		Kind:        phases.InputKindSelect,
+		Default:     source,
+		Options: []phases.InputOption{
+			{Value: KeySourceFile, Label: "Key file"},
+			{Value: KeySourceAgent, Label: "ssh-agent identity", Description: "Install the public key of a loaded identity; no key file is written"},
+		},
+	}
+}
+
+// agentKeyDefinition describes the agent identity input, offering
+// identities when they are known.
+func agentKeyDefinition(identities []*agent.Key) phases.InputDefinition {
+	def := phases.InputDefinition{
+		ID:          InputAgentKey,
+		Label:       "ssh-agent Identity",
+		Description: "SHA256 fingerprint of the ssh-agent identity to install for the ansible user (with the ssh-agent key source).",
+		Kind:        phases.InputKindText,
+	}
+	if len(identities) == 0 {
+		return def
+	}
+	def.Kind = phases.InputKindSelect
+	def.Required = true
+	for _, identity := range identities {
+		fingerprint := ssh.FingerprintSHA256(identity)
+		label := fingerprint
+		if identity.Comment != "" {
+			label = identity.Comment
+		}
+		def.Options = append(def.Options, phases.InputOption{Value: fingerprint, Label: label, Description: identity.Type() + " " + fingerprint})
 	}
-	return filepath.Join(home, ".ssh", defaultKeyName)
+	def.Default = def.Options[0].Value
+	return def
 }
 
-type sudoRunner struct {
-	client *privilege.ElevatedClient
+func keyWriteDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputKeyWrite,
+		Label:       "authorized_keys Writer",
+		Description: "Who writes the ansible user's authorized_keys; pick the user for NFS homes exported with root_squash.",
+		Kind:        phases.InputKindSelect,
+		Default:     KeyWriteRoot,
+		Options: []phases.InputOption{
+			{Value: KeyWriteRoot, Label: "root"},
+			{Value: KeyWriteUser, Label: "The ansible user", Description: "Write ~/.ssh as the user via su"},
+		},
+	}
 }
 
-func (r *sudoRunner) Run(cmd string) (string, string, error) {
-	return r.client.Run(cmd)
+func targetHost(ctx *phases.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	val, ok := ctx.Get(sshconnect.ContextKeyTargetHost)
+	if !ok {
+		return ""
+	}
+	host, _ := val.(string)
+	return strings.TrimSpace(host)
+}
+
+func targetPort(ctx *phases.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	val, _ := ctx.Get(sshconnect.ContextKeyTargetPort)
+	port, _ := val.(int)
+	return port
+}
+
+// verifySudo opens a second SSH connection as the new user, so a broken
+// sudoers drop-in fails this phase instead of the first ansible run.
+func verifySudo(ctx context.Context, host string, port int, username, keyPath string, commands ...string) error {
+	cred := sshconnection.Credential{KeyPath: keyPath}
+	if fingerprint, ok := strings.CutPrefix(keyPath, agentKeyPrefix); ok {
+		cred = sshconnection.Credential{Agent: true, AgentFingerprint: fingerprint}
+	}
+	client, err := sshconnection.Connect(host, port, username, cred, sshconnection.WithMDNS())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	trace := "sudo -n true"
+	if len(commands) > 0 {
+		trace = "sudo -n -l " + strings.Join(commands, ", ")
+	}
+	finish := phases.TraceCommand(ctx, trace)
+	err = privilege.VerifyPasswordlessSudo(client, commands...)
+	finish(err)
+	return err
+}
+
+func defaultKeyPath(host string) string {
+	name := defaultKeyName
+	if slug := hostSlug(host); slug != "" {
+		name = "ansible_" + slug
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return filepath.Join("~", ".ssh", name)
+	}
+	return filepath.Join(home, ".ssh", name)
+}
+
+// hostSlug makes a host safe for use in a file name; IPv6 colons and any
+// other separators become underscores.
+func hostSlug(host string) string {
+	host = strings.Trim(strings.TrimSpace(host), "[]")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, host)
 }
@@ -2,13 +2,20 @@ package ansibleuser
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
 	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
 	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
 	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
@@ -68,6 +75,20 @@ func TestPhaseRequestsKeyPath(t *testing.T) {
 	require.Equal(t, phaseID, inputErr.PhaseID)
 }
 
+func TestPhaseDefaultsKeyPathToTargetHost(t *testing.T) {
+	t.Parallel()
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(sshconnect.ContextKeyTargetHost, "fe80::1")
+
+	err := New().Run(context.Background(), ctx)
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, err, &inputErr)
+	require.Equal(t, "ansible_fe80__1", filepath.Base(inputErr.Input.Default.(string)))
+	require.Contains(t, inputErr.Reason, "ansible_fe80__1")
+}
+
 func TestPhaseRequiresElevatedClient(t *testing.T) {
 	t.Parallel()
 
@@ -80,3 +101,481 @@ func TestPhaseRequiresElevatedClient(t *testing.T) {
 	var valErr phases.ValidationError
 	require.ErrorAs(t, err, &valErr)
 }
+
+func TestPhaseCheckModeReportsDiffsWithoutApplying(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	publicPath := privatePath + ".pub"
+	require.NoError(t, os.WriteFile(publicPath, []byte("ssh-ed25519 NEW ansible\n"), 0o600))
+
+	phase := New().WithCheckMode().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: publicPath}, nil
+		}).
+		WithUserEnsurer(func(systemuser.Runner, string, string, ...systemuser.Option) (*systemuser.Result, error) {
+			t.Fatal("check mode must not apply changes")
+			return nil, nil
+		}).
+		WithUserPlanner(func(_ systemuser.Runner, username, publicKey string, _ ...systemuser.Option) (*systemuser.Plan, error) {
+			require.Equal(t, "ssh-ed25519 NEW ansible", publicKey)
+			return &systemuser.Plan{
+				Username:   username,
+				UserExists: true,
+				Files: []systemuser.FileChange{
+					{Path: "/home/ansible/.ssh/authorized_keys", Exists: true, Current: "ssh-rsa OLD\n", Planned: "ssh-ed25519 NEW ansible\n"},
+					{Path: "/etc/sudoers.d/ansible", Exists: true, Current: "ansible ALL=(ALL) NOPASSWD:ALL\n", Planned: "ansible ALL=(ALL) NOPASSWD:ALL\n"},
+				},
+			}, nil
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+
+	observer := &taskRecorder{}
+	manager := phases.NewManager(phases.WithObserver(observer))
+	require.NoError(t, manager.Register(phase))
+	require.NoError(t, manager.Run(context.Background(), ctx))
+
+	require.Len(t, observer.events, 2)
+	require.Equal(t, phases.TaskChanged, observer.events[0].Status)
+	require.Equal(t, "10.0.0.5", observer.events[0].Host)
+	require.Contains(t, observer.events[0].Message, "-ssh-rsa OLD\n+ssh-ed25519 NEW ansible\n")
+	require.Equal(t, phases.TaskEvent{Task: "/etc/sudoers.d/ansible", Host: "10.0.0.5", Status: phases.TaskOK}, observer.events[1])
+
+	_, ok := ctx.Get(ContextKeyUserResult)
+	require.False(t, ok)
+	plan, ok := ctx.Get(ContextKeyUserPlan)
+	require.True(t, ok)
+	require.True(t, plan.(*systemuser.Plan).Changed())
+}
+
+type taskRecorder struct {
+	events []phases.TaskEvent
+}
+
+func (r *taskRecorder) PhaseStarted(phases.PhaseMetadata)          {}
+func (r *taskRecorder) PhaseCompleted(phases.PhaseMetadata, error) {}
+
+func (r *taskRecorder) TaskProgress(_ phases.PhaseMetadata, event phases.TaskEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestPhaseVerifiesPasswordlessSudo(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+
+	tests := []struct {
+		name       string
+		verifyErr  error
+		configured bool
+		wantCalls  int
+	}{
+		{name: "verified", configured: true, wantCalls: 1},
+		{name: "sudo still prompts", configured: true, verifyErr: privilege.PasswordlessSudoError{Stderr: "sudo: a password is required"}, wantCalls: 1},
+		{name: "no sudoers drop-in", configured: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			phase := New().
+				WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+					return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+				}).
+				WithUserEnsurer(func(_ systemuser.Runner, username, _ string, _ ...systemuser.Option) (*systemuser.Result, error) {
+					return &systemuser.Result{Username: username, PasswordlessConfigured: tt.configured}, nil
+				}).
+				WithSudoVerifier(func(_ context.Context, host string, port int, username, keyPath string, _ ...string) error {
+					calls++
+					require.Equal(t, "10.0.0.5", host)
+					require.Equal(t, 2222, port)
+					require.Equal(t, defaultUsername, username)
+					require.Equal(t, privatePath, keyPath)
+					return tt.verifyErr
+				})
+
+			ctx := phases.NewContext()
+			ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+			ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+			ctx.Set(sshconnect.ContextKeyTargetPort, 2222)
+			phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+
+			err := phase.Run(context.Background(), ctx)
+			require.Equal(t, tt.wantCalls, calls)
+			verified, _ := ctx.Get(ContextKeySudoVerified)
+			if tt.verifyErr != nil {
+				require.ErrorAs(t, err, &privilege.PasswordlessSudoError{})
+				require.Nil(t, verified)
+				return
+			}
+			require.NoError(t, err)
+			if tt.configured {
+				require.Equal(t, true, verified)
+			}
+		})
+	}
+}
+
+func TestPhasePassesSudoGroupOverride(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+
+	var script string
+	phase := New().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+		}).
+		WithUserEnsurer(func(_ systemuser.Runner, username, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			r := &commandRecorder{}
+			res, err := systemuser.EnsureUser(r, username, publicKey, opts...)
+			script = strings.Join(r.cmds, "\n")
+			return res, err
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	phases.SetInput(ctx, phaseID, InputSudoGroup, "admins")
+
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, "group='admins'")
+	require.NotContains(t, script, "for candidate in")
+}
+
+func TestPhaseConfiguresSudoLogging(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+
+	var script string
+	phase := New().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+		}).
+		WithUserEnsurer(func(_ systemuser.Runner, username, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			r := &commandRecorder{}
+			res, err := systemuser.EnsureUser(r, username, publicKey, opts...)
+			script = strings.Join(r.cmds, "\n")
+			return res, err
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	phases.SetInput(ctx, phaseID, InputSudoLogFile, "sudo.log")
+
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputSudoLogFile, inputErr.Input.ID)
+
+	phases.SetInput(ctx, phaseID, InputSudoLogFile, "/var/log/sudo-ansible.log")
+	phases.SetInput(ctx, phaseID, InputSudoIOLog, "yes")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, `Defaults:ansible logfile="/var/log/sudo-ansible.log"`)
+	require.Contains(t, script, "Defaults:ansible log_output")
+}
+
+type commandRecorder struct {
+	cmds []string
+}
+
+func (r *commandRecorder) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	return "", "", nil
+}
+
+func TestPhaseOffersUserOwnedKeysOnNFS(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+
+	var script string
+	phase := New().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+		}).
+		WithUserEnsurer(func(_ systemuser.Runner, username, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			r := &commandRecorder{}
+			res, err := systemuser.EnsureUser(r, username, publicKey, opts...)
+			script = strings.Join(r.cmds, "\n")
+			if err == nil && !strings.Contains(script, "su -s /bin/sh") {
+				return nil, systemuser.NFSHomeError{HomeDir: "/net/home/ansible", Err: errors.New("exit status 1")}
+			}
+			return res, err
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	phases.SetInput(ctx, phaseID, InputHomeBase, "/net/home")
+
+	err := phase.Run(context.Background(), ctx)
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, err, &inputErr)
+	require.Equal(t, InputKeyWrite, inputErr.Input.ID)
+	require.Contains(t, inputErr.Reason, "/net/home/ansible")
+	require.Contains(t, script, "base='/net/home'")
+
+	phases.SetInput(ctx, phaseID, InputKeyWrite, KeyWriteUser)
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, "su -s /bin/sh 'ansible' -c")
+}
+
+func TestPhaseUndoRemovesCreatedUserOnly(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		result     *systemuser.Result
+		wantRemove bool
+	}{
+		{name: "created by the phase", result: &systemuser.Result{Username: "ansible", UserCreated: true}, wantRemove: true},
+		{name: "already existed", result: &systemuser.Result{Username: "ansible"}},
+		{name: "phase never ran"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var removed string
+			phase := New().WithUserRemover(func(_ systemuser.Runner, username string, _ ...systemuser.Option) error {
+				removed = username
+				return nil
+			})
+			ctx := phases.NewContext()
+			ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+			if tt.result != nil {
+				ctx.Set(ContextKeyUserResult, tt.result)
+			}
+
+			require.NoError(t, phase.Undo(context.Background(), ctx))
+			_, stillSet := ctx.Get(ContextKeyUserResult)
+			if tt.wantRemove {
+				require.Equal(t, "ansible", removed)
+				require.False(t, stillSet)
+				return
+			}
+			require.Empty(t, removed)
+			require.Equal(t, tt.result != nil, stillSet)
+		})
+	}
+}
+
+func TestPhaseAuthorizesExtraKeys(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+	teamPath := filepath.Join(tempDir, "team.pub")
+
+	var script string
+	phase := New().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+		}).
+		WithUserEnsurer(func(_ systemuser.Runner, username, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			r := &commandRecorder{}
+			res, err := systemuser.EnsureUser(r, username, publicKey, opts...)
+			script = strings.Join(r.cmds, "\n")
+			return res, err
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	phases.SetInput(ctx, phaseID, InputExtraKeys, teamPath)
+
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputExtraKeys, inputErr.Input.ID)
+
+	require.NoError(t, os.WriteFile(teamPath, []byte("# ops\nssh-ed25519 BBB alice\n\nssh-ed25519 CCC bob\n"), 0o600))
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, "ssh-rsa AAA ansible")
+	require.Contains(t, script, "ssh-ed25519 BBB alice")
+	require.Contains(t, script, "ssh-ed25519 CCC bob")
+}
+
+func TestPhaseLocksOrSetsPassword(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+
+	var script string
+	phase := New().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+		}).
+		WithUserEnsurer(func(_ systemuser.Runner, username, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			r := &commandRecorder{}
+			res, err := systemuser.EnsureUser(r, username, publicKey, opts...)
+			script = strings.Join(r.cmds, "\n")
+			return res, err
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	phases.SetInput(ctx, phaseID, InputLockPassword, "yes")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, "usermod -p '*' 'ansible'")
+
+	phases.SetInput(ctx, phaseID, InputPasswordHash, "$6$salt$hash")
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputPasswordHash, inputErr.Input.ID)
+
+	phases.SetInput(ctx, phaseID, InputLockPassword, "no")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, "usermod -p '$6$salt$hash' 'ansible'")
+}
+
+func TestPhaseRestrictsSudoCommands(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	privatePath := filepath.Join(tempDir, "id_ansible")
+	require.NoError(t, os.WriteFile(privatePath+".pub", []byte("ssh-rsa AAA ansible\n"), 0o600))
+
+	var script string
+	var verified []string
+	phase := New().
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			return &sshkeypair.KeyPairInfo{PrivatePath: privatePath, PublicPath: privatePath + ".pub"}, nil
+		}).
+		WithUserEnsurer(func(_ systemuser.Runner, username, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			r := &commandRecorder{}
+			res, err := systemuser.EnsureUser(r, username, publicKey, opts...)
+			script = strings.Join(r.cmds, "\n")
+			return res, err
+		}).
+		WithSudoVerifier(func(_ context.Context, _ string, _ int, _, _ string, commands ...string) error {
+			verified = commands
+			return nil
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	phases.SetInput(ctx, phaseID, InputSudoCommands, "/bin/sh, apt-get")
+
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputSudoCommands, inputErr.Input.ID)
+
+	phases.SetInput(ctx, phaseID, InputSudoCommands, "/bin/sh, /usr/bin/apt-get")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, script, "ansible ALL=(ALL) NOPASSWD: /bin/sh, /usr/bin/apt-get\n")
+	require.Equal(t, []string{"/bin/sh", "/usr/bin/apt-get"}, verified)
+}
+
+func TestPhaseRecordsKeyFingerprints(t *testing.T) {
+	t.Parallel()
+
+	privatePath := filepath.Join(t.TempDir(), "id_ansible")
+	info, err := sshkeypair.EnsureKeyPair(privatePath, sshkeypair.WithKeyType(sshkeypair.KeyTypeEd25519))
+	require.NoError(t, err)
+	want, err := sshkeypair.Fingerprint(info.PublicPath)
+	require.NoError(t, err)
+
+	phase := New().
+		WithUserEnsurer(func(r systemuser.Runner, username string, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			return &systemuser.Result{Username: username}, nil
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeyPath, privatePath)
+	require.NoError(t, phase.Run(context.Background(), ctx))
+
+	sha, _ := ctx.Get(ContextKeyKeyFingerprint)
+	md5, _ := ctx.Get(ContextKeyKeyFingerprintMD5)
+	require.Equal(t, want.SHA256, sha)
+	require.Equal(t, want.MD5, md5)
+}
+
+func TestPhaseInstallsAgentIdentity(t *testing.T) {
+	t.Parallel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv, Comment: "yubikey"}))
+	identities, err := keyring.List()
+	require.NoError(t, err)
+	fingerprint := ssh.FingerprintSHA256(identities[0])
+
+	var installed, verifiedWith string
+	phase := New().
+		WithKeySource(KeySourceAgent).
+		WithAgentKeyLister(keyring.List).
+		WithKeyPairEnsurer(func(string, ...sshkeypair.Option) (*sshkeypair.KeyPairInfo, error) {
+			t.Fatal("agent identities need no key pair")
+			return nil, nil
+		}).
+		WithUserEnsurer(func(r systemuser.Runner, username string, publicKey string, opts ...systemuser.Option) (*systemuser.Result, error) {
+			installed = publicKey
+			return &systemuser.Result{Username: username, PasswordlessConfigured: true}, nil
+		}).
+		WithSudoVerifier(func(_ context.Context, _ string, _ int, _, keyPath string, _ ...string) error {
+			verifiedWith = keyPath
+			return nil
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+
+	err = phase.Run(context.Background(), ctx)
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, err, &inputErr)
+	require.Equal(t, InputAgentKey, inputErr.Input.ID)
+	require.Equal(t, phases.InputKindSelect, inputErr.Input.Kind)
+	require.Equal(t, []phases.InputOption{{Value: fingerprint, Label: "yubikey", Description: "ssh-ed25519 " + fingerprint}}, inputErr.Input.Options)
+
+	phases.SetInput(ctx, phaseID, InputAgentKey, fingerprint)
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(identities[0])))+" yubikey", installed)
+	require.Equal(t, "agent:"+fingerprint, verifiedWith)
+
+	agentKey, _ := ctx.Get(ContextKeyAgentKey)
+	require.Equal(t, fingerprint, agentKey)
+	sha, _ := ctx.Get(ContextKeyKeyFingerprint)
+	require.Equal(t, fingerprint, sha)
+	_, ok := ctx.Get(ContextKeyKeyInfo)
+	require.False(t, ok)
+}
+
+func TestPhaseAsksForKeySourceWhenAgentIsEmpty(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithAgentKeyLister(func() ([]*agent.Key, error) { return nil, nil })
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputKeySource, KeySourceAgent)
+
+	err := phase.Run(context.Background(), ctx)
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, err, &inputErr)
+	require.Equal(t, InputKeySource, inputErr.Input.ID)
+	require.Contains(t, inputErr.Reason, "ssh-add")
+}
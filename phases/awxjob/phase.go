@@ -0,0 +1,253 @@
+package awxjob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/hostvars"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/awx"
+)
+
+const (
+	defaultPhaseID = "awx_job"
+	// defaultEstimatedDuration weights the phase in pipeline progress when
+	// Config.EstimatedDuration is zero.
+	defaultEstimatedDuration = 5 * time.Minute
+
+	// Input identifiers.
+	InputURL           = "url"
+	InputToken         = "token"
+	InputInventoryID   = "inventory_id"
+	InputJobTemplateID = "job_template_id"
+
+	// Context keys
+	ContextKeyHostID = "awx:host_id"
+	ContextKeyJobID  = "awx:job_id"
+)
+
+// localOnlyVars are host variables that only make sense on this machine and
+// are not copied into the AWX inventory.
+var localOnlyVars = map[string]bool{"ansible_ssh_private_key_file": true}
+
+// Config describes an AWX job phase. Anything left zero is requested as input.
+type Config struct {
+	ID            string
+	Title         string
+	Description   string
+	URL           string
+	Token         string
+	InventoryID   int
+	JobTemplateID int
+	ExtraVars     map[string]any
+	Tags          []string
+	// Stdout receives the job output as it is produced (default: discarded).
+	Stdout        io.Writer
+	ClientOptions []awx.Option
+	// EstimatedDuration is roughly how long the job takes, which weights
+	// the phase in pipeline progress (default five minutes).
+	EstimatedDuration time.Duration
+}
+
+// Phase registers the prepared host in an AWX inventory, launches a job
+// template limited to it, and waits for the job to finish.
+type Phase struct {
+	meta phases.PhaseMetadata
+	cfg  Config
+}
+
+// New constructs an AWX job phase from cfg.
+func New(cfg Config) *Phase {
+	id := strings.TrimSpace(cfg.ID)
+	if id == "" {
+		id = defaultPhaseID
+	}
+	title := strings.TrimSpace(cfg.Title)
+	if title == "" {
+		title = "Run AWX Job Template"
+	}
+	desc := strings.TrimSpace(cfg.Description)
+	if desc == "" {
+		desc = "Add the host to an AWX inventory and run a job template against it."
+	}
+
+	cfg.URL = strings.TrimSpace(cfg.URL)
+	cfg.Token = strings.TrimSpace(cfg.Token)
+	cfg.ClientOptions = append([]awx.Option{}, cfg.ClientOptions...)
+
+	var inputs []phases.InputDefinition
+	for _, def := range inputDefinitions() {
+		if !cfg.provides(def.ID) {
+			inputs = append(inputs, def)
+		}
+	}
+
+	estimate := cfg.EstimatedDuration
+	if estimate <= 0 {
+		estimate = defaultEstimatedDuration
+	}
+
+	return &Phase{
+		meta: phases.PhaseMetadata{
+			ID:                id,
+			Title:             title,
+			Description:       desc,
+			Inputs:            inputs,
+			Tags:              append([]string{}, cfg.Tags...),
+			EstimatedDuration: estimate,
+		},
+		cfg: cfg,
+	}
+}
+
+// Metadata returns the configured phase metadata.
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return p.meta
+}
+
+// Run registers the host, launches the job template, and streams its output
+// until the job finishes.
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+
+	val, _ := phaseCtx.Get(sshconnect.ContextKeyTargetHost)
+	host, _ := val.(string)
+	if host = strings.TrimSpace(host); host == "" {
+		return phases.ValidationError{Reason: "SSH connection phase must complete before launching an AWX job"}
+	}
+
+	baseURL, err := p.resolveString(phaseCtx, InputURL, p.cfg.URL, "AWX URL is required")
+	if err != nil {
+		return err
+	}
+	token, err := p.resolveString(phaseCtx, InputToken, p.cfg.Token, "AWX API token is required")
+	if err != nil {
+		return err
+	}
+	inventoryID, err := p.resolveID(phaseCtx, InputInventoryID, p.cfg.InventoryID, "AWX inventory ID is required")
+	if err != nil {
+		return err
+	}
+	templateID, err := p.resolveID(phaseCtx, InputJobTemplateID, p.cfg.JobTemplateID, "AWX job template ID is required")
+	if err != nil {
+		return err
+	}
+
+	client := awx.New(baseURL, token, p.cfg.ClientOptions...)
+	hostID, err := client.EnsureHost(ctx, inventoryID, host, inventoryVars(phaseCtx))
+	if err != nil {
+		return fmt.Errorf("awx phase: register host: %w", err)
+	}
+	phaseCtx.Set(ContextKeyHostID, hostID)
+
+	jobID, err := client.Launch(ctx, templateID, awx.LaunchRequest{Limit: host, ExtraVars: p.cfg.ExtraVars})
+	if err != nil {
+		return fmt.Errorf("awx phase: launch job template %d: %w", templateID, err)
+	}
+	phaseCtx.Set(ContextKeyJobID, jobID)
+
+	if _, err := client.Wait(ctx, jobID, p.cfg.Stdout); err != nil {
+		return fmt.Errorf("awx phase: %w", err)
+	}
+	return nil
+}
+
+// inventoryVars converts the collected host variables for the AWX inventory.
+func inventoryVars(phaseCtx *phases.Context) map[string]any {
+	vars := make(map[string]any)
+	for _, v := range hostvars.Collect(phaseCtx) {
+		if !localOnlyVars[v.Name] {
+			vars[v.Name] = v.Value
+		}
+	}
+	return vars
+}
+
+func (p *Phase) resolveString(phaseCtx *phases.Context, inputID, configured, reason string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	if val, ok := phases.GetInputString(phaseCtx, p.meta.ID, inputID); ok && val != "" {
+		return val, nil
+	}
+	return "", p.inputRequestError(inputID, reason)
+}
+
+func (p *Phase) resolveID(phaseCtx *phases.Context, inputID string, configured int, reason string) (int, error) {
+	if configured > 0 {
+		return configured, nil
+	}
+	id, ok, err := phases.GetInputInt(phaseCtx, p.meta.ID, inputID)
+	switch {
+	case err != nil || (ok && id <= 0):
+		return 0, p.inputRequestError(inputID, "must be a positive number")
+	case !ok:
+		return 0, p.inputRequestError(inputID, reason)
+	}
+	return id, nil
+}
+
+func (p *Phase) inputRequestError(inputID, reason string) phases.InputRequestError {
+	var input phases.InputDefinition
+	for _, def := range inputDefinitions() {
+		if def.ID == inputID {
+			input = def
+		}
+	}
+	return phases.InputRequestError{PhaseID: p.meta.ID, Input: input, Reason: reason}
+}
+
+func (cfg Config) provides(inputID string) bool {
+	switch inputID {
+	case InputURL:
+		return cfg.URL != ""
+	case InputToken:
+		return cfg.Token != ""
+	case InputInventoryID:
+		return cfg.InventoryID > 0
+	case InputJobTemplateID:
+		return cfg.JobTemplateID > 0
+	default:
+		return false
+	}
+}
+
+func inputDefinitions() []phases.InputDefinition {
+	return []phases.InputDefinition{
+		{
+			ID:          InputURL,
+			Label:       "AWX URL",
+			Description: "Base URL of the AWX / Automation Platform controller.",
+			Kind:        phases.InputKindText,
+			Required:    true,
+		},
+		{
+			ID:          InputToken,
+			Label:       "AWX Token",
+			Description: "OAuth2 token with permission to edit the inventory and launch the template.",
+			Kind:        phases.InputKindSecret,
+			Required:    true,
+			Secret:      true,
+		},
+		{
+			ID:          InputInventoryID,
+			Label:       "Inventory ID",
+			Description: "Numeric ID of the inventory the host is added to.",
+			Kind:        phases.InputKindText,
+			Required:    true,
+		},
+		{
+			ID:          InputJobTemplateID,
+			Label:       "Job Template ID",
+			Description: "Numeric ID of the job template to launch; it must prompt for limit on launch.",
+			Kind:        phases.InputKindText,
+			Required:    true,
+		},
+	}
+}
@@ -0,0 +1,99 @@
+package awxjob
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/awx"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+func TestRunRegistersHostAndLaunchesTemplate(t *testing.T) {
+	t.Parallel()
+
+	var hostVars map[string]any
+	var launch awx.LaunchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v2/inventories/3/hosts/":
+			_, _ = w.Write([]byte(`{"results": []}`))
+		case "POST /api/v2/inventories/3/hosts/":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.NoError(t, json.Unmarshal([]byte(body["variables"]), &hostVars))
+			_, _ = w.Write([]byte(`{"id": 11}`))
+		case "POST /api/v2/job_templates/5/launch/":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&launch))
+			_, _ = w.Write([]byte(`{"job": 99}`))
+		case "GET /api/v2/jobs/99/":
+			_, _ = w.Write([]byte(`{"id": 99, "status": "successful"}`))
+		case "GET /api/v2/jobs/99/stdout/":
+			_, _ = w.Write([]byte("ok: [10.0.0.5]\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	phaseCtx := phases.NewContext()
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	phaseCtx.Set(ansibleuser.ContextKeyUserResult, &systemuser.Result{Username: "ansible"})
+	phaseCtx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/home/me/.ssh/id_ansible"})
+
+	var stdout bytes.Buffer
+	phase := New(Config{
+		URL:           server.URL,
+		Token:         "tok",
+		InventoryID:   3,
+		JobTemplateID: 5,
+		ExtraVars:     map[string]any{"role": "web"},
+		Stdout:        &stdout,
+		ClientOptions: []awx.Option{awx.WithPollInterval(time.Millisecond)},
+	})
+	require.Empty(t, phase.Metadata().Inputs)
+	require.NoError(t, phase.Run(context.Background(), phaseCtx))
+
+	require.Equal(t, "ansible", hostVars["ansible_user"])
+	require.NotContains(t, hostVars, "ansible_ssh_private_key_file")
+	require.Equal(t, "10.0.0.5", launch.Limit)
+	require.Equal(t, "web", launch.ExtraVars["role"])
+	require.Equal(t, "ok: [10.0.0.5]\n", stdout.String())
+
+	jobID, _ := phaseCtx.Get(ContextKeyJobID)
+	require.Equal(t, 99, jobID)
+	hostID, _ := phaseCtx.Get(ContextKeyHostID)
+	require.Equal(t, 11, hostID)
+}
+
+func TestRunRequestsMissingSettings(t *testing.T) {
+	t.Parallel()
+
+	phaseCtx := phases.NewContext()
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	phase := New(Config{URL: "https://awx.example.com", InventoryID: 3})
+	require.Len(t, phase.Metadata().Inputs, 2)
+
+	err := phase.Run(context.Background(), phaseCtx)
+	var reqErr phases.InputRequestError
+	require.ErrorAs(t, err, &reqErr)
+	require.Equal(t, InputToken, reqErr.Input.ID)
+	require.True(t, reqErr.Input.Secret)
+
+	phases.SetInput(phaseCtx, phase.Metadata().ID, InputToken, "tok")
+	phases.SetInput(phaseCtx, phase.Metadata().ID, InputJobTemplateID, "zero")
+	err = phase.Run(context.Background(), phaseCtx)
+	require.ErrorAs(t, err, &reqErr)
+	require.Equal(t, InputJobTemplateID, reqErr.Input.ID)
+	require.Contains(t, reqErr.Reason, "positive number")
+}
@@ -0,0 +1,131 @@
+package bootstrapcleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/authkeys"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+const (
+	phaseID = "bootstrap_cleanup"
+
+	// ContextKeyRevoked records which bootstrap credential was revoked:
+	// RevokedPassword or RevokedKey.
+	ContextKeyRevoked = "bootstrap:revoked"
+
+	// RevokedPassword means the bootstrap user's password was disabled.
+	RevokedPassword = "password"
+	// RevokedKey means the bootstrap key was removed from the bootstrap
+	// user's authorized_keys.
+	RevokedKey = "key"
+)
+
+// PasswordDisabler wraps systemuser.DisablePassword.
+type PasswordDisabler func(r systemuser.Runner, username string) error
+
+// KeyRemover wraps authkeys.Remove.
+type KeyRemover func(r authkeys.Runner, user, fingerprint string) (int, error)
+
+// Phase revokes the credential used for the initial SSH login once the
+// ansible user has been verified, so the weaker bootstrap access does not
+// outlive the run.
+type Phase struct {
+	disablePassword PasswordDisabler
+	removeKey       KeyRemover
+}
+
+// New creates a bootstrap credential cleanup phase.
+func New() *Phase {
+	return &Phase{
+		disablePassword: systemuser.DisablePassword,
+		removeKey:       authkeys.Remove,
+	}
+}
+
+// WithPasswordDisabler overrides how the bootstrap password is disabled (for
+// tests).
+func (p *Phase) WithPasswordDisabler(fn PasswordDisabler) *Phase {
+	if fn != nil {
+		p.disablePassword = fn
+	}
+	return p
+}
+
+// WithKeyRemover overrides how the bootstrap key is removed (for tests).
+func (p *Phase) WithKeyRemover(fn KeyRemover) *Phase {
+	if fn != nil {
+		p.removeKey = fn
+	}
+	return p
+}
+
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{
+		ID:                phaseID,
+		Title:             "Revoke Bootstrap Credentials",
+		Requires:          []string{ansibleuser.PhaseID},
+		Description:       "Disable the bootstrap user's password, or remove its key, after the ansible user is verified.",
+		EstimatedDuration: 5 * time.Second,
+	}
+}
+
+// Run disables the bootstrap user's password when the session logged in with
+// one, or removes the login key from its authorized_keys otherwise. It
+// refuses to run unless ansible_user verified that the new user can log in
+// and sudo, so the host is never left without a way in.
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+
+	if verified, _ := phaseCtx.Get(ansibleuser.ContextKeySudoVerified); verified != true {
+		return phases.ValidationError{Reason: "the ansible user must be verified before bootstrap credentials are revoked"}
+	}
+
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
+		return phases.ValidationError{Reason: "sudo phase must complete before revoking bootstrap credentials"}
+	}
+	userVal, _ := phaseCtx.Get(sshconnect.ContextKeyTargetUser)
+	user, _ := userVal.(string)
+	if user == "" {
+		return phases.ValidationError{Reason: "bootstrap user unknown; run the SSH phase first"}
+	}
+
+	runner := phases.NewTracedRunner(ctx, elevatedClient)
+	if fingerprintVal, ok := phaseCtx.Get(sshconnect.ContextKeyKeyFingerprint); ok {
+		fingerprint, _ := fingerprintVal.(string)
+		if p.removeKey == nil {
+			p.removeKey = authkeys.Remove
+		}
+		removed, err := p.removeKey(runner, user, fingerprint)
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return fmt.Errorf("bootstrap key %s not found in %s's authorized_keys", fingerprint, user)
+		}
+		phaseCtx.Set(ContextKeyRevoked, RevokedKey)
+		return nil
+	}
+
+	if _, ok := phaseCtx.Get(sshconnect.ContextKeySSHPassword); ok {
+		if p.disablePassword == nil {
+			p.disablePassword = systemuser.DisablePassword
+		}
+		if err := p.disablePassword(runner, user); err != nil {
+			return err
+		}
+		phaseCtx.Set(ContextKeyRevoked, RevokedPassword)
+		return nil
+	}
+
+	return phases.ValidationError{Reason: "cannot tell which bootstrap credential was used; choose a key path rather than the whole agent"}
+}
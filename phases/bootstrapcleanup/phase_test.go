@@ -0,0 +1,112 @@
+package bootstrapcleanup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/authkeys"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+func verifiedContext() *phases.Context {
+	ctx := phases.NewContext()
+	ctx.Set(ansibleuser.ContextKeySudoVerified, true)
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(sshconnect.ContextKeyTargetUser, "pi")
+	return ctx
+}
+
+func TestPhaseRevokesBootstrapCredential(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		setup       func(*phases.Context)
+		removed     int
+		wantRevoked string
+		wantErr     bool
+	}{
+		{
+			name:        "password login",
+			setup:       func(ctx *phases.Context) { ctx.Set(sshconnect.ContextKeySSHPassword, "raspberry") },
+			wantRevoked: RevokedPassword,
+		},
+		{
+			name:        "key login",
+			setup:       func(ctx *phases.Context) { ctx.Set(sshconnect.ContextKeyKeyFingerprint, "SHA256:boot") },
+			removed:     1,
+			wantRevoked: RevokedKey,
+		},
+		{
+			name:    "key already gone",
+			setup:   func(ctx *phases.Context) { ctx.Set(sshconnect.ContextKeyKeyFingerprint, "SHA256:boot") },
+			wantErr: true,
+		},
+		{
+			name:    "unknown credential",
+			setup:   func(*phases.Context) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var disabled, keyUser, keyFingerprint string
+			phase := New().
+				WithPasswordDisabler(func(_ systemuser.Runner, username string) error {
+					disabled = username
+					return nil
+				}).
+				WithKeyRemover(func(_ authkeys.Runner, user, fingerprint string) (int, error) {
+					keyUser, keyFingerprint = user, fingerprint
+					return tt.removed, nil
+				})
+			ctx := verifiedContext()
+			tt.setup(ctx)
+
+			err := phase.Run(context.Background(), ctx)
+			revoked, _ := ctx.Get(ContextKeyRevoked)
+			if tt.wantErr {
+				require.Error(t, err)
+				require.Nil(t, revoked)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRevoked, revoked)
+			if tt.wantRevoked == RevokedPassword {
+				require.Equal(t, "pi", disabled)
+				require.Empty(t, keyUser)
+				return
+			}
+			require.Empty(t, disabled)
+			require.Equal(t, "pi", keyUser)
+			require.Equal(t, "SHA256:boot", keyFingerprint)
+		})
+	}
+}
+
+func TestPhaseRequiresVerifiedAnsibleUser(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	phase := New().WithPasswordDisabler(func(systemuser.Runner, string) error {
+		called = true
+		return nil
+	})
+	ctx := verifiedContext()
+	ctx.Set(ansibleuser.ContextKeySudoVerified, false)
+	ctx.Set(sshconnect.ContextKeySSHPassword, "raspberry")
+
+	err := phase.Run(context.Background(), ctx)
+	var valErr phases.ValidationError
+	require.ErrorAs(t, err, &valErr)
+	require.False(t, called)
+}
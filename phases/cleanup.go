@@ -0,0 +1,78 @@
+package phases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// CleanupError reports cleanup functions registered with AddCleanup that
+// failed after a phase otherwise succeeded.
+type CleanupError struct {
+	Err error
+}
+
+func (e CleanupError) Error() string {
+	return fmt.Sprintf("phase cleanup failed: %v", e.Err)
+}
+
+func (e CleanupError) Unwrap() error {
+	return e.Err
+}
+
+type cleanupKey struct{}
+
+type cleanupScope struct {
+	mu  sync.Mutex
+	fns []func() error
+}
+
+// AddCleanup registers fn to run when the current phase's Run returns,
+// whether it succeeded, failed, or asked for input; functions run in reverse
+// registration order. Use it for phase-scoped resources such as a remote
+// temporary directory. It reports false when ctx does not come from a
+// Manager, in which case the caller must clean up itself.
+func AddCleanup(ctx context.Context, fn func() error) bool {
+	if ctx == nil || fn == nil {
+		return false
+	}
+	scope, ok := ctx.Value(cleanupKey{}).(*cleanupScope)
+	if !ok {
+		return false
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	scope.fns = append(scope.fns, fn)
+	return true
+}
+
+func withCleanupScope(ctx context.Context) (context.Context, *cleanupScope) {
+	scope := &cleanupScope{}
+	return context.WithValue(ctx, cleanupKey{}, scope), scope
+}
+
+// run executes the registered functions and folds their errors into the
+// phase result: a phase's own error is kept first, and cleanup failures of
+// a successful phase become a CleanupError.
+func (s *cleanupScope) run(err error) error {
+	s.mu.Lock()
+	fns := s.fns
+	s.fns = nil
+	s.mu.Unlock()
+
+	var cleanupErrs []error
+	for i := len(fns) - 1; i >= 0; i-- {
+		if cleanupErr := fns[i](); cleanupErr != nil {
+			cleanupErrs = append(cleanupErrs, cleanupErr)
+		}
+	}
+	if len(cleanupErrs) == 0 {
+		return err
+	}
+	cleanupErr := CleanupError{Err: errors.Join(cleanupErrs...)}
+	if err == nil {
+		return cleanupErr
+	}
+	return errors.Join(err, cleanupErr)
+}
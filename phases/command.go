@@ -0,0 +1,52 @@
+package phases
+
+import "context"
+
+// CommandObserver is optionally implemented by observers that want to hear
+// about remote commands phases run. The returned function is called with the
+// command's result once it finishes.
+type CommandObserver interface {
+	CommandStarted(meta PhaseMetadata, cmd string) (finish func(err error))
+}
+
+type commandHookKey struct{}
+
+type commandHook func(cmd string) func(err error)
+
+// TraceCommand reports a remote command to the manager's command observers
+// and returns the function to call with its result. Runners wrapping remote
+// execution should call it around every command; it is a no-op when nothing
+// is listening.
+func TraceCommand(ctx context.Context, cmd string) (finish func(err error)) {
+	if ctx != nil {
+		if hook, ok := ctx.Value(commandHookKey{}).(commandHook); ok {
+			return hook(cmd)
+		}
+	}
+	return func(error) {}
+}
+
+func withCommandHook(ctx context.Context, meta PhaseMetadata, observers []Observer) context.Context {
+	var cmdObservers []CommandObserver
+	for _, obs := range observers {
+		if cmdObs, ok := obs.(CommandObserver); ok {
+			cmdObservers = append(cmdObservers, cmdObs)
+		}
+	}
+	if len(cmdObservers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, commandHookKey{}, commandHook(func(cmd string) func(error) {
+		finishers := make([]func(error), 0, len(cmdObservers))
+		for _, obs := range cmdObservers {
+			if finish := obs.CommandStarted(meta, cmd); finish != nil {
+				finishers = append(finishers, finish)
+			}
+		}
+		return func(err error) {
+			for _, finish := range finishers {
+				finish(err)
+			}
+		}
+	}))
+}
@@ -1,11 +1,26 @@
 package phases
 
-import "sync"
+import (
+	"errors"
+	"io"
+	"sync"
+)
 
 // Context stores arbitrary key/value pairs shared between phases.
 type Context struct {
-	mu    sync.RWMutex
-	store map[string]any
+	mu       sync.RWMutex
+	store    map[string]any
+	watchers map[string]map[int]func(any)
+	watchSeq int
+	secrets  map[string]struct{}
+	inputs   map[string]InputDefinition
+
+	// Views returned by Scope keep their entries in root under prefix and
+	// fall back to parent for keys they do not hold.
+	root   *Context
+	parent *Context
+	prefix string
+	scope  string
 }
 
 // NewContext creates an empty context.
@@ -15,17 +30,88 @@ func NewContext() *Context {
 	}
 }
 
+// Scope returns a view of c for one host of a multi-host run, so phases
+// running against different hosts can share one context tree while
+// "ssh:client" and friends stay separate. Values set through the view are
+// stored in c under "<hostID>/<key>". Get looks in the view first and then
+// falls back to c, so run-wide values such as operator inputs set on c stay
+// visible; Delete only removes the view's own entry. Scopes nest, and an
+// empty hostID returns c itself.
+func (c *Context) Scope(hostID string) *Context {
+	if c == nil || hostID == "" {
+		return c
+	}
+	root := c
+	if c.root != nil {
+		root = c.root
+	}
+	return &Context{root: root, parent: c, prefix: c.prefix + hostID + "/", scope: hostID}
+}
+
+// ScopeID returns the hostID the view was scoped to with Scope, or "" for a
+// context that is not a scoped view.
+func (c *Context) ScopeID() string {
+	if c == nil {
+		return ""
+	}
+	return c.scope
+}
+
 // Set assigns a value under the provided key.
 func (c *Context) Set(key string, value any) {
 	if c == nil {
 		return
 	}
+	if c.root != nil {
+		c.root.Set(c.prefix+key, value)
+		return
+	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	if c.store == nil {
 		c.store = make(map[string]any)
 	}
 	c.store[key] = value
+	fns := make([]func(any), 0, len(c.watchers[key]))
+	for _, fn := range c.watchers[key] {
+		fns = append(fns, fn)
+	}
+	c.mu.Unlock()
+	for _, fn := range fns {
+		fn(value)
+	}
+}
+
+// Watch registers fn to be called with the new value each time key is set,
+// so a UI can show derived state such as the detected OS as soon as a phase
+// stores it. fn runs on the setting goroutine after the value is stored and
+// must not block. Watching through a Scope view only sees that view's own
+// entry. Call the returned function to stop watching.
+func (c *Context) Watch(key string, fn func(value any)) (cancel func()) {
+	if c == nil || fn == nil {
+		return func() {}
+	}
+	if c.root != nil {
+		return c.root.Watch(c.prefix+key, fn)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchers == nil {
+		c.watchers = make(map[string]map[int]func(any))
+	}
+	if c.watchers[key] == nil {
+		c.watchers[key] = make(map[int]func(any))
+	}
+	c.watchSeq++
+	id := c.watchSeq
+	c.watchers[key][id] = fn
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.watchers[key], id)
+		if len(c.watchers[key]) == 0 {
+			delete(c.watchers, key)
+		}
+	}
 }
 
 // Get retrieves a value, returning false when the key is not present.
@@ -33,12 +119,131 @@ func (c *Context) Get(key string) (any, bool) {
 	if c == nil {
 		return nil, false
 	}
+	if c.root != nil {
+		if val, ok := c.root.Get(c.prefix + key); ok {
+			return val, true
+		}
+		return c.parent.Get(key)
+	}
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	val, ok := c.store[key]
 	return val, ok
 }
 
+// Delete removes key, e.g. when a phase is undone.
+func (c *Context) Delete(key string) {
+	if c == nil {
+		return
+	}
+	if c.root != nil {
+		c.root.Delete(c.prefix + key)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.store, key)
+}
+
+// MarkSecret records that the entries under keys hold secrets, such as
+// passwords, which Export then seals or omits. Marks made through a Scope
+// view apply to the view's entries.
+func (c *Context) MarkSecret(keys ...string) {
+	if c == nil {
+		return
+	}
+	if c.root != nil {
+		for _, key := range keys {
+			c.root.MarkSecret(c.prefix + key)
+		}
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.secrets == nil {
+		c.secrets = make(map[string]struct{})
+	}
+	for _, key := range keys {
+		c.secrets[key] = struct{}{}
+	}
+}
+
+// DefineInputs records the input definitions of metas so SetInput and
+// GetInput coerce answers with CoerceInput, and marks the secret ones with
+// MarkSecretInputs. Manager runs do this for their phases.
+func (c *Context) DefineInputs(metas ...PhaseMetadata) {
+	for _, meta := range metas {
+		for _, def := range meta.Inputs {
+			c.defineInput(meta.ID, def)
+		}
+	}
+}
+
+func (c *Context) defineInput(phaseID string, def InputDefinition) {
+	if c == nil {
+		return
+	}
+	if c.root != nil {
+		c.root.defineInput(phaseID, def)
+		return
+	}
+	c.markSecretInput(phaseID, def)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inputs == nil {
+		c.inputs = make(map[string]InputDefinition)
+	}
+	c.inputs[inputKey(phaseID, def.ID)] = def
+}
+
+func (c *Context) inputDefinition(phaseID, inputID string) (InputDefinition, bool) {
+	if c == nil {
+		return InputDefinition{}, false
+	}
+	if c.root != nil {
+		return c.root.inputDefinition(phaseID, inputID)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	def, ok := c.inputs[inputKey(phaseID, inputID)]
+	return def, ok
+}
+
+// MarkSecretInputs marks the stored answers to the secret inputs of metas
+// with MarkSecret. Manager runs do this for their phases.
+func (c *Context) MarkSecretInputs(metas ...PhaseMetadata) {
+	for _, meta := range metas {
+		for _, def := range meta.Inputs {
+			c.markSecretInput(meta.ID, def)
+		}
+	}
+}
+
+func (c *Context) markSecretInput(phaseID string, def InputDefinition) {
+	if def.Kind == InputKindSecret || def.Secret {
+		c.MarkSecret(inputKey(phaseID, def.ID))
+	}
+}
+
+// IsSecret reports whether key was marked with MarkSecret. Like Get, a Scope
+// view also honours marks made on its parents, and an entry of a view is
+// secret when the same key is secret without the host prefix.
+func (c *Context) IsSecret(key string) bool {
+	if c == nil {
+		return false
+	}
+	if c.root != nil {
+		return c.root.IsSecret(c.prefix+key) || c.parent.IsSecret(key)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.secrets[key]; ok {
+		return true
+	}
+	_, ok := c.secrets[baseKey(key)]
+	return ok
+}
+
 // MustGet returns the value or panics if the key is missing.
 func (c *Context) MustGet(key string) any {
 	val, ok := c.Get(key)
@@ -47,3 +252,21 @@ func (c *Context) MustGet(key string) any {
 	}
 	return val
 }
+
+// Close closes and removes every value the context holds that is an
+// io.Closer, such as SSH clients, when a run is over and the context is
+// being dropped. A Scope view only closes its own entries.
+func (c *Context) Close() error {
+	var errs []error
+	for key, value := range c.entries() {
+		closer, ok := value.(io.Closer)
+		if !ok {
+			continue
+		}
+		c.Delete(key)
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,129 @@
+package phases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextScopeIsolatesHosts(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext()
+	ctx.Set("input:ssh.username", "admin")
+	web, db := ctx.Scope("web01"), ctx.Scope("db01")
+	require.Equal(t, "web01", web.ScopeID())
+	require.Empty(t, ctx.ScopeID())
+	require.Same(t, ctx, ctx.Scope(""))
+
+	web.Set("ssh:client", "web client")
+	db.Set("ssh:client", "db client")
+
+	got, _ := web.Get("ssh:client")
+	require.Equal(t, "web client", got)
+	got, _ = db.Get("ssh:client")
+	require.Equal(t, "db client", got)
+	_, ok := ctx.Get("ssh:client")
+	require.False(t, ok)
+	got, _ = ctx.Get("web01/ssh:client")
+	require.Equal(t, "web client", got)
+
+	// Run-wide values stay visible through every scope.
+	got, _ = db.Get("input:ssh.username")
+	require.Equal(t, "admin", got)
+
+	// Scopes nest and fall back through their parents.
+	nested := web.Scope("eth1")
+	got, _ = nested.Get("ssh:client")
+	require.Equal(t, "web client", got)
+	nested.Set("ssh:client", "eth1 client")
+	got, _ = ctx.Get("web01/eth1/ssh:client")
+	require.Equal(t, "eth1 client", got)
+
+	web.Delete("ssh:client")
+	_, ok = web.Get("ssh:client")
+	require.False(t, ok)
+	got, _ = db.Get("ssh:client")
+	require.Equal(t, "db client", got)
+
+	key := NewKey[int]("ssh:target_port")
+	SetContext(web, key, 2222)
+	port, ok := GetContext(web, key)
+	require.True(t, ok)
+	require.Equal(t, 2222, port)
+	_, ok = GetContext(db, key)
+	require.False(t, ok)
+}
+
+func TestContextWatchNotifiesOnSet(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext()
+	var seen []any
+	cancel := ctx.Watch("host:os", func(value any) {
+		seen = append(seen, value)
+	})
+
+	ctx.Set("host:distro", "debian")
+	ctx.Set("host:os", "debian 12")
+	ctx.Set("host:os", "debian 13")
+	require.Equal(t, []any{"debian 12", "debian 13"}, seen)
+
+	// Watchers may read the context they are notified about.
+	ctx.Watch("ansible:user", func(any) {
+		_, ok := ctx.Get("ansible:user")
+		require.True(t, ok)
+	})
+	ctx.Set("ansible:user", "ansible")
+
+	cancel()
+	ctx.Set("host:os", "ubuntu 24.04")
+	require.Len(t, seen, 2)
+
+	// Scoped watchers only see their own host's entry.
+	web := ctx.Scope("web01")
+	var webSeen []any
+	web.Watch("host:os", func(value any) {
+		webSeen = append(webSeen, value)
+	})
+	ctx.Scope("db01").Set("host:os", "rocky 9")
+	web.Set("host:os", "debian 12")
+	require.Equal(t, []any{"debian 12"}, webSeen)
+
+	var ports []int
+	WatchContext(ctx, NewKey[int]("ssh:target_port"), func(port int) {
+		ports = append(ports, port)
+	})
+	ctx.Set("ssh:target_port", "not a port")
+	ctx.Set("ssh:target_port", 2222)
+	require.Equal(t, []int{2222}, ports)
+}
+
+type closeCounter struct{ closed int }
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestContextCloseClosesHeldClients(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext()
+	client, scoped := &closeCounter{}, &closeCounter{}
+	ctx.Set("ssh:client", client)
+	ctx.Set("ssh:target_host", "web01")
+	ctx.Scope("db01").Set("ssh:client", scoped)
+
+	require.NoError(t, ctx.Scope("db01").Close())
+	require.Equal(t, 1, scoped.closed)
+	require.Zero(t, client.closed)
+
+	require.NoError(t, ctx.Close())
+	require.Equal(t, 1, client.closed)
+	require.Equal(t, 1, scoped.closed)
+	_, ok := ctx.Get("ssh:client")
+	require.False(t, ok)
+	host, _ := ctx.Get("ssh:target_host")
+	require.Equal(t, "web01", host)
+}
@@ -0,0 +1,141 @@
+// Package driftcheck compares a prepared host with what ansible_user would
+// write in check mode and reports what changed since it was prepared.
+package driftcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+const (
+	phaseID = "drift_check"
+
+	// ContextKeyFindings holds the []Finding of the check, empty when the
+	// host has not drifted.
+	ContextKeyFindings = "drift:findings"
+
+	// Finding checks.
+	CheckUser   = "user"
+	CheckFile   = "file"
+	CheckPython = "python"
+)
+
+// Finding is one way a host differs from how it was prepared.
+type Finding struct {
+	Check  string `json:"check"`
+	Detail string `json:"detail"`
+}
+
+// DriftError reports a host that drifted; notifiers see it as a failed run.
+type DriftError struct {
+	Host     string
+	Findings []Finding
+}
+
+func (e DriftError) Error() string {
+	details := make([]string, 0, len(e.Findings))
+	for _, finding := range e.Findings {
+		details = append(details, finding.Detail)
+	}
+	return fmt.Sprintf("drift on %s: %s", e.Host, strings.Join(details, "; "))
+}
+
+// Runner executes commands on the target system.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// InterpreterLocator resolves the remote python3 interpreter; an error means
+// it is missing.
+type InterpreterLocator func(r Runner) (string, error)
+
+// Phase turns the check-mode plan of ansible_user into drift findings and
+// checks that python is still installed.
+type Phase struct {
+	locate InterpreterLocator
+}
+
+// New creates a drift check phase. Register it after ansible_user in check
+// mode (see ansibleprep.DriftBundle).
+func New() *Phase {
+	return &Phase{locate: locateInterpreter}
+}
+
+// WithInterpreterLocator overrides how the interpreter is found (for tests).
+func (p *Phase) WithInterpreterLocator(fn InterpreterLocator) *Phase {
+	if fn != nil {
+		p.locate = fn
+	}
+	return p
+}
+
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{
+		ID:                phaseID,
+		Title:             "Check for Drift",
+		Requires:          []string{ansibleuser.PhaseID},
+		Description:       "Report changes to the ansible user, its sudoers rule and keys, or python since the host was prepared.",
+		EstimatedDuration: 5 * time.Second,
+	}
+}
+
+// Run fails with a DriftError when the ansible user is gone, a file it
+// needs differs from what prep writes, or python3 is missing.
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+	planVal, _ := phaseCtx.Get(ansibleuser.ContextKeyUserPlan)
+	plan, ok := planVal.(*systemuser.Plan)
+	if !ok || plan == nil {
+		return phases.ValidationError{Reason: "ansible_user must run in check mode before the drift check"}
+	}
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
+		return phases.ValidationError{Reason: "sudo phase must complete before the drift check"}
+	}
+
+	var findings []Finding
+	if !plan.UserExists {
+		findings = append(findings, Finding{Check: CheckUser, Detail: fmt.Sprintf("user %s was removed", plan.Username)})
+	}
+	for _, file := range plan.Files {
+		switch {
+		case !file.Changed():
+		case !file.Exists:
+			findings = append(findings, Finding{Check: CheckFile, Detail: file.Path + " was removed"})
+		default:
+			findings = append(findings, Finding{Check: CheckFile, Detail: file.Path + " was changed"})
+		}
+	}
+	if _, err := p.locate(phases.NewTracedRunner(ctx, elevatedClient)); err != nil {
+		findings = append(findings, Finding{Check: CheckPython, Detail: "python3 is missing"})
+	}
+
+	phaseCtx.Set(ContextKeyFindings, findings)
+	if len(findings) > 0 {
+		host, _ := phaseCtx.Get(sshconnect.ContextKeyTargetHost)
+		return DriftError{Host: fmt.Sprint(host), Findings: findings}
+	}
+	return nil
+}
+
+func locateInterpreter(r Runner) (string, error) {
+	stdout, _, err := r.Run("command -v python3")
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSpace(stdout)
+	if path == "" {
+		return "", fmt.Errorf("python3 not found")
+	}
+	return path, nil
+}
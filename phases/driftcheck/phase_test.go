@@ -0,0 +1,80 @@
+package driftcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+func planContext(plan *systemuser.Plan) *phases.Context {
+	ctx := phases.NewContext()
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(ansibleuser.ContextKeyUserPlan, plan)
+	return ctx
+}
+
+func python(found bool) InterpreterLocator {
+	return func(Runner) (string, error) {
+		if !found {
+			return "", errors.New("python3 not found")
+		}
+		return "/usr/bin/python3", nil
+	}
+}
+
+func TestPhaseReportsNoDrift(t *testing.T) {
+	t.Parallel()
+
+	ctx := planContext(&systemuser.Plan{
+		Username:   "ansible",
+		UserExists: true,
+		Files:      []systemuser.FileChange{{Path: "/etc/sudoers.d/ansible", Exists: true, Current: "rule\n", Planned: "rule\n"}},
+	})
+	require.NoError(t, New().WithInterpreterLocator(python(true)).Run(context.Background(), ctx))
+	findings, ok := ctx.Get(ContextKeyFindings)
+	require.True(t, ok)
+	require.Empty(t, findings)
+}
+
+func TestPhaseReportsDrift(t *testing.T) {
+	t.Parallel()
+
+	ctx := planContext(&systemuser.Plan{
+		Username: "ansible",
+		Files: []systemuser.FileChange{
+			{Path: "/etc/sudoers.d/ansible", Exists: true, Current: "ansible ALL=(ALL) ALL\n", Planned: "ansible ALL=(ALL) NOPASSWD: ALL\n"},
+			{Path: "/home/ansible/.ssh/authorized_keys", Planned: "ssh-ed25519 AAAA\n"},
+		},
+	})
+	err := New().WithInterpreterLocator(python(false)).Run(context.Background(), ctx)
+
+	var driftErr DriftError
+	require.ErrorAs(t, err, &driftErr)
+	require.Equal(t, "10.0.0.5", driftErr.Host)
+	require.Equal(t, []Finding{
+		{Check: CheckUser, Detail: "user ansible was removed"},
+		{Check: CheckFile, Detail: "/etc/sudoers.d/ansible was changed"},
+		{Check: CheckFile, Detail: "/home/ansible/.ssh/authorized_keys was removed"},
+		{Check: CheckPython, Detail: "python3 is missing"},
+	}, driftErr.Findings)
+	require.Contains(t, err.Error(), "drift on 10.0.0.5: user ansible was removed; ")
+}
+
+func TestPhaseRequiresCheckModePlan(t *testing.T) {
+	t.Parallel()
+
+	ctx := planContext(nil)
+	ctx.Delete(ansibleuser.ContextKeyUserPlan)
+	var validationErr phases.ValidationError
+	require.ErrorAs(t, New().Run(context.Background(), ctx), &validationErr)
+}
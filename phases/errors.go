@@ -1,6 +1,9 @@
 package phases
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // DuplicatePhaseError occurs when a phase with an existing ID is registered.
 type DuplicatePhaseError struct {
@@ -11,6 +14,15 @@ func (e DuplicatePhaseError) Error() string {
 	return fmt.Sprintf("phase with id %q already registered", e.ID)
 }
 
+// UnknownPhaseError occurs when a phase ID names no registered phase.
+type UnknownPhaseError struct {
+	ID string
+}
+
+func (e UnknownPhaseError) Error() string {
+	return fmt.Sprintf("no phase with id %q registered", e.ID)
+}
+
 // ValidationError represents invalid manager/phase configuration.
 type ValidationError struct {
 	Reason string
@@ -47,3 +59,30 @@ func (e PhaseExecutionError) Error() string {
 func (e PhaseExecutionError) Unwrap() error {
 	return e.Err
 }
+
+// InputProblem describes a single pre-supplied input that failed validation.
+type InputProblem struct {
+	PhaseID string
+	InputID string
+	Reason  string
+}
+
+func (p InputProblem) String() string {
+	if p.InputID == "" {
+		return fmt.Sprintf("%s: %s", p.PhaseID, p.Reason)
+	}
+	return fmt.Sprintf("%s.%s: %s", p.PhaseID, p.InputID, p.Reason)
+}
+
+// InputValidationError aggregates every problem found while validating pre-supplied inputs.
+type InputValidationError struct {
+	Problems []InputProblem
+}
+
+func (e InputValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		lines = append(lines, problem.String())
+	}
+	return fmt.Sprintf("%d invalid input(s): %s", len(e.Problems), strings.Join(lines, "; "))
+}
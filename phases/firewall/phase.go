@@ -0,0 +1,98 @@
+package firewall
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/firewall"
+)
+
+const (
+	phaseID = "firewall"
+
+	// ContextKeyBackend records the firewall in use ("ufw" or "firewalld").
+	ContextKeyBackend = "firewall:backend"
+	// ContextKeyAllowedPorts lists every port the phase allowed, SSH first
+	// ([]string, e.g. "22/tcp").
+	ContextKeyAllowedPorts = "firewall:allowed_ports"
+
+	// InputAllowedPorts is a comma-separated list of extra ports to allow.
+	InputAllowedPorts = "allowed_ports"
+
+	defaultSSHPort = 22
+)
+
+// EnsureFunc wraps firewall.Ensure for dependency injection.
+type EnsureFunc func(r firewall.Runner, opts ...firewall.Option) (*firewall.Result, error)
+
+// Phase installs and enables a host firewall that keeps SSH reachable.
+type Phase struct {
+	ensure EnsureFunc
+}
+
+// New creates a firewall hardening phase.
+func New() *Phase {
+	return &Phase{ensure: firewall.Ensure}
+}
+
+// WithEnsureFunc overrides how the firewall is configured (for tests).
+func (p *Phase) WithEnsureFunc(fn EnsureFunc) *Phase {
+	if fn != nil {
+		p.ensure = fn
+	}
+	return p
+}
+
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{
+		ID:                phaseID,
+		Title:             "Harden Firewall",
+		Requires:          []string{sudoensure.PhaseID},
+		Description:       "Install ufw or firewalld, allow SSH and any extra ports, and enable the firewall.",
+		EstimatedDuration: 30 * time.Second,
+		Inputs: []phases.InputDefinition{
+			{
+				ID:          InputAllowedPorts,
+				Label:       "Extra Allowed Ports",
+				Description: "Comma-separated ports to allow besides SSH, e.g. 80,443/tcp,53/udp.",
+				Kind:        phases.InputKindText,
+			},
+		},
+	}
+}
+
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if p.ensure == nil {
+		p.ensure = firewall.Ensure
+	}
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
+		return phases.ValidationError{Reason: "sudo phase must complete before configuring the firewall"}
+	}
+
+	sshPort := defaultSSHPort
+	if val, ok := phaseCtx.Get(sshconnect.ContextKeyTargetPort); ok {
+		if port, ok := val.(int); ok && port > 0 {
+			sshPort = port
+		}
+	}
+	extra, _ := phases.GetInputString(phaseCtx, phaseID, InputAllowedPorts)
+
+	runner := phases.NewTracedRunner(ctx, elevatedClient)
+	result, err := p.ensure(runner, firewall.WithSSHPort(sshPort), firewall.WithAllowedPorts(strings.Split(extra, ",")...))
+	if err != nil {
+		return err
+	}
+
+	phaseCtx.Set(ContextKeyBackend, result.Backend)
+	phaseCtx.Set(ContextKeyAllowedPorts, result.Ports)
+	return nil
+}
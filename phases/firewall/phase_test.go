@@ -0,0 +1,81 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/firewall"
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+)
+
+// scriptRunner answers firewall detection and configuration like a host
+// with ufw installed.
+type scriptRunner struct {
+	cmds []string
+}
+
+func (r *scriptRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	if len(r.cmds) == 1 {
+		return "ufw=present firewalld=missing apt=yes\n", "", nil
+	}
+	return "activated\n", "", nil
+}
+
+func TestPhaseAllowsSSHAndExtraPorts(t *testing.T) {
+	t.Parallel()
+
+	target := &scriptRunner{}
+	phase := New().WithEnsureFunc(func(r firewall.Runner, opts ...firewall.Option) (*firewall.Result, error) {
+		require.IsType(t, &phases.TracedRunner{}, r)
+		opts = append(opts, firewall.WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+			return nil, errors.New("ufw is already installed")
+		}))
+		return firewall.Ensure(target, opts...)
+	})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(sshconnect.ContextKeyTargetPort, 2222)
+	phases.SetInput(ctx, phaseID, InputAllowedPorts, "80, 443/tcp,53/udp")
+
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Len(t, target.cmds, 2)
+	require.Contains(t, target.cmds[1], "ufw allow 2222/tcp")
+
+	backend, _ := ctx.Get(ContextKeyBackend)
+	require.Equal(t, firewall.BackendUFW, backend)
+	ports, _ := ctx.Get(ContextKeyAllowedPorts)
+	require.Equal(t, []string{"2222/tcp", "80/tcp", "443/tcp", "53/udp"}, ports)
+}
+
+func TestPhaseRequiresElevatedClient(t *testing.T) {
+	t.Parallel()
+
+	err := New().Run(context.Background(), phases.NewContext())
+	var valErr phases.ValidationError
+	require.ErrorAs(t, err, &valErr)
+}
+
+func TestPhaseRejectsInvalidPorts(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithEnsureFunc(func(r firewall.Runner, opts ...firewall.Option) (*firewall.Result, error) {
+		return firewall.Ensure(&scriptRunner{}, opts...)
+	})
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	phases.SetInput(ctx, phaseID, InputAllowedPorts, "http")
+
+	err := phase.Run(context.Background(), ctx)
+	require.ErrorAs(t, err, &firewall.ValidationError{})
+	_, ok := ctx.Get(ContextKeyBackend)
+	require.False(t, ok)
+}
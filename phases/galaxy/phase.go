@@ -0,0 +1,154 @@
+package galaxy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/ansiblegalaxy"
+)
+
+const (
+	defaultPhaseID          = "ansible_galaxy"
+	defaultRequirementsPath = "requirements.yml"
+	// defaultEstimatedDuration weights the phase in pipeline progress when
+	// Config.EstimatedDuration is zero.
+	defaultEstimatedDuration = time.Minute
+
+	// Input identifiers.
+	InputRequirementsPath = "requirements_path"
+
+	// Context keys
+	ContextKeyRequirementsPath = "galaxy:requirements_path"
+	ContextKeyResult           = "galaxy:result"
+)
+
+// Installer runs ansible-galaxy.
+type Installer func(context.Context, ansiblegalaxy.InstallRequest, ...ansiblegalaxy.Option) (*ansiblegalaxy.Result, error)
+
+// Config describes a requirements install phase. Register it before the
+// playbook phase that needs the roles and collections.
+type Config struct {
+	ID          string
+	Title       string
+	Description string
+	// RequirementsPath skips the input prompt when set.
+	RequirementsPath string
+	RolesPath        string
+	CollectionsPath  string
+	Force            bool
+	Tags             []string
+	Options          []ansiblegalaxy.Option
+	// EstimatedDuration is roughly how long the install takes, which
+	// weights the phase in pipeline progress (default one minute).
+	EstimatedDuration time.Duration
+}
+
+// Phase installs roles and collections on the controller.
+type Phase struct {
+	meta    phases.PhaseMetadata
+	cfg     Config
+	install Installer
+}
+
+// New constructs an ansible-galaxy phase from cfg.
+func New(cfg Config) *Phase {
+	id := strings.TrimSpace(cfg.ID)
+	if id == "" {
+		id = defaultPhaseID
+	}
+	title := strings.TrimSpace(cfg.Title)
+	if title == "" {
+		title = "Install Galaxy Requirements"
+	}
+	desc := strings.TrimSpace(cfg.Description)
+	if desc == "" {
+		desc = "Install the roles and collections listed in a requirements file on this machine."
+	}
+
+	cfg.RequirementsPath = strings.TrimSpace(cfg.RequirementsPath)
+	cfg.Options = append([]ansiblegalaxy.Option{}, cfg.Options...)
+
+	var inputs []phases.InputDefinition
+	if cfg.RequirementsPath == "" {
+		inputs = append(inputs, requirementsDefinition())
+	}
+
+	estimate := cfg.EstimatedDuration
+	if estimate <= 0 {
+		estimate = defaultEstimatedDuration
+	}
+
+	return &Phase{
+		meta: phases.PhaseMetadata{
+			ID:                id,
+			Title:             title,
+			Description:       desc,
+			Inputs:            inputs,
+			Tags:              append([]string{}, cfg.Tags...),
+			EstimatedDuration: estimate,
+		},
+		cfg:     cfg,
+		install: ansiblegalaxy.Install,
+	}
+}
+
+// Metadata returns the configured phase metadata.
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return p.meta
+}
+
+// WithInstaller overrides the ansible-galaxy executor (useful for tests).
+func (p *Phase) WithInstaller(fn Installer) *Phase {
+	if fn != nil {
+		p.install = fn
+	}
+	return p
+}
+
+// Run installs the requirements and records the result.
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+
+	path := p.cfg.RequirementsPath
+	if path == "" {
+		input, ok := phases.GetInputPath(phaseCtx, p.meta.ID, InputRequirementsPath)
+		if !ok || input == "" {
+			return phases.InputRequestError{
+				PhaseID: p.meta.ID,
+				Input:   requirementsDefinition(),
+				Reason:  "requirements file path is required",
+			}
+		}
+		path = input
+	}
+
+	res, err := p.install(ctx, ansiblegalaxy.InstallRequest{
+		RequirementsPath: path,
+		RolesPath:        p.cfg.RolesPath,
+		CollectionsPath:  p.cfg.CollectionsPath,
+		Force:            p.cfg.Force,
+	}, p.cfg.Options...)
+	if err != nil {
+		return fmt.Errorf("galaxy phase: %w", err)
+	}
+
+	phaseCtx.Set(ContextKeyRequirementsPath, path)
+	phaseCtx.Set(ContextKeyResult, res)
+	return nil
+}
+
+func requirementsDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputRequirementsPath,
+		Label:       "Requirements File",
+		Description: "Path to the ansible-galaxy requirements.yml listing roles and collections.",
+		Kind:        phases.InputKindText,
+		Required:    true,
+		Default:     defaultRequirementsPath,
+	}
+}
@@ -0,0 +1,52 @@
+package galaxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/ansiblegalaxy"
+)
+
+func TestRunRequestsPathThenInstalls(t *testing.T) {
+	t.Parallel()
+
+	var got ansiblegalaxy.InstallRequest
+	want := &ansiblegalaxy.Result{Installed: []string{"community.general:9.0.0"}}
+	phase := New(Config{RolesPath: "roles"}).WithInstaller(func(_ context.Context, req ansiblegalaxy.InstallRequest, _ ...ansiblegalaxy.Option) (*ansiblegalaxy.Result, error) {
+		got = req
+		return want, nil
+	})
+
+	phaseCtx := phases.NewContext()
+	err := phase.Run(context.Background(), phaseCtx)
+	var reqErr phases.InputRequestError
+	require.ErrorAs(t, err, &reqErr)
+	require.Equal(t, InputRequirementsPath, reqErr.Input.ID)
+
+	phases.SetInput(phaseCtx, phase.Metadata().ID, InputRequirementsPath, " /srv/site/requirements.yml ")
+	require.NoError(t, phase.Run(context.Background(), phaseCtx))
+	require.Equal(t, ansiblegalaxy.InstallRequest{RequirementsPath: "/srv/site/requirements.yml", RolesPath: "roles"}, got)
+
+	path, _ := phaseCtx.Get(ContextKeyRequirementsPath)
+	require.Equal(t, "/srv/site/requirements.yml", path)
+	res, _ := phaseCtx.Get(ContextKeyResult)
+	require.Same(t, want, res)
+}
+
+func TestRunWrapsInstallFailure(t *testing.T) {
+	t.Parallel()
+
+	phase := New(Config{RequirementsPath: "requirements.yml"}).WithInstaller(func(context.Context, ansiblegalaxy.InstallRequest, ...ansiblegalaxy.Option) (*ansiblegalaxy.Result, error) {
+		return nil, errors.New("galaxy unreachable")
+	})
+	require.Empty(t, phase.Metadata().Inputs)
+
+	phaseCtx := phases.NewContext()
+	require.ErrorContains(t, phase.Run(context.Background(), phaseCtx), "galaxy phase: galaxy unreachable")
+	_, ok := phaseCtx.Get(ContextKeyResult)
+	require.False(t, ok)
+}
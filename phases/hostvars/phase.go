@@ -0,0 +1,172 @@
+package hostvars
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+)
+
+const (
+	phaseID = "host_vars"
+
+	// Input identifiers
+	InputDir = "dir"
+
+	// Context keys
+	ContextKeyPath = "hostvars:path"
+
+	defaultDir = "host_vars"
+)
+
+// Phase writes host_vars/<host>.yml with everything the prep learned so
+// downstream ansible runs need no manual variable setup.
+type Phase struct {
+	dir string
+}
+
+// New constructs the host_vars phase writing under ./host_vars.
+func New() *Phase {
+	return &Phase{dir: defaultDir}
+}
+
+// WithDir overrides the host_vars directory.
+func (p *Phase) WithDir(dir string) *Phase {
+	if dir = strings.TrimSpace(dir); dir != "" {
+		p.dir = dir
+	}
+	return p
+}
+
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{
+		ID:                phaseID,
+		Title:             "Write host_vars",
+		Requires:          []string{ansibleuser.PhaseID},
+		Description:       "Record the connection details and choices made during prep in host_vars/<host>.yml.",
+		EstimatedDuration: time.Second,
+		Inputs: []phases.InputDefinition{
+			{
+				ID:          InputDir,
+				Label:       "host_vars Directory",
+				Description: "Directory the host_vars file is written to (defaults to ./host_vars).",
+				Kind:        phases.InputKindText,
+				Default:     p.dir,
+			},
+		},
+	}
+}
+
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+
+	host := contextString(phaseCtx, sshconnect.ContextKeyTargetHost)
+	if host == "" {
+		return phases.ValidationError{Reason: "SSH connection phase must complete before writing host_vars"}
+	}
+	if strings.ContainsAny(host, `/\`) || host == "." || host == ".." {
+		return phases.ValidationError{Reason: fmt.Sprintf("host %q cannot be used as a host_vars file name", host)}
+	}
+
+	dir := p.dir
+	if val, ok := phases.GetInputPath(phaseCtx, phaseID, InputDir); ok && val != "" {
+		dir = val
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("host_vars phase: create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, host+".yml")
+	if err := atomicfile.WriteFile(path, []byte(Render(Collect(phaseCtx))), 0o644); err != nil {
+		return fmt.Errorf("host_vars phase: write %s: %w", path, err)
+	}
+
+	phaseCtx.Set(ContextKeyPath, path)
+	return nil
+}
+
+// Var is a single host variable.
+type Var struct {
+	Name  string
+	Value any
+}
+
+// Collect gathers the ansible connection variables known from earlier phases,
+// in a stable order. Variables without a value are omitted.
+func Collect(phaseCtx *phases.Context) []Var {
+	var vars []Var
+	add := func(name string, value any) {
+		switch v := value.(type) {
+		case nil:
+			return
+		case string:
+			if v == "" {
+				return
+			}
+		}
+		vars = append(vars, Var{Name: name, Value: value})
+	}
+
+	add("ansible_host", contextString(phaseCtx, sshconnect.ContextKeyTargetHost))
+	if val, ok := phaseCtx.Get(sshconnect.ContextKeyTargetPort); ok {
+		if port, ok := val.(int); ok && port > 0 {
+			add("ansible_port", port)
+		}
+	}
+
+	user := contextString(phaseCtx, sshconnect.ContextKeyTargetUser)
+	viaAnsibleUser := false
+	if res, ok := phases.GetContext(phaseCtx, ansibleuser.KeyUserResult); ok && res != nil && res.Username != "" {
+		user = res.Username
+		viaAnsibleUser = res.PasswordlessConfigured
+	}
+	add("ansible_user", user)
+
+	if info, ok := phases.GetContext(phaseCtx, ansibleuser.KeyKeyPair); ok && info != nil {
+		add("ansible_ssh_private_key_file", info.PrivatePath)
+	}
+
+	add("ansible_python_interpreter", contextString(phaseCtx, pythonensure.ContextKeyInterpreter))
+	add("host_prep_arch", contextString(phaseCtx, pythonensure.ContextKeyArch))
+
+	if viaAnsibleUser {
+		add("ansible_become", true)
+		add("ansible_become_method", "sudo")
+	}
+	return vars
+}
+
+// Render formats vars as a YAML document. Strings are emitted double-quoted,
+// which YAML parses identically to JSON strings.
+func Render(vars []Var) string {
+	var b strings.Builder
+	b.WriteString("---\n# Generated by ansible-host-prep.\n")
+	for _, v := range vars {
+		value, err := json.Marshal(v.Value)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %s\n", v.Name, value)
+	}
+	return b.String()
+}
+
+func contextString(phaseCtx *phases.Context, key string) string {
+	val, ok := phaseCtx.Get(key)
+	if !ok {
+		return ""
+	}
+	str, _ := val.(string)
+	return strings.TrimSpace(str)
+}
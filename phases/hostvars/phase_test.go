@@ -0,0 +1,73 @@
+package hostvars
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+func TestPhaseWritesHostVars(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "inventory", "host_vars")
+	ctx := phases.NewContext()
+	ctx.Set(sshconnect.ContextKeyTargetHost, "web01.example.com")
+	ctx.Set(sshconnect.ContextKeyTargetPort, 2222)
+	ctx.Set(sshconnect.ContextKeyTargetUser, "root")
+	ctx.Set(ansibleuser.ContextKeyUserResult, &systemuser.Result{Username: "ansible", PasswordlessConfigured: true})
+	ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/home/ops/.ssh/ansible_web01"})
+	ctx.Set(pythonensure.ContextKeyInterpreter, "/usr/bin/python3")
+	ctx.Set(pythonensure.ContextKeyArch, "arm64")
+
+	require.NoError(t, New().WithDir(dir).Run(context.Background(), ctx))
+
+	path := filepath.Join(dir, "web01.example.com.yml")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `---
+# Generated by ansible-host-prep.
+ansible_host: "web01.example.com"
+ansible_port: 2222
+ansible_user: "ansible"
+ansible_ssh_private_key_file: "/home/ops/.ssh/ansible_web01"
+ansible_python_interpreter: "/usr/bin/python3"
+host_prep_arch: "arm64"
+ansible_become: true
+ansible_become_method: "sudo"
+`, string(data))
+
+	stored, ok := ctx.Get(ContextKeyPath)
+	require.True(t, ok)
+	require.Equal(t, path, stored)
+}
+
+func TestPhaseOmitsUnknownVars(t *testing.T) {
+	t.Parallel()
+
+	ctx := phases.NewContext()
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	ctx.Set(sshconnect.ContextKeyTargetUser, "admin")
+
+	require.Equal(t, []Var{
+		{Name: "ansible_host", Value: "10.0.0.5"},
+		{Name: "ansible_user", Value: "admin"},
+	}, Collect(ctx))
+}
+
+func TestPhaseRequiresTargetHost(t *testing.T) {
+	t.Parallel()
+
+	err := New().WithDir(t.TempDir()).Run(context.Background(), phases.NewContext())
+	var valErr phases.ValidationError
+	require.ErrorAs(t, err, &valErr)
+}
@@ -1,23 +1,202 @@
 package phases
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
 
 func inputKey(phaseID, inputID string) string {
 	return fmt.Sprintf("phase:%s:input:%s", phaseID, inputID)
 }
 
-// SetInput stores an input value for a given phase.
+// SetInput stores an input value for a given phase. When the context knows
+// the input's definition (see Context.DefineInputs) the value is normalized
+// with CoerceInput first; values it rejects are stored as given and
+// reported missing by GetInput.
 func SetInput(ctx *Context, phaseID, inputID string, value any) {
 	if ctx == nil {
 		return
 	}
+	if def, ok := ctx.inputDefinition(phaseID, inputID); ok {
+		if coerced, err := CoerceInput(def, value); err == nil {
+			value = coerced
+		}
+	}
 	ctx.Set(inputKey(phaseID, inputID), value)
 }
 
-// GetInput retrieves an input value for a given phase.
+// GetInput retrieves an input value for a given phase. Values of defined
+// inputs, including ones seeded before the definition was known, are
+// normalized with CoerceInput; a value it rejects reads as missing so the
+// phase requests it again.
 func GetInput(ctx *Context, phaseID, inputID string) (any, bool) {
 	if ctx == nil {
 		return nil, false
 	}
-	return ctx.Get(inputKey(phaseID, inputID))
+	value, ok := ctx.Get(inputKey(phaseID, inputID))
+	if !ok {
+		return nil, false
+	}
+	if def, defined := ctx.inputDefinition(phaseID, inputID); defined {
+		coerced, err := CoerceInput(def, value)
+		if err != nil {
+			return nil, false
+		}
+		return coerced, true
+	}
+	return value, true
+}
+
+// InputText formats an input or context value as trimmed text. Scalars are
+// converted as CoerceInput does, other values with fmt.Sprint; nil reads as
+// empty.
+func InputText(value any) string {
+	str, err := scalarString(value)
+	if err != nil {
+		str = fmt.Sprint(value)
+	}
+	str = strings.TrimSpace(str)
+	if str == "<nil>" {
+		return ""
+	}
+	return str
+}
+
+// GetContextString retrieves a context entry as trimmed text with InputText.
+// ok is false when the entry is missing or empty.
+func GetContextString(ctx *Context, key string) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	val, ok := ctx.Get(key)
+	if !ok {
+		return "", false
+	}
+	str := InputText(val)
+	return str, str != ""
+}
+
+// GetInputString retrieves an input as trimmed text. Non-string scalars are
+// formatted; values that cannot be represented as text report false.
+func GetInputString(ctx *Context, phaseID, inputID string) (string, bool) {
+	val, ok := GetInput(ctx, phaseID, inputID)
+	if !ok {
+		return "", false
+	}
+	str, err := scalarString(val)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(str), true
+}
+
+// GetInputInt retrieves an input parsed as an integer. ok is false when the
+// input is missing or empty; err reports a value that is not an integer.
+func GetInputInt(ctx *Context, phaseID, inputID string) (value int, ok bool, err error) {
+	str, present := GetInputString(ctx, phaseID, inputID)
+	if !present || str == "" {
+		return 0, false, nil
+	}
+	value, err = strconv.Atoi(str)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s must be an integer, got %q", inputID, str)
+	}
+	return value, true, nil
+}
+
+// GetInputBool retrieves an input parsed as a boolean (true/false, yes/no, 1/0).
+// ok is false when the input is missing or empty; err reports an unrecognized value.
+func GetInputBool(ctx *Context, phaseID, inputID string) (value bool, ok bool, err error) {
+	str, present := GetInputString(ctx, phaseID, inputID)
+	if !present || str == "" {
+		return false, false, nil
+	}
+	switch strings.ToLower(str) {
+	case "y", "yes", "on":
+		return true, true, nil
+	case "n", "no", "off":
+		return false, true, nil
+	}
+	value, err = strconv.ParseBool(str)
+	if err != nil {
+		return false, false, fmt.Errorf("%s must be yes or no, got %q", inputID, str)
+	}
+	return value, true, nil
+}
+
+// GetInputPath retrieves an input as a filesystem path, expanding a leading ~
+// to the operator's home directory.
+func GetInputPath(ctx *Context, phaseID, inputID string) (string, bool) {
+	str, ok := GetInputString(ctx, phaseID, inputID)
+	if !ok {
+		return "", false
+	}
+	return ExpandHome(str), true
+}
+
+// ExpandHome replaces a leading "~" or "~/" with the current user's home
+// directory. Other paths, including "~user" forms, are returned unchanged.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// CoerceInput normalizes a raw answer for def: scalars are converted to text,
+// text is trimmed (secrets are kept verbatim), and select values must match
+// one of the definition's options.
+func CoerceInput(def InputDefinition, raw any) (string, error) {
+	value, err := scalarString(raw)
+	if err != nil {
+		return "", fmt.Errorf("expected a scalar %s value, got %T", def.Kind, raw)
+	}
+	if def.Kind != InputKindSecret && !def.Secret {
+		value = strings.TrimSpace(value)
+	}
+	if def.Kind == InputKindSelect && value != "" && len(def.Options) > 0 {
+		for _, opt := range def.Options {
+			if opt.Value == value {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of %s", value, strings.Join(optionValues(def.Options), ", "))
+	}
+	return value, nil
+}
+
+func scalarString(raw any) (string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported input type %T", raw)
+	}
+}
+
+func optionValues(opts []InputOption) []string {
+	values := make([]string, 0, len(opts))
+	for _, opt := range opts {
+		values = append(values, opt.Value)
+	}
+	return values
 }
@@ -0,0 +1,117 @@
+package phases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedInputGetters(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext()
+	SetInput(ctx, "ssh", "host", "  example.com \n")
+	SetInput(ctx, "ssh", "port", float64(2222))
+	SetInput(ctx, "ssh", "bad_port", "twenty")
+	SetInput(ctx, "ssh", "agent", "yes")
+	SetInput(ctx, "ssh", "key", "~/.ssh/id_ed25519")
+
+	host, ok := GetInputString(ctx, "ssh", "host")
+	require.True(t, ok)
+	require.Equal(t, "example.com", host)
+
+	port, ok, err := GetInputInt(ctx, "ssh", "port")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 2222, port)
+
+	_, _, err = GetInputInt(ctx, "ssh", "bad_port")
+	require.Error(t, err)
+
+	_, ok, err = GetInputInt(ctx, "ssh", "missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	agent, ok, err := GetInputBool(ctx, "ssh", "agent")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, agent)
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	key, ok := GetInputPath(ctx, "ssh", "key")
+	require.True(t, ok)
+	require.Equal(t, filepath.Join(home, ".ssh", "id_ed25519"), key)
+	require.Equal(t, "~other/key", ExpandHome("~other/key"))
+}
+
+func TestDefinedInputsAreCoerced(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext()
+	// Seeded before the definitions are known, as headless runs do.
+	SetInput(ctx, "ssh", "host", "  example.com ")
+	ctx.DefineInputs(PhaseMetadata{ID: "ssh", Inputs: []InputDefinition{
+		{ID: "host", Kind: InputKindText},
+		{ID: "port", Kind: InputKindText},
+		{ID: "auth", Kind: InputKindSelect, Options: []InputOption{{Value: "key"}}},
+		{ID: "password", Kind: InputKindSecret},
+	}})
+	SetInput(ctx, "ssh", "port", 2222)
+	SetInput(ctx, "ssh", "auth", "kerberos")
+	SetInput(ctx, "ssh", "password", " pass ")
+
+	host, ok := GetInput(ctx, "ssh", "host")
+	require.True(t, ok)
+	require.Equal(t, "example.com", host)
+	stored, _ := ctx.Get(inputKey("ssh", "port"))
+	require.Equal(t, "2222", stored)
+	_, ok = GetInput(ctx, "ssh", "auth")
+	require.False(t, ok, "values outside the options read as missing")
+	password, ok := GetInput(ctx.Scope("web1"), "ssh", "password")
+	require.True(t, ok)
+	require.Equal(t, " pass ", password)
+	require.True(t, ctx.IsSecret(inputKey("ssh", "password")))
+
+	require.Equal(t, "", InputText(nil))
+	require.Equal(t, "42", InputText(42))
+	require.Equal(t, "[a]", InputText([]string{"a"}))
+}
+
+func TestCoerceInput(t *testing.T) {
+	t.Parallel()
+
+	selectDef := InputDefinition{ID: "auth", Kind: InputKindSelect, Options: []InputOption{{Value: "password"}, {Value: "key"}}}
+
+	tests := []struct {
+		name    string
+		def     InputDefinition
+		raw     any
+		want    string
+		wantErr bool
+	}{
+		{name: "trims text", def: InputDefinition{Kind: InputKindText}, raw: "  host ", want: "host"},
+		{name: "keeps secrets verbatim", def: InputDefinition{Kind: InputKindSecret}, raw: " pw ", want: " pw "},
+		{name: "formats numbers", def: InputDefinition{Kind: InputKindText}, raw: float64(22), want: "22"},
+		{name: "accepts select option", def: selectDef, raw: " key ", want: "key"},
+		{name: "rejects unknown option", def: selectDef, raw: "kerberos", wantErr: true},
+		{name: "rejects composite values", def: InputDefinition{Kind: InputKindText}, raw: map[string]any{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := CoerceInput(tt.def, tt.raw)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Package k8snode provides the optional phases that prepare a host as a
+// Kubernetes node after ansible prep: swap off, kernel modules, sysctl
+// settings, and containerd. Every phase carries Tag, so a caller can pick
+// the whole set with phasedapp.WithTag, or single steps by their own tag.
+package k8snode
+
+import (
+	"context"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/k8snode"
+)
+
+const (
+	PhaseIDSwap       = "k8s_swap"
+	PhaseIDModules    = "k8s_kernel_modules"
+	PhaseIDSysctl     = "k8s_sysctl"
+	PhaseIDContainerd = "k8s_containerd"
+
+	// Tag marks every Kubernetes node phase.
+	Tag = "k8s"
+
+	// ContextKeyContainerd holds the *k8snode.ContainerdResult of the
+	// containerd phase.
+	ContextKeyContainerd = "k8s:containerd"
+
+	// InputContainerdPackage overrides the containerd package name.
+	InputContainerdPackage = "package"
+)
+
+// StepFunc applies one prerequisite and reports whether it changed the host.
+type StepFunc func(r k8snode.Runner) (bool, error)
+
+// Phase applies one of the swap, kernel module, or sysctl prerequisites.
+type Phase struct {
+	meta phases.PhaseMetadata
+	step StepFunc
+}
+
+// NewSwap creates the phase that disables swap, which the kubelet requires.
+func NewSwap() *Phase {
+	return &Phase{
+		meta: phases.PhaseMetadata{
+			ID:                PhaseIDSwap,
+			Title:             "Disable Swap",
+			Description:       "Turn swap off and keep it off across reboots (fstab entries and systemd swap units).",
+			EstimatedDuration: 5 * time.Second,
+			Tags:              []string{Tag, "swap"},
+			Requires:          []string{sudoensure.PhaseID},
+		},
+		step: k8snode.DisableSwap,
+	}
+}
+
+// NewKernelModules creates the phase that loads overlay and br_netfilter.
+func NewKernelModules() *Phase {
+	return &Phase{
+		meta: phases.PhaseMetadata{
+			ID:                PhaseIDModules,
+			Title:             "Load Kernel Modules",
+			Description:       "Load overlay and br_netfilter now and at boot.",
+			EstimatedDuration: 5 * time.Second,
+			Tags:              []string{Tag, "kernel-modules"},
+			Requires:          []string{sudoensure.PhaseID},
+		},
+		step: func(r k8snode.Runner) (bool, error) { return k8snode.LoadModules(r) },
+	}
+}
+
+// NewSysctl creates the phase that enables bridged traffic filtering and IP
+// forwarding.
+func NewSysctl() *Phase {
+	return &Phase{
+		meta: phases.PhaseMetadata{
+			ID:                PhaseIDSysctl,
+			Title:             "Apply Kubernetes Sysctls",
+			Description:       "Set net.bridge.bridge-nf-call-iptables, bridge-nf-call-ip6tables, and net.ipv4.ip_forward to 1.",
+			EstimatedDuration: 5 * time.Second,
+			Tags:              []string{Tag, "sysctl"},
+			Requires:          []string{sudoensure.PhaseID, PhaseIDModules},
+		},
+		step: func(r k8snode.Runner) (bool, error) { return k8snode.ApplySysctls(r) },
+	}
+}
+
+// WithStepFunc overrides the step the phase applies (for tests).
+func (p *Phase) WithStepFunc(fn StepFunc) *Phase {
+	if fn != nil {
+		p.step = fn
+	}
+	return p
+}
+
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return p.meta
+}
+
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	runner, err := elevatedRunner(ctx, phaseCtx)
+	if err != nil {
+		return err
+	}
+	changed, err := p.step(runner)
+	if err != nil {
+		return err
+	}
+	reportChange(ctx, p.meta.Title, changed)
+	return nil
+}
+
+// EnsureContainerdFunc wraps k8snode.EnsureContainerd for dependency
+// injection.
+type EnsureContainerdFunc func(r k8snode.Runner, opts ...k8snode.ContainerdOption) (*k8snode.ContainerdResult, error)
+
+// ContainerdPhase installs containerd and configures it for kubeadm.
+type ContainerdPhase struct {
+	ensure EnsureContainerdFunc
+}
+
+// NewContainerd creates the containerd phase.
+func NewContainerd() *ContainerdPhase {
+	return &ContainerdPhase{ensure: k8snode.EnsureContainerd}
+}
+
+// WithEnsureFunc overrides how containerd is set up (for tests).
+func (p *ContainerdPhase) WithEnsureFunc(fn EnsureContainerdFunc) *ContainerdPhase {
+	if fn != nil {
+		p.ensure = fn
+	}
+	return p
+}
+
+func (p *ContainerdPhase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{
+		ID:                PhaseIDContainerd,
+		Title:             "Install containerd",
+		Description:       "Install containerd, enable its CRI plugin with the systemd cgroup driver, and start it.",
+		EstimatedDuration: 2 * time.Minute,
+		Tags:              []string{Tag, "containerd"},
+		Requires:          []string{sudoensure.PhaseID},
+		Inputs: []phases.InputDefinition{
+			{
+				ID:          InputContainerdPackage,
+				Label:       "containerd Package",
+				Description: `Package providing containerd, e.g. "containerd.io" with Docker's repositories.`,
+				Kind:        phases.InputKindText,
+				Default:     "containerd",
+			},
+		},
+	}
+}
+
+func (p *ContainerdPhase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	runner, err := elevatedRunner(ctx, phaseCtx)
+	if err != nil {
+		return err
+	}
+	var opts []k8snode.ContainerdOption
+	if pkg, ok := phases.GetInputString(phaseCtx, PhaseIDContainerd, InputContainerdPackage); ok && pkg != "" {
+		opts = append(opts, k8snode.WithPackage(pkg))
+	}
+	result, err := p.ensure(runner, opts...)
+	if err != nil {
+		return err
+	}
+	phaseCtx.Set(ContextKeyContainerd, result)
+	reportChange(ctx, "Install containerd", result.Installed || result.Configured)
+	return nil
+}
+
+func elevatedRunner(ctx context.Context, phaseCtx *phases.Context) (*phases.TracedRunner, error) {
+	if phaseCtx == nil {
+		return nil, phases.ValidationError{Reason: "phase context is required"}
+	}
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
+		return nil, phases.ValidationError{Reason: "sudo phase must complete before kubernetes node prep"}
+	}
+	return phases.NewTracedRunner(ctx, elevatedClient), nil
+}
+
+func reportChange(ctx context.Context, task string, changed bool) {
+	status := phases.TaskOK
+	if changed {
+		status = phases.TaskChanged
+	}
+	phases.ReportTask(ctx, phases.TaskEvent{Task: task, Status: status})
+}
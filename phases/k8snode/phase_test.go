@@ -0,0 +1,63 @@
+package k8snode
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/k8snode"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+)
+
+func elevatedContext() *phases.Context {
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	return ctx
+}
+
+func TestStepPhasesRunWithElevatedRunner(t *testing.T) {
+	t.Parallel()
+
+	for _, phase := range []*Phase{NewSwap(), NewKernelModules(), NewSysctl()} {
+		require.Contains(t, phase.Metadata().Tags, Tag)
+
+		ran := false
+		phase.WithStepFunc(func(r k8snode.Runner) (bool, error) {
+			require.IsType(t, &phases.TracedRunner{}, r)
+			ran = true
+			return true, nil
+		})
+		require.NoError(t, phase.Run(context.Background(), elevatedContext()))
+		require.True(t, ran, phase.Metadata().ID)
+	}
+
+	failure := errors.New("modprobe: FATAL: Module br_netfilter not found")
+	phase := NewKernelModules().WithStepFunc(func(k8snode.Runner) (bool, error) { return false, failure })
+	require.ErrorIs(t, phase.Run(context.Background(), elevatedContext()), failure)
+
+	var valErr phases.ValidationError
+	require.ErrorAs(t, NewSwap().Run(context.Background(), phases.NewContext()), &valErr)
+}
+
+func TestContainerdPhaseRecordsResult(t *testing.T) {
+	t.Parallel()
+
+	var opts []k8snode.ContainerdOption
+	phase := NewContainerd().WithEnsureFunc(func(_ k8snode.Runner, o ...k8snode.ContainerdOption) (*k8snode.ContainerdResult, error) {
+		opts = o
+		return &k8snode.ContainerdResult{Installed: true, Version: "1.7.24"}, nil
+	})
+	ctx := elevatedContext()
+	phases.SetInput(ctx, PhaseIDContainerd, InputContainerdPackage, "containerd.io")
+
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Len(t, opts, 1)
+	result, ok := ctx.Get(ContextKeyContainerd)
+	require.True(t, ok)
+	require.Equal(t, "1.7.24", result.(*k8snode.ContainerdResult).Version)
+	require.Contains(t, phase.Metadata().Tags, Tag)
+}
@@ -0,0 +1,86 @@
+package phases
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Key names a Context value of type T, so phases share values with
+// compile-time checked types instead of asserting on Get's result. Declare
+// keys beside the string constants they type:
+//
+//	var KeySSHClient = phases.NewKey[*ssh.Client](ContextKeySSHClient)
+//
+// Values stored with Context.Set under the same name are visible through the
+// key, and the other way round.
+type Key[T any] struct {
+	name   string
+	secret bool
+}
+
+// NewKey returns the key for the context entry name holding a T.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// NewSecretKey is NewKey for entries holding secrets such as passwords.
+// SetContext marks such entries with Context.MarkSecret, so Export seals or
+// omits them without callers listing them.
+func NewSecretKey[T any](name string) Key[T] {
+	return Key[T]{name: name, secret: true}
+}
+
+// Secret reports whether the key was declared with NewSecretKey.
+func (k Key[T]) Secret() bool {
+	return k.secret
+}
+
+// Name returns the context entry the key refers to.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+func (k Key[T]) String() string {
+	return k.name
+}
+
+// SetContext stores value under key, marking the entry secret for keys
+// declared with NewSecretKey.
+func SetContext[T any](ctx *Context, key Key[T], value T) {
+	if key.secret {
+		ctx.MarkSecret(key.name)
+	}
+	ctx.Set(key.name, value)
+}
+
+// GetContext returns the value under key. It reports false when the entry
+// is missing or holds a value of another type.
+func GetContext[T any](ctx *Context, key Key[T]) (T, bool) {
+	val, ok := ctx.Get(key.name)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	typed, ok := val.(T)
+	return typed, ok
+}
+
+// WatchContext calls fn each time a T is set under key; see Context.Watch.
+// Values of another type stored under the same name are ignored.
+func WatchContext[T any](ctx *Context, key Key[T], fn func(T)) (cancel func()) {
+	return ctx.Watch(key.name, func(val any) {
+		if typed, ok := val.(T); ok {
+			fn(typed)
+		}
+	})
+}
+
+// MustGetContext returns the value under key or panics when it is missing or
+// of another type.
+func MustGetContext[T any](ctx *Context, key Key[T]) T {
+	val, ok := GetContext(ctx, key)
+	if !ok {
+		panic(fmt.Sprintf("phases: missing context key %s of type %s", key.name, reflect.TypeFor[T]()))
+	}
+	return val
+}
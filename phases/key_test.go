@@ -0,0 +1,36 @@
+package phases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type widget struct{ name string }
+
+func TestKeyStoresTypedValues(t *testing.T) {
+	t.Parallel()
+
+	key := NewKey[*widget]("test:widget")
+	ctx := NewContext()
+
+	_, ok := GetContext(ctx, key)
+	require.False(t, ok)
+	require.PanicsWithValue(t, "phases: missing context key test:widget of type *phases.widget", func() { MustGetContext(ctx, key) })
+
+	SetContext(ctx, key, &widget{name: "gear"})
+	got, ok := GetContext(ctx, key)
+	require.True(t, ok)
+	require.Equal(t, "gear", got.name)
+
+	raw, ok := ctx.Get(key.Name())
+	require.True(t, ok)
+	require.Same(t, got, raw)
+
+	ctx.Set(key.Name(), "not a widget")
+	_, ok = GetContext(ctx, key)
+	require.False(t, ok)
+
+	_, ok = GetContext(nil, key)
+	require.False(t, ok)
+}
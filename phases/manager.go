@@ -3,6 +3,7 @@ package phases
 import (
 	"context"
 	"errors"
+	"sync"
 )
 
 // Manager coordinates the ordered execution of phases.
@@ -10,8 +11,22 @@ type Manager struct {
 	phases       []Phase
 	observers    []Observer
 	inputHandler InputHandler
+	beforeHooks  []BeforePhaseHook
+	afterHooks   []AfterPhaseHook
+	middleware   []PhaseMiddleware
+	// mu guards completed, which runs and Rollback may touch concurrently.
+	mu sync.Mutex
+	// completed holds the unwrapped phases that succeeded, in run order,
+	// for Rollback.
+	completed []Phase
 }
 
+// BeforePhaseHook runs before each phase; returning an error fails the phase without running it.
+type BeforePhaseHook func(meta PhaseMetadata, phaseCtx *Context) error
+
+// AfterPhaseHook runs after each phase with the phase's result; returning an error fails an otherwise successful phase.
+type AfterPhaseHook func(meta PhaseMetadata, phaseCtx *Context, err error) error
+
 // ManagerOption mutates manager configuration.
 type ManagerOption func(*Manager)
 
@@ -35,6 +50,38 @@ func WithInputHandler(handler InputHandler) ManagerOption {
 	}
 }
 
+// WithBeforePhase registers a hook invoked before every phase, in registration order.
+func WithBeforePhase(hook BeforePhaseHook) ManagerOption {
+	return func(m *Manager) {
+		if hook == nil {
+			return
+		}
+		m.beforeHooks = append(m.beforeHooks, hook)
+	}
+}
+
+// WithAfterPhase registers a hook invoked after every phase, in registration order.
+func WithAfterPhase(hook AfterPhaseHook) ManagerOption {
+	return func(m *Manager) {
+		if hook == nil {
+			return
+		}
+		m.afterHooks = append(m.afterHooks, hook)
+	}
+}
+
+// WithMiddleware wraps every registered phase with the given middleware at run
+// time. The first middleware supplied is the outermost wrapper.
+func WithMiddleware(mw ...PhaseMiddleware) ManagerOption {
+	return func(m *Manager) {
+		for _, fn := range mw {
+			if fn != nil {
+				m.middleware = append(m.middleware, fn)
+			}
+		}
+	}
+}
+
 // NewManager constructs an empty Manager.
 func NewManager(opts ...ManagerOption) *Manager {
 	m := &Manager{}
@@ -65,6 +112,15 @@ func (m *Manager) Register(phases ...Phase) error {
 	return nil
 }
 
+// Metadata returns the metadata of every registered phase in execution order.
+func (m *Manager) Metadata() []PhaseMetadata {
+	metas := make([]PhaseMetadata, 0, len(m.phases))
+	for _, p := range m.phases {
+		metas = append(metas, p.Metadata())
+	}
+	return metas
+}
+
 // Run executes all registered phases sequentially.
 func (m *Manager) Run(ctx context.Context, phaseCtx *Context) error {
 	return m.runFrom(ctx, phaseCtx, 0)
@@ -81,26 +137,78 @@ func (m *Manager) RunFrom(ctx context.Context, phaseCtx *Context, start int) err
 	return m.runFrom(ctx, phaseCtx, start)
 }
 
+// RunOnly executes just the phases named by ids, in their registered order,
+// e.g. to re-run ssh_connection and sudo_ensure without a new manager. The
+// phases they Require are not added, so earlier values they read must
+// already be in phaseCtx.
+func (m *Manager) RunOnly(ctx context.Context, phaseCtx *Context, ids ...string) error {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !m.hasPhase(id) {
+			return UnknownPhaseError{ID: id}
+		}
+		selected[id] = true
+	}
+	list := make([]Phase, 0, len(selected))
+	for _, p := range m.phases {
+		if selected[p.Metadata().ID] {
+			list = append(list, p)
+		}
+	}
+	return m.runList(ctx, phaseCtx, list)
+}
+
+// RunUntil executes phases from the first through the phase with id,
+// inclusive, and stops there.
+func (m *Manager) RunUntil(ctx context.Context, phaseCtx *Context, id string) error {
+	for i, p := range m.phases {
+		if p.Metadata().ID == id {
+			return m.runList(ctx, phaseCtx, m.phases[:i+1])
+		}
+	}
+	return UnknownPhaseError{ID: id}
+}
+
 func (m *Manager) runFrom(ctx context.Context, phaseCtx *Context, start int) error {
+	return m.runList(ctx, phaseCtx, m.phases[start:])
+}
+
+func (m *Manager) runList(ctx context.Context, phaseCtx *Context, list []Phase) error {
 	if phaseCtx == nil {
 		phaseCtx = NewContext()
 	}
-	for i := start; i < len(m.phases); i++ {
-		phase := m.phases[i]
+	// Coerce answers and keep secret ones out of context snapshots.
+	phaseCtx.DefineInputs(m.Metadata()...)
+	err := m.runPhases(ctx, phaseCtx, list)
+	m.notifyPipeline(phaseCtx, err)
+	return err
+}
+
+func (m *Manager) runPhases(ctx context.Context, phaseCtx *Context, list []Phase) error {
+	for _, registered := range list {
+		phase := m.wrap(registered)
 		meta := phase.Metadata()
 		m.notifyStart(meta)
-		err := m.executePhase(ctx, phaseCtx, phase, meta)
+		err := m.runBeforeHooks(meta, phaseCtx)
+		if err == nil {
+			err = m.executePhase(ctx, phaseCtx, phase, meta)
+		}
+		err = m.runAfterHooks(meta, phaseCtx, err)
 		m.notifyComplete(meta, err)
 		if err != nil {
 			return PhaseExecutionError{Phase: meta, Err: err}
 		}
+		m.markCompleted(registered)
 	}
 	return nil
 }
 
 func (m *Manager) executePhase(ctx context.Context, phaseCtx *Context, phase Phase, meta PhaseMetadata) error {
+	ctx = withCommandHook(ctx, meta, m.observers)
+	ctx = withTaskHook(ctx, meta, m.observers)
 	for {
-		err := phase.Run(ctx, phaseCtx)
+		runCtx, cleanup := withCleanupScope(ctx)
+		err := cleanup.run(phase.Run(runCtx, phaseCtx))
 		if err == nil {
 			return nil
 		}
@@ -109,10 +217,12 @@ func (m *Manager) executePhase(ctx context.Context, phaseCtx *Context, phase Pha
 			if m.inputHandler == nil {
 				return err
 			}
-			value, handlerErr := m.inputHandler.RequestInput(meta, inputErr.Input, inputErr.Reason)
+			value, handlerErr := m.requestInput(meta, inputErr)
 			if handlerErr != nil {
 				return handlerErr
 			}
+			// Inputs requested on the fly may not be in the metadata.
+			phaseCtx.defineInput(inputErr.PhaseID, inputErr.Input)
 			SetInput(phaseCtx, inputErr.PhaseID, inputErr.Input.ID, value)
 			continue
 		}
@@ -120,6 +230,61 @@ func (m *Manager) executePhase(ctx context.Context, phaseCtx *Context, phase Pha
 	}
 }
 
+// maxInputAttempts bounds how often requestInput asks for one input, so a
+// handler that keeps answering the same invalid value cannot loop forever.
+const maxInputAttempts = 3
+
+// requestInput asks the handler for a value and normalizes it with
+// CoerceInput, asking again with the coercion error as the reason until the
+// handler supplies an acceptable value, fails, or runs out of attempts.
+func (m *Manager) requestInput(meta PhaseMetadata, inputErr InputRequestError) (string, error) {
+	reason := inputErr.Reason
+	for range maxInputAttempts {
+		m.notifyInput(meta, inputErr.Input, reason)
+		raw, err := m.inputHandler.RequestInput(meta, inputErr.Input, reason)
+		if err != nil {
+			return "", err
+		}
+		value, err := CoerceInput(inputErr.Input, raw)
+		if err == nil {
+			return value, nil
+		}
+		reason = err.Error()
+	}
+	return "", InputValidationError{Problems: []InputProblem{{
+		PhaseID: inputErr.PhaseID,
+		InputID: inputErr.Input.ID,
+		Reason:  reason,
+	}}}
+}
+
+func (m *Manager) wrap(phase Phase) Phase {
+	for i := len(m.middleware) - 1; i >= 0; i-- {
+		if wrapped := m.middleware[i](phase); wrapped != nil {
+			phase = wrapped
+		}
+	}
+	return phase
+}
+
+func (m *Manager) runBeforeHooks(meta PhaseMetadata, phaseCtx *Context) error {
+	for _, hook := range m.beforeHooks {
+		if err := hook(meta, phaseCtx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) runAfterHooks(meta PhaseMetadata, phaseCtx *Context, err error) error {
+	for _, hook := range m.afterHooks {
+		if hookErr := hook(meta, phaseCtx, err); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	return err
+}
+
 func (m *Manager) hasPhase(id string) bool {
 	for _, p := range m.phases {
 		if p.Metadata().ID == id {
@@ -140,3 +305,19 @@ func (m *Manager) notifyComplete(meta PhaseMetadata, err error) {
 		obs.PhaseCompleted(meta, err)
 	}
 }
+
+func (m *Manager) notifyInput(meta PhaseMetadata, input InputDefinition, reason string) {
+	for _, obs := range m.observers {
+		if inputObs, ok := obs.(InputObserver); ok {
+			inputObs.InputRequested(meta, input, reason)
+		}
+	}
+}
+
+func (m *Manager) notifyPipeline(phaseCtx *Context, err error) {
+	for _, obs := range m.observers {
+		if pipelineObs, ok := obs.(PipelineObserver); ok {
+			pipelineObs.PipelineCompleted(phaseCtx, err)
+		}
+	}
+}
@@ -142,6 +142,63 @@ func TestManagerHandlesInputRequest(t *testing.T) {
 	require.Equal(t, 1, handlerCalls)
 }
 
+func TestManagerRerequestsInvalidSelectValues(t *testing.T) {
+	t.Parallel()
+
+	def := InputDefinition{ID: "auth", Kind: InputKindSelect, Options: []InputOption{{Value: "password"}, {Value: "key"}}}
+	phase := &fakePhase{
+		meta: PhaseMetadata{ID: "ssh"},
+		run: func(_ context.Context, c *Context) error {
+			if val, ok := GetInput(c, "ssh", "auth"); ok {
+				require.Equal(t, "key", val)
+				return nil
+			}
+			return InputRequestError{PhaseID: "ssh", Input: def}
+		},
+	}
+
+	answers := []any{"kerberos", " key "}
+	var reasons []string
+	handler := InputHandlerFunc(func(_ PhaseMetadata, _ InputDefinition, reason string) (any, error) {
+		reasons = append(reasons, reason)
+		answer := answers[0]
+		answers = answers[1:]
+		return answer, nil
+	})
+
+	manager := NewManager(WithInputHandler(handler))
+	require.NoError(t, manager.Register(phase))
+	require.NoError(t, manager.Run(context.Background(), NewContext()))
+	require.Len(t, reasons, 2)
+	require.Contains(t, reasons[1], "not one of")
+}
+
+func TestManagerStopsAskingAfterRepeatedInvalidInput(t *testing.T) {
+	t.Parallel()
+
+	def := InputDefinition{ID: "auth", Kind: InputKindSelect, Options: []InputOption{{Value: "key"}}}
+	phase := &fakePhase{
+		meta: PhaseMetadata{ID: "ssh"},
+		run: func(context.Context, *Context) error {
+			return InputRequestError{PhaseID: "ssh", Input: def}
+		},
+	}
+
+	calls := 0
+	handler := InputHandlerFunc(func(PhaseMetadata, InputDefinition, string) (any, error) {
+		calls++
+		return "kerberos", nil
+	})
+
+	manager := NewManager(WithInputHandler(handler))
+	require.NoError(t, manager.Register(phase))
+	err := manager.Run(context.Background(), NewContext())
+	var validationErr InputValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "auth", validationErr.Problems[0].InputID)
+	require.Equal(t, maxInputAttempts, calls)
+}
+
 func TestManagerInputHandlerError(t *testing.T) {
 	t.Parallel()
 
@@ -194,6 +251,171 @@ func TestManagerPropagatesInputRequestWithoutHandler(t *testing.T) {
 	require.ErrorAs(t, execErr.Err, &inputErr)
 }
 
+func TestManagerRunsPhaseHooks(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	manager := NewManager(
+		WithBeforePhase(func(meta PhaseMetadata, _ *Context) error {
+			order = append(order, "before:"+meta.ID)
+			return nil
+		}),
+		WithAfterPhase(func(meta PhaseMetadata, _ *Context, err error) error {
+			order = append(order, fmt.Sprintf("after:%s:%v", meta.ID, err))
+			return nil
+		}),
+	)
+	require.NoError(t, manager.Register(&fakePhase{
+		meta: PhaseMetadata{ID: "ssh"},
+		run: func(context.Context, *Context) error {
+			order = append(order, "run:ssh")
+			return nil
+		},
+	}))
+	require.NoError(t, manager.Run(context.Background(), nil))
+	require.Equal(t, []string{"before:ssh", "run:ssh", "after:ssh:<nil>"}, order)
+}
+
+func TestManagerHookErrorsFailPhase(t *testing.T) {
+	t.Parallel()
+
+	hookErr := errors.New("missing ssh client")
+	ran := false
+	manager := NewManager(WithBeforePhase(func(PhaseMetadata, *Context) error { return hookErr }))
+	require.NoError(t, manager.Register(&fakePhase{
+		meta: PhaseMetadata{ID: "sudo"},
+		run: func(context.Context, *Context) error {
+			ran = true
+			return nil
+		},
+	}))
+	err := manager.Run(context.Background(), nil)
+	require.ErrorIs(t, err, hookErr)
+	require.False(t, ran)
+
+	afterErr := errors.New("post-check failed")
+	manager = NewManager(WithAfterPhase(func(PhaseMetadata, *Context, error) error { return afterErr }))
+	require.NoError(t, manager.Register(&fakePhase{
+		meta: PhaseMetadata{ID: "sudo"},
+		run:  func(context.Context, *Context) error { return nil },
+	}))
+	require.ErrorIs(t, manager.Run(context.Background(), nil), afterErr)
+}
+
+func TestManagerAppliesMiddlewareInOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	trace := func(name string) PhaseMiddleware {
+		return func(next Phase) Phase {
+			return WrapRun(next, func(ctx context.Context, phaseCtx *Context) error {
+				order = append(order, name+":"+next.Metadata().ID)
+				return next.Run(ctx, phaseCtx)
+			})
+		}
+	}
+
+	manager := NewManager(WithMiddleware(trace("outer"), trace("inner")))
+	require.NoError(t, manager.Register(&fakePhase{
+		meta: PhaseMetadata{ID: "ssh"},
+		run: func(context.Context, *Context) error {
+			order = append(order, "run:ssh")
+			return nil
+		},
+	}))
+	require.NoError(t, manager.Run(context.Background(), nil))
+	require.Equal(t, []string{"outer:ssh", "inner:ssh", "run:ssh"}, order)
+}
+
+func TestManagerReportsTaskProgress(t *testing.T) {
+	t.Parallel()
+
+	phase := &fakePhase{
+		meta: PhaseMetadata{ID: "playbook"},
+		run: func(ctx context.Context, _ *Context) error {
+			ReportTask(ctx, TaskEvent{Task: "Install nginx", Status: TaskStarted})
+			ReportTask(ctx, TaskEvent{Task: "Install nginx", Host: "web01", Status: TaskChanged})
+			return nil
+		},
+	}
+
+	observer := &taskRecorder{}
+	manager := NewManager(WithObserver(observer), WithObserver(ObserverFunc{}))
+	require.NoError(t, manager.Register(phase))
+	require.NoError(t, manager.Run(context.Background(), nil))
+
+	require.Equal(t, []TaskEvent{
+		{Task: "Install nginx", Status: TaskStarted},
+		{Task: "Install nginx", Host: "web01", Status: TaskChanged},
+	}, observer.events)
+	require.Equal(t, "playbook", observer.phaseID)
+
+	// Without a listening observer reporting is a no-op.
+	ReportTask(context.Background(), TaskEvent{Task: "ignored"})
+}
+
+func TestManagerRunsPhaseCleanups(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		runErr     error
+		cleanupErr error
+		wantErr    string
+	}{
+		{name: "success"},
+		{name: "phase failure still cleans up", runErr: errors.New("boom"), wantErr: "boom"},
+		{name: "cleanup failure fails the phase", cleanupErr: errors.New("rm failed"), wantErr: "phase cleanup failed: rm failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var order []string
+			phase := &fakePhase{
+				meta: PhaseMetadata{ID: "upload"},
+				run: func(ctx context.Context, _ *Context) error {
+					require.True(t, AddCleanup(ctx, func() error {
+						order = append(order, "first")
+						return tt.cleanupErr
+					}))
+					require.True(t, AddCleanup(ctx, func() error {
+						order = append(order, "second")
+						return nil
+					}))
+					return tt.runErr
+				},
+			}
+
+			manager := NewManager()
+			require.NoError(t, manager.Register(phase))
+			err := manager.Run(context.Background(), nil)
+			require.Equal(t, []string{"second", "first"}, order)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			if tt.runErr != nil {
+				require.ErrorIs(t, err, tt.runErr)
+			}
+		})
+	}
+
+	require.False(t, AddCleanup(context.Background(), func() error { return nil }))
+}
+
+type taskRecorder struct {
+	ObserverFunc
+	phaseID string
+	events  []TaskEvent
+}
+
+func (r *taskRecorder) TaskProgress(meta PhaseMetadata, event TaskEvent) {
+	r.phaseID = meta.ID
+	r.events = append(r.events, event)
+}
+
 type fakePhase struct {
 	meta PhaseMetadata
 	run  func(context.Context, *Context) error
@@ -224,3 +446,34 @@ func (o ObserverFunc) PhaseCompleted(meta PhaseMetadata, err error) {
 		o.OnComplete(meta, err)
 	}
 }
+
+func TestManagerRunsSelectedPhases(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	record := func(id string) *fakePhase {
+		return &fakePhase{
+			meta: PhaseMetadata{ID: id},
+			run: func(context.Context, *Context) error {
+				order = append(order, id)
+				return nil
+			},
+		}
+	}
+	manager := NewManager()
+	require.NoError(t, manager.Register(record("ssh_connection"), record("sudo_ensure"), record("os_detect"), record("ansible_user")))
+
+	require.NoError(t, manager.RunOnly(context.Background(), NewContext(), "os_detect", "ssh_connection"))
+	require.Equal(t, []string{"ssh_connection", "os_detect"}, order)
+
+	order = nil
+	require.NoError(t, manager.RunUntil(context.Background(), NewContext(), "sudo_ensure"))
+	require.Equal(t, []string{"ssh_connection", "sudo_ensure"}, order)
+
+	order = nil
+	var unknown UnknownPhaseError
+	require.ErrorAs(t, manager.RunOnly(context.Background(), nil, "ssh_connection", "nope"), &unknown)
+	require.Equal(t, "nope", unknown.ID)
+	require.ErrorAs(t, manager.RunUntil(context.Background(), nil, "nope"), &unknown)
+	require.Empty(t, order)
+}
@@ -0,0 +1,26 @@
+package phases
+
+import "context"
+
+// PhaseMiddleware wraps a phase with reusable behavior such as retries,
+// timing, or logging. Middleware must preserve the wrapped phase's metadata.
+type PhaseMiddleware func(Phase) Phase
+
+// WrapRun returns a Phase that reports next's metadata but executes run, for
+// middleware that only needs to decorate Run.
+func WrapRun(next Phase, run func(ctx context.Context, phaseCtx *Context) error) Phase {
+	return runWrapper{next: next, run: run}
+}
+
+type runWrapper struct {
+	next Phase
+	run  func(ctx context.Context, phaseCtx *Context) error
+}
+
+func (w runWrapper) Metadata() PhaseMetadata {
+	return w.next.Metadata()
+}
+
+func (w runWrapper) Run(ctx context.Context, phaseCtx *Context) error {
+	return w.run(ctx, phaseCtx)
+}
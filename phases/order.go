@@ -0,0 +1,84 @@
+package phases
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrderError reports a phase order that names unknown or missing phases, or
+// that runs a phase before one it requires.
+type OrderError struct {
+	Problems []string
+}
+
+func (e OrderError) Error() string {
+	return "invalid phase order: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateOrder checks that every phase runs after the phases listed in its
+// Requires. Required phases that are not in metas are ignored, so embedders
+// can leave out phases they replace.
+func ValidateOrder(metas []PhaseMetadata) error {
+	position := make(map[string]int, len(metas))
+	for i, meta := range metas {
+		position[meta.ID] = i
+	}
+	var problems []string
+	for i, meta := range metas {
+		for _, required := range meta.Requires {
+			if at, ok := position[required]; ok && at > i {
+				problems = append(problems, fmt.Sprintf("%s must run after %s", meta.ID, required))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return OrderError{Problems: problems}
+	}
+	return nil
+}
+
+// OrderPhases returns list rearranged to follow ids, which must name every
+// phase exactly once, and checks the result with ValidateOrder. An empty ids
+// keeps list as it is.
+func OrderPhases(list []Phase, ids []string) ([]Phase, error) {
+	if len(ids) == 0 {
+		return list, nil
+	}
+	byID := make(map[string]Phase, len(list))
+	for _, phase := range list {
+		byID[phase.Metadata().ID] = phase
+	}
+
+	var problems []string
+	ordered := make([]Phase, 0, len(list))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		phase, ok := byID[id]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown phase %q", id))
+		case seen[id]:
+			problems = append(problems, fmt.Sprintf("phase %q listed twice", id))
+		default:
+			ordered = append(ordered, phase)
+		}
+		seen[id] = true
+	}
+	for _, phase := range list {
+		if id := phase.Metadata().ID; !seen[id] {
+			problems = append(problems, fmt.Sprintf("phase %q missing from the order", id))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, OrderError{Problems: problems}
+	}
+
+	metas := make([]PhaseMetadata, 0, len(ordered))
+	for _, phase := range ordered {
+		metas = append(metas, phase.Metadata())
+	}
+	if err := ValidateOrder(metas); err != nil {
+		return nil, err
+	}
+	return ordered, nil
+}
@@ -0,0 +1,69 @@
+package phases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderPhases(t *testing.T) {
+	t.Parallel()
+
+	phase := func(id string, requires ...string) Phase {
+		return &fakePhase{meta: PhaseMetadata{ID: id, Requires: requires}}
+	}
+	list := []Phase{
+		phase("ssh"),
+		phase("sudo", "ssh"),
+		phase("user", "sudo"),
+		phase("hostname", "sudo"),
+		phase("timesync", "sudo", "ntp"),
+	}
+
+	tests := []struct {
+		name    string
+		ids     []string
+		want    []string
+		wantErr []string
+	}{
+		{name: "empty keeps registration order", want: []string{"ssh", "sudo", "user", "hostname", "timesync"}},
+		{
+			name: "interleaves after dependencies",
+			ids:  []string{"ssh", "sudo", "hostname", "timesync", "user"},
+			want: []string{"ssh", "sudo", "hostname", "timesync", "user"},
+		},
+		{
+			name:    "dependency runs later",
+			ids:     []string{"ssh", "hostname", "sudo", "timesync", "user"},
+			wantErr: []string{"hostname must run after sudo"},
+		},
+		{
+			name: "unknown, repeated, and missing phases",
+			ids:  []string{"ssh", "sudo", "sudo", "chrony", "user", "hostname"},
+			wantErr: []string{
+				`phase "sudo" listed twice`,
+				`unknown phase "chrony"`,
+				`phase "timesync" missing from the order`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ordered, err := OrderPhases(list, tt.ids)
+			if tt.wantErr != nil {
+				var orderErr OrderError
+				require.ErrorAs(t, err, &orderErr)
+				require.Equal(t, tt.wantErr, orderErr.Problems)
+				return
+			}
+			require.NoError(t, err)
+			var ids []string
+			for _, p := range ordered {
+				ids = append(ids, p.Metadata().ID)
+			}
+			require.Equal(t, tt.want, ids)
+		})
+	}
+}
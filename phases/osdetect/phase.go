@@ -0,0 +1,89 @@
+package osdetect
+
+import (
+	"context"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/osdetect"
+)
+
+const (
+	// PhaseID identifies the OS detection phase.
+	PhaseID = "os_detect"
+
+	// ContextKeyInfo holds everything detected about the target
+	// (*osdetect.Info).
+	ContextKeyInfo = "host:os"
+	// ContextKeyDistro is the os-release ID, e.g. "ubuntu" or "alpine".
+	ContextKeyDistro = "host:distro"
+	// ContextKeyVersion is the distribution version, e.g. "24.04".
+	ContextKeyVersion = "host:version"
+	// ContextKeyArch records the target architecture as one of the
+	// pkginstaller.Arch constants. It is only set for supported
+	// architectures.
+	ContextKeyArch = "host:arch"
+)
+
+// KeyInfo is the typed key for ContextKeyInfo.
+var KeyInfo = phases.NewKey[*osdetect.Info](ContextKeyInfo)
+
+// DetectFunc wraps osdetect.Detect for dependency injection.
+type DetectFunc func(r osdetect.Runner) (*osdetect.Info, error)
+
+// Phase identifies the target operating system so later phases can branch on
+// the distribution, version, and architecture.
+type Phase struct {
+	detect DetectFunc
+}
+
+// New creates an OS detection phase.
+func New() *Phase {
+	return &Phase{detect: osdetect.Detect}
+}
+
+// WithDetectFunc overrides how the target is inspected (for tests).
+func (p *Phase) WithDetectFunc(fn DetectFunc) *Phase {
+	if fn != nil {
+		p.detect = fn
+	}
+	return p
+}
+
+func (p *Phase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{
+		ID:                PhaseID,
+		Title:             "Detect Operating System",
+		Requires:          []string{sudoensure.PhaseID},
+		Description:       "Read os-release, uname, and the init system so later phases know the distribution and architecture.",
+		EstimatedDuration: 3 * time.Second,
+	}
+}
+
+func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	if p.detect == nil {
+		p.detect = osdetect.Detect
+	}
+	if phaseCtx == nil {
+		phaseCtx = phases.NewContext()
+	}
+
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
+		return phases.ValidationError{Reason: "sudo phase must complete before detecting the operating system"}
+	}
+
+	info, err := p.detect(phases.NewTracedRunner(ctx, elevatedClient))
+	if err != nil {
+		return err
+	}
+
+	phases.SetContext(phaseCtx, KeyInfo, info)
+	phaseCtx.Set(ContextKeyDistro, info.ID)
+	phaseCtx.Set(ContextKeyVersion, info.Version)
+	if info.Arch != "" {
+		phaseCtx.Set(ContextKeyArch, info.Arch)
+	}
+	return nil
+}
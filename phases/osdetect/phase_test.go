@@ -0,0 +1,70 @@
+package osdetect
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/osdetect"
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+)
+
+func TestPhaseStoresDetectedOS(t *testing.T) {
+	t.Parallel()
+
+	info := &osdetect.Info{Kernel: "Linux", Machine: "aarch64", Arch: pkginstaller.ArchARM64, ID: "debian", Version: "12"}
+	phase := New().WithDetectFunc(func(r osdetect.Runner) (*osdetect.Info, error) {
+		require.IsType(t, &phases.TracedRunner{}, r)
+		return info, nil
+	})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	require.NoError(t, phase.Run(context.Background(), ctx))
+
+	got, _ := ctx.Get(ContextKeyInfo)
+	require.Same(t, info, got)
+	distro, _ := ctx.Get(ContextKeyDistro)
+	require.Equal(t, "debian", distro)
+	version, _ := ctx.Get(ContextKeyVersion)
+	require.Equal(t, "12", version)
+	arch, _ := ctx.Get(ContextKeyArch)
+	require.Equal(t, pkginstaller.ArchARM64, arch)
+}
+
+func TestPhaseLeavesUnsupportedArchUnset(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithDetectFunc(func(osdetect.Runner) (*osdetect.Info, error) {
+		return &osdetect.Info{Kernel: "OpenBSD", Machine: "sparc64", ID: "openbsd", Version: "7.5"}, nil
+	})
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	require.NoError(t, phase.Run(context.Background(), ctx))
+
+	_, ok := ctx.Get(ContextKeyArch)
+	require.False(t, ok)
+}
+
+func TestPhaseErrors(t *testing.T) {
+	t.Parallel()
+
+	err := New().Run(context.Background(), phases.NewContext())
+	var valErr phases.ValidationError
+	require.ErrorAs(t, err, &valErr)
+
+	boom := errors.New("connection lost")
+	phase := New().WithDetectFunc(func(osdetect.Runner) (*osdetect.Info, error) {
+		return nil, boom
+	})
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	require.ErrorIs(t, phase.Run(context.Background(), ctx), boom)
+	_, ok := ctx.Get(ContextKeyInfo)
+	require.False(t, ok)
+}
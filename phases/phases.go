@@ -1,6 +1,9 @@
 package phases
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Phase represents a single unit of work in the bootstrap pipeline.
 type Phase interface {
@@ -15,6 +18,13 @@ type PhaseMetadata struct {
 	Description string
 	Inputs      []InputDefinition
 	Tags        []string
+	// Requires lists the IDs of phases that must run before this one when
+	// they are registered; see ValidateOrder.
+	Requires []string
+	// EstimatedDuration is roughly how long the phase takes on a typical
+	// host. It weights the phase in pipeline progress (see Progress); zero
+	// means DefaultEstimatedDuration.
+	EstimatedDuration time.Duration
 }
 
 // Observer receives lifecycle callbacks for each phase.
@@ -23,6 +33,18 @@ type Observer interface {
 	PhaseCompleted(meta PhaseMetadata, err error)
 }
 
+// InputObserver is optionally implemented by observers that want to know when
+// the manager asks the input handler for a value.
+type InputObserver interface {
+	InputRequested(meta PhaseMetadata, input InputDefinition, reason string)
+}
+
+// PipelineObserver is optionally implemented by observers that want to know
+// when a Run/RunFrom call finishes, successfully or not.
+type PipelineObserver interface {
+	PipelineCompleted(phaseCtx *Context, err error)
+}
+
 // InputDefinition describes data a phase requires from the operator/UI.
 type InputDefinition struct {
 	ID          string
@@ -33,6 +55,11 @@ type InputDefinition struct {
 	Secret      bool
 	Options     []InputOption
 	Default     any
+	// ProvidedBy lists the IDs of phases that supply this input's value
+	// through the context when they run first, e.g. the target host found
+	// by the SSH phase. A required input need not be supplied up front when
+	// one of them runs earlier in the pipeline; see ValidateInputs.
+	ProvidedBy []string
 }
 
 // InputKind identifies how an input should be rendered.
@@ -2,31 +2,43 @@ package playbook
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
 	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
 	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
 	ansiblepb "github.com/BrianJOC/ansible-host-prep/utils/ansibleplaybook"
-	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
-	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
 )
 
 const (
 	defaultPhaseID = "ansible_playbook"
+	// defaultEstimatedDuration weights the phase in pipeline progress when
+	// Config.EstimatedDuration is zero; playbooks dominate most runs.
+	defaultEstimatedDuration = 5 * time.Minute
 
 	// Input identifiers.
 	InputTargetHost     = "target_host"
 	InputAnsibleUser    = "ansible_user"
 	InputPrivateKeyPath = "private_key_path"
 	InputPlaybookPath   = "playbook_path"
+	InputVerbosity      = "verbosity"
+	InputInventoryPath  = "inventory_path"
+	InputBecomePassword = "become_password"
+	InputForks          = "forks"
+	InputStrategy       = "strategy"
 
 	// Context keys for sharing resolved values.
 	ContextKeyTargetHost     = "playbook:target_host"
 	ContextKeyAnsibleUser    = "playbook:ansible_user"
 	ContextKeyPrivateKeyPath = "playbook:key_path"
 	ContextKeyPlaybookPath   = "playbook:path"
+	ContextKeyInventoryPath  = "playbook:inventory_path"
+	ContextKeyPlaybooks      = "playbook:playbooks"
 )
 
 // Runner executes the ansible playbook.
@@ -38,16 +50,42 @@ type Config struct {
 	Title        string
 	Description  string
 	PlaybookPath string
-	Tags         []string
-	Options      []ansiblepb.Option
+	// Playbooks run in order after PlaybookPath within this one phase (e.g.
+	// pre-tasks, site, verify). The run stops at the first failing playbook
+	// unless ansibleplaybook.WithContinueOnError is among Options, and the
+	// error names every playbook that failed.
+	Playbooks []string
+	// InventoryPath points at an existing inventory (file or directory) so
+	// its groups and group_vars apply; the target host becomes --limit.
+	// Empty means an inline inventory, unless the inventory_path input is set.
+	InventoryPath string
+	Tags          []string
+	Options       []ansiblepb.Option
+	// RawCommands run through ansible.builtin.raw in a generated playbook
+	// instead of PlaybookPath when pythonensure left the target without
+	// python (raw mode). Without them such targets fail with a clear error,
+	// since regular modules need python on the target.
+	RawCommands []string
+	// TaskProgress reports each task start and per-host result to task
+	// observers (see phases.TaskObserver) as the playbook runs. With the
+	// default backend it needs the ansible.posix collection on the control
+	// node for its jsonl callback.
+	TaskProgress bool
+	// EstimatedDuration is roughly how long the playbooks take, which
+	// weights the phase in pipeline progress (default five minutes).
+	EstimatedDuration time.Duration
 }
 
 // Phase coordinates collecting target/user/key details and running an ansible playbook.
 type Phase struct {
-	meta         phases.PhaseMetadata
-	playbookPath string
-	options      []ansiblepb.Option
-	run          Runner
+	meta          phases.PhaseMetadata
+	playbookPath  string
+	playbooks     []string
+	inventoryPath string
+	rawCommands   []string
+	taskProgress  bool
+	options       []ansiblepb.Option
+	run           Runner
 }
 
 // New constructs a reusable ansible playbook phase based on the provided config.
@@ -62,29 +100,45 @@ func New(cfg Config) *Phase {
 		title = "Run Ansible Playbook"
 	}
 
+	playbookPath := strings.TrimSpace(cfg.PlaybookPath)
+	var playbooks []string
+	for _, path := range cfg.Playbooks {
+		if path = strings.TrimSpace(path); path != "" {
+			playbooks = append(playbooks, path)
+		}
+	}
+
 	desc := strings.TrimSpace(cfg.Description)
 	if desc == "" {
 		desc = "Execute an Ansible playbook against the target host."
-		if cfg.PlaybookPath != "" {
-			desc = fmt.Sprintf("Execute %s against the target host.", strings.TrimSpace(cfg.PlaybookPath))
+		if configured := configuredPlaybooks(playbookPath, playbooks); len(configured) > 0 {
+			desc = fmt.Sprintf("Execute %s against the target host.", strings.Join(configured, ", "))
 		}
 	}
 
-	playbookPath := strings.TrimSpace(cfg.PlaybookPath)
+	estimate := cfg.EstimatedDuration
+	if estimate <= 0 {
+		estimate = defaultEstimatedDuration
+	}
 
 	meta := phases.PhaseMetadata{
-		ID:          id,
-		Title:       title,
-		Description: desc,
-		Inputs:      inputDefinitions(playbookPath == ""),
-		Tags:        append([]string{}, cfg.Tags...),
+		ID:                id,
+		Title:             title,
+		Description:       desc,
+		Inputs:            inputDefinitions(playbookPath == "" && len(playbooks) == 0, strings.TrimSpace(cfg.InventoryPath) == ""),
+		Tags:              append([]string{}, cfg.Tags...),
+		EstimatedDuration: estimate,
 	}
 
 	return &Phase{
-		meta:         meta,
-		playbookPath: playbookPath,
-		options:      append([]ansiblepb.Option{}, cfg.Options...),
-		run:          ansiblepb.Run,
+		meta:          meta,
+		playbookPath:  playbookPath,
+		playbooks:     playbooks,
+		inventoryPath: strings.TrimSpace(cfg.InventoryPath),
+		rawCommands:   append([]string{}, cfg.RawCommands...),
+		taskProgress:  cfg.TaskProgress,
+		options:       append([]ansiblepb.Option{}, cfg.Options...),
+		run:           ansiblepb.Run,
 	}
 }
 
@@ -134,6 +188,16 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 		return err
 	}
 
+	opts, err := p.runOptions(ctx, phaseCtx, user)
+	if err != nil {
+		return err
+	}
+	inventoryPath := p.resolveInventoryPath(phaseCtx)
+
+	if rawOnly, _ := phaseCtx.Get(pythonensure.ContextKeyRawOnly); rawOnly == true {
+		return p.runRaw(ctx, phaseCtx, target, user, keyPath, inventoryPath, opts)
+	}
+
 	playbookPath, err := p.resolvePlaybookPath(phaseCtx)
 	if err != nil {
 		return err
@@ -144,9 +208,11 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 		Target:         target,
 		PlaybookPath:   playbookPath,
 		PrivateKeyPath: keyPath,
+		InventoryPath:  inventoryPath,
+		Playbooks:      p.playbooks,
 	}
 
-	if err := p.run(ctx, req, p.options...); err != nil {
+	if err := p.run(ctx, req, opts...); err != nil {
 		return fmt.Errorf("playbook phase: run ansible playbook: %w", err)
 	}
 
@@ -154,21 +220,139 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 	phaseCtx.Set(ContextKeyAnsibleUser, user)
 	phaseCtx.Set(ContextKeyPrivateKeyPath, keyPath)
 	phaseCtx.Set(ContextKeyPlaybookPath, playbookPath)
+	phaseCtx.Set(ContextKeyPlaybooks, configuredPlaybooks(playbookPath, p.playbooks))
+	if inventoryPath != "" {
+		phaseCtx.Set(ContextKeyInventoryPath, inventoryPath)
+	}
+
+	return nil
+}
+
+// runRaw runs the configured raw commands in a generated playbook, since the
+// target has no python for regular modules.
+func (p *Phase) runRaw(ctx context.Context, phaseCtx *phases.Context, target, user, keyPath, inventoryPath string, opts []ansiblepb.Option) error {
+	if len(p.rawCommands) == 0 {
+		return fmt.Errorf("playbook phase: %s has no python (raw mode) and no raw commands are configured; only ansible.builtin.raw tasks can run there", target)
+	}
+
+	file, err := os.CreateTemp("", "raw-bootstrap-*.yml")
+	if err != nil {
+		return fmt.Errorf("playbook phase: create raw bootstrap playbook: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(RawPlaybook(p.rawCommands))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("playbook phase: write raw bootstrap playbook: %w", err)
+	}
 
+	req := ansiblepb.RunRequest{
+		User:           user,
+		Target:         target,
+		PlaybookPath:   file.Name(),
+		PrivateKeyPath: keyPath,
+		InventoryPath:  inventoryPath,
+	}
+	if err := p.run(ctx, req, opts...); err != nil {
+		return fmt.Errorf("playbook phase: run raw bootstrap playbook: %w", err)
+	}
+
+	phaseCtx.Set(ContextKeyTargetHost, target)
+	phaseCtx.Set(ContextKeyAnsibleUser, user)
+	phaseCtx.Set(ContextKeyPrivateKeyPath, keyPath)
 	return nil
 }
 
+// RawPlaybook renders a playbook that runs each command with
+// ansible.builtin.raw and skips fact gathering, so it works on targets
+// without python.
+func RawPlaybook(commands []string) string {
+	var b strings.Builder
+	b.WriteString("---\n# Generated by ansible-host-prep for a target without python.\n")
+	b.WriteString("- hosts: all\n  gather_facts: false\n  tasks:\n")
+	for i, cmd := range commands {
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.raw: %s\n", yamlString(fmt.Sprintf("raw %d: %s", i+1, cmd)), yamlString(cmd))
+	}
+	return b.String()
+}
+
+// yamlString quotes s as a JSON string, which YAML accepts as a
+// double-quoted scalar.
+func yamlString(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// runOptions returns the configured options plus the verbosity, forks,
+// strategy, become password, and task progress reporting chosen for this
+// run, if any.
+func (p *Phase) runOptions(runCtx context.Context, ctx *phases.Context, user string) ([]ansiblepb.Option, error) {
+	level, ok, err := phases.GetInputInt(ctx, p.meta.ID, InputVerbosity)
+	if err != nil || (ok && (level < 0 || level > ansiblepb.MaxVerbosity)) {
+		return nil, p.inputRequestError(InputVerbosity, fmt.Sprintf("verbosity must be between 0 and %d", ansiblepb.MaxVerbosity))
+	}
+	opts := append([]ansiblepb.Option{}, p.options...)
+	if ok && level > 0 {
+		opts = append(opts, ansiblepb.WithVerbosity(level))
+	}
+	forks, ok, err := phases.GetInputInt(ctx, p.meta.ID, InputForks)
+	if err != nil || (ok && forks < 1) {
+		return nil, p.inputRequestError(InputForks, "forks must be a whole number of at least 1")
+	}
+	if ok {
+		opts = append(opts, ansiblepb.WithForks(forks))
+	}
+	if strategy, ok := phases.GetInputString(ctx, p.meta.ID, InputStrategy); ok && strategy != "" {
+		opts = append(opts, ansiblepb.WithStrategy(strategy))
+	}
+	if password := p.resolveBecomePassword(ctx, user); password != "" {
+		opts = append(opts, ansiblepb.WithBecomePassword(password))
+	}
+	if p.taskProgress {
+		opts = append(opts, ansiblepb.WithTaskEvents(func(event ansiblepb.TaskEvent) {
+			phases.ReportTask(runCtx, phases.TaskEvent{
+				Task:    event.Task,
+				Host:    event.Host,
+				Status:  phases.TaskStatus(event.Status),
+				Message: event.Message,
+			})
+		}))
+	}
+	return opts, nil
+}
+
+// resolveBecomePassword prefers the become_password input. Otherwise, when
+// the playbook connects as the SSH user rather than a NOPASSWD ansible user,
+// the SSH password (which sudoensure verified for sudo) is reused.
+func (p *Phase) resolveBecomePassword(ctx *phases.Context, user string) string {
+	if password, ok := phases.GetInputString(ctx, p.meta.ID, InputBecomePassword); ok && password != "" {
+		return password
+	}
+	if res, ok := phases.GetContext(ctx, ansibleuser.KeyUserResult); ok && res != nil && res.PasswordlessConfigured {
+		return ""
+	}
+	sshUser, _ := ctx.Get(sshconnect.ContextKeyTargetUser)
+	if sshUser != user {
+		return ""
+	}
+	password, _ := ctx.Get(sshconnect.ContextKeySSHPassword)
+	str, _ := password.(string)
+	return str
+}
+
 func (p *Phase) resolveTarget(ctx *phases.Context) (string, error) {
 	if ctx != nil {
-		if val, ok := ctx.Get(sshconnect.ContextKeyTargetHost); ok {
-			host := strings.TrimSpace(fmt.Sprint(val))
-			if host != "" {
-				return host, nil
-			}
+		if host, ok := phases.GetContextString(ctx, sshconnect.ContextKeyTargetHost); ok {
+			return host, nil
 		}
 	}
 
-	if host, ok := getInput(ctx, p.meta.ID, InputTargetHost); ok && host != "" {
+	if host, ok := phases.GetInputString(ctx, p.meta.ID, InputTargetHost); ok && host != "" {
 		return host, nil
 	}
 
@@ -177,24 +361,18 @@ func (p *Phase) resolveTarget(ctx *phases.Context) (string, error) {
 
 func (p *Phase) resolveUser(ctx *phases.Context) (string, error) {
 	if ctx != nil {
-		if val, ok := ctx.Get(ansibleuser.ContextKeyUserResult); ok {
-			if res, ok := val.(*systemuser.Result); ok && res != nil {
-				user := strings.TrimSpace(res.Username)
-				if user != "" {
-					return user, nil
-				}
+		if res, ok := phases.GetContext(ctx, ansibleuser.KeyUserResult); ok && res != nil {
+			if user := strings.TrimSpace(res.Username); user != "" {
+				return user, nil
 			}
 		}
 
-		if val, ok := ctx.Get(sshconnect.ContextKeyTargetUser); ok {
-			user := strings.TrimSpace(fmt.Sprint(val))
-			if user != "" {
-				return user, nil
-			}
+		if user, ok := phases.GetContextString(ctx, sshconnect.ContextKeyTargetUser); ok {
+			return user, nil
 		}
 	}
 
-	if user, ok := getInput(ctx, p.meta.ID, InputAnsibleUser); ok && user != "" {
+	if user, ok := phases.GetInputString(ctx, p.meta.ID, InputAnsibleUser); ok && user != "" {
 		return user, nil
 	}
 
@@ -203,17 +381,14 @@ func (p *Phase) resolveUser(ctx *phases.Context) (string, error) {
 
 func (p *Phase) resolveKeyPath(ctx *phases.Context) (string, error) {
 	if ctx != nil {
-		if val, ok := ctx.Get(ansibleuser.ContextKeyKeyInfo); ok {
-			if info, ok := val.(*sshkeypair.KeyPairInfo); ok && info != nil {
-				keyPath := strings.TrimSpace(info.PrivatePath)
-				if keyPath != "" {
-					return keyPath, nil
-				}
+		if info, ok := phases.GetContext(ctx, ansibleuser.KeyKeyPair); ok && info != nil {
+			if keyPath := strings.TrimSpace(info.PrivatePath); keyPath != "" {
+				return keyPath, nil
 			}
 		}
 	}
 
-	if keyPath, ok := getInput(ctx, p.meta.ID, InputPrivateKeyPath); ok && keyPath != "" {
+	if keyPath, ok := phases.GetInputPath(ctx, p.meta.ID, InputPrivateKeyPath); ok && keyPath != "" {
 		return keyPath, nil
 	}
 
@@ -221,17 +396,36 @@ func (p *Phase) resolveKeyPath(ctx *phases.Context) (string, error) {
 }
 
 func (p *Phase) resolvePlaybookPath(ctx *phases.Context) (string, error) {
-	if p.playbookPath != "" {
+	if p.playbookPath != "" || len(p.playbooks) > 0 {
 		return p.playbookPath, nil
 	}
 
-	if path, ok := getInput(ctx, p.meta.ID, InputPlaybookPath); ok && path != "" {
+	if path, ok := phases.GetInputPath(ctx, p.meta.ID, InputPlaybookPath); ok && path != "" {
 		return path, nil
 	}
 
 	return "", p.inputRequestError(InputPlaybookPath, "playbook path is required")
 }
 
+// configuredPlaybooks lists every playbook a run covers, in order.
+func configuredPlaybooks(playbookPath string, playbooks []string) []string {
+	all := make([]string, 0, len(playbooks)+1)
+	if playbookPath != "" {
+		all = append(all, playbookPath)
+	}
+	return append(all, playbooks...)
+}
+
+// resolveInventoryPath returns the configured inventory, then the optional
+// input; empty means an inline inventory.
+func (p *Phase) resolveInventoryPath(ctx *phases.Context) string {
+	if p.inventoryPath != "" {
+		return p.inventoryPath
+	}
+	path, _ := phases.GetInputPath(ctx, p.meta.ID, InputInventoryPath)
+	return path
+}
+
 func (p *Phase) inputRequestError(inputID, reason string) phases.InputRequestError {
 	return phases.InputRequestError{
 		PhaseID: p.meta.ID,
@@ -240,7 +434,10 @@ func (p *Phase) inputRequestError(inputID, reason string) phases.InputRequestErr
 	}
 }
 
-func inputDefinitions(includePlaybook bool) []phases.InputDefinition {
+// inputDefinitions lists the phase's inputs for metadata. Target, user, and key
+// path are normally carried over from earlier phases, so they are only
+// required when prompted for.
+func inputDefinitions(includePlaybook, includeInventory bool) []phases.InputDefinition {
 	inputs := []phases.InputDefinition{
 		targetDefinition(),
 		userDefinition(),
@@ -250,6 +447,10 @@ func inputDefinitions(includePlaybook bool) []phases.InputDefinition {
 	if includePlaybook {
 		inputs = append(inputs, playbookPathDefinition())
 	}
+	if includeInventory {
+		inputs = append(inputs, inventoryPathDefinition())
+	}
+	inputs = append(inputs, becomePasswordDefinition(), verbosityDefinition(), forksDefinition(), strategyDefinition())
 
 	return inputs
 }
@@ -264,6 +465,16 @@ func inputDefinition(inputID string) phases.InputDefinition {
 		return keyPathDefinition()
 	case InputPlaybookPath:
 		return playbookPathDefinition()
+	case InputInventoryPath:
+		return inventoryPathDefinition()
+	case InputBecomePassword:
+		return becomePasswordDefinition()
+	case InputVerbosity:
+		return verbosityDefinition()
+	case InputForks:
+		return forksDefinition()
+	case InputStrategy:
+		return strategyDefinition()
 	default:
 		return phases.InputDefinition{
 			ID:    inputID,
@@ -280,6 +491,7 @@ func targetDefinition() phases.InputDefinition {
 		Description: "Hostname or IP of the target to run the playbook against.",
 		Kind:        phases.InputKindText,
 		Required:    true,
+		ProvidedBy:  []string{sshconnect.PhaseID},
 	}
 }
 
@@ -290,6 +502,7 @@ func userDefinition() phases.InputDefinition {
 		Description: "Remote user Ansible should connect as.",
 		Kind:        phases.InputKindText,
 		Required:    true,
+		ProvidedBy:  []string{ansibleuser.PhaseID, sshconnect.PhaseID},
 	}
 }
 
@@ -300,6 +513,7 @@ func keyPathDefinition() phases.InputDefinition {
 		Description: "Path to the private key for the ansible user.",
 		Kind:        phases.InputKindText,
 		Required:    true,
+		ProvidedBy:  []string{ansibleuser.PhaseID},
 	}
 }
 
@@ -313,10 +527,62 @@ func playbookPathDefinition() phases.InputDefinition {
 	}
 }
 
-func getInput(ctx *phases.Context, phaseID, inputID string) (string, bool) {
-	val, ok := phases.GetInput(ctx, phaseID, inputID)
-	if !ok {
-		return "", false
+func inventoryPathDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputInventoryPath,
+		Label:       "Inventory Path",
+		Description: "Existing inventory file or directory to use instead of an inline one; the target host becomes --limit. Leave empty for an inline inventory.",
+		Kind:        phases.InputKindText,
+	}
+}
+
+func becomePasswordDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputBecomePassword,
+		Label:       "Become Password",
+		Description: "sudo password for the ansible user when it lacks NOPASSWD. Leave empty to reuse the SSH password when connecting as the SSH user.",
+		Kind:        phases.InputKindSecret,
+		Secret:      true,
+	}
+}
+
+func verbosityDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputVerbosity,
+		Label:       "Verbosity",
+		Description: "How much detail ansible prints; raise it to debug a failing playbook.",
+		Kind:        phases.InputKindSelect,
+		Default:     "0",
+		Options: []phases.InputOption{
+			{Value: "0", Label: "Normal"},
+			{Value: "1", Label: "-v", Description: "Task results"},
+			{Value: "2", Label: "-vv", Description: "Task input and file paths"},
+			{Value: "3", Label: "-vvv", Description: "Connection details"},
+			{Value: "4", Label: "-vvvv", Description: "Connection debugging"},
+		},
+	}
+}
+
+func forksDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputForks,
+		Label:       "Forks",
+		Description: "How many hosts ansible works on in parallel; raise it for large inventories. Leave empty for ansible's default of 5.",
+		Kind:        phases.InputKindText,
+	}
+}
+
+func strategyDefinition() phases.InputDefinition {
+	return phases.InputDefinition{
+		ID:          InputStrategy,
+		Label:       "Strategy",
+		Description: "How ansible moves hosts through the play. Plays that set a strategy keep their own.",
+		Kind:        phases.InputKindSelect,
+		Options: []phases.InputOption{
+			{Value: ansiblepb.StrategyLinear, Label: "Linear", Description: "Each task finishes on every host first (ansible's default)"},
+			{Value: ansiblepb.StrategyFree, Label: "Free", Description: "Hosts run ahead without waiting for each other"},
+			{Value: ansiblepb.StrategyMitogenLinear, Label: "Mitogen linear", Description: "Linear with Mitogen's lower overhead; needs Mitogen for Ansible"},
+			{Value: ansiblepb.StrategyMitogenFree, Label: "Mitogen free", Description: "Free with Mitogen's lower overhead; needs Mitogen for Ansible"},
+		},
 	}
-	return strings.TrimSpace(fmt.Sprint(val)), true
 }
@@ -2,13 +2,17 @@ package playbook
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
 	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
 	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
 	ansiblepb "github.com/BrianJOC/ansible-host-prep/utils/ansibleplaybook"
 	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
@@ -173,3 +177,326 @@ func TestRunAppliesOptions(t *testing.T) {
 	err := phase.Run(context.Background(), ctx)
 	require.NoError(t, err)
 }
+
+func TestRunMultiplePlaybooks(t *testing.T) {
+	t.Parallel()
+
+	ctx := phases.NewContext()
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	ctx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+	ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+
+	var ran []string
+	backend := backendFunc(func(_ context.Context, req ansiblepb.RunRequest, _ ansiblepb.Environment) error {
+		ran = append(ran, req.PlaybookPath)
+		return nil
+	})
+	phase := New(Config{Playbooks: []string{"pre.yml", "site.yml", "verify.yml"}}).
+		WithOptions(ansiblepb.WithBackend(backend))
+	for _, input := range phase.Metadata().Inputs {
+		require.NotEqual(t, InputPlaybookPath, input.ID)
+	}
+	require.Equal(t, "Execute pre.yml, site.yml, verify.yml against the target host.", phase.Metadata().Description)
+
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, []string{"pre.yml", "site.yml", "verify.yml"}, ran)
+	playbooks, ok := ctx.Get(ContextKeyPlaybooks)
+	require.True(t, ok)
+	require.Equal(t, ran, playbooks)
+}
+
+func TestRunUsesInventoryPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config Config
+		input  string
+		want   string
+	}{
+		{name: "inline", config: Config{PlaybookPath: "site.yml"}},
+		{name: "from config", config: Config{PlaybookPath: "site.yml", InventoryPath: "inventory/prod.ini"}, want: "inventory/prod.ini"},
+		{name: "from input", config: Config{PlaybookPath: "site.yml"}, input: "inventory/staging", want: "inventory/staging"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := phases.NewContext()
+			ctx.Set(sshconnect.ContextKeyTargetHost, "web01")
+			ctx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+			ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+
+			var got ansiblepb.RunRequest
+			phase := New(tt.config).WithRunner(func(_ context.Context, req ansiblepb.RunRequest, _ ...ansiblepb.Option) error {
+				got = req
+				return nil
+			})
+			if tt.input != "" {
+				phases.SetInput(ctx, phase.Metadata().ID, InputInventoryPath, tt.input)
+			}
+
+			require.NoError(t, phase.Run(context.Background(), ctx))
+			require.Equal(t, "web01", got.Target)
+			require.Equal(t, tt.want, got.InventoryPath)
+
+			val, ok := ctx.Get(ContextKeyInventoryPath)
+			require.Equal(t, tt.want != "", ok)
+			if ok {
+				require.Equal(t, tt.want, val)
+			}
+
+			var listed bool
+			for _, def := range phase.Metadata().Inputs {
+				listed = listed || def.ID == InputInventoryPath
+			}
+			require.Equal(t, tt.config.InventoryPath == "", listed)
+		})
+	}
+}
+
+func TestRunResolvesBecomePassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		setup func(*phases.Context, string)
+		want  string
+	}{
+		{
+			name: "ssh user reuses ssh password",
+			setup: func(ctx *phases.Context, _ string) {
+				ctx.Set(sshconnect.ContextKeySSHPassword, "ssh-pass")
+			},
+			want: "ssh-pass",
+		},
+		{
+			name: "input wins",
+			setup: func(ctx *phases.Context, id string) {
+				ctx.Set(sshconnect.ContextKeySSHPassword, "ssh-pass")
+				phases.SetInput(ctx, id, InputBecomePassword, "become-pass")
+			},
+			want: "become-pass",
+		},
+		{
+			name: "passwordless ansible user",
+			setup: func(ctx *phases.Context, _ string) {
+				ctx.Set(sshconnect.ContextKeySSHPassword, "ssh-pass")
+				ctx.Set(ansibleuser.ContextKeyUserResult, &systemuser.Result{Username: "ansible", PasswordlessConfigured: true})
+			},
+		},
+		{name: "no password known"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := phases.NewContext()
+			ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+			ctx.Set(sshconnect.ContextKeyTargetUser, "ops")
+			ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+
+			var got string
+			capture := backendFunc(func(_ context.Context, _ ansiblepb.RunRequest, env ansiblepb.Environment) error {
+				if path := env.Vars[ansiblepb.BecomePasswordFileEnv]; path != "" {
+					data, err := os.ReadFile(path)
+					require.NoError(t, err)
+					got = strings.TrimSpace(string(data))
+				}
+				return nil
+			})
+			phase := New(Config{PlaybookPath: "/tmp/site.yml"}).
+				WithRunner(func(ctx context.Context, req ansiblepb.RunRequest, opts ...ansiblepb.Option) error {
+					return ansiblepb.Run(ctx, req, append(opts, ansiblepb.WithBackend(capture))...)
+				})
+			if tt.setup != nil {
+				tt.setup(ctx, phase.Metadata().ID)
+			}
+
+			require.NoError(t, phase.Run(context.Background(), ctx))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type backendFunc func(context.Context, ansiblepb.RunRequest, ansiblepb.Environment) error
+
+func (f backendFunc) Run(ctx context.Context, req ansiblepb.RunRequest, env ansiblepb.Environment) error {
+	return f(ctx, req, env)
+}
+
+func TestRunReportsTaskProgress(t *testing.T) {
+	t.Parallel()
+
+	ctx := phases.NewContext()
+	ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	ctx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+	ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+
+	backend := backendFunc(func(_ context.Context, _ ansiblepb.RunRequest, env ansiblepb.Environment) error {
+		require.NotNil(t, env.OnTask)
+		env.OnTask(ansiblepb.TaskEvent{Task: "Install nginx", Status: ansiblepb.TaskStarted})
+		env.OnTask(ansiblepb.TaskEvent{Task: "Install nginx", Host: "10.0.0.5", Status: ansiblepb.TaskFailed, Message: "No package nginx"})
+		return nil
+	})
+	phase := New(Config{PlaybookPath: "/tmp/site.yml", TaskProgress: true}).
+		WithRunner(func(ctx context.Context, req ansiblepb.RunRequest, opts ...ansiblepb.Option) error {
+			return ansiblepb.Run(ctx, req, append(opts, ansiblepb.WithBackend(backend))...)
+		})
+
+	observer := &taskRecorder{}
+	manager := phases.NewManager(phases.WithObserver(observer))
+	require.NoError(t, manager.Register(phase))
+	require.NoError(t, manager.Run(context.Background(), ctx))
+	require.Equal(t, []phases.TaskEvent{
+		{Task: "Install nginx", Status: phases.TaskStarted},
+		{Task: "Install nginx", Host: "10.0.0.5", Status: phases.TaskFailed, Message: "No package nginx"},
+	}, observer.events)
+}
+
+type taskRecorder struct {
+	events []phases.TaskEvent
+}
+
+func (r *taskRecorder) PhaseStarted(phases.PhaseMetadata)          {}
+func (r *taskRecorder) PhaseCompleted(phases.PhaseMetadata, error) {}
+
+func (r *taskRecorder) TaskProgress(_ phases.PhaseMetadata, event phases.TaskEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestRunAppliesVerbosityInput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: ""},
+		{name: "normal", value: "0", want: ""},
+		{name: "vvv", value: "3", want: "-vvv"},
+		{name: "out of range", value: "7", wantErr: true},
+		{name: "not a number", value: "loud", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := phases.NewContext()
+			ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+			ctx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+			ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+
+			var called bool
+			phase := New(Config{PlaybookPath: "/tmp/site.yml"}).
+				WithRunner(func(_ context.Context, req ansiblepb.RunRequest, opts ...ansiblepb.Option) error {
+					called = true
+					cmd, err := ansiblepb.BuildCommand(req, opts...)
+					require.NoError(t, err)
+					args, err := cmd.Options.GenerateCommandOptions()
+					require.NoError(t, err)
+					if tt.want == "" {
+						require.NotContains(t, args, "-v")
+					} else {
+						require.Contains(t, args, tt.want)
+					}
+					return nil
+				})
+			if tt.value != "" {
+				phases.SetInput(ctx, phase.Metadata().ID, InputVerbosity, tt.value)
+			}
+
+			err := phase.Run(context.Background(), ctx)
+			if tt.wantErr {
+				var inputErr phases.InputRequestError
+				require.ErrorAs(t, err, &inputErr)
+				require.Equal(t, InputVerbosity, inputErr.Input.ID)
+				require.False(t, called)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, called)
+		})
+	}
+}
+
+func TestRunRawModeUsesGeneratedPlaybook(t *testing.T) {
+	t.Parallel()
+
+	newCtx := func() *phases.Context {
+		ctx := phases.NewContext()
+		ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+		ctx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+		ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+		ctx.Set(pythonensure.ContextKeyRawOnly, true)
+		return ctx
+	}
+
+	var generated string
+	runner := func(_ context.Context, req ansiblepb.RunRequest, _ ...ansiblepb.Option) error {
+		data, err := os.ReadFile(req.PlaybookPath)
+		require.NoError(t, err)
+		generated = string(data)
+		return nil
+	}
+
+	err := New(Config{PlaybookPath: "/tmp/site.yml"}).WithRunner(runner).Run(context.Background(), newCtx())
+	require.ErrorContains(t, err, "no python (raw mode)")
+
+	phase := New(Config{PlaybookPath: "/tmp/site.yml", RawCommands: []string{`echo "hi" > /tmp/motd`}}).WithRunner(runner)
+	require.NoError(t, phase.Run(context.Background(), newCtx()))
+	require.Contains(t, generated, "gather_facts: false")
+	require.Contains(t, generated, `ansible.builtin.raw: "echo \"hi\" > /tmp/motd"`)
+}
+
+func TestRunAppliesForksAndStrategyInputs(t *testing.T) {
+	t.Parallel()
+
+	script := filepath.Join(t.TempDir(), "ansible-playbook")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho \"strategy=$ANSIBLE_STRATEGY $*\"\n"), 0o700))
+
+	newCtx := func() *phases.Context {
+		ctx := phases.NewContext()
+		ctx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+		ctx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+		ctx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PrivatePath: "/tmp/id_ansible"})
+		return ctx
+	}
+
+	var stdout strings.Builder
+	phase := New(Config{PlaybookPath: "/tmp/site.yml", Options: []ansiblepb.Option{ansiblepb.WithBinary(script), ansiblepb.WithStdout(&stdout)}})
+	ctx := newCtx()
+	phases.SetInput(ctx, phase.Metadata().ID, InputForks, "40")
+	phases.SetInput(ctx, phase.Metadata().ID, InputStrategy, ansiblepb.StrategyFree)
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Contains(t, stdout.String(), "strategy=free ")
+	require.Contains(t, stdout.String(), "--forks 40")
+
+	ctx = newCtx()
+	phases.SetInput(ctx, phase.Metadata().ID, InputForks, "0")
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputForks, inputErr.Input.ID)
+}
+
+func TestInputsRequiredUnlessProvidedUpstream(t *testing.T) {
+	t.Parallel()
+
+	phase := New(Config{PlaybookPath: "site.yml"})
+	meta := phase.Metadata()
+
+	_, err := phases.ValidateInputs([]phases.PhaseMetadata{meta}, nil)
+	var validationErr phases.InputValidationError
+	require.ErrorAs(t, err, &validationErr)
+	var missing []string
+	for _, problem := range validationErr.Problems {
+		missing = append(missing, problem.InputID)
+	}
+	require.Equal(t, []string{InputTargetHost, InputAnsibleUser, InputPrivateKeyPath}, missing)
+
+	upstream := []phases.PhaseMetadata{{ID: sshconnect.PhaseID}, {ID: ansibleuser.PhaseID}, meta}
+	_, err = phases.ValidateInputs(upstream, nil)
+	require.NoError(t, err)
+}
@@ -0,0 +1,41 @@
+package phases
+
+import "time"
+
+// DefaultEstimatedDuration weights phases that declare no EstimatedDuration.
+const DefaultEstimatedDuration = 10 * time.Second
+
+// Estimate returns the phase's EstimatedDuration, or
+// DefaultEstimatedDuration when it declares none.
+func (m PhaseMetadata) Estimate() time.Duration {
+	if m.EstimatedDuration > 0 {
+		return m.EstimatedDuration
+	}
+	return DefaultEstimatedDuration
+}
+
+// Progress reports how far through metas a pipeline is, from 0 to 1, with
+// each phase weighted by its Estimate, so a long playbook phase moves the
+// bar more than a quick check. done returns how much of a phase has
+// finished, from 0 to 1: 1 for completed phases, a partial share for the
+// running one if the caller can estimate it.
+func Progress(metas []PhaseMetadata, done func(PhaseMetadata) float64) float64 {
+	var total, finished float64
+	for _, meta := range metas {
+		weight := float64(meta.Estimate())
+		total += weight
+		finished += weight * min(max(done(meta), 0), 1)
+	}
+	if total == 0 {
+		return 0
+	}
+	return finished / total
+}
+
+// RunningShare estimates how much of a running phase is done after elapsed,
+// from its Estimate. It stops short of 1 so an overrunning phase never looks
+// finished.
+func RunningShare(meta PhaseMetadata, elapsed time.Duration) float64 {
+	const ceiling = 0.95
+	return min(float64(elapsed)/float64(meta.Estimate()), ceiling)
+}
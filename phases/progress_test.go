@@ -0,0 +1,38 @@
+package phases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressWeightsPhasesByEstimate(t *testing.T) {
+	t.Parallel()
+
+	metas := []PhaseMetadata{
+		{ID: "connect", EstimatedDuration: 10 * time.Second},
+		{ID: "sudo", EstimatedDuration: 10 * time.Second},
+		{ID: "python"},
+		{ID: "playbook", EstimatedDuration: 10 * time.Minute},
+	}
+	completed := map[string]bool{"connect": true, "sudo": true, "python": true}
+	done := func(meta PhaseMetadata) float64 {
+		if completed[meta.ID] {
+			return 1
+		}
+		return 0
+	}
+	require.InDelta(t, 30.0/630.0, Progress(metas, done), 1e-9)
+
+	running := func(meta PhaseMetadata) float64 {
+		if meta.ID == "playbook" {
+			return RunningShare(meta, 5*time.Minute)
+		}
+		return done(meta)
+	}
+	require.InDelta(t, 330.0/630.0, Progress(metas, running), 1e-9)
+
+	require.Equal(t, 0.95, RunningShare(metas[0], time.Hour))
+	require.Zero(t, Progress(nil, done))
+}
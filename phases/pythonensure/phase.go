@@ -2,33 +2,77 @@ package pythonensure
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/osdetect"
 	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
 	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
-	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
 )
 
 const (
-	phaseID             = "python_ensure"
-	defaultPackageName  = "python3"
-	defaultBinaryName   = "python3"
-	ContextKeyInstalled = "python:installed"
+	phaseID               = "python_ensure"
+	defaultPackageName    = "python3"
+	defaultBinaryName     = "python3"
+	defaultInterpreter    = "/usr/bin/python3"
+	ContextKeyInstalled   = "python:installed"
+	ContextKeyInterpreter = "python:interpreter"
+	// ContextKeyRawOnly is true when the operator chose raw mode and the target
+	// has no python3, so later phases may only use ansible's raw module.
+	ContextKeyRawOnly = "python:raw_only"
+	// ContextKeyArch records the target architecture as one of the
+	// pkginstaller.Arch constants, so later install steps can pick packages.
+	// It is shared with the os_detect phase.
+	ContextKeyArch = osdetect.ContextKeyArch
+
+	// Input identifiers
+	InputMode = "mode"
+
+	// Modes for InputMode.
+	ModeInstall = "install"
+	ModeRaw     = "raw"
 )
 
 // InstallerFunc wraps pkginstaller.Ensure for dependency injection.
 type InstallerFunc func(r pkginstaller.Runner, packageName string, opts ...pkginstaller.Option) (*pkginstaller.Result, error)
 
+// InterpreterLocator resolves the absolute path of the python3 interpreter.
+type InterpreterLocator func(r pkginstaller.Runner) (string, error)
+
+// ArchDetector reports the normalized target architecture.
+type ArchDetector func(r pkginstaller.Runner) (string, error)
+
 // Phase ensures Python 3 is present on the remote target.
 type Phase struct {
-	install InstallerFunc
+	install    InstallerFunc
+	locate     InterpreterLocator
+	detectArch ArchDetector
 }
 
 // New creates a Python ensure phase.
 func New() *Phase {
 	return &Phase{
-		install: pkginstaller.Ensure,
+		install:    pkginstaller.Ensure,
+		locate:     locateInterpreter,
+		detectArch: pkginstaller.DetectArch,
+	}
+}
+
+// WithArchDetector overrides how the target architecture is detected (for tests).
+func (p *Phase) WithArchDetector(fn ArchDetector) *Phase {
+	if fn != nil {
+		p.detectArch = fn
 	}
+	return p
+}
+
+// WithInterpreterLocator overrides how the interpreter path is resolved (for tests).
+func (p *Phase) WithInterpreterLocator(fn InterpreterLocator) *Phase {
+	if fn != nil {
+		p.locate = fn
+	}
+	return p
 }
 
 // WithInstaller allows providing a custom installer (for tests).
@@ -41,9 +85,24 @@ func (p *Phase) WithInstaller(fn InstallerFunc) *Phase {
 
 func (p *Phase) Metadata() phases.PhaseMetadata {
 	return phases.PhaseMetadata{
-		ID:          phaseID,
-		Title:       "Ensure Python 3",
-		Description: "Install or verify python3 on the target system.",
+		ID:                phaseID,
+		Title:             "Ensure Python 3",
+		Requires:          []string{sudoensure.PhaseID},
+		Description:       "Install or verify python3 on the target system.",
+		EstimatedDuration: time.Minute,
+		Inputs: []phases.InputDefinition{
+			{
+				ID:          InputMode,
+				Label:       "Python Mode",
+				Description: "Install python3 if missing, or leave the target without python and limit ansible to the raw module.",
+				Kind:        phases.InputKindSelect,
+				Default:     ModeInstall,
+				Options: []phases.InputOption{
+					{Value: ModeInstall, Label: "Install python3"},
+					{Value: ModeRaw, Label: "Raw only", Description: "Do not install python; playbooks are limited to ansible.builtin.raw"},
+				},
+			},
+		},
 	}
 }
 
@@ -55,17 +114,26 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 		phaseCtx = phases.NewContext()
 	}
 
-	elevatedVal, ok := phaseCtx.Get(sudoensure.ContextKeyElevatedClient)
-	if !ok {
+	elevatedClient, ok := phases.GetContext(phaseCtx, sudoensure.KeyElevatedClient)
+	if !ok || elevatedClient == nil {
 		return phases.ValidationError{Reason: "sudo phase must complete before ensuring python"}
 	}
 
-	elevatedClient, ok := elevatedVal.(*privilege.ElevatedClient)
-	if !ok || elevatedClient == nil {
-		return phases.ValidationError{Reason: "invalid elevated client in context"}
+	runner := phases.NewTracedRunner(ctx, elevatedClient)
+
+	// Unsupported architectures fail here, before anything is installed. An
+	// architecture recorded by os_detect is reused.
+	if _, known := phaseCtx.Get(ContextKeyArch); !known && p.detectArch != nil {
+		arch, err := p.detectArch(runner)
+		if err != nil {
+			return err
+		}
+		phaseCtx.Set(ContextKeyArch, arch)
 	}
 
-	runner := &sudoRunner{client: elevatedClient}
+	if mode, _ := phases.GetInputString(phaseCtx, phaseID, InputMode); mode == ModeRaw {
+		return p.runRaw(runner, phaseCtx)
+	}
 
 	_, err := p.install(runner, defaultPackageName, pkginstaller.WithCustomCheck("command -v "+defaultBinaryName+" >/dev/null 2>&1"))
 	if err != nil {
@@ -73,13 +141,38 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 	}
 
 	phaseCtx.Set(ContextKeyInstalled, true)
+
+	interpreter := defaultInterpreter
+	if p.locate != nil {
+		if path, err := p.locate(runner); err == nil && path != "" {
+			interpreter = path
+		}
+	}
+	phaseCtx.Set(ContextKeyInterpreter, interpreter)
 	return nil
 }
 
-type sudoRunner struct {
-	client *privilege.ElevatedClient
+// runRaw records an existing interpreter without installing anything, and
+// marks the target raw-only when there is none.
+func (p *Phase) runRaw(runner pkginstaller.Runner, phaseCtx *phases.Context) error {
+	var interpreter string
+	if p.locate != nil {
+		interpreter, _ = p.locate(runner)
+	}
+	if interpreter == "" {
+		phaseCtx.Set(ContextKeyInstalled, false)
+		phaseCtx.Set(ContextKeyRawOnly, true)
+		return nil
+	}
+	phaseCtx.Set(ContextKeyInstalled, true)
+	phaseCtx.Set(ContextKeyInterpreter, interpreter)
+	return nil
 }
 
-func (r *sudoRunner) Run(cmd string) (string, string, error) {
-	return r.client.Run(cmd)
+func locateInterpreter(r pkginstaller.Runner) (string, error) {
+	stdout, _, err := r.Run("command -v " + defaultBinaryName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout), nil
 }
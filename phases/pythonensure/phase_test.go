@@ -17,10 +17,12 @@ func TestPhaseEnsuresPython(t *testing.T) {
 	t.Parallel()
 
 	var called bool
-	phase := New().WithInstaller(func(r pkginstaller.Runner, packageName string, opts ...pkginstaller.Option) (*pkginstaller.Result, error) {
+	phase := New().WithArchDetector(fixedArch).WithInstaller(func(r pkginstaller.Runner, packageName string, opts ...pkginstaller.Option) (*pkginstaller.Result, error) {
 		called = true
 		require.Equal(t, defaultPackageName, packageName)
 		return &pkginstaller.Result{Installed: true}, nil
+	}).WithInterpreterLocator(func(pkginstaller.Runner) (string, error) {
+		return "/usr/local/bin/python3", nil
 	})
 
 	ctx := phases.NewContext()
@@ -33,6 +35,14 @@ func TestPhaseEnsuresPython(t *testing.T) {
 	val, ok := ctx.Get(ContextKeyInstalled)
 	require.True(t, ok)
 	require.Equal(t, true, val)
+
+	interpreter, ok := ctx.Get(ContextKeyInterpreter)
+	require.True(t, ok)
+	require.Equal(t, "/usr/local/bin/python3", interpreter)
+
+	arch, ok := ctx.Get(ContextKeyArch)
+	require.True(t, ok)
+	require.Equal(t, pkginstaller.ArchARM64, arch)
 }
 
 func TestPhaseRequiresElevatedClient(t *testing.T) {
@@ -48,7 +58,7 @@ func TestPhaseRequiresElevatedClient(t *testing.T) {
 func TestPhasePropagatesInstallerError(t *testing.T) {
 	t.Parallel()
 
-	phase := New().WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+	phase := New().WithArchDetector(fixedArch).WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
 		return nil, errors.New("install failed")
 	})
 
@@ -58,3 +68,87 @@ func TestPhasePropagatesInstallerError(t *testing.T) {
 	err := phase.Run(context.Background(), ctx)
 	require.EqualError(t, err, "install failed")
 }
+
+func TestPhaseRawModeSkipsInstall(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		interpreter string
+		rawOnly     bool
+	}{
+		{name: "python missing", rawOnly: true},
+		{name: "python present", interpreter: "/usr/bin/python3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			phase := New().WithArchDetector(fixedArch).WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+				t.Fatal("installer must not run in raw mode")
+				return nil, nil
+			}).WithInterpreterLocator(func(pkginstaller.Runner) (string, error) {
+				if tt.interpreter == "" {
+					return "", errors.New("exit status 1")
+				}
+				return tt.interpreter, nil
+			})
+
+			ctx := phases.NewContext()
+			ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+			phases.SetInput(ctx, phaseID, InputMode, ModeRaw)
+			require.NoError(t, phase.Run(context.Background(), ctx))
+
+			rawOnly, _ := ctx.Get(ContextKeyRawOnly)
+			installed, _ := ctx.Get(ContextKeyInstalled)
+			interpreter, _ := ctx.Get(ContextKeyInterpreter)
+			if tt.rawOnly {
+				require.Equal(t, true, rawOnly)
+				require.Equal(t, false, installed)
+				require.Nil(t, interpreter)
+				return
+			}
+			require.Nil(t, rawOnly)
+			require.Equal(t, tt.interpreter, interpreter)
+		})
+	}
+}
+
+func fixedArch(pkginstaller.Runner) (string, error) {
+	return pkginstaller.ArchARM64, nil
+}
+
+func TestPhaseFailsOnUnsupportedArch(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithArchDetector(func(pkginstaller.Runner) (string, error) {
+		return "", pkginstaller.UnsupportedArchError{Arch: "riscv64"}
+	}).WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+		t.Fatal("installer must not run on an unsupported architecture")
+		return nil, nil
+	})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+
+	err := phase.Run(context.Background(), ctx)
+	require.ErrorAs(t, err, new(pkginstaller.UnsupportedArchError))
+	_, ok := ctx.Get(ContextKeyArch)
+	require.False(t, ok)
+}
+
+func TestPhaseReusesDetectedArch(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithArchDetector(func(pkginstaller.Runner) (string, error) {
+		t.Fatal("arch detector must not run")
+		return "", nil
+	}).WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+		return &pkginstaller.Result{Skipped: true}, nil
+	})
+
+	ctx := phases.NewContext()
+	ctx.Set(sudoensure.ContextKeyElevatedClient, &privilege.ElevatedClient{})
+	ctx.Set(ContextKeyArch, pkginstaller.ArchAMD64)
+	require.NoError(t, phase.Run(context.Background(), ctx))
+}
@@ -0,0 +1,48 @@
+package phases
+
+import (
+	"context"
+	"errors"
+)
+
+// ContextRunner runs commands on a target under a context, as
+// *privilege.ElevatedClient does.
+type ContextRunner interface {
+	RunContext(ctx context.Context, cmd string) (stdout, stderr string, err error)
+}
+
+// TracedRunner adapts a ContextRunner to the Run(cmd) runners that utils
+// packages take. It binds the phase's ctx, so cancelling the run stops the
+// command, and reports every command through TraceCommand.
+type TracedRunner struct {
+	ctx    context.Context
+	client ContextRunner
+}
+
+// NewTracedRunner returns a runner that executes commands with client under
+// ctx.
+func NewTracedRunner(ctx context.Context, client ContextRunner) *TracedRunner {
+	return &TracedRunner{ctx: ctx, client: client}
+}
+
+// Run executes cmd and reports it to the manager's command observers.
+func (r *TracedRunner) Run(cmd string) (string, string, error) {
+	finish := TraceCommand(r.ctx, cmd)
+	stdout, stderr, err := r.client.RunContext(r.ctx, cmd)
+	finish(err)
+	return stdout, stderr, err
+}
+
+// Exec runs cmd like Run but reports a non-zero exit status as exitCode
+// rather than as an error, so packages such as pkginstaller can tell a
+// failed check from a failed session. err is set only when the command did
+// not run to completion.
+func (r *TracedRunner) Exec(cmd string) (stdout, stderr string, exitCode int, err error) {
+	stdout, stderr, err = r.Run(cmd)
+	// *ssh.ExitError, which elevated clients return, carries the status.
+	var status interface{ ExitStatus() int }
+	if errors.As(err, &status) {
+		return stdout, stderr, status.ExitStatus(), nil
+	}
+	return stdout, stderr, 0, err
+}
@@ -0,0 +1,55 @@
+package phases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exitError struct{ status int }
+
+func (e exitError) Error() string   { return "exited" }
+func (e exitError) ExitStatus() int { return e.status }
+
+type fakeContextRunner struct {
+	err error
+}
+
+func (r fakeContextRunner) RunContext(_ context.Context, cmd string) (string, string, error) {
+	return "ran " + cmd, "", r.err
+}
+
+func TestTracedRunnerReportsCommandsAndExitCodes(t *testing.T) {
+	t.Parallel()
+
+	var traced []string
+	manager := NewManager(WithObserver(commandRecorder(func(cmd string) { traced = append(traced, cmd) })))
+	phase := &fakePhase{
+		meta: PhaseMetadata{ID: "runner"},
+		run: func(ctx context.Context, _ *Context) error {
+			stdout, _, code, err := NewTracedRunner(ctx, fakeContextRunner{err: exitError{status: 3}}).Exec("check")
+			require.NoError(t, err)
+			require.Equal(t, 3, code)
+			require.Equal(t, "ran check", stdout)
+
+			sessionErr := errors.New("ssh: channel open failed")
+			_, _, _, err = NewTracedRunner(ctx, fakeContextRunner{err: sessionErr}).Exec("install")
+			require.ErrorIs(t, err, sessionErr)
+			return nil
+		},
+	}
+	require.NoError(t, manager.Register(phase))
+	require.NoError(t, manager.Run(context.Background(), NewContext()))
+	require.Equal(t, []string{"check", "install"}, traced)
+}
+
+type commandRecorder func(cmd string)
+
+func (commandRecorder) PhaseStarted(PhaseMetadata)          {}
+func (commandRecorder) PhaseCompleted(PhaseMetadata, error) {}
+func (r commandRecorder) CommandStarted(_ PhaseMetadata, cmd string) func(error) {
+	r(cmd)
+	return func(error) {}
+}
@@ -0,0 +1,110 @@
+package phases
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// SchemaDialect is the JSON Schema draft emitted by ExportSchema.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a minimal JSON Schema node covering the constructs phase inputs need.
+type Schema struct {
+	Dialect              string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Const                string             `json:"const,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+	Format               string             `json:"format,omitempty"`
+}
+
+// ExportSchema describes every registered phase's inputs as a JSON Schema so
+// external form builders can collect answers up front. The document is an
+// object keyed by phase ID whose values are objects keyed by input ID, the same
+// shape headless runners accept as pre-supplied inputs.
+func ExportSchema(manager *Manager) ([]byte, error) {
+	schema, err := BuildSchema(manager)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// BuildSchema returns the schema ExportSchema serializes, for callers that want
+// to post-process it before encoding.
+func BuildSchema(manager *Manager) (*Schema, error) {
+	if manager == nil {
+		return nil, errors.New("phases: manager must not be nil")
+	}
+
+	closed := false
+	root := &Schema{
+		Dialect:              SchemaDialect,
+		Title:                "Phase inputs",
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: &closed,
+	}
+	earlier := make(map[string]bool)
+	for _, meta := range manager.Metadata() {
+		if len(meta.Inputs) > 0 {
+			root.Properties[meta.ID] = phaseSchema(meta, earlier)
+		}
+		earlier[meta.ID] = true
+	}
+	return root, nil
+}
+
+// phaseSchema describes meta's inputs. Inputs provided by one of the earlier
+// phases are not listed as required.
+func phaseSchema(meta PhaseMetadata, earlier map[string]bool) *Schema {
+	closed := false
+	node := &Schema{
+		Title:                meta.Title,
+		Description:          meta.Description,
+		Type:                 "object",
+		Properties:           make(map[string]*Schema, len(meta.Inputs)),
+		AdditionalProperties: &closed,
+	}
+	for _, input := range meta.Inputs {
+		node.Properties[input.ID] = inputSchema(input)
+		if input.Required && !providedBy(input, earlier) {
+			node.Required = append(node.Required, input.ID)
+		}
+	}
+	return node
+}
+
+func inputSchema(input InputDefinition) *Schema {
+	node := &Schema{
+		Title:       input.Label,
+		Description: input.Description,
+		Type:        "string",
+	}
+	if input.Kind == InputKindSecret || input.Secret {
+		node.WriteOnly = true
+		node.Format = "password"
+		return node
+	}
+	if input.Default != nil && input.Default != "" {
+		node.Default = input.Default
+	}
+	if input.Kind == InputKindSelect && len(input.Options) > 0 {
+		for _, opt := range input.Options {
+			node.Enum = append(node.Enum, opt.Value)
+			node.OneOf = append(node.OneOf, &Schema{
+				Const:       opt.Value,
+				Title:       opt.Label,
+				Description: opt.Description,
+			})
+		}
+	}
+	return node
+}
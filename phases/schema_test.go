@@ -0,0 +1,62 @@
+package phases
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportSchemaDescribesInputs(t *testing.T) {
+	t.Parallel()
+
+	manager := NewManager()
+	require.NoError(t, manager.Register(
+		&fakePhase{meta: PhaseMetadata{
+			ID:    "ssh",
+			Title: "SSH",
+			Inputs: []InputDefinition{
+				{ID: "host", Label: "Host", Kind: InputKindText, Required: true},
+				{ID: "port", Label: "Port", Kind: InputKindText, Default: "22"},
+				{ID: "password", Label: "Password", Kind: InputKindSecret, Default: "leaked"},
+			},
+		}},
+		&fakePhase{meta: PhaseMetadata{
+			ID: "mode",
+			Inputs: []InputDefinition{{
+				ID:      "strategy",
+				Kind:    InputKindSelect,
+				Options: []InputOption{{Value: "fast", Label: "Fast"}, {Value: "safe", Label: "Safe"}},
+			}},
+		}},
+		&fakePhase{meta: PhaseMetadata{ID: "noop"}},
+	))
+
+	raw, err := ExportSchema(manager)
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	require.Equal(t, SchemaDialect, doc["$schema"])
+
+	props := doc["properties"].(map[string]any)
+	require.NotContains(t, props, "noop")
+
+	ssh := props["ssh"].(map[string]any)
+	require.Equal(t, []any{"host"}, ssh["required"])
+	sshProps := ssh["properties"].(map[string]any)
+	require.Equal(t, "22", sshProps["port"].(map[string]any)["default"])
+	password := sshProps["password"].(map[string]any)
+	require.Equal(t, true, password["writeOnly"])
+	require.NotContains(t, password, "default")
+
+	strategy := props["mode"].(map[string]any)["properties"].(map[string]any)["strategy"].(map[string]any)
+	require.Equal(t, []any{"fast", "safe"}, strategy["enum"])
+}
+
+func TestExportSchemaRejectsNilManager(t *testing.T) {
+	t.Parallel()
+
+	_, err := ExportSchema(nil)
+	require.Error(t, err)
+}
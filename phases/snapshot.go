@@ -0,0 +1,354 @@
+package phases
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SnapshotVersion is the format written by Context.Export.
+const SnapshotVersion = 1
+
+// SealKeySize is the length of the key WithSealKey expects (AES-256).
+const SealKeySize = 32
+
+// Snapshot is the JSON document written by Context.Export. Values holds the
+// exported entries; Sealed holds secrets encrypted with the seal key; Omitted
+// names entries that were left out, such as SSH clients or secrets exported
+// without a seal key, so a resumed run knows what it has to rebuild.
+type Snapshot struct {
+	Version int                        `json:"version"`
+	Values  map[string]json.RawMessage `json:"values"`
+	Sealed  map[string][]byte          `json:"sealed,omitempty"`
+	Omitted []string                   `json:"omitted,omitempty"`
+}
+
+// SnapshotKey is a typed key whose structured values Export and Import carry
+// through JSON. Key[T] implements it.
+type SnapshotKey interface {
+	Name() string
+	decodeSnapshot(data []byte) (any, error)
+}
+
+func (k Key[T]) decodeSnapshot(data []byte) (any, error) {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// SnapshotError reports an entry that could not be exported or imported.
+type SnapshotError struct {
+	Key string
+	Err error
+}
+
+func (e SnapshotError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("context snapshot: %v", e.Err)
+	}
+	return fmt.Sprintf("context snapshot entry %s: %v", e.Key, e.Err)
+}
+
+func (e SnapshotError) Unwrap() error {
+	return e.Err
+}
+
+// SnapshotOption configures Export and Import.
+type SnapshotOption func(*snapshotConfig)
+
+type snapshotConfig struct {
+	secrets map[string]struct{}
+	typed   map[string]SnapshotKey
+	sealKey []byte
+}
+
+// WithSecretKeys marks further entries as secrets for one Export, on top of
+// those marked on the context with MarkSecret. Secrets are sealed when a seal
+// key is set and omitted otherwise.
+func WithSecretKeys(keys ...string) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		for _, key := range keys {
+			cfg.secrets[key] = struct{}{}
+		}
+	}
+}
+
+// WithSecretInputs marks the stored answers to the secret inputs of metas,
+// such as SSH and sudo passwords, as secrets for one Export. A Manager
+// marks the secret inputs of its phases on the context it runs with.
+func WithSecretInputs(metas ...PhaseMetadata) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		for _, meta := range metas {
+			for _, def := range meta.Inputs {
+				if def.Kind == InputKindSecret || def.Secret {
+					cfg.secrets[inputKey(meta.ID, def.ID)] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+// WithTypedKeys exports the structured values stored under keys, which are
+// otherwise omitted, and lets Import restore them with their Go type.
+func WithTypedKeys(keys ...SnapshotKey) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		for _, key := range keys {
+			cfg.typed[key.Name()] = key
+		}
+	}
+}
+
+// WithSealKey encrypts secrets with AES-256-GCM under key, which must be
+// SealKeySize bytes, instead of omitting them.
+func WithSealKey(key []byte) SnapshotOption {
+	return func(cfg *snapshotConfig) {
+		cfg.sealKey = key
+	}
+}
+
+func newSnapshotConfig(opts []SnapshotOption) (*snapshotConfig, error) {
+	cfg := &snapshotConfig{
+		secrets: make(map[string]struct{}),
+		typed:   make(map[string]SnapshotKey),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	if cfg.sealKey != nil && len(cfg.sealKey) != SealKeySize {
+		return nil, SnapshotError{Err: fmt.Errorf("seal key must be %d bytes, got %d", SealKeySize, len(cfg.sealKey))}
+	}
+	return cfg, nil
+}
+
+// baseKey strips the host scope from a stored key name, so options naming
+// "ssh:password" also match "web01/ssh:password".
+func baseKey(key string) string {
+	if idx := strings.LastIndex(key, "/"); idx >= 0 {
+		return key[idx+1:]
+	}
+	return key
+}
+
+// Export serializes the context for checkpoints and replays. Strings,
+// numbers, booleans, and slices and string-keyed maps of them are written
+// as-is, as are values under WithTypedKeys. Anything else, such as an
+// *ssh.Client or an elevated client, is a live handle and is omitted.
+// Secrets, whether marked on the context with MarkSecret (which SetContext
+// does for NewSecretKey keys and a Manager does for secret inputs) or named
+// by WithSecretKeys, are sealed with WithSealKey or else omitted, so a bare
+// Export never writes them in clear. A Scope
+// view exports only its own entries, without the host prefix.
+func (c *Context) Export(opts ...SnapshotOption) ([]byte, error) {
+	cfg, err := newSnapshotConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	snap := Snapshot{Version: SnapshotVersion, Values: make(map[string]json.RawMessage)}
+	for key, value := range c.entries() {
+		base := baseKey(key)
+		_, typed := cfg.typed[base]
+		if !typed && !plainValue(reflect.ValueOf(value)) {
+			snap.Omitted = append(snap.Omitted, key)
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, SnapshotError{Key: key, Err: err}
+		}
+		if !cfg.secret(base) && !c.IsSecret(key) {
+			snap.Values[key] = data
+			continue
+		}
+		if cfg.sealKey == nil {
+			snap.Omitted = append(snap.Omitted, key)
+			continue
+		}
+		sealed, err := seal(cfg.sealKey, key, data)
+		if err != nil {
+			return nil, SnapshotError{Key: key, Err: err}
+		}
+		if snap.Sealed == nil {
+			snap.Sealed = make(map[string][]byte)
+		}
+		snap.Sealed[key] = sealed
+	}
+	sort.Strings(snap.Omitted)
+	return json.MarshalIndent(snap, "", "  ")
+}
+
+// Import sets the entries of a snapshot written by Export. Values under
+// WithTypedKeys are decoded to their Go type; other values come back as
+// strings, float64 numbers, booleans, slices, and maps. Sealed secrets are
+// restored only with the seal key they were written with and skipped
+// without one, so the operator is prompted for them again.
+func (c *Context) Import(data []byte, opts ...SnapshotOption) error {
+	cfg, err := newSnapshotConfig(opts)
+	if err != nil {
+		return err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return SnapshotError{Err: err}
+	}
+	if snap.Version != SnapshotVersion {
+		return SnapshotError{Err: fmt.Errorf("unsupported snapshot version %d", snap.Version)}
+	}
+	values := make(map[string]any, len(snap.Values)+len(snap.Sealed))
+	for key, raw := range snap.Values {
+		value, err := cfg.decode(key, raw)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	if cfg.sealKey != nil {
+		for key, sealed := range snap.Sealed {
+			raw, err := open(cfg.sealKey, key, sealed)
+			if err != nil {
+				return SnapshotError{Key: key, Err: err}
+			}
+			value, err := cfg.decode(key, raw)
+			if err != nil {
+				return err
+			}
+			values[key] = value
+			// Keep the restored secret out of later exports too.
+			c.MarkSecret(key)
+		}
+	}
+	for key, value := range values {
+		c.Set(key, value)
+	}
+	return nil
+}
+
+func (cfg *snapshotConfig) secret(key string) bool {
+	_, ok := cfg.secrets[key]
+	return ok
+}
+
+func (cfg *snapshotConfig) decode(key string, raw []byte) (any, error) {
+	if typed, ok := cfg.typed[baseKey(key)]; ok {
+		value, err := typed.decodeSnapshot(raw)
+		if err != nil {
+			return nil, SnapshotError{Key: key, Err: err}
+		}
+		return value, nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, SnapshotError{Key: key, Err: err}
+	}
+	return value, nil
+}
+
+// entries copies the values the context holds itself: everything for a
+// root context, and the view's own entries for a Scope view.
+func (c *Context) entries() map[string]any {
+	if c == nil {
+		return nil
+	}
+	root := c
+	if c.root != nil {
+		root = c.root
+	}
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+	out := make(map[string]any, len(root.store))
+	for key, value := range root.store {
+		if c.prefix != "" {
+			if !strings.HasPrefix(key, c.prefix) {
+				continue
+			}
+			key = strings.TrimPrefix(key, c.prefix)
+		}
+		out[key] = value
+	}
+	return out
+}
+
+// plainValue reports whether v is JSON data rather than a live handle.
+func plainValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !plainValue(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if !plainValue(iter.Value()) {
+				return false
+			}
+		}
+		return true
+	case reflect.Interface:
+		return plainValue(v.Elem())
+	default:
+		return false
+	}
+}
+
+var errSealedEntry = errors.New("sealed entry is corrupt or was written with another seal key")
+
+// seal encrypts data with AES-GCM, binding it to key so a sealed value
+// cannot be moved to another entry.
+func seal(sealKey []byte, key string, data []byte) ([]byte, error) {
+	gcm, err := newGCM(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, []byte(key)), nil
+}
+
+func open(sealKey []byte, key string, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errSealedEntry
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	data, err := gcm.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, errSealedEntry
+	}
+	return data, nil
+}
+
+func newGCM(sealKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(sealKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
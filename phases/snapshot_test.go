@@ -0,0 +1,146 @@
+package phases
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type snapshotUser struct {
+	Username string
+	Groups   []string
+}
+
+type liveHandle struct {
+	conn chan struct{}
+}
+
+func TestContextExportImportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	userKey := NewKey[*snapshotUser]("ansible:user_result")
+	meta := PhaseMetadata{ID: "ssh", Inputs: []InputDefinition{
+		{ID: "host", Kind: InputKindText},
+		{ID: "password", Kind: InputKindSecret},
+	}}
+
+	ctx := NewContext()
+	SetInput(ctx, "ssh", "host", "web01")
+	SetInput(ctx, "ssh", "password", "hunter2")
+	ctx.Set("ssh:client", &liveHandle{conn: make(chan struct{})})
+	ctx.Set("ssh:target_port", 2222)
+	SetContext(ctx, userKey, &snapshotUser{Username: "ansible", Groups: []string{"sudo"}})
+	ctx.Scope("db01").Set("host:distro", "rocky")
+
+	sealKey := bytes.Repeat([]byte{7}, SealKeySize)
+	data, err := ctx.Export(WithSecretInputs(meta), WithTypedKeys(userKey), WithSealKey(sealKey))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hunter2")
+
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Equal(t, []string{"ssh:client"}, snap.Omitted)
+	require.Contains(t, snap.Sealed, "phase:ssh:input:password")
+
+	restored := NewContext()
+	require.NoError(t, restored.Import(data, WithTypedKeys(userKey), WithSealKey(sealKey)))
+	host, _ := GetInputString(restored, "ssh", "host")
+	require.Equal(t, "web01", host)
+	password, _ := GetInputString(restored, "ssh", "password")
+	require.Equal(t, "hunter2", password)
+	port, _ := restored.Get("ssh:target_port")
+	require.Equal(t, float64(2222), port)
+	user, ok := GetContext(restored, userKey)
+	require.True(t, ok)
+	require.Equal(t, &snapshotUser{Username: "ansible", Groups: []string{"sudo"}}, user)
+	distro, _ := restored.Scope("db01").Get("host:distro")
+	require.Equal(t, "rocky", distro)
+	_, ok = restored.Get("ssh:client")
+	require.False(t, ok)
+
+	// Without the seal key secrets stay behind and the operator is asked again.
+	unsealed := NewContext()
+	require.NoError(t, unsealed.Import(data, WithTypedKeys(userKey)))
+	_, ok = GetInput(unsealed, "ssh", "password")
+	require.False(t, ok)
+
+	var snapErr SnapshotError
+	err = NewContext().Import(data, WithSealKey(bytes.Repeat([]byte{8}, SealKeySize)))
+	require.True(t, errors.As(err, &snapErr))
+	require.Equal(t, "phase:ssh:input:password", snapErr.Key)
+}
+
+func TestContextExportOmitsSecretsWithoutSealKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := NewContext()
+	ctx.Set("sudo:password", "hunter2")
+	ctx.Set("host:os", map[string]any{"id": "debian", "like": []string{"linux"}})
+	web := ctx.Scope("web01")
+	web.Set("sudo:password", "s3cret")
+	web.Set("ssh:user", "admin")
+
+	data, err := ctx.Export(WithSecretKeys("sudo:password"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hunter2")
+	require.NotContains(t, string(data), "s3cret")
+
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Equal(t, []string{"sudo:password", "web01/sudo:password"}, snap.Omitted)
+	require.Contains(t, snap.Values, "host:os")
+
+	// A view exports its own entries without the host prefix.
+	data, err = web.Export()
+	require.NoError(t, err)
+	snap = Snapshot{}
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Len(t, snap.Values, 2)
+	require.Contains(t, snap.Values, "ssh:user")
+
+	_, err = ctx.Export(WithSealKey([]byte("short")))
+	require.Error(t, err)
+	require.Error(t, NewContext().Import([]byte(`{"version":99}`)))
+}
+
+func TestContextExportOmitsMarkedSecretsByDefault(t *testing.T) {
+	t.Parallel()
+
+	phase := &fakePhase{
+		meta: PhaseMetadata{ID: "snapshot_sudo", Inputs: []InputDefinition{
+			{ID: "password", Kind: InputKindText, Secret: true},
+		}},
+		run: func(context.Context, *Context) error { return nil },
+	}
+	manager := NewManager()
+	require.NoError(t, manager.Register(phase))
+	tokenKey := NewSecretKey[string]("snapshot:token")
+
+	ctx := NewContext()
+	SetInput(ctx, "snapshot_sudo", "password", "hunter2")
+	SetContext(ctx, tokenKey, "s3cret")
+	require.NoError(t, manager.Run(context.Background(), ctx))
+
+	data, err := ctx.Export()
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hunter2")
+	require.NotContains(t, string(data), "s3cret")
+
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Equal(t, []string{"phase:snapshot_sudo:input:password", "snapshot:token"}, snap.Omitted)
+	require.True(t, ctx.IsSecret("snapshot:token"))
+	require.True(t, ctx.Scope("web1").IsSecret("snapshot:token"))
+
+	// Marks live on the context, not in the process.
+	other := NewContext()
+	SetInput(other, "snapshot_sudo", "password", "hunter2")
+	require.False(t, other.IsSecret("snapshot:token"))
+	data, err = other.Export()
+	require.NoError(t, err)
+	require.Contains(t, string(data), "hunter2")
+}
@@ -0,0 +1,129 @@
+package sshconnect
+
+import (
+	"errors"
+	"os"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+)
+
+// CredentialProvider resolves the login credential for one authentication
+// method, such as a password, a private key, a certificate, or a secret read
+// from a vault. Providers are offered as options of the auth_method input in
+// the order they were added, and the one the operator picks is asked for the
+// credential.
+type CredentialProvider interface {
+	// Method describes the auth_method option this provider handles; its
+	// Value is what ContextKeyAuthMethod holds after a login.
+	Method() phases.InputOption
+	// Resolve reads the provider's inputs from phaseCtx and returns the
+	// credential, or a phases.InputRequestError while input is missing.
+	Resolve(phaseCtx *phases.Context) (sshconnection.Credential, error)
+}
+
+// CredentialInputs is implemented by providers that read inputs of their
+// own, so they appear in the phase metadata for prompts, headless input
+// validation, and the exported schema.
+type CredentialInputs interface {
+	Inputs() []phases.InputDefinition
+}
+
+// WithCredentialProvider adds cp as an authentication method, after the
+// built-in password, private key, and certificate methods. A provider whose method value
+// is already taken replaces the existing one in place.
+func (p *Phase) WithCredentialProvider(cp CredentialProvider) *Phase {
+	if cp == nil {
+		return p
+	}
+	for i, existing := range p.credentials {
+		if existing.Method().Value == cp.Method().Value {
+			p.credentials[i] = cp
+			return p
+		}
+	}
+	p.credentials = append(p.credentials, cp)
+	return p
+}
+
+func (p *Phase) credentialProvider(method string) (CredentialProvider, bool) {
+	for _, cp := range p.credentials {
+		if cp.Method().Value == method {
+			return cp, true
+		}
+	}
+	return nil, false
+}
+
+// passwordCredentials logs in with the password input.
+type passwordCredentials struct{}
+
+func (passwordCredentials) Method() phases.InputOption {
+	return phases.InputOption{Value: authMethodPassword, Label: "Password"}
+}
+
+func (passwordCredentials) Inputs() []phases.InputDefinition {
+	return []phases.InputDefinition{inputDefinition(InputPassword)}
+}
+
+func (passwordCredentials) Resolve(phaseCtx *phases.Context) (sshconnection.Credential, error) {
+	password, err := getRequiredInput(phaseCtx, InputPassword, "password is required for password authentication")
+	if err != nil {
+		return sshconnection.Credential{}, err
+	}
+	return sshconnection.Credential{Password: password}, nil
+}
+
+// keyCredentials logs in with a private key file or an ssh-agent identity,
+// offering the keys the phase's KeyDiscoverer finds.
+type keyCredentials struct {
+	phase *Phase
+}
+
+func (keyCredentials) Method() phases.InputOption {
+	return phases.InputOption{Value: authMethodKeyPath, Label: "Private Key"}
+}
+
+func (keyCredentials) Inputs() []phases.InputDefinition {
+	return []phases.InputDefinition{inputDefinition(InputKeyPath)}
+}
+
+func (k keyCredentials) Resolve(phaseCtx *phases.Context) (sshconnection.Credential, error) {
+	return k.phase.resolveKeyCredential(phaseCtx)
+}
+
+// certCredentials logs in with a private key file and the OpenSSH certificate
+// an SSH CA issued for it. The certificate defaults to the key path with a
+// -cert.pub suffix, where ssh-keygen -s writes it.
+type certCredentials struct {
+	phase *Phase
+}
+
+func (certCredentials) Method() phases.InputOption {
+	return phases.InputOption{Value: authMethodCertificate, Label: "Certificate"}
+}
+
+func (certCredentials) Inputs() []phases.InputDefinition {
+	return []phases.InputDefinition{inputDefinition(InputKeyPath), inputDefinition(InputCertPath)}
+}
+
+func (c certCredentials) Resolve(phaseCtx *phases.Context) (sshconnection.Credential, error) {
+	cred, err := c.phase.resolveKeyCredential(phaseCtx)
+	if err != nil {
+		return sshconnection.Credential{}, err
+	}
+	if cred.Agent {
+		return sshconnection.Credential{}, inputRequestError(InputKeyPath, "certificate authentication needs a private key file, not an agent identity")
+	}
+
+	if certPath, ok := phases.GetInputPath(phaseCtx, phaseID, InputCertPath); ok && certPath != "" {
+		cred.CertPath = certPath
+		return cred, nil
+	}
+	certPath := sshconnection.CertificatePath(cred.KeyPath)
+	if _, err := os.Stat(certPath); errors.Is(err, os.ErrNotExist) {
+		return sshconnection.Credential{}, inputRequestError(InputCertPath, "no certificate at "+certPath+"; enter the certificate path")
+	}
+	cred.CertPath = certPath
+	return cred, nil
+}
@@ -3,19 +3,24 @@ package sshconnect
 import (
 	"context"
 	"fmt"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/mdns"
+	"github.com/BrianJOC/ansible-host-prep/utils/netscan"
 	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
 )
 
 const (
 	phaseID = "ssh_connection"
+	// PhaseID identifies the phase, e.g. in other phases' Requires.
+	PhaseID = phaseID
 
 	// Input identifiers
 	InputHost       = "host"
@@ -24,33 +29,147 @@ const (
 	InputAuthMethod = "auth_method"
 	InputPassword   = "password"
 	InputKeyPath    = "key_path"
+	InputCertPath   = "cert_path"
 
 	// Context keys for downstream phases
 	ContextKeySSHClient   = "ssh:client"
 	ContextKeySSHPassword = "ssh:password"
 	ContextKeyTargetHost  = "ssh:target_host"
 	ContextKeyTargetUser  = "ssh:target_user"
+	ContextKeyTargetPort  = "ssh:target_port"
 	ContextKeyAuthMethod  = "ssh:auth_method"
+	// ContextKeyKeyFingerprint is the SHA256 fingerprint of the key used to
+	// log in, when key authentication was used and the key is known.
+	ContextKeyKeyFingerprint = "ssh:key_fingerprint"
+	// ContextKeyConnectionInfo holds the sshconnection.ConnectionInfo
+	// recorded during the handshake: server version, banner, and offered
+	// authentication methods. It is also set when authentication fails.
+	ContextKeyConnectionInfo = "ssh:connection_info"
+)
+
+// Typed keys for context entries other phases read; see phases.Key.
+var (
+	KeySSHClient      = phases.NewKey[*ssh.Client](ContextKeySSHClient)
+	KeyConnectionInfo = phases.NewKey[sshconnection.ConnectionInfo](ContextKeyConnectionInfo)
+	// KeySSHPassword is a secret: context snapshots never write it in clear.
+	KeySSHPassword = phases.NewSecretKey[string](ContextKeySSHPassword)
+)
+
+// Values of InputAuthMethod offered by the built-in credential providers.
+const (
+	AuthMethodPassword    = authMethodPassword
+	AuthMethodPrivateKey  = authMethodKeyPath
+	AuthMethodCertificate = authMethodCertificate
 )
 
 const (
 	authMethodPassword = "password"
 	authMethodKeyPath  = "private_key"
+	// authMethodCertificate logs in with a private key and the OpenSSH
+	// certificate a CA issued for it.
+	authMethodCertificate = "certificate"
+
+	// keyPathOther lets the operator type a path not in the discovered list.
+	keyPathOther = "other"
+	// agentKeyPrefix marks key path values that select an ssh-agent identity.
+	agentKeyPrefix = "agent:"
+	// hostOther lets the operator type a host that was not discovered; it is
+	// not a valid host name, so it cannot clash with one.
+	hostOther = "(other)"
+
+	// hostDiscoveryTimeout bounds how long the host prompt waits for mDNS
+	// answers.
+	hostDiscoveryTimeout = 1500 * time.Millisecond
 )
 
+// KeyDiscoverer lists private keys and agent identities on the control node.
+type KeyDiscoverer func() []sshconnection.LocalKey
+
+// HostDiscoverer lists SSH hosts announced on the local network.
+type HostDiscoverer func() []mdns.Service
+
+// HostScanner probes a network for SSH servers, e.g. with netscan.Scan.
+type HostScanner func() []netscan.Candidate
+
 // Connector establishes SSH clients.
 type Connector func(host string, port int, username string, cred sshconnection.Credential, opts ...sshconnection.Option) (*ssh.Client, error)
 
+// HealthChecker reports whether a cached client can be reused, like
+// sshconnection.HealthCheck.
+type HealthChecker func(client *ssh.Client) error
+
 // Phase establishes an SSH client based on operator-provided inputs.
 type Phase struct {
-	connect Connector
+	connect       Connector
+	discoverKeys  KeyDiscoverer
+	discoverHosts HostDiscoverer
+	scanHosts     HostScanner
+	connectOpts   []sshconnection.Option
+	healthCheck   HealthChecker
+	credentials   []CredentialProvider
+	defaultHost   string
 }
 
-// New creates a Phase that uses sshconnection.Connect.
+// New creates a Phase that uses sshconnection.Connect and offers password
+// and private key authentication.
 func New() *Phase {
-	return &Phase{
-		connect: sshconnection.Connect,
+	p := &Phase{
+		connect:       sshconnection.Connect,
+		discoverKeys:  sshconnection.DiscoverLocalKeys,
+		discoverHosts: discoverSSHHosts,
+		healthCheck:   sshconnection.HealthCheck,
 	}
+	p.credentials = []CredentialProvider{passwordCredentials{}, keyCredentials{phase: p}, certCredentials{phase: p}}
+	return p
+}
+
+// WithHostDiscoverer overrides how hosts are discovered for the host prompt
+// (useful for tests).
+func (p *Phase) WithHostDiscoverer(fn HostDiscoverer) *Phase {
+	if fn != nil {
+		p.discoverHosts = fn
+	}
+	return p
+}
+
+// WithKeyDiscoverer overrides how local keys are discovered for the key path
+// prompt (useful for tests).
+func (p *Phase) WithKeyDiscoverer(fn KeyDiscoverer) *Phase {
+	if fn != nil {
+		p.discoverKeys = fn
+	}
+	return p
+}
+
+// WithHostScanner adds the hosts fn finds to the host prompt, after those
+// announced over mDNS. Scanning is off unless a scanner is given, because
+// probing a network should be an explicit choice.
+func (p *Phase) WithHostScanner(fn HostScanner) *Phase {
+	p.scanHosts = fn
+	return p
+}
+
+// WithConnectOptions passes opts to every connection attempt, e.g.
+// sshconnection.WithRetryWindow for targets that take a while to boot.
+func (p *Phase) WithConnectOptions(opts ...sshconnection.Option) *Phase {
+	p.connectOpts = append(p.connectOpts, opts...)
+	return p
+}
+
+// WithHealthChecker overrides how a client left by an earlier run is checked
+// before it is reused (useful for tests).
+func (p *Phase) WithHealthChecker(fn HealthChecker) *Phase {
+	if fn != nil {
+		p.healthCheck = fn
+	}
+	return p
+}
+
+// WithDefaultHost suggests host at the host prompt, e.g. when re-preparing a
+// known host; the operator can still enter another one.
+func (p *Phase) WithDefaultHost(host string) *Phase {
+	p.defaultHost = strings.TrimSpace(host)
+	return p
 }
 
 // WithConnector allows injecting a custom connector (useful for tests).
@@ -66,14 +185,14 @@ var (
 		{
 			ID:          InputHost,
 			Label:       "Target Host",
-			Description: "Hostname or IP of the remote system.",
+			Description: "Hostname or IP of the remote system, optionally with a port: host:2222 or [2001:db8::1]:2222.",
 			Kind:        phases.InputKindText,
 			Required:    true,
 		},
 		{
 			ID:          InputPort,
 			Label:       "Port",
-			Description: "SSH port (defaults to 22).",
+			Description: "SSH port (defaults to 22, or the port given with the host).",
 			Kind:        phases.InputKindText,
 			Required:    false,
 		},
@@ -87,13 +206,9 @@ var (
 		{
 			ID:          InputAuthMethod,
 			Label:       "Authentication Method",
-			Description: "Choose password or existing private key.",
+			Description: "Choose how to log in, e.g. password or existing private key.",
 			Kind:        phases.InputKindSelect,
 			Required:    true,
-			Options: []phases.InputOption{
-				{Value: authMethodPassword, Label: "Password"},
-				{Value: authMethodKeyPath, Label: "Private Key"},
-			},
 		},
 		{
 			ID:          InputPassword,
@@ -106,7 +221,14 @@ var (
 		{
 			ID:          InputKeyPath,
 			Label:       "Private Key Path",
-			Description: "Absolute path to an existing private key.",
+			Description: "Path to an existing private key, or agent:<fingerprint> for an ssh-agent identity.",
+			Kind:        phases.InputKindText,
+			Required:    false,
+		},
+		{
+			ID:          InputCertPath,
+			Label:       "Certificate Path",
+			Description: "OpenSSH certificate for the private key (defaults to <key>-cert.pub).",
 			Kind:        phases.InputKindText,
 			Required:    false,
 		},
@@ -123,11 +245,52 @@ var (
 
 func (p *Phase) Metadata() phases.PhaseMetadata {
 	return phases.PhaseMetadata{
-		ID:          phaseID,
-		Title:       "SSH Connection",
-		Description: "Collect target details and establish an SSH session.",
-		Inputs:      cloneInputDefinitions(),
+		ID:                phaseID,
+		Title:             "SSH Connection",
+		Description:       "Collect target details and establish an SSH session.",
+		EstimatedDuration: 5 * time.Second,
+		Inputs:            p.inputDefinitions(),
+	}
+}
+
+// inputDefinitions lists the connection inputs, with the credential
+// providers as auth_method options, followed by the providers' own inputs.
+func (p *Phase) inputDefinitions() []phases.InputDefinition {
+	defs := []phases.InputDefinition{
+		inputDefinition(InputHost),
+		inputDefinition(InputPort),
+		inputDefinition(InputUsername),
+		p.authMethodDefinition(),
+	}
+	seen := map[string]bool{}
+	for _, def := range defs {
+		seen[def.ID] = true
+	}
+	for _, cp := range p.credentials {
+		withInputs, ok := cp.(CredentialInputs)
+		if !ok {
+			continue
+		}
+		for _, def := range withInputs.Inputs() {
+			if !seen[def.ID] {
+				seen[def.ID] = true
+				defs = append(defs, def)
+			}
+		}
 	}
+	return defs
+}
+
+func (p *Phase) authMethodDefinition() phases.InputDefinition {
+	def := inputDefinition(InputAuthMethod)
+	for _, cp := range p.credentials {
+		def.Options = append(def.Options, cp.Method())
+	}
+	return def
+}
+
+func (p *Phase) authMethodRequest(reason string) phases.InputRequestError {
+	return phases.InputRequestError{PhaseID: phaseID, Input: p.authMethodDefinition(), Reason: reason}
 }
 
 func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
@@ -138,9 +301,16 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 		phaseCtx = phases.NewContext()
 	}
 
-	host, err := getRequiredInput(phaseCtx, InputHost, "host is required")
-	if err != nil {
-		return err
+	target, ok := phases.GetInputString(phaseCtx, phaseID, InputHost)
+	switch {
+	case !ok || target == "":
+		return p.hostRequest("host is required")
+	case target == hostOther:
+		return inputRequestError(InputHost, "enter the hostname or IP of the target")
+	}
+	host, hostPort, reason := splitTarget(target)
+	if reason != "" {
+		return inputRequestError(InputHost, reason)
 	}
 	username, err := getRequiredInput(phaseCtx, InputUsername, "username is required")
 	if err != nil {
@@ -148,73 +318,239 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 	}
 
 	port := 22
-	if str, ok := getInput(phaseCtx, InputPort); ok && str != "" {
-		value, convErr := strconv.Atoi(str)
-		if convErr != nil || value <= 0 {
-			return inputRequestError(InputPort, "port must be a positive integer")
-		}
+	value, ok, convErr := phases.GetInputInt(phaseCtx, phaseID, InputPort)
+	if convErr != nil || (ok && (value < 1 || value > 65535)) {
+		return inputRequestError(InputPort, "port must be between 1 and 65535")
+	}
+	switch {
+	case ok && hostPort != 0 && value != hostPort:
+		return inputRequestError(InputPort, fmt.Sprintf("port %d conflicts with port %d in the host", value, hostPort))
+	case hostPort != 0:
+		port = hostPort
+	case ok:
 		port = value
 	}
 
-	authMethod, err := getRequiredInput(phaseCtx, InputAuthMethod, "select an authentication method")
+	authMethod, ok := phases.GetInputString(phaseCtx, phaseID, InputAuthMethod)
+	if !ok || authMethod == "" {
+		return p.authMethodRequest("select an authentication method")
+	}
+	provider, ok := p.credentialProvider(authMethod)
+	if !ok {
+		return p.authMethodRequest("unsupported authentication method")
+	}
+	credential, err := provider.Resolve(phaseCtx)
 	if err != nil {
 		return err
 	}
-
-	var credential sshconnection.Credential
-	switch authMethod {
-	case authMethodPassword:
-		password, pErr := getRequiredInput(phaseCtx, InputPassword, "password is required for password authentication")
-		if pErr != nil {
-			return pErr
-		}
-		credential = sshconnection.Credential{Password: password}
-		phaseCtx.Set(ContextKeySSHPassword, password)
-	case authMethodKeyPath:
-		keyPath, kErr := getRequiredInput(phaseCtx, InputKeyPath, "key path is required for private key authentication")
-		if kErr != nil {
-			return kErr
-		}
-		credential = sshconnection.Credential{KeyPath: keyPath}
-	default:
-		return inputRequestError(InputAuthMethod, "unsupported authentication method")
+	if credential.Password != "" {
+		// sudoensure reuses the login password for elevation.
+		phases.SetContext(phaseCtx, KeySSHPassword, credential.Password)
 	}
 
-	client, err := p.connect(host, port, username, credential)
+	if p.reuseClient(phaseCtx, host, port, username, authMethod) {
+		return nil
+	}
+	var info sshconnection.ConnectionInfo
+	opts := append(slices.Clone(p.connectOpts), sshconnection.WithConnectionInfo(&info))
+	client, err := p.connect(host, port, username, credential, opts...)
+	if info.ServerVersion != "" {
+		phases.SetContext(phaseCtx, KeyConnectionInfo, info)
+	} else {
+		phaseCtx.Delete(ContextKeyConnectionInfo)
+	}
 	if err != nil {
 		return err
 	}
 
-	phaseCtx.Set(ContextKeySSHClient, client)
+	phases.SetContext(phaseCtx, KeySSHClient, client)
 	phaseCtx.Set(ContextKeyTargetHost, host)
 	phaseCtx.Set(ContextKeyTargetUser, username)
+	phaseCtx.Set(ContextKeyTargetPort, port)
 	phaseCtx.Set(ContextKeyAuthMethod, authMethod)
+	if fingerprint, err := credential.Fingerprint(); err == nil && fingerprint != "" {
+		phaseCtx.Set(ContextKeyKeyFingerprint, fingerprint)
+	}
 
 	return nil
 }
 
-func getInput(ctx *phases.Context, inputID string) (string, bool) {
-	val, ok := phases.GetInput(ctx, phaseID, inputID)
-	if !ok {
-		return "", false
+// reuseClient reports whether the context already holds a client for the
+// same target and login from an earlier run, e.g. a retry or the scheduled
+// run after the preflight, that still passes a health check. A client that
+// fails it is closed so a fresh one replaces it.
+func (p *Phase) reuseClient(phaseCtx *phases.Context, host string, port int, username, authMethod string) bool {
+	client, ok := phases.GetContext(phaseCtx, KeySSHClient)
+	if !ok || client == nil || client.Conn == nil {
+		return false
+	}
+	same := contextValue(phaseCtx, ContextKeyTargetHost) == host &&
+		contextValue(phaseCtx, ContextKeyTargetPort) == port &&
+		contextValue(phaseCtx, ContextKeyTargetUser) == username &&
+		contextValue(phaseCtx, ContextKeyAuthMethod) == authMethod
+	healthCheck := p.healthCheck
+	if healthCheck == nil {
+		healthCheck = sshconnection.HealthCheck
 	}
-	return strings.TrimSpace(fmt.Sprint(val)), true
+	if same && healthCheck(client) == nil {
+		return true
+	}
+	_ = client.Close()
+	return false
+}
+
+func contextValue(phaseCtx *phases.Context, key string) any {
+	val, _ := phaseCtx.Get(key)
+	return val
+}
+
+// hostRequest asks for the target host, offering the SSH hosts announced
+// over mDNS (e.g. by Avahi on a freshly flashed device) and those found by
+// the host scanner as a select.
+func (p *Phase) hostRequest(reason string) phases.InputRequestError {
+	var hosts []mdns.Service
+	if p.discoverHosts != nil {
+		hosts = p.discoverHosts()
+	}
+	def := inputDefinition(InputHost)
+	seen := map[string]bool{}
+	if p.defaultHost != "" {
+		def.Default = p.defaultHost
+	}
+	for _, host := range hosts {
+		if option, ok := hostOption(host); ok && !seen[option.Value] {
+			seen[option.Value] = true
+			def.Options = append(def.Options, option)
+		}
+	}
+	if p.scanHosts != nil {
+		for _, candidate := range p.scanHosts() {
+			if option := scanOption(candidate); !seen[option.Value] {
+				seen[option.Value] = true
+				def.Options = append(def.Options, option)
+			}
+		}
+	}
+	if len(def.Options) == 0 {
+		return phases.InputRequestError{PhaseID: phaseID, Input: def, Reason: reason}
+	}
+	if p.defaultHost != "" && !seen[p.defaultHost] {
+		def.Options = append([]phases.InputOption{{Value: p.defaultHost, Label: p.defaultHost}}, def.Options...)
+	}
+
+	def.Kind = phases.InputKindSelect
+	def.Description = "Choose a host announced on the local network, or Other to enter one."
+	def.Options = append(def.Options, phases.InputOption{Value: hostOther, Label: "Other…", Description: "Enter a hostname or IP manually"})
+	return phases.InputRequestError{PhaseID: phaseID, Input: def, Reason: reason}
+}
+
+// hostOption offers a discovered host by address, which works without an
+// mDNS-aware resolver, and falls back to its .local name.
+func hostOption(svc mdns.Service) (phases.InputOption, bool) {
+	value := svc.Host
+	if len(svc.Addrs) > 0 {
+		value = svc.Addrs[0].String()
+	}
+	if value == "" {
+		return phases.InputOption{}, false
+	}
+	desc := svc.Host
+	if value != svc.Host {
+		desc = fmt.Sprintf("%s (%s)", svc.Host, value)
+	}
+	value = targetValue(value, svc.Port)
+	if svc.Port != 0 && svc.Port != 22 {
+		desc += fmt.Sprintf(", SSH on port %d", svc.Port)
+	}
+	label := svc.Instance
+	if label == "" {
+		label = value
+	}
+	return phases.InputOption{Value: value, Label: label, Description: desc}, true
+}
+
+func scanOption(candidate netscan.Candidate) phases.InputOption {
+	desc := candidate.Banner
+	if desc == "" {
+		desc = "no SSH banner"
+	}
+	if candidate.Port != 22 {
+		desc += fmt.Sprintf(", port %d", candidate.Port)
+	}
+	addr := candidate.Addr.String()
+	return phases.InputOption{Value: targetValue(addr, candidate.Port), Label: addr, Description: desc}
+}
+
+func discoverSSHHosts() []mdns.Service {
+	ctx, cancel := context.WithTimeout(context.Background(), hostDiscoveryTimeout)
+	defer cancel()
+	hosts, err := mdns.Browse(ctx, "_ssh._tcp")
+	if err != nil {
+		return nil
+	}
+	return hosts
+}
+
+// resolveKeyCredential turns the key path input into a credential. When no
+// path has been chosen yet, discovered keys and agent identities are offered
+// as a select so the operator does not have to type absolute paths.
+func (p *Phase) resolveKeyCredential(ctx *phases.Context) (sshconnection.Credential, error) {
+	const reason = "key path is required for private key authentication"
+
+	keyPath, ok := phases.GetInputString(ctx, phaseID, InputKeyPath)
+	switch {
+	case !ok || keyPath == "":
+		return sshconnection.Credential{}, p.keyPathRequest(reason)
+	case keyPath == keyPathOther:
+		return sshconnection.Credential{}, inputRequestError(InputKeyPath, "enter the path to a private key")
+	case strings.HasPrefix(keyPath, agentKeyPrefix):
+		return sshconnection.Credential{Agent: true, AgentFingerprint: strings.TrimPrefix(keyPath, agentKeyPrefix)}, nil
+	}
+	return sshconnection.Credential{KeyPath: phases.ExpandHome(keyPath)}, nil
+}
+
+func (p *Phase) keyPathRequest(reason string) phases.InputRequestError {
+	var keys []sshconnection.LocalKey
+	if p.discoverKeys != nil {
+		keys = p.discoverKeys()
+	}
+	if len(keys) == 0 {
+		return inputRequestError(InputKeyPath, reason)
+	}
+
+	def := inputDefinition(InputKeyPath)
+	def.Kind = phases.InputKindSelect
+	def.Description = "Choose a discovered key or agent identity, or Other to enter a path."
+	for _, key := range keys {
+		def.Options = append(def.Options, keyOption(key))
+	}
+	def.Options = append(def.Options, phases.InputOption{Value: keyPathOther, Label: "Other…", Description: "Enter a private key path manually"})
+	return phases.InputRequestError{PhaseID: phaseID, Input: def, Reason: reason}
+}
+
+func keyOption(key sshconnection.LocalKey) phases.InputOption {
+	if key.FromAgent {
+		label := "Agent: " + key.Fingerprint
+		if key.Comment != "" {
+			label = "Agent: " + key.Comment
+		}
+		return phases.InputOption{Value: agentKeyPrefix + key.Fingerprint, Label: label, Description: key.Fingerprint}
+	}
+	desc := key.Fingerprint
+	if key.Comment != "" {
+		desc = strings.TrimSpace(key.Comment + " " + key.Fingerprint)
+	}
+	return phases.InputOption{Value: key.Path, Label: key.Path, Description: desc}
 }
 
 func getRequiredInput(ctx *phases.Context, inputID string, reason string) (string, error) {
-	value, ok := getInput(ctx, inputID)
+	value, ok := phases.GetInputString(ctx, phaseID, inputID)
 	if !ok || value == "" {
 		return "", inputRequestError(inputID, reason)
 	}
 	return value, nil
 }
 
-func cloneInputDefinitions() []phases.InputDefinition {
-	defs := make([]phases.InputDefinition, len(phaseInputs))
-	copy(defs, phaseInputs)
-	return defs
-}
-
 func inputDefinition(inputID string) phases.InputDefinition {
 	if def, ok := inputLookup[inputID]; ok {
 		return def
@@ -2,13 +2,21 @@ package sshconnect
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
 
 	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/mdns"
+	"github.com/BrianJOC/ansible-host-prep/utils/netscan"
 	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
 )
 
@@ -73,10 +81,54 @@ func TestPhaseHandlesKeyAuth(t *testing.T) {
 	require.False(t, passwordStored)
 }
 
+func TestPhaseOffersDiscoveredKeys(t *testing.T) {
+	t.Parallel()
+
+	var capturedCred sshconnection.Credential
+	phase := New().
+		WithConnector(func(_ string, _ int, _ string, cred sshconnection.Credential, _ ...sshconnection.Option) (*ssh.Client, error) {
+			capturedCred = cred
+			return &ssh.Client{}, nil
+		}).
+		WithKeyDiscoverer(func() []sshconnection.LocalKey {
+			return []sshconnection.LocalKey{
+				{Path: "/home/ops/.ssh/id_ed25519", Fingerprint: "SHA256:file"},
+				{Fingerprint: "SHA256:agent", Comment: "yubikey", FromAgent: true},
+			}
+		})
+
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "example.com",
+		InputUsername:   "deploy",
+		InputAuthMethod: authMethodKeyPath,
+	})
+
+	err := phase.Run(context.Background(), ctx)
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, err, &inputErr)
+	require.Equal(t, phases.InputKindSelect, inputErr.Input.Kind)
+	var values []string
+	for _, opt := range inputErr.Input.Options {
+		values = append(values, opt.Value)
+	}
+	require.Equal(t, []string{"/home/ops/.ssh/id_ed25519", "agent:SHA256:agent", keyPathOther}, values)
+
+	phases.SetInput(ctx, phaseID, InputKeyPath, keyPathOther)
+	err = phase.Run(context.Background(), ctx)
+	require.ErrorAs(t, err, &inputErr)
+	require.Equal(t, phases.InputKindText, inputErr.Input.Kind)
+
+	phases.SetInput(ctx, phaseID, InputKeyPath, "agent:SHA256:agent")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.True(t, capturedCred.Agent)
+	require.Equal(t, "SHA256:agent", capturedCred.AgentFingerprint)
+}
+
 func TestPhaseValidationError(t *testing.T) {
 	t.Parallel()
 
-	phase := New()
+	phase := New().WithHostDiscoverer(func() []mdns.Service { return nil })
 	ctx := phases.NewContext()
 	setInputs(ctx, map[string]string{
 		InputUsername:   "deploy",
@@ -89,6 +141,74 @@ func TestPhaseValidationError(t *testing.T) {
 	var inputErr phases.InputRequestError
 	require.ErrorAs(t, err, &inputErr)
 	require.Equal(t, InputHost, inputErr.Input.ID)
+	require.Equal(t, phases.InputKindText, inputErr.Input.Kind)
+}
+
+func TestPhaseOffersDiscoveredHosts(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithHostDiscoverer(func() []mdns.Service {
+		return []mdns.Service{
+			{Instance: "raspberrypi", Host: "raspberrypi.local", Port: 22, Addrs: []net.IP{net.IPv4(192, 168, 1, 42)}},
+			{Instance: "nas", Host: "nas.local", Port: 2222},
+			{Instance: "unresolved"},
+		}
+	})
+	ctx := phases.NewContext()
+
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputHost, inputErr.Input.ID)
+	require.Equal(t, phases.InputKindSelect, inputErr.Input.Kind)
+	require.Equal(t, []phases.InputOption{
+		{Value: "192.168.1.42", Label: "raspberrypi", Description: "raspberrypi.local (192.168.1.42)"},
+		{Value: "nas.local:2222", Label: "nas", Description: "nas.local, SSH on port 2222"},
+		{Value: hostOther, Label: "Other…", Description: "Enter a hostname or IP manually"},
+	}, inputErr.Input.Options)
+
+	setInputs(ctx, map[string]string{InputHost: hostOther})
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, phases.InputKindText, inputErr.Input.Kind)
+}
+
+func TestPhaseOffersScannedHosts(t *testing.T) {
+	t.Parallel()
+
+	phase := New().
+		WithHostDiscoverer(func() []mdns.Service {
+			return []mdns.Service{{Instance: "pi", Host: "pi.local", Addrs: []net.IP{net.IPv4(10, 0, 0, 5)}}}
+		}).
+		WithHostScanner(func() []netscan.Candidate {
+			return []netscan.Candidate{
+				{Addr: netip.MustParseAddr("10.0.0.5"), Port: 22, Banner: "SSH-2.0-OpenSSH_9.2p1"},
+				{Addr: netip.MustParseAddr("10.0.0.9"), Port: 22, Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"},
+			}
+		})
+
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), phases.NewContext()), &inputErr)
+	require.Len(t, inputErr.Input.Options, 3)
+	require.Equal(t, "10.0.0.5", inputErr.Input.Options[0].Value)
+	require.Equal(t, phases.InputOption{Value: "10.0.0.9", Label: "10.0.0.9", Description: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3"}, inputErr.Input.Options[1])
+}
+
+func TestPhaseSuggestsDefaultHost(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithHostDiscoverer(func() []mdns.Service { return nil }).WithDefaultHost("10.0.0.7")
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), phases.NewContext()), &inputErr)
+	require.Equal(t, phases.InputKindText, inputErr.Input.Kind)
+	require.Equal(t, "10.0.0.7", inputErr.Input.Default)
+
+	phase.WithHostDiscoverer(func() []mdns.Service {
+		return []mdns.Service{{Instance: "pi", Host: "pi.local", Addrs: []net.IP{net.IPv4(10, 0, 0, 5)}}}
+	})
+	require.ErrorAs(t, phase.Run(context.Background(), phases.NewContext()), &inputErr)
+	require.Equal(t, phases.InputKindSelect, inputErr.Input.Kind)
+	require.Equal(t, "10.0.0.7", inputErr.Input.Default)
+	require.Equal(t, phases.InputOption{Value: "10.0.0.7", Label: "10.0.0.7"}, inputErr.Input.Options[0])
+	require.Len(t, inputErr.Input.Options, 3)
 }
 
 func TestPhasePropagatesConnectorError(t *testing.T) {
@@ -135,3 +255,259 @@ func setInputs(ctx *phases.Context, values map[string]string) {
 		phases.SetInput(ctx, phaseID, id, value)
 	}
 }
+
+func TestPhasePassesConnectOptions(t *testing.T) {
+	t.Parallel()
+
+	var got int
+	phase := New().
+		WithConnectOptions(sshconnection.WithRetryWindow(time.Minute), sshconnection.WithMDNS()).
+		WithConnector(func(_ string, _ int, _ string, _ sshconnection.Credential, opts ...sshconnection.Option) (*ssh.Client, error) {
+			got = len(opts)
+			return &ssh.Client{}, nil
+		})
+
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "raspberrypi.local",
+		InputUsername:   "pi",
+		InputAuthMethod: authMethodPassword,
+		InputPassword:   "raspberry",
+	})
+
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	// The configured options plus WithConnectionInfo.
+	require.Equal(t, 3, got)
+}
+
+// closeRecorder stands in for a live connection; only Close is called.
+type closeRecorder struct {
+	ssh.Conn
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestPhaseReusesHealthyClient(t *testing.T) {
+	t.Parallel()
+
+	var conns []*closeRecorder
+	healthErr := error(nil)
+	phase := New().
+		WithConnector(func(string, int, string, sshconnection.Credential, ...sshconnection.Option) (*ssh.Client, error) {
+			conn := &closeRecorder{}
+			conns = append(conns, conn)
+			return &ssh.Client{Conn: conn}, nil
+		}).
+		WithHealthChecker(func(*ssh.Client) error { return healthErr })
+
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "example.com",
+		InputUsername:   "deploy",
+		InputAuthMethod: authMethodPassword,
+		InputPassword:   "secret",
+	})
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Len(t, conns, 1)
+
+	healthErr = sshconnection.UnhealthyError{Check: "exec", Err: errors.New("EOF")}
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Len(t, conns, 2)
+	require.True(t, conns[0].closed)
+
+	// Another target never reuses the client.
+	healthErr = nil
+	phases.SetInput(ctx, phaseID, InputUsername, "admin")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Len(t, conns, 3)
+	require.True(t, conns[1].closed)
+}
+
+func TestPhaseDropsStaleConnectionInfo(t *testing.T) {
+	t.Parallel()
+
+	phase := New().WithConnector(func(string, int, string, sshconnection.Credential, ...sshconnection.Option) (*ssh.Client, error) {
+		return nil, errors.New("connect failed")
+	})
+
+	ctx := phases.NewContext()
+	ctx.Set(ContextKeyConnectionInfo, sshconnection.ConnectionInfo{ServerVersion: "SSH-2.0-stale"})
+	setInputs(ctx, map[string]string{
+		InputHost:       "example.com",
+		InputUsername:   "deploy",
+		InputAuthMethod: authMethodPassword,
+		InputPassword:   "secret",
+	})
+
+	require.EqualError(t, phase.Run(context.Background(), ctx), "connect failed")
+	_, ok := ctx.Get(ContextKeyConnectionInfo)
+	require.False(t, ok, "info from an earlier connection must not survive")
+}
+
+// vaultCredentials stands in for a provider that reads the password from a
+// secret store.
+type vaultCredentials struct {
+	secrets map[string]string
+}
+
+func (vaultCredentials) Method() phases.InputOption {
+	return phases.InputOption{Value: "vault", Label: "Vault"}
+}
+
+func (vaultCredentials) Inputs() []phases.InputDefinition {
+	return []phases.InputDefinition{{ID: "vault_path", Label: "Vault Path", Kind: phases.InputKindText}}
+}
+
+func (v vaultCredentials) Resolve(phaseCtx *phases.Context) (sshconnection.Credential, error) {
+	path, ok := phases.GetInputString(phaseCtx, phaseID, "vault_path")
+	if !ok || path == "" {
+		return sshconnection.Credential{}, phases.InputRequestError{PhaseID: phaseID, Input: v.Inputs()[0], Reason: "vault path is required"}
+	}
+	return sshconnection.Credential{Password: v.secrets[path]}, nil
+}
+
+func TestPhaseUsesCredentialProviders(t *testing.T) {
+	t.Parallel()
+
+	var capturedCred sshconnection.Credential
+	phase := New().
+		WithCredentialProvider(vaultCredentials{secrets: map[string]string{"hosts/web01": "from-vault"}}).
+		WithConnector(func(_ string, _ int, _ string, cred sshconnection.Credential, _ ...sshconnection.Option) (*ssh.Client, error) {
+			capturedCred = cred
+			return &ssh.Client{}, nil
+		})
+
+	meta := phase.Metadata()
+	var ids []string
+	for _, def := range meta.Inputs {
+		ids = append(ids, def.ID)
+		if def.ID == InputAuthMethod {
+			require.Equal(t, []phases.InputOption{
+				{Value: authMethodPassword, Label: "Password"},
+				{Value: authMethodKeyPath, Label: "Private Key"},
+				{Value: authMethodCertificate, Label: "Certificate"},
+				{Value: "vault", Label: "Vault"},
+			}, def.Options)
+		}
+	}
+	require.Equal(t, []string{InputHost, InputPort, InputUsername, InputAuthMethod, InputPassword, InputKeyPath, InputCertPath, "vault_path"}, ids)
+
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "web01",
+		InputUsername:   "deploy",
+		InputAuthMethod: "vault",
+	})
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, "vault_path", inputErr.Input.ID)
+
+	phases.SetInput(ctx, phaseID, "vault_path", "hosts/web01")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, "from-vault", capturedCred.Password)
+	password, _ := ctx.Get(ContextKeySSHPassword)
+	require.Equal(t, "from-vault", password)
+	method, _ := ctx.Get(ContextKeyAuthMethod)
+	require.Equal(t, "vault", method)
+}
+
+// fixedPassword replaces the built-in password method.
+type fixedPassword string
+
+func (fixedPassword) Method() phases.InputOption {
+	return phases.InputOption{Value: authMethodPassword, Label: "Stored password"}
+}
+
+func (f fixedPassword) Resolve(*phases.Context) (sshconnection.Credential, error) {
+	return sshconnection.Credential{Password: string(f)}, nil
+}
+
+func TestPhaseCredentialProviderReplacesBuiltIn(t *testing.T) {
+	t.Parallel()
+
+	var capturedCred sshconnection.Credential
+	phase := New().
+		WithCredentialProvider(fixedPassword("stored")).
+		WithConnector(func(_ string, _ int, _ string, cred sshconnection.Credential, _ ...sshconnection.Option) (*ssh.Client, error) {
+			capturedCred = cred
+			return &ssh.Client{}, nil
+		})
+	require.Len(t, phase.credentials, 3)
+	require.Equal(t, "Stored password", phase.authMethodDefinition().Options[0].Label)
+
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "web01",
+		InputUsername:   "deploy",
+		InputAuthMethod: "kerberos",
+	})
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputAuthMethod, inputErr.Input.ID)
+	require.Len(t, inputErr.Input.Options, 3)
+
+	phases.SetInput(ctx, phaseID, InputAuthMethod, authMethodPassword)
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, "stored", capturedCred.Password)
+}
+
+func TestPhaseCertificateCredentials(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	var capturedCred sshconnection.Credential
+	phase := New().WithConnector(func(_ string, _ int, _ string, cred sshconnection.Credential, _ ...sshconnection.Option) (*ssh.Client, error) {
+		capturedCred = cred
+		return &ssh.Client{}, nil
+	})
+
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "web01",
+		InputUsername:   "deploy",
+		InputAuthMethod: authMethodCertificate,
+		InputKeyPath:    agentKeyPrefix + "SHA256:abc",
+	})
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputKeyPath, inputErr.Input.ID)
+
+	phases.SetInput(ctx, phaseID, InputKeyPath, keyPath)
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputCertPath, inputErr.Input.ID)
+	require.Contains(t, inputErr.Reason, keyPath+"-cert.pub")
+
+	require.NoError(t, os.WriteFile(keyPath+"-cert.pub", []byte("cert"), 0o600))
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, sshconnection.Credential{KeyPath: keyPath, CertPath: keyPath + "-cert.pub"}, capturedCred)
+	method, _ := ctx.Get(ContextKeyAuthMethod)
+	require.Equal(t, AuthMethodCertificate, method)
+
+	phases.SetInput(ctx, phaseID, InputCertPath, filepath.Join(dir, "issued.pub"))
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, filepath.Join(dir, "issued.pub"), capturedCred.CertPath)
+}
+
+func TestExportOmitsSSHPassword(t *testing.T) {
+	t.Parallel()
+
+	phaseCtx := phases.NewContext()
+	phases.SetContext(phaseCtx, KeySSHPassword, "hunter2")
+	phaseCtx.Set(ContextKeyTargetHost, "web01")
+
+	data, err := phaseCtx.Export()
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hunter2")
+
+	var snap phases.Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+	require.Equal(t, []string{ContextKeySSHPassword}, snap.Omitted)
+	require.NotContains(t, snap.Values, ContextKeySSHPassword)
+	require.Contains(t, snap.Values, ContextKeyTargetHost)
+}
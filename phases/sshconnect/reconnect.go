@@ -0,0 +1,191 @@
+package sshconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+)
+
+const (
+	defaultReconnectTimeout  = 5 * time.Minute
+	defaultReconnectInterval = 5 * time.Second
+	defaultMaxReconnects     = 3
+)
+
+// Probe reports whether the SSH connection stored in the phase context still
+// works. Contexts without a client count as alive.
+type Probe func(phaseCtx *phases.Context) bool
+
+// ReconnectOption customizes Reconnect.
+type ReconnectOption func(*reconnector)
+
+// WithReconnectTimeout bounds how long to wait for the host to accept SSH
+// connections again (default 5m).
+func WithReconnectTimeout(d time.Duration) ReconnectOption {
+	return func(r *reconnector) {
+		if d > 0 {
+			r.timeout = d
+		}
+	}
+}
+
+// WithReconnectInterval sets the pause between reconnect attempts (default 5s).
+func WithReconnectInterval(d time.Duration) ReconnectOption {
+	return func(r *reconnector) {
+		if d > 0 {
+			r.interval = d
+		}
+	}
+}
+
+// WithMaxReconnects limits how many times one phase run is retried after
+// reconnecting (default 3).
+func WithMaxReconnects(n int) ReconnectOption {
+	return func(r *reconnector) {
+		if n > 0 {
+			r.maxReconnects = n
+		}
+	}
+}
+
+// WithProbe overrides how a dropped connection is detected (useful for tests).
+func WithProbe(fn Probe) ReconnectOption {
+	return func(r *reconnector) {
+		if fn != nil {
+			r.probe = fn
+		}
+	}
+}
+
+// HostLostError reports a target whose SSH connection dropped mid-phase and
+// that did not accept connections again before the reconnect timeout.
+type HostLostError struct {
+	Host    string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e HostLostError) Error() string {
+	return fmt.Sprintf("ssh connection to %s lost and the host did not come back within %s: %v", e.Host, e.Timeout, e.Err)
+}
+
+func (e HostLostError) Unwrap() error {
+	return e.Err
+}
+
+type reconnector struct {
+	restore       []phases.Phase
+	timeout       time.Duration
+	interval      time.Duration
+	maxReconnects int
+	probe         Probe
+}
+
+// Reconnect returns middleware that survives the target going away mid-phase,
+// for example because it rebooted. When a wrapped phase fails and the SSH
+// client in the context no longer answers, it reports a "waiting for host"
+// task, re-runs the restore phases (typically this package's phase followed
+// by sudoensure) until the host accepts connections again, and then retries
+// the interrupted phase. The SSH connection phase itself is never wrapped.
+func Reconnect(restore []phases.Phase, opts ...ReconnectOption) phases.PhaseMiddleware {
+	r := &reconnector{
+		restore:       restore,
+		timeout:       defaultReconnectTimeout,
+		interval:      defaultReconnectInterval,
+		maxReconnects: defaultMaxReconnects,
+		probe:         healthyClient,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+
+	return func(next phases.Phase) phases.Phase {
+		meta := next.Metadata()
+		if meta.ID == phaseID {
+			return next
+		}
+		return phases.WrapRun(next, func(ctx context.Context, phaseCtx *phases.Context) error {
+			for attempt := 0; ; attempt++ {
+				err := next.Run(ctx, phaseCtx)
+				if err == nil || phaseCtx == nil || attempt >= r.maxReconnects || ctx.Err() != nil {
+					return err
+				}
+				var inputErr phases.InputRequestError
+				if errors.As(err, &inputErr) || r.probe(phaseCtx) {
+					return err
+				}
+				if err := r.recover(ctx, phaseCtx, meta.ID); err != nil {
+					return err
+				}
+			}
+		})
+	}
+}
+
+// recover waits for the host to come back by re-running the restore phases,
+// skipping the interrupted phase since it is retried anyway.
+func (r *reconnector) recover(ctx context.Context, phaseCtx *phases.Context, interrupted string) error {
+	host, _ := phaseCtx.Get(ContextKeyTargetHost)
+	hostName := fmt.Sprint(host)
+	task := fmt.Sprintf("Waiting for %s to come back", hostName)
+	phases.ReportTask(ctx, phases.TaskEvent{Task: task, Status: phases.TaskStarted})
+	closeClient(phaseCtx)
+
+	deadline := time.Now().Add(r.timeout)
+	timer := time.NewTimer(r.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		err := r.runRestore(ctx, phaseCtx, interrupted)
+		if err == nil {
+			phases.ReportTask(ctx, phases.TaskEvent{Task: task, Host: hostName, Status: phases.TaskOK})
+			return nil
+		}
+		var inputErr phases.InputRequestError
+		if errors.As(err, &inputErr) {
+			return err
+		}
+		if !time.Now().Before(deadline) {
+			phases.ReportTask(ctx, phases.TaskEvent{Task: task, Host: hostName, Status: phases.TaskUnreachable, Message: err.Error()})
+			return HostLostError{Host: hostName, Timeout: r.timeout, Err: err}
+		}
+		timer.Reset(r.interval)
+	}
+}
+
+func (r *reconnector) runRestore(ctx context.Context, phaseCtx *phases.Context, interrupted string) error {
+	for _, phase := range r.restore {
+		if phase == nil || phase.Metadata().ID == interrupted {
+			continue
+		}
+		if err := phase.Run(ctx, phaseCtx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func healthyClient(phaseCtx *phases.Context) bool {
+	client, ok := phases.GetContext(phaseCtx, KeySSHClient)
+	if !ok || client == nil {
+		return true
+	}
+	return sshconnection.HealthCheck(client) == nil
+}
+
+func closeClient(phaseCtx *phases.Context) {
+	if client, ok := phases.GetContext(phaseCtx, KeySSHClient); ok && client != nil && client.Conn != nil {
+		_ = client.Close()
+	}
+}
@@ -0,0 +1,124 @@
+package sshconnect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+func TestReconnectRetriesPhaseAfterHostReturns(t *testing.T) {
+	t.Parallel()
+
+	ctx := phases.NewContext()
+	ctx.Set(ContextKeyTargetHost, "10.0.0.5")
+
+	var restores, runs int
+	restore := stubPhase{id: "sudo_ensure", run: func(context.Context, *phases.Context) error {
+		restores++
+		if restores < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}}
+	alive := false
+	interrupted := stubPhase{id: "python_ensure", run: func(context.Context, *phases.Context) error {
+		runs++
+		if runs == 1 {
+			return errors.New("EOF")
+		}
+		return nil
+	}}
+
+	mw := Reconnect([]phases.Phase{restore},
+		WithReconnectInterval(time.Millisecond),
+		WithProbe(func(*phases.Context) bool {
+			defer func() { alive = true }()
+			return alive
+		}),
+	)
+	observer := &taskRecorder{}
+	manager := phases.NewManager(phases.WithObserver(observer), phases.WithMiddleware(mw))
+	require.NoError(t, manager.Register(interrupted))
+	require.NoError(t, manager.Run(context.Background(), ctx))
+
+	require.Equal(t, 2, runs)
+	require.Equal(t, 3, restores)
+	require.Equal(t, []phases.TaskEvent{
+		{Task: "Waiting for 10.0.0.5 to come back", Status: phases.TaskStarted},
+		{Task: "Waiting for 10.0.0.5 to come back", Host: "10.0.0.5", Status: phases.TaskOK},
+	}, observer.events)
+}
+
+func TestReconnectGivesUpWhenHostStaysDown(t *testing.T) {
+	t.Parallel()
+
+	ctx := phases.NewContext()
+	ctx.Set(ContextKeyTargetHost, "10.0.0.5")
+	down := errors.New("no route to host")
+
+	mw := Reconnect([]phases.Phase{stubPhase{id: phaseID, run: func(context.Context, *phases.Context) error { return down }}},
+		WithReconnectInterval(time.Millisecond),
+		WithReconnectTimeout(10*time.Millisecond),
+		WithProbe(func(*phases.Context) bool { return false }),
+	)
+	phase := mw(stubPhase{id: "python_ensure", run: func(context.Context, *phases.Context) error { return errors.New("EOF") }})
+
+	err := phase.Run(context.Background(), ctx)
+	var lost HostLostError
+	require.ErrorAs(t, err, &lost)
+	require.Equal(t, "10.0.0.5", lost.Host)
+	require.ErrorIs(t, err, down)
+}
+
+func TestReconnectLeavesOtherFailuresAlone(t *testing.T) {
+	t.Parallel()
+
+	failure := errors.New("package not found")
+	restored := false
+	mw := Reconnect([]phases.Phase{stubPhase{id: phaseID, run: func(context.Context, *phases.Context) error {
+		restored = true
+		return nil
+	}}}, WithProbe(func(*phases.Context) bool { return true }))
+
+	runs := 0
+	phase := mw(stubPhase{id: "python_ensure", run: func(context.Context, *phases.Context) error {
+		runs++
+		return failure
+	}})
+	require.ErrorIs(t, phase.Run(context.Background(), phases.NewContext()), failure)
+	require.Equal(t, 1, runs)
+	require.False(t, restored)
+
+	// The SSH connection phase itself is never wrapped.
+	connect := New()
+	require.Same(t, connect, mw(connect))
+}
+
+type stubPhase struct {
+	id  string
+	run func(context.Context, *phases.Context) error
+}
+
+func (s stubPhase) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{ID: s.id, Title: s.id}
+}
+
+func (s stubPhase) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	return s.run(ctx, phaseCtx)
+}
+
+type taskRecorder struct {
+	events []phases.TaskEvent
+}
+
+func (r *taskRecorder) PhaseStarted(phases.PhaseMetadata)          {}
+func (r *taskRecorder) PhaseCompleted(phases.PhaseMetadata, error) {}
+
+func (r *taskRecorder) TaskProgress(_ phases.PhaseMetadata, event phases.TaskEvent) {
+	r.events = append(r.events, event)
+}
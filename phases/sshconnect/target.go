@@ -0,0 +1,72 @@
+package sshconnect
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// splitTarget parses the host input, which may carry a port: "host",
+// "host:2222", "2001:db8::1", "[2001:db8::1]", or "[2001:db8::1]:2222".
+// port is 0 when the input has none. IPv6 literals, brackets or not, must
+// be valid addresses; a zone such as fe80::1%eth0 is kept. reason explains
+// a malformed input.
+func splitTarget(input string) (host string, port int, reason string) {
+	input = strings.TrimSpace(input)
+	switch {
+	case strings.HasPrefix(input, "["):
+		host = strings.TrimSuffix(strings.TrimPrefix(input, "["), "]")
+		if !strings.HasSuffix(input, "]") {
+			h, p, err := net.SplitHostPort(input)
+			if err != nil {
+				return "", 0, "use [address]:port for an IPv6 address with a port"
+			}
+			if port, reason = parsePort(p); reason != "" {
+				return "", 0, reason
+			}
+			host = h
+		}
+		if _, err := netip.ParseAddr(host); err != nil || !strings.Contains(host, ":") {
+			return "", 0, "brackets must enclose an IPv6 address"
+		}
+	case strings.Count(input, ":") > 1:
+		if _, err := netip.ParseAddr(input); err != nil {
+			return "", 0, "invalid IPv6 address; use [address]:port to add a port"
+		}
+		host = input
+	case strings.Contains(input, ":"):
+		h, p, err := net.SplitHostPort(input)
+		if err != nil || h == "" {
+			return "", 0, "use host:port to add a port"
+		}
+		if port, reason = parsePort(p); reason != "" {
+			return "", 0, reason
+		}
+		host = h
+	default:
+		host = input
+	}
+	if host == "" {
+		return "", 0, "host is required"
+	}
+	return host, port, ""
+}
+
+// parsePort parses a TCP port number.
+func parsePort(value string) (int, string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, "port must be between 1 and 65535"
+	}
+	return port, ""
+}
+
+// targetValue formats host and port for the host input, leaving out the
+// default port.
+func targetValue(host string, port int) string {
+	if port == 0 || port == 22 {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,87 @@
+package sshconnect
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+)
+
+func TestSplitTarget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		host    string
+		port    int
+		invalid bool
+	}{
+		{input: "example.com", host: "example.com"},
+		{input: " 10.0.0.5:2222 ", host: "10.0.0.5", port: 2222},
+		{input: "pi.local:22", host: "pi.local", port: 22},
+		{input: "2001:db8::1", host: "2001:db8::1"},
+		{input: "[2001:db8::1]", host: "2001:db8::1"},
+		{input: "[2001:db8::1]:2222", host: "2001:db8::1", port: 2222},
+		{input: "[fe80::1%eth0]:22", host: "fe80::1%eth0", port: 22},
+		{input: "example.com:ssh", invalid: true},
+		{input: "example.com:70000", invalid: true},
+		{input: ":2222", invalid: true},
+		{input: "2001:db8::1:2222:zz", invalid: true},
+		{input: "[2001:db8::1", invalid: true},
+		{input: "[example.com]:22", invalid: true},
+		{input: "[10.0.0.5]", invalid: true},
+	}
+	for _, tt := range tests {
+		host, port, reason := splitTarget(tt.input)
+		if tt.invalid {
+			require.NotEmpty(t, reason, tt.input)
+			continue
+		}
+		require.Empty(t, reason, tt.input)
+		require.Equal(t, tt.host, host, tt.input)
+		require.Equal(t, tt.port, port, tt.input)
+	}
+}
+
+func TestPhaseTakesPortFromHost(t *testing.T) {
+	t.Parallel()
+
+	var gotHost string
+	var gotPort int
+	phase := New().WithConnector(func(host string, port int, _ string, _ sshconnection.Credential, _ ...sshconnection.Option) (*ssh.Client, error) {
+		gotHost, gotPort = host, port
+		return &ssh.Client{}, nil
+	})
+	ctx := phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "[2001:db8::1]:2222",
+		InputUsername:   "deploy",
+		InputAuthMethod: authMethodPassword,
+		InputPassword:   "secret",
+	})
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, "2001:db8::1", gotHost)
+	require.Equal(t, 2222, gotPort)
+	host, _ := ctx.Get(ContextKeyTargetHost)
+	require.Equal(t, "2001:db8::1", host)
+
+	ctx = phases.NewContext()
+	setInputs(ctx, map[string]string{
+		InputHost:       "example.com:2222",
+		InputPort:       "2200",
+		InputUsername:   "deploy",
+		InputAuthMethod: authMethodPassword,
+		InputPassword:   "secret",
+	})
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputPort, inputErr.Input.ID)
+
+	setInputs(ctx, map[string]string{InputHost: "[example.com]:2222"})
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputHost, inputErr.Input.ID)
+}
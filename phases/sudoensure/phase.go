@@ -3,6 +3,8 @@ package sudoensure
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 
@@ -13,28 +15,41 @@ import (
 
 const (
 	phaseID = "sudo_ensure"
+	// PhaseID identifies the phase, e.g. in other phases' Requires.
+	PhaseID = phaseID
 
 	// Input identifiers
 	InputPassword = "password"
+	// InputElevation picks the elevation methods: ElevationAuto, ElevationSu
+	// (try su first), or ElevationSudo (sudo only).
+	InputElevation = "elevation"
+	// InputInstallSudo ("yes"/"no") allows installing sudo on hosts without it.
+	InputInstallSudo = "install_sudo"
+
+	ElevationAuto = "auto"
+	ElevationSu   = "su"
+	ElevationSudo = "sudo"
 
 	// Context keys
 	ContextKeyElevatedClient = "sudo:elevated_client"
 )
 
+// KeyElevatedClient is the typed key for ContextKeyElevatedClient.
+var KeyElevatedClient = phases.NewKey[*privilege.ElevatedClient](ContextKeyElevatedClient)
+
 // Ensurer wraps privilege escalation.
-type Ensurer func(client *ssh.Client, password privilege.Password) (*privilege.ElevatedClient, error)
+type Ensurer func(client *ssh.Client, password privilege.Password, opts ...privilege.Option) (*privilege.ElevatedClient, error)
 
 // Phase ensures sudo/root access is available.
 type Phase struct {
-	ensure Ensurer
+	ensure        Ensurer
+	elevationOpts []privilege.Option
 }
 
 // New creates a Phase that uses privilege.EnsureElevatedClient.
 func New() *Phase {
 	return &Phase{
-		ensure: func(client *ssh.Client, password privilege.Password) (*privilege.ElevatedClient, error) {
-			return privilege.EnsureElevatedClient(client, password)
-		},
+		ensure: privilege.EnsureElevatedClient,
 	}
 }
 
@@ -46,11 +61,20 @@ func (p *Phase) WithEnsurer(fn Ensurer) *Phase {
 	return p
 }
 
+// WithElevationOptions passes opts to every privilege.EnsureElevatedClient
+// call, ahead of those derived from the phase inputs.
+func (p *Phase) WithElevationOptions(opts ...privilege.Option) *Phase {
+	p.elevationOpts = append(p.elevationOpts, opts...)
+	return p
+}
+
 func (p *Phase) Metadata() phases.PhaseMetadata {
 	return phases.PhaseMetadata{
-		ID:          phaseID,
-		Title:       "Ensure Sudo",
-		Description: "Validate sudo access and install sudo if required.",
+		ID:                phaseID,
+		Title:             "Ensure Sudo",
+		Requires:          []string{sshconnect.PhaseID},
+		Description:       "Validate sudo access and install sudo if required.",
+		EstimatedDuration: 20 * time.Second,
 		Inputs: []phases.InputDefinition{
 			{
 				ID:          InputPassword,
@@ -60,36 +84,57 @@ func (p *Phase) Metadata() phases.PhaseMetadata {
 				Secret:      true,
 				Required:    false,
 			},
+			{
+				ID:          InputElevation,
+				Label:       "Elevation Method",
+				Description: "How to become root: sudo with a su fallback, su first, or sudo only.",
+				Kind:        phases.InputKindSelect,
+				Default:     ElevationAuto,
+				Options: []phases.InputOption{
+					{Value: ElevationAuto, Label: "Auto"},
+					{Value: ElevationSu, Label: "Prefer su"},
+					{Value: ElevationSudo, Label: "sudo only"},
+				},
+			},
+			{
+				ID:          InputInstallSudo,
+				Label:       "Install sudo",
+				Description: "Install sudo on hosts without it; with no, su is used there and the ansible user's sudoers rule waits for sudo to be installed.",
+				Kind:        phases.InputKindSelect,
+				Default:     "yes",
+				Options: []phases.InputOption{
+					{Value: "yes", Label: "Yes"},
+					{Value: "no", Label: "No"},
+				},
+			},
 		},
 	}
 }
 
 func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 	if p.ensure == nil {
-		p.ensure = func(client *ssh.Client, password privilege.Password) (*privilege.ElevatedClient, error) {
-			return privilege.EnsureElevatedClient(client, password)
-		}
+		p.ensure = privilege.EnsureElevatedClient
 	}
 	if phaseCtx == nil {
 		phaseCtx = phases.NewContext()
 	}
 
-	clientVal, ok := phaseCtx.Get(sshconnect.ContextKeySSHClient)
-	if !ok {
+	client, ok := phases.GetContext(phaseCtx, sshconnect.KeySSHClient)
+	if !ok || client == nil {
 		return phases.ValidationError{Reason: "SSH connection phase must complete before sudo phase"}
 	}
 
-	client, ok := clientVal.(*ssh.Client)
-	if !ok || client == nil {
-		return phases.ValidationError{Reason: "invalid ssh client in context"}
+	password, inputErr := p.resolvePassword(phaseCtx)
+	if inputErr != nil {
+		return inputErr
 	}
 
-	password, inputErr := p.resolvePassword(phaseCtx)
+	opts, inputErr := p.elevationOptions(phaseCtx)
 	if inputErr != nil {
 		return inputErr
 	}
 
-	elevated, err := p.ensure(client, privilege.Password{Value: password})
+	elevated, err := p.ensure(client, privilege.Password{Value: password}, opts...)
 	if err != nil {
 		if shouldRequestPassword(err) {
 			phaseCtx.Set(sshconnect.ContextKeySSHPassword, nil)
@@ -102,12 +147,44 @@ func (p *Phase) Run(ctx context.Context, phaseCtx *phases.Context) error {
 		return err
 	}
 
-	phaseCtx.Set(ContextKeyElevatedClient, elevated)
-	phaseCtx.Set(sshconnect.ContextKeySSHPassword, password)
+	phases.SetContext(phaseCtx, KeyElevatedClient, elevated)
+	phases.SetContext(phaseCtx, sshconnect.KeySSHPassword, password)
 
 	return nil
 }
 
+func (p *Phase) elevationOptions(ctx *phases.Context) ([]privilege.Option, error) {
+	opts := append([]privilege.Option{}, p.elevationOpts...)
+	method, _ := phases.GetInputString(ctx, phaseID, InputElevation)
+	switch method {
+	case "", ElevationAuto:
+	case ElevationSu:
+		opts = append(opts, privilege.WithPreferSu())
+	case ElevationSudo:
+		opts = append(opts, privilege.WithSudoOnly())
+	default:
+		return nil, p.inputRequest(InputElevation, fmt.Sprintf("unknown elevation method %q", method))
+	}
+	install, ok, err := phases.GetInputBool(ctx, phaseID, InputInstallSudo)
+	if err != nil {
+		return nil, p.inputRequest(InputInstallSudo, "install sudo must be yes or no")
+	}
+	if ok && !install {
+		opts = append(opts, privilege.WithoutSudoInstall())
+	}
+	return opts, nil
+}
+
+func (p *Phase) inputRequest(inputID, reason string) phases.InputRequestError {
+	req := phases.InputRequestError{PhaseID: phaseID, Reason: reason}
+	for _, def := range p.Metadata().Inputs {
+		if def.ID == inputID {
+			req.Input = def
+		}
+	}
+	return req
+}
+
 func (p *Phase) resolvePassword(ctx *phases.Context) (string, error) {
 	if val, ok := ctx.Get(sshconnect.ContextKeySSHPassword); ok {
 		if str, ok := val.(string); ok && str != "" {
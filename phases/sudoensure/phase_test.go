@@ -20,7 +20,7 @@ func TestPhaseUsesExistingPassword(t *testing.T) {
 	called := false
 	fakeClient := &privilege.ElevatedClient{}
 
-	phase := New().WithEnsurer(func(client *ssh.Client, password privilege.Password) (*privilege.ElevatedClient, error) {
+	phase := New().WithEnsurer(func(client *ssh.Client, password privilege.Password, _ ...privilege.Option) (*privilege.ElevatedClient, error) {
 		require.Equal(t, expectedPassword, password.Value)
 		called = true
 		return fakeClient, nil
@@ -56,7 +56,7 @@ func TestPhaseRequestsPasswordWhenMissing(t *testing.T) {
 func TestPhaseRequestsNewPasswordOnAuthFailure(t *testing.T) {
 	t.Parallel()
 
-	phase := New().WithEnsurer(func(client *ssh.Client, password privilege.Password) (*privilege.ElevatedClient, error) {
+	phase := New().WithEnsurer(func(client *ssh.Client, password privilege.Password, _ ...privilege.Option) (*privilege.ElevatedClient, error) {
 		return nil, privilege.SudoAuthenticationError{Err: errors.New("bad password")}
 	})
 
@@ -74,7 +74,7 @@ func TestPhaseRequestsNewPasswordOnAuthFailure(t *testing.T) {
 func TestPhasePropagatesOtherErrors(t *testing.T) {
 	t.Parallel()
 
-	phase := New().WithEnsurer(func(client *ssh.Client, password privilege.Password) (*privilege.ElevatedClient, error) {
+	phase := New().WithEnsurer(func(client *ssh.Client, password privilege.Password, _ ...privilege.Option) (*privilege.ElevatedClient, error) {
 		return nil, errors.New("network down")
 	})
 
@@ -85,3 +85,31 @@ func TestPhasePropagatesOtherErrors(t *testing.T) {
 	err := phase.Run(context.Background(), ctx)
 	require.EqualError(t, err, "network down")
 }
+
+func TestPhasePassesElevationOptions(t *testing.T) {
+	t.Parallel()
+
+	var got int
+	phase := New().
+		WithElevationOptions(privilege.WithoutSudoInstall()).
+		WithEnsurer(func(_ *ssh.Client, _ privilege.Password, opts ...privilege.Option) (*privilege.ElevatedClient, error) {
+			got = len(opts)
+			return &privilege.ElevatedClient{}, nil
+		})
+
+	ctx := phases.NewContext()
+	ctx.Set(sshconnect.ContextKeySSHClient, &ssh.Client{})
+	ctx.Set(sshconnect.ContextKeySSHPassword, "secret")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, 1, got)
+
+	phases.SetInput(ctx, phaseID, InputElevation, ElevationSudo)
+	phases.SetInput(ctx, phaseID, InputInstallSudo, "no")
+	require.NoError(t, phase.Run(context.Background(), ctx))
+	require.Equal(t, 3, got)
+
+	phases.SetInput(ctx, phaseID, InputElevation, "doas")
+	var inputErr phases.InputRequestError
+	require.ErrorAs(t, phase.Run(context.Background(), ctx), &inputErr)
+	require.Equal(t, InputElevation, inputErr.Input.ID)
+}
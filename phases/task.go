@@ -0,0 +1,63 @@
+package phases
+
+import "context"
+
+// TaskStatus is the state of a task reported by a long-running tool such as
+// ansible-playbook.
+type TaskStatus string
+
+const (
+	TaskStarted     TaskStatus = "started"
+	TaskOK          TaskStatus = "ok"
+	TaskChanged     TaskStatus = "changed"
+	TaskFailed      TaskStatus = "failed"
+	TaskSkipped     TaskStatus = "skipped"
+	TaskUnreachable TaskStatus = "unreachable"
+)
+
+// TaskEvent is one step of progress inside a phase. Host is empty for
+// TaskStarted, which covers every host the task runs on.
+type TaskEvent struct {
+	Task    string
+	Host    string
+	Status  TaskStatus
+	Message string
+}
+
+// TaskObserver is optionally implemented by observers that want per-task
+// progress from phases that drive multi-step tools.
+type TaskObserver interface {
+	TaskProgress(meta PhaseMetadata, event TaskEvent)
+}
+
+type taskHookKey struct{}
+
+type taskHook func(event TaskEvent)
+
+// ReportTask passes a task event to the manager's task observers. It is a
+// no-op when nothing is listening.
+func ReportTask(ctx context.Context, event TaskEvent) {
+	if ctx == nil {
+		return
+	}
+	if hook, ok := ctx.Value(taskHookKey{}).(taskHook); ok {
+		hook(event)
+	}
+}
+
+func withTaskHook(ctx context.Context, meta PhaseMetadata, observers []Observer) context.Context {
+	var taskObservers []TaskObserver
+	for _, obs := range observers {
+		if taskObs, ok := obs.(TaskObserver); ok {
+			taskObservers = append(taskObservers, taskObs)
+		}
+	}
+	if len(taskObservers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, taskHookKey{}, taskHook(func(event TaskEvent) {
+		for _, obs := range taskObservers {
+			obs.TaskProgress(meta, event)
+		}
+	}))
+}
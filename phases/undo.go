@@ -0,0 +1,141 @@
+package phases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Undoable is optionally implemented by phases that can revert what a
+// successful Run changed, e.g. removing a user they created. Undo must
+// tolerate state that is already gone.
+type Undoable interface {
+	Undo(ctx context.Context, phaseCtx *Context) error
+}
+
+// RollbackObserver is optionally implemented by observers that want to know
+// when Rollback reverts a phase.
+type RollbackObserver interface {
+	PhaseUndone(meta PhaseMetadata, err error)
+}
+
+// UndoFailure is a phase whose Undo failed during Rollback.
+type UndoFailure struct {
+	Phase PhaseMetadata
+	Err   error
+}
+
+// RollbackError lists the phases Rollback could not revert. Those phases
+// stay recorded as completed, so Rollback can be retried.
+type RollbackError struct {
+	Failures []UndoFailure
+}
+
+func (e RollbackError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		parts = append(parts, fmt.Sprintf("%s: %v", f.Phase.ID, f.Err))
+	}
+	return "rollback incomplete: " + strings.Join(parts, "; ")
+}
+
+func (e RollbackError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
+// UndoablePhases reports the phases that completed successfully and can be undone,
+// in the order Rollback would revert them.
+func (m *Manager) UndoablePhases() []PhaseMetadata {
+	completed := m.completedPhases()
+	var metas []PhaseMetadata
+	for i := len(completed) - 1; i >= 0; i-- {
+		if _, ok := completed[i].(Undoable); ok {
+			metas = append(metas, completed[i].Metadata())
+		}
+	}
+	return metas
+}
+
+// Rollback reverts completed phases in reverse order by calling Undo on those
+// that implement Undoable; other phases are skipped. Every undoable phase is
+// attempted even when an earlier Undo fails, and the failures are returned as
+// a RollbackError. Undo runs with the same command, task, and cleanup hooks
+// as Run, and observers implementing RollbackObserver hear about each phase.
+func (m *Manager) Rollback(ctx context.Context, phaseCtx *Context) error {
+	if phaseCtx == nil {
+		phaseCtx = NewContext()
+	}
+	// Undo runs without holding mu, so phases that complete meanwhile are
+	// recorded rather than blocked, and only undone phases are forgotten.
+	completed := m.completedPhases()
+	var failures []UndoFailure
+	for i := len(completed) - 1; i >= 0; i-- {
+		undoable, ok := completed[i].(Undoable)
+		if !ok {
+			continue
+		}
+		meta := completed[i].Metadata()
+		err := m.undoPhase(ctx, phaseCtx, undoable, meta)
+		m.notifyUndone(meta, err)
+		if err != nil {
+			failures = append(failures, UndoFailure{Phase: meta, Err: err})
+			continue
+		}
+		m.forgetCompleted(meta.ID)
+	}
+	if len(failures) > 0 {
+		return RollbackError{Failures: failures}
+	}
+	return nil
+}
+
+func (m *Manager) undoPhase(ctx context.Context, phaseCtx *Context, phase Undoable, meta PhaseMetadata) error {
+	ctx = withCommandHook(ctx, meta, m.observers)
+	ctx = withTaskHook(ctx, meta, m.observers)
+	runCtx, cleanup := withCleanupScope(ctx)
+	return cleanup.run(phase.Undo(runCtx, phaseCtx))
+}
+
+// markCompleted records phase as the most recent successful phase, replacing
+// an earlier record when the phase is re-run.
+func (m *Manager) markCompleted(phase Phase) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeCompleted(phase.Metadata().ID)
+	m.completed = append(m.completed, phase)
+}
+
+func (m *Manager) forgetCompleted(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeCompleted(id)
+}
+
+// removeCompleted drops the record for id; the caller holds mu.
+func (m *Manager) removeCompleted(id string) {
+	for i, done := range m.completed {
+		if done.Metadata().ID == id {
+			m.completed = append(m.completed[:i], m.completed[i+1:]...)
+			return
+		}
+	}
+}
+
+// completedPhases returns a copy of completed, in run order.
+func (m *Manager) completedPhases() []Phase {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Phase(nil), m.completed...)
+}
+
+func (m *Manager) notifyUndone(meta PhaseMetadata, err error) {
+	for _, obs := range m.observers {
+		if rollbackObs, ok := obs.(RollbackObserver); ok {
+			rollbackObs.PhaseUndone(meta, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package phases
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type undoablePhase struct {
+	fakePhase
+	undo func(context.Context, *Context) error
+}
+
+func (p *undoablePhase) Undo(ctx context.Context, c *Context) error {
+	return p.undo(ctx, c)
+}
+
+type undoRecorder struct {
+	ObserverFunc
+	undone []string
+	errs   []error
+}
+
+func (r *undoRecorder) PhaseUndone(meta PhaseMetadata, err error) {
+	r.undone = append(r.undone, meta.ID)
+	r.errs = append(r.errs, err)
+}
+
+func TestManagerRollbackUnwindsCompletedPhases(t *testing.T) {
+	t.Parallel()
+
+	var undone []string
+	undoable := func(id string, undoErr error) *undoablePhase {
+		return &undoablePhase{
+			fakePhase: fakePhase{meta: PhaseMetadata{ID: id}, run: func(context.Context, *Context) error { return nil }},
+			undo: func(ctx context.Context, _ *Context) error {
+				TraceCommand(ctx, "undo "+id)(nil)
+				undone = append(undone, id)
+				return undoErr
+			},
+		}
+	}
+	sudoersErr := errors.New("rm: read-only file system")
+	plain := &fakePhase{meta: PhaseMetadata{ID: "connect"}, run: func(context.Context, *Context) error { return nil }}
+	failing := &fakePhase{meta: PhaseMetadata{ID: "playbook"}, run: func(context.Context, *Context) error { return errors.New("boom") }}
+
+	observer := &undoRecorder{}
+	manager := NewManager(WithObserver(observer), WithMiddleware(func(next Phase) Phase {
+		// Middleware hides Undo; Rollback must still reach the registered phase.
+		return WrapRun(next, next.Run)
+	}))
+	require.NoError(t, manager.Register(plain, undoable("user", nil), undoable("sudoers", sudoersErr), failing))
+
+	require.Error(t, manager.Run(context.Background(), NewContext()))
+	require.Equal(t, []PhaseMetadata{{ID: "sudoers"}, {ID: "user"}}, manager.UndoablePhases())
+
+	err := manager.Rollback(context.Background(), NewContext())
+	var rollbackErr RollbackError
+	require.ErrorAs(t, err, &rollbackErr)
+	require.ErrorIs(t, err, sudoersErr)
+	require.Equal(t, "sudoers", rollbackErr.Failures[0].Phase.ID)
+	require.Equal(t, []string{"sudoers", "user"}, undone)
+	require.Equal(t, []string{"sudoers", "user"}, observer.undone)
+	require.Equal(t, []error{sudoersErr, nil}, observer.errs)
+
+	// Only the phase that failed to undo is left to retry.
+	require.Equal(t, []PhaseMetadata{{ID: "sudoers"}}, manager.UndoablePhases())
+}
+
+func TestManagerRecordsRerunPhaseOnce(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	phase := &undoablePhase{
+		fakePhase: fakePhase{meta: PhaseMetadata{ID: "user"}, run: func(context.Context, *Context) error { return nil }},
+		undo: func(context.Context, *Context) error {
+			calls++
+			return nil
+		},
+	}
+	manager := NewManager()
+	require.NoError(t, manager.Register(phase))
+	require.NoError(t, manager.Run(context.Background(), NewContext()))
+	require.NoError(t, manager.RunFrom(context.Background(), NewContext(), 0))
+
+	require.NoError(t, manager.Rollback(context.Background(), nil))
+	require.Equal(t, 1, calls)
+	require.Empty(t, manager.UndoablePhases())
+	require.NoError(t, manager.Rollback(context.Background(), nil))
+	require.Equal(t, 1, calls)
+}
+
+func TestManagerRollbackWhilePhasesComplete(t *testing.T) {
+	t.Parallel()
+
+	noop := func(context.Context, *Context) error { return nil }
+	manager := NewManager()
+	for _, id := range []string{"user", "sudoers", "python"} {
+		require.NoError(t, manager.Register(&undoablePhase{
+			fakePhase: fakePhase{meta: PhaseMetadata{ID: id}, run: noop},
+			undo:      noop,
+		}))
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = manager.Run(context.Background(), NewContext())
+		}()
+		go func() {
+			defer wg.Done()
+			_ = manager.UndoablePhases()
+			_ = manager.Rollback(context.Background(), nil)
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, manager.Rollback(context.Background(), nil))
+	require.Empty(t, manager.UndoablePhases())
+}
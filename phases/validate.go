@@ -0,0 +1,123 @@
+package phases
+
+import (
+	"sort"
+)
+
+// Inputs holds pre-supplied answers keyed by phase ID and then input ID, the
+// shape described by ExportSchema.
+type Inputs map[string]map[string]any
+
+// ValidateInputs checks supplied values against the registered phases' input
+// definitions and returns the normalized values.
+func (m *Manager) ValidateInputs(supplied Inputs) (Inputs, error) {
+	return ValidateInputs(m.Metadata(), supplied)
+}
+
+// ValidateInputs coerces every supplied value to its input kind, confirms
+// required inputs are present (or have a default, or are provided by an
+// earlier phase listed in their ProvidedBy), and checks select values
+// against their options. All problems are collected into a single
+// InputValidationError so headless callers can fix them in one pass.
+func ValidateInputs(metas []PhaseMetadata, supplied Inputs) (Inputs, error) {
+	return validateInputs(metas, supplied, true)
+}
+
+// ValidateSuppliedInputs checks only the values that were supplied; missing
+// required inputs are not problems. Interactive callers use it to seed a
+// partial set of answers and prompt for the rest.
+func ValidateSuppliedInputs(metas []PhaseMetadata, supplied Inputs) (Inputs, error) {
+	return validateInputs(metas, supplied, false)
+}
+
+func validateInputs(metas []PhaseMetadata, supplied Inputs, requireAll bool) (Inputs, error) {
+	known := make(map[string]PhaseMetadata, len(metas))
+	for _, meta := range metas {
+		known[meta.ID] = meta
+	}
+
+	var problems []InputProblem
+	for _, phaseID := range sortedKeys(supplied) {
+		if _, ok := known[phaseID]; !ok {
+			problems = append(problems, InputProblem{PhaseID: phaseID, Reason: "unknown phase"})
+		}
+	}
+
+	normalized := make(Inputs, len(supplied))
+	earlier := make(map[string]bool, len(metas))
+	for _, meta := range metas {
+		values := supplied[meta.ID]
+		defs := make(map[string]InputDefinition, len(meta.Inputs))
+		for _, def := range meta.Inputs {
+			defs[def.ID] = def
+		}
+		for _, inputID := range sortedKeys(values) {
+			if _, ok := defs[inputID]; !ok {
+				problems = append(problems, InputProblem{PhaseID: meta.ID, InputID: inputID, Reason: "unknown input"})
+			}
+		}
+
+		for _, def := range meta.Inputs {
+			raw, present := values[def.ID]
+			if !present {
+				if requireAll && def.Required && defaultString(def.Default) == "" && !providedBy(def, earlier) {
+					problems = append(problems, InputProblem{PhaseID: meta.ID, InputID: def.ID, Reason: "required input missing"})
+				}
+				continue
+			}
+			value, err := CoerceInput(def, raw)
+			if err != nil {
+				problems = append(problems, InputProblem{PhaseID: meta.ID, InputID: def.ID, Reason: err.Error()})
+				continue
+			}
+			if value == "" && def.Required && defaultString(def.Default) == "" && !providedBy(def, earlier) {
+				problems = append(problems, InputProblem{PhaseID: meta.ID, InputID: def.ID, Reason: "required input is empty"})
+				continue
+			}
+			if normalized[meta.ID] == nil {
+				normalized[meta.ID] = make(map[string]any)
+			}
+			normalized[meta.ID][def.ID] = value
+		}
+		earlier[meta.ID] = true
+	}
+
+	if len(problems) > 0 {
+		return nil, InputValidationError{Problems: problems}
+	}
+	return normalized, nil
+}
+
+// SeedInputs stores pre-supplied answers in the context so phases read them
+// instead of requesting input.
+func SeedInputs(ctx *Context, inputs Inputs) {
+	for phaseID, values := range inputs {
+		for inputID, value := range values {
+			SetInput(ctx, phaseID, inputID, value)
+		}
+	}
+}
+
+// providedBy reports whether one of the phases in phaseIDs, typically those
+// that run before the input's phase, supplies def through the context.
+func providedBy(def InputDefinition, phaseIDs map[string]bool) bool {
+	for _, id := range def.ProvidedBy {
+		if phaseIDs[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultString(value any) string {
+	return InputText(value)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package phases
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateInputs(t *testing.T) {
+	t.Parallel()
+
+	metas := []PhaseMetadata{
+		{
+			ID: "ssh",
+			Inputs: []InputDefinition{
+				{ID: "host", Kind: InputKindText, Required: true},
+				{ID: "port", Kind: InputKindText},
+				{ID: "auth", Kind: InputKindSelect, Required: true, Options: []InputOption{{Value: "password"}, {Value: "key"}}},
+				{ID: "password", Kind: InputKindSecret, Secret: true},
+			},
+		},
+		{
+			ID:     "user",
+			Inputs: []InputDefinition{{ID: "key_path", Kind: InputKindText, Required: true, Default: "~/.ssh/id"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		supplied Inputs
+		want     Inputs
+		problems []string
+	}{
+		{
+			name:     "coerces and trims",
+			supplied: Inputs{"ssh": {"host": " example.com ", "port": float64(2222), "auth": "key", "password": " pw "}},
+			want:     Inputs{"ssh": {"host": "example.com", "port": "2222", "auth": "key", "password": " pw "}},
+		},
+		{
+			name:     "reports every problem",
+			supplied: Inputs{"ssh": {"host": "  ", "auth": "kerberos", "extra": "x", "port": []any{1}}, "nope": {}},
+			problems: []string{
+				"nope: unknown phase",
+				"ssh.extra: unknown input",
+				"ssh.host: required input is empty",
+				`ssh.port: expected a scalar text value, got []interface {}`,
+				`ssh.auth: "kerberos" is not one of password, key`,
+			},
+		},
+		{
+			name:     "missing required",
+			supplied: Inputs{},
+			problems: []string{"ssh.host: required input missing", "ssh.auth: required input missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ValidateInputs(metas, tt.supplied)
+			if len(tt.problems) == 0 {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+				return
+			}
+			var validationErr InputValidationError
+			require.ErrorAs(t, err, &validationErr)
+			var problems []string
+			for _, p := range validationErr.Problems {
+				problems = append(problems, p.String())
+			}
+			require.Equal(t, tt.problems, problems)
+		})
+	}
+}
+
+func TestValidateSuppliedInputsAllowsMissingRequired(t *testing.T) {
+	t.Parallel()
+
+	metas := []PhaseMetadata{{
+		ID: "ssh",
+		Inputs: []InputDefinition{
+			{ID: "host", Kind: InputKindText, Required: true},
+			{ID: "user", Kind: InputKindText, Required: true},
+		},
+	}}
+
+	got, err := ValidateSuppliedInputs(metas, Inputs{"ssh": {"host": " example.com "}})
+	require.NoError(t, err)
+	require.Equal(t, Inputs{"ssh": {"host": "example.com"}}, got)
+
+	_, err = ValidateSuppliedInputs(metas, Inputs{"ssh": {"user": " "}})
+	require.ErrorContains(t, err, "ssh.user: required input is empty")
+}
+
+func TestValidateInputsAcceptsInputsProvidedByEarlierPhases(t *testing.T) {
+	t.Parallel()
+
+	playbook := PhaseMetadata{
+		ID: "playbook",
+		Inputs: []InputDefinition{
+			{ID: "target", Kind: InputKindText, Required: true, ProvidedBy: []string{"ssh"}},
+		},
+	}
+	ssh := PhaseMetadata{ID: "ssh"}
+
+	_, err := ValidateInputs([]PhaseMetadata{ssh, playbook}, nil)
+	require.NoError(t, err)
+
+	_, err = ValidateInputs([]PhaseMetadata{playbook}, nil)
+	require.ErrorContains(t, err, "playbook.target: required input missing")
+
+	// A provider that runs later cannot supply the value in time.
+	_, err = ValidateInputs([]PhaseMetadata{playbook, ssh}, nil)
+	require.ErrorContains(t, err, "playbook.target: required input missing")
+}
@@ -0,0 +1,162 @@
+// Package config loads optional settings for integrations, such as CMDB
+// credentials or an HTTP proxy, from a JSON file so they never have to be
+// typed at a prompt. Keeping one file per environment and picking it with
+// --config gives per-profile settings.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultNetBoxTokenEnv  = "NETBOX_TOKEN"
+	defaultSMTPPasswordEnv = "SMTP_PASSWORD"
+	defaultSMTPPort        = 587
+)
+
+// Config is the top-level settings file.
+type Config struct {
+	NetBox *NetBox `json:"netbox,omitempty"`
+	Email  *Email  `json:"email,omitempty"`
+	Proxy  *Proxy  `json:"proxy,omitempty"`
+	// PhaseOrder lists every phase ID in the order to run them, e.g. to
+	// run a custom hostname phase before ansible_user.
+	PhaseOrder []string `json:"phase_order,omitempty"`
+}
+
+// NetBox configures the post-run NetBox sync. The token may be given inline
+// or read from the environment variable named by TokenEnv (default
+// NETBOX_TOKEN).
+type NetBox struct {
+	URL              string `json:"url"`
+	Token            string `json:"token,omitempty"`
+	TokenEnv         string `json:"token_env,omitempty"`
+	Tag              string `json:"tag,omitempty"`
+	UserField        string `json:"user_field,omitempty"`
+	FingerprintField string `json:"fingerprint_field,omitempty"`
+}
+
+// ResolveToken returns the inline token or the one from the environment.
+func (n *NetBox) ResolveToken() string {
+	if token := strings.TrimSpace(n.Token); token != "" {
+		return token
+	}
+	env := n.TokenEnv
+	if env == "" {
+		env = defaultNetBoxTokenEnv
+	}
+	return strings.TrimSpace(os.Getenv(env))
+}
+
+// Email configures the SMTP run report. The password may be given inline or
+// read from the environment variable named by PasswordEnv (default
+// SMTP_PASSWORD); authentication is skipped when Username is empty.
+type Email struct {
+	Host         string   `json:"host"`
+	Port         int      `json:"port,omitempty"`
+	Username     string   `json:"username,omitempty"`
+	Password     string   `json:"password,omitempty"`
+	PasswordEnv  string   `json:"password_env,omitempty"`
+	From         string   `json:"from"`
+	To           []string `json:"to"`
+	FailuresOnly bool     `json:"failures_only,omitempty"`
+}
+
+// ResolvePort returns the configured port or 587.
+func (e *Email) ResolvePort() int {
+	if e.Port > 0 {
+		return e.Port
+	}
+	return defaultSMTPPort
+}
+
+// ResolvePassword returns the inline password or the one from the environment.
+func (e *Email) ResolvePassword() string {
+	if e.Password != "" {
+		return e.Password
+	}
+	env := e.PasswordEnv
+	if env == "" {
+		env = defaultSMTPPasswordEnv
+	}
+	return os.Getenv(env)
+}
+
+// Dir is the tool's state directory, <user config dir>/ansible-host-prep,
+// or "" when the platform has no config directory.
+func Dir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ansible-host-prep")
+}
+
+// DefaultPath is the settings file used when none is given:
+// <user config dir>/ansible-host-prep/config.json.
+func DefaultPath() string {
+	dir := Dir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// Load reads and validates the settings at path. A missing file yields an
+// empty Config only when optional is true.
+func Load(path string, optional bool) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && optional {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("config: decode %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate reports settings that cannot work.
+func (c *Config) Validate() error {
+	if c.NetBox != nil {
+		if strings.TrimSpace(c.NetBox.URL) == "" {
+			return errors.New("netbox.url is required")
+		}
+		if c.NetBox.ResolveToken() == "" {
+			return errors.New("netbox token is not set (token or token_env)")
+		}
+	}
+	for _, id := range c.PhaseOrder {
+		if strings.TrimSpace(id) == "" {
+			return errors.New("phase_order must not contain empty phase IDs")
+		}
+	}
+	if c.Proxy != nil {
+		if err := c.Proxy.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Email != nil {
+		switch {
+		case strings.TrimSpace(c.Email.Host) == "":
+			return errors.New("email.host is required")
+		case strings.TrimSpace(c.Email.From) == "":
+			return errors.New("email.from is required")
+		case len(c.Email.To) == 0:
+			return errors.New("email.to needs at least one recipient")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+		return path
+	}
+	t.Setenv("TEST_NETBOX_TOKEN", "from-env")
+
+	tests := []struct {
+		name     string
+		path     string
+		optional bool
+		token    string
+		wantErr  string
+	}{
+		{name: "missing optional", path: filepath.Join(dir, "none.json"), optional: true},
+		{name: "missing required", path: filepath.Join(dir, "none.json"), wantErr: "read"},
+		{name: "inline token", path: write("inline.json", `{"netbox": {"url": "https://nb", "token": "abc"}}`), token: "abc"},
+		{name: "env token", path: write("env.json", `{"netbox": {"url": "https://nb", "token_env": "TEST_NETBOX_TOKEN"}}`), token: "from-env"},
+		{name: "no url", path: write("nourl.json", `{"netbox": {"token": "abc"}}`), wantErr: "netbox.url is required"},
+		{name: "email without recipients", path: write("email.json", `{"email": {"host": "smtp", "from": "prep@example.com"}}`), wantErr: "email.to"},
+		{name: "no token", path: write("notoken.json", `{"netbox": {"url": "https://nb", "token_env": "TEST_UNSET_TOKEN"}}`), wantErr: "token is not set"},
+		{name: "blank phase id", path: write("order.json", `{"phase_order": ["ssh_connection", ""]}`), wantErr: "phase_order"},
+		{name: "bad proxy", path: write("proxy.json", `{"proxy": {"https_proxy": "ftp://proxy:21"}}`), wantErr: "proxy.https_proxy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.path, tt.optional)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.token == "" {
+				require.Nil(t, cfg.NetBox)
+				return
+			}
+			require.Equal(t, tt.token, cfg.NetBox.ResolveToken())
+		})
+	}
+}
+
+func TestProxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	for _, key := range []string{"http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+
+	tests := []struct {
+		name   string
+		config *Config
+		target string
+		want   string
+	}{
+		{name: "environment only", config: &Config{}, target: "http://netbox.example.com", want: "http://env-proxy:3128"},
+		{name: "nil config", target: "http://netbox.example.com", want: "http://env-proxy:3128"},
+		{name: "configured https", config: &Config{Proxy: &Proxy{HTTPSProxy: "http://corp:8080"}}, target: "https://awx.example.com", want: "http://corp:8080"},
+		{name: "configured overrides env", config: &Config{Proxy: &Proxy{HTTPProxy: "http://corp:8080"}}, target: "http://hooks.example.com", want: "http://corp:8080"},
+		{name: "no proxy", config: &Config{Proxy: &Proxy{HTTPProxy: "http://corp:8080", NoProxy: ".internal"}}, target: "http://netbox.internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.config.HTTPClient(0)
+			req, err := http.NewRequest(http.MethodGet, tt.target, nil)
+			require.NoError(t, err)
+			proxyURL, err := client.Transport.(*http.Transport).Proxy(req)
+			require.NoError(t, err)
+			if tt.want == "" {
+				require.Nil(t, proxyURL)
+				return
+			}
+			require.Equal(t, tt.want, proxyURL.String())
+		})
+	}
+
+	env := (&Proxy{HTTPSProxy: "http://corp:8080", NoProxy: "localhost"}).Env()
+	require.Equal(t, map[string]string{
+		"HTTP_PROXY": "http://env-proxy:3128", "http_proxy": "http://env-proxy:3128",
+		"HTTPS_PROXY": "http://corp:8080", "https_proxy": "http://corp:8080",
+		"NO_PROXY": "localhost", "no_proxy": "localhost",
+	}, env)
+}
+
+func TestApplyProxyEnv(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+
+	require.NoError(t, (&Config{}).ApplyProxyEnv())
+	require.Empty(t, os.Getenv("HTTPS_PROXY"))
+
+	cfg := &Config{Proxy: &Proxy{HTTPSProxy: "http://corp:8080", NoProxy: ".internal"}}
+	require.NoError(t, cfg.ApplyProxyEnv())
+	require.Equal(t, "http://corp:8080", os.Getenv("HTTPS_PROXY"))
+	require.Equal(t, "http://corp:8080", os.Getenv("https_proxy"))
+	require.Equal(t, ".internal", os.Getenv("NO_PROXY"))
+	require.Empty(t, os.Getenv("HTTP_PROXY"))
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Proxy routes the tool's own HTTP traffic (webhooks, NetBox, AWX) and
+// ansible-galaxy downloads through a proxy. Empty fields fall back to HTTP_PROXY, HTTPS_PROXY, and NO_PROXY
+// (or their lowercase forms), so a settings file only needs what differs from
+// the environment.
+type Proxy struct {
+	HTTPProxy  string `json:"http_proxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	NoProxy    string `json:"no_proxy,omitempty"`
+}
+
+// resolve merges the configured values over the environment.
+func (p *Proxy) resolve() *httpproxy.Config {
+	cfg := httpproxy.FromEnvironment()
+	if p == nil {
+		return cfg
+	}
+	if v := strings.TrimSpace(p.HTTPProxy); v != "" {
+		cfg.HTTPProxy = v
+	}
+	if v := strings.TrimSpace(p.HTTPSProxy); v != "" {
+		cfg.HTTPSProxy = v
+	}
+	if v := strings.TrimSpace(p.NoProxy); v != "" {
+		cfg.NoProxy = v
+	}
+	return cfg
+}
+
+// ProxyFunc returns the proxy selector for an http.Transport. It is safe to
+// call on a nil Proxy, which uses the environment alone.
+func (p *Proxy) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	fn := p.resolve().ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return fn(req.URL)
+	}
+}
+
+// Env returns the resolved proxy as environment variables for child
+// processes such as ansible-galaxy, which read them directly. ApplyProxyEnv
+// exports them for the whole process.
+func (p *Proxy) Env() map[string]string {
+	cfg := p.resolve()
+	env := make(map[string]string, 6)
+	for _, kv := range []struct{ key, value string }{
+		{"HTTP_PROXY", cfg.HTTPProxy},
+		{"HTTPS_PROXY", cfg.HTTPSProxy},
+		{"NO_PROXY", cfg.NoProxy},
+	} {
+		if kv.value == "" {
+			continue
+		}
+		env[kv.key] = kv.value
+		env[strings.ToLower(kv.key)] = kv.value
+	}
+	return env
+}
+
+func (p *Proxy) validate() error {
+	for field, raw := range map[string]string{"proxy.http_proxy": p.HTTPProxy, "proxy.https_proxy": p.HTTPSProxy} {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("%s %q is not a proxy URL", field, raw)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("%s %q must use http, https, or socks5", field, raw)
+		}
+	}
+	return nil
+}
+
+// ApplyProxyEnv exports the configured proxy to the process environment, so
+// HTTP clients built without HTTPClient (such as the AWX client's default)
+// and child processes such as ansible-galaxy use it as well. net/http reads
+// the environment once, so call it before the first request. It does
+// nothing without a proxy section.
+func (c *Config) ApplyProxyEnv() error {
+	if c == nil || c.Proxy == nil {
+		return nil
+	}
+	for key, value := range c.Proxy.Env() {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// HTTPClient returns a client with the given timeout whose requests honor the
+// proxy settings. It is safe to call on a nil Config.
+func (c *Config) HTTPClient(timeout time.Duration) *http.Client {
+	var proxy *Proxy
+	if c != nil {
+		proxy = c.Proxy
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy.ProxyFunc()
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,175 @@
+// Package hostprofile loads what a prep run learned about a host from its
+// JSON reports and compares two hosts, so an operator can see why one node of
+// a pair behaves differently under ansible.
+package hostprofile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/terraform"
+)
+
+// Profile is a flat view of one host's prep results.
+type Profile struct {
+	// Name identifies the profile in output, e.g. "web01.json".
+	Name string
+	// Values maps an attribute (a summary label, a task, or a host
+	// variable) to its value. Planned file contents are multi-line.
+	Values map[string]string
+}
+
+// Load reads a profile from path, which is either the NDJSON written by a
+// headless run with --output json (including check-mode runs) or a Terraform
+// export document. A document with several hosts needs a "#host" suffix on
+// path to pick one, e.g. "hosts.json#web01".
+func Load(path string) (*Profile, error) {
+	file, host := path, ""
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		file, host = path[:i], path[i+1:]
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read profile: %w", err)
+	}
+
+	var doc terraform.Document
+	if err := json.Unmarshal(data, &doc); err == nil && doc.Hosts != nil {
+		return fromDocument(path, &doc, host)
+	}
+	if host != "" {
+		return nil, fmt.Errorf("%s: only Terraform export documents hold several hosts", file)
+	}
+	return fromEvents(path, data)
+}
+
+func fromDocument(name string, doc *terraform.Document, host string) (*Profile, error) {
+	if host == "" {
+		if len(doc.Hosts) != 1 {
+			return nil, fmt.Errorf("%s: document has %d hosts; append #<host> to choose one", name, len(doc.Hosts))
+		}
+		for h := range doc.Hosts {
+			host = h
+		}
+	}
+	attrs, ok := doc.Hosts[host]
+	if !ok {
+		return nil, fmt.Errorf("%s: host %q not in document", name, host)
+	}
+	profile := &Profile{Name: name, Values: make(map[string]string, len(attrs))}
+	for key, value := range attrs {
+		// When each host was prepared is always different and never the cause.
+		if key == "prepared_at" {
+			continue
+		}
+		profile.Values[key] = value
+	}
+	return profile, nil
+}
+
+// fromEvents collects the pipeline summary and the outcome of every task. A
+// check-mode task whose message is a diff contributes the file content it
+// would write under "planned <task>".
+func fromEvents(name string, data []byte) (*Profile, error) {
+	profile := &Profile{Name: name, Values: make(map[string]string)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	events := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event phasedapp.JSONEvent
+		if err := json.Unmarshal(line, &event); err != nil || event.Event == "" {
+			return nil, fmt.Errorf("%s: not a JSON report (line %d)", name, events+1)
+		}
+		events++
+		switch event.Event {
+		case "task":
+			if event.Task == nil {
+				continue
+			}
+			profile.Values["task "+event.Task.Name] = event.Task.Status
+			if planned, ok := plannedContent(event.Task.Message); ok {
+				profile.Values["planned "+event.Task.Name] = planned
+			}
+		case "pipeline_finished":
+			for label, value := range event.Summary {
+				profile.Values[label] = value
+			}
+			if event.Success != nil {
+				profile.Values["result"] = "failed"
+				if *event.Success {
+					profile.Values["result"] = "succeeded"
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if events == 0 {
+		return nil, fmt.Errorf("%s: report is empty", name)
+	}
+	return profile, nil
+}
+
+// plannedContent recovers the new side of a unified diff: its context and
+// added lines.
+func plannedContent(diff string) (string, bool) {
+	if !strings.HasPrefix(diff, "--- ") {
+		return "", false
+	}
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "@@"):
+		case strings.HasPrefix(line, " "), strings.HasPrefix(line, "+"):
+			out.WriteString(line[1:] + "\n")
+		}
+	}
+	return out.String(), true
+}
+
+// Difference is one attribute whose value differs between two profiles. A
+// side without the attribute has MissingA or MissingB set.
+type Difference struct {
+	Key      string
+	A, B     string
+	MissingA bool
+	MissingB bool
+}
+
+// Diff lists the attributes that differ between a and b, sorted by key.
+func Diff(a, b *Profile) []Difference {
+	keys := make(map[string]bool, len(a.Values)+len(b.Values))
+	for key := range a.Values {
+		keys[key] = true
+	}
+	for key := range b.Values {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var diffs []Difference
+	for _, key := range sorted {
+		va, okA := a.Values[key]
+		vb, okB := b.Values[key]
+		if okA && okB && va == vb {
+			continue
+		}
+		diffs = append(diffs, Difference{Key: key, A: va, B: vb, MissingA: !okA, MissingB: !okB})
+	}
+	return diffs
+}
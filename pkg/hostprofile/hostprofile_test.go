@@ -0,0 +1,90 @@
+package hostprofile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadJSONReport(t *testing.T) {
+	t.Parallel()
+
+	path := writeFile(t, t.TempDir(), "web01.json", `{"event":"phase_started","phase":{"id":"ansible_user","title":"Ensure Ansible User"}}
+{"event":"task","task":{"name":"create user ansible","host":"web01","status":"changed"}}
+{"event":"task","task":{"name":"/etc/sudoers.d/ansible","host":"web01","status":"changed","message":"--- /dev/null\n+++ /etc/sudoers.d/ansible\n@@ -0,0 +1,2 @@\n+ansible ALL=(ALL) NOPASSWD: ALL\n+Defaults:ansible log_output\n"}}
+{"event":"pipeline_finished","success":true,"summary":{"Target host":"web01","Python interpreter":"/usr/bin/python3"}}
+`)
+	profile, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"task create user ansible":       "changed",
+		"task /etc/sudoers.d/ansible":    "changed",
+		"planned /etc/sudoers.d/ansible": "ansible ALL=(ALL) NOPASSWD: ALL\nDefaults:ansible log_output\n",
+		"Target host":                    "web01",
+		"Python interpreter":             "/usr/bin/python3",
+		"result":                         "succeeded",
+	}, profile.Values)
+}
+
+func TestLoadTerraformDocument(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := writeFile(t, dir, "hosts.json", `{"hosts": {
+		"web01": {"ansible_host": "web01", "ansible_user": "ansible", "prepared_at": "2026-01-02T03:04:05Z"},
+		"web02": {"ansible_host": "web02", "ansible_user": "deploy"}
+	}}`)
+
+	profile, err := Load(path + "#web02")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"ansible_host": "web02", "ansible_user": "deploy"}, profile.Values)
+
+	profile, err = Load(path + "#web01")
+	require.NoError(t, err)
+	require.NotContains(t, profile.Values, "prepared_at")
+
+	_, err = Load(path)
+	require.ErrorContains(t, err, "#<host>")
+	_, err = Load(path + "#db01")
+	require.ErrorContains(t, err, `host "db01" not in document`)
+}
+
+func TestLoadRejectsOtherFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, err := Load(writeFile(t, dir, "web01.yml", "ansible_user: ansible\n"))
+	require.ErrorContains(t, err, "not a JSON report")
+
+	_, err = Load(writeFile(t, dir, "empty.json", "\n"))
+	require.ErrorContains(t, err, "empty")
+
+	_, err = Load(writeFile(t, dir, "events.json", `{"event":"pipeline_finished"}`) + "#web01")
+	require.Error(t, err)
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	a := &Profile{Name: "a", Values: map[string]string{"user": "ansible", "python": "/usr/bin/python3", "os": "ubuntu 24.04"}}
+	b := &Profile{Name: "b", Values: map[string]string{"user": "ansible", "python": "/usr/bin/python3.12", "fingerprint": "SHA256:x"}}
+
+	require.Equal(t, []Difference{
+		{Key: "fingerprint", B: "SHA256:x", MissingA: true},
+		{Key: "os", A: "ubuntu 24.04", MissingB: true},
+		{Key: "python", A: "/usr/bin/python3", B: "/usr/bin/python3.12"},
+	}, Diff(a, b))
+	require.Empty(t, Diff(a, a))
+}
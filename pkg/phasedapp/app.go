@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"time"
@@ -35,18 +36,42 @@ type Config struct {
 	Phases         []phases.Phase
 	ManagerOptions []phases.ManagerOption
 	ProgramOptions []tea.ProgramOption
+	SummaryFields  []SummaryField
+	LiveFields     []LiveField
+	NextSteps      []SummaryHint
+	StartAt        time.Time
+	Preflight      []string
+	// PreflightPhases replace registered phases of the same ID during
+	// preflight; see WithPreflightPhases.
+	PreflightPhases []phases.Phase
+	// SecretIdleTimeout locks idle secret prompts; zero uses the default and
+	// a negative value disables locking.
+	SecretIdleTimeout time.Duration
+	TranscriptDir     string
+	TranscriptOnExit  bool
+	JSONOutput        bool
+	// PhaseOrder rearranges Phases by ID; see phases.OrderPhases.
+	PhaseOrder []string
+	// LogOutput receives human-readable progress alongside JSON output.
+	LogOutput io.Writer
+
+	// sources rebuilds Phases for each server run, in the order WithPhases
+	// and WithBundle were given, so concurrent runs never share phase
+	// instances that keep state while they run.
+	sources []func() []phases.Phase
 }
 
 // Option mutates Config during construction.
 type Option func(*Config)
 
 // WithPhases sets the ordered phases the app should execute.
-func WithPhases(phases ...phases.Phase) Option {
+func WithPhases(list ...phases.Phase) Option {
 	return func(cfg *Config) {
 		if cfg == nil {
 			return
 		}
-		cfg.Phases = append(cfg.Phases, phases...)
+		cfg.Phases = append(cfg.Phases, list...)
+		cfg.sources = append(cfg.sources, func() []phases.Phase { return list })
 	}
 }
 
@@ -90,6 +115,11 @@ func New(opts ...Option) (*App, error) {
 	if len(cfg.Phases) == 0 {
 		return nil, ErrNoPhases
 	}
+	ordered, err := phases.OrderPhases(cfg.Phases, cfg.PhaseOrder)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Phases = ordered
 	return &App{cfg: cfg}, nil
 }
 
@@ -158,6 +188,12 @@ func (a *App) start(ctx context.Context, start int) error {
 	close(done)
 	cancel()
 
+	if a.cfg.TranscriptOnExit {
+		if _, err := model.writeTranscript(); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+
 	a.mu.Lock()
 	a.program = nil
 	a.cancel = nil
@@ -187,14 +223,17 @@ const (
 )
 
 type phaseState struct {
-	meta   phases.PhaseMetadata
-	status phaseStatus
-	err    error
-	logs   []string
+	meta       phases.PhaseMetadata
+	status     phaseStatus
+	err        error
+	logs       []string
+	startedAt  time.Time
+	finishedAt time.Time
 }
 
 type model struct {
 	manager      *phases.Manager
+	preflight    *phases.Manager
 	phaseCtx     *phases.Context
 	observer     *phaseObserver
 	inputHandler *bubbleInputHandler
@@ -210,6 +249,11 @@ type model struct {
 	prompting    bool
 	selectIndex  int
 
+	promptLocked      bool
+	lastActivity      time.Time
+	idleSeq           int
+	secretIdleTimeout time.Duration
+
 	savedInputs  map[string]map[string]any
 	secretValues map[string]struct{}
 
@@ -218,6 +262,20 @@ type model struct {
 	helpVisible    bool
 	pipelineActive bool
 	actionsVisible bool
+	summaryVisible bool
+
+	summaryFields []SummaryField
+	nextSteps     []SummaryHint
+
+	liveFields     []LiveField
+	contextChanges chan contextChangedMsg
+	unwatch        []func()
+
+	scheduledAt   time.Time
+	awaitingStart bool
+
+	transcript    []transcriptEntry
+	transcriptDir string
 
 	statusMsg string
 	done      error
@@ -248,6 +306,11 @@ func newModel(cfg Config, startIndex int, runCtx context.Context) (*model, error
 		return nil, err
 	}
 
+	preflight, err := newPreflightManager(cfg, observer, inputHandler)
+	if err != nil {
+		return nil, err
+	}
+
 	states := make(map[string]*phaseState, len(cfg.Phases))
 	order := make([]string, 0, len(cfg.Phases))
 	for _, ph := range cfg.Phases {
@@ -270,8 +333,9 @@ func newModel(cfg Config, startIndex int, runCtx context.Context) (*model, error
 		runCtx = context.Background()
 	}
 
-	return &model{
+	m := &model{
 		manager:           manager,
+		preflight:         preflight,
 		phaseCtx:          phaseCtx,
 		observer:          observer,
 		inputHandler:      inputHandler,
@@ -286,12 +350,25 @@ func newModel(cfg Config, startIndex int, runCtx context.Context) (*model, error
 		secretValues:      make(map[string]struct{}),
 		statusMsg:         "Awaiting phase events…",
 		pipelineActive:    false,
+		summaryFields:     append([]SummaryField{}, cfg.SummaryFields...),
+		nextSteps:         append([]SummaryHint{}, cfg.NextSteps...),
+		liveFields:        append([]LiveField{}, cfg.LiveFields...),
+		contextChanges:    make(chan contextChangedMsg, 1),
+		scheduledAt:       cfg.StartAt,
+		secretIdleTimeout: resolveSecretIdleTimeout(cfg.SecretIdleTimeout),
+		transcriptDir:     cfg.TranscriptDir,
 		initialStartIndex: startIndex,
-	}, nil
+	}
+	m.watchContext()
+	return m, nil
 }
 
 func (m *model) Init() tea.Cmd {
-	return m.startPipelineFrom(m.initialStartIndex)
+	watch := waitContextChangeCmd(m.contextChanges)
+	if m.scheduled() {
+		return tea.Batch(watch, m.startPreflight())
+	}
+	return tea.Batch(watch, m.startPipelineFrom(m.initialStartIndex))
 }
 
 func (m *model) startPipeline() tea.Cmd {
@@ -308,6 +385,8 @@ func (m *model) startPipelineFrom(start int) tea.Cmd {
 	}
 	m.pipelineActive = true
 	m.actionsVisible = false
+	m.summaryVisible = false
+	m.awaitingStart = false
 	return tea.Batch(
 		runManagerCmd(m.runCtx, m.manager, m.phaseCtx, start),
 		waitPhaseEventCmd(m.observer),
@@ -341,6 +420,15 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case tea.KeyMsg:
+		m.lastActivity = time.Now()
+		if m.promptLocked {
+			return m, m.handleLockedPromptKey(msg)
+		}
+		if m.summaryVisible {
+			if handled, cmd := m.handleSummaryKeys(msg); handled {
+				return m, cmd
+			}
+		}
 		if m.actionsVisible {
 			if handled, cmd := m.handleActionKeys(msg); handled {
 				return m, cmd
@@ -382,6 +470,12 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case '?', 'h', 'H':
 					m.helpVisible = !m.helpVisible
 					return m, nil
+				case 's', 'S':
+					if !m.pipelineActive && !m.prompting {
+						m.summaryVisible = true
+						m.actionsVisible = false
+						return m, nil
+					}
 				}
 			}
 		}
@@ -405,16 +499,51 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.handlePhaseCompleted(msg)
 		return m, tea.Batch(waitPhaseEventCmd(m.observer), m.spinner.Tick)
 
+	case taskProgressMsg:
+		m.handleTaskProgress(msg)
+		return m, waitPhaseEventCmd(m.observer)
+
 	case inputRequestMsg:
+		m.recordTranscript(msg.meta.ID, "prompt", fmt.Sprintf("%s requested (%s)", msg.input.Label, msg.reason))
 		m.preparePrompt(msg)
+		return m, m.watchIdleSecret()
+
+	case idleTickMsg:
+		return m, m.handleIdleTick(msg)
+
+	case preflightFinishedMsg:
+		return m, m.handlePreflightFinished(msg)
+
+	case scheduleTickMsg:
+		return m, m.handleScheduleTick(time.Time(msg))
+
+	case phaseUndoneMsg:
+		m.handlePhaseUndone(msg)
+		return m, waitPhaseEventCmd(m.observer)
+
+	case rollbackFinishedMsg:
+		m.pipelineActive = false
+		if msg.err != nil {
+			m.recordTranscript("", "rollback", "failed: "+msg.err.Error())
+			m.setStatus(msg.err.Error())
+		} else {
+			m.recordTranscript("", "rollback", "completed phases reverted")
+			m.setStatus("Completed phases reverted")
+		}
 		return m, nil
 
+	case contextChangedMsg:
+		return m, waitContextChangeCmd(m.contextChanges)
+
 	case phasesFinishedMsg:
 		m.pipelineActive = false
+		m.summaryVisible = true
 		m.done = msg.err
 		if msg.err != nil {
+			m.recordTranscript("", "pipeline", "failed: "+msg.err.Error())
 			m.setStatus(msg.err.Error())
 		} else {
+			m.recordTranscript("", "pipeline", "all phases completed")
 			m.setStatus("All phases completed")
 		}
 		return m, nil
@@ -427,8 +556,11 @@ func (m *model) handlePhaseStarted(msg phaseStartedMsg) {
 	if state, ok := m.phases[msg.meta.ID]; ok {
 		state.status = statusRunning
 		state.err = nil
+		state.startedAt = time.Now()
+		state.finishedAt = time.Time{}
 		m.appendLog(state, fmt.Sprintf("%s started", msg.meta.Title))
 	}
+	m.recordTranscript(msg.meta.ID, "event", "started")
 	m.setStatusf("Running %s", msg.meta.Title)
 }
 
@@ -437,19 +569,67 @@ func (m *model) handlePhaseCompleted(msg phaseCompletedMsg) {
 	if !ok {
 		return
 	}
+	state.finishedAt = time.Now()
 	if msg.err != nil {
 		state.status = statusFailed
 		state.err = msg.err
+		m.recordTranscript(msg.meta.ID, "error", msg.err.Error())
 		m.appendLog(state, fmt.Sprintf("%s failed: %v", msg.meta.Title, msg.err))
 		m.setStatusf("%s failed — %v", msg.meta.Title, msg.err)
 	} else {
 		state.status = statusSuccess
 		state.err = nil
+		m.recordTranscript(msg.meta.ID, "event", "completed")
 		m.appendLog(state, fmt.Sprintf("%s completed", msg.meta.Title))
 		m.setStatusf("%s completed", msg.meta.Title)
 	}
 }
 
+func (m *model) handlePhaseUndone(msg phaseUndoneMsg) {
+	state, ok := m.phases[msg.meta.ID]
+	if !ok {
+		return
+	}
+	if msg.err != nil {
+		m.recordTranscript(msg.meta.ID, "error", "revert failed: "+msg.err.Error())
+		m.appendLog(state, fmt.Sprintf("%s revert failed: %v", msg.meta.Title, msg.err))
+		return
+	}
+	state.status = statusPending
+	state.err = nil
+	m.recordTranscript(msg.meta.ID, "event", "reverted")
+	m.appendLog(state, fmt.Sprintf("%s reverted", msg.meta.Title))
+}
+
+func (m *model) handleTaskProgress(msg taskProgressMsg) {
+	line := taskLine(msg.event)
+	if state, ok := m.phases[msg.meta.ID]; ok {
+		m.appendLog(state, line)
+	}
+	m.recordTranscript(msg.meta.ID, "task", line)
+	if msg.event.Status == phases.TaskStarted {
+		m.setStatusf("Running %s: %s", msg.meta.Title, msg.event.Task)
+	}
+}
+
+// taskLine renders a task event for logs: "TASK [name]" when it starts, then
+// "status: [host] name" per host, with the message for failures.
+func taskLine(event phases.TaskEvent) string {
+	if event.Status == phases.TaskStarted {
+		return fmt.Sprintf("TASK [%s]", event.Task)
+	}
+	line := fmt.Sprintf("%s: [%s] %s", event.Status, event.Host, event.Task)
+	switch {
+	case event.Message == "":
+	case event.Status == phases.TaskFailed || event.Status == phases.TaskUnreachable:
+		line += " — " + event.Message
+	case event.Status == phases.TaskChanged && strings.Contains(event.Message, "\n"):
+		// Multi-line messages on changed tasks are diffs from check mode.
+		line += "\n" + strings.TrimRight(event.Message, "\n")
+	}
+	return line
+}
+
 func (m *model) preparePrompt(msg inputRequestMsg) {
 	m.actionsVisible = false
 	msg.reason = sanitizeInputReason(msg.input, msg.reason)
@@ -549,6 +729,7 @@ func (m *model) recordInput(value any) {
 	if m.activePrompt.input.Kind == phases.InputKindSecret {
 		m.trackSecretValue(value)
 	}
+	m.recordInputTranscript(m.activePrompt.meta, m.activePrompt.input, value)
 	phases.SetInput(m.phaseCtx, m.activePrompt.meta.ID, m.activePrompt.input.ID, value)
 }
 
@@ -590,7 +771,19 @@ func (m *model) restartPipeline() tea.Cmd {
 		return nil
 	}
 
+	m.resetPipeline()
+	m.setStatus("Restarting pipeline")
+	return m.startPipeline()
+}
+
+// resetPipeline replaces the shared context (re-seeding saved answers) and
+// marks every phase pending ahead of a fresh run. Connections held by the
+// old context, such as the SSH client a preflight opened, are closed.
+func (m *model) resetPipeline() {
+	m.awaitingStart = false
+	_ = m.phaseCtx.Close()
 	m.phaseCtx = phases.NewContext()
+	m.watchContext()
 	for phaseID, inputs := range m.savedInputs {
 		for inputID, value := range inputs {
 			phases.SetInput(m.phaseCtx, phaseID, inputID, value)
@@ -602,12 +795,12 @@ func (m *model) restartPipeline() tea.Cmd {
 			state.status = statusPending
 			state.err = nil
 			state.logs = nil
+			state.startedAt = time.Time{}
+			state.finishedAt = time.Time{}
 		}
 	}
 	m.selectedPhase = 0
 	m.done = nil
-	m.setStatus("Restarting pipeline")
-	return m.startPipeline()
 }
 
 func (m *model) retrySelectedPhase() tea.Cmd {
@@ -626,6 +819,8 @@ func (m *model) retrySelectedPhase() tea.Cmd {
 			st.status = statusPending
 			st.err = nil
 			st.logs = nil
+			st.startedAt = time.Time{}
+			st.finishedAt = time.Time{}
 		}
 	}
 	m.done = nil
@@ -669,11 +864,40 @@ func (m *model) handleActionKeys(msg tea.KeyMsg) (bool, tea.Cmd) {
 			m.copySelectedError()
 			m.actionsVisible = false
 			return true, nil
+		case '4', 't', 'T':
+			m.exportTranscript()
+			m.actionsVisible = false
+			return true, nil
+		case '5', 'u', 'U':
+			cmd := m.rollbackCompletedPhases()
+			m.actionsVisible = false
+			return true, cmd
 		}
 	}
 	return false, nil
 }
 
+// canRollback reports whether the run has failed and left phases that can be
+// undone.
+func (m *model) canRollback() bool {
+	return !m.pipelineActive && m.done != nil && len(m.manager.UndoablePhases()) > 0
+}
+
+func (m *model) rollbackCompletedPhases() tea.Cmd {
+	if !m.canRollback() {
+		m.setStatus("Nothing to revert")
+		return nil
+	}
+	m.pipelineActive = true
+	m.summaryVisible = false
+	m.setStatus("Reverting completed phases")
+	return tea.Batch(
+		rollbackCmd(m.runCtx, m.manager, m.phaseCtx),
+		waitPhaseEventCmd(m.observer),
+		m.spinner.Tick,
+	)
+}
+
 func (m *model) copySelectedError() {
 	state := m.currentPhaseState()
 	if state == nil || state.err == nil {
@@ -687,6 +911,22 @@ func (m *model) copySelectedError() {
 	m.setStatus("Error copied to clipboard")
 }
 
+func (m *model) handleSummaryKeys(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyEnter:
+		m.summaryVisible = false
+		return true, nil
+	}
+	if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 {
+		switch msg.Runes[0] {
+		case 's', 'S':
+			m.summaryVisible = false
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (m *model) handlePhaseNavigation(msg tea.KeyMsg) bool {
 	if m.actionsVisible {
 		return false
@@ -757,15 +997,18 @@ func (m *model) handleSelectPromptNavigation(msg tea.KeyMsg) bool {
 }
 
 func (m *model) View() string {
-	header := renderHeader(completedCount(m.phases), len(m.order))
+	header := renderHeader(completedCount(m.phases), len(m.order), m.progress())
 	body := m.renderBody()
+	if m.summaryVisible {
+		body = m.renderSummary()
+	}
 	promptPanel := m.renderPromptPanel()
 	var actionsPanel string
 	if m.actionsVisible {
 		actionsPanel = m.renderActionsPanel()
 	}
 	statusBar := statusBarStyle.Render(m.statusMsg)
-	footer := footerStyle.Render("↑/↓ or j/k move • Enter actions • Tab switch focus • r restart • ? help • Ctrl+C quit")
+	footer := footerStyle.Render("↑/↓ or j/k move • Enter actions • Tab switch focus • s summary • r restart • ? help • Ctrl+C quit")
 
 	sections := []string{header, body}
 	if actionsPanel != "" {
@@ -791,9 +1034,9 @@ func (m *model) View() string {
 	return lipgloss.Place(renderWidth, renderHeight, lipgloss.Left, lipgloss.Top, view)
 }
 
-func renderHeader(done, total int) string {
+func renderHeader(done, total int, fraction float64) string {
 	title := titleStyle.Render("Ansible Host Prep")
-	progress := subtitleStyle.Render(fmt.Sprintf("Progress: %d/%d complete", done, total))
+	progress := subtitleStyle.Render(fmt.Sprintf("Progress: %d/%d complete (%d%%)", done, total, int(fraction*100)))
 	return lipgloss.JoinHorizontal(lipgloss.Top, title, "  ", progress)
 }
 
@@ -873,6 +1116,13 @@ func (m *model) renderPhaseDetails(width int) string {
 	if errLine != "" {
 		body = append(body, errLine)
 	}
+	if live := m.liveOutputs(); len(live) > 0 {
+		discovered := logSectionStyle.Render("Discovered:")
+		for _, line := range live {
+			discovered += "\n" + infoTextStyle.Render(line)
+		}
+		body = append(body, discovered)
+	}
 	if logLines != "" {
 		body = append(body, logLines)
 	}
@@ -893,6 +1143,12 @@ func (m *model) renderPromptPanel() string {
 		return style.Render("Prompt\n" + content)
 	}
 
+	if m.promptLocked {
+		locked := fmt.Sprintf("Prompt — %s • %s\n", m.activePrompt.meta.Title, m.activePrompt.input.Label)
+		locked += infoTextStyle.Render("Locked after inactivity; the typed value was cleared. Press Enter to resume or Esc to cancel.")
+		return style.Render(locked)
+	}
+
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("Prompt — %s • %s\n", m.activePrompt.meta.Title, m.activePrompt.input.Label))
 	b.WriteString(m.activePrompt.input.Description)
@@ -922,6 +1178,8 @@ func (m *model) renderActionsPanel() string {
 		actionLine("1", "Close", true),
 		actionLine("2", "Retry from this phase", !m.pipelineActive),
 		actionLine("3", "Copy error message", state.err != nil),
+		actionLine("4", "Export session transcript", true),
+		actionLine("5", "Revert completed phases", m.canRollback()),
 	}
 	header := fmt.Sprintf("Actions — %s", state.meta.Title)
 	content := header + "\n" + strings.Join(options, "\n")
@@ -954,6 +1212,7 @@ func renderHelp() string {
 		"  ↑/↓ or j/k  Move phase selection",
 		"  Enter        Submit input / open phase actions",
 		"  Tab          Switch focus between phases and prompt",
+		"  s            Toggle end-of-run summary",
 		"  r / Ctrl+R   Restart pipeline",
 		"  Esc          Cancel prompt, hide help, or close actions",
 		"  ?            Toggle this help",
@@ -1009,22 +1268,16 @@ func (m *model) optionIndex(value string) int {
 
 func (m *model) lookupInputString(phaseID, inputID string) (string, bool) {
 	if inputs, ok := m.savedInputs[phaseID]; ok {
-		if val, ok := inputs[inputID]; ok {
-			str := strings.TrimSpace(fmt.Sprint(val))
-			if str != "" {
-				return str, true
-			}
+		if str := phases.InputText(inputs[inputID]); str != "" {
+			return str, true
 		}
 	}
 	val, ok := phases.GetInput(m.phaseCtx, phaseID, inputID)
 	if !ok {
 		return "", false
 	}
-	str := strings.TrimSpace(fmt.Sprint(val))
-	if str == "" {
-		return "", false
-	}
-	return str, true
+	str := phases.InputText(val)
+	return str, str != ""
 }
 
 func statusLabel(s phaseStatus) string {
@@ -1042,6 +1295,29 @@ func statusLabel(s phaseStatus) string {
 	}
 }
 
+// progress is the weighted share of the pipeline that is done, counting the
+// elapsed part of the running phase's estimate so long phases do not make
+// the header jump.
+func (m *model) progress() float64 {
+	metas := make([]phases.PhaseMetadata, 0, len(m.order))
+	for _, id := range m.order {
+		if state, ok := m.phases[id]; ok {
+			metas = append(metas, state.meta)
+		}
+	}
+	return phases.Progress(metas, func(meta phases.PhaseMetadata) float64 {
+		state := m.phases[meta.ID]
+		switch state.status {
+		case statusSuccess:
+			return 1
+		case statusRunning:
+			return phases.RunningShare(meta, time.Since(state.startedAt))
+		default:
+			return 0
+		}
+	})
+}
+
 func completedCount(states map[string]*phaseState) int {
 	count := 0
 	for _, st := range states {
@@ -1095,11 +1371,8 @@ func (m *model) terminalWidth() int {
 }
 
 func (m *model) trackSecretValue(value any) {
-	if value == nil {
-		return
-	}
-	str := strings.TrimSpace(fmt.Sprint(value))
-	if str == "" || str == "<nil>" {
+	str := phases.InputText(value)
+	if str == "" {
 		return
 	}
 	m.secretValues[str] = struct{}{}
@@ -1161,14 +1434,7 @@ func placeholderText(def phases.InputDefinition, defaultValue string) string {
 }
 
 func defaultString(value any) string {
-	if value == nil {
-		return ""
-	}
-	str := strings.TrimSpace(fmt.Sprint(value))
-	if str == "" || str == "<nil>" {
-		return ""
-	}
-	return str
+	return phases.InputText(value)
 }
 
 func actionLine(key, label string, enabled bool) string {
@@ -1246,10 +1512,24 @@ type phaseCompletedMsg struct {
 	err  error
 }
 
+type taskProgressMsg struct {
+	meta  phases.PhaseMetadata
+	event phases.TaskEvent
+}
+
 type phasesFinishedMsg struct {
 	err error
 }
 
+type phaseUndoneMsg struct {
+	meta phases.PhaseMetadata
+	err  error
+}
+
+type rollbackFinishedMsg struct {
+	err error
+}
+
 type inputRequestMsg struct {
 	meta   phases.PhaseMetadata
 	input  phases.InputDefinition
@@ -1276,6 +1556,14 @@ func (o *phaseObserver) PhaseCompleted(meta phases.PhaseMetadata, err error) {
 	o.events <- phaseCompletedMsg{meta: meta, err: err}
 }
 
+func (o *phaseObserver) TaskProgress(meta phases.PhaseMetadata, event phases.TaskEvent) {
+	o.events <- taskProgressMsg{meta: meta, event: event}
+}
+
+func (o *phaseObserver) PhaseUndone(meta phases.PhaseMetadata, err error) {
+	o.events <- phaseUndoneMsg{meta: meta, err: err}
+}
+
 func waitPhaseEventCmd(observer *phaseObserver) tea.Cmd {
 	return func() tea.Msg {
 		msg, ok := <-observer.events
@@ -1342,3 +1630,12 @@ func runManagerCmd(runCtx context.Context, manager *phases.Manager, ctx *phases.
 		return phasesFinishedMsg{err: err}
 	}
 }
+
+func rollbackCmd(runCtx context.Context, manager *phases.Manager, ctx *phases.Context) tea.Cmd {
+	return func() tea.Msg {
+		if runCtx == nil {
+			runCtx = context.Background()
+		}
+		return rollbackFinishedMsg{err: manager.Rollback(runCtx, ctx)}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"io"
+	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -160,6 +162,293 @@ func TestAppStartReturnsWhenContextCancelled(t *testing.T) {
 	}
 }
 
+func TestModelShowsSummaryWhenPipelineFinishes(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Phases: []phasespkg.Phase{newStubPhase("one"), newStubPhase("two")},
+		SummaryFields: []SummaryField{
+			ContextField("Target host", Namespace("ssh", "target_host")),
+			ContextField("Missing", Namespace("ssh", "missing")),
+		},
+		NextSteps: []SummaryHint{
+			func(*phasespkg.Context) (string, bool) { return "run ansible ping", true },
+		},
+	}
+	m, err := newModel(cfg, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+	SetContext(m.phaseCtx, Namespace("ssh", "target_host"), "10.0.0.5")
+
+	for _, id := range []string{"one", "two"} {
+		meta := m.phases[id].meta
+		m.Update(phaseStartedMsg{meta: meta})
+		m.Update(phaseCompletedMsg{meta: meta})
+	}
+	m.Update(phasesFinishedMsg{})
+
+	if !m.summaryVisible {
+		t.Fatal("expected summary to be visible after pipeline finished")
+	}
+	view := m.View()
+	for _, want := range []string{"Run Summary", "Target host: 10.0.0.5", "run ansible ping"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("summary missing %q:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "Missing:") {
+		t.Fatalf("summary should skip empty fields:\n%s", view)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.summaryVisible {
+		t.Fatal("expected Esc to return to the dashboard")
+	}
+}
+
+func TestModelSummaryHintsOnFailure(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Phases: []phasespkg.Phase{newStubPhase("one")},
+		NextSteps: []SummaryHint{
+			func(*phasespkg.Context) (string, bool) { return "run ansible ping", true },
+		},
+	}
+	m, err := newModel(cfg, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	meta := m.phases["one"].meta
+	failure := errors.New("boom")
+	m.Update(phaseStartedMsg{meta: meta})
+	m.Update(phaseCompletedMsg{meta: meta, err: failure})
+	m.Update(phasesFinishedMsg{err: failure})
+
+	view := m.View()
+	if strings.Contains(view, "run ansible ping") {
+		t.Fatalf("success hints should not render after failure:\n%s", view)
+	}
+	if !strings.Contains(view, "Retry") {
+		t.Fatalf("expected retry hint after failure:\n%s", view)
+	}
+}
+
+func TestModelLogsTaskProgress(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Config{Phases: []phasespkg.Phase{newStubPhase("one")}}, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	meta := m.phases["one"].meta
+	m.Update(phaseStartedMsg{meta: meta})
+	m.Update(taskProgressMsg{meta: meta, event: phasespkg.TaskEvent{Task: "Install nginx", Status: phasespkg.TaskStarted}})
+	if !strings.Contains(m.statusMsg, "Install nginx") {
+		t.Fatalf("expected status to name the running task, got %q", m.statusMsg)
+	}
+	m.Update(taskProgressMsg{meta: meta, event: phasespkg.TaskEvent{
+		Task: "Install nginx", Host: "web01", Status: phasespkg.TaskFailed, Message: "No package nginx",
+	}})
+	m.Update(taskProgressMsg{meta: meta, event: phasespkg.TaskEvent{
+		Task: "/etc/sudoers.d/ansible", Host: "web01", Status: phasespkg.TaskChanged, Message: "--- /dev/null\n+++ /etc/sudoers.d/ansible\n",
+	}})
+
+	logs := strings.Join(m.phases["one"].logs, "\n")
+	for _, want := range []string{"TASK [Install nginx]", "failed: [web01] Install nginx — No package nginx", "changed: [web01] /etc/sudoers.d/ansible\n--- /dev/null\n+++ /etc/sudoers.d/ansible"} {
+		if !strings.Contains(logs, want) {
+			t.Fatalf("logs missing %q:\n%s", want, logs)
+		}
+	}
+}
+
+func TestModelScheduledStartWaitsForPreflightAndTime(t *testing.T) {
+	t.Parallel()
+
+	startAt := time.Now().Add(time.Hour)
+	cfg := Config{
+		Phases:    []phasespkg.Phase{newStubPhase("connect"), newStubPhase("work")},
+		StartAt:   startAt,
+		Preflight: []string{"connect"},
+	}
+	m, err := newModel(cfg, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+	if !m.scheduled() || m.preflight == nil {
+		t.Fatal("expected a scheduled model with a preflight manager")
+	}
+
+	m.Update(preflightFinishedMsg{})
+	if !m.awaitingStart {
+		t.Fatal("expected model to wait for the scheduled time after preflight")
+	}
+
+	m.Update(scheduleTickMsg(startAt.Add(-time.Minute)))
+	if !m.awaitingStart || m.pipelineActive {
+		t.Fatal("pipeline should not start before the scheduled time")
+	}
+
+	m.Update(scheduleTickMsg(startAt))
+	if m.awaitingStart || !m.pipelineActive {
+		t.Fatal("pipeline should start once the scheduled time is reached")
+	}
+}
+
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestModelPreflightUsesReplacementPhasesAndClosesClients(t *testing.T) {
+	t.Parallel()
+
+	var ran []string
+	startAt := time.Now().Add(time.Hour)
+	client := &closeRecorder{}
+	cfg := Config{
+		Phases: []phasespkg.Phase{
+			newStubPhaseFunc("connect", func(_ context.Context, phaseCtx *phasespkg.Context) error {
+				phaseCtx.Set("ssh:client", client)
+				return nil
+			}),
+			newStubPhaseFunc("sudo", func(context.Context, *phasespkg.Context) error {
+				ran = append(ran, "installing sudo")
+				return nil
+			}),
+		},
+		StartAt:   startAt,
+		Preflight: []string{"connect", "sudo"},
+		PreflightPhases: []phasespkg.Phase{newStubPhaseFunc("sudo", func(context.Context, *phasespkg.Context) error {
+			ran = append(ran, "checking sudo")
+			return nil
+		})},
+	}
+	m, err := newModel(cfg, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	go func() {
+		for range m.observer.events {
+		}
+	}()
+	if err := m.preflight.Run(context.Background(), m.phaseCtx); err != nil {
+		t.Fatalf("preflight: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "checking sudo" {
+		t.Fatalf("expected only the read-only sudo check in preflight, got %v", ran)
+	}
+
+	m.Update(preflightFinishedMsg{})
+	m.Update(scheduleTickMsg(startAt))
+	if !client.closed {
+		t.Fatal("expected the preflight's client to be closed when the scheduled run starts")
+	}
+	if _, ok := m.phaseCtx.Get("ssh:client"); ok {
+		t.Fatal("expected a fresh context for the scheduled run")
+	}
+}
+
+func TestModelPreflightFailureCancelsSchedule(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Phases:    []phasespkg.Phase{newStubPhase("connect")},
+		StartAt:   time.Now().Add(time.Hour),
+		Preflight: []string{"connect"},
+	}
+	m, err := newModel(cfg, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	m.Update(preflightFinishedMsg{err: errors.New("unreachable")})
+	if m.awaitingStart || m.scheduled() {
+		t.Fatal("preflight failure should cancel the scheduled start")
+	}
+}
+
+func TestNewModelRejectsUnknownPreflightPhase(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Phases:    []phasespkg.Phase{newStubPhase("connect")},
+		StartAt:   time.Now().Add(time.Hour),
+		Preflight: []string{"missing"},
+	}
+	if _, err := newModel(cfg, 0, context.Background()); err == nil {
+		t.Fatal("expected unknown preflight phase to be rejected")
+	}
+}
+
+func TestModelLocksIdleSecretPrompt(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Config{
+		Phases:            []phasespkg.Phase{newStubPhase("sudo")},
+		SecretIdleTimeout: time.Minute,
+	}, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	_, cmd := m.Update(inputRequestMsg{
+		meta:  m.phases["sudo"].meta,
+		input: phasespkg.InputDefinition{ID: "password", Label: "Sudo Password", Kind: phasespkg.InputKindSecret, Secret: true},
+	})
+	if cmd == nil {
+		t.Fatal("expected an idle watch for secret prompts")
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hunter2")})
+	if m.prompt.Value() != "hunter2" {
+		t.Fatalf("expected typed value, got %q", m.prompt.Value())
+	}
+
+	m.Update(idleTickMsg{seq: m.idleSeq, now: m.lastActivity.Add(30 * time.Second)})
+	if m.promptLocked {
+		t.Fatal("prompt locked before the idle timeout elapsed")
+	}
+
+	m.Update(idleTickMsg{seq: m.idleSeq, now: m.lastActivity.Add(2 * time.Minute)})
+	if !m.promptLocked {
+		t.Fatal("expected idle secret prompt to lock")
+	}
+	if m.prompt.Value() != "" {
+		t.Fatalf("expected typed secret to be cleared, got %q", m.prompt.Value())
+	}
+	if strings.Contains(m.View(), "hunter2") {
+		t.Fatal("locked view must not leak the typed secret")
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.promptLocked || !m.prompting {
+		t.Fatal("Enter should unlock the prompt without submitting it")
+	}
+}
+
+func TestModelDoesNotWatchTextPrompts(t *testing.T) {
+	t.Parallel()
+
+	m, err := newModel(Config{Phases: []phasespkg.Phase{newStubPhase("ssh")}}, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	_, cmd := m.Update(inputRequestMsg{
+		meta:  m.phases["ssh"].meta,
+		input: phasespkg.InputDefinition{ID: "host", Label: "Host", Kind: phasespkg.InputKindText},
+	})
+	if cmd != nil {
+		t.Fatal("text prompts should not start an idle watch")
+	}
+}
+
 // --- helpers ---
 
 func newTestApp(t *testing.T, opts ...Option) *App {
@@ -271,9 +560,8 @@ func (o *recordingObserver) PhaseCompleted(meta phasespkg.PhaseMetadata, err err
 		o.eventLog = append(o.eventLog, "complete:"+meta.ID)
 	}
 	o.completed++
-	if o.completed >= o.target && o.done != nil {
+	if o.completed == o.target {
 		close(o.done)
-		o.done = nil
 	}
 }
 
@@ -308,3 +596,200 @@ func equalStrings(a, b []string) bool {
 	}
 	return true
 }
+
+func TestModelWritesRedactedTranscript(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	m, err := newModel(Config{
+		Phases:        []phasespkg.Phase{newStubPhase("sudo")},
+		TranscriptDir: dir,
+	}, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	meta := m.phases["sudo"].meta
+	m.Update(inputRequestMsg{
+		meta:  meta,
+		input: phasespkg.InputDefinition{ID: "password", Label: "Sudo Password", Kind: phasespkg.InputKindSecret, Secret: true},
+	})
+	m.recordInput("hunter2")
+	m.Update(phaseCompletedMsg{meta: meta, err: errors.New("sudo rejected hunter2")})
+
+	path, err := m.writeTranscript()
+	if err != nil {
+		t.Fatalf("write transcript: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read transcript: %v", err)
+	}
+	out := string(data)
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("transcript leaked a secret:\n%s", out)
+	}
+	for _, want := range []string{"Sudo Password requested", "Sudo Password = [secret]", "[sudo] error:"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("transcript missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunHeadlessValidatesBeforeRunning(t *testing.T) {
+	t.Parallel()
+
+	ran := false
+	phase := stubPhase{
+		meta: phasespkg.PhaseMetadata{
+			ID:     "ssh",
+			Title:  "SSH",
+			Inputs: []phasespkg.InputDefinition{{ID: "host", Kind: phasespkg.InputKindText, Required: true}},
+		},
+		run: func(_ context.Context, phaseCtx *phasespkg.Context) error {
+			ran = true
+			if _, ok := phasespkg.GetInput(phaseCtx, "ssh", "host"); !ok {
+				return phasespkg.InputRequestError{PhaseID: "ssh", Input: phasespkg.InputDefinition{ID: "host"}}
+			}
+			return nil
+		},
+	}
+	app, err := New(WithPhases(phase))
+	if err != nil {
+		t.Fatalf("new app: %v", err)
+	}
+
+	var validationErr phasespkg.InputValidationError
+	if err := app.RunHeadless(context.Background(), phasespkg.Inputs{}, io.Discard); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if ran {
+		t.Fatal("phase must not run when inputs are invalid")
+	}
+
+	var out bytes.Buffer
+	if err := app.RunHeadless(context.Background(), phasespkg.Inputs{"ssh": {"host": "10.0.0.5"}}, &out); err != nil {
+		t.Fatalf("headless run: %v", err)
+	}
+	if !strings.Contains(out.String(), "[ok] SSH") {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+type undoableStubPhase struct {
+	phasespkg.Phase
+}
+
+func (undoableStubPhase) Undo(context.Context, *phasespkg.Context) error {
+	return nil
+}
+
+// driveManager runs a manager command while feeding observer events through
+// the model, returning the command's final message.
+func driveManager(m *model, cmd tea.Cmd) tea.Msg {
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	for {
+		select {
+		case msg := <-m.observer.events:
+			m.Update(msg)
+		case msg := <-done:
+			return msg
+		}
+	}
+}
+
+func TestModelRevertsCompletedPhasesAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	failure := errors.New("playbook failed")
+	m, err := newModel(Config{Phases: []phasespkg.Phase{
+		undoableStubPhase{Phase: newStubPhase("user")},
+		newStubPhaseFunc("playbook", func(context.Context, *phasespkg.Context) error { return failure }),
+	}}, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+
+	m.Update(driveManager(m, runManagerCmd(context.Background(), m.manager, m.phaseCtx, 0)))
+	if !strings.Contains(strings.Join(m.summaryHints(), "\n"), "Revert") {
+		t.Fatalf("expected a revert hint, got %v", m.summaryHints())
+	}
+
+	if m.rollbackCompletedPhases() == nil || !m.pipelineActive {
+		t.Fatal("expected rollback to start")
+	}
+	m.Update(driveManager(m, rollbackCmd(context.Background(), m.manager, m.phaseCtx)))
+
+	if m.pipelineActive {
+		t.Fatal("expected rollback to finish")
+	}
+	if got := m.phases["user"].status; got != statusPending {
+		t.Fatalf("expected reverted phase to be pending, got %v", got)
+	}
+	if !strings.Contains(strings.Join(m.phases["user"].logs, "\n"), "user reverted") {
+		t.Fatalf("expected revert in logs: %v", m.phases["user"].logs)
+	}
+	if m.canRollback() {
+		t.Fatal("nothing should be left to revert")
+	}
+}
+
+func TestNewAppliesPhaseOrder(t *testing.T) {
+	t.Parallel()
+
+	app, err := New(
+		WithPhases(newStubPhase("one"), newStubPhase("two")),
+		WithPhaseOrder("two", "one"),
+	)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if got := app.cfg.Phases[0].Metadata().ID; got != "two" {
+		t.Fatalf("expected two to run first, got %s", got)
+	}
+
+	if _, err := New(WithPhases(newStubPhase("one")), WithPhaseOrder("one", "two")); err == nil {
+		t.Fatal("expected an unknown phase in the order to fail")
+	}
+}
+
+func TestModelShowsLiveFieldsWhenKeysAreSet(t *testing.T) {
+	t.Parallel()
+
+	osField := WatchField(ContextField("Operating system", "host:os"), "host:os")
+	m, err := newModel(Config{
+		Phases:     []phasespkg.Phase{newStubPhase("detect")},
+		LiveFields: []LiveField{osField},
+	}, 0, context.Background())
+	if err != nil {
+		t.Fatalf("model init error: %v", err)
+	}
+	if strings.Contains(m.renderPhaseDetails(80), "Discovered:") {
+		t.Fatalf("expected no discovered values before the key is set")
+	}
+
+	m.phaseCtx.Set("host:os", "debian 12 (amd64)")
+	select {
+	case msg := <-m.contextChanges:
+		_, cmd := m.Update(msg)
+		if cmd == nil {
+			t.Fatalf("expected the model to keep waiting for context changes")
+		}
+	default:
+		t.Fatalf("expected a context change notification")
+	}
+	if details := m.renderPhaseDetails(80); !strings.Contains(details, "Operating system: debian 12 (amd64)") {
+		t.Fatalf("details missing live field:\n%s", details)
+	}
+
+	// A restart swaps the context; the old one no longer notifies.
+	old := m.phaseCtx
+	m.resetPipeline()
+	old.Set("host:os", "rocky 9")
+	select {
+	case <-m.contextChanges:
+		t.Fatalf("unexpected notification from the replaced context")
+	default:
+	}
+}
@@ -0,0 +1,141 @@
+package phasedapp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/webhook"
+)
+
+// ErrUnauthenticated reports an API request without a valid bearer token.
+var ErrUnauthenticated = errors.New("phasedapp: missing or invalid bearer token")
+
+// ContextKeyPrincipal holds the name of whoever started an API run, as
+// authenticated by WithAuthTokens. It is unset for anonymous runs.
+const ContextKeyPrincipal = ContextKey(webhook.ContextKeyPrincipal)
+
+// Audit actions recorded by WithAuditLog.
+const (
+	AuditRunStarted    = "run_started"
+	AuditInputProvided = "input_provided"
+	AuditRunCancelled  = "run_cancelled"
+	AuditRunFinished   = "run_finished"
+	AuditAuthFailed    = "auth_failed"
+)
+
+// AuditEntry is one line of the audit log. Principal is the caller of the
+// action, except on AuditRunFinished where it is whoever started the run.
+// Input names the answered input as "phase.input"; its value is never logged.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Principal string    `json:"principal,omitempty"`
+	RunID     string    `json:"run,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Input     string    `json:"input,omitempty"`
+	Status    RunStatus `json:"status,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Remote    string    `json:"remote,omitempty"`
+}
+
+// WithAuthTokens requires every request to carry an "Authorization: Bearer"
+// header with one of tokens, which maps each token to the principal it
+// identifies (a user or service name). Runs record the principal that
+// started them. Without tokens the API is open to anyone who can reach it.
+func WithAuthTokens(tokens map[string]string) ServerOption {
+	return func(s *Server) {
+		s.tokens = make(map[string]string, len(tokens))
+		for token, principal := range tokens {
+			if token != "" {
+				s.tokens[token] = principal
+			}
+		}
+	}
+}
+
+// WithAuditLog writes an AuditEntry as a JSON line to w whenever a run is
+// started, answered, cancelled, or finishes, and for every rejected token.
+func WithAuditLog(w io.Writer) ServerOption {
+	return func(s *Server) {
+		if w != nil {
+			s.auditLog = json.NewEncoder(w)
+		}
+	}
+}
+
+// Authenticate returns the principal token belongs to. Without
+// WithAuthTokens every token, including an empty one, is accepted as the
+// anonymous principal "". Rejections are audited with remote as the
+// caller's address.
+func (s *Server) Authenticate(token, remote string) (string, bool) {
+	if len(s.tokens) == 0 {
+		return "", true
+	}
+	var principal string
+	found := false
+	for candidate, name := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			principal, found = name, true
+		}
+	}
+	if !found {
+		s.audit(AuditEntry{Action: AuditAuthFailed, Remote: remote})
+	}
+	return principal, found
+}
+
+// BearerToken extracts the token from an Authorization header value.
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+type principalKey struct{}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := s.Authenticate(BearerToken(r.Header.Get("Authorization")), r.RemoteAddr)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="host-prep"`)
+			writeAPIError(w, http.StatusUnauthorized, ErrUnauthenticated)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+func requestPrincipal(r *http.Request) string {
+	principal, _ := r.Context().Value(principalKey{}).(string)
+	return principal
+}
+
+func (s *Server) audit(entry AuditEntry) {
+	if s.auditLog == nil {
+		return
+	}
+	entry.Time = s.now().UTC()
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+	_ = s.auditLog.Encode(entry)
+}
+
+func (s *Server) auditFinished(run *serverRun) {
+	info := run.info()
+	s.audit(AuditEntry{
+		Action:    AuditRunFinished,
+		Principal: info.Principal,
+		RunID:     info.ID,
+		Host:      run.host,
+		Status:    info.Status,
+		Error:     info.Error,
+	})
+}
@@ -3,9 +3,13 @@ package ansibleprep
 import (
 	"github.com/BrianJOC/ansible-host-prep/phases"
 	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/driftcheck"
+	"github.com/BrianJOC/ansible-host-prep/phases/hostvars"
+	"github.com/BrianJOC/ansible-host-prep/phases/osdetect"
 	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
 	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
 	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
 )
 
 // Bundle returns the default ansible host preparation phases in execution order.
@@ -13,7 +17,62 @@ func Bundle() []phases.Phase {
 	return []phases.Phase{
 		sshconnect.New(),
 		sudoensure.New(),
+		osdetect.New(),
 		pythonensure.New(),
 		ansibleuser.New(),
+		hostvars.New(),
 	}
 }
+
+// CheckBundle connects and elevates like Bundle, then shows the sudoers and
+// authorized_keys changes ansible_user would make as diffs instead of
+// applying them. OS detection, Python, and host_vars are skipped. sudo_ensure may still
+// install sudo on hosts without it.
+func CheckBundle() []phases.Phase {
+	return []phases.Phase{
+		sshconnect.New(),
+		sudoensure.New(),
+		ansibleuser.New().WithCheckMode(),
+	}
+}
+
+// DriftBundle re-checks a prepared host as its ansible user: it logs in with
+// the ansible key, elevates with sudo only (never installing it), plans
+// ansible_user in check mode, and fails with a driftcheck.DriftError when
+// the user, its sudoers rule or keys, or python changed. Nothing on the host
+// is modified.
+func DriftBundle() []phases.Phase {
+	return []phases.Phase{
+		sshconnect.New(),
+		sudoensure.New().WithElevationOptions(privilege.WithSudoOnly(), privilege.WithoutSudoInstall()),
+		ansibleuser.New().WithCheckMode(),
+		driftcheck.New(),
+	}
+}
+
+// PreflightPhases lists the phases that validate operator input and target
+// connectivity; scheduled runs execute them immediately before waiting.
+// Pass PreflightChecks to phasedapp.WithPreflightPhases so they stay
+// read-only.
+func PreflightPhases() []string {
+	return []string{
+		sshconnect.New().Metadata().ID,
+		sudoensure.New().Metadata().ID,
+	}
+}
+
+// PreflightChecks replaces sudo_ensure during a scheduled run's preflight
+// with a check that elevates without installing sudo, falling back to su,
+// so the host is not changed before the scheduled window.
+func PreflightChecks() []phases.Phase {
+	return []phases.Phase{
+		sudoensure.New().WithElevationOptions(privilege.WithoutSudoInstall()),
+	}
+}
+
+// Reconnect returns middleware that waits for the target to come back when
+// its SSH connection drops mid-phase (e.g. a reboot), re-establishes the SSH
+// and sudo sessions, and retries the interrupted phase.
+func Reconnect(opts ...sshconnect.ReconnectOption) phases.PhaseMiddleware {
+	return sshconnect.Reconnect([]phases.Phase{sshconnect.New(), sudoensure.New()}, opts...)
+}
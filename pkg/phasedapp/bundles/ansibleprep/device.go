@@ -0,0 +1,43 @@
+package ansibleprep
+
+import (
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/hostvars"
+	"github.com/BrianJOC/ansible-host-prep/phases/osdetect"
+	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sudoensure"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
+)
+
+// DeviceRetryWindow is how long DeviceBundle keeps trying to reach a device
+// that is still booting, which takes a while on SD cards.
+const DeviceRetryWindow = 3 * time.Minute
+
+// DeviceBundle is Bundle tuned for small ARM devices prepared headless, such
+// as Raspberry Pis: SSH is retried for DeviceRetryWindow, .local names are
+// resolved with mDNS so a freshly flashed device is reachable by the
+// hostname it announces over DHCP, and the ansible key is ed25519 rather
+// than 4096-bit RSA. Only the phases Bundle runs are included; the optional
+// ones that install large packages, such as the Kubernetes node phases, are
+// left out.
+func DeviceBundle() []phases.Phase {
+	return []phases.Phase{
+		sshconnect.New().WithConnectOptions(sshconnection.WithRetryWindow(DeviceRetryWindow), sshconnection.WithMDNS()),
+		sudoensure.New(),
+		osdetect.New(),
+		pythonensure.New(),
+		ansibleuser.New().WithKeyPairOptions(sshkeypair.WithKeyType(sshkeypair.KeyTypeEd25519)),
+		hostvars.New(),
+	}
+}
+
+// DeviceReconnect is Reconnect for DeviceBundle: it resolves .local names
+// with mDNS when re-establishing the session.
+func DeviceReconnect(opts ...sshconnect.ReconnectOption) phases.PhaseMiddleware {
+	return sshconnect.Reconnect([]phases.Phase{sshconnect.New().WithConnectOptions(sshconnection.WithMDNS()), sudoensure.New()}, opts...)
+}
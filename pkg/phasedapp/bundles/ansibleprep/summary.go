@@ -0,0 +1,128 @@
+package ansibleprep
+
+import (
+	"fmt"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/osdetect"
+	"github.com/BrianJOC/ansible-host-prep/phases/pythonensure"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+)
+
+// SummaryFields returns the key outputs shown on the end-of-run summary.
+func SummaryFields() []phasedapp.SummaryField {
+	return []phasedapp.SummaryField{
+		phasedapp.ContextField("Target host", phasedapp.ContextKey(sshconnect.ContextKeyTargetHost)),
+		{Label: "SSH server", Value: sshServer},
+		{Label: "Operating system", Value: operatingSystem},
+		{Label: "Ansible user", Value: ansibleUser},
+		{Label: "Sudo policy", Value: sudoPolicy},
+		phasedapp.ContextField("Python interpreter", phasedapp.ContextKey(pythonensure.ContextKeyInterpreter)),
+		{Label: "Private key", Value: privateKeyPath},
+		phasedapp.ContextField("Ansible key fingerprint", phasedapp.ContextKey(ansibleuser.ContextKeyKeyFingerprint)),
+		phasedapp.ContextField("Login key fingerprint", phasedapp.ContextKey(sshconnect.ContextKeyKeyFingerprint)),
+		phasedapp.ContextField("Initiated by", phasedapp.ContextKeyPrincipal),
+	}
+}
+
+// LiveFields returns the values the TUI shows in the details panel as soon
+// as a phase discovers them.
+func LiveFields() []phasedapp.LiveField {
+	return []phasedapp.LiveField{
+		phasedapp.WatchField(phasedapp.SummaryField{Label: "SSH server", Value: sshServer}, phasedapp.ContextKey(sshconnect.ContextKeyConnectionInfo)),
+		phasedapp.WatchField(phasedapp.SummaryField{Label: "Operating system", Value: operatingSystem}, phasedapp.ContextKey(osdetect.ContextKeyInfo)),
+		phasedapp.WatchField(phasedapp.SummaryField{Label: "Ansible user", Value: ansibleUser}, phasedapp.ContextKey(ansibleuser.ContextKeyUserResult)),
+		phasedapp.WatchField(phasedapp.SummaryField{Label: "Sudo policy", Value: sudoPolicy}, phasedapp.ContextKey(ansibleuser.ContextKeyUserResult)),
+	}
+}
+
+// SnapshotKeys returns the structured context entries of an ansible prep
+// run that phases.Context.Export should keep, for phases.WithTypedKeys.
+func SnapshotKeys() []phases.SnapshotKey {
+	return []phases.SnapshotKey{osdetect.KeyInfo, ansibleuser.KeyUserResult, ansibleuser.KeyKeyPair}
+}
+
+// NextSteps returns hints shown after a successful ansible prep run.
+func NextSteps() []phasedapp.SummaryHint {
+	return []phasedapp.SummaryHint{
+		func(phaseCtx *phases.Context) (string, bool) {
+			host, ok := targetHost(phaseCtx)
+			if !ok {
+				return "", false
+			}
+			user, ok := ansibleUser(phaseCtx)
+			if !ok {
+				return "", false
+			}
+			key, ok := privateKeyPath(phaseCtx)
+			if !ok {
+				return "", false
+			}
+			return fmt.Sprintf("Verify access: ansible all -i '%s,' -u %s --private-key %s -m ping", host, user, key), true
+		},
+		func(phaseCtx *phases.Context) (string, bool) {
+			user, ok := ansibleUser(phaseCtx)
+			if !ok {
+				return "", false
+			}
+			return fmt.Sprintf("Set ansible_user=%s for this host in your inventory.", user), true
+		},
+	}
+}
+
+func targetHost(phaseCtx *phases.Context) (string, bool) {
+	return phases.GetContextString(phaseCtx, sshconnect.ContextKeyTargetHost)
+}
+
+func ansibleUser(phaseCtx *phases.Context) (string, bool) {
+	res, ok := phases.GetContext(phaseCtx, ansibleuser.KeyUserResult)
+	if !ok || res == nil || res.Username == "" {
+		return "", false
+	}
+	return res.Username, true
+}
+
+// sshServer names the target's SSH server software, e.g.
+// "OpenSSH_9.2p1 Debian-2+deb12u3".
+func sshServer(phaseCtx *phases.Context) (string, bool) {
+	info, ok := phases.GetContext(phaseCtx, sshconnect.KeyConnectionInfo)
+	if !ok || info.Software() == "" {
+		return "", false
+	}
+	return info.Software(), true
+}
+
+func operatingSystem(phaseCtx *phases.Context) (string, bool) {
+	info, ok := phases.GetContext(phaseCtx, osdetect.KeyInfo)
+	if !ok || info == nil {
+		return "", false
+	}
+	return info.String(), true
+}
+
+// sudoPolicy describes how the ansible user was granted sudo, e.g.
+// "group wheel, passwordless".
+func sudoPolicy(phaseCtx *phases.Context) (string, bool) {
+	res, ok := phases.GetContext(phaseCtx, ansibleuser.KeyUserResult)
+	if !ok || res == nil || res.SudoGroup == "" {
+		return "", false
+	}
+	policy := "group " + res.SudoGroup
+	if res.PasswordlessConfigured {
+		policy += ", passwordless"
+		if n := len(res.SudoCommands); n > 0 {
+			policy += fmt.Sprintf(" for %d commands", n)
+		}
+	}
+	return policy, true
+}
+
+func privateKeyPath(phaseCtx *phases.Context) (string, bool) {
+	info, ok := phases.GetContext(phaseCtx, ansibleuser.KeyKeyPair)
+	if !ok || info == nil || info.PrivatePath == "" {
+		return "", false
+	}
+	return info.PrivatePath, true
+}
@@ -0,0 +1,40 @@
+// Package k8snode bundles the optional Kubernetes node phases for
+// phasedapp. They run after ansible prep and need its SSH and sudo phases.
+package k8snode
+
+import (
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/k8snode"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+	k8snodeutil "github.com/BrianJOC/ansible-host-prep/utils/k8snode"
+)
+
+// Tag selects every phase of the bundle; see phasedapp.WithTag.
+const Tag = k8snode.Tag
+
+// Bundle returns the Kubernetes node prerequisite phases in execution order.
+// Each carries Tag plus a tag of its own ("swap", "kernel-modules",
+// "sysctl", "containerd") for picking single steps with phasedapp.SelectPhases.
+func Bundle() []phases.Phase {
+	return []phases.Phase{
+		k8snode.NewSwap(),
+		k8snode.NewKernelModules(),
+		k8snode.NewSysctl(),
+		k8snode.NewContainerd(),
+	}
+}
+
+// SummaryFields returns the bundle's outputs for the end-of-run summary.
+func SummaryFields() []phasedapp.SummaryField {
+	return []phasedapp.SummaryField{
+		{Label: "containerd", Value: containerdVersion},
+	}
+}
+
+func containerdVersion(phaseCtx *phases.Context) (string, bool) {
+	result, ok := phasedapp.GetContext[*k8snodeutil.ContainerdResult](phaseCtx, phasedapp.ContextKey(k8snode.ContextKeyContainerd))
+	if !ok || result == nil || result.Version == "" {
+		return "", false
+	}
+	return result.Version, true
+}
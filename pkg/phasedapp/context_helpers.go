@@ -18,29 +18,16 @@ func (k ContextKey) String() string {
 	return string(k)
 }
 
-// SetContext stores a typed value under the provided key.
+// SetContext stores a typed value under the provided key. Prefer a
+// phases.Key with phases.SetContext, which fixes the type once per key.
 func SetContext[T any](ctx *phases.Context, key ContextKey, value T) {
-	if ctx == nil {
-		return
-	}
-	ctx.Set(string(key), value)
+	phases.SetContext(ctx, phases.NewKey[T](string(key)), value)
 }
 
-// GetContext retrieves a typed value from the shared context.
+// GetContext retrieves a typed value from the shared context. Prefer a
+// phases.Key with phases.GetContext.
 func GetContext[T any](ctx *phases.Context, key ContextKey) (T, bool) {
-	var zero T
-	if ctx == nil {
-		return zero, false
-	}
-	val, ok := ctx.Get(string(key))
-	if !ok {
-		return zero, false
-	}
-	casted, ok := val.(T)
-	if !ok {
-		return zero, false
-	}
-	return casted, true
+	return phases.GetContext(ctx, phases.NewKey[T](string(key)))
 }
 
 // MustGetContext retrieves a typed value or panics if missing.
@@ -0,0 +1,307 @@
+// Package grpcapi exposes a phasedapp.Server over gRPC using the
+// hostprep.v1.HostPrepService definition in hostprepv1, so other tools can
+// orchestrate host prep programmatically.
+//
+// Regenerate the bindings after editing hostprepv1/hostprep.proto with
+// `just proto`.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/grpcapi/hostprepv1"
+)
+
+// Service implements hostprepv1.HostPrepServiceServer on top of a
+// phasedapp.Server, sharing its runs with the HTTP API when both are served.
+type Service struct {
+	hostprepv1.UnimplementedHostPrepServiceServer
+	server *phasedapp.Server
+}
+
+// New wraps server as a gRPC service.
+func New(server *phasedapp.Server) *Service {
+	return &Service{server: server}
+}
+
+// Serve listens on addr until ctx is cancelled, then stops gracefully.
+func (s *Service) Serve(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("grpc server: %w", err)
+	}
+	srv := grpc.NewServer(s.ServerOptions()...)
+	hostprepv1.RegisterHostPrepServiceServer(srv, s)
+
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			srv.Stop()
+		}
+	}()
+
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("grpc server: %w", err)
+	}
+	return nil
+}
+
+// ServerOptions returns the interceptors that negotiate the API version (see
+// phasedapp.APIVersion) and authenticate each call's "authorization: Bearer"
+// metadata against the server's tokens (see phasedapp.WithAuthTokens). Serve
+// installs them; pass them to grpc.NewServer when registering the service
+// yourself.
+func (s *Service) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := negotiateVersion(ctx, grpc.SetHeader); err != nil {
+				return nil, err
+			}
+			ctx, err := s.authenticate(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			setHeader := func(_ context.Context, md metadata.MD) error { return stream.SetHeader(md) }
+			if err := negotiateVersion(stream.Context(), setHeader); err != nil {
+				return err
+			}
+			ctx, err := s.authenticate(stream.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+		}),
+	}
+}
+
+// Metadata keys carrying the API versions; see phasedapp.APIVersionHeader.
+var (
+	apiVersionKey    = strings.ToLower(phasedapp.APIVersionHeader)
+	minAPIVersionKey = strings.ToLower(phasedapp.MinAPIVersionHeader)
+)
+
+// negotiateVersion sends the server's API versions as header metadata and
+// rejects calls whose "host-prep-api-version" metadata is unsupported.
+func negotiateVersion(ctx context.Context, setHeader func(context.Context, metadata.MD) error) error {
+	_ = setHeader(ctx, metadata.Pairs(
+		apiVersionKey, strconv.Itoa(phasedapp.APIVersion),
+		minAPIVersionKey, strconv.Itoa(phasedapp.MinAPIVersion),
+	))
+	var version string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(apiVersionKey); len(values) > 0 {
+			version = values[0]
+		}
+	}
+	if err := phasedapp.AcceptAPIVersion(version); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+type principalKey struct{}
+
+func (s *Service) authenticate(ctx context.Context) (context.Context, error) {
+	var token, remote string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = phasedapp.BearerToken(values[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	principal, ok := s.server.Authenticate(token, remote)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, phasedapp.ErrUnauthenticated.Error())
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+func principalFrom(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// principalStream carries the authenticated context into streaming handlers.
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (p *principalStream) Context() context.Context {
+	return p.ctx
+}
+
+func (s *Service) RegisterRun(ctx context.Context, req *hostprepv1.RegisterRunRequest) (*hostprepv1.RegisterRunResponse, error) {
+	inputs := make(phases.Inputs, len(req.GetInputs()))
+	for phaseID, values := range req.GetInputs() {
+		inputs[phaseID] = make(map[string]any, len(values.GetValues()))
+		for inputID, value := range values.GetValues() {
+			inputs[phaseID][inputID] = value
+		}
+	}
+	info, err := s.server.StartAs(principalFrom(ctx), req.GetHost(), inputs)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &hostprepv1.RegisterRunResponse{Run: toRun(info)}, nil
+}
+
+func (s *Service) StreamEvents(req *hostprepv1.StreamEventsRequest, stream grpc.ServerStreamingServer[hostprepv1.Event]) error {
+	history, events, unsubscribe, err := s.server.Subscribe(req.GetRunId(), int(req.GetAfterSeq()))
+	if err != nil {
+		return toStatus(err)
+	}
+	defer unsubscribe()
+
+	for _, event := range history {
+		if err := stream.Send(toEvent(event)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(toEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Service) ProvideInput(ctx context.Context, req *hostprepv1.ProvideInputRequest) (*hostprepv1.ProvideInputResponse, error) {
+	if err := s.server.AnswerAs(principalFrom(ctx), req.GetRunId(), req.GetPhaseId(), req.GetInputId(), req.GetValue()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &hostprepv1.ProvideInputResponse{}, nil
+}
+
+func (s *Service) GetRun(_ context.Context, req *hostprepv1.GetRunRequest) (*hostprepv1.Run, error) {
+	info, err := s.server.Run(req.GetRunId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toRun(info), nil
+}
+
+func (s *Service) CancelRun(ctx context.Context, req *hostprepv1.CancelRunRequest) (*hostprepv1.CancelRunResponse, error) {
+	if err := s.server.CancelAs(principalFrom(ctx), req.GetRunId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &hostprepv1.CancelRunResponse{}, nil
+}
+
+func toStatus(err error) error {
+	var validationErr phases.InputValidationError
+	switch {
+	case errors.Is(err, phasedapp.ErrRunNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, phasedapp.ErrNoPendingInput):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, phasedapp.ErrHostUnsupported), errors.As(err, &validationErr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+var runStatuses = map[phasedapp.RunStatus]hostprepv1.RunStatus{
+	phasedapp.RunQueued:       hostprepv1.RunStatus_RUN_STATUS_QUEUED,
+	phasedapp.RunRunning:      hostprepv1.RunStatus_RUN_STATUS_RUNNING,
+	phasedapp.RunWaitingInput: hostprepv1.RunStatus_RUN_STATUS_WAITING_INPUT,
+	phasedapp.RunSucceeded:    hostprepv1.RunStatus_RUN_STATUS_SUCCEEDED,
+	phasedapp.RunFailed:       hostprepv1.RunStatus_RUN_STATUS_FAILED,
+	phasedapp.RunCancelled:    hostprepv1.RunStatus_RUN_STATUS_CANCELLED,
+}
+
+func toRun(info phasedapp.RunInfo) *hostprepv1.Run {
+	run := &hostprepv1.Run{
+		Id:           info.ID,
+		Status:       runStatuses[info.Status],
+		Principal:    info.Principal,
+		SubmittedAt:  timestamppb.New(info.SubmittedAt),
+		Error:        info.Error,
+		PendingInput: toPendingInput(info.Pending),
+	}
+	if !info.StartedAt.IsZero() {
+		run.StartedAt = timestamppb.New(info.StartedAt)
+	}
+	if info.FinishedAt != nil {
+		run.FinishedAt = timestamppb.New(*info.FinishedAt)
+	}
+	return run
+}
+
+func toEvent(event phasedapp.RunEvent) *hostprepv1.Event {
+	out := &hostprepv1.Event{
+		Seq:       int64(event.Seq),
+		Type:      event.Type,
+		Timestamp: timestamppb.New(event.Timestamp),
+		Input:     toPendingInput(event.Input),
+		Status:    runStatuses[event.Status],
+		Error:     event.Error,
+		Command:   event.Command,
+		Summary:   event.Summary,
+	}
+	if event.Phase != nil {
+		out.Phase = &hostprepv1.Phase{Id: event.Phase.ID, Title: event.Phase.Title}
+	}
+	if task := event.Task; task != nil {
+		out.Task = &hostprepv1.Task{Name: task.Name, Host: task.Host, Status: task.Status, Message: task.Message}
+	}
+	return out
+}
+
+func toPendingInput(pending *phasedapp.PendingInput) *hostprepv1.PendingInput {
+	if pending == nil {
+		return nil
+	}
+	out := &hostprepv1.PendingInput{
+		PhaseId:     pending.PhaseID,
+		InputId:     pending.InputID,
+		Label:       pending.Label,
+		Description: pending.Description,
+		Kind:        string(pending.Kind),
+		Required:    pending.Required,
+		Secret:      pending.Secret,
+		Reason:      pending.Reason,
+	}
+	if pending.Default != nil {
+		out.DefaultValue = fmt.Sprint(pending.Default)
+	}
+	for _, opt := range pending.Options {
+		out.Options = append(out.Options, &hostprepv1.InputOption{Value: opt.Value, Label: opt.Label, Description: opt.Description})
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/grpcapi/hostprepv1"
+)
+
+func TestServiceRunsPipelineWithProvidedInput(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, phasedapp.NewPhase(
+		phases.PhaseMetadata{ID: "ssh", Title: "SSH", Inputs: []phases.InputDefinition{
+			{ID: "host", Kind: phases.InputKindText, Required: true},
+			{ID: "user", Kind: phases.InputKindText, Required: true},
+		}},
+		func(_ context.Context, phaseCtx *phases.Context) error {
+			if _, ok := phases.GetInput(phaseCtx, "ssh", "user"); !ok {
+				return phases.InputRequestError{PhaseID: "ssh", Input: phases.InputDefinition{ID: "user", Label: "User", Kind: phases.InputKindText}}
+			}
+			return nil
+		},
+	))
+	ctx := context.Background()
+
+	resp, err := client.RegisterRun(ctx, &hostprepv1.RegisterRunRequest{Host: "10.0.0.5"})
+	require.NoError(t, err)
+	runID := resp.GetRun().GetId()
+
+	stream, err := client.StreamEvents(ctx, &hostprepv1.StreamEventsRequest{RunId: runID})
+	require.NoError(t, err)
+
+	var types []string
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		types = append(types, event.GetType())
+		if event.GetType() == phasedapp.RunEventInputRequested {
+			require.Equal(t, "user", event.GetInput().GetInputId())
+			_, err := client.ProvideInput(ctx, &hostprepv1.ProvideInputRequest{RunId: runID, PhaseId: "ssh", InputId: "user", Value: "admin"})
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, []string{
+		phasedapp.RunEventPhaseStarted, phasedapp.RunEventInputRequested,
+		phasedapp.RunEventPhaseCompleted, phasedapp.RunEventPipelineFinished,
+	}, types)
+
+	run, err := client.GetRun(ctx, &hostprepv1.GetRunRequest{RunId: runID})
+	require.NoError(t, err)
+	require.Equal(t, hostprepv1.RunStatus_RUN_STATUS_SUCCEEDED, run.GetStatus())
+
+	_, err = client.ProvideInput(ctx, &hostprepv1.ProvideInputRequest{RunId: runID, PhaseId: "ssh", InputId: "user"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.GetRun(ctx, &hostprepv1.GetRunRequest{RunId: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServiceAuthenticatesTokens(t *testing.T) {
+	t.Parallel()
+
+	app, err := phasedapp.New(phasedapp.WithPhases(phasedapp.NewPhase(phases.PhaseMetadata{ID: "ssh"}, func(context.Context, *phases.Context) error { return nil })))
+	require.NoError(t, err)
+	client := newServiceClient(t, New(app.NewServer(phasedapp.WithAuthTokens(map[string]string{"t0ken": "ci"}))))
+
+	_, err = client.RegisterRun(context.Background(), &hostprepv1.RegisterRunRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer t0ken")
+	resp, err := client.RegisterRun(ctx, &hostprepv1.RegisterRunRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "ci", resp.GetRun().GetPrincipal())
+
+	stream, err := client.StreamEvents(context.Background(), &hostprepv1.StreamEventsRequest{RunId: resp.GetRun().GetId()})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func newTestClient(t *testing.T, phaseList ...phases.Phase) hostprepv1.HostPrepServiceClient {
+	t.Helper()
+
+	app, err := phasedapp.New(phasedapp.WithPhases(phaseList...))
+	require.NoError(t, err)
+	return newServiceClient(t, New(app.NewServer(phasedapp.WithHostInput("ssh", "host"))))
+}
+
+func newServiceClient(t *testing.T, service *Service) hostprepv1.HostPrepServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(service.ServerOptions()...)
+	hostprepv1.RegisterHostPrepServiceServer(srv, service)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return hostprepv1.NewHostPrepServiceClient(conn)
+}
+
+func TestServiceRejectsUnsupportedAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, phasedapp.NewPhase(phases.PhaseMetadata{ID: "ssh", Title: "SSH"}, func(context.Context, *phases.Context) error { return nil }))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "host-prep-api-version", "99")
+
+	var header metadata.MD
+	_, err := client.GetRun(ctx, &hostprepv1.GetRunRequest{RunId: "missing"}, grpc.Header(&header))
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "upgrade the server")
+	require.Equal(t, []string{strconv.Itoa(phasedapp.APIVersion)}, header.Get("host-prep-api-version"))
+}
@@ -0,0 +1,1219 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: hostprep.proto
+
+package hostprepv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RunStatus int32
+
+const (
+	RunStatus_RUN_STATUS_UNSPECIFIED   RunStatus = 0
+	RunStatus_RUN_STATUS_RUNNING       RunStatus = 1
+	RunStatus_RUN_STATUS_WAITING_INPUT RunStatus = 2
+	RunStatus_RUN_STATUS_SUCCEEDED     RunStatus = 3
+	RunStatus_RUN_STATUS_FAILED        RunStatus = 4
+	RunStatus_RUN_STATUS_CANCELLED     RunStatus = 5
+	// Waiting for a free slot on a server with a concurrency limit.
+	RunStatus_RUN_STATUS_QUEUED RunStatus = 6
+)
+
+// Enum value maps for RunStatus.
+var (
+	RunStatus_name = map[int32]string{
+		0: "RUN_STATUS_UNSPECIFIED",
+		1: "RUN_STATUS_RUNNING",
+		2: "RUN_STATUS_WAITING_INPUT",
+		3: "RUN_STATUS_SUCCEEDED",
+		4: "RUN_STATUS_FAILED",
+		5: "RUN_STATUS_CANCELLED",
+		6: "RUN_STATUS_QUEUED",
+	}
+	RunStatus_value = map[string]int32{
+		"RUN_STATUS_UNSPECIFIED":   0,
+		"RUN_STATUS_RUNNING":       1,
+		"RUN_STATUS_WAITING_INPUT": 2,
+		"RUN_STATUS_SUCCEEDED":     3,
+		"RUN_STATUS_FAILED":        4,
+		"RUN_STATUS_CANCELLED":     5,
+		"RUN_STATUS_QUEUED":        6,
+	}
+)
+
+func (x RunStatus) Enum() *RunStatus {
+	p := new(RunStatus)
+	*p = x
+	return p
+}
+
+func (x RunStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RunStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_hostprep_proto_enumTypes[0].Descriptor()
+}
+
+func (RunStatus) Type() protoreflect.EnumType {
+	return &file_hostprep_proto_enumTypes[0]
+}
+
+func (x RunStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RunStatus.Descriptor instead.
+func (RunStatus) EnumDescriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{0}
+}
+
+type RegisterRunRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Target host, mapped onto the SSH phase's host input.
+	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	// Pre-supplied answers keyed by phase ID.
+	Inputs        map[string]*PhaseInputs `protobuf:"bytes,2,rep,name=inputs,proto3" json:"inputs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterRunRequest) Reset() {
+	*x = RegisterRunRequest{}
+	mi := &file_hostprep_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRunRequest) ProtoMessage() {}
+
+func (x *RegisterRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRunRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRunRequest) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *RegisterRunRequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *RegisterRunRequest) GetInputs() map[string]*PhaseInputs {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+type PhaseInputs struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Values keyed by input ID.
+	Values        map[string]string `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PhaseInputs) Reset() {
+	*x = PhaseInputs{}
+	mi := &file_hostprep_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PhaseInputs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PhaseInputs) ProtoMessage() {}
+
+func (x *PhaseInputs) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PhaseInputs.ProtoReflect.Descriptor instead.
+func (*PhaseInputs) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PhaseInputs) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type RegisterRunResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Run           *Run                   `protobuf:"bytes,1,opt,name=run,proto3" json:"run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterRunResponse) Reset() {
+	*x = RegisterRunResponse{}
+	mi := &file_hostprep_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRunResponse) ProtoMessage() {}
+
+func (x *RegisterRunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRunResponse.ProtoReflect.Descriptor instead.
+func (*RegisterRunResponse) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RegisterRunResponse) GetRun() *Run {
+	if x != nil {
+		return x.Run
+	}
+	return nil
+}
+
+type Run struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status RunStatus              `protobuf:"varint,2,opt,name=status,proto3,enum=hostprep.v1.RunStatus" json:"status,omitempty"`
+	// Unset while the run is queued.
+	StartedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Error        string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	PendingInput *PendingInput          `protobuf:"bytes,6,opt,name=pending_input,json=pendingInput,proto3" json:"pending_input,omitempty"`
+	SubmittedAt  *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=submitted_at,json=submittedAt,proto3" json:"submitted_at,omitempty"`
+	// Who started the run; empty unless the server requires tokens.
+	Principal     string `protobuf:"bytes,8,opt,name=principal,proto3" json:"principal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Run) Reset() {
+	*x = Run{}
+	mi := &file_hostprep_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Run) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Run) ProtoMessage() {}
+
+func (x *Run) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Run.ProtoReflect.Descriptor instead.
+func (*Run) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Run) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Run) GetStatus() RunStatus {
+	if x != nil {
+		return x.Status
+	}
+	return RunStatus_RUN_STATUS_UNSPECIFIED
+}
+
+func (x *Run) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Run) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Run) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Run) GetPendingInput() *PendingInput {
+	if x != nil {
+		return x.PendingInput
+	}
+	return nil
+}
+
+func (x *Run) GetSubmittedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SubmittedAt
+	}
+	return nil
+}
+
+func (x *Run) GetPrincipal() string {
+	if x != nil {
+		return x.Principal
+	}
+	return ""
+}
+
+type PendingInput struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	PhaseId     string                 `protobuf:"bytes,1,opt,name=phase_id,json=phaseId,proto3" json:"phase_id,omitempty"`
+	InputId     string                 `protobuf:"bytes,2,opt,name=input_id,json=inputId,proto3" json:"input_id,omitempty"`
+	Label       string                 `protobuf:"bytes,3,opt,name=label,proto3" json:"label,omitempty"`
+	Description string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Kind        string                 `protobuf:"bytes,5,opt,name=kind,proto3" json:"kind,omitempty"`
+	Required    bool                   `protobuf:"varint,6,opt,name=required,proto3" json:"required,omitempty"`
+	Secret      bool                   `protobuf:"varint,7,opt,name=secret,proto3" json:"secret,omitempty"`
+	Options     []*InputOption         `protobuf:"bytes,8,rep,name=options,proto3" json:"options,omitempty"`
+	// Never set for secret inputs.
+	DefaultValue  string `protobuf:"bytes,9,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"`
+	Reason        string `protobuf:"bytes,10,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingInput) Reset() {
+	*x = PendingInput{}
+	mi := &file_hostprep_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingInput) ProtoMessage() {}
+
+func (x *PendingInput) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingInput.ProtoReflect.Descriptor instead.
+func (*PendingInput) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PendingInput) GetPhaseId() string {
+	if x != nil {
+		return x.PhaseId
+	}
+	return ""
+}
+
+func (x *PendingInput) GetInputId() string {
+	if x != nil {
+		return x.InputId
+	}
+	return ""
+}
+
+func (x *PendingInput) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *PendingInput) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *PendingInput) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *PendingInput) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *PendingInput) GetSecret() bool {
+	if x != nil {
+		return x.Secret
+	}
+	return false
+}
+
+func (x *PendingInput) GetOptions() []*InputOption {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *PendingInput) GetDefaultValue() string {
+	if x != nil {
+		return x.DefaultValue
+	}
+	return ""
+}
+
+func (x *PendingInput) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type InputOption struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InputOption) Reset() {
+	*x = InputOption{}
+	mi := &file_hostprep_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InputOption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InputOption) ProtoMessage() {}
+
+func (x *InputOption) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InputOption.ProtoReflect.Descriptor instead.
+func (*InputOption) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InputOption) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *InputOption) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *InputOption) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	AfterSeq      int64                  `protobuf:"varint,2,opt,name=after_seq,json=afterSeq,proto3" json:"after_seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_hostprep_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamEventsRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetAfterSeq() int64 {
+	if x != nil {
+		return x.AfterSeq
+	}
+	return 0
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   int64                  `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	// phase_started, phase_completed, input_requested, command, task, or
+	// pipeline_finished.
+	Type      string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Phase     *Phase                 `protobuf:"bytes,4,opt,name=phase,proto3" json:"phase,omitempty"`
+	Input     *PendingInput          `protobuf:"bytes,5,opt,name=input,proto3" json:"input,omitempty"`
+	Status    RunStatus              `protobuf:"varint,6,opt,name=status,proto3,enum=hostprep.v1.RunStatus" json:"status,omitempty"`
+	Error     string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	// The remote command of a command event.
+	Command string `protobuf:"bytes,8,opt,name=command,proto3" json:"command,omitempty"`
+	Task    *Task  `protobuf:"bytes,9,opt,name=task,proto3" json:"task,omitempty"`
+	// The app's summary fields on pipeline_finished, keyed by label.
+	Summary       map[string]string `protobuf:"bytes,10,rep,name=summary,proto3" json:"summary,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_hostprep_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Event) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Event) GetPhase() *Phase {
+	if x != nil {
+		return x.Phase
+	}
+	return nil
+}
+
+func (x *Event) GetInput() *PendingInput {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *Event) GetStatus() RunStatus {
+	if x != nil {
+		return x.Status
+	}
+	return RunStatus_RUN_STATUS_UNSPECIFIED
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Event) GetTask() *Task {
+	if x != nil {
+		return x.Task
+	}
+	return nil
+}
+
+func (x *Event) GetSummary() map[string]string {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+type Task struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Host          string                 `protobuf:"bytes,2,opt,name=host,proto3" json:"host,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_hostprep_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Task) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Task) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Task) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Task) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type Phase struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Phase) Reset() {
+	*x = Phase{}
+	mi := &file_hostprep_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Phase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Phase) ProtoMessage() {}
+
+func (x *Phase) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Phase.ProtoReflect.Descriptor instead.
+func (*Phase) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Phase) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Phase) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+type ProvideInputRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	PhaseId       string                 `protobuf:"bytes,2,opt,name=phase_id,json=phaseId,proto3" json:"phase_id,omitempty"`
+	InputId       string                 `protobuf:"bytes,3,opt,name=input_id,json=inputId,proto3" json:"input_id,omitempty"`
+	Value         string                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProvideInputRequest) Reset() {
+	*x = ProvideInputRequest{}
+	mi := &file_hostprep_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProvideInputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvideInputRequest) ProtoMessage() {}
+
+func (x *ProvideInputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvideInputRequest.ProtoReflect.Descriptor instead.
+func (*ProvideInputRequest) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ProvideInputRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *ProvideInputRequest) GetPhaseId() string {
+	if x != nil {
+		return x.PhaseId
+	}
+	return ""
+}
+
+func (x *ProvideInputRequest) GetInputId() string {
+	if x != nil {
+		return x.InputId
+	}
+	return ""
+}
+
+func (x *ProvideInputRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type ProvideInputResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProvideInputResponse) Reset() {
+	*x = ProvideInputResponse{}
+	mi := &file_hostprep_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProvideInputResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvideInputResponse) ProtoMessage() {}
+
+func (x *ProvideInputResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvideInputResponse.ProtoReflect.Descriptor instead.
+func (*ProvideInputResponse) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{11}
+}
+
+type GetRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRunRequest) Reset() {
+	*x = GetRunRequest{}
+	mi := &file_hostprep_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRunRequest) ProtoMessage() {}
+
+func (x *GetRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRunRequest.ProtoReflect.Descriptor instead.
+func (*GetRunRequest) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetRunRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type CancelRunRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunRequest) Reset() {
+	*x = CancelRunRequest{}
+	mi := &file_hostprep_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunRequest) ProtoMessage() {}
+
+func (x *CancelRunRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunRequest.ProtoReflect.Descriptor instead.
+func (*CancelRunRequest) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CancelRunRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type CancelRunResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRunResponse) Reset() {
+	*x = CancelRunResponse{}
+	mi := &file_hostprep_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRunResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRunResponse) ProtoMessage() {}
+
+func (x *CancelRunResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hostprep_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRunResponse.ProtoReflect.Descriptor instead.
+func (*CancelRunResponse) Descriptor() ([]byte, []int) {
+	return file_hostprep_proto_rawDescGZIP(), []int{14}
+}
+
+var File_hostprep_proto protoreflect.FileDescriptor
+
+const file_hostprep_proto_rawDesc = "" +
+	"\n" +
+	"\x0ehostprep.proto\x12\vhostprep.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc2\x01\n" +
+	"\x12RegisterRunRequest\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12C\n" +
+	"\x06inputs\x18\x02 \x03(\v2+.hostprep.v1.RegisterRunRequest.InputsEntryR\x06inputs\x1aS\n" +
+	"\vInputsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x05value\x18\x02 \x01(\v2\x18.hostprep.v1.PhaseInputsR\x05value:\x028\x01\"\x86\x01\n" +
+	"\vPhaseInputs\x12<\n" +
+	"\x06values\x18\x01 \x03(\v2$.hostprep.v1.PhaseInputs.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"9\n" +
+	"\x13RegisterRunResponse\x12\"\n" +
+	"\x03run\x18\x01 \x01(\v2\x10.hostprep.v1.RunR\x03run\"\xf0\x02\n" +
+	"\x03Run\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12.\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x16.hostprep.v1.RunStatusR\x06status\x129\n" +
+	"\n" +
+	"started_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12>\n" +
+	"\rpending_input\x18\x06 \x01(\v2\x19.hostprep.v1.PendingInputR\fpendingInput\x12=\n" +
+	"\fsubmitted_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\vsubmittedAt\x12\x1c\n" +
+	"\tprincipal\x18\b \x01(\tR\tprincipal\"\xb5\x02\n" +
+	"\fPendingInput\x12\x19\n" +
+	"\bphase_id\x18\x01 \x01(\tR\aphaseId\x12\x19\n" +
+	"\binput_id\x18\x02 \x01(\tR\ainputId\x12\x14\n" +
+	"\x05label\x18\x03 \x01(\tR\x05label\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04kind\x18\x05 \x01(\tR\x04kind\x12\x1a\n" +
+	"\brequired\x18\x06 \x01(\bR\brequired\x12\x16\n" +
+	"\x06secret\x18\a \x01(\bR\x06secret\x122\n" +
+	"\aoptions\x18\b \x03(\v2\x18.hostprep.v1.InputOptionR\aoptions\x12#\n" +
+	"\rdefault_value\x18\t \x01(\tR\fdefaultValue\x12\x16\n" +
+	"\x06reason\x18\n" +
+	" \x01(\tR\x06reason\"[\n" +
+	"\vInputOption\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\"I\n" +
+	"\x13StreamEventsRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x1b\n" +
+	"\tafter_seq\x18\x02 \x01(\x03R\bafterSeq\"\xc0\x03\n" +
+	"\x05Event\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x03R\x03seq\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12(\n" +
+	"\x05phase\x18\x04 \x01(\v2\x12.hostprep.v1.PhaseR\x05phase\x12/\n" +
+	"\x05input\x18\x05 \x01(\v2\x19.hostprep.v1.PendingInputR\x05input\x12.\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x16.hostprep.v1.RunStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x12\x18\n" +
+	"\acommand\x18\b \x01(\tR\acommand\x12%\n" +
+	"\x04task\x18\t \x01(\v2\x11.hostprep.v1.TaskR\x04task\x129\n" +
+	"\asummary\x18\n" +
+	" \x03(\v2\x1f.hostprep.v1.Event.SummaryEntryR\asummary\x1a:\n" +
+	"\fSummaryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"`\n" +
+	"\x04Task\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04host\x18\x02 \x01(\tR\x04host\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"-\n" +
+	"\x05Phase\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\"x\n" +
+	"\x13ProvideInputRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12\x19\n" +
+	"\bphase_id\x18\x02 \x01(\tR\aphaseId\x12\x19\n" +
+	"\binput_id\x18\x03 \x01(\tR\ainputId\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\"\x16\n" +
+	"\x14ProvideInputResponse\"&\n" +
+	"\rGetRunRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\")\n" +
+	"\x10CancelRunRequest\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\"\x13\n" +
+	"\x11CancelRunResponse*\xbf\x01\n" +
+	"\tRunStatus\x12\x1a\n" +
+	"\x16RUN_STATUS_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12RUN_STATUS_RUNNING\x10\x01\x12\x1c\n" +
+	"\x18RUN_STATUS_WAITING_INPUT\x10\x02\x12\x18\n" +
+	"\x14RUN_STATUS_SUCCEEDED\x10\x03\x12\x15\n" +
+	"\x11RUN_STATUS_FAILED\x10\x04\x12\x18\n" +
+	"\x14RUN_STATUS_CANCELLED\x10\x05\x12\x15\n" +
+	"\x11RUN_STATUS_QUEUED\x10\x062\x84\x03\n" +
+	"\x0fHostPrepService\x12P\n" +
+	"\vRegisterRun\x12\x1f.hostprep.v1.RegisterRunRequest\x1a .hostprep.v1.RegisterRunResponse\x12F\n" +
+	"\fStreamEvents\x12 .hostprep.v1.StreamEventsRequest\x1a\x12.hostprep.v1.Event0\x01\x12S\n" +
+	"\fProvideInput\x12 .hostprep.v1.ProvideInputRequest\x1a!.hostprep.v1.ProvideInputResponse\x126\n" +
+	"\x06GetRun\x12\x1a.hostprep.v1.GetRunRequest\x1a\x10.hostprep.v1.Run\x12J\n" +
+	"\tCancelRun\x12\x1d.hostprep.v1.CancelRunRequest\x1a\x1e.hostprep.v1.CancelRunResponseBSZQgithub.com/BrianJOC/ansible-host-prep/pkg/phasedapp/grpcapi/hostprepv1;hostprepv1b\x06proto3"
+
+var (
+	file_hostprep_proto_rawDescOnce sync.Once
+	file_hostprep_proto_rawDescData []byte
+)
+
+func file_hostprep_proto_rawDescGZIP() []byte {
+	file_hostprep_proto_rawDescOnce.Do(func() {
+		file_hostprep_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_hostprep_proto_rawDesc), len(file_hostprep_proto_rawDesc)))
+	})
+	return file_hostprep_proto_rawDescData
+}
+
+var file_hostprep_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_hostprep_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_hostprep_proto_goTypes = []any{
+	(RunStatus)(0),                // 0: hostprep.v1.RunStatus
+	(*RegisterRunRequest)(nil),    // 1: hostprep.v1.RegisterRunRequest
+	(*PhaseInputs)(nil),           // 2: hostprep.v1.PhaseInputs
+	(*RegisterRunResponse)(nil),   // 3: hostprep.v1.RegisterRunResponse
+	(*Run)(nil),                   // 4: hostprep.v1.Run
+	(*PendingInput)(nil),          // 5: hostprep.v1.PendingInput
+	(*InputOption)(nil),           // 6: hostprep.v1.InputOption
+	(*StreamEventsRequest)(nil),   // 7: hostprep.v1.StreamEventsRequest
+	(*Event)(nil),                 // 8: hostprep.v1.Event
+	(*Task)(nil),                  // 9: hostprep.v1.Task
+	(*Phase)(nil),                 // 10: hostprep.v1.Phase
+	(*ProvideInputRequest)(nil),   // 11: hostprep.v1.ProvideInputRequest
+	(*ProvideInputResponse)(nil),  // 12: hostprep.v1.ProvideInputResponse
+	(*GetRunRequest)(nil),         // 13: hostprep.v1.GetRunRequest
+	(*CancelRunRequest)(nil),      // 14: hostprep.v1.CancelRunRequest
+	(*CancelRunResponse)(nil),     // 15: hostprep.v1.CancelRunResponse
+	nil,                           // 16: hostprep.v1.RegisterRunRequest.InputsEntry
+	nil,                           // 17: hostprep.v1.PhaseInputs.ValuesEntry
+	nil,                           // 18: hostprep.v1.Event.SummaryEntry
+	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
+}
+var file_hostprep_proto_depIdxs = []int32{
+	16, // 0: hostprep.v1.RegisterRunRequest.inputs:type_name -> hostprep.v1.RegisterRunRequest.InputsEntry
+	17, // 1: hostprep.v1.PhaseInputs.values:type_name -> hostprep.v1.PhaseInputs.ValuesEntry
+	4,  // 2: hostprep.v1.RegisterRunResponse.run:type_name -> hostprep.v1.Run
+	0,  // 3: hostprep.v1.Run.status:type_name -> hostprep.v1.RunStatus
+	19, // 4: hostprep.v1.Run.started_at:type_name -> google.protobuf.Timestamp
+	19, // 5: hostprep.v1.Run.finished_at:type_name -> google.protobuf.Timestamp
+	5,  // 6: hostprep.v1.Run.pending_input:type_name -> hostprep.v1.PendingInput
+	19, // 7: hostprep.v1.Run.submitted_at:type_name -> google.protobuf.Timestamp
+	6,  // 8: hostprep.v1.PendingInput.options:type_name -> hostprep.v1.InputOption
+	19, // 9: hostprep.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
+	10, // 10: hostprep.v1.Event.phase:type_name -> hostprep.v1.Phase
+	5,  // 11: hostprep.v1.Event.input:type_name -> hostprep.v1.PendingInput
+	0,  // 12: hostprep.v1.Event.status:type_name -> hostprep.v1.RunStatus
+	9,  // 13: hostprep.v1.Event.task:type_name -> hostprep.v1.Task
+	18, // 14: hostprep.v1.Event.summary:type_name -> hostprep.v1.Event.SummaryEntry
+	2,  // 15: hostprep.v1.RegisterRunRequest.InputsEntry.value:type_name -> hostprep.v1.PhaseInputs
+	1,  // 16: hostprep.v1.HostPrepService.RegisterRun:input_type -> hostprep.v1.RegisterRunRequest
+	7,  // 17: hostprep.v1.HostPrepService.StreamEvents:input_type -> hostprep.v1.StreamEventsRequest
+	11, // 18: hostprep.v1.HostPrepService.ProvideInput:input_type -> hostprep.v1.ProvideInputRequest
+	13, // 19: hostprep.v1.HostPrepService.GetRun:input_type -> hostprep.v1.GetRunRequest
+	14, // 20: hostprep.v1.HostPrepService.CancelRun:input_type -> hostprep.v1.CancelRunRequest
+	3,  // 21: hostprep.v1.HostPrepService.RegisterRun:output_type -> hostprep.v1.RegisterRunResponse
+	8,  // 22: hostprep.v1.HostPrepService.StreamEvents:output_type -> hostprep.v1.Event
+	12, // 23: hostprep.v1.HostPrepService.ProvideInput:output_type -> hostprep.v1.ProvideInputResponse
+	4,  // 24: hostprep.v1.HostPrepService.GetRun:output_type -> hostprep.v1.Run
+	15, // 25: hostprep.v1.HostPrepService.CancelRun:output_type -> hostprep.v1.CancelRunResponse
+	21, // [21:26] is the sub-list for method output_type
+	16, // [16:21] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_hostprep_proto_init() }
+func file_hostprep_proto_init() {
+	if File_hostprep_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_hostprep_proto_rawDesc), len(file_hostprep_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_hostprep_proto_goTypes,
+		DependencyIndexes: file_hostprep_proto_depIdxs,
+		EnumInfos:         file_hostprep_proto_enumTypes,
+		MessageInfos:      file_hostprep_proto_msgTypes,
+	}.Build()
+	File_hostprep_proto = out.File
+	file_hostprep_proto_goTypes = nil
+	file_hostprep_proto_depIdxs = nil
+}
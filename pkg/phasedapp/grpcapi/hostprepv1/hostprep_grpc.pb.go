@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.28.3
+// source: hostprep.proto
+
+package hostprepv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	HostPrepService_RegisterRun_FullMethodName  = "/hostprep.v1.HostPrepService/RegisterRun"
+	HostPrepService_StreamEvents_FullMethodName = "/hostprep.v1.HostPrepService/StreamEvents"
+	HostPrepService_ProvideInput_FullMethodName = "/hostprep.v1.HostPrepService/ProvideInput"
+	HostPrepService_GetRun_FullMethodName       = "/hostprep.v1.HostPrepService/GetRun"
+	HostPrepService_CancelRun_FullMethodName    = "/hostprep.v1.HostPrepService/CancelRun"
+)
+
+// HostPrepServiceClient is the client API for HostPrepService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// HostPrepService mirrors the phases.Manager lifecycle so other tools can
+// start a prep, follow its events, and answer the inputs phases request.
+type HostPrepServiceClient interface {
+	// RegisterRun validates the supplied inputs and starts a pipeline run.
+	RegisterRun(ctx context.Context, in *RegisterRunRequest, opts ...grpc.CallOption) (*RegisterRunResponse, error)
+	// StreamEvents replays a run's events after after_seq and follows new ones
+	// until the run finishes.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	// ProvideInput answers the input a run is waiting on.
+	ProvideInput(ctx context.Context, in *ProvideInputRequest, opts ...grpc.CallOption) (*ProvideInputResponse, error)
+	// GetRun returns a run's status and pending input.
+	GetRun(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error)
+	// CancelRun stops a run.
+	CancelRun(ctx context.Context, in *CancelRunRequest, opts ...grpc.CallOption) (*CancelRunResponse, error)
+}
+
+type hostPrepServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHostPrepServiceClient(cc grpc.ClientConnInterface) HostPrepServiceClient {
+	return &hostPrepServiceClient{cc}
+}
+
+func (c *hostPrepServiceClient) RegisterRun(ctx context.Context, in *RegisterRunRequest, opts ...grpc.CallOption) (*RegisterRunResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterRunResponse)
+	err := c.cc.Invoke(ctx, HostPrepService_RegisterRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostPrepServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &HostPrepService_ServiceDesc.Streams[0], HostPrepService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HostPrepService_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *hostPrepServiceClient) ProvideInput(ctx context.Context, in *ProvideInputRequest, opts ...grpc.CallOption) (*ProvideInputResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProvideInputResponse)
+	err := c.cc.Invoke(ctx, HostPrepService_ProvideInput_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostPrepServiceClient) GetRun(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Run)
+	err := c.cc.Invoke(ctx, HostPrepService_GetRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostPrepServiceClient) CancelRun(ctx context.Context, in *CancelRunRequest, opts ...grpc.CallOption) (*CancelRunResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelRunResponse)
+	err := c.cc.Invoke(ctx, HostPrepService_CancelRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HostPrepServiceServer is the server API for HostPrepService service.
+// All implementations must embed UnimplementedHostPrepServiceServer
+// for forward compatibility.
+//
+// HostPrepService mirrors the phases.Manager lifecycle so other tools can
+// start a prep, follow its events, and answer the inputs phases request.
+type HostPrepServiceServer interface {
+	// RegisterRun validates the supplied inputs and starts a pipeline run.
+	RegisterRun(context.Context, *RegisterRunRequest) (*RegisterRunResponse, error)
+	// StreamEvents replays a run's events after after_seq and follows new ones
+	// until the run finishes.
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	// ProvideInput answers the input a run is waiting on.
+	ProvideInput(context.Context, *ProvideInputRequest) (*ProvideInputResponse, error)
+	// GetRun returns a run's status and pending input.
+	GetRun(context.Context, *GetRunRequest) (*Run, error)
+	// CancelRun stops a run.
+	CancelRun(context.Context, *CancelRunRequest) (*CancelRunResponse, error)
+	mustEmbedUnimplementedHostPrepServiceServer()
+}
+
+// UnimplementedHostPrepServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHostPrepServiceServer struct{}
+
+func (UnimplementedHostPrepServiceServer) RegisterRun(context.Context, *RegisterRunRequest) (*RegisterRunResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RegisterRun not implemented")
+}
+func (UnimplementedHostPrepServiceServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedHostPrepServiceServer) ProvideInput(context.Context, *ProvideInputRequest) (*ProvideInputResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ProvideInput not implemented")
+}
+func (UnimplementedHostPrepServiceServer) GetRun(context.Context, *GetRunRequest) (*Run, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRun not implemented")
+}
+func (UnimplementedHostPrepServiceServer) CancelRun(context.Context, *CancelRunRequest) (*CancelRunResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CancelRun not implemented")
+}
+func (UnimplementedHostPrepServiceServer) mustEmbedUnimplementedHostPrepServiceServer() {}
+func (UnimplementedHostPrepServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeHostPrepServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HostPrepServiceServer will
+// result in compilation errors.
+type UnsafeHostPrepServiceServer interface {
+	mustEmbedUnimplementedHostPrepServiceServer()
+}
+
+func RegisterHostPrepServiceServer(s grpc.ServiceRegistrar, srv HostPrepServiceServer) {
+	// If the following call panics, it indicates UnimplementedHostPrepServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HostPrepService_ServiceDesc, srv)
+}
+
+func _HostPrepService_RegisterRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostPrepServiceServer).RegisterRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostPrepService_RegisterRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostPrepServiceServer).RegisterRun(ctx, req.(*RegisterRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostPrepService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(HostPrepServiceServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type HostPrepService_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+func _HostPrepService_ProvideInput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProvideInputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostPrepServiceServer).ProvideInput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostPrepService_ProvideInput_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostPrepServiceServer).ProvideInput(ctx, req.(*ProvideInputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostPrepService_GetRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostPrepServiceServer).GetRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostPrepService_GetRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostPrepServiceServer).GetRun(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostPrepService_CancelRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostPrepServiceServer).CancelRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostPrepService_CancelRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostPrepServiceServer).CancelRun(ctx, req.(*CancelRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HostPrepService_ServiceDesc is the grpc.ServiceDesc for HostPrepService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HostPrepService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "hostprep.v1.HostPrepService",
+	HandlerType: (*HostPrepServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterRun",
+			Handler:    _HostPrepService_RegisterRun_Handler,
+		},
+		{
+			MethodName: "ProvideInput",
+			Handler:    _HostPrepService_ProvideInput_Handler,
+		},
+		{
+			MethodName: "GetRun",
+			Handler:    _HostPrepService_GetRun_Handler,
+		},
+		{
+			MethodName: "CancelRun",
+			Handler:    _HostPrepService_CancelRun_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _HostPrepService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "hostprep.proto",
+}
@@ -0,0 +1,103 @@
+package phasedapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+// ErrInputNotSupplied reports that a headless run reached an input that was
+// not part of the pre-supplied answers.
+var ErrInputNotSupplied = errors.New("phasedapp: input not supplied")
+
+// RunHeadless executes the pipeline without the TUI. Supplied inputs are
+// validated up front, before any phase runs, and every problem is reported in
+// a single phases.InputValidationError. Progress lines are written to out, as
+// NDJSON events when WithJSONOutput is set (with the lines also going to the
+// WithLogOutput writer, if any).
+func (a *App) RunHeadless(ctx context.Context, inputs phases.Inputs, out io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if out == nil {
+		out = io.Discard
+	}
+
+	var jsonOut *jsonObserver
+	var observer phases.Observer = headlessObserver{out: out}
+	if a.cfg.JSONOutput {
+		jsonOut = newJSONObserver(out, a.cfg.SummaryFields)
+		observer = jsonOut
+	}
+
+	managerOpts := append([]phases.ManagerOption{}, a.cfg.ManagerOptions...)
+	if jsonOut != nil && a.cfg.LogOutput != nil {
+		managerOpts = append(managerOpts, phases.WithObserver(headlessObserver{out: a.cfg.LogOutput}))
+	}
+	managerOpts = append(managerOpts,
+		phases.WithObserver(observer),
+		phases.WithInputHandler(&headlessInputs{defaulted: make(map[string]bool)}),
+	)
+	manager := phases.NewManager(managerOpts...)
+	if err := manager.Register(a.cfg.Phases...); err != nil {
+		return err
+	}
+
+	if jsonOut != nil {
+		jsonOut.trackSecrets(manager.Metadata(), inputs)
+		jsonOut.trackPhases(manager.Metadata())
+	}
+	normalized, err := manager.ValidateInputs(inputs)
+	if err != nil {
+		if jsonOut != nil {
+			jsonOut.validationFailed(err)
+		}
+		return err
+	}
+
+	phaseCtx := phases.NewContext()
+	phases.SeedInputs(phaseCtx, normalized)
+	return manager.Run(ctx, phaseCtx)
+}
+
+// headlessInputs answers input requests during a headless run. An input with a
+// default is answered with it once, so required inputs with sensible defaults
+// need not be supplied explicitly; anything else fails the run.
+type headlessInputs struct {
+	defaulted map[string]bool
+}
+
+func (h *headlessInputs) RequestInput(meta phases.PhaseMetadata, input phases.InputDefinition, reason string) (any, error) {
+	key := meta.ID + "." + input.ID
+	if input.Default != nil && input.Default != "" && input.Kind != phases.InputKindSecret && !h.defaulted[key] {
+		h.defaulted[key] = true
+		return input.Default, nil
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInputNotSupplied, key)
+	}
+	return nil, fmt.Errorf("%w: %s (%s)", ErrInputNotSupplied, key, reason)
+}
+
+type headlessObserver struct {
+	out io.Writer
+}
+
+func (o headlessObserver) PhaseStarted(meta phases.PhaseMetadata) {
+	fmt.Fprintf(o.out, "==> %s\n", meta.Title)
+}
+
+func (o headlessObserver) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	if err != nil {
+		fmt.Fprintf(o.out, "[failed] %s: %v\n", meta.Title, err)
+		return
+	}
+	fmt.Fprintf(o.out, "[ok] %s\n", meta.Title)
+}
+
+func (o headlessObserver) TaskProgress(_ phases.PhaseMetadata, event phases.TaskEvent) {
+	fmt.Fprintf(o.out, "    %s\n", taskLine(event))
+}
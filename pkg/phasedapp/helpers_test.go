@@ -67,6 +67,21 @@ func TestSelectPhasesByTag(t *testing.T) {
 	require.Equal(t, "one", selected[0].Metadata().ID)
 }
 
+func TestSelectPhasesByAnyTag(t *testing.T) {
+	t.Parallel()
+
+	list := []phasespkg.Phase{
+		SimplePhase{meta: phasespkg.PhaseMetadata{ID: "one", Tags: []string{"ansible"}}},
+		SimplePhase{meta: phasespkg.PhaseMetadata{ID: "two", Tags: []string{"k8s", "swap"}}},
+		SimplePhase{meta: phasespkg.PhaseMetadata{ID: "three", Tags: []string{"k8s", "sysctl"}}},
+	}
+	selected := SelectPhases(list, WithAnyTag("Swap", " ansible ", ""))
+	require.Len(t, selected, 2)
+	require.Equal(t, "one", selected[0].Metadata().ID)
+	require.Equal(t, "two", selected[1].Metadata().ID)
+	require.Empty(t, SelectPhases(list, WithAnyTag()))
+}
+
 func TestContextHelpers(t *testing.T) {
 	t.Parallel()
 
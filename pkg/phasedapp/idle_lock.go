@@ -0,0 +1,95 @@
+package phasedapp
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+const (
+	defaultSecretIdleTimeout = 2 * time.Minute
+	idleCheckInterval        = 5 * time.Second
+)
+
+// WithSecretIdleTimeout controls how long a secret prompt may sit idle before
+// the typed value is cleared and the prompt locks until the operator confirms
+// with Enter. Zero keeps the default; a negative duration disables locking.
+func WithSecretIdleTimeout(d time.Duration) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.SecretIdleTimeout = d
+	}
+}
+
+type idleTickMsg struct {
+	seq int
+	now time.Time
+}
+
+func resolveSecretIdleTimeout(d time.Duration) time.Duration {
+	if d == 0 {
+		return defaultSecretIdleTimeout
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// watchIdleSecret starts an idle watch for the active prompt when it is secret.
+func (m *model) watchIdleSecret() tea.Cmd {
+	m.idleSeq++
+	m.promptLocked = false
+	m.lastActivity = time.Now()
+	if m.secretIdleTimeout <= 0 || !m.isSecretPrompt() {
+		return nil
+	}
+	return idleTickCmd(m.idleSeq)
+}
+
+func (m *model) handleIdleTick(msg idleTickMsg) tea.Cmd {
+	if msg.seq != m.idleSeq || !m.isSecretPrompt() {
+		return nil
+	}
+	if !m.promptLocked && msg.now.Sub(m.lastActivity) >= m.secretIdleTimeout {
+		m.lockPrompt()
+	}
+	return idleTickCmd(m.idleSeq)
+}
+
+func (m *model) lockPrompt() {
+	m.promptLocked = true
+	m.prompt.SetValue("")
+	m.prompt.Blur()
+	m.setStatusf("%s locked after %s idle — press Enter to resume", m.activePrompt.input.Label, m.secretIdleTimeout)
+}
+
+func (m *model) handleLockedPromptKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEsc:
+		m.promptLocked = false
+		return m.handleEscape()
+	case tea.KeyEnter:
+		m.promptLocked = false
+		m.focus = focusPrompt
+		m.prompt.Focus()
+		m.setStatusf("%s needs %s", m.activePrompt.meta.Title, m.activePrompt.input.Label)
+	}
+	return nil
+}
+
+func (m *model) isSecretPrompt() bool {
+	return m.prompting && m.activePrompt != nil && m.activePrompt.input.Kind == phases.InputKindSecret
+}
+
+func idleTickCmd(seq int) tea.Cmd {
+	return tea.Tick(idleCheckInterval, func(t time.Time) tea.Msg {
+		return idleTickMsg{seq: seq, now: t}
+	})
+}
@@ -0,0 +1,259 @@
+package phasedapp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+// WithJSONOutput makes RunHeadless write one JSON object per event (NDJSON)
+// instead of human-readable progress lines. Supplied secret values are
+// replaced with "[secret]" wherever they appear.
+func WithJSONOutput() Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.JSONOutput = true
+	}
+}
+
+// WithLogOutput makes RunHeadless also write its human-readable progress
+// lines to w when WithJSONOutput is set, so wrappers can read events from
+// stdout while operators follow the run on stderr.
+func WithLogOutput(w io.Writer) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.LogOutput = w
+	}
+}
+
+// JSONEvent is a single line of NDJSON output.
+type JSONEvent struct {
+	Time     time.Time  `json:"time"`
+	Event    string     `json:"event"`
+	Phase    *JSONPhase `json:"phase,omitempty"`
+	Input    string     `json:"input,omitempty"`
+	Command  string     `json:"command,omitempty"`
+	Task     *JSONTask  `json:"task,omitempty"`
+	Success  *bool      `json:"success,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Problems []string   `json:"problems,omitempty"`
+	// Summary carries the summary fields on "pipeline_finished", keyed by
+	// label, so a run's results can be compared with another host's.
+	Summary map[string]string `json:"summary,omitempty"`
+	// Progress places phase_started and phase_completed events in the
+	// pipeline, so wrappers can draw a progress bar.
+	Progress *JSONProgress `json:"progress,omitempty"`
+}
+
+// JSONPhase identifies the phase a JSONEvent refers to.
+type JSONPhase struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// JSONProgress is the position of a phase in the pipeline. Phase counts
+// from 1; a phase_completed event with Phase == Total ends the last phase.
+// Percent is the share of the pipeline done, with phases weighted by
+// their estimated duration (see phases.Progress).
+type JSONProgress struct {
+	Phase   int `json:"phase"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// JSONTask is the per-task progress carried by "task" events.
+type JSONTask struct {
+	Name    string `json:"name"`
+	Host    string `json:"host,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// jsonObserver writes NDJSON events. It also observes inputs and commands so
+// log scrapers see every step the run takes.
+type jsonObserver struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	secrets []string
+	fields  []SummaryField
+	// positions maps phase IDs to their 1-based place in the pipeline.
+	positions map[string]int
+	metas     []phases.PhaseMetadata
+	now       func() time.Time
+}
+
+func newJSONObserver(out io.Writer, fields []SummaryField) *jsonObserver {
+	return &jsonObserver{enc: json.NewEncoder(out), fields: fields, now: time.Now}
+}
+
+// trackSecrets records the secret values among inputs for redaction.
+func (o *jsonObserver) trackSecrets(metas []phases.PhaseMetadata, inputs phases.Inputs) {
+	o.secrets = append(o.secrets, secretValues(metas, inputs)...)
+}
+
+// trackPhases records the pipeline order for progress reporting.
+func (o *jsonObserver) trackPhases(metas []phases.PhaseMetadata) {
+	o.positions = make(map[string]int, len(metas))
+	o.metas = metas
+	for i, meta := range metas {
+		o.positions[meta.ID] = i + 1
+	}
+}
+
+// progress places meta in the pipeline. Phases before it count as done, and
+// meta itself too once it has completed.
+func (o *jsonObserver) progress(meta phases.PhaseMetadata, completed bool) *JSONProgress {
+	position, ok := o.positions[meta.ID]
+	if !ok {
+		return nil
+	}
+	fraction := phases.Progress(o.metas, func(other phases.PhaseMetadata) float64 {
+		if done := o.positions[other.ID]; done < position || (completed && done == position) {
+			return 1
+		}
+		return 0
+	})
+	return &JSONProgress{Phase: position, Total: len(o.positions), Percent: int(fraction * 100)}
+}
+
+func (o *jsonObserver) PhaseStarted(meta phases.PhaseMetadata) {
+	o.emit(JSONEvent{Event: "phase_started", Phase: jsonPhase(meta), Progress: o.progress(meta, false)})
+}
+
+func (o *jsonObserver) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	event := JSONEvent{Event: "phase_completed", Phase: jsonPhase(meta), Progress: o.progress(meta, err == nil), Success: boolPtr(err == nil)}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	o.emit(event)
+}
+
+func (o *jsonObserver) InputRequested(meta phases.PhaseMetadata, input phases.InputDefinition, reason string) {
+	event := JSONEvent{Event: "input_requested", Phase: jsonPhase(meta), Input: input.ID}
+	if input.Kind != phases.InputKindSecret && !input.Secret {
+		event.Error = reason
+	}
+	o.emit(event)
+}
+
+func (o *jsonObserver) CommandStarted(meta phases.PhaseMetadata, cmd string) func(error) {
+	o.emit(JSONEvent{Event: "command_started", Phase: jsonPhase(meta), Command: cmd})
+	return func(err error) {
+		event := JSONEvent{Event: "command_finished", Phase: jsonPhase(meta), Command: cmd, Success: boolPtr(err == nil)}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		o.emit(event)
+	}
+}
+
+func (o *jsonObserver) TaskProgress(meta phases.PhaseMetadata, event phases.TaskEvent) {
+	o.emit(JSONEvent{Event: "task", Phase: jsonPhase(meta), Task: &JSONTask{
+		Name:    event.Task,
+		Host:    event.Host,
+		Status:  string(event.Status),
+		Message: event.Message,
+	}})
+}
+
+func (o *jsonObserver) PipelineCompleted(phaseCtx *phases.Context, err error) {
+	event := JSONEvent{Event: "pipeline_finished", Success: boolPtr(err == nil)}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	event.Summary = summaryValues(o.fields, phaseCtx)
+	o.emit(event)
+}
+
+// summaryValues renders fields against phaseCtx, keyed by label. It returns
+// nil when no field has a value.
+func summaryValues(fields []SummaryField, phaseCtx *phases.Context) map[string]string {
+	var summary map[string]string
+	for _, field := range fields {
+		if field.Value == nil || phaseCtx == nil {
+			continue
+		}
+		if value, ok := field.Value(phaseCtx); ok {
+			if summary == nil {
+				summary = make(map[string]string)
+			}
+			summary[field.Label] = value
+		}
+	}
+	return summary
+}
+
+// validationFailed reports inputs rejected before any phase ran.
+func (o *jsonObserver) validationFailed(err error) {
+	event := JSONEvent{Event: "validation_failed", Success: boolPtr(false), Error: err.Error()}
+	var validationErr phases.InputValidationError
+	if errors.As(err, &validationErr) {
+		for _, problem := range validationErr.Problems {
+			event.Problems = append(event.Problems, problem.String())
+		}
+	}
+	o.emit(event)
+}
+
+func (o *jsonObserver) emit(event JSONEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	event.Time = o.now().UTC()
+	event.Command = o.redact(event.Command)
+	event.Error = o.redact(event.Error)
+	if event.Task != nil {
+		event.Task.Message = o.redact(event.Task.Message)
+	}
+	for i, problem := range event.Problems {
+		event.Problems[i] = o.redact(problem)
+	}
+	for label, value := range event.Summary {
+		event.Summary[label] = o.redact(value)
+	}
+	_ = o.enc.Encode(event)
+}
+
+func (o *jsonObserver) redact(text string) string {
+	return redactSecrets(text, o.secrets)
+}
+
+// secretValues returns the supplied values of secret inputs.
+func secretValues(metas []phases.PhaseMetadata, inputs phases.Inputs) []string {
+	var secrets []string
+	for _, meta := range metas {
+		for _, def := range meta.Inputs {
+			if def.Kind != phases.InputKindSecret && !def.Secret {
+				continue
+			}
+			if value := defaultString(inputs[meta.ID][def.ID]); value != "" {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	return secrets
+}
+
+// redactSecrets replaces every occurrence of a secret in text with "[secret]".
+func redactSecrets(text string, secrets []string) string {
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, "[secret]")
+	}
+	return text
+}
+
+func jsonPhase(meta phases.PhaseMetadata) *JSONPhase {
+	return &JSONPhase{ID: meta.ID, Title: meta.Title}
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
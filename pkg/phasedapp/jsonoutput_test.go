@@ -0,0 +1,130 @@
+package phasedapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	phasespkg "github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+func TestRunHeadlessJSONOutputRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	phase := stubPhase{
+		meta: phasespkg.PhaseMetadata{
+			ID:    "sudo",
+			Title: "Sudo",
+			Inputs: []phasespkg.InputDefinition{
+				{ID: "password", Kind: phasespkg.InputKindSecret, Secret: true},
+			},
+		},
+		run: func(ctx context.Context, phaseCtx *phasespkg.Context) error {
+			password, _ := phasespkg.GetInputString(phaseCtx, "sudo", "password")
+			finish := phasespkg.TraceCommand(ctx, "echo "+password+" | sudo -S true")
+			phasespkg.ReportTask(ctx, phasespkg.TaskEvent{Task: "Check sudo", Host: "web01", Status: phasespkg.TaskFailed, Message: "rejected " + password})
+			err := errors.New("sudo rejected " + password)
+			finish(err)
+			return err
+		},
+	}
+	app, err := New(WithPhases(phase), WithJSONOutput())
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = app.RunHeadless(context.Background(), phasespkg.Inputs{"sudo": {"password": "hunter2"}}, &out)
+	require.Error(t, err)
+	require.NotContains(t, out.String(), "hunter2")
+
+	var events []JSONEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event JSONEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Event)
+	}
+	require.Equal(t, []string{"phase_started", "command_started", "task", "command_finished", "phase_completed", "pipeline_finished"}, types)
+	require.Equal(t, "echo [secret] | sudo -S true", events[1].Command)
+	require.Equal(t, &JSONTask{Name: "Check sudo", Host: "web01", Status: "failed", Message: "rejected [secret]"}, events[2].Task)
+	require.Equal(t, "sudo", events[4].Phase.ID)
+	require.False(t, *events[4].Success)
+	require.Equal(t, "phase sudo failed: sudo rejected [secret]", events[5].Error)
+}
+
+func TestRunHeadlessJSONOutputReportsValidationProblems(t *testing.T) {
+	t.Parallel()
+
+	app, err := New(WithPhases(newStubPhase("ssh")), WithJSONOutput())
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.Error(t, app.RunHeadless(context.Background(), phasespkg.Inputs{"nope": {}}, &out))
+
+	var event JSONEvent
+	require.NoError(t, json.Unmarshal(out.Bytes(), &event))
+	require.Equal(t, "validation_failed", event.Event)
+	require.Equal(t, []string{"nope: unknown phase"}, event.Problems)
+}
+
+func TestRunHeadlessJSONOutputIncludesSummary(t *testing.T) {
+	t.Parallel()
+
+	phase := newStubPhaseFunc("ssh", func(_ context.Context, phaseCtx *phasespkg.Context) error {
+		phaseCtx.Set("ssh:host", "web01")
+		return nil
+	})
+	app, err := New(
+		WithPhases(phase),
+		WithJSONOutput(),
+		WithSummaryFields(
+			ContextField("Target host", ContextKey("ssh:host")),
+			ContextField("Python", ContextKey("python:interpreter")),
+		),
+	)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, app.RunHeadless(context.Background(), phasespkg.Inputs{}, &out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var event JSONEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &event))
+	require.Equal(t, "pipeline_finished", event.Event)
+	require.Equal(t, map[string]string{"Target host": "web01"}, event.Summary)
+}
+
+func TestRunHeadlessJSONOutputReportsProgressAndLogs(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	// sudo is estimated to take three times as long as ssh's default.
+	sudo := stubPhase{meta: phasespkg.PhaseMetadata{ID: "sudo", Title: "sudo", EstimatedDuration: 3 * phasespkg.DefaultEstimatedDuration}}
+	app, err := New(
+		WithPhases(newStubPhase("ssh"), sudo),
+		WithJSONOutput(),
+		WithLogOutput(&logs),
+	)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, app.RunHeadless(context.Background(), phasespkg.Inputs{}, &out))
+
+	var progress []JSONProgress
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event JSONEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		if event.Progress != nil {
+			progress = append(progress, *event.Progress)
+		}
+	}
+	require.Equal(t, []JSONProgress{{1, 2, 0}, {1, 2, 25}, {2, 2, 25}, {2, 2, 100}}, progress)
+	require.Equal(t, "==> ssh\n[ok] ssh\n==> sudo\n[ok] sudo\n", logs.String())
+}
@@ -0,0 +1,70 @@
+package phasedapp
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// LiveField is a SummaryField the TUI also shows in the phase details panel
+// while the pipeline runs, refreshed as soon as a phase sets one of Keys.
+type LiveField struct {
+	SummaryField
+	Keys []ContextKey
+}
+
+// WatchField builds a LiveField rendering field whenever any of keys is set.
+func WatchField(field SummaryField, keys ...ContextKey) LiveField {
+	return LiveField{SummaryField: field, Keys: keys}
+}
+
+// WithLiveFields appends fields shown in the details panel as phases
+// discover them, e.g. the detected OS or the created ansible user.
+func WithLiveFields(fields ...LiveField) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.LiveFields = append(cfg.LiveFields, fields...)
+	}
+}
+
+// contextChangedMsg reports that a phase set a key behind a live field.
+type contextChangedMsg struct{}
+
+// watchContext subscribes to the live field keys of the current context,
+// dropping the subscriptions of the context it replaces.
+func (m *model) watchContext() {
+	for _, cancel := range m.unwatch {
+		cancel()
+	}
+	m.unwatch = nil
+	changes := m.contextChanges
+	for _, field := range m.liveFields {
+		for _, key := range field.Keys {
+			m.unwatch = append(m.unwatch, m.phaseCtx.Watch(key.String(), func(any) {
+				// One pending notification is enough: the view reads the
+				// context itself when it renders.
+				select {
+				case changes <- contextChangedMsg{}:
+				default:
+				}
+			}))
+		}
+	}
+}
+
+func waitContextChangeCmd(changes <-chan contextChangedMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-changes
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// liveOutputs renders the live fields that currently have a value.
+func (m *model) liveOutputs() []string {
+	fields := make([]SummaryField, 0, len(m.liveFields))
+	for _, field := range m.liveFields {
+		fields = append(fields, field.SummaryField)
+	}
+	return m.fieldLines(fields)
+}
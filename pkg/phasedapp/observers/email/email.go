@@ -0,0 +1,172 @@
+// Package email provides a phases.Observer that mails a run summary, with the
+// full JSON report attached, to configured recipients when a pipeline run
+// finishes.
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+)
+
+// SendFunc delivers a message; it matches smtp.SendMail.
+type SendFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// Observer sends one email per finished run. Delivery failures never fail the
+// pipeline; they are passed to the error handler.
+type Observer struct {
+	*report.Recorder
+
+	addr      string
+	auth      smtp.Auth
+	from      string
+	to        []string
+	onlyFails bool
+	send      SendFunc
+	onError   func(error)
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithAuth authenticates with PLAIN auth (the SMTP server must offer TLS).
+func WithAuth(username, password string) Option {
+	return func(o *Observer) {
+		if username != "" {
+			host, _, _ := net.SplitHostPort(o.addr)
+			o.auth = smtp.PlainAuth("", username, password, host)
+		}
+	}
+}
+
+// WithFailuresOnly only sends reports for failed runs.
+func WithFailuresOnly() Option {
+	return func(o *Observer) {
+		o.onlyFails = true
+	}
+}
+
+// WithSendFunc overrides message delivery (default: smtp.SendMail).
+func WithSendFunc(send SendFunc) Option {
+	return func(o *Observer) {
+		if send != nil {
+			o.send = send
+		}
+	}
+}
+
+// WithErrorHandler receives delivery failures (default: ignored).
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *Observer) {
+		if fn != nil {
+			o.onError = fn
+		}
+	}
+}
+
+// New constructs an Observer sending through the SMTP server at host:port.
+func New(host string, port int, from string, to []string, opts ...Option) *Observer {
+	o := &Observer{
+		addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		from:    from,
+		to:      to,
+		send:    smtp.SendMail,
+		onError: func(error) {},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	o.Recorder = report.NewRecorder(o.deliver)
+	return o
+}
+
+func (o *Observer) deliver(rep *report.Report) {
+	if o.onlyFails && rep.Success {
+		return
+	}
+	msg, err := Compose(o.from, o.to, rep)
+	if err != nil {
+		o.onError(err)
+		return
+	}
+	if err := o.send(o.addr, o.auth, o.from, o.to, msg); err != nil {
+		o.onError(fmt.Errorf("email: send report: %w", err))
+	}
+}
+
+// Compose builds a multipart message with the text summary as the body and
+// the JSON report attached.
+func Compose(from string, to []string, rep *report.Report) ([]byte, error) {
+	attachment, err := rep.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("email: encode report: %w", err)
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	outcome := "succeeded"
+	if !rep.Success {
+		outcome = "FAILED"
+	}
+	host := rep.Host
+	if host == "" {
+		host = "unknown host"
+	}
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: [ansible-host-prep] %s %s\r\n", host, outcome)
+	fmt.Fprintf(&buf, "Date: %s\r\n", rep.FinishedAt.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	body, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, fmt.Errorf("email: compose: %w", err)
+	}
+	if _, err := body.Write([]byte(strings.ReplaceAll(rep.Text(), "\n", "\r\n"))); err != nil {
+		return nil, fmt.Errorf("email: compose: %w", err)
+	}
+
+	name := fmt.Sprintf("host-prep-%s-%s.json", fileSafe(host), rep.FinishedAt.UTC().Format("20060102T150405Z"))
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"application/json"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("email: compose: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+	for len(encoded) > 76 {
+		fmt.Fprintf(part, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(part, "%s\r\n", encoded)
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("email: compose: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
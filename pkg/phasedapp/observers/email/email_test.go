@@ -0,0 +1,95 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+)
+
+func TestObserverMailsReportWithAttachment(t *testing.T) {
+	t.Parallel()
+
+	var sentTo []string
+	var sent []byte
+	obs := New("smtp.example.com", 587, "prep@example.com", []string{"ops@example.com"},
+		WithSendFunc(func(addr string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+			require.Equal(t, "smtp.example.com:587", addr)
+			sentTo, sent = to, msg
+			return nil
+		}),
+	)
+
+	manager := phases.NewManager(phases.WithObserver(obs))
+	require.NoError(t, manager.Register(phaseFunc{id: "ssh", err: errors.New("connection refused")}))
+	require.Error(t, manager.Run(context.Background(), nil))
+	require.Equal(t, []string{"ops@example.com"}, sentTo)
+
+	msg, err := mail.ReadMessage(bytes.NewReader(sent))
+	require.NoError(t, err)
+	require.Equal(t, "[ansible-host-prep] 10.0.0.5 FAILED", msg.Header.Get("Subject"))
+
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	body, err := reader.NextPart()
+	require.NoError(t, err)
+	text, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Contains(t, string(text), "[failed] SSH")
+	require.Contains(t, string(text), "connection refused")
+
+	attachment, err := reader.NextPart()
+	require.NoError(t, err)
+	require.Contains(t, attachment.FileName(), "host-prep-10.0.0.5-")
+	var rep report.Report
+	require.NoError(t, json.NewDecoder(base64.NewDecoder(base64.StdEncoding, attachment)).Decode(&rep))
+	require.False(t, rep.Success)
+	require.Equal(t, "10.0.0.5", rep.Host)
+	require.Len(t, rep.Phases, 1)
+}
+
+func TestObserverFailuresOnlySkipsSuccess(t *testing.T) {
+	t.Parallel()
+
+	sends := 0
+	obs := New("smtp.example.com", 25, "prep@example.com", []string{"ops@example.com"},
+		WithFailuresOnly(),
+		WithSendFunc(func(string, smtp.Auth, string, []string, []byte) error {
+			sends++
+			return nil
+		}),
+	)
+	manager := phases.NewManager(phases.WithObserver(obs))
+	require.NoError(t, manager.Register(phaseFunc{id: "ssh"}))
+	require.NoError(t, manager.Run(context.Background(), nil))
+	require.Zero(t, sends)
+}
+
+type phaseFunc struct {
+	id  string
+	err error
+}
+
+func (p phaseFunc) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{ID: p.id, Title: "SSH"}
+}
+
+func (p phaseFunc) Run(_ context.Context, phaseCtx *phases.Context) error {
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	return p.err
+}
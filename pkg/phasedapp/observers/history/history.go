@@ -0,0 +1,289 @@
+// Package history provides a phases.Observer that keeps the report of every
+// pipeline run, failed ones included, in a JSON Lines file or a
+// storage.Store, and turns that run history into a per-host fleet dataset for
+// dashboards such as Grafana.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+)
+
+// keyPrefix holds one object per run in a storage.Store.
+const keyPrefix = "history/"
+
+// storeTimeout bounds saving one report to a store.
+const storeTimeout = 30 * time.Second
+
+// Observer saves one report.Report per finished run. Write failures never
+// fail the pipeline; they are passed to the error handler.
+type Observer struct {
+	*report.Recorder
+
+	save    func(*report.Report) error
+	onError func(error)
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithErrorHandler receives write failures (default: ignored).
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *Observer) {
+		if fn != nil {
+			o.onError = fn
+		}
+	}
+}
+
+// New constructs an Observer appending to the history file at path.
+func New(path string, opts ...Option) *Observer {
+	return newObserver(func(rep *report.Report) error { return Append(path, rep) }, opts)
+}
+
+// NewStore constructs an Observer saving each report as its own object in
+// store, so runs on several machines sharing the store never overwrite each
+// other's records.
+func NewStore(store storage.Store, opts ...Option) *Observer {
+	return newObserver(func(rep *report.Report) error {
+		ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+		defer cancel()
+		return Save(ctx, store, rep)
+	}, opts)
+}
+
+func newObserver(save func(*report.Report) error, opts []Option) *Observer {
+	o := &Observer{save: save, onError: func(error) {}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	o.Recorder = report.NewRecorder(func(rep *report.Report) {
+		if err := o.save(rep); err != nil {
+			o.onError(err)
+		}
+	})
+	return o
+}
+
+// Save stores rep under a key made of its finish time and host, which lists
+// in run order.
+func Save(ctx context.Context, store storage.Store, rep *report.Report) error {
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return fmt.Errorf("history: encode: %w", err)
+	}
+	if err := store.Put(ctx, runKey(rep), data); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	return nil
+}
+
+// LoadStore reads every report saved in store, oldest first.
+func LoadStore(ctx context.Context, store storage.Store) ([]report.Report, error) {
+	keys, err := store.List(ctx, keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	reports := make([]report.Report, 0, len(keys))
+	for _, key := range keys {
+		data, err := store.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("history: %w", err)
+		}
+		var rep report.Report
+		if err := json.Unmarshal(data, &rep); err != nil {
+			return nil, fmt.Errorf("history: decode %s: %w", key, err)
+		}
+		reports = append(reports, rep)
+	}
+	return reports, nil
+}
+
+func runKey(rep *report.Report) string {
+	host := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, rep.Host)
+	if host == "" || strings.HasPrefix(host, ".") {
+		host = "_" + host
+	}
+	return keyPrefix + rep.FinishedAt.UTC().Format("20060102T150405.000000000Z") + "-" + host + ".json"
+}
+
+// Append adds rep as the last line of the history file at path.
+func Append(path string, rep *report.Report) error {
+	line, err := json.Marshal(rep)
+	if err != nil {
+		return fmt.Errorf("history: encode: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("history: read %s: %w", path, err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+	data = append(append(data, line...), '\n')
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("history: create %s: %w", dir, err)
+	}
+	if err := atomicfile.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	return nil
+}
+
+// Load reads every report in the history file at path, oldest first; a
+// missing file yields no reports.
+func Load(path string) ([]report.Report, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("history: read %s: %w", path, err)
+	}
+	var reports []report.Report
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rep report.Report
+		if err := json.Unmarshal(line, &rep); err != nil {
+			return nil, fmt.Errorf("history: decode %s line %d: %w", path, n, err)
+		}
+		reports = append(reports, rep)
+	}
+	return reports, scanner.Err()
+}
+
+// Host is one row of the fleet dataset: where a host stands after its runs.
+type Host struct {
+	Host string `json:"host"`
+	// Status is the outcome of the last run, report.StatusSucceeded or
+	// report.StatusFailed.
+	Status      string     `json:"status"`
+	LastRun     time.Time  `json:"last_run"`
+	LastSuccess *time.Time `json:"last_success"`
+	// FailingPhase is the ID of the phase the last run failed in, empty when
+	// it succeeded or stopped between phases.
+	FailingPhase string `json:"failing_phase"`
+	Error        string `json:"error"`
+	Distro       string `json:"distro"`
+	AnsibleUser  string `json:"ansible_user"`
+	Runs         int    `json:"runs"`
+}
+
+// Fleet summarizes reports, oldest first, into one Host per target, sorted by
+// host. Runs that failed before a host was known are skipped.
+func Fleet(reports []report.Report) []Host {
+	byHost := make(map[string]*Host)
+	for _, rep := range reports {
+		if rep.Host == "" {
+			continue
+		}
+		host, ok := byHost[rep.Host]
+		if !ok {
+			host = &Host{Host: rep.Host}
+			byHost[rep.Host] = host
+		}
+		host.Runs++
+		host.LastRun = rep.FinishedAt.UTC()
+		host.Status, host.FailingPhase, host.Error = report.StatusSucceeded, "", ""
+		if rep.Success {
+			at := host.LastRun
+			host.LastSuccess = &at
+		} else {
+			host.Status, host.Error = report.StatusFailed, rep.Error
+			host.FailingPhase = failingPhase(rep.Phases)
+		}
+		// Failed runs may stop before the distro or user is known; keep
+		// what earlier runs learned.
+		if rep.Distro != "" {
+			host.Distro = rep.Distro
+		}
+		if user := rep.HostVars["ansible_user"]; user != "" {
+			host.AnsibleUser = user
+		}
+	}
+
+	hosts := make([]Host, 0, len(byHost))
+	for _, host := range byHost {
+		hosts = append(hosts, *host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+func failingPhase(results []report.PhaseResult) string {
+	for _, result := range results {
+		if result.Status == report.StatusFailed {
+			return result.ID
+		}
+	}
+	return ""
+}
+
+// WriteJSON writes hosts as a JSON array of flat objects, the shape Grafana's
+// JSON and Infinity data sources read as a table.
+func WriteJSON(w io.Writer, hosts []Host) error {
+	if hosts == nil {
+		hosts = []Host{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(hosts)
+}
+
+// csvHeader names the columns WriteCSV writes, matching the JSON field names.
+var csvHeader = []string{"host", "status", "last_run", "last_success", "failing_phase", "error", "distro", "ansible_user", "runs"}
+
+// WriteCSV writes hosts as CSV with a header row. Times are RFC 3339; a host
+// that never succeeded has an empty last_success.
+func WriteCSV(w io.Writer, hosts []Host) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		lastSuccess := ""
+		if host.LastSuccess != nil {
+			lastSuccess = host.LastSuccess.Format(time.RFC3339)
+		}
+		record := []string{
+			host.Host, host.Status, host.LastRun.Format(time.RFC3339), lastSuccess,
+			host.FailingPhase, host.Error, host.Distro, host.AnsibleUser, fmt.Sprint(host.Runs),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,158 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/osdetect"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+func TestObserverAppendsEveryRun(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "history.jsonl")
+	obs := New(path)
+	run := func(host string, failure error) {
+		manager := phases.NewManager(phases.WithObserver(obs))
+		require.NoError(t, manager.Register(phaseFunc{run: func(phaseCtx *phases.Context) error {
+			phaseCtx.Set(sshconnect.ContextKeyTargetHost, host)
+			phaseCtx.Set(sshconnect.ContextKeyTargetUser, "ansible")
+			phaseCtx.Set(osdetect.ContextKeyDistro, "debian")
+			phaseCtx.Set(osdetect.ContextKeyVersion, "12")
+			return failure
+		}}))
+		_ = manager.Run(context.Background(), nil)
+	}
+	run("10.0.0.5", nil)
+	run("10.0.0.5", errors.New("sudo rejected"))
+
+	reports, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.True(t, reports[0].Success)
+	require.Equal(t, "debian 12", reports[0].Distro)
+	require.False(t, reports[1].Success)
+	require.Equal(t, "ssh", failingPhase(reports[1].Phases))
+
+	hosts := Fleet(reports)
+	require.Len(t, hosts, 1)
+	require.Equal(t, report.StatusFailed, hosts[0].Status)
+	require.Equal(t, "ssh", hosts[0].FailingPhase)
+	require.NotNil(t, hosts[0].LastSuccess)
+	require.Equal(t, 2, hosts[0].Runs)
+}
+
+func TestStoreObserver(t *testing.T) {
+	t.Parallel()
+
+	store := storage.NewFileStore(t.TempDir())
+	obs := NewStore(store)
+	for _, host := range []string{"10.0.0.5", "fe80::1", ""} {
+		manager := phases.NewManager(phases.WithObserver(obs))
+		require.NoError(t, manager.Register(phaseFunc{run: func(phaseCtx *phases.Context) error {
+			phaseCtx.Set(sshconnect.ContextKeyTargetHost, host)
+			return nil
+		}}))
+		require.NoError(t, manager.Run(context.Background(), nil))
+	}
+
+	keys, err := store.List(context.Background(), "history/")
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	require.True(t, strings.HasSuffix(keys[1], "-fe80__1.json"), keys[1])
+	require.True(t, strings.HasSuffix(keys[2], "-_.json"), keys[2])
+
+	reports, err := LoadStore(context.Background(), store)
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	require.Equal(t, "10.0.0.5", reports[0].Host)
+	require.Len(t, Fleet(reports), 2)
+
+	require.NoError(t, store.Put(context.Background(), "history/broken.json", []byte("{")))
+	_, err = LoadStore(context.Background(), store)
+	require.ErrorContains(t, err, "history/broken.json")
+}
+
+func TestFleet(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC) }
+	reports := []report.Report{
+		{Host: "web02", FinishedAt: day(1), Success: true, Distro: "ubuntu 24.04", HostVars: map[string]string{"ansible_user": "ansible"}},
+		{Host: "web01", FinishedAt: day(2), Error: "phase sudo_ensure failed", Phases: []report.PhaseResult{
+			{ID: "ssh_connection", Status: report.StatusSucceeded},
+			{ID: "sudo_ensure", Status: report.StatusFailed, Error: "password rejected"},
+		}, HostVars: map[string]string{"ansible_user": "admin"}},
+		{FinishedAt: day(3), Error: "host is required"},
+		{Host: "web02", FinishedAt: day(4), Error: "dial timeout", HostVars: map[string]string{"ansible_host": "web02"}},
+		{Host: "web03", FinishedAt: day(5), Error: "phase python failed", Phases: []report.PhaseResult{{ID: "python_ensure", Status: report.StatusFailed}}},
+		{Host: "web03", FinishedAt: day(6), Success: true, Distro: "alpine 3.20"},
+	}
+	first, sixth := day(1), day(6)
+
+	hosts := Fleet(reports)
+	require.Equal(t, []Host{
+		{Host: "web01", Status: report.StatusFailed, LastRun: day(2), FailingPhase: "sudo_ensure", Error: "phase sudo_ensure failed", AnsibleUser: "admin", Runs: 1},
+		{Host: "web02", Status: report.StatusFailed, LastRun: day(4), LastSuccess: &first, Error: "dial timeout", Distro: "ubuntu 24.04", AnsibleUser: "ansible", Runs: 2},
+		{Host: "web03", Status: report.StatusSucceeded, LastRun: day(6), LastSuccess: &sixth, Distro: "alpine 3.20", Runs: 2},
+	}, hosts)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteCSV(&out, hosts))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	require.Equal(t, "host,status,last_run,last_success,failing_phase,error,distro,ansible_user,runs", lines[0])
+	require.Equal(t, "web01,failed,2026-03-02T09:00:00Z,,sudo_ensure,phase sudo_ensure failed,,admin,1", lines[1])
+
+	out.Reset()
+	require.NoError(t, WriteJSON(&out, hosts[2:]))
+	require.JSONEq(t, `[{"host": "web03", "status": "succeeded", "last_run": "2026-03-06T09:00:00Z", "last_success": "2026-03-06T09:00:00Z",
+		"failing_phase": "", "error": "", "distro": "alpine 3.20", "ansible_user": "", "runs": 2}]`, out.String())
+
+	out.Reset()
+	require.NoError(t, WriteJSON(&out, nil))
+	require.JSONEq(t, `[]`, out.String())
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	reports, err := Load(filepath.Join(dir, "missing.jsonl"))
+	require.NoError(t, err)
+	require.Empty(t, reports)
+
+	path := filepath.Join(dir, "history.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"host": "web01", "success": true}`), 0o600))
+	require.NoError(t, Append(path, &report.Report{Host: "web02"}))
+	reports, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, reports, 2, "a last line without a newline is kept whole")
+	require.Equal(t, "web02", reports[1].Host)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"host\": \"web01\"}\n\nnot json\n"), 0o600))
+	_, err = Load(path)
+	require.ErrorContains(t, err, "line 3")
+}
+
+type phaseFunc struct {
+	run func(*phases.Context) error
+}
+
+func (p phaseFunc) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{ID: "ssh", Title: "SSH"}
+}
+
+func (p phaseFunc) Run(_ context.Context, phaseCtx *phases.Context) error { return p.run(phaseCtx) }
@@ -0,0 +1,237 @@
+// Package metrics provides a phases.Observer that records phase runs,
+// failures, durations, and input prompts and exposes them in the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+// DefaultBuckets are the phase duration histogram upper bounds, in seconds.
+var DefaultBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+const defaultNamespace = "ansible_host_prep"
+
+// Observer collects phase metrics. It is safe for concurrent use.
+type Observer struct {
+	namespace string
+	buckets   []float64
+	now       func() time.Time
+
+	mu        sync.Mutex
+	started   map[string]time.Time
+	runs      map[string]uint64
+	failures  map[string]uint64
+	prompts   map[promptKey]uint64
+	durations map[string]*histogram
+}
+
+type promptKey struct {
+	phase string
+	input string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithNamespace overrides the metric name prefix (default "ansible_host_prep").
+func WithNamespace(namespace string) Option {
+	return func(o *Observer) {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			o.namespace = namespace
+		}
+	}
+}
+
+// WithBuckets overrides the duration histogram bucket upper bounds (seconds).
+func WithBuckets(buckets ...float64) Option {
+	return func(o *Observer) {
+		if len(buckets) == 0 {
+			return
+		}
+		sorted := append([]float64(nil), buckets...)
+		sort.Float64s(sorted)
+		o.buckets = sorted
+	}
+}
+
+// WithClock injects a time source for testing.
+func WithClock(now func() time.Time) Option {
+	return func(o *Observer) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// New constructs an Observer.
+func New(opts ...Option) *Observer {
+	o := &Observer{
+		namespace: defaultNamespace,
+		buckets:   DefaultBuckets,
+		now:       time.Now,
+		started:   make(map[string]time.Time),
+		runs:      make(map[string]uint64),
+		failures:  make(map[string]uint64),
+		prompts:   make(map[promptKey]uint64),
+		durations: make(map[string]*histogram),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// PhaseStarted implements phases.Observer.
+func (o *Observer) PhaseStarted(meta phases.PhaseMetadata) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started[meta.ID] = o.now()
+}
+
+// PhaseCompleted implements phases.Observer.
+func (o *Observer) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.runs[meta.ID]++
+	if err != nil {
+		o.failures[meta.ID]++
+	}
+	start, ok := o.started[meta.ID]
+	if !ok {
+		return
+	}
+	delete(o.started, meta.ID)
+	o.observe(meta.ID, o.now().Sub(start).Seconds())
+}
+
+// InputRequested implements phases.InputObserver.
+func (o *Observer) InputRequested(meta phases.PhaseMetadata, input phases.InputDefinition, _ string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prompts[promptKey{phase: meta.ID, input: input.ID}]++
+}
+
+func (o *Observer) observe(phaseID string, seconds float64) {
+	h, ok := o.durations[phaseID]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(o.buckets))}
+		o.durations[phaseID] = h
+	}
+	for i, upper := range o.buckets {
+		if seconds <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// WriteTo renders all metrics in the Prometheus text exposition format.
+func (o *Observer) WriteTo(w io.Writer) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var b strings.Builder
+	o.writeCounter(&b, "phase_runs_total", "Phase executions, by phase.", o.runs)
+	o.writeCounter(&b, "phase_failures_total", "Phase executions that returned an error, by phase.", o.failures)
+
+	name := o.namespace + "_input_prompts_total"
+	fmt.Fprintf(&b, "# HELP %s Input requests sent to the input handler, by phase and input.\n# TYPE %s counter\n", name, name)
+	keys := make([]promptKey, 0, len(o.prompts))
+	for key := range o.prompts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].phase != keys[j].phase {
+			return keys[i].phase < keys[j].phase
+		}
+		return keys[i].input < keys[j].input
+	})
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s{phase=%q,input=%q} %d\n", name, key.phase, key.input, o.prompts[key])
+	}
+
+	name = o.namespace + "_phase_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Phase execution time in seconds, by phase.\n# TYPE %s histogram\n", name, name)
+	for _, phaseID := range sortedIDs(o.durations) {
+		h := o.durations[phaseID]
+		for i, upper := range o.buckets {
+			fmt.Fprintf(&b, "%s_bucket{phase=%q,le=%q} %d\n", name, phaseID, formatFloat(upper), h.counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{phase=%q,le=\"+Inf\"} %d\n", name, phaseID, h.count)
+		fmt.Fprintf(&b, "%s_sum{phase=%q} %s\n", name, phaseID, formatFloat(h.sum))
+		fmt.Fprintf(&b, "%s_count{phase=%q} %d\n", name, phaseID, h.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (o *Observer) writeCounter(b *strings.Builder, suffix, help string, values map[string]uint64) {
+	name := o.namespace + "_" + suffix
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, phaseID := range sortedIDs(values) {
+		fmt.Fprintf(b, "%s{phase=%q} %d\n", name, phaseID, values[phaseID])
+	}
+}
+
+// Handler serves the metrics for scraping.
+func (o *Observer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = o.WriteTo(w)
+	})
+}
+
+// Serve exposes /metrics on addr until ctx is cancelled.
+func (o *Observer) Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", o.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server: %w", err)
+	}
+	return nil
+}
+
+func sortedIDs[V any](m map[string]V) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
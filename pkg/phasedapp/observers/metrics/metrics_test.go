@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+func TestObserverRecordsPhaseMetrics(t *testing.T) {
+	t.Parallel()
+
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	obs := New(WithBuckets(1, 5), WithClock(func() time.Time { return clock }))
+
+	var attempts int
+	phase := phaseFunc{
+		meta: phases.PhaseMetadata{ID: "sudo_ensure"},
+		run: func(_ context.Context, phaseCtx *phases.Context) error {
+			attempts++
+			if _, ok := phases.GetInput(phaseCtx, "sudo_ensure", "password"); !ok {
+				return phases.InputRequestError{PhaseID: "sudo_ensure", Input: phases.InputDefinition{ID: "password"}}
+			}
+			clock = clock.Add(3 * time.Second)
+			if attempts < 3 {
+				return errors.New("sudo rejected")
+			}
+			return nil
+		},
+	}
+
+	handler := phases.InputHandlerFunc(func(phases.PhaseMetadata, phases.InputDefinition, string) (any, error) {
+		return "pw", nil
+	})
+	manager := phases.NewManager(phases.WithObserver(obs), phases.WithInputHandler(handler))
+	require.NoError(t, manager.Register(phase))
+
+	require.Error(t, manager.Run(context.Background(), nil))
+	require.NoError(t, manager.Run(context.Background(), nil))
+
+	rec := httptest.NewRecorder()
+	obs.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`ansible_host_prep_phase_runs_total{phase="sudo_ensure"} 2`,
+		`ansible_host_prep_phase_failures_total{phase="sudo_ensure"} 1`,
+		`ansible_host_prep_input_prompts_total{phase="sudo_ensure",input="password"} 2`,
+		`ansible_host_prep_phase_duration_seconds_bucket{phase="sudo_ensure",le="1"} 0`,
+		`ansible_host_prep_phase_duration_seconds_bucket{phase="sudo_ensure",le="5"} 2`,
+		`ansible_host_prep_phase_duration_seconds_bucket{phase="sudo_ensure",le="+Inf"} 2`,
+		`ansible_host_prep_phase_duration_seconds_sum{phase="sudo_ensure"} 6`,
+	} {
+		require.True(t, strings.Contains(body, want), "missing %q in:\n%s", want, body)
+	}
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+}
+
+type phaseFunc struct {
+	meta phases.PhaseMetadata
+	run  func(context.Context, *phases.Context) error
+}
+
+func (p phaseFunc) Metadata() phases.PhaseMetadata { return p.meta }
+
+func (p phaseFunc) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	return p.run(ctx, phaseCtx)
+}
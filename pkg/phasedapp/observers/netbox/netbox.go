@@ -0,0 +1,321 @@
+// Package netbox provides a phases.Observer that, after a successful run,
+// marks the prepared host as ansible-managed in NetBox and records the
+// ansible user and key fingerprint in custom fields.
+package netbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
+)
+
+const (
+	defaultTimeout          = 10 * time.Second
+	defaultTag              = "ansible-managed"
+	defaultUserField        = "ansible_user"
+	defaultFingerprintField = "ansible_key_fingerprint"
+)
+
+// ErrHostNotFound reports that no device or virtual machine matches the host.
+var ErrHostNotFound = errors.New("netbox: host not found")
+
+// objectEndpoints are searched in order for the prepared host.
+var objectEndpoints = []string{"dcim/devices", "virtualization/virtual-machines"}
+
+// Observer syncs the prepared host to NetBox when the pipeline succeeds.
+// Failures never fail the pipeline; they are passed to the error handler.
+type Observer struct {
+	baseURL          string
+	token            string
+	client           *http.Client
+	tag              string
+	userField        string
+	fingerprintField string
+	onError          func(error)
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithHTTPClient overrides the HTTP client (default: 10s timeout).
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Observer) {
+		if client != nil {
+			o.client = client
+		}
+	}
+}
+
+// WithTag overrides the tag applied to the host (default: ansible-managed).
+func WithTag(tag string) Option {
+	return func(o *Observer) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			o.tag = tag
+		}
+	}
+}
+
+// WithCustomFields overrides the custom field names for the ansible user and
+// key fingerprint; empty names keep the defaults.
+func WithCustomFields(userField, fingerprintField string) Option {
+	return func(o *Observer) {
+		if userField != "" {
+			o.userField = userField
+		}
+		if fingerprintField != "" {
+			o.fingerprintField = fingerprintField
+		}
+	}
+}
+
+// WithErrorHandler receives sync failures (default: ignored).
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *Observer) {
+		if fn != nil {
+			o.onError = fn
+		}
+	}
+}
+
+// New constructs an Observer for the NetBox instance at baseURL.
+func New(baseURL, token string, opts ...Option) *Observer {
+	o := &Observer{
+		baseURL:          strings.TrimRight(baseURL, "/"),
+		token:            token,
+		client:           &http.Client{Timeout: defaultTimeout},
+		tag:              defaultTag,
+		userField:        defaultUserField,
+		fingerprintField: defaultFingerprintField,
+		onError:          func(error) {},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// PhaseStarted implements phases.Observer.
+func (o *Observer) PhaseStarted(phases.PhaseMetadata) {}
+
+// PhaseCompleted implements phases.Observer.
+func (o *Observer) PhaseCompleted(phases.PhaseMetadata, error) {}
+
+// PipelineCompleted implements phases.PipelineObserver.
+func (o *Observer) PipelineCompleted(phaseCtx *phases.Context, err error) {
+	if err != nil || phaseCtx == nil {
+		return
+	}
+	if err := o.Sync(context.Background(), phaseCtx); err != nil {
+		o.onError(err)
+	}
+}
+
+// Sync finds the host in NetBox by name (or by IP address) and tags it,
+// recording the ansible user and key fingerprint.
+func (o *Observer) Sync(ctx context.Context, phaseCtx *phases.Context) error {
+	host, _ := contextString(phaseCtx, sshconnect.ContextKeyTargetHost)
+	if host == "" {
+		return errors.New("netbox: no target host in context")
+	}
+
+	obj, err := o.findObject(ctx, host)
+	if err != nil {
+		return err
+	}
+	tagID, err := o.ensureTag(ctx)
+	if err != nil {
+		return err
+	}
+
+	tags := []map[string]int{{"id": tagID}}
+	for _, tag := range obj.Tags {
+		if tag.ID != tagID {
+			tags = append(tags, map[string]int{"id": tag.ID})
+		}
+	}
+	fields := make(map[string]string)
+	if res, ok := phases.GetContext(phaseCtx, ansibleuser.KeyUserResult); ok && res != nil && res.Username != "" {
+		fields[o.userField] = res.Username
+	}
+	if fp := keyFingerprint(phaseCtx); fp != "" {
+		fields[o.fingerprintField] = fp
+	}
+
+	patch := map[string]any{"tags": tags}
+	if len(fields) > 0 {
+		patch["custom_fields"] = fields
+	}
+	return o.do(ctx, http.MethodPatch, fmt.Sprintf("/api/%s/%d/", obj.endpoint, obj.ID), patch, nil)
+}
+
+type tagRef struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+}
+
+type object struct {
+	ID       int      `json:"id"`
+	Tags     []tagRef `json:"tags"`
+	endpoint string
+}
+
+type list[T any] struct {
+	Results []T `json:"results"`
+}
+
+type ipAddress struct {
+	AssignedObject *struct {
+		Device *struct {
+			ID int `json:"id"`
+		} `json:"device"`
+		VirtualMachine *struct {
+			ID int `json:"id"`
+		} `json:"virtual_machine"`
+	} `json:"assigned_object"`
+}
+
+func (o *Observer) findObject(ctx context.Context, host string) (*object, error) {
+	for _, endpoint := range objectEndpoints {
+		var found list[object]
+		if err := o.do(ctx, http.MethodGet, "/api/"+endpoint+"/?name="+url.QueryEscape(host), nil, &found); err != nil {
+			return nil, err
+		}
+		if len(found.Results) > 0 {
+			obj := found.Results[0]
+			obj.endpoint = endpoint
+			return &obj, nil
+		}
+	}
+
+	if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrHostNotFound, host)
+	}
+	var addrs list[ipAddress]
+	if err := o.do(ctx, http.MethodGet, "/api/ipam/ip-addresses/?address="+url.QueryEscape(host), nil, &addrs); err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs.Results {
+		if addr.AssignedObject == nil {
+			continue
+		}
+		var endpoint string
+		var id int
+		switch {
+		case addr.AssignedObject.Device != nil:
+			endpoint, id = objectEndpoints[0], addr.AssignedObject.Device.ID
+		case addr.AssignedObject.VirtualMachine != nil:
+			endpoint, id = objectEndpoints[1], addr.AssignedObject.VirtualMachine.ID
+		default:
+			continue
+		}
+		var obj object
+		if err := o.do(ctx, http.MethodGet, fmt.Sprintf("/api/%s/%d/", endpoint, id), nil, &obj); err != nil {
+			return nil, err
+		}
+		obj.endpoint = endpoint
+		return &obj, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrHostNotFound, host)
+}
+
+func (o *Observer) ensureTag(ctx context.Context) (int, error) {
+	slug := slugify(o.tag)
+	var found list[tagRef]
+	if err := o.do(ctx, http.MethodGet, "/api/extras/tags/?slug="+url.QueryEscape(slug), nil, &found); err != nil {
+		return 0, err
+	}
+	if len(found.Results) > 0 {
+		return found.Results[0].ID, nil
+	}
+	var created tagRef
+	if err := o.do(ctx, http.MethodPost, "/api/extras/tags/", map[string]string{"name": o.tag, "slug": slug}, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+func (o *Observer) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("netbox: encode %s %s: %w", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, o.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("netbox: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+o.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("netbox: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("netbox: %s %s: unexpected status %s: %s", method, path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("netbox: decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func keyFingerprint(phaseCtx *phases.Context) string {
+	info, ok := phases.GetContext(phaseCtx, ansibleuser.KeyKeyPair)
+	if !ok || info == nil || info.PublicPath == "" {
+		return ""
+	}
+	fingerprints, err := sshkeypair.Fingerprint(info.PublicPath)
+	if err != nil {
+		return ""
+	}
+	return fingerprints.SHA256
+}
+
+func slugify(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, strings.TrimSpace(name)), "-")
+}
+
+func contextString(phaseCtx *phases.Context, key string) (string, bool) {
+	val, ok := phaseCtx.Get(key)
+	if !ok {
+		return "", false
+	}
+	str, ok := val.(string)
+	return strings.TrimSpace(str), ok
+}
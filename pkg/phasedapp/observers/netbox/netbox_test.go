@@ -0,0 +1,105 @@
+package netbox
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/ansibleuser"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+func TestSyncTagsHostFoundByIP(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var patch map[string]any
+	var createdTag map[string]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/dcim/devices/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"results": []}`))
+	})
+	mux.HandleFunc("GET /api/virtualization/virtual-machines/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"results": []}`))
+	})
+	mux.HandleFunc("GET /api/ipam/ip-addresses/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "10.0.0.5", r.URL.Query().Get("address"))
+		_, _ = w.Write([]byte(`{"results": [{"assigned_object": {"virtual_machine": {"id": 7}}}]}`))
+	})
+	mux.HandleFunc("GET /api/virtualization/virtual-machines/7/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"id": 7, "tags": [{"id": 3, "slug": "prod"}]}`))
+	})
+	mux.HandleFunc("GET /api/extras/tags/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"results": []}`))
+	})
+	mux.HandleFunc("POST /api/extras/tags/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&createdTag))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 11, "slug": "ansible-managed"}`))
+	})
+	mux.HandleFunc("PATCH /api/virtualization/virtual-machines/7/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&patch))
+		_, _ = w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	pubPath := filepath.Join(t.TempDir(), "ansible.pub")
+	require.NoError(t, os.WriteFile(pubPath, ssh.MarshalAuthorizedKey(sshPub), 0o644))
+
+	phaseCtx := phases.NewContext()
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	phaseCtx.Set(ansibleuser.ContextKeyUserResult, &systemuser.Result{Username: "ansible"})
+	phaseCtx.Set(ansibleuser.ContextKeyKeyInfo, &sshkeypair.KeyPairInfo{PublicPath: pubPath})
+
+	require.NoError(t, New(server.URL+"/", "secret").Sync(context.Background(), phaseCtx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, map[string]string{"name": "ansible-managed", "slug": "ansible-managed"}, createdTag)
+	require.Equal(t, []any{map[string]any{"id": float64(11)}, map[string]any{"id": float64(3)}}, patch["tags"])
+	require.Equal(t, map[string]any{
+		"ansible_user":            "ansible",
+		"ansible_key_fingerprint": ssh.FingerprintSHA256(sshPub),
+	}, patch["custom_fields"])
+}
+
+func TestSyncReportsUnknownHost(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"results": []}`))
+	}))
+	t.Cleanup(server.Close)
+
+	phaseCtx := phases.NewContext()
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, "web01")
+
+	var errs []error
+	obs := New(server.URL, "secret", WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	obs.PipelineCompleted(phaseCtx, nil)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], ErrHostNotFound)
+}
@@ -0,0 +1,145 @@
+// Package terraform provides a phases.Observer that records prepared hosts in
+// a Terraform/OpenTofu-friendly JSON file, so infrastructure pipelines can
+// read prep results with jsondecode(file(...)).
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/hostvars"
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+)
+
+// Document is the exported file. Hosts are keyed by ansible_host; every value
+// is a string so the map converts cleanly to Terraform's map(string).
+type Document struct {
+	Hosts map[string]map[string]string `json:"hosts"`
+}
+
+// Observer upserts the host of every successful pipeline run into the
+// document at path. Failed runs leave the file untouched.
+type Observer struct {
+	path    string
+	onError func(error)
+	now     func() time.Time
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithErrorHandler receives write failures (default: ignored).
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *Observer) {
+		if fn != nil {
+			o.onError = fn
+		}
+	}
+}
+
+// WithClock overrides the clock used for prepared_at.
+func WithClock(now func() time.Time) Option {
+	return func(o *Observer) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// New constructs an Observer writing to path.
+func New(path string, opts ...Option) *Observer {
+	o := &Observer{path: path, onError: func(error) {}, now: time.Now}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// PhaseStarted implements phases.Observer.
+func (o *Observer) PhaseStarted(phases.PhaseMetadata) {}
+
+// PhaseCompleted implements phases.Observer.
+func (o *Observer) PhaseCompleted(phases.PhaseMetadata, error) {}
+
+// PipelineCompleted implements phases.PipelineObserver.
+func (o *Observer) PipelineCompleted(phaseCtx *phases.Context, err error) {
+	if err != nil || phaseCtx == nil {
+		return
+	}
+	if err := o.Record(phaseCtx); err != nil {
+		o.onError(err)
+	}
+}
+
+// Record upserts the host described by phaseCtx into the document.
+func (o *Observer) Record(phaseCtx *phases.Context) error {
+	host := HostAttributes(phaseCtx)
+	name := host["ansible_host"]
+	if name == "" {
+		return errors.New("terraform export: no target host in context")
+	}
+	host["prepared_at"] = o.now().UTC().Format(time.RFC3339)
+
+	doc, err := Load(o.path)
+	if err != nil {
+		return err
+	}
+	doc.Hosts[name] = host
+	return write(o.path, doc)
+}
+
+// HostAttributes flattens the host variables learned during prep into strings.
+func HostAttributes(phaseCtx *phases.Context) map[string]string {
+	attrs := make(map[string]string)
+	for _, v := range hostvars.Collect(phaseCtx) {
+		attrs[v.Name] = fmt.Sprint(v.Value)
+	}
+	if val, ok := phaseCtx.Get(hostvars.ContextKeyPath); ok {
+		if path, ok := val.(string); ok && path != "" {
+			attrs["host_vars_file"] = path
+		}
+	}
+	return attrs
+}
+
+// Load reads the document at path; a missing file yields an empty document.
+func Load(path string) (*Document, error) {
+	doc := &Document{Hosts: make(map[string]map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return doc, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("terraform export: read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("terraform export: decode %s: %w", path, err)
+	}
+	if doc.Hosts == nil {
+		doc.Hosts = make(map[string]map[string]string)
+	}
+	return doc, nil
+}
+
+// write replaces path atomically so readers never see a partial document.
+func write(path string, doc *Document) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("terraform export: encode: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("terraform export: create %s: %w", dir, err)
+	}
+	if err := atomicfile.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("terraform export: %w", err)
+	}
+	return nil
+}
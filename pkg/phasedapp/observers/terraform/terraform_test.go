@@ -0,0 +1,65 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+)
+
+func TestObserverUpsertsPreparedHosts(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "out", "prepared-hosts.json")
+	clock := time.Date(2026, 3, 10, 14, 30, 0, 0, time.UTC)
+	obs := New(path, WithClock(func() time.Time { return clock }))
+
+	run := func(host string, failure error) {
+		manager := phases.NewManager(phases.WithObserver(obs))
+		require.NoError(t, manager.Register(phaseFunc{run: func(phaseCtx *phases.Context) error {
+			phaseCtx.Set(sshconnect.ContextKeyTargetHost, host)
+			phaseCtx.Set(sshconnect.ContextKeyTargetUser, "admin")
+			phaseCtx.Set(sshconnect.ContextKeyTargetPort, 22)
+			return failure
+		}}))
+		_ = manager.Run(context.Background(), nil)
+	}
+
+	run("10.0.0.5", nil)
+	run("10.0.0.6", nil)
+	run("10.0.0.7", errors.New("sudo rejected"))
+
+	doc, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, doc.Hosts, 2)
+	require.Equal(t, map[string]string{
+		"ansible_host": "10.0.0.6",
+		"ansible_port": "22",
+		"ansible_user": "admin",
+		"prepared_at":  "2026-03-10T14:30:00Z",
+	}, doc.Hosts["10.0.0.6"])
+}
+
+func TestLoadMissingFileIsEmpty(t *testing.T) {
+	t.Parallel()
+
+	doc, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	require.Empty(t, doc.Hosts)
+}
+
+type phaseFunc struct {
+	run func(*phases.Context) error
+}
+
+func (p phaseFunc) Metadata() phases.PhaseMetadata {
+	return phases.PhaseMetadata{ID: "ssh", Title: "SSH"}
+}
+
+func (p phaseFunc) Run(_ context.Context, phaseCtx *phases.Context) error { return p.run(phaseCtx) }
@@ -0,0 +1,154 @@
+// Package tracing provides a phases.Observer that records each phase, and the
+// remote commands it runs, as OpenTelemetry spans.
+package tracing
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+const instrumentationName = "github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/tracing"
+
+// Observer opens a span per phase and a child span per remote command. It is
+// safe for concurrent use.
+type Observer struct {
+	tracer trace.Tracer
+	parent context.Context
+
+	mu    sync.Mutex
+	spans map[string]phaseSpan
+}
+
+type phaseSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithTracerProvider sets the provider spans are created from (default: the
+// global provider from otel.GetTracerProvider).
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *Observer) {
+		if provider != nil {
+			o.tracer = provider.Tracer(instrumentationName)
+		}
+	}
+}
+
+// WithParent nests phase spans under the span carried by ctx, e.g. a span
+// covering the whole provisioning job.
+func WithParent(ctx context.Context) Option {
+	return func(o *Observer) {
+		if ctx != nil {
+			o.parent = ctx
+		}
+	}
+}
+
+// New constructs an Observer.
+func New(opts ...Option) *Observer {
+	o := &Observer{
+		tracer: otel.GetTracerProvider().Tracer(instrumentationName),
+		parent: context.Background(),
+		spans:  make(map[string]phaseSpan),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// PhaseStarted implements phases.Observer.
+func (o *Observer) PhaseStarted(meta phases.PhaseMetadata) {
+	ctx, span := o.tracer.Start(o.parent, "phase "+meta.ID,
+		trace.WithAttributes(
+			attribute.String("phase.id", meta.ID),
+			attribute.String("phase.title", meta.Title),
+		),
+	)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.spans[meta.ID] = phaseSpan{ctx: ctx, span: span}
+}
+
+// PhaseCompleted implements phases.Observer.
+func (o *Observer) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	o.mu.Lock()
+	ps, ok := o.spans[meta.ID]
+	delete(o.spans, meta.ID)
+	o.mu.Unlock()
+	if !ok {
+		return
+	}
+	endSpan(ps.span, err)
+}
+
+// InputRequested implements phases.InputObserver by recording an event on the
+// phase span; time spent waiting on the operator shows up between events.
+func (o *Observer) InputRequested(meta phases.PhaseMetadata, input phases.InputDefinition, reason string) {
+	o.mu.Lock()
+	ps, ok := o.spans[meta.ID]
+	o.mu.Unlock()
+	if !ok {
+		return
+	}
+	ps.span.AddEvent("input requested", trace.WithAttributes(
+		attribute.String("input.id", input.ID),
+		attribute.String("input.reason", reason),
+	))
+}
+
+// CommandStarted implements phases.CommandObserver. Only the command's
+// program name is recorded so arguments never leak into traces.
+func (o *Observer) CommandStarted(meta phases.PhaseMetadata, cmd string) func(error) {
+	o.mu.Lock()
+	ps, ok := o.spans[meta.ID]
+	o.mu.Unlock()
+	parent := o.parent
+	if ok {
+		parent = ps.ctx
+	}
+
+	name := commandName(cmd)
+	_, span := o.tracer.Start(parent, "exec "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("phase.id", meta.ID),
+			attribute.String("command.name", name),
+		),
+	)
+	return func(err error) {
+		endSpan(span, err)
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
+
+func commandName(cmd string) string {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return "shell"
+	}
+	return fields[0]
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+func TestObserverRecordsPhaseAndCommandSpans(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	obs := New(WithTracerProvider(provider))
+
+	failure := errors.New("install failed")
+	manager := phases.NewManager(phases.WithObserver(obs))
+	require.NoError(t, manager.Register(
+		phaseFunc{
+			meta: phases.PhaseMetadata{ID: "python_ensure"},
+			run: func(ctx context.Context, _ *phases.Context) error {
+				finish := phases.TraceCommand(ctx, "apt-get install -y python3")
+				finish(failure)
+				return failure
+			},
+		},
+	))
+	require.Error(t, manager.Run(context.Background(), nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	cmdSpan, phaseSpan := spans[0], spans[1]
+	require.Equal(t, "exec apt-get", cmdSpan.Name())
+	require.Equal(t, "phase python_ensure", phaseSpan.Name())
+	require.Equal(t, phaseSpan.SpanContext().SpanID(), cmdSpan.Parent().SpanID())
+	require.Equal(t, codes.Error, phaseSpan.Status().Code)
+	for _, attr := range cmdSpan.Attributes() {
+		require.NotContains(t, attr.Value.Emit(), "python3", "command arguments must not be recorded")
+	}
+}
+
+type phaseFunc struct {
+	meta phases.PhaseMetadata
+	run  func(context.Context, *phases.Context) error
+}
+
+func (p phaseFunc) Metadata() phases.PhaseMetadata { return p.meta }
+
+func (p phaseFunc) Run(ctx context.Context, phaseCtx *phases.Context) error {
+	return p.run(ctx, phaseCtx)
+}
@@ -0,0 +1,208 @@
+// Package webhook provides a phases.Observer that POSTs JSON events for phase
+// and pipeline lifecycle changes to an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Request headers set on every delivery. SignatureHeader is only present when
+// a secret is configured and carries "sha256=" followed by the hex HMAC of the
+// request body.
+const (
+	EventHeader     = "X-Host-Prep-Event"
+	SignatureHeader = "X-Host-Prep-Signature-256"
+)
+
+// Event types posted by the observer.
+const (
+	EventPhaseStarted     = "phase_started"
+	EventPhaseCompleted   = "phase_completed"
+	EventPipelineFinished = "pipeline_finished"
+)
+
+// ContextKeyPrincipal holds who started the run, when known (see
+// phasedapp.WithAuthTokens). It is reported on pipeline_finished events.
+const ContextKeyPrincipal = "run:principal"
+
+// Event is the JSON body of every webhook request. Host and Principal are
+// only set on pipeline_finished.
+type Event struct {
+	Type      string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Phase     *Phase    `json:"phase,omitempty"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Host      string    `json:"host,omitempty"`
+	Principal string    `json:"principal,omitempty"`
+}
+
+// Phase identifies the phase an event refers to.
+type Phase struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// Observer delivers events synchronously so receivers see them in order.
+// Delivery failures never fail the pipeline; they are passed to the error
+// handler instead.
+type Observer struct {
+	url     string
+	client  *http.Client
+	headers http.Header
+	secret  []byte
+	onError func(error)
+	now     func() time.Time
+}
+
+// Option customizes an Observer.
+type Option func(*Observer)
+
+// WithHTTPClient overrides the HTTP client (default: 5s timeout).
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Observer) {
+		if client != nil {
+			o.client = client
+		}
+	}
+}
+
+// WithHeader adds a header to every request, e.g. an Authorization token.
+func WithHeader(key, value string) Option {
+	return func(o *Observer) {
+		o.headers.Add(key, value)
+	}
+}
+
+// WithSecret signs every request body with HMAC-SHA256 so receivers can
+// verify it came from this tool (see Verify).
+func WithSecret(secret string) Option {
+	return func(o *Observer) {
+		if secret != "" {
+			o.secret = []byte(secret)
+		}
+	}
+}
+
+// WithErrorHandler receives delivery failures (default: ignored).
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *Observer) {
+		if fn != nil {
+			o.onError = fn
+		}
+	}
+}
+
+// New constructs an Observer posting to url.
+func New(url string, opts ...Option) *Observer {
+	o := &Observer{
+		url:     url,
+		client:  &http.Client{Timeout: defaultTimeout},
+		headers: make(http.Header),
+		onError: func(error) {},
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// PhaseStarted implements phases.Observer.
+func (o *Observer) PhaseStarted(meta phases.PhaseMetadata) {
+	o.post(Event{Type: EventPhaseStarted, Phase: phaseOf(meta), Success: true})
+}
+
+// PhaseCompleted implements phases.Observer.
+func (o *Observer) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	o.post(withError(Event{Type: EventPhaseCompleted, Phase: phaseOf(meta)}, err))
+}
+
+// PipelineCompleted implements phases.PipelineObserver.
+func (o *Observer) PipelineCompleted(phaseCtx *phases.Context, err error) {
+	event := Event{Type: EventPipelineFinished}
+	if phaseCtx != nil {
+		if principal, ok := phaseCtx.Get(ContextKeyPrincipal); ok {
+			event.Principal, _ = principal.(string)
+		}
+		if host, ok := phaseCtx.Get(sshconnect.ContextKeyTargetHost); ok {
+			event.Host, _ = host.(string)
+		}
+	}
+	o.post(withError(event, err))
+}
+
+func (o *Observer) post(event Event) {
+	event.Timestamp = o.now().UTC()
+	body, err := json.Marshal(event)
+	if err != nil {
+		o.onError(fmt.Errorf("webhook: encode %s event: %w", event.Type, err))
+		return
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		o.onError(fmt.Errorf("webhook: build request: %w", err))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	if len(o.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(o.secret, body))
+	}
+	for key, values := range o.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		o.onError(fmt.Errorf("webhook: deliver %s event: %w", event.Type, err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		o.onError(fmt.Errorf("webhook: deliver %s event: unexpected status %s", event.Type, resp.Status))
+	}
+}
+
+// Sign returns the SignatureHeader value for body under secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid SignatureHeader value for body
+// under secret, comparing in constant time.
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func phaseOf(meta phases.PhaseMetadata) *Phase {
+	return &Phase{ID: meta.ID, Title: meta.Title}
+}
+
+func withError(event Event, err error) Event {
+	event.Success = err == nil
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
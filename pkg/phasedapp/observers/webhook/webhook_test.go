@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/sshconnect"
+)
+
+func TestObserverPostsLifecycleEvents(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var events []Event
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events = append(events, event)
+		auth = append(auth, r.Header.Get("Authorization"))
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	obs := New(server.URL, WithHeader("Authorization", "Bearer token"))
+	failure := errors.New("sudo rejected")
+	manager := phases.NewManager(phases.WithObserver(obs))
+	require.NoError(t, manager.Register(
+		phaseFunc{meta: phases.PhaseMetadata{ID: "ssh", Title: "SSH"}},
+		phaseFunc{meta: phases.PhaseMetadata{ID: "sudo", Title: "Sudo"}, err: failure},
+	))
+	phaseCtx := phases.NewContext()
+	phaseCtx.Set(ContextKeyPrincipal, "alice")
+	phaseCtx.Set(sshconnect.ContextKeyTargetHost, "10.0.0.5")
+	require.Error(t, manager.Run(context.Background(), phaseCtx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []string{
+		EventPhaseStarted, EventPhaseCompleted,
+		EventPhaseStarted, EventPhaseCompleted,
+		EventPipelineFinished,
+	}, types)
+	require.Equal(t, "sudo", events[3].Phase.ID)
+	require.False(t, events[3].Success)
+	require.Contains(t, events[4].Error, "sudo rejected")
+	require.Equal(t, "alice", events[4].Principal)
+	require.Empty(t, events[0].Principal)
+	require.Equal(t, "10.0.0.5", events[4].Host)
+	require.Empty(t, events[0].Host)
+	require.Equal(t, "Bearer token", auth[0])
+}
+
+func TestObserverReportsDeliveryFailures(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	var errs []error
+	obs := New(server.URL, WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	obs.PhaseStarted(phases.PhaseMetadata{ID: "ssh"})
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "502")
+}
+
+func TestObserverSignsBodies(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("s3cret")
+	var verified, eventHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		eventHeader = r.Header.Get(EventHeader)
+		if Verify(secret, body, r.Header.Get(SignatureHeader)) {
+			verified = "ok"
+		}
+		require.False(t, Verify([]byte("other"), body, r.Header.Get(SignatureHeader)))
+	}))
+	t.Cleanup(server.Close)
+
+	obs := New(server.URL, WithSecret(string(secret)))
+	obs.PhaseCompleted(phases.PhaseMetadata{ID: "ssh"}, nil)
+	require.Equal(t, "ok", verified)
+	require.Equal(t, EventPhaseCompleted, eventHeader)
+}
+
+type phaseFunc struct {
+	meta phases.PhaseMetadata
+	err  error
+}
+
+func (p phaseFunc) Metadata() phases.PhaseMetadata { return p.meta }
+
+func (p phaseFunc) Run(context.Context, *phases.Context) error { return p.err }
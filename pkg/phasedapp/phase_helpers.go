@@ -110,6 +110,25 @@ func WithTag(tag string) PhaseFilter {
 	}
 }
 
+// WithAnyTag matches phases containing at least one of tags
+// (case-insensitive).
+func WithAnyTag(tags ...string) PhaseFilter {
+	filters := make([]PhaseFilter, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filters = append(filters, WithTag(tag))
+		}
+	}
+	return func(meta phases.PhaseMetadata) bool {
+		for _, filter := range filters {
+			if filter(meta) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
 // SelectPhases returns phases that satisfy every provided filter. When no
 // filters are supplied, all phases are returned.
 func SelectPhases(list []phases.Phase, filters ...PhaseFilter) []phases.Phase {
@@ -141,13 +160,42 @@ outer:
 	return res
 }
 
-// WithBundle appends all phases from the provided bundle function.
+// WithBundle appends all phases from the provided bundle function. Server
+// runs call bundle again for fresh phase instances, so it must build new
+// phases on every call.
 func WithBundle(bundle func() []phases.Phase) Option {
 	return func(cfg *Config) {
 		if cfg == nil || bundle == nil {
 			return
 		}
 		cfg.Phases = append(cfg.Phases, bundle()...)
+		cfg.sources = append(cfg.sources, bundle)
+	}
+}
+
+// freshPhases builds the phases for one run: bundles are called again and
+// the result is put in PhaseOrder. Phases given with WithPhases, or set on
+// Config directly, are shared between runs.
+func (cfg Config) freshPhases() ([]phases.Phase, error) {
+	if len(cfg.sources) == 0 {
+		return cfg.Phases, nil
+	}
+	var list []phases.Phase
+	for _, source := range cfg.sources {
+		list = append(list, source()...)
+	}
+	return phases.OrderPhases(list, cfg.PhaseOrder)
+}
+
+// WithPhaseOrder runs the phases in the order of ids, which must name every
+// phase once and keep each phase after the phases it requires. It applies
+// after all other options, so it can interleave phases from several bundles.
+func WithPhaseOrder(ids ...string) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.PhaseOrder = append([]string(nil), ids...)
 	}
 }
 
@@ -0,0 +1,78 @@
+package phasedapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/history"
+	"github.com/BrianJOC/ansible-host-prep/pkg/report"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+// runStoreTimeout bounds each write to and read from the run store.
+const runStoreTimeout = 30 * time.Second
+
+// WithRunStore keeps finished runs in store: each run's report joins the run
+// history (read it with history.LoadStore), and its status is saved under
+// runs/<id>.json, so Run and GET /runs/{id} still answer after
+// WithRunRetention drops the run or the server restarts. Servers sharing a
+// store share the history. onError receives store failures, which never fail
+// a run (default: ignored).
+func WithRunStore(store storage.Store, onError func(error)) ServerOption {
+	return func(s *Server) {
+		s.store = store
+		s.storeError = onError
+		if s.storeError == nil {
+			s.storeError = func(error) {}
+		}
+	}
+}
+
+// historyRecorder records one run for the run history. Each run gets its own
+// recorder, as a report.Recorder follows one run at a time.
+func (s *Server) historyRecorder() *report.Recorder {
+	return report.NewRecorder(func(rep *report.Report) {
+		ctx, cancel := context.WithTimeout(context.Background(), runStoreTimeout)
+		defer cancel()
+		if err := history.Save(ctx, s.store, rep); err != nil {
+			s.storeError(err)
+		}
+	})
+}
+
+func (s *Server) storeRun(run *serverRun) {
+	if s.store == nil {
+		return
+	}
+	data, err := json.Marshal(run.info())
+	if err != nil {
+		s.storeError(err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), runStoreTimeout)
+	defer cancel()
+	if err := s.store.Put(ctx, runStoreKey(run.id), data); err != nil {
+		s.storeError(fmt.Errorf("store run %s: %w", run.id, err))
+	}
+}
+
+// storedRun reads a finished run saved by storeRun.
+func (s *Server) storedRun(id string) (RunInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), runStoreTimeout)
+	defer cancel()
+	data, err := s.store.Get(ctx, runStoreKey(id))
+	if err != nil {
+		return RunInfo{}, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	var info RunInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return RunInfo{}, fmt.Errorf("decode stored run %s: %w", id, err)
+	}
+	return info, nil
+}
+
+func runStoreKey(id string) string {
+	return "runs/" + id + ".json"
+}
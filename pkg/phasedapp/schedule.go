@@ -0,0 +1,140 @@
+package phasedapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+// WithSchedule delays the pipeline until at. Phases listed in preflight run
+// immediately so operator input and connectivity are validated up front; once
+// the scheduled time arrives the full pipeline re-runs with the collected
+// answers.
+func WithSchedule(at time.Time, preflight ...string) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.StartAt = at
+		cfg.Preflight = append(cfg.Preflight, preflight...)
+	}
+}
+
+// WithPreflightPhases runs list during the preflight of a scheduled run in
+// place of the registered phases with the same IDs, e.g. a sudo check that
+// never installs sudo, so nothing on the host changes before the window.
+func WithPreflightPhases(list ...phases.Phase) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.PreflightPhases = append(cfg.PreflightPhases, list...)
+	}
+}
+
+type preflightFinishedMsg struct {
+	err error
+}
+
+type scheduleTickMsg time.Time
+
+func newPreflightManager(cfg Config, observer *phaseObserver, handler *bubbleInputHandler) (*phases.Manager, error) {
+	if len(cfg.Preflight) == 0 {
+		return nil, nil
+	}
+	byID := make(map[string]phases.Phase, len(cfg.Phases))
+	for _, ph := range append(append([]phases.Phase{}, cfg.Phases...), cfg.PreflightPhases...) {
+		if ph != nil {
+			byID[ph.Metadata().ID] = ph
+		}
+	}
+	manager := phases.NewManager(
+		phases.WithObserver(observer),
+		phases.WithInputHandler(handler),
+	)
+	for _, id := range cfg.Preflight {
+		ph, ok := byID[id]
+		if !ok {
+			return nil, phases.ValidationError{Reason: fmt.Sprintf("preflight phase %q is not registered", id)}
+		}
+		if err := manager.Register(ph); err != nil {
+			return nil, err
+		}
+	}
+	return manager, nil
+}
+
+func (m *model) scheduled() bool {
+	return !m.scheduledAt.IsZero() && time.Now().Before(m.scheduledAt)
+}
+
+func (m *model) startPreflight() tea.Cmd {
+	if m.preflight == nil {
+		m.awaitingStart = true
+		m.setStatusf("Scheduled start at %s", m.scheduledAt.Format(time.DateTime))
+		return scheduleTickCmd()
+	}
+	m.pipelineActive = true
+	m.setStatus("Running preflight checks before the scheduled start")
+	return tea.Batch(
+		runPreflightCmd(m.runCtx, m.preflight, m.phaseCtx),
+		waitPhaseEventCmd(m.observer),
+		waitInputRequestCmd(m.inputHandler),
+		m.spinner.Tick,
+	)
+}
+
+func (m *model) handlePreflightFinished(msg preflightFinishedMsg) tea.Cmd {
+	m.pipelineActive = false
+	if msg.err != nil {
+		m.done = msg.err
+		m.scheduledAt = time.Time{}
+		m.setStatusf("Preflight failed, scheduled start cancelled — %v", msg.err)
+		return nil
+	}
+	m.awaitingStart = true
+	m.setStatusf("Preflight passed; starting in %s", m.countdown(time.Now()))
+	return scheduleTickCmd()
+}
+
+func (m *model) handleScheduleTick(now time.Time) tea.Cmd {
+	if !m.awaitingStart {
+		return nil
+	}
+	if now.Before(m.scheduledAt) {
+		m.setStatusf("Scheduled start at %s (in %s) • r starts now", m.scheduledAt.Format(time.DateTime), m.countdown(now))
+		return scheduleTickCmd()
+	}
+	m.awaitingStart = false
+	// resetPipeline closes the connection the preflight opened.
+	m.resetPipeline()
+	m.setStatus("Scheduled start reached")
+	return m.startPipelineFrom(m.initialStartIndex)
+}
+
+func (m *model) countdown(now time.Time) string {
+	remaining := m.scheduledAt.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining.Round(time.Second).String()
+}
+
+func scheduleTickCmd() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return scheduleTickMsg(t)
+	})
+}
+
+func runPreflightCmd(runCtx context.Context, manager *phases.Manager, ctx *phases.Context) tea.Cmd {
+	return func() tea.Msg {
+		if runCtx == nil {
+			runCtx = context.Background()
+		}
+		return preflightFinishedMsg{err: manager.Run(runCtx, ctx)}
+	}
+}
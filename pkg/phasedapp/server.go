@@ -0,0 +1,865 @@
+package phasedapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+var (
+	// ErrRunNotFound reports that no run exists with the requested ID.
+	ErrRunNotFound = errors.New("phasedapp: run not found")
+	// ErrNoPendingInput reports an answer for a run that is not waiting for one.
+	ErrNoPendingInput = errors.New("phasedapp: run is not waiting for input")
+	// ErrHostUnsupported reports a start request naming a host on a server
+	// without WithHostInput.
+	ErrHostUnsupported = errors.New("phasedapp: host is not supported by this server; supply it in inputs")
+)
+
+// RunStatus is the lifecycle state of a pipeline run started over HTTP.
+type RunStatus string
+
+const (
+	// RunQueued runs wait for a free slot under WithMaxConcurrentRuns.
+	RunQueued       RunStatus = "queued"
+	RunRunning      RunStatus = "running"
+	RunWaitingInput RunStatus = "waiting_input"
+	RunSucceeded    RunStatus = "succeeded"
+	RunFailed       RunStatus = "failed"
+	RunCancelled    RunStatus = "cancelled"
+)
+
+// Run event types streamed by the server.
+const (
+	RunEventPhaseStarted     = "phase_started"
+	RunEventPhaseCompleted   = "phase_completed"
+	RunEventInputRequested   = "input_requested"
+	RunEventPipelineFinished = "pipeline_finished"
+	// RunEventCommand records a remote command once it finishes, with Error
+	// set when it failed.
+	RunEventCommand = "command"
+	// RunEventTask records per-task progress reported by a phase.
+	RunEventTask = "task"
+)
+
+// RunEvent is a single entry in a run's event stream.
+type RunEvent struct {
+	Seq       int            `json:"seq"`
+	Type      string         `json:"event"`
+	Timestamp time.Time      `json:"timestamp"`
+	Phase     *RunEventPhase `json:"phase,omitempty"`
+	Input     *PendingInput  `json:"input,omitempty"`
+	Status    RunStatus      `json:"status,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Command   string         `json:"command,omitempty"`
+	Task      *RunTask       `json:"task,omitempty"`
+	// Summary carries the app's summary fields on "pipeline_finished",
+	// keyed by label.
+	Summary map[string]string `json:"summary,omitempty"`
+}
+
+// RunEventPhase identifies the phase an event refers to.
+type RunEventPhase struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// RunTask is the task progress carried by "task" events.
+type RunTask struct {
+	Name    string `json:"name"`
+	Host    string `json:"host,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// PendingInput describes the input a run is blocked on. Secret inputs never
+// carry their default.
+type PendingInput struct {
+	PhaseID     string               `json:"phase"`
+	InputID     string               `json:"input"`
+	Label       string               `json:"label"`
+	Description string               `json:"description,omitempty"`
+	Kind        phases.InputKind     `json:"kind"`
+	Required    bool                 `json:"required"`
+	Secret      bool                 `json:"secret"`
+	Options     []PendingInputOption `json:"options,omitempty"`
+	Default     any                  `json:"default,omitempty"`
+	Reason      string               `json:"reason,omitempty"`
+}
+
+// PendingInputOption is one choice of a select input.
+type PendingInputOption struct {
+	Value       string `json:"value"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// RunInfo is the JSON view of a run. StartedAt is zero while the run is
+// queued; Principal is empty for runs started without WithAuthTokens.
+type RunInfo struct {
+	ID          string        `json:"id"`
+	Status      RunStatus     `json:"status"`
+	Principal   string        `json:"principal,omitempty"`
+	SubmittedAt time.Time     `json:"submitted_at"`
+	StartedAt   time.Time     `json:"started_at,omitzero"`
+	FinishedAt  *time.Time    `json:"finished_at,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Pending     *PendingInput `json:"pending_input,omitempty"`
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithHostInput maps the "host" field of a start request onto the given
+// phase input, so clients can start a run for a host without knowing the
+// input layout.
+func WithHostInput(phaseID, inputID string) ServerOption {
+	return func(s *Server) {
+		s.hostPhase = phaseID
+		s.hostInput = inputID
+	}
+}
+
+// WithMaxConcurrentRuns bounds how many runs execute at once. Further runs
+// are queued with status RunQueued and start in submission order as slots
+// free up. Zero or less runs everything immediately.
+func WithMaxConcurrentRuns(n int) ServerOption {
+	return func(s *Server) {
+		s.maxActive = max(n, 0)
+	}
+}
+
+// WithRunRetention keeps the status and events of at most n finished runs,
+// forgetting the oldest first. Zero or less keeps every run.
+func WithRunRetention(n int) ServerOption {
+	return func(s *Server) {
+		s.retain = max(n, 0)
+	}
+}
+
+// Server drives the app's pipeline over HTTP so a web frontend or another
+// service can start runs, follow their events via server-sent events, and
+// answer input requests without a terminal. With WithMaxConcurrentRuns it
+// acts as a job queue for a shared prep service; each run keeps its events,
+// including the commands it ran, until WithRunRetention drops it.
+// WithAuthTokens restricts every route to bearer-token holders and records
+// who started each run; WithAuditLog keeps a trail of those actions.
+// WithRunStore keeps finished runs and their reports in durable storage.
+//
+// Routes:
+//
+//	GET    /schema            input JSON Schema (phases.ExportSchema)
+//	POST   /runs              start a run: {"host": "...", "inputs": {...}}
+//	GET    /runs              list runs
+//	GET    /runs/{id}         run status and pending input
+//	DELETE /runs/{id}         cancel a run
+//	GET    /runs/{id}/events  event stream (text/event-stream)
+//	POST   /runs/{id}/input   answer the pending input: {"phase", "input", "value"}
+type Server struct {
+	app       *App
+	hostPhase string
+	hostInput string
+	now       func() time.Time
+
+	maxActive int
+	retain    int
+	tokens    map[string]string
+
+	auditMu  sync.Mutex
+	auditLog *json.Encoder
+
+	store      storage.Store
+	storeError func(error)
+
+	mu       sync.Mutex
+	runs     map[string]*serverRun
+	active   int
+	queue    []*serverRun
+	finished []string
+}
+
+// NewServer constructs an HTTP server for the app's phases.
+func (a *App) NewServer(opts ...ServerOption) *Server {
+	s := &Server{app: a, now: time.Now, runs: make(map[string]*serverRun)}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(s)
+		}
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /version", s.handleVersion)
+	mux.HandleFunc("GET /schema", s.handleSchema)
+	mux.HandleFunc("POST /runs", s.handleStart)
+	mux.HandleFunc("GET /runs", s.handleList)
+	mux.HandleFunc("GET /runs/{id}", s.handleGet)
+	mux.HandleFunc("DELETE /runs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /runs/{id}/events", s.handleEvents)
+	mux.HandleFunc("POST /runs/{id}/input", s.handleInput)
+	return negotiateVersion(s.authenticate(mux))
+}
+
+// Serve listens on addr until ctx is cancelled, then cancels every active run
+// and shuts the listener down.
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler(), ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		s.cancelAll()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("api server: %w", err)
+	}
+	return nil
+}
+
+// Start launches a pipeline run for host (optional) with the supplied
+// answers, or queues it when WithMaxConcurrentRuns slots are all busy.
+// Values are validated up front; anything missing is requested through the
+// event stream.
+func (s *Server) Start(host string, inputs phases.Inputs) (RunInfo, error) {
+	return s.StartAs("", host, inputs)
+}
+
+// StartAs is Start on behalf of principal, which is recorded on the run, in
+// the audit log, and under ContextKeyPrincipal for observers and summaries.
+func (s *Server) StartAs(principal, host string, inputs phases.Inputs) (RunInfo, error) {
+	inputs, err := s.withHost(host, inputs)
+	if err != nil {
+		return RunInfo{}, err
+	}
+
+	managerOpts := append([]phases.ManagerOption{}, s.app.cfg.ManagerOptions...)
+	run := newServerRun(newRunID(), s.now)
+	run.principal = principal
+	run.host = strings.TrimSpace(host)
+	managerOpts = append(managerOpts, phases.WithObserver(run), phases.WithInputHandler(run))
+	if s.store != nil {
+		managerOpts = append(managerOpts, phases.WithObserver(s.historyRecorder()))
+	}
+	manager := phases.NewManager(managerOpts...)
+	// Phases keep per-run state in their fields, so each run gets its own.
+	list, err := s.app.cfg.freshPhases()
+	if err != nil {
+		return RunInfo{}, err
+	}
+	if err := manager.Register(list...); err != nil {
+		return RunInfo{}, err
+	}
+
+	normalized, err := phases.ValidateSuppliedInputs(manager.Metadata(), inputs)
+	if err != nil {
+		return RunInfo{}, err
+	}
+	run.secrets = secretValues(manager.Metadata(), inputs)
+	phaseCtx := phases.NewContext()
+	phases.SeedInputs(phaseCtx, normalized)
+	if principal != "" {
+		SetContext(phaseCtx, ContextKeyPrincipal, principal)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run.ctx = ctx
+	run.cancel = cancel
+	run.launch = func() {
+		run.markStarted()
+		go func() {
+			defer cancel()
+			err := manager.Run(ctx, phaseCtx)
+			run.finish(err, summaryValues(s.app.cfg.SummaryFields, phaseCtx))
+			s.runFinished(run)
+		}()
+	}
+
+	s.mu.Lock()
+	s.runs[run.id] = run
+	if s.maxActive > 0 && s.active >= s.maxActive {
+		run.status = RunQueued
+		s.queue = append(s.queue, run)
+	} else {
+		s.active++
+		run.launch()
+	}
+	s.mu.Unlock()
+	s.audit(AuditEntry{Action: AuditRunStarted, Principal: principal, RunID: run.id, Host: run.host})
+	return run.info(), nil
+}
+
+// runFinished frees the run's slot for the next queued run and forgets the
+// oldest finished runs beyond the retention limit.
+func (s *Server) runFinished(run *serverRun) {
+	s.auditFinished(run)
+	s.storeRun(run)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	for s.active < s.maxActive && len(s.queue) > 0 {
+		next := s.queue[0]
+		s.queue = s.queue[1:]
+		s.active++
+		next.launch()
+	}
+	s.retireLocked(run.id)
+}
+
+func (s *Server) retireLocked(id string) {
+	s.finished = append(s.finished, id)
+	for s.retain > 0 && len(s.finished) > s.retain {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// Answer delivers value for the input a run is waiting on.
+func (s *Server) Answer(id, phaseID, inputID string, value any) error {
+	return s.AnswerAs("", id, phaseID, inputID, value)
+}
+
+// AnswerAs is Answer on behalf of principal, for the audit log.
+func (s *Server) AnswerAs(principal, id, phaseID, inputID string, value any) error {
+	run, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	if err := run.answer(phaseID, inputID, value); err != nil {
+		return err
+	}
+	s.audit(AuditEntry{Action: AuditInputProvided, Principal: principal, RunID: id, Host: run.host, Input: phaseID + "." + inputID})
+	return nil
+}
+
+// Cancel stops a run. A queued run is removed from the queue and never
+// starts.
+func (s *Server) Cancel(id string) error {
+	return s.CancelAs("", id)
+}
+
+// CancelAs is Cancel on behalf of principal, for the audit log.
+func (s *Server) CancelAs(principal, id string) error {
+	run, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	s.audit(AuditEntry{Action: AuditRunCancelled, Principal: principal, RunID: id, Host: run.host})
+	run.cancel()
+	if s.dequeue(run) {
+		run.finish(context.Canceled, nil)
+		s.auditFinished(run)
+		s.storeRun(run)
+		s.mu.Lock()
+		s.retireLocked(run.id)
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// dequeue removes run from the queue and reports whether it was queued.
+func (s *Server) dequeue(run *serverRun) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, queued := range s.queue {
+		if queued == run {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Run returns the current state of a run.
+func (s *Server) Run(id string) (RunInfo, error) {
+	run, err := s.lookup(id)
+	if err != nil && s.store != nil {
+		return s.storedRun(id)
+	}
+	if err != nil {
+		return RunInfo{}, err
+	}
+	return run.info(), nil
+}
+
+// Subscribe returns a run's events after seq, a channel for later ones, and
+// a function releasing the subscription. The channel is closed when the run
+// finishes or the subscriber falls too far behind.
+func (s *Server) Subscribe(id string, after int) ([]RunEvent, <-chan RunEvent, func(), error) {
+	run, err := s.lookup(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	history, events, unsubscribe := run.subscribe(after)
+	return history, events, unsubscribe, nil
+}
+
+func (s *Server) withHost(host string, inputs phases.Inputs) (phases.Inputs, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return inputs, nil
+	}
+	if s.hostPhase == "" {
+		return nil, ErrHostUnsupported
+	}
+	merged := make(phases.Inputs, len(inputs)+1)
+	for phaseID, values := range inputs {
+		merged[phaseID] = values
+	}
+	values := make(map[string]any, len(merged[s.hostPhase])+1)
+	for inputID, value := range merged[s.hostPhase] {
+		values[inputID] = value
+	}
+	values[s.hostInput] = host
+	merged[s.hostPhase] = values
+	return merged, nil
+}
+
+func (s *Server) lookup(id string) (*serverRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return run, nil
+}
+
+func (s *Server) cancelAll() {
+	s.mu.Lock()
+	queued := s.queue
+	s.queue = nil
+	for _, run := range s.runs {
+		run.cancel()
+	}
+	s.mu.Unlock()
+	for _, run := range queued {
+		run.finish(context.Canceled, nil)
+		s.auditFinished(run)
+		s.storeRun(run)
+		s.mu.Lock()
+		s.retireLocked(run.id)
+		s.mu.Unlock()
+	}
+}
+
+// maxRequestBody bounds the JSON bodies of start and answer requests.
+const maxRequestBody = 1 << 20
+
+type startRequest struct {
+	Host   string        `json:"host"`
+	Inputs phases.Inputs `json:"inputs"`
+}
+
+type answerRequest struct {
+	Phase string `json:"phase"`
+	Input string `json:"input"`
+	Value any    `json:"value"`
+}
+
+func (s *Server) handleSchema(w http.ResponseWriter, _ *http.Request) {
+	manager := phases.NewManager()
+	if err := manager.Register(s.app.cfg.Phases...); err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	schema, err := phases.BuildSchema(manager)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schema)
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	var req startRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("decode request: %w", err))
+		return
+	}
+	info, err := s.StartAs(requestPrincipal(r), req.Host, req.Inputs)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var validationErr phases.InputValidationError
+		if errors.As(err, &validationErr) || errors.Is(err, ErrHostUnsupported) {
+			status = http.StatusBadRequest
+		}
+		writeAPIError(w, status, err)
+		return
+	}
+	w.Header().Set("Location", "/runs/"+info.ID)
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	infos := make([]RunInfo, 0, len(s.runs))
+	for _, run := range s.runs {
+		infos = append(infos, run.info())
+	}
+	s.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].SubmittedAt.Before(infos[j].SubmittedAt) })
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	info, err := s.Run(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if err := s.CancelAs(requestPrincipal(r), r.PathValue("id")); err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleInput(w http.ResponseWriter, r *http.Request) {
+	var req answerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("decode request: %w", err))
+		return
+	}
+	err := s.AnswerAs(requestPrincipal(r), r.PathValue("id"), req.Phase, req.Input, req.Value)
+	switch {
+	case errors.Is(err, ErrRunNotFound):
+		writeAPIError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNoPendingInput):
+		writeAPIError(w, http.StatusConflict, err)
+	case err != nil:
+		writeAPIError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// handleEvents streams a run's events as server-sent events, replaying
+// history after Last-Event-ID (or from the start) and closing once the run
+// has finished.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	after, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	history, events, unsubscribe, err := s.Subscribe(r.PathValue("id"), after)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range history {
+		writeSSE(w, event)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeSSE(w, event)
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event RunEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	body := map[string]any{"error": err.Error()}
+	var validationErr phases.InputValidationError
+	if errors.As(err, &validationErr) {
+		problems := make([]string, 0, len(validationErr.Problems))
+		for _, problem := range validationErr.Problems {
+			problems = append(problems, problem.String())
+		}
+		body["problems"] = problems
+	}
+	writeJSON(w, status, body)
+}
+
+func newRunID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// serverRun tracks one pipeline run. It is the run's observer and input
+// handler: input requests block until answered over HTTP or cancelled.
+type serverRun struct {
+	id     string
+	now    func() time.Time
+	ctx    context.Context
+	cancel context.CancelFunc
+	// launch starts the pipeline; Server calls it once a slot is free.
+	launch func()
+	// principal started the run for host; both may be empty.
+	principal string
+	host      string
+
+	mu        sync.Mutex
+	status    RunStatus
+	submitted time.Time
+	started   time.Time
+	finished  time.Time
+	err       string
+	pending   *PendingInput
+	answers   chan any
+	events    []RunEvent
+	subs      map[chan RunEvent]struct{}
+	done      bool
+	// secrets are redacted from commands, task messages, and errors.
+	secrets []string
+}
+
+func newServerRun(id string, now func() time.Time) *serverRun {
+	return &serverRun{
+		id:        id,
+		now:       now,
+		status:    RunRunning,
+		submitted: now(),
+		answers:   make(chan any, 1),
+		subs:      make(map[chan RunEvent]struct{}),
+	}
+}
+
+func (r *serverRun) markStarted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = RunRunning
+	r.started = r.now()
+}
+
+func (r *serverRun) CommandStarted(_ phases.PhaseMetadata, cmd string) func(error) {
+	return func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		event := RunEvent{Type: RunEventCommand, Command: cmd}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		r.emitLocked(event)
+	}
+}
+
+func (r *serverRun) TaskProgress(meta phases.PhaseMetadata, event phases.TaskEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emitLocked(RunEvent{Type: RunEventTask, Phase: eventPhase(meta), Task: &RunTask{
+		Name:    event.Task,
+		Host:    event.Host,
+		Status:  string(event.Status),
+		Message: event.Message,
+	}})
+}
+
+func (r *serverRun) PhaseStarted(meta phases.PhaseMetadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.emitLocked(RunEvent{Type: RunEventPhaseStarted, Phase: eventPhase(meta)})
+}
+
+func (r *serverRun) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event := RunEvent{Type: RunEventPhaseCompleted, Phase: eventPhase(meta)}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	r.emitLocked(event)
+}
+
+func (r *serverRun) RequestInput(meta phases.PhaseMetadata, input phases.InputDefinition, reason string) (any, error) {
+	pending := pendingInput(meta, input, reason)
+
+	r.mu.Lock()
+	r.pending = pending
+	r.status = RunWaitingInput
+	r.emitLocked(RunEvent{Type: RunEventInputRequested, Phase: eventPhase(meta), Input: pending})
+	r.mu.Unlock()
+
+	select {
+	case value := <-r.answers:
+		return value, nil
+	case <-r.ctx.Done():
+		r.mu.Lock()
+		r.pending = nil
+		r.mu.Unlock()
+		return nil, r.ctx.Err()
+	}
+}
+
+func (r *serverRun) answer(phaseID, inputID string, value any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		return fmt.Errorf("%w: %s", ErrNoPendingInput, r.id)
+	}
+	if r.pending.PhaseID != phaseID || r.pending.InputID != inputID {
+		return fmt.Errorf("%w: %s is waiting for %s.%s", ErrNoPendingInput, r.id, r.pending.PhaseID, r.pending.InputID)
+	}
+	if r.pending.Secret {
+		if secret := defaultString(value); secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	r.pending = nil
+	r.status = RunRunning
+	r.answers <- value
+	return nil
+}
+
+func (r *serverRun) finish(err error, summary map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.finished = r.now()
+	r.pending = nil
+	switch {
+	case err == nil:
+		r.status = RunSucceeded
+	case errors.Is(err, context.Canceled):
+		r.status = RunCancelled
+		r.err = redactSecrets(err.Error(), r.secrets)
+	default:
+		r.status = RunFailed
+		r.err = redactSecrets(err.Error(), r.secrets)
+	}
+	r.emitLocked(RunEvent{Type: RunEventPipelineFinished, Status: r.status, Error: r.err, Summary: summary})
+	r.done = true
+	for sub := range r.subs {
+		close(sub)
+	}
+	r.subs = nil
+}
+
+// subscribe returns the events after seq and a channel for later ones. The
+// channel is closed when the run finishes or the subscriber falls behind.
+func (r *serverRun) subscribe(after int) ([]RunEvent, <-chan RunEvent, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var history []RunEvent
+	for _, event := range r.events {
+		if event.Seq > after {
+			history = append(history, event)
+		}
+	}
+	ch := make(chan RunEvent, 64)
+	if r.done {
+		close(ch)
+		return history, ch, func() {}
+	}
+	r.subs[ch] = struct{}{}
+	return history, ch, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := r.subs[ch]; ok {
+			delete(r.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (r *serverRun) emitLocked(event RunEvent) {
+	event.Seq = len(r.events) + 1
+	event.Timestamp = r.now().UTC()
+	event.Command = redactSecrets(event.Command, r.secrets)
+	event.Error = redactSecrets(event.Error, r.secrets)
+	if event.Task != nil {
+		event.Task.Message = redactSecrets(event.Task.Message, r.secrets)
+	}
+	for label, value := range event.Summary {
+		event.Summary[label] = redactSecrets(value, r.secrets)
+	}
+	r.events = append(r.events, event)
+	for sub := range r.subs {
+		select {
+		case sub <- event:
+		default:
+			delete(r.subs, sub)
+			close(sub)
+		}
+	}
+}
+
+func (r *serverRun) info() RunInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := RunInfo{ID: r.id, Status: r.status, Principal: r.principal, SubmittedAt: r.submitted, StartedAt: r.started, Error: r.err, Pending: r.pending}
+	if !r.finished.IsZero() {
+		finished := r.finished
+		info.FinishedAt = &finished
+	}
+	return info
+}
+
+func eventPhase(meta phases.PhaseMetadata) *RunEventPhase {
+	return &RunEventPhase{ID: meta.ID, Title: meta.Title}
+}
+
+func pendingInput(meta phases.PhaseMetadata, input phases.InputDefinition, reason string) *PendingInput {
+	pending := &PendingInput{
+		PhaseID:     meta.ID,
+		InputID:     input.ID,
+		Label:       input.Label,
+		Description: input.Description,
+		Kind:        input.Kind,
+		Required:    input.Required,
+		Secret:      input.Secret || input.Kind == phases.InputKindSecret,
+		Reason:      reason,
+	}
+	if !pending.Secret {
+		pending.Default = input.Default
+	}
+	for _, opt := range input.Options {
+		pending.Options = append(pending.Options, PendingInputOption{Value: opt.Value, Label: opt.Label, Description: opt.Description})
+	}
+	return pending
+}
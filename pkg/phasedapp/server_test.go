@@ -0,0 +1,406 @@
+package phasedapp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	phasespkg "github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/history"
+	"github.com/BrianJOC/ansible-host-prep/pkg/storage"
+)
+
+func TestServerDrivesRunOverHTTP(t *testing.T) {
+	t.Parallel()
+
+	phase := stubPhase{
+		meta: phasespkg.PhaseMetadata{
+			ID:    "ssh",
+			Title: "SSH",
+			Inputs: []phasespkg.InputDefinition{
+				{ID: "host", Kind: phasespkg.InputKindText, Required: true},
+				{ID: "password", Kind: phasespkg.InputKindSecret, Secret: true, Default: "hunter2"},
+			},
+		},
+		run: func(_ context.Context, phaseCtx *phasespkg.Context) error {
+			if _, ok := phasespkg.GetInput(phaseCtx, "ssh", "host"); !ok {
+				return phasespkg.InputRequestError{PhaseID: "ssh", Input: phasespkg.InputDefinition{ID: "host", Kind: phasespkg.InputKindText}}
+			}
+			if _, ok := phasespkg.GetInput(phaseCtx, "ssh", "password"); !ok {
+				return phasespkg.InputRequestError{PhaseID: "ssh", Input: phasespkg.InputDefinition{ID: "password", Kind: phasespkg.InputKindSecret, Secret: true, Default: "hunter2"}}
+			}
+			return nil
+		},
+	}
+	app, err := New(WithPhases(phase))
+	require.NoError(t, err)
+	server := httptest.NewServer(app.NewServer(WithHostInput("ssh", "host")).Handler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Post(server.URL+"/runs", "application/json", strings.NewReader(`{"host": " 10.0.0.5 "}`))
+	require.NoError(t, err)
+	var info RunInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	stream, err := http.Get(server.URL + "/runs/" + info.ID + "/events")
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	require.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+
+	var events []RunEvent
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event RunEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		events = append(events, event)
+
+		if event.Type == RunEventInputRequested {
+			require.Equal(t, "password", event.Input.InputID)
+			require.True(t, event.Input.Secret)
+			require.Nil(t, event.Input.Default)
+
+			wrong, err := http.Post(server.URL+"/runs/"+info.ID+"/input", "application/json", strings.NewReader(`{"phase": "ssh", "input": "host", "value": "x"}`))
+			require.NoError(t, err)
+			wrong.Body.Close()
+			require.Equal(t, http.StatusConflict, wrong.StatusCode)
+
+			answer, err := http.Post(server.URL+"/runs/"+info.ID+"/input", "application/json", strings.NewReader(`{"phase": "ssh", "input": "password", "value": "s3cret"}`))
+			require.NoError(t, err)
+			answer.Body.Close()
+			require.Equal(t, http.StatusAccepted, answer.StatusCode)
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []string{
+		RunEventPhaseStarted, RunEventInputRequested, RunEventPhaseCompleted, RunEventPipelineFinished,
+	}, types)
+	require.Equal(t, RunSucceeded, events[len(events)-1].Status)
+
+	status, err := http.Get(server.URL + "/runs/" + info.ID)
+	require.NoError(t, err)
+	defer status.Body.Close()
+	require.NoError(t, json.NewDecoder(status.Body).Decode(&info))
+	require.Equal(t, RunSucceeded, info.Status)
+	require.NotNil(t, info.FinishedAt)
+}
+
+func TestServerRejectsInvalidInputs(t *testing.T) {
+	t.Parallel()
+
+	app, err := New(WithPhases(stubPhase{meta: phasespkg.PhaseMetadata{ID: "ssh"}}))
+	require.NoError(t, err)
+	server := httptest.NewServer(app.NewServer().Handler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Post(server.URL+"/runs", "application/json", strings.NewReader(`{"inputs": {"nope": {}}}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var body struct {
+		Problems []string `json:"problems"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, []string{"nope: unknown phase"}, body.Problems)
+
+	missing, err := http.Get(server.URL + "/runs/unknown")
+	require.NoError(t, err)
+	missing.Body.Close()
+	require.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
+
+func TestServerQueuesRunsBeyondConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	phase := stubPhase{
+		meta: phasespkg.PhaseMetadata{
+			ID: "ssh",
+			Inputs: []phasespkg.InputDefinition{
+				{ID: "password", Kind: phasespkg.InputKindSecret, Secret: true},
+			},
+		},
+		run: func(ctx context.Context, phaseCtx *phasespkg.Context) error {
+			password, _ := phasespkg.GetInputString(phaseCtx, "ssh", "password")
+			phasespkg.TraceCommand(ctx, "echo "+password+" | sudo -S true")(nil)
+			<-release
+			return nil
+		},
+	}
+	app, err := New(WithPhases(phase))
+	require.NoError(t, err)
+	server := app.NewServer(WithMaxConcurrentRuns(1), WithRunRetention(2))
+
+	inputs := phasespkg.Inputs{"ssh": {"password": "hunter2"}}
+	first, err := server.Start("", inputs)
+	require.NoError(t, err)
+	require.Equal(t, RunRunning, first.Status)
+	second, err := server.Start("", inputs)
+	require.NoError(t, err)
+	require.Equal(t, RunQueued, second.Status)
+	require.True(t, second.StartedAt.IsZero())
+	third, err := server.Start("", inputs)
+	require.NoError(t, err)
+
+	require.NoError(t, server.Cancel(third.ID))
+	info, err := server.Run(third.ID)
+	require.NoError(t, err)
+	require.Equal(t, RunCancelled, info.Status)
+
+	close(release)
+	waitForStatus(t, server, second.ID, RunSucceeded)
+
+	history, _, unsubscribe, err := server.Subscribe(second.ID, 0)
+	require.NoError(t, err)
+	unsubscribe()
+	require.Equal(t, RunEventCommand, history[1].Type)
+	require.Equal(t, "echo [secret] | sudo -S true", history[1].Command)
+
+	// Retention keeps the two most recently finished runs.
+	require.Eventually(t, func() bool {
+		_, err := server.Run(third.ID)
+		return errors.Is(err, ErrRunNotFound)
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = server.Run(first.ID)
+	require.NoError(t, err)
+}
+
+// hostPhase remembers its host in a field while it runs, like the built-in
+// phases do.
+type hostPhase struct {
+	host    string
+	started *sync.WaitGroup
+}
+
+func (p *hostPhase) Metadata() phasespkg.PhaseMetadata {
+	return phasespkg.PhaseMetadata{ID: "ssh", Inputs: []phasespkg.InputDefinition{{ID: "host", Kind: phasespkg.InputKindText}}}
+}
+
+func (p *hostPhase) Run(_ context.Context, phaseCtx *phasespkg.Context) error {
+	host, _ := phasespkg.GetInputString(phaseCtx, "ssh", "host")
+	p.host = host
+	p.started.Done()
+	p.started.Wait()
+	if p.host != host {
+		return errors.New("host changed to " + p.host + " by another run")
+	}
+	return nil
+}
+
+func TestServerRunsGetTheirOwnPhases(t *testing.T) {
+	t.Parallel()
+
+	var started sync.WaitGroup
+	started.Add(2)
+	app, err := New(WithBundle(func() []phasespkg.Phase {
+		return []phasespkg.Phase{&hostPhase{started: &started}}
+	}))
+	require.NoError(t, err)
+	server := app.NewServer(WithHostInput("ssh", "host"), WithMaxConcurrentRuns(2))
+
+	web, err := server.Start("web01", nil)
+	require.NoError(t, err)
+	db, err := server.Start("db01", nil)
+	require.NoError(t, err)
+	waitForStatus(t, server, web.ID, RunSucceeded)
+	waitForStatus(t, server, db.ID, RunSucceeded)
+}
+
+func waitForStatus(t *testing.T, server *Server, id string, want RunStatus) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		info, err := server.Run(id)
+		return err == nil && info.Status == want
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServerRequiresTokenAndRecordsPrincipal(t *testing.T) {
+	t.Parallel()
+
+	app, err := New(
+		WithPhases(stubPhase{meta: phasespkg.PhaseMetadata{ID: "ssh"}}),
+		WithSummaryFields(ContextField("Initiated by", ContextKeyPrincipal)),
+	)
+	require.NoError(t, err)
+	audit := &lockedBuffer{}
+	api := app.NewServer(WithAuthTokens(map[string]string{"t0ken": "alice"}), WithAuditLog(audit))
+	server := httptest.NewServer(api.Handler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/runs")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	require.Equal(t, `Bearer realm="host-prep"`, resp.Header.Get("WWW-Authenticate"))
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/runs", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer t0ken")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var info RunInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "alice", info.Principal)
+
+	waitForStatus(t, api, info.ID, RunSucceeded)
+	history, _, unsubscribe, err := api.Subscribe(info.ID, 0)
+	require.NoError(t, err)
+	unsubscribe()
+	require.Equal(t, map[string]string{"Initiated by": "alice"}, history[len(history)-1].Summary)
+
+	var actions []string
+	require.Eventually(t, func() bool {
+		actions = nil
+		for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+			var entry AuditEntry
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			actions = append(actions, entry.Action+" "+entry.Principal)
+		}
+		return len(actions) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{AuditAuthFailed + " ", AuditRunStarted + " alice", AuditRunFinished + " alice"}, actions)
+}
+
+func TestBearerToken(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "abc", BearerToken("Bearer abc"))
+	require.Equal(t, "abc", BearerToken(" bearer  abc "))
+	require.Empty(t, BearerToken("Basic abc"))
+	require.Empty(t, BearerToken(""))
+}
+
+// lockedBuffer is a bytes.Buffer safe to read while the server writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServerKeepsFinishedRunsInStore(t *testing.T) {
+	t.Parallel()
+
+	phase := stubPhase{
+		meta: phasespkg.PhaseMetadata{ID: "ssh"},
+		run: func(_ context.Context, phaseCtx *phasespkg.Context) error {
+			phaseCtx.Set("ssh:target_host", "10.0.0.5")
+			return nil
+		},
+	}
+	app, err := New(WithPhases(phase))
+	require.NoError(t, err)
+	store := storage.NewFileStore(t.TempDir())
+	server := app.NewServer(WithRunRetention(1), WithRunStore(store, func(err error) { t.Errorf("store: %v", err) }))
+
+	first, err := server.Start("", nil)
+	require.NoError(t, err)
+	waitForStatus(t, server, first.ID, RunSucceeded)
+	second, err := server.Start("", nil)
+	require.NoError(t, err)
+	waitForStatus(t, server, second.ID, RunSucceeded)
+
+	// Retention dropped the first run from memory; the store still has it.
+	require.Eventually(t, func() bool {
+		_, err := server.lookup(first.ID)
+		return errors.Is(err, ErrRunNotFound)
+	}, 5*time.Second, 10*time.Millisecond)
+	info, err := server.Run(first.ID)
+	require.NoError(t, err)
+	require.Equal(t, RunSucceeded, info.Status)
+	require.NotNil(t, info.FinishedAt)
+
+	restarted := app.NewServer(WithRunStore(store, nil))
+	info, err = restarted.Run(second.ID)
+	require.NoError(t, err)
+	require.Equal(t, second.ID, info.ID)
+	_, err = restarted.Run("../escape")
+	require.ErrorIs(t, err, ErrRunNotFound)
+
+	reports, err := history.LoadStore(context.Background(), store)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.Equal(t, "10.0.0.5", reports[0].Host)
+	require.True(t, reports[1].Success)
+}
+
+func TestServerNegotiatesAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	app, err := New(WithPhases(stubPhase{meta: phasespkg.PhaseMetadata{ID: "ssh"}}))
+	require.NoError(t, err)
+	server := httptest.NewServer(app.NewServer().Handler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/version")
+	require.NoError(t, err)
+	var versions map[string]int
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&versions))
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, map[string]int{"api_version": APIVersion, "min_api_version": MinAPIVersion}, versions)
+	require.NoError(t, CheckServerVersion(resp.Header))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/runs", nil)
+	require.NoError(t, err)
+	req.Header.Set(APIVersionHeader, strconv.Itoa(APIVersion+1))
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Contains(t, body["error"], "upgrade the server")
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, CheckServerVersion(http.Header{}))
+
+	header := http.Header{}
+	header.Set(APIVersionHeader, strconv.Itoa(APIVersion+2))
+	header.Set(MinAPIVersionHeader, strconv.Itoa(APIVersion+1))
+	err := CheckServerVersion(header)
+	var versionErr VersionError
+	require.ErrorAs(t, err, &versionErr)
+	require.Equal(t, VersionError{Client: APIVersion, Min: APIVersion + 1, Max: APIVersion + 2}, versionErr)
+	require.ErrorContains(t, err, "upgrade the client")
+
+	header.Set(APIVersionHeader, "v2")
+	require.ErrorContains(t, CheckServerVersion(header), "invalid")
+}
@@ -0,0 +1,170 @@
+package phasedapp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+)
+
+// SummaryField renders a labelled value from the shared context on the
+// end-of-run summary screen.
+type SummaryField struct {
+	Label string
+	Value func(phaseCtx *phases.Context) (string, bool)
+}
+
+// ContextField builds a SummaryField that prints the value stored under key.
+func ContextField(label string, key ContextKey) SummaryField {
+	return SummaryField{
+		Label: label,
+		Value: func(phaseCtx *phases.Context) (string, bool) {
+			if phaseCtx == nil {
+				return "", false
+			}
+			val, ok := phaseCtx.Get(key.String())
+			if !ok {
+				return "", false
+			}
+			str := defaultString(val)
+			return str, str != ""
+		},
+	}
+}
+
+// SummaryHint produces a next-step suggestion shown once the pipeline succeeds.
+type SummaryHint func(phaseCtx *phases.Context) (string, bool)
+
+// WithSummaryFields appends key outputs rendered on the end-of-run summary.
+func WithSummaryFields(fields ...SummaryField) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.SummaryFields = append(cfg.SummaryFields, fields...)
+	}
+}
+
+// WithNextSteps appends hints rendered on the summary after a successful run.
+func WithNextSteps(hints ...SummaryHint) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.NextSteps = append(cfg.NextSteps, hints...)
+	}
+}
+
+func (m *model) renderSummary() string {
+	width := m.viewportWidth()
+	var b strings.Builder
+
+	outcome := "All phases completed"
+	if m.done != nil {
+		outcome = "Pipeline stopped with an error"
+	}
+	b.WriteString(detailTitleStyle.Render("Run Summary — " + outcome))
+	b.WriteString("\n\n")
+
+	for _, id := range m.order {
+		state := m.phases[id]
+		if state == nil {
+			continue
+		}
+		line := fmt.Sprintf("%-28s %-8s %s", state.meta.Title, statusDisplay(state.status), phaseDuration(state))
+		b.WriteString(statusStyles[state.status].Render(line))
+		b.WriteString("\n")
+	}
+
+	if outputs := m.summaryOutputs(); len(outputs) > 0 {
+		b.WriteString("\n")
+		b.WriteString(logSectionStyle.Render("Outputs:"))
+		for _, line := range outputs {
+			b.WriteString("\n")
+			b.WriteString(infoTextStyle.Render("  " + line))
+		}
+		b.WriteString("\n")
+	}
+
+	if hints := m.summaryHints(); len(hints) > 0 {
+		b.WriteString("\n")
+		b.WriteString(logSectionStyle.Render("Next steps:"))
+		for _, line := range hints {
+			b.WriteString("\n")
+			b.WriteString(infoTextStyle.Render("• " + line))
+		}
+	}
+
+	return styleForWidth(summaryPanelStyle, width).Render(strings.TrimRight(b.String(), "\n"))
+}
+
+func (m *model) summaryOutputs() []string {
+	return m.fieldLines(m.summaryFields)
+}
+
+// fieldLines renders "Label: value" for each field that has a value.
+func (m *model) fieldLines(fields []SummaryField) []string {
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.Value == nil {
+			continue
+		}
+		value, ok := field.Value(m.phaseCtx)
+		if !ok || value == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", field.Label, m.redactSecrets(value)))
+	}
+	return lines
+}
+
+func (m *model) summaryHints() []string {
+	if m.done != nil {
+		if state := m.failedPhase(); state != nil {
+			hints := []string{
+				fmt.Sprintf("Press s to return to the dashboard, select %s, and choose Retry from its actions.", state.meta.Title),
+				"Press r to restart the whole pipeline with your previous answers.",
+			}
+			if m.canRollback() {
+				hints = append(hints, "Choose Revert from any phase's actions to undo the completed phases.")
+			}
+			return hints
+		}
+		return []string{"Press r to restart the pipeline with your previous answers."}
+	}
+	hints := make([]string, 0, len(m.nextSteps))
+	for _, hint := range m.nextSteps {
+		if hint == nil {
+			continue
+		}
+		if line, ok := hint(m.phaseCtx); ok && line != "" {
+			hints = append(hints, m.redactSecrets(line))
+		}
+	}
+	return hints
+}
+
+func (m *model) failedPhase() *phaseState {
+	for _, id := range m.order {
+		if state := m.phases[id]; state != nil && state.status == statusFailed {
+			return state
+		}
+	}
+	return nil
+}
+
+func phaseDuration(state *phaseState) string {
+	if state == nil || state.startedAt.IsZero() {
+		return "—"
+	}
+	end := state.finishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(state.startedAt).Round(10 * time.Millisecond).String()
+}
+
+var summaryPanelStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#34D399")).Padding(0, 1)
@@ -0,0 +1,97 @@
+package phasedapp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+)
+
+const transcriptTimeLayout = "20060102-150405"
+
+// WithTranscriptDir sets where session transcripts are written (default: the
+// working directory).
+func WithTranscriptDir(dir string) Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.TranscriptDir = strings.TrimSpace(dir)
+	}
+}
+
+// WithTranscriptOnExit writes a session transcript automatically when the
+// program exits.
+func WithTranscriptOnExit() Option {
+	return func(cfg *Config) {
+		if cfg == nil {
+			return
+		}
+		cfg.TranscriptOnExit = true
+	}
+}
+
+type transcriptEntry struct {
+	at      time.Time
+	phaseID string
+	kind    string
+	message string
+}
+
+func (m *model) recordTranscript(phaseID, kind, message string) {
+	m.transcript = append(m.transcript, transcriptEntry{
+		at:      time.Now(),
+		phaseID: phaseID,
+		kind:    kind,
+		message: message,
+	})
+}
+
+func (m *model) recordInputTranscript(meta phases.PhaseMetadata, def phases.InputDefinition, value any) {
+	shown := defaultString(value)
+	if def.Kind == phases.InputKindSecret || def.Secret {
+		shown = "[secret]"
+	}
+	m.recordTranscript(meta.ID, "input", fmt.Sprintf("%s = %s", def.Label, shown))
+}
+
+// writeTranscript renders the session into a timestamped file and returns its path.
+func (m *model) writeTranscript() (string, error) {
+	dir := m.transcriptDir
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("create transcript dir: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("# ansible-host-prep session transcript\n")
+	for _, entry := range m.transcript {
+		phaseID := entry.phaseID
+		if phaseID == "" {
+			phaseID = "-"
+		}
+		fmt.Fprintf(&b, "%s [%s] %s: %s\n",
+			entry.at.Format(time.RFC3339), phaseID, entry.kind, m.redactSecrets(entry.message))
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("bootstrap-transcript-%s.log", time.Now().Format(transcriptTimeLayout)))
+	if err := atomicfile.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return "", fmt.Errorf("write transcript: %w", err)
+	}
+	return path, nil
+}
+
+func (m *model) exportTranscript() {
+	path, err := m.writeTranscript()
+	if err != nil {
+		m.setStatusf("Transcript export failed — %v", err)
+		return
+	}
+	m.setStatusf("Transcript written to %s", path)
+}
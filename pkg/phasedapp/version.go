@@ -0,0 +1,122 @@
+package phasedapp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIVersion is the version of the HTTP and gRPC API served by Server. It is
+// bumped whenever a change would break clients written against the previous
+// version; additive changes keep it.
+const APIVersion = 1
+
+// MinAPIVersion is the oldest client API version the server still serves.
+const MinAPIVersion = 1
+
+// Headers (and, lower-cased, gRPC metadata keys) used to negotiate the API
+// version. Clients send APIVersionHeader with the version they speak; every
+// response carries the server's APIVersion and MinAPIVersion. A request
+// without the header is treated as API version 1, which predates
+// negotiation.
+const (
+	APIVersionHeader    = "Host-Prep-Api-Version"
+	MinAPIVersionHeader = "Host-Prep-Min-Api-Version"
+)
+
+// legacyAPIVersion is assumed for clients that do not send APIVersionHeader.
+const legacyAPIVersion = 1
+
+// VersionError reports a client whose API version falls outside the range a
+// server supports. It is returned both by the server, when it rejects a
+// request, and by CheckServerVersion on the client side.
+type VersionError struct {
+	Client int
+	Min    int
+	Max    int
+}
+
+func (e VersionError) Error() string {
+	supported := strconv.Itoa(e.Max)
+	if e.Min != e.Max {
+		supported = fmt.Sprintf("%d to %d", e.Min, e.Max)
+	}
+	upgrade := "upgrade the server"
+	if e.Client < e.Min {
+		upgrade = "upgrade the client"
+	}
+	return fmt.Sprintf("phasedapp: client speaks API version %d but the server supports %s; %s", e.Client, supported, upgrade)
+}
+
+// AcceptAPIVersion checks the API version a client sent in APIVersionHeader
+// against the versions this server supports. An empty value is accepted as
+// the legacy version.
+func AcceptAPIVersion(value string) error {
+	client := legacyAPIVersion
+	if value = strings.TrimSpace(value); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("phasedapp: invalid API version %q", value)
+		}
+		client = parsed
+	}
+	if client < MinAPIVersion || client > APIVersion {
+		return VersionError{Client: client, Min: MinAPIVersion, Max: APIVersion}
+	}
+	return nil
+}
+
+// CheckServerVersion is the client side of the negotiation: given the
+// headers of any API response, it reports a VersionError when the server
+// cannot serve this build's APIVersion. Servers that predate negotiation
+// send no headers and are treated as API version 1.
+func CheckServerVersion(header http.Header) error {
+	newest, err := headerVersion(header, APIVersionHeader, legacyAPIVersion)
+	if err != nil {
+		return err
+	}
+	oldest, err := headerVersion(header, MinAPIVersionHeader, newest)
+	if err != nil {
+		return err
+	}
+	if APIVersion < oldest || APIVersion > newest {
+		return VersionError{Client: APIVersion, Min: oldest, Max: newest}
+	}
+	return nil
+}
+
+func headerVersion(header http.Header, name string, fallback int) (int, error) {
+	value := strings.TrimSpace(header.Get(name))
+	if value == "" {
+		return fallback, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("phasedapp: invalid %s %q", name, value)
+	}
+	return version, nil
+}
+
+// negotiateVersion advertises the server's API versions on every response
+// and rejects clients that speak an unsupported one before they reach
+// authentication, so an outdated CLI gets a clear error instead of a
+// confusing failure halfway through a run.
+func negotiateVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, strconv.Itoa(APIVersion))
+		w.Header().Set(MinAPIVersionHeader, strconv.Itoa(MinAPIVersion))
+		if err := AcceptAPIVersion(r.Header.Get(APIVersionHeader)); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]int{
+		"api_version":     APIVersion,
+		"min_api_version": MinAPIVersion,
+	})
+}
@@ -0,0 +1,20 @@
+package phasedapp
+
+import (
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/pkg/phasedapp/observers/webhook"
+)
+
+// WithWebhook posts JSON events for phase start/complete and pipeline finish
+// to url so external systems can react to prep outcomes. Delivery failures
+// never fail the pipeline.
+func WithWebhook(url string, opts ...webhook.Option) Option {
+	return func(cfg *Config) {
+		if cfg == nil || strings.TrimSpace(url) == "" {
+			return
+		}
+		cfg.ManagerOptions = append(cfg.ManagerOptions, phases.WithObserver(webhook.New(strings.TrimSpace(url), opts...)))
+	}
+}
@@ -0,0 +1,179 @@
+// Package report records the outcome of a pipeline run as a structured
+// Report that notifiers can render as text or attach as JSON evidence.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/phases"
+	"github.com/BrianJOC/ansible-host-prep/phases/hostvars"
+	"github.com/BrianJOC/ansible-host-prep/phases/osdetect"
+)
+
+// Phase statuses recorded in a Report.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Report describes one pipeline run.
+type Report struct {
+	Host string `json:"host,omitempty"`
+	// Distro is the detected distribution and version, e.g. "ubuntu 24.04".
+	Distro     string            `json:"distro,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Success    bool              `json:"success"`
+	Error      string            `json:"error,omitempty"`
+	Phases     []PhaseResult     `json:"phases"`
+	HostVars   map[string]string `json:"host_vars,omitempty"`
+}
+
+// PhaseResult is the outcome of a single phase.
+type PhaseResult struct {
+	ID       string        `json:"id"`
+	Title    string        `json:"title"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// JSON returns the indented JSON form of the report.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Text renders a plain-text summary suitable for an email body.
+func (r *Report) Text() string {
+	var b strings.Builder
+	outcome := "succeeded"
+	if !r.Success {
+		outcome = "FAILED"
+	}
+	host := r.Host
+	if host == "" {
+		host = "(unknown host)"
+	}
+	fmt.Fprintf(&b, "Host prep for %s %s.\n\n", host, outcome)
+	fmt.Fprintf(&b, "Started:  %s\n", r.StartedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Finished: %s\n", r.FinishedAt.UTC().Format(time.RFC3339))
+	if r.Distro != "" {
+		fmt.Fprintf(&b, "Distro:   %s\n", r.Distro)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "Error:    %s\n", r.Error)
+	}
+
+	b.WriteString("\nPhases:\n")
+	for _, phase := range r.Phases {
+		fmt.Fprintf(&b, "  [%s] %s (%s)", phase.Status, phase.Title, phase.Duration.Round(time.Millisecond))
+		if phase.Error != "" {
+			fmt.Fprintf(&b, ": %s", phase.Error)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(r.HostVars) > 0 {
+		b.WriteString("\nHost variables:\n")
+		for _, v := range sortedVars(r.HostVars) {
+			fmt.Fprintf(&b, "  %s: %s\n", v, r.HostVars[v])
+		}
+	}
+	return b.String()
+}
+
+// Recorder is a phases.Observer that builds a Report. It resets once a run
+// finishes, so one Recorder can follow repeated runs.
+type Recorder struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	current Report
+	started map[string]time.Time
+	done    func(*Report)
+}
+
+// NewRecorder constructs a Recorder; done, if set, receives each finished report.
+func NewRecorder(done func(*Report)) *Recorder {
+	return &Recorder{now: time.Now, started: make(map[string]time.Time), done: done}
+}
+
+// PhaseStarted implements phases.Observer.
+func (r *Recorder) PhaseStarted(meta phases.PhaseMetadata) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if r.current.StartedAt.IsZero() {
+		r.current.StartedAt = now
+	}
+	r.started[meta.ID] = now
+}
+
+// PhaseCompleted implements phases.Observer.
+func (r *Recorder) PhaseCompleted(meta phases.PhaseMetadata, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := PhaseResult{ID: meta.ID, Title: meta.Title, Status: StatusSucceeded}
+	if started, ok := r.started[meta.ID]; ok {
+		result.Duration = r.now().Sub(started)
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	r.current.Phases = append(r.current.Phases, result)
+}
+
+// PipelineCompleted implements phases.PipelineObserver.
+func (r *Recorder) PipelineCompleted(phaseCtx *phases.Context, err error) {
+	r.mu.Lock()
+	rep := r.current
+	r.current = Report{}
+	r.started = make(map[string]time.Time)
+	r.mu.Unlock()
+
+	rep.FinishedAt = r.now()
+	if rep.StartedAt.IsZero() {
+		rep.StartedAt = rep.FinishedAt
+	}
+	rep.Success = err == nil
+	if err != nil {
+		rep.Error = err.Error()
+	}
+	if phaseCtx != nil {
+		rep.HostVars = make(map[string]string)
+		for _, v := range hostvars.Collect(phaseCtx) {
+			rep.HostVars[v.Name] = fmt.Sprint(v.Value)
+		}
+		rep.Host = rep.HostVars["ansible_host"]
+		rep.Distro = distro(phaseCtx)
+	}
+	if r.done != nil {
+		r.done(&rep)
+	}
+}
+
+func distro(phaseCtx *phases.Context) string {
+	var parts []string
+	for _, key := range []string{osdetect.ContextKeyDistro, osdetect.ContextKeyVersion} {
+		if val, ok := phaseCtx.Get(key); ok {
+			if str, ok := val.(string); ok && str != "" {
+				parts = append(parts, str)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedVars(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	// Registers the pure-Go "sqlite" driver, so no cgo toolchain is needed.
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps every key in one table of a SQLite database, a single
+// durable file that several processes on a host can share.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database at path.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, errors.New("storage: sqlite path is required")
+	}
+	// Writers from other processes are waited for instead of failing with
+	// SQLITE_BUSY; WAL lets readers proceed while one writes.
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open %s: %w", path, err)
+	}
+	const schema = `CREATE TABLE IF NOT EXISTS entries (
+		key        TEXT PRIMARY KEY,
+		value      BLOB NOT NULL,
+		updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("storage: create schema in %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Get implements Store.
+func (s *SQLiteStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM entries WHERE key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return data, err
+}
+
+// Put implements Store.
+func (s *SQLiteStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO entries (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`, key, data)
+	return err
+}
+
+// Delete implements Store.
+func (s *SQLiteStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM entries WHERE key = ?`, key)
+	return err
+}
+
+// List implements Store.
+func (s *SQLiteStore) List(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT key FROM entries WHERE substr(key, 1, length(?)) = ? ORDER BY key`, prefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Close implements Store.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,112 @@
+// Package storage keeps the tool's persistent records, such as run history
+// and state archives, behind a small key/value interface. Local files are
+// enough on a workstation; a prep service on shared infrastructure can use a
+// SQLite database or an S3-compatible bucket instead, so every instance sees
+// the same durable records.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ErrNotFound reports that no value is stored under a key.
+var ErrNotFound = errors.New("storage: not found")
+
+// Store is a flat key/value store. Keys are slash-separated paths such as
+// "history/20260310T143000Z-web01.json"; List returns those under a prefix
+// in lexical order, so time-stamped keys list oldest first.
+type Store interface {
+	// Get returns the value under key, or an error wrapping ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put replaces the value under key. Readers see the old or the new
+	// value, never part of one.
+	Put(ctx context.Context, key string, data []byte) error
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys starting with prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Close releases the store's resources.
+	Close() error
+}
+
+// KeyError reports a key the stores refuse, such as one escaping the store
+// with "..".
+type KeyError struct {
+	Key    string
+	Reason string
+}
+
+func (e KeyError) Error() string {
+	return fmt.Sprintf("storage: invalid key %q: %s", e.Key, e.Reason)
+}
+
+// checkKey accepts relative slash-separated keys whose segments are not
+// empty, ".", "..", or hidden; hidden names are left to the stores' own
+// temporary files.
+func checkKey(key string) error {
+	if key == "" {
+		return KeyError{Key: key, Reason: "empty"}
+	}
+	if strings.ContainsRune(key, '\\') || strings.ContainsRune(key, 0) {
+		return KeyError{Key: key, Reason: "contains a backslash or NUL"}
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || strings.HasPrefix(segment, ".") {
+			return KeyError{Key: key, Reason: "segments must be non-empty and not start with a dot"}
+		}
+	}
+	return nil
+}
+
+// Open returns the store a URL names:
+//
+//	/var/lib/host-prep or file:///var/lib/host-prep   files in a directory
+//	sqlite:///var/lib/host-prep/state.db               a SQLite database
+//	s3://bucket/prefix?endpoint=https://minio:9000     an S3-compatible bucket
+//
+// S3 credentials come from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN, and the region from the region parameter or AWS_REGION.
+func Open(rawURL string) (Store, error) {
+	if strings.TrimSpace(rawURL) == "" {
+		return nil, errors.New("storage: empty store URL")
+	}
+	if !strings.Contains(rawURL, "://") && !strings.HasPrefix(rawURL, "sqlite:") {
+		return NewFileStore(rawURL), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("storage: parse %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "file":
+		return NewFileStore(u.Path), nil
+	case "sqlite":
+		path := u.Path
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+		return OpenSQLite(path)
+	case "s3":
+		query := u.Query()
+		cfg := S3Config{
+			Endpoint:     query.Get("endpoint"),
+			Bucket:       u.Host,
+			Prefix:       strings.TrimPrefix(u.Path, "/"),
+			Region:       query.Get("region"),
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		if cfg.Region == "" {
+			cfg.Region = os.Getenv("AWS_REGION")
+		}
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("storage: unsupported store URL scheme %q: expected file, sqlite, or s3", u.Scheme)
+	}
+}
@@ -0,0 +1,253 @@
+package ansibleadhoc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apenella/go-ansible/pkg/adhoc"
+	"github.com/apenella/go-ansible/pkg/execute"
+	"github.com/apenella/go-ansible/pkg/options"
+)
+
+const (
+	becomeMethod = "sudo"
+	becomeUser   = "root"
+)
+
+// Common modules for quick verification.
+const (
+	ModulePing  = "ping"
+	ModuleSetup = "setup"
+	ModuleShell = "shell"
+)
+
+// RunRequest captures the minimum information required to run a module.
+type RunRequest struct {
+	User           string
+	Target         string
+	Module         string
+	Args           string
+	PrivateKeyPath string
+}
+
+// Option configures how the ansible command is built or executed.
+type Option func(*runConfig) error
+
+type runConfig struct {
+	stdout          io.Writer
+	stderr          io.Writer
+	env             map[string]string
+	executorFactory func(...execute.ExecuteOptions) execute.Executor
+	binary          string
+	become          bool
+}
+
+// ValidationError indicates an invalid or missing user-supplied value.
+type ValidationError struct {
+	Field string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("ansibleadhoc: %s is required", e.Field)
+}
+
+// WithStdout overrides where ansible stdout is written (default io.Discard).
+func WithStdout(w io.Writer) Option {
+	return func(cfg *runConfig) error {
+		cfg.stdout = w
+		return nil
+	}
+}
+
+// WithStderr overrides where ansible stderr is written (default io.Discard).
+func WithStderr(w io.Writer) Option {
+	return func(cfg *runConfig) error {
+		cfg.stderr = w
+		return nil
+	}
+}
+
+// WithEnvVars merges the provided environment variables into the ansible process environment.
+func WithEnvVars(env map[string]string) Option {
+	return func(cfg *runConfig) error {
+		if len(env) == 0 {
+			return nil
+		}
+		if cfg.env == nil {
+			cfg.env = make(map[string]string, len(env))
+		}
+		for k, v := range env {
+			cfg.env[k] = v
+		}
+		return nil
+	}
+}
+
+// WithEnvVar adds a single environment variable to the ansible process environment.
+func WithEnvVar(key, value string) Option {
+	return func(cfg *runConfig) error {
+		if cfg.env == nil {
+			cfg.env = make(map[string]string, 1)
+		}
+		cfg.env[key] = value
+		return nil
+	}
+}
+
+// WithExecutorFactory swaps the execute.Executor constructor (useful for tests).
+func WithExecutorFactory(factory func(...execute.ExecuteOptions) execute.Executor) Option {
+	return func(cfg *runConfig) error {
+		if factory == nil {
+			return fmt.Errorf("executor factory must not be nil")
+		}
+		cfg.executorFactory = factory
+		return nil
+	}
+}
+
+// WithBinary overrides the ansible binary path used to run the command.
+func WithBinary(path string) Option {
+	return func(cfg *runConfig) error {
+		cfg.binary = strings.TrimSpace(path)
+		return nil
+	}
+}
+
+// WithBecome runs the module as root via sudo (default: as the connecting user).
+func WithBecome() Option {
+	return func(cfg *runConfig) error {
+		cfg.become = true
+		return nil
+	}
+}
+
+// Run builds and executes an ansible ad-hoc command for the provided request.
+func Run(ctx context.Context, req RunRequest, opts ...Option) error {
+	cmd, err := BuildCommand(req, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Run(ctx); err != nil {
+		return fmt.Errorf("ansibleadhoc: run %s: %w", cmd.Options.ModuleName, err)
+	}
+
+	return nil
+}
+
+// BuildCommand constructs a configured ansible ad-hoc command without executing it.
+func BuildCommand(req RunRequest, opts ...Option) (*adhoc.AnsibleAdhocCmd, error) {
+	cfg, err := buildConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	norm, err := normalizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &adhoc.AnsibleAdhocCmd{
+		Pattern: norm.Target,
+		Options: &adhoc.AnsibleAdhocOptions{
+			Inventory:  inlineInventory(norm.Target),
+			ModuleName: norm.Module,
+			Args:       norm.Args,
+		},
+		ConnectionOptions: &options.AnsibleConnectionOptions{
+			User:       norm.User,
+			PrivateKey: norm.PrivateKeyPath,
+		},
+		Exec: cfg.executorFactory(buildExecutorOptions(cfg)...),
+	}
+
+	if cfg.become {
+		cmd.PrivilegeEscalationOptions = &options.AnsiblePrivilegeEscalationOptions{
+			Become:       true,
+			BecomeMethod: becomeMethod,
+			BecomeUser:   becomeUser,
+		}
+	}
+
+	if cfg.binary != "" {
+		cmd.Binary = cfg.binary
+	}
+
+	return cmd, nil
+}
+
+func normalizeRequest(req RunRequest) (RunRequest, error) {
+	norm := RunRequest{
+		User:           strings.TrimSpace(req.User),
+		Target:         strings.TrimSpace(req.Target),
+		Module:         strings.TrimSpace(req.Module),
+		Args:           strings.TrimSpace(req.Args),
+		PrivateKeyPath: strings.TrimSpace(req.PrivateKeyPath),
+	}
+
+	switch {
+	case norm.User == "":
+		return RunRequest{}, ValidationError{Field: "user"}
+	case norm.Target == "":
+		return RunRequest{}, ValidationError{Field: "target"}
+	case norm.Module == "":
+		return RunRequest{}, ValidationError{Field: "module"}
+	case norm.Module == ModuleShell && norm.Args == "":
+		return RunRequest{}, ValidationError{Field: "shell command"}
+	case norm.PrivateKeyPath == "":
+		return RunRequest{}, ValidationError{Field: "private key path"}
+	}
+
+	return norm, nil
+}
+
+func buildConfig(opts ...Option) (*runConfig, error) {
+	cfg := &runConfig{
+		stdout: io.Discard,
+		stderr: io.Discard,
+		env: map[string]string{
+			options.AnsibleHostKeyCheckingEnv: "false",
+		},
+		executorFactory: func(options ...execute.ExecuteOptions) execute.Executor {
+			return execute.NewDefaultExecute(options...)
+		},
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+func inlineInventory(target string) string {
+	if strings.HasSuffix(target, ",") {
+		return target
+	}
+	return target + ","
+}
+
+func buildExecutorOptions(cfg *runConfig) []execute.ExecuteOptions {
+	var execOpts []execute.ExecuteOptions
+
+	if cfg.stdout != nil {
+		execOpts = append(execOpts, execute.WithWrite(cfg.stdout))
+	}
+
+	if cfg.stderr != nil {
+		execOpts = append(execOpts, execute.WithWriteError(cfg.stderr))
+	}
+
+	for key, value := range cfg.env {
+		execOpts = append(execOpts, execute.WithEnvVar(key, value))
+	}
+
+	return execOpts
+}
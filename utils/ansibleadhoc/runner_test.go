@@ -0,0 +1,91 @@
+package ansibleadhoc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/apenella/go-ansible/pkg/execute"
+	"github.com/apenella/go-ansible/pkg/options"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommandValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		req   RunRequest
+		field string
+	}{
+		{name: "missing user", req: RunRequest{}, field: "user"},
+		{name: "missing module", req: RunRequest{User: "ansible", Target: "10.0.0.5"}, field: "module"},
+		{name: "shell without command", req: RunRequest{User: "ansible", Target: "10.0.0.5", Module: ModuleShell}, field: "shell command"},
+		{name: "missing key", req: RunRequest{User: "ansible", Target: "10.0.0.5", Module: ModulePing}, field: "private key path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := BuildCommand(tt.req)
+			var valErr ValidationError
+			require.ErrorAs(t, err, &valErr)
+			require.Equal(t, tt.field, valErr.Field)
+		})
+	}
+}
+
+func TestBuildCommandPopulatesCommand(t *testing.T) {
+	t.Parallel()
+
+	stdout := &bytes.Buffer{}
+
+	cmd, err := BuildCommand(
+		RunRequest{
+			User:           " ansible ",
+			Target:         "10.0.0.5 ",
+			Module:         ModuleShell,
+			Args:           " uptime ",
+			PrivateKeyPath: "/tmp/id_ansible ",
+		},
+		WithStdout(stdout),
+		WithBecome(),
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, "10.0.0.5", cmd.Pattern)
+	require.Equal(t, "10.0.0.5,", cmd.Options.Inventory)
+	require.Equal(t, ModuleShell, cmd.Options.ModuleName)
+	require.Equal(t, "uptime", cmd.Options.Args)
+	require.Equal(t, "ansible", cmd.ConnectionOptions.User)
+	require.Equal(t, "/tmp/id_ansible", cmd.ConnectionOptions.PrivateKey)
+	require.True(t, cmd.PrivilegeEscalationOptions.Become)
+	require.Equal(t, becomeMethod, cmd.PrivilegeEscalationOptions.BecomeMethod)
+
+	exec, ok := cmd.Exec.(*execute.DefaultExecute)
+	require.True(t, ok)
+	require.Equal(t, stdout, exec.Write)
+	require.Equal(t, "false", exec.EnvVars[options.AnsibleHostKeyCheckingEnv])
+}
+
+func TestBuildCommandWithoutBecome(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := BuildCommand(RunRequest{User: "ansible", Target: "10.0.0.5", Module: ModulePing, PrivateKeyPath: "/tmp/id"})
+	require.NoError(t, err)
+	require.Nil(t, cmd.PrivilegeEscalationOptions)
+}
+
+func TestRunWithCustomBinary(t *testing.T) {
+	t.Parallel()
+
+	req := RunRequest{
+		User:           "ansible",
+		Target:         "10.0.0.5",
+		Module:         ModulePing,
+		PrivateKeyPath: "/tmp/id_ansible",
+	}
+
+	err := Run(context.Background(), req, WithBinary("/usr/bin/true"))
+	require.NoError(t, err)
+}
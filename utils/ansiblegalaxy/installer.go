@@ -0,0 +1,185 @@
+// Package ansiblegalaxy installs roles and collections on the controller with
+// `ansible-galaxy install -r <requirements>`.
+package ansiblegalaxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+const defaultBinary = "ansible-galaxy"
+
+// InstallRequest describes one requirements install.
+type InstallRequest struct {
+	RequirementsPath string
+	// RolesPath and CollectionsPath override where content is installed;
+	// empty uses the ansible.cfg / default locations.
+	RolesPath       string
+	CollectionsPath string
+	// Force reinstalls content that is already present.
+	Force bool
+}
+
+// Result lists what ansible-galaxy reported, as "name (version)" for roles
+// and "namespace.name:version" for collections.
+type Result struct {
+	RequirementsPath string
+	Installed        []string
+	AlreadyInstalled []string
+}
+
+// Option configures how ansible-galaxy is executed.
+type Option func(*runConfig) error
+
+type runConfig struct {
+	stdout io.Writer
+	stderr io.Writer
+	env    map[string]string
+	binary string
+}
+
+// ValidationError indicates an invalid or missing user-supplied value.
+type ValidationError struct {
+	Field string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("ansiblegalaxy: %s is required", e.Field)
+}
+
+// WithStdout overrides where ansible-galaxy stdout is written (default io.Discard).
+func WithStdout(w io.Writer) Option {
+	return func(cfg *runConfig) error {
+		cfg.stdout = w
+		return nil
+	}
+}
+
+// WithStderr overrides where ansible-galaxy stderr is written (default io.Discard).
+func WithStderr(w io.Writer) Option {
+	return func(cfg *runConfig) error {
+		cfg.stderr = w
+		return nil
+	}
+}
+
+// WithEnvVars merges the provided environment variables into the process environment.
+func WithEnvVars(env map[string]string) Option {
+	return func(cfg *runConfig) error {
+		for k, v := range env {
+			cfg.env[k] = v
+		}
+		return nil
+	}
+}
+
+// WithEnvVar adds a single environment variable to the process environment.
+func WithEnvVar(key, value string) Option {
+	return func(cfg *runConfig) error {
+		cfg.env[key] = value
+		return nil
+	}
+}
+
+// WithBinary overrides the ansible-galaxy binary path.
+func WithBinary(path string) Option {
+	return func(cfg *runConfig) error {
+		cfg.binary = strings.TrimSpace(path)
+		return nil
+	}
+}
+
+// Install runs ansible-galaxy for req and parses what it installed.
+func Install(ctx context.Context, req InstallRequest, opts ...Option) (*Result, error) {
+	cmd, err := BuildCommand(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var captured bytes.Buffer
+	cmd.Stdout = io.MultiWriter(cmd.Stdout, &captured)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ansiblegalaxy: install %s: %w", req.RequirementsPath, err)
+	}
+
+	res := parseOutput(&captured)
+	res.RequirementsPath = strings.TrimSpace(req.RequirementsPath)
+	return res, nil
+}
+
+// BuildCommand constructs the ansible-galaxy command without running it.
+func BuildCommand(ctx context.Context, req InstallRequest, opts ...Option) (*exec.Cmd, error) {
+	cfg := &runConfig{
+		stdout: io.Discard,
+		stderr: io.Discard,
+		env:    map[string]string{},
+		binary: defaultBinary,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.binary == "" {
+		cfg.binary = defaultBinary
+	}
+
+	path := strings.TrimSpace(req.RequirementsPath)
+	if path == "" {
+		return nil, ValidationError{Field: "requirements path"}
+	}
+
+	args := []string{"install", "-r", path}
+	if roles := strings.TrimSpace(req.RolesPath); roles != "" {
+		args = append(args, "--roles-path", roles)
+	}
+	if collections := strings.TrimSpace(req.CollectionsPath); collections != "" {
+		args = append(args, "--collections-path", collections)
+	}
+	if req.Force {
+		args = append(args, "--force")
+	}
+
+	cmd := exec.CommandContext(ctx, cfg.binary, args...)
+	cmd.Stdout = cfg.stdout
+	cmd.Stderr = cfg.stderr
+	if len(cfg.env) > 0 {
+		keys := make([]string, 0, len(cfg.env))
+		for k := range cfg.env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+cfg.env[k])
+		}
+	}
+	return cmd, nil
+}
+
+// parseOutput picks the "<name> was installed successfully" and "<name> is
+// already installed, skipping." lines out of ansible-galaxy output; role lines
+// carry a leading "- ", collection lines do not.
+func parseOutput(r io.Reader) *Result {
+	res := &Result{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "- ")
+		if name, ok := strings.CutSuffix(line, " was installed successfully"); ok {
+			res.Installed = append(res.Installed, name)
+		} else if name, ok := strings.CutSuffix(line, " is already installed, skipping."); ok {
+			res.AlreadyInstalled = append(res.AlreadyInstalled, name)
+		}
+	}
+	return res
+}
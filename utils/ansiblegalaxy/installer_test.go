@@ -0,0 +1,61 @@
+package ansiblegalaxy
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	_, err := BuildCommand(context.Background(), InstallRequest{})
+	require.ErrorAs(t, err, &ValidationError{})
+
+	cmd, err := BuildCommand(context.Background(), InstallRequest{
+		RequirementsPath: " requirements.yml ",
+		RolesPath:        "roles",
+		CollectionsPath:  "collections",
+		Force:            true,
+	}, WithEnvVar("ANSIBLE_NOCOLOR", "1"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"ansible-galaxy", "install", "-r", "requirements.yml",
+		"--roles-path", "roles", "--collections-path", "collections", "--force",
+	}, cmd.Args)
+	require.Contains(t, cmd.Env, "ANSIBLE_NOCOLOR=1")
+}
+
+func TestInstallParsesOutput(t *testing.T) {
+	t.Parallel()
+
+	script := filepath.Join(t.TempDir(), "ansible-galaxy")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "Starting galaxy role install process"
+echo "- geerlingguy.docker (7.4.1) was installed successfully"
+echo "- geerlingguy.pip (3.0.0) is already installed, skipping."
+echo "community.general:9.0.0 was installed successfully"
+`), 0o755))
+
+	var stdout bytes.Buffer
+	res, err := Install(context.Background(), InstallRequest{RequirementsPath: "requirements.yml"},
+		WithBinary(script), WithStdout(&stdout))
+	require.NoError(t, err)
+	require.Equal(t, &Result{
+		RequirementsPath: "requirements.yml",
+		Installed:        []string{"geerlingguy.docker (7.4.1)", "community.general:9.0.0"},
+		AlreadyInstalled: []string{"geerlingguy.pip (3.0.0)"},
+	}, res)
+	require.Contains(t, stdout.String(), "Starting galaxy role install")
+}
+
+func TestInstallReportsFailure(t *testing.T) {
+	t.Parallel()
+
+	_, err := Install(context.Background(), InstallRequest{RequirementsPath: "requirements.yml"}, WithBinary("/usr/bin/false"))
+	require.ErrorContains(t, err, "ansiblegalaxy: install requirements.yml")
+}
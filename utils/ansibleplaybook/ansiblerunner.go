@@ -0,0 +1,279 @@
+package ansibleplaybook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+const (
+	defaultRunnerBinary       = "ansible-runner"
+	defaultRunnerPollInterval = 250 * time.Millisecond
+)
+
+// Event is one entry from ansible-runner's artifacts/<ident>/job_events
+// directory, e.g. playbook_on_task_start, runner_on_ok, or runner_on_failed.
+type Event struct {
+	Counter int       `json:"counter"`
+	UUID    string    `json:"uuid"`
+	Type    string    `json:"event"`
+	Stdout  string    `json:"stdout"`
+	Created string    `json:"created"`
+	Data    EventData `json:"event_data"`
+}
+
+// EventData holds the per-task fields of an Event; Res is the raw module
+// result for runner_on_* events.
+type EventData struct {
+	Play       string          `json:"play"`
+	Task       string          `json:"task"`
+	TaskAction string          `json:"task_action"`
+	Host       string          `json:"host"`
+	Changed    bool            `json:"changed"`
+	Res        json.RawMessage `json:"res,omitempty"`
+}
+
+// Failed reports whether the event records a failed or unreachable task.
+func (e Event) Failed() bool {
+	return e.Type == "runner_on_failed" || e.Type == "runner_on_unreachable"
+}
+
+// AnsibleRunner is a Backend that runs playbooks through ansible-runner's
+// private data directory protocol and reports each job event as it appears.
+type AnsibleRunner struct {
+	binary         string
+	privateDataDir string
+	onEvent        func(Event)
+	pollInterval   time.Duration
+}
+
+// RunnerOption customizes an AnsibleRunner.
+type RunnerOption func(*AnsibleRunner)
+
+// WithRunnerBinary overrides the ansible-runner binary path.
+func WithRunnerBinary(path string) RunnerOption {
+	return func(r *AnsibleRunner) {
+		if path = strings.TrimSpace(path); path != "" {
+			r.binary = path
+		}
+	}
+}
+
+// WithPrivateDataDir keeps inputs and artifacts in dir instead of a temporary
+// directory that is removed after the run.
+func WithPrivateDataDir(dir string) RunnerOption {
+	return func(r *AnsibleRunner) {
+		r.privateDataDir = strings.TrimSpace(dir)
+	}
+}
+
+// WithEventHandler receives every job event in counter order.
+func WithEventHandler(fn func(Event)) RunnerOption {
+	return func(r *AnsibleRunner) {
+		r.onEvent = fn
+	}
+}
+
+// WithEventPollInterval overrides how often job_events is scanned (default 250ms).
+func WithEventPollInterval(interval time.Duration) RunnerOption {
+	return func(r *AnsibleRunner) {
+		if interval > 0 {
+			r.pollInterval = interval
+		}
+	}
+}
+
+// NewAnsibleRunner constructs the ansible-runner backend.
+func NewAnsibleRunner(opts ...RunnerOption) *AnsibleRunner {
+	r := &AnsibleRunner{
+		binary:       defaultRunnerBinary,
+		pollInterval: defaultRunnerPollInterval,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// Run implements Backend.
+func (r *AnsibleRunner) Run(ctx context.Context, req RunRequest, env Environment) error {
+	dir := r.privateDataDir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "ansible-runner-")
+		if err != nil {
+			return fmt.Errorf("ansible-runner: create private data dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	playbookPath, err := filepath.Abs(req.PlaybookPath)
+	if err != nil {
+		return fmt.Errorf("ansible-runner: resolve playbook: %w", err)
+	}
+	if err := writePrivateData(dir, req, env); err != nil {
+		return err
+	}
+
+	ident := "host-prep-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	cmd := exec.CommandContext(ctx, r.binary, "run", dir, "-p", playbookPath, "--ident", ident)
+	cmd.Stdout = env.Stdout
+	cmd.Stderr = env.Stderr
+	if env.CleanEnv {
+		cmd.Env = cleanEnviron(nil)
+	}
+	stopGroup := stopGroupOnCancel(ctx, cmd)
+
+	events := &eventTail{dir: filepath.Join(dir, "artifacts", ident, "job_events"), seen: map[string]bool{}}
+	onEvent := r.eventHandler(env.OnTask)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if onEvent != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(r.pollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					events.deliver(onEvent)
+				}
+			}
+		}()
+	}
+
+	runErr := cmd.Run()
+	stopGroup()
+	close(done)
+	wg.Wait()
+	if onEvent != nil {
+		events.deliver(onEvent)
+	}
+	if runErr != nil {
+		return fmt.Errorf("ansible-runner: %w", runErr)
+	}
+	return nil
+}
+
+// eventHandler combines the configured event handler with per-task
+// progress reporting; it returns nil when neither is wanted.
+func (r *AnsibleRunner) eventHandler(onTask func(TaskEvent)) func(Event) {
+	if onTask == nil {
+		return r.onEvent
+	}
+	return func(event Event) {
+		if r.onEvent != nil {
+			r.onEvent(event)
+		}
+		if taskEvent, ok := taskEventFromJob(event); ok {
+			onTask(taskEvent)
+		}
+	}
+}
+
+// writePrivateData lays out the inventory, env vars, and ansible-playbook
+// arguments ansible-runner reads from its private data directory.
+func writePrivateData(dir string, req RunRequest, env Environment) error {
+	envVars, err := json.Marshal(env.Vars)
+	if err != nil {
+		return fmt.Errorf("ansible-runner: encode env vars: %w", err)
+	}
+	args := []string{
+		"--limit", shellquote.Quote(req.Target),
+		"--user", shellquote.Quote(req.User),
+		"--private-key", shellquote.Quote(req.PrivateKeyPath),
+		"--become", "--become-method", becomeMethod, "--become-user", becomeUser,
+	}
+	if flag := VerbosityFlag(env.Verbosity); flag != "" {
+		args = append(args, flag)
+	}
+	if env.Forks > 0 {
+		args = append(args, "--forks", strconv.Itoa(env.Forks))
+	}
+	if env.Timeout > 0 {
+		args = append(args, "--timeout", strconv.Itoa(env.Timeout))
+	}
+	if env.SSHCommonArgs != "" {
+		args = append(args, "--ssh-common-args", shellquote.Quote(env.SSHCommonArgs))
+	}
+	if env.SSHExtraArgs != "" {
+		args = append(args, "--ssh-extra-args", shellquote.Quote(env.SSHExtraArgs))
+	}
+
+	files := map[string]string{
+		filepath.Join("env", "envvars"): string(envVars),
+	}
+	if req.InventoryPath != "" {
+		inventory, err := filepath.Abs(req.InventoryPath)
+		if err != nil {
+			return fmt.Errorf("ansible-runner: resolve inventory: %w", err)
+		}
+		args = append(args, "--inventory", shellquote.Quote(inventory))
+	} else {
+		files[filepath.Join("inventory", "hosts")] = req.Target + "\n"
+	}
+	files[filepath.Join("env", "cmdline")] = strings.Join(args, " ")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return fmt.Errorf("ansible-runner: create %s: %w", filepath.Dir(path), err)
+		}
+		if err := atomicfile.WriteFile(path, []byte(content), 0o600); err != nil {
+			return fmt.Errorf("ansible-runner: write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// eventTail tracks which job event files have been delivered.
+type eventTail struct {
+	dir  string
+	seen map[string]bool
+}
+
+// deliver passes events not yet seen to fn in counter order. Files that
+// ansible-runner is still writing (-partial.json) or that fail to decode are
+// left for the next scan.
+func (t *eventTail) deliver(fn func(Event)) {
+	entries, err := os.ReadDir(t.dir)
+	if err != nil {
+		return
+	}
+	var fresh []Event
+	for _, entry := range entries {
+		name := entry.Name()
+		if t.seen[name] || !strings.HasSuffix(name, ".json") || strings.HasSuffix(name, "-partial.json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(t.dir, name))
+		if err != nil {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			continue
+		}
+		t.seen[name] = true
+		fresh = append(fresh, event)
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].Counter < fresh[j].Counter })
+	for _, event := range fresh {
+		fn(event)
+	}
+}
@@ -0,0 +1,109 @@
+package ansibleplaybook
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner emulates `ansible-runner run DIR -p PLAYBOOK --ident ID` by
+// echoing the playbook and writing job events out of counter order.
+const fakeRunner = `#!/bin/sh
+dir=$2; playbook=$4; ident=$6
+echo "playbook=$playbook"
+events="$dir/artifacts/$ident/job_events"
+mkdir -p "$events"
+echo '{"counter": 10, "event": "runner_on_failed", "event_data": {"task": "Install nginx", "host": "10.0.0.5", "res": {"msg": "No package nginx"}}}' > "$events/10-b.json"
+echo '{"counter": 2, "event": "playbook_on_task_start", "event_data": {"task": "Install nginx"}}' > "$events/2-a.json"
+echo '{"counter": 11}' > "$events/11-c-partial.json"
+`
+
+func TestAnsibleRunnerBackend(t *testing.T) {
+	t.Parallel()
+
+	bin := filepath.Join(t.TempDir(), "ansible-runner")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeRunner), 0o755))
+	dataDir := t.TempDir()
+
+	var events []Event
+	backend := NewAnsibleRunner(
+		WithRunnerBinary(bin),
+		WithPrivateDataDir(dataDir),
+		WithEventHandler(func(e Event) { events = append(events, e) }),
+	)
+
+	var stdout bytes.Buffer
+	err := Run(context.Background(), RunRequest{
+		User:           "ansible",
+		Target:         "10.0.0.5",
+		PlaybookPath:   "/srv/site.yml",
+		PrivateKeyPath: "/tmp/id ansible",
+	}, WithBackend(backend), WithStdout(&stdout), WithEnvVar("ANSIBLE_FORCE_COLOR", "0"), WithVerbosity(2))
+	require.NoError(t, err)
+	require.Equal(t, "playbook=/srv/site.yml\n", stdout.String())
+
+	require.Len(t, events, 2)
+	require.Equal(t, "playbook_on_task_start", events[0].Type)
+	require.True(t, events[1].Failed())
+	require.Equal(t, "10.0.0.5", events[1].Data.Host)
+
+	cmdline, err := os.ReadFile(filepath.Join(dataDir, "env", "cmdline"))
+	require.NoError(t, err)
+	require.Contains(t, string(cmdline), "--private-key '/tmp/id ansible'")
+	require.Contains(t, string(cmdline), "--become-user root -vv")
+	envvars, err := os.ReadFile(filepath.Join(dataDir, "env", "envvars"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"ANSIBLE_FORCE_COLOR": "0", "ANSIBLE_HOST_KEY_CHECKING": "false"}`, string(envvars))
+	inventory, err := os.ReadFile(filepath.Join(dataDir, "inventory", "hosts"))
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.5\n", string(inventory))
+}
+
+func TestAnsibleRunnerBackendReportsTasks(t *testing.T) {
+	t.Parallel()
+
+	bin := filepath.Join(t.TempDir(), "ansible-runner")
+	require.NoError(t, os.WriteFile(bin, []byte(fakeRunner), 0o755))
+
+	var tasks []TaskEvent
+	err := Run(context.Background(), RunRequest{
+		User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id",
+	}, WithBackend(NewAnsibleRunner(WithRunnerBinary(bin))), WithTaskEvents(func(e TaskEvent) { tasks = append(tasks, e) }))
+	require.NoError(t, err)
+	require.Equal(t, []TaskEvent{
+		{Task: "Install nginx", Status: TaskStarted},
+		{Task: "Install nginx", Host: "10.0.0.5", Status: TaskFailed, Message: "No package nginx"},
+	}, tasks)
+}
+
+func TestAnsibleRunnerBackendUsesInventoryFile(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	err := Run(context.Background(), RunRequest{
+		User: "ansible", Target: "web01", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id",
+		InventoryPath: "/srv/infra/inventory/production.ini",
+	}, WithBackend(NewAnsibleRunner(WithRunnerBinary("/usr/bin/true"), WithPrivateDataDir(dataDir))),
+		WithForks(20), WithTimeout(45), WithSSHCommonArgs("-o ProxyJump=bastion"))
+	require.NoError(t, err)
+
+	cmdline, err := os.ReadFile(filepath.Join(dataDir, "env", "cmdline"))
+	require.NoError(t, err)
+	require.Contains(t, string(cmdline), "--limit 'web01'")
+	require.Contains(t, string(cmdline), "--inventory '/srv/infra/inventory/production.ini'")
+	require.Contains(t, string(cmdline), "--forks 20 --timeout 45 --ssh-common-args '-o ProxyJump=bastion'")
+	require.NoFileExists(t, filepath.Join(dataDir, "inventory", "hosts"))
+}
+
+func TestAnsibleRunnerBackendFailure(t *testing.T) {
+	t.Parallel()
+
+	err := Run(context.Background(), RunRequest{
+		User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id",
+	}, WithBackend(NewAnsibleRunner(WithRunnerBinary("/usr/bin/false"))))
+	require.ErrorContains(t, err, "ansibleplaybook: run playbook: ansible-runner: exit status 1")
+}
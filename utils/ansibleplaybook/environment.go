@@ -0,0 +1,130 @@
+package ansibleplaybook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/apenella/go-ansible/pkg/execute"
+	"github.com/apenella/go-ansible/pkg/stdoutcallback"
+)
+
+const (
+	// CollectionsPathEnv and RolesPathEnv list the directories ansible
+	// searches for collections and roles.
+	CollectionsPathEnv = "ANSIBLE_COLLECTIONS_PATH"
+	RolesPathEnv       = "ANSIBLE_ROLES_PATH"
+)
+
+// cleanEnvKeep are the operator's variables a clean environment still
+// inherits, because ansible cannot find ssh or its local temp directory
+// without them. WithEnvVar overrides them like any other variable.
+var cleanEnvKeep = []string{"PATH", "HOME"}
+
+// WithCollectionsPath makes ansible load collections only from dirs
+// (ANSIBLE_COLLECTIONS_PATH), e.g. a collections directory checked in next
+// to the playbook, instead of whatever the operator installed. Relative
+// directories are resolved against the working directory.
+func WithCollectionsPath(dirs ...string) Option {
+	return func(cfg *runConfig) error {
+		return setSearchPath(cfg, CollectionsPathEnv, "collections path", dirs)
+	}
+}
+
+// WithRolesPath makes ansible load roles only from dirs
+// (ANSIBLE_ROLES_PATH). Relative directories are resolved against the
+// working directory.
+func WithRolesPath(dirs ...string) Option {
+	return func(cfg *runConfig) error {
+		return setSearchPath(cfg, RolesPathEnv, "roles path", dirs)
+	}
+}
+
+func setSearchPath(cfg *runConfig, key, field string, dirs []string) error {
+	var abs []string
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if strings.Contains(dir, string(os.PathListSeparator)) {
+			return fmt.Errorf("%s entry %q must not contain %q", field, dir, os.PathListSeparator)
+		}
+		path, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("%s entry %q: %w", field, dir, err)
+		}
+		abs = append(abs, path)
+	}
+	if len(abs) == 0 {
+		return fmt.Errorf("%s must list at least one directory", field)
+	}
+	if cfg.env == nil {
+		cfg.env = make(map[string]string, 1)
+	}
+	cfg.env[key] = strings.Join(abs, string(os.PathListSeparator))
+	return nil
+}
+
+// WithCleanEnv runs ansible with only the variables set through options
+// (WithEnvVar, WithCollectionsPath, ...) plus PATH and HOME, so ANSIBLE_*
+// settings, proxies, or a virtualenv in the operator's shell cannot change
+// the run. Both backends honor it; with ansible-runner it applies to the
+// ansible-runner process, which passes its own variables on to ansible.
+func WithCleanEnv() Option {
+	return func(cfg *runConfig) error {
+		cfg.cleanEnv = true
+		return nil
+	}
+}
+
+// cleanEnviron returns vars plus the kept operator variables as KEY=value
+// pairs, sorted for stable command lines.
+func cleanEnviron(vars map[string]string) []string {
+	env := make(map[string]string, len(vars)+len(cleanEnvKeep))
+	for _, key := range cleanEnvKeep {
+		if value, ok := os.LookupEnv(key); ok {
+			env[key] = value
+		}
+	}
+	for key, value := range vars {
+		env[key] = value
+	}
+	pairs := make([]string, 0, len(env))
+	for key, value := range env {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return pairs
+}
+
+// cleanEnvExecutor runs the command through `env -i`, because go-ansible's
+// executor always starts from the operator's environment. The binary is
+// resolved on the operator's PATH first, so it is found even when the clean
+// PATH differs.
+type cleanEnvExecutor struct {
+	inner execute.Executor
+	vars  map[string]string
+}
+
+func (e cleanEnvExecutor) Execute(ctx context.Context, command []string, resultsFunc stdoutcallback.StdoutCallbackResultsFunc, opts ...execute.ExecuteOptions) error {
+	if len(command) == 0 {
+		return fmt.Errorf("ansibleplaybook: empty command")
+	}
+	binary, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("ansibleplaybook: %w", err)
+	}
+	envBinary, err := exec.LookPath("env")
+	if err != nil {
+		return fmt.Errorf("ansibleplaybook: clean environment needs env: %w", err)
+	}
+	wrapped := append([]string{envBinary, "-i"}, cleanEnviron(e.vars)...)
+	wrapped = append(wrapped, binary)
+	wrapped = append(wrapped, command[1:]...)
+	return e.inner.Execute(ctx, wrapped, resultsFunc, opts...)
+}
@@ -0,0 +1,71 @@
+package ansibleplaybook
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchPathOptions(t *testing.T) {
+	t.Parallel()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	cfg, err := buildConfig(
+		WithCollectionsPath(" collections ", "", "/usr/share/ansible/collections"),
+		WithRolesPath("/srv/roles"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(wd, "collections")+":/usr/share/ansible/collections", cfg.env[CollectionsPathEnv])
+	require.Equal(t, "/srv/roles", cfg.env[RolesPathEnv])
+
+	for _, opt := range []Option{WithCollectionsPath(), WithRolesPath(" "), WithRolesPath("/a:/b")} {
+		_, err := buildConfig(opt)
+		require.Error(t, err)
+	}
+}
+
+func TestRunWithCleanEnv(t *testing.T) {
+	t.Setenv("ANSIBLE_CONFIG", "/home/ops/.ansible.cfg")
+
+	script := filepath.Join(t.TempDir(), "ansible-playbook")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nenv\n"), 0o700))
+	req := RunRequest{User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id"}
+
+	var stdout bytes.Buffer
+	require.NoError(t, Run(context.Background(), req,
+		WithBinary(script),
+		WithStdout(&stdout),
+		WithCleanEnv(),
+		WithRolesPath("/srv/roles"),
+		WithEnvVar("HOME", "/var/lib/ansible"),
+	))
+	env := stdout.String()
+	require.Contains(t, env, "ANSIBLE_HOST_KEY_CHECKING=false\n")
+	require.Contains(t, env, RolesPathEnv+"=/srv/roles\n")
+	require.Contains(t, env, "HOME=/var/lib/ansible\n")
+	require.Contains(t, env, "PATH="+os.Getenv("PATH")+"\n")
+	require.NotContains(t, env, "ANSIBLE_CONFIG")
+
+	stdout.Reset()
+	require.NoError(t, Run(context.Background(), req, WithBinary(script), WithStdout(&stdout)))
+	require.Contains(t, stdout.String(), "ANSIBLE_CONFIG=/home/ops/.ansible.cfg\n", "the operator's environment is inherited by default")
+}
+
+func TestCleanEnvReachesBackend(t *testing.T) {
+	t.Parallel()
+
+	var got Environment
+	backend := backendFunc(func(_ context.Context, _ RunRequest, env Environment) error {
+		got = env
+		return nil
+	})
+	req := RunRequest{User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id"}
+	require.NoError(t, Run(context.Background(), req, WithBackend(backend), WithCleanEnv(), WithCollectionsPath("/srv/collections")))
+	require.True(t, got.CleanEnv)
+	require.Equal(t, "/srv/collections", got.Vars[CollectionsPathEnv])
+}
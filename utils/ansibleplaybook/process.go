@@ -0,0 +1,171 @@
+package ansibleplaybook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/apenella/go-ansible/pkg/execute"
+	"github.com/apenella/go-ansible/pkg/stdoutcallback"
+	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
+)
+
+// killGrace is how long ansible gets after SIGTERM to stop its workers and
+// clean up before the process group is killed.
+var killGrace = 10 * time.Second
+
+// TimeoutError reports a playbook stopped because it ran longer than
+// WithRunTimeout allows.
+type TimeoutError struct {
+	Playbook string
+	Timeout  time.Duration
+	Err      error
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("ansibleplaybook: %s did not finish within %s and was killed: %v", e.Playbook, e.Timeout, e.Err)
+}
+
+// Unwrap reports context.DeadlineExceeded, so callers can treat a playbook
+// timeout like any other deadline.
+func (e TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// WithRunTimeout stops each playbook that runs longer than d: its whole
+// process group, including ansible's ssh connections, gets SIGTERM and, if
+// still running after a grace period, SIGKILL. The run then fails with a
+// TimeoutError. Cancelling Run's context stops the playbook the same way.
+// (WithTimeout is ansible's connection timeout.)
+func WithRunTimeout(d time.Duration) Option {
+	return func(cfg *runConfig) error {
+		if d <= 0 {
+			return fmt.Errorf("run timeout must be greater than zero, got %s", d)
+		}
+		cfg.runTimeout = d
+		return nil
+	}
+}
+
+// stopGroupOnCancel makes cmd, created with exec.CommandContext, stop its
+// whole process group when the context ends: SIGTERM first, then SIGKILL
+// for anything left once the leader exited or killGrace passed. Call the
+// returned func after Wait.
+func stopGroupOnCancel(ctx context.Context, cmd *exec.Cmd) func() {
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminateGroup(cmd)
+	}
+	cmd.WaitDelay = killGrace
+	return func() {
+		if ctx.Err() != nil && cmd.Process != nil {
+			_ = killGroup(cmd)
+		}
+	}
+}
+
+// withProcessGroup swaps go-ansible's executor, which only kills the
+// ansible-playbook process and then waits for output that its ssh children
+// keep open, for groupExecutor. Other executors are left alone.
+func withProcessGroup(e execute.Executor) execute.Executor {
+	switch exec := e.(type) {
+	case *execute.DefaultExecute:
+		return groupExecutor{DefaultExecute: exec}
+	case cleanEnvExecutor:
+		exec.inner = withProcessGroup(exec.inner)
+		return exec
+	}
+	return e
+}
+
+// groupExecutor runs commands like execute.DefaultExecute, with the same
+// writers, environment, and output handling, but in their own process
+// group that is stopped as a whole when the context ends. Stdin is not
+// connected, so a prompt fails instead of competing with the TUI for input.
+type groupExecutor struct {
+	*execute.DefaultExecute
+}
+
+func (e groupExecutor) Execute(ctx context.Context, command []string, resultsFunc stdoutcallback.StdoutCallbackResultsFunc, opts ...execute.ExecuteOptions) error {
+	if len(command) == 0 {
+		return fmt.Errorf("ansibleplaybook: empty command")
+	}
+	for _, opt := range opts {
+		opt(e.DefaultExecute)
+	}
+	if resultsFunc == nil {
+		resultsFunc = results.DefaultStdoutCallbackResults
+	}
+	stdout, stderr := e.Write, e.WriterError
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = e.CmdRunDir
+	if len(e.EnvVars) > 0 {
+		cmd.Env = append(os.Environ(), e.EnvVars.Environ()...)
+	}
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	after := stopGroupOnCancel(ctx, cmd)
+
+	var (
+		wg     sync.WaitGroup
+		outErr error
+	)
+	wg.Add(2)
+	// The output funcs get a fresh context: they must read to EOF, or the
+	// process would block writing after a cancellation.
+	go func() {
+		defer wg.Done()
+		outErr = resultsFunc(context.Background(), stdoutR, stdout, e.Transformers...)
+		_, _ = io.Copy(io.Discard, stdoutR)
+	}()
+	go func() {
+		defer wg.Done()
+		_ = results.DefaultStdoutCallbackResults(context.Background(), stderrR, stderr)
+		_, _ = io.Copy(io.Discard, stderrR)
+	}()
+
+	err := cmd.Start()
+	if err == nil {
+		err = cmd.Wait()
+		after()
+	}
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	wg.Wait()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg, ok := exitMessages[exitErr.ExitCode()]; ok {
+				return fmt.Errorf("%s: %w", msg, err)
+			}
+		}
+		return fmt.Errorf("%s: %w", filepath.Base(command[0]), err)
+	}
+	return outErr
+}
+
+// exitMessages explains ansible-playbook's exit codes, as go-ansible does.
+var exitMessages = map[int]string{
+	execute.AnsiblePlaybookErrorCodeGeneralError:             execute.AnsiblePlaybookErrorMessageGeneralError,
+	execute.AnsiblePlaybookErrorCodeOneOrMoreHostFailed:      execute.AnsiblePlaybookErrorMessageOneOrMoreHostFailed,
+	execute.AnsiblePlaybookErrorCodeOneOrMoreHostUnreachable: execute.AnsiblePlaybookErrorMessageOneOrMoreHostUnreachable,
+	execute.AnsiblePlaybookErrorCodeParserError:              execute.AnsiblePlaybookErrorMessageParserError,
+	execute.AnsiblePlaybookErrorCodeBadOrIncompleteOptions:   execute.AnsiblePlaybookErrorMessageBadOrIncompleteOptions,
+	execute.AnsiblePlaybookErrorCodeUserInterruptedExecution: execute.AnsiblePlaybookErrorMessageUserInterruptedExecution,
+	execute.AnsiblePlaybookErrorCodeUnexpectedError:          execute.AnsiblePlaybookErrorMessageUnexpectedError,
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package ansibleplaybook
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are unavailable; only the
+// ansible process itself is stopped.
+func setProcessGroup(*exec.Cmd) {}
+
+func terminateGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func killGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
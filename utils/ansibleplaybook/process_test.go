@@ -0,0 +1,64 @@
+package ansibleplaybook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hungPlaybook leaves a child holding stdout open, like an ssh connection
+// to an unresponsive host.
+const hungPlaybook = `#!/bin/sh
+sleep 60 &
+sleep 60
+`
+
+func TestRunTimeoutKillsProcessGroup(t *testing.T) {
+	t.Parallel()
+
+	bin := filepath.Join(t.TempDir(), "ansible-playbook")
+	require.NoError(t, os.WriteFile(bin, []byte(hungPlaybook), 0o755))
+	req := RunRequest{User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id"}
+
+	start := time.Now()
+	err := Run(context.Background(), req, WithBinary(bin), WithStdout(io.Discard), WithRunTimeout(200*time.Millisecond))
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	var timeoutErr TimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.Equal(t, "site.yml", timeoutErr.Playbook)
+	require.Equal(t, 200*time.Millisecond, timeoutErr.Timeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRunStopsProcessGroupOnCancel(t *testing.T) {
+	t.Parallel()
+
+	bin := filepath.Join(t.TempDir(), "ansible-playbook")
+	require.NoError(t, os.WriteFile(bin, []byte(hungPlaybook), 0o755))
+	req := RunRequest{User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := Run(ctx, req, WithBinary(bin), WithStdout(io.Discard), WithRunTimeout(time.Minute))
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Error(t, err)
+	require.False(t, errors.As(err, new(TimeoutError)), "a cancelled run is not a playbook timeout")
+}
+
+func TestWithRunTimeoutValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := buildConfig(WithRunTimeout(0))
+	require.Error(t, err)
+	cfg, err := buildConfig(WithRunTimeout(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, cfg.runTimeout)
+}
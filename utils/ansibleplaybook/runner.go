@@ -2,9 +2,13 @@ package ansibleplaybook
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apenella/go-ansible/pkg/execute"
 	"github.com/apenella/go-ansible/pkg/options"
@@ -14,6 +18,13 @@ import (
 const (
 	becomeMethod = "sudo"
 	becomeUser   = "root"
+
+	// MaxVerbosity is the highest level accepted by WithVerbosity (-vvvv).
+	MaxVerbosity = 4
+
+	// BecomePasswordFileEnv names the file ansible (2.12+) reads the become
+	// password from.
+	BecomePasswordFileEnv = "ANSIBLE_BECOME_PASSWORD_FILE"
 )
 
 // RunRequest captures the minimum information required to execute a playbook.
@@ -22,6 +33,14 @@ type RunRequest struct {
 	Target         string
 	PlaybookPath   string
 	PrivateKeyPath string
+	// InventoryPath is an existing inventory file or directory. When set,
+	// Target only limits the run (--limit), so the inventory's groups and
+	// group_vars apply; otherwise an inline "target," inventory is used.
+	InventoryPath string
+	// Playbooks run in order after PlaybookPath (e.g. site, then verify),
+	// each as its own ansible-playbook run so failures can be attributed.
+	// PlaybookPath may be empty when Playbooks is set.
+	Playbooks []string
 }
 
 // Option configures how the playbook command is built or executed.
@@ -33,6 +52,82 @@ type runConfig struct {
 	env             map[string]string
 	executorFactory func(...execute.ExecuteOptions) execute.Executor
 	binary          string
+	backend         Backend
+	verbosity       int
+	becomePassword  string
+	onTask          func(TaskEvent)
+	continueOnError bool
+	forks           int
+	timeout         int
+	sshCommonArgs   string
+	sshExtraArgs    string
+	cleanEnv        bool
+	runTimeout      time.Duration
+}
+
+// Backend executes a validated playbook request. The default shells out to
+// ansible-playbook through go-ansible; AnsibleRunner is the alternative.
+type Backend interface {
+	Run(ctx context.Context, req RunRequest, env Environment) error
+}
+
+// Environment carries the output writers and environment variables configured
+// through options to a Backend.
+type Environment struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Vars   map[string]string
+	// Verbosity is the number of -v flags to pass to ansible.
+	Verbosity int
+	// OnTask, when set, receives per-task progress (see WithTaskEvents).
+	OnTask func(TaskEvent)
+	// Forks and Timeout are ansible's --forks and --timeout; zero keeps
+	// ansible's defaults.
+	Forks   int
+	Timeout int
+	// SSHCommonArgs and SSHExtraArgs are passed through --ssh-common-args and
+	// --ssh-extra-args when set.
+	SSHCommonArgs string
+	SSHExtraArgs  string
+	// CleanEnv asks the backend to pass only Vars, PATH, and HOME on to
+	// ansible (see WithCleanEnv).
+	CleanEnv bool
+}
+
+// PlaybookFailure is one failed playbook of a multi-playbook run.
+type PlaybookFailure struct {
+	Playbook string
+	Err      error
+}
+
+// PlaybooksError aggregates the outcome of a multi-playbook run that did not
+// fully succeed. Skipped lists playbooks never started because an earlier one
+// failed.
+type PlaybooksError struct {
+	Succeeded []string
+	Failed    []PlaybookFailure
+	Skipped   []string
+}
+
+func (e PlaybooksError) Error() string {
+	total := len(e.Succeeded) + len(e.Failed) + len(e.Skipped)
+	parts := make([]string, 0, len(e.Failed))
+	for _, failure := range e.Failed {
+		parts = append(parts, fmt.Sprintf("%s: %v", failure.Playbook, failure.Err))
+	}
+	msg := fmt.Sprintf("ansibleplaybook: %d of %d playbooks failed: %s", len(e.Failed), total, strings.Join(parts, "; "))
+	if len(e.Skipped) > 0 {
+		msg += fmt.Sprintf(" (skipped %s)", strings.Join(e.Skipped, ", "))
+	}
+	return msg
+}
+
+func (e PlaybooksError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, failure := range e.Failed {
+		errs = append(errs, failure.Err)
+	}
+	return errs
 }
 
 // ValidationError indicates an invalid or missing user-supplied value.
@@ -106,41 +201,255 @@ func WithBinary(path string) Option {
 	}
 }
 
-// Run builds and executes an ansible-playbook command for the provided request.
+// WithVerbosity sets ansible's verbosity: 0 is the default output, and 1 to
+// MaxVerbosity map to -v through -vvvv.
+func WithVerbosity(level int) Option {
+	return func(cfg *runConfig) error {
+		if level < 0 || level > MaxVerbosity {
+			return fmt.Errorf("verbosity must be between 0 and %d, got %d", MaxVerbosity, level)
+		}
+		cfg.verbosity = level
+		return nil
+	}
+}
+
+// WithBecomePassword supplies the sudo password for targets where the remote
+// user lacks NOPASSWD. Run writes it to a private temporary file, points
+// ansible at it through ANSIBLE_BECOME_PASSWORD_FILE, and removes the file
+// afterwards, so the secret never appears in the process arguments or
+// environment. BuildCommand ignores it.
+func WithBecomePassword(secret string) Option {
+	return func(cfg *runConfig) error {
+		if secret == "" {
+			return fmt.Errorf("become password must not be empty")
+		}
+		cfg.becomePassword = secret
+		return nil
+	}
+}
+
+// WithForks sets how many hosts ansible works on in parallel (--forks;
+// ansible's default is 5). Raise it for fleet-sized inventories.
+func WithForks(n int) Option {
+	return func(cfg *runConfig) error {
+		if n < 1 {
+			return fmt.Errorf("forks must be at least 1, got %d", n)
+		}
+		cfg.forks = n
+		return nil
+	}
+}
+
+// WithTimeout sets ansible's connection timeout in seconds (--timeout).
+func WithTimeout(seconds int) Option {
+	return func(cfg *runConfig) error {
+		if seconds < 1 {
+			return fmt.Errorf("timeout must be at least 1 second, got %d", seconds)
+		}
+		cfg.timeout = seconds
+		return nil
+	}
+}
+
+// WithSSHCommonArgs passes args to ssh, sftp, and scp (--ssh-common-args),
+// e.g. "-o ProxyJump=bastion" to reach hosts behind a jump host.
+func WithSSHCommonArgs(args string) Option {
+	return func(cfg *runConfig) error {
+		if args = strings.TrimSpace(args); args == "" {
+			return fmt.Errorf("ssh common args must not be empty")
+		}
+		cfg.sshCommonArgs = args
+		return nil
+	}
+}
+
+// WithSSHExtraArgs passes args to ssh only (--ssh-extra-args), e.g.
+// "-o ServerAliveInterval=30".
+func WithSSHExtraArgs(args string) Option {
+	return func(cfg *runConfig) error {
+		if args = strings.TrimSpace(args); args == "" {
+			return fmt.Errorf("ssh extra args must not be empty")
+		}
+		cfg.sshExtraArgs = args
+		return nil
+	}
+}
+
+// WithContinueOnError keeps running the remaining playbooks of a
+// multi-playbook request after one fails, e.g. so a verify playbook still
+// reports. All failures are aggregated into a PlaybooksError.
+func WithContinueOnError() Option {
+	return func(cfg *runConfig) error {
+		cfg.continueOnError = true
+		return nil
+	}
+}
+
+// VerbosityFlag returns the -v flag for level, or "" for level 0.
+func VerbosityFlag(level int) string {
+	if level <= 0 {
+		return ""
+	}
+	return "-" + strings.Repeat("v", min(level, MaxVerbosity))
+}
+
+// WithBackend runs the playbook with backend instead of ansible-playbook.
+// WithBinary and WithExecutorFactory only apply to the default backend.
+func WithBackend(backend Backend) Option {
+	return func(cfg *runConfig) error {
+		if backend == nil {
+			return fmt.Errorf("backend must not be nil")
+		}
+		cfg.backend = backend
+		return nil
+	}
+}
+
+// Run builds and executes an ansible-playbook command for the provided
+// request. Requests with several playbooks run them one after another,
+// stopping at the first failure unless WithContinueOnError is set, and report
+// failures as a PlaybooksError.
 func Run(ctx context.Context, req RunRequest, opts ...Option) error {
-	cmd, err := BuildCommand(req, opts...)
+	cfg, err := buildConfig(opts...)
+	if err != nil {
+		return err
+	}
+	norm, err := normalizeRequest(req)
 	if err != nil {
 		return err
 	}
+	if cfg.becomePassword != "" {
+		cleanup, err := writeBecomePasswordFile(cfg)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
 
-	if err := cmd.Run(ctx); err != nil {
-		return fmt.Errorf("ansibleplaybook: run playbook: %w", err)
+	var tasks *taskEventWriter
+	if cfg.onTask != nil && cfg.backend == nil {
+		tasks = newTaskEventWriter(cfg.stdout, cfg.onTask)
+		cfg.stdout = tasks
+		if cfg.env == nil {
+			cfg.env = make(map[string]string, 1)
+		}
+		cfg.env[StdoutCallbackEnv] = JSONLinesCallback
 	}
 
+	playbooks := append([]string{norm.PlaybookPath}, norm.Playbooks...)
+	if len(playbooks) == 1 {
+		return runPlaybook(ctx, cfg, norm, tasks)
+	}
+
+	var result PlaybooksError
+	for i, playbook := range playbooks {
+		if len(result.Failed) > 0 && !cfg.continueOnError {
+			result.Skipped = append(result.Skipped, playbooks[i:]...)
+			break
+		}
+		single := norm
+		single.PlaybookPath = playbook
+		single.Playbooks = nil
+		if err := runPlaybook(ctx, cfg, single, tasks); err != nil {
+			result.Failed = append(result.Failed, PlaybookFailure{Playbook: playbook, Err: err})
+			continue
+		}
+		result.Succeeded = append(result.Succeeded, playbook)
+	}
+	if len(result.Failed) > 0 {
+		return result
+	}
 	return nil
 }
 
-// BuildCommand constructs a configured ansible-playbook command without executing it.
+// runPlaybook executes a normalized single-playbook request with the
+// configured backend or ansible-playbook.
+func runPlaybook(ctx context.Context, cfg *runConfig, req RunRequest, tasks *taskEventWriter) error {
+	if cfg.runTimeout <= 0 {
+		return execPlaybook(ctx, cfg, req, tasks)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, cfg.runTimeout)
+	defer cancel()
+	err := execPlaybook(runCtx, cfg, req, tasks)
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return TimeoutError{Playbook: req.PlaybookPath, Timeout: cfg.runTimeout, Err: err}
+	}
+	return err
+}
+
+// execPlaybook runs req until ctx ends.
+func execPlaybook(ctx context.Context, cfg *runConfig, req RunRequest, tasks *taskEventWriter) error {
+	if cfg.backend != nil {
+		env := Environment{
+			Stdout:        cfg.stdout,
+			Stderr:        cfg.stderr,
+			Vars:          cfg.env,
+			Verbosity:     cfg.verbosity,
+			OnTask:        cfg.onTask,
+			Forks:         cfg.forks,
+			Timeout:       cfg.timeout,
+			SSHCommonArgs: cfg.sshCommonArgs,
+			SSHExtraArgs:  cfg.sshExtraArgs,
+			CleanEnv:      cfg.cleanEnv,
+		}
+		if err := cfg.backend.Run(ctx, req, env); err != nil {
+			return fmt.Errorf("ansibleplaybook: run playbook: %w", err)
+		}
+		return nil
+	}
+
+	cmd, err := buildCommand(cfg, req)
+	if err != nil {
+		return err
+	}
+	cmd.Exec = withProcessGroup(cmd.Exec)
+
+	runErr := cmd.Run(ctx)
+	if tasks != nil {
+		if err := tasks.Flush(); err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	if runErr != nil {
+		return fmt.Errorf("ansibleplaybook: run playbook: %w", runErr)
+	}
+
+	return nil
+}
+
+// BuildCommand constructs a configured ansible-playbook command without
+// executing it. Multi-playbook requests become a single ansible-playbook
+// invocation listing every playbook.
 func BuildCommand(req RunRequest, opts ...Option) (*playbook.AnsiblePlaybookCmd, error) {
 	cfg, err := buildConfig(opts...)
 	if err != nil {
 		return nil, err
 	}
+	return buildCommand(cfg, req)
+}
 
+func buildCommand(cfg *runConfig, req RunRequest) (*playbook.AnsiblePlaybookCmd, error) {
 	norm, err := normalizeRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
 	cmd := &playbook.AnsiblePlaybookCmd{
-		Playbooks: []string{norm.PlaybookPath},
+		Playbooks: append([]string{norm.PlaybookPath}, norm.Playbooks...),
 		Options: &playbook.AnsiblePlaybookOptions{
-			Inventory: inlineInventory(norm.Target),
-			Limit:     norm.Target,
+			Inventory:   inventoryFor(norm),
+			Limit:       norm.Target,
+			VerboseV:    cfg.verbosity == 1,
+			VerboseVV:   cfg.verbosity == 2,
+			VerboseVVV:  cfg.verbosity == 3,
+			VerboseVVVV: cfg.verbosity == 4,
 		},
 		ConnectionOptions: &options.AnsibleConnectionOptions{
-			User:       norm.User,
-			PrivateKey: norm.PrivateKeyPath,
+			User:          norm.User,
+			PrivateKey:    norm.PrivateKeyPath,
+			Timeout:       cfg.timeout,
+			SSHCommonArgs: cfg.sshCommonArgs,
+			SSHExtraArgs:  cfg.sshExtraArgs,
 		},
 		PrivilegeEscalationOptions: &options.AnsiblePrivilegeEscalationOptions{
 			Become:       true,
@@ -149,7 +458,13 @@ func BuildCommand(req RunRequest, opts ...Option) (*playbook.AnsiblePlaybookCmd,
 		},
 		Exec: cfg.executorFactory(buildExecutorOptions(cfg)...),
 	}
+	if cfg.cleanEnv {
+		cmd.Exec = cleanEnvExecutor{inner: cmd.Exec, vars: cfg.env}
+	}
 
+	if cfg.forks > 0 {
+		cmd.Options.Forks = strconv.Itoa(cfg.forks)
+	}
 	if cfg.binary != "" {
 		cmd.Binary = cfg.binary
 	}
@@ -163,6 +478,15 @@ func normalizeRequest(req RunRequest) (RunRequest, error) {
 		Target:         strings.TrimSpace(req.Target),
 		PlaybookPath:   strings.TrimSpace(req.PlaybookPath),
 		PrivateKeyPath: strings.TrimSpace(req.PrivateKeyPath),
+		InventoryPath:  strings.TrimSpace(req.InventoryPath),
+	}
+	for _, path := range req.Playbooks {
+		if path = strings.TrimSpace(path); path != "" {
+			norm.Playbooks = append(norm.Playbooks, path)
+		}
+	}
+	if norm.PlaybookPath == "" && len(norm.Playbooks) > 0 {
+		norm.PlaybookPath, norm.Playbooks = norm.Playbooks[0], norm.Playbooks[1:]
 	}
 
 	switch {
@@ -203,6 +527,36 @@ func buildConfig(opts ...Option) (*runConfig, error) {
 	return cfg, nil
 }
 
+// writeBecomePasswordFile stores the become password in a 0600 temporary file
+// and records its path in the ansible environment.
+func writeBecomePasswordFile(cfg *runConfig) (func(), error) {
+	file, err := os.CreateTemp("", "ansible-become-*")
+	if err != nil {
+		return nil, fmt.Errorf("ansibleplaybook: create become password file: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+	_, err = file.WriteString(cfg.becomePassword + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("ansibleplaybook: write become password file: %w", err)
+	}
+	if cfg.env == nil {
+		cfg.env = make(map[string]string, 1)
+	}
+	cfg.env[BecomePasswordFileEnv] = file.Name()
+	return cleanup, nil
+}
+
+func inventoryFor(req RunRequest) string {
+	if req.InventoryPath != "" {
+		return req.InventoryPath
+	}
+	return inlineInventory(req.Target)
+}
+
 func inlineInventory(target string) string {
 	if strings.HasSuffix(target, ",") {
 		return target
@@ -221,8 +575,10 @@ func buildExecutorOptions(cfg *runConfig) []execute.ExecuteOptions {
 		execOpts = append(execOpts, execute.WithWriteError(cfg.stderr))
 	}
 
-	for key, value := range cfg.env {
-		execOpts = append(execOpts, execute.WithEnvVar(key, value))
+	if !cfg.cleanEnv {
+		for key, value := range cfg.env {
+			execOpts = append(execOpts, execute.WithEnvVar(key, value))
+		}
 	}
 
 	return execOpts
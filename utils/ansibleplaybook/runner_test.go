@@ -3,6 +3,9 @@ package ansibleplaybook
 import (
 	"bytes"
 	"context"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/apenella/go-ansible/pkg/execute"
@@ -59,6 +62,171 @@ func TestBuildCommandPopulatesCommand(t *testing.T) {
 	require.Equal(t, "json", exec.EnvVars["ANSIBLE_STDOUT_CALLBACK"])
 }
 
+func TestBuildCommandInventoryFile(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := BuildCommand(RunRequest{
+		User:           "ansible",
+		Target:         "web01",
+		PlaybookPath:   "/srv/infra/site.yml",
+		PrivateKeyPath: "/tmp/id_ansible",
+		InventoryPath:  " /srv/infra/inventory/production.ini ",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "/srv/infra/inventory/production.ini", cmd.Options.Inventory)
+	require.Equal(t, "web01", cmd.Options.Limit)
+}
+
+func TestRunWithBecomePassword(t *testing.T) {
+	t.Parallel()
+
+	var passwordFile string
+	backend := backendFunc(func(_ context.Context, _ RunRequest, env Environment) error {
+		passwordFile = env.Vars[BecomePasswordFileEnv]
+		data, err := os.ReadFile(passwordFile)
+		require.NoError(t, err)
+		require.Equal(t, "s3cret\n", string(data))
+		info, err := os.Stat(passwordFile)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		return nil
+	})
+
+	req := RunRequest{User: "ops", Target: "10.0.0.5", PlaybookPath: "/tmp/site.yml", PrivateKeyPath: "/tmp/id_ansible"}
+	require.NoError(t, Run(context.Background(), req, WithBackend(backend), WithBecomePassword("s3cret")))
+	require.NotEmpty(t, passwordFile)
+	require.NoFileExists(t, passwordFile)
+
+	require.Error(t, Run(context.Background(), req, WithBackend(backend), WithBecomePassword("")))
+}
+
+func TestRunMultiplePlaybooks(t *testing.T) {
+	t.Parallel()
+
+	failure := errors.New("verify failed")
+	tests := []struct {
+		name       string
+		opts       []Option
+		failOn     string
+		wantRan    []string
+		wantErr    string
+		wantResult PlaybooksError
+	}{
+		{name: "all succeed", wantRan: []string{"pre.yml", "site.yml", "verify.yml"}},
+		{
+			name:       "stops at first failure",
+			failOn:     "site.yml",
+			wantRan:    []string{"pre.yml", "site.yml"},
+			wantErr:    "1 of 3 playbooks failed: site.yml: ansibleplaybook: run playbook: verify failed (skipped verify.yml)",
+			wantResult: PlaybooksError{Succeeded: []string{"pre.yml"}, Skipped: []string{"verify.yml"}},
+		},
+		{
+			name:       "continue on error",
+			opts:       []Option{WithContinueOnError()},
+			failOn:     "site.yml",
+			wantRan:    []string{"pre.yml", "site.yml", "verify.yml"},
+			wantErr:    "1 of 3 playbooks failed",
+			wantResult: PlaybooksError{Succeeded: []string{"pre.yml", "verify.yml"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ran []string
+			backend := backendFunc(func(_ context.Context, req RunRequest, _ Environment) error {
+				require.Empty(t, req.Playbooks)
+				ran = append(ran, req.PlaybookPath)
+				if req.PlaybookPath == tt.failOn {
+					return failure
+				}
+				return nil
+			})
+			err := Run(context.Background(), RunRequest{
+				User: "ansible", Target: "10.0.0.5", PrivateKeyPath: "/tmp/id",
+				Playbooks: []string{" pre.yml", "site.yml", "", "verify.yml"},
+			}, append(tt.opts, WithBackend(backend))...)
+			require.Equal(t, tt.wantRan, ran)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			require.ErrorIs(t, err, failure)
+			var result PlaybooksError
+			require.ErrorAs(t, err, &result)
+			require.Equal(t, tt.wantResult.Succeeded, result.Succeeded)
+			require.Equal(t, tt.wantResult.Skipped, result.Skipped)
+			require.Len(t, result.Failed, 1)
+			require.Equal(t, "site.yml", result.Failed[0].Playbook)
+		})
+	}
+
+	cmd, err := BuildCommand(RunRequest{
+		User: "ansible", Target: "10.0.0.5", PrivateKeyPath: "/tmp/id",
+		PlaybookPath: "site.yml", Playbooks: []string{"verify.yml"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"site.yml", "verify.yml"}, cmd.Playbooks)
+}
+
+type backendFunc func(context.Context, RunRequest, Environment) error
+
+func (f backendFunc) Run(ctx context.Context, req RunRequest, env Environment) error {
+	return f(ctx, req, env)
+}
+
+func TestBuildCommandVerbosity(t *testing.T) {
+	t.Parallel()
+
+	req := RunRequest{User: "ansible", Target: "10.0.0.5", PlaybookPath: "/tmp/site.yml", PrivateKeyPath: "/tmp/id_ansible"}
+
+	for level, want := range []string{"", "-v", "-vv", "-vvv", "-vvvv"} {
+		cmd, err := BuildCommand(req, WithVerbosity(level))
+		require.NoError(t, err)
+		args, err := cmd.Options.GenerateCommandOptions()
+		require.NoError(t, err)
+		require.Equal(t, want, VerbosityFlag(level))
+		if want == "" {
+			require.NotContains(t, strings.Join(args, " "), "-v")
+			continue
+		}
+		require.Contains(t, args, want, "level %d", level)
+	}
+
+	_, err := BuildCommand(req, WithVerbosity(MaxVerbosity+1))
+	require.Error(t, err)
+	_, err = BuildCommand(req, WithVerbosity(-1))
+	require.Error(t, err)
+}
+
+func TestBuildCommandTuning(t *testing.T) {
+	t.Parallel()
+
+	req := RunRequest{User: "ansible", Target: "10.0.0.5", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id"}
+	cmd, err := BuildCommand(req,
+		WithForks(50),
+		WithTimeout(30),
+		WithSSHCommonArgs(" -o ProxyJump=bastion "),
+		WithSSHExtraArgs("-o ServerAliveInterval=30"),
+	)
+	require.NoError(t, err)
+	args, err := cmd.Options.GenerateCommandOptions()
+	require.NoError(t, err)
+	require.Contains(t, strings.Join(args, " "), "--forks 50")
+	connArgs, err := cmd.ConnectionOptions.GenerateCommandConnectionOptions()
+	require.NoError(t, err)
+	joined := strings.Join(connArgs, " ")
+	require.Contains(t, joined, "--timeout 30")
+	require.Contains(t, joined, "--ssh-common-args '-o ProxyJump=bastion'")
+	require.Contains(t, joined, "--ssh-extra-args '-o ServerAliveInterval=30'")
+
+	for _, opt := range []Option{WithForks(0), WithTimeout(0), WithSSHCommonArgs(" "), WithSSHExtraArgs("")} {
+		_, err := BuildCommand(req, opt)
+		require.Error(t, err)
+	}
+}
+
 func TestRunWithCustomBinary(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,110 @@
+package ansibleplaybook
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// StrategyEnv and StrategyPluginsEnv select ansible's strategy plugin
+	// and the extra directories it is loaded from.
+	StrategyEnv        = "ANSIBLE_STRATEGY"
+	StrategyPluginsEnv = "ANSIBLE_STRATEGY_PLUGINS"
+
+	// StrategyLinear runs each task on every host before the next task
+	// starts (ansible's default).
+	StrategyLinear = "linear"
+	// StrategyFree lets every host run through the play as fast as it can.
+	StrategyFree = "free"
+	// StrategyMitogenLinear and StrategyMitogenFree are the linear and free
+	// strategies of Mitogen for Ansible, which cuts per-task overhead on
+	// large fleets. Mitogen must be installed for ansible's python.
+	StrategyMitogenLinear = "mitogen_linear"
+	StrategyMitogenFree   = "mitogen_free"
+)
+
+var strategyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// mitogenProbeTimeout bounds the python call that locates Mitogen.
+const mitogenProbeTimeout = 10 * time.Second
+
+// findMitogen locates Mitogen's strategy plugins; tests replace it.
+var findMitogen = MitogenStrategyPlugins
+
+// WithStrategy selects the play strategy (ANSIBLE_STRATEGY), e.g.
+// StrategyFree so fast hosts do not wait for slow ones. The Mitogen
+// strategies also point ANSIBLE_STRATEGY_PLUGINS at Mitogen's plugins and
+// fail when it is not installed. Plays that set `strategy:` themselves keep
+// their own.
+func WithStrategy(name string) Option {
+	return func(cfg *runConfig) error {
+		name = strings.TrimSpace(name)
+		if !strategyPattern.MatchString(name) {
+			return fmt.Errorf("invalid strategy %q", name)
+		}
+		if cfg.env == nil {
+			cfg.env = make(map[string]string, 2)
+		}
+		if strings.HasPrefix(name, "mitogen_") {
+			dir, err := findMitogen()
+			if err != nil {
+				return err
+			}
+			cfg.env[StrategyPluginsEnv] = dir
+		}
+		cfg.env[StrategyEnv] = name
+		return nil
+	}
+}
+
+// MitogenStrategyPlugins returns the strategy plugin directory of Mitogen for
+// Ansible as installed for the python that runs ansible-playbook, or an
+// error when Mitogen is missing.
+func MitogenStrategyPlugins() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mitogenProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, ansiblePython(), "-c",
+		"import os, ansible_mitogen; print(os.path.dirname(ansible_mitogen.__file__))").Output()
+	if err != nil {
+		return "", fmt.Errorf("mitogen strategy needs Mitogen for Ansible (pip install mitogen): %w", err)
+	}
+	dir := filepath.Join(strings.TrimSpace(string(out)), "plugins", "strategy")
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("mitogen strategy plugins not found at %s", dir)
+	}
+	return dir, nil
+}
+
+// ansiblePython returns the interpreter named in ansible-playbook's shebang,
+// so a virtualenv install is probed rather than the system python.
+func ansiblePython() string {
+	const fallback = "python3"
+	path, err := exec.LookPath("ansible-playbook")
+	if err != nil {
+		return fallback
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fallback
+	}
+	defer file.Close()
+	line, _ := bufio.NewReader(file).ReadString('\n')
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if !strings.HasPrefix(line, "#!") || len(fields) == 0 {
+		return fallback
+	}
+	if filepath.Base(fields[0]) == "env" {
+		if len(fields) > 1 {
+			return fields[1]
+		}
+		return fallback
+	}
+	return fields[0]
+}
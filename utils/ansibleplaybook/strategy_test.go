@@ -0,0 +1,32 @@
+package ansibleplaybook
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStrategy(t *testing.T) {
+	cfg, err := buildConfig(WithForks(50), WithStrategy(" free "))
+	require.NoError(t, err)
+	require.Equal(t, 50, cfg.forks)
+	require.Equal(t, StrategyFree, cfg.env[StrategyEnv])
+	require.NotContains(t, cfg.env, StrategyPluginsEnv)
+
+	for _, name := range []string{"", "free; rm -rf /", "linear free"} {
+		_, err := buildConfig(WithStrategy(name))
+		require.Error(t, err, name)
+	}
+
+	findMitogen = func() (string, error) { return "/opt/mitogen/plugins/strategy", nil }
+	t.Cleanup(func() { findMitogen = MitogenStrategyPlugins })
+	cfg, err = buildConfig(WithStrategy(StrategyMitogenLinear))
+	require.NoError(t, err)
+	require.Equal(t, StrategyMitogenLinear, cfg.env[StrategyEnv])
+	require.Equal(t, "/opt/mitogen/plugins/strategy", cfg.env[StrategyPluginsEnv])
+
+	findMitogen = func() (string, error) { return "", errors.New("no module named ansible_mitogen") }
+	_, err = buildConfig(WithStrategy(StrategyMitogenFree))
+	require.ErrorContains(t, err, "ansible_mitogen")
+}
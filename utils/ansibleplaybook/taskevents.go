@@ -0,0 +1,209 @@
+package ansibleplaybook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// StdoutCallbackEnv selects ansible's stdout callback plugin.
+	StdoutCallbackEnv = "ANSIBLE_STDOUT_CALLBACK"
+	// JSONLinesCallback streams one JSON document per event. It ships with the
+	// ansible.posix collection, which WithTaskEvents requires on the control
+	// node when the default backend is used.
+	JSONLinesCallback = "ansible.posix.jsonl"
+)
+
+// TaskStatus is the outcome of a task on one host, or TaskStarted when the
+// task begins.
+type TaskStatus string
+
+const (
+	TaskStarted     TaskStatus = "started"
+	TaskOK          TaskStatus = "ok"
+	TaskChanged     TaskStatus = "changed"
+	TaskFailed      TaskStatus = "failed"
+	TaskSkipped     TaskStatus = "skipped"
+	TaskUnreachable TaskStatus = "unreachable"
+)
+
+// TaskEvent is structured per-task progress. Host is empty for TaskStarted.
+type TaskEvent struct {
+	Task    string
+	Host    string
+	Status  TaskStatus
+	Message string
+}
+
+// WithTaskEvents reports each task start and per-host result to fn while the
+// playbook runs. The default backend switches ansible to the
+// ansible.posix.jsonl callback and renders its events back into the familiar
+// "TASK [...]" / "ok: [host]" lines on stdout; AnsibleRunner derives the
+// events from its job events instead.
+func WithTaskEvents(fn func(TaskEvent)) Option {
+	return func(cfg *runConfig) error {
+		if fn == nil {
+			return fmt.Errorf("task event handler must not be nil")
+		}
+		cfg.onTask = fn
+		return nil
+	}
+}
+
+// jsonlEvent is one line written by the ansible.posix.jsonl callback.
+type jsonlEvent struct {
+	Event string `json:"_event"`
+	Task  struct {
+		Name string `json:"name"`
+	} `json:"task"`
+	Hosts map[string]jsonlResult `json:"hosts"`
+}
+
+type jsonlResult struct {
+	Changed bool   `json:"changed"`
+	Msg     string `json:"msg"`
+}
+
+var jsonlStatuses = map[string]TaskStatus{
+	"v2_playbook_on_task_start": TaskStarted,
+	"v2_runner_on_ok":           TaskOK,
+	"v2_runner_on_failed":       TaskFailed,
+	"v2_runner_on_skipped":      TaskSkipped,
+	"v2_runner_on_unreachable":  TaskUnreachable,
+}
+
+// taskEventWriter parses ansible.posix.jsonl output line by line, reports
+// task events, and writes a human-readable rendering to out. Lines that are
+// not JSON (warnings, deprecation notices) pass through unchanged.
+type taskEventWriter struct {
+	mu      sync.Mutex
+	out     io.Writer
+	onTask  func(TaskEvent)
+	pending []byte
+}
+
+func newTaskEventWriter(out io.Writer, onTask func(TaskEvent)) *taskEventWriter {
+	if out == nil {
+		out = io.Discard
+	}
+	return &taskEventWriter{out: out, onTask: onTask}
+}
+
+func (w *taskEventWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(w.pending[:idx])
+		w.pending = w.pending[idx+1:]
+		if err := w.handleLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush handles a trailing line without a newline.
+func (w *taskEventWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+	line := string(w.pending)
+	w.pending = nil
+	return w.handleLine(line)
+}
+
+func (w *taskEventWriter) handleLine(line string) error {
+	var event jsonlEvent
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") || json.Unmarshal([]byte(trimmed), &event) != nil || event.Event == "" {
+		_, err := fmt.Fprintln(w.out, line)
+		return err
+	}
+
+	status, ok := jsonlStatuses[event.Event]
+	if !ok {
+		return nil
+	}
+	if status == TaskStarted {
+		w.onTask(TaskEvent{Task: event.Task.Name, Status: TaskStarted})
+		_, err := fmt.Fprintf(w.out, "TASK [%s]\n", event.Task.Name)
+		return err
+	}
+
+	hosts := make([]string, 0, len(event.Hosts))
+	for host := range event.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		result := event.Hosts[host]
+		taskEvent := TaskEvent{Task: event.Task.Name, Host: host, Status: status, Message: result.Msg}
+		if status == TaskOK && result.Changed {
+			taskEvent.Status = TaskChanged
+		}
+		w.onTask(taskEvent)
+		if _, err := fmt.Fprintln(w.out, renderTaskEvent(taskEvent)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderTaskEvent formats a per-host result the way ansible's default
+// callback does.
+func renderTaskEvent(event TaskEvent) string {
+	switch event.Status {
+	case TaskFailed:
+		return fmt.Sprintf("fatal: [%s]: FAILED! => %s", event.Host, event.Message)
+	case TaskUnreachable:
+		return fmt.Sprintf("fatal: [%s]: UNREACHABLE! => %s", event.Host, event.Message)
+	case TaskSkipped:
+		return fmt.Sprintf("skipping: [%s]", event.Host)
+	default:
+		return fmt.Sprintf("%s: [%s]", event.Status, event.Host)
+	}
+}
+
+// taskEventFromJob maps an ansible-runner job event to a TaskEvent.
+func taskEventFromJob(event Event) (TaskEvent, bool) {
+	taskEvent := TaskEvent{Task: event.Data.Task, Host: event.Data.Host}
+	switch event.Type {
+	case "playbook_on_task_start":
+		taskEvent.Host = ""
+		taskEvent.Status = TaskStarted
+	case "runner_on_ok":
+		taskEvent.Status = TaskOK
+		if event.Data.Changed {
+			taskEvent.Status = TaskChanged
+		}
+	case "runner_on_failed":
+		taskEvent.Status = TaskFailed
+	case "runner_on_skipped":
+		taskEvent.Status = TaskSkipped
+	case "runner_on_unreachable":
+		taskEvent.Status = TaskUnreachable
+	default:
+		return TaskEvent{}, false
+	}
+	if taskEvent.Status == TaskFailed || taskEvent.Status == TaskUnreachable {
+		var res jsonlResult
+		if json.Unmarshal(event.Data.Res, &res) == nil {
+			taskEvent.Message = res.Msg
+		}
+	}
+	return taskEvent, true
+}
@@ -0,0 +1,57 @@
+package ansibleplaybook
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakePlaybook emulates ansible-playbook with the jsonl callback: it reports
+// the callback it was given, then streams events mixed with a plain warning.
+const fakePlaybook = `#!/bin/sh
+echo "[WARNING]: callback=$ANSIBLE_STDOUT_CALLBACK"
+echo '{"_event": "v2_playbook_on_task_start", "task": {"name": "Install nginx"}, "hosts": {}}'
+echo '{"_event": "v2_runner_on_ok", "task": {"name": "Install nginx"}, "hosts": {"web02": {"changed": false}, "web01": {"changed": true}}}'
+echo '{"_event": "v2_runner_on_failed", "task": {"name": "Install nginx"}, "hosts": {"web03": {"msg": "No package nginx"}}}'
+echo '{"_event": "v2_playbook_on_stats", "stats": {}}'
+printf '{"_event": "v2_runner_on_skipped", "task": {"name": "Reboot"}, "hosts": {"web01": {}}}'
+`
+
+func TestRunReportsTaskEvents(t *testing.T) {
+	t.Parallel()
+
+	bin := filepath.Join(t.TempDir(), "ansible-playbook")
+	require.NoError(t, os.WriteFile(bin, []byte(fakePlaybook), 0o755))
+
+	var stdout bytes.Buffer
+	var tasks []TaskEvent
+	err := Run(context.Background(), RunRequest{
+		User: "ansible", Target: "web01", PlaybookPath: "site.yml", PrivateKeyPath: "/tmp/id",
+	}, WithBinary(bin), WithStdout(&stdout), WithTaskEvents(func(e TaskEvent) { tasks = append(tasks, e) }))
+	require.NoError(t, err)
+
+	require.Equal(t, []TaskEvent{
+		{Task: "Install nginx", Status: TaskStarted},
+		{Task: "Install nginx", Host: "web01", Status: TaskChanged},
+		{Task: "Install nginx", Host: "web02", Status: TaskOK},
+		{Task: "Install nginx", Host: "web03", Status: TaskFailed, Message: "No package nginx"},
+		{Task: "Reboot", Host: "web01", Status: TaskSkipped},
+	}, tasks)
+	require.Equal(t, "[WARNING]: callback=ansible.posix.jsonl\n"+
+		"TASK [Install nginx]\n"+
+		"changed: [web01]\n"+
+		"ok: [web02]\n"+
+		"fatal: [web03]: FAILED! => No package nginx\n"+
+		"skipping: [web01]\n", stdout.String())
+}
+
+func TestWithTaskEventsRequiresHandler(t *testing.T) {
+	t.Parallel()
+
+	_, err := BuildCommand(RunRequest{}, WithTaskEvents(nil))
+	require.ErrorContains(t, err, "task event handler must not be nil")
+}
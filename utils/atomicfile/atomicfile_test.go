@@ -0,0 +1,60 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "id_ansible")
+
+	require.NoError(t, WriteFile(path, []byte("first"), 0o600))
+	require.NoError(t, WriteFile(path, []byte("second"), 0o600))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "second", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	require.NoError(t, WriteFile(path, []byte("public"), 0o644))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files may be left behind")
+}
+
+func TestWriteFileKeepsOldContentOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	require.NoError(t, WriteFile(path, []byte(`{"ok":true}`), 0o600))
+
+	// Renaming a file over a non-empty directory fails after the data has
+	// been written to the temporary file.
+	blocked := filepath.Join(dir, "blocked")
+	require.NoError(t, os.MkdirAll(filepath.Join(blocked, "child"), 0o700))
+	require.Error(t, WriteFile(blocked, []byte("new"), 0o600))
+
+	require.Error(t, WriteFile(filepath.Join(dir, "missing", "file"), []byte("x"), 0o600))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `{"ok":true}`, string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "temporary files must be removed on failure")
+}
@@ -0,0 +1,172 @@
+// Package authkeys reads and inspects authorized_keys files on a target host.
+package authkeys
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+// noUserMarker is printed by the listing script when the account is missing.
+const noUserMarker = "__AUTHKEYS_NO_USER__"
+
+// Runner executes commands on the target system with elevated privileges.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Entry is one line of an authorized_keys file.
+type Entry struct {
+	Line        int
+	Type        string
+	Fingerprint string
+	Comment     string
+	Options     []string
+	// Invalid is set when the line could not be parsed as a public key.
+	Invalid bool
+	Raw     string
+}
+
+// UserKeys lists the authorized keys of one account.
+type UserKeys struct {
+	User    string
+	Path    string
+	Exists  bool
+	Entries []Entry
+}
+
+// List reads ~user/.ssh/authorized_keys on the target. A missing account or
+// file is reported through UserKeys rather than as an error.
+func List(r Runner, user string) (*UserKeys, error) {
+	path, body, err := read(r, user)
+	if err != nil {
+		return nil, err
+	}
+	result := &UserKeys{User: strings.TrimSpace(user)}
+	if path == "" {
+		return result, nil
+	}
+	result.Exists = true
+	result.Path = path
+	result.Entries = Parse(body)
+	return result, nil
+}
+
+// Remove deletes every entry whose key has the given SHA256 fingerprint from
+// ~user/.ssh/authorized_keys, keeping other lines, comments, and the file's
+// owner and mode. It reports how many entries were removed; a missing account
+// or file removes nothing.
+func Remove(r Runner, user, fingerprint string) (int, error) {
+	fingerprint = strings.TrimSpace(fingerprint)
+	if !strings.HasPrefix(fingerprint, "SHA256:") {
+		return 0, ValidationError{Reason: fmt.Sprintf("invalid fingerprint %q", fingerprint)}
+	}
+	path, body, err := read(r, user)
+	if err != nil || path == "" {
+		return 0, err
+	}
+
+	var kept strings.Builder
+	removed := 0
+	for _, line := range strings.SplitAfter(body, "\n") {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err == nil && ssh.FingerprintSHA256(key) == fingerprint {
+			removed++
+			continue
+		}
+		kept.WriteString(line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	script := fmt.Sprintf(`
+f=%s
+tmp="$f.host-prep.$$"
+cp -p "$f" "$tmp" && printf '%%s' %s > "$tmp" && mv "$tmp" "$f"
+`, shellquote.Quote(path), shellquote.Quote(kept.String()))
+	if _, stderr, err := r.Run(script); err != nil {
+		return 0, CommandError{Step: "write authorized_keys", Err: err, Stderr: stderr}
+	}
+	return removed, nil
+}
+
+// read returns the path and content of user's authorized_keys, or an empty
+// path when the account does not exist.
+func read(r Runner, user string) (string, string, error) {
+	if r == nil {
+		return "", "", RunnerError{}
+	}
+	user = strings.TrimSpace(user)
+	if user == "" || strings.ContainsAny(user, " /:") {
+		return "", "", ValidationError{Reason: fmt.Sprintf("invalid user %q", user)}
+	}
+
+	script := fmt.Sprintf(`
+home=$(getent passwd %s | cut -d: -f6)
+if [ -z "$home" ]; then
+	echo %s
+	exit 0
+fi
+f="$home/.ssh/authorized_keys"
+echo "$f"
+if [ -f "$f" ]; then
+	cat "$f"
+fi
+`, shellquote.Quote(user), noUserMarker)
+
+	stdout, stderr, err := r.Run(script)
+	if err != nil {
+		return "", "", CommandError{Step: "read authorized_keys", Err: err, Stderr: stderr}
+	}
+	path, body, _ := strings.Cut(stdout, "\n")
+	path = strings.TrimSpace(path)
+	if path == noUserMarker {
+		return "", "", nil
+	}
+	return path, body, nil
+}
+
+// Parse extracts entries from authorized_keys content, skipping blank lines
+// and comments. Lines that fail to parse are kept and marked Invalid.
+func Parse(data string) []Entry {
+	var entries []Entry
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, comment, options, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			entries = append(entries, Entry{Line: lineNo, Invalid: true, Raw: line})
+			continue
+		}
+		entries = append(entries, Entry{
+			Line:        lineNo,
+			Type:        key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			Comment:     comment,
+			Options:     options,
+			Raw:         line,
+		})
+	}
+	return entries
+}
+
+// Fingerprints returns the SHA256 fingerprints of every valid entry in data,
+// e.g. a local authorized_keys-format allowlist.
+func Fingerprints(data string) map[string]bool {
+	set := make(map[string]bool)
+	for _, entry := range Parse(data) {
+		if !entry.Invalid {
+			set[entry.Fingerprint] = true
+		}
+	}
+	return set
+}
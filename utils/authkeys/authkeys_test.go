@@ -0,0 +1,149 @@
+package authkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestListParsesAuthorizedKeys(t *testing.T) {
+	t.Parallel()
+
+	key := newPublicKey(t)
+	body := fmt.Sprintf("# managed\n\nfrom=\"10.0.0.0/8\" %s ops@laptop\nnot-a-key\n", key)
+	r := &fakeRunner{responses: []fakeResponse{{match: "getent passwd 'ansible'", stdout: "/home/ansible/.ssh/authorized_keys\n" + body}}}
+
+	res, err := List(r, "ansible")
+	require.NoError(t, err)
+	require.True(t, res.Exists)
+	require.Equal(t, "/home/ansible/.ssh/authorized_keys", res.Path)
+	require.Len(t, res.Entries, 2)
+
+	entry := res.Entries[0]
+	require.Equal(t, 3, entry.Line)
+	require.Equal(t, "ssh-ed25519", entry.Type)
+	require.Equal(t, "ops@laptop", entry.Comment)
+	require.Equal(t, []string{`from="10.0.0.0/8"`}, entry.Options)
+	require.True(t, Fingerprints(body)[entry.Fingerprint])
+
+	require.True(t, res.Entries[1].Invalid)
+	require.Equal(t, 4, res.Entries[1].Line)
+}
+
+func TestListReportsMissingUser(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{responses: []fakeResponse{{match: "getent", stdout: noUserMarker + "\n"}}}
+	res, err := List(r, "ghost")
+	require.NoError(t, err)
+	require.False(t, res.Exists)
+	require.Empty(t, res.Entries)
+}
+
+func TestListValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := List(nil, "root")
+	require.IsType(t, RunnerError{}, err)
+
+	_, err = List(&fakeRunner{}, "../root")
+	require.IsType(t, ValidationError{}, err)
+
+	r := &fakeRunner{responses: []fakeResponse{{match: "getent", err: errors.New("exit status 1"), stderr: "sudo: a password is required"}}}
+	_, err = List(r, "root")
+	require.IsType(t, CommandError{}, err)
+}
+
+func TestRemoveDeletesMatchingKeys(t *testing.T) {
+	t.Parallel()
+
+	bootstrap, other := newPublicKey(t), newPublicKey(t)
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(bootstrap))
+	require.NoError(t, err)
+	fingerprint := ssh.FingerprintSHA256(parsed)
+
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	body := fmt.Sprintf("# managed\n%s ops@laptop\n%s ansible\nno-pty %s\n", bootstrap, other, bootstrap)
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+
+	r := &localRunner{read: path + "\n" + body}
+	removed, err := Remove(r, "ops", fingerprint)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("# managed\n%s ansible\n", other), string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	r = &localRunner{read: path + "\n" + string(data)}
+	removed, err = Remove(r, "ops", fingerprint)
+	require.NoError(t, err)
+	require.Zero(t, removed)
+	require.Equal(t, 1, r.calls)
+
+	_, err = Remove(r, "ops", "MD5:aa")
+	require.IsType(t, ValidationError{}, err)
+}
+
+// localRunner answers the first command with read and runs the rest with the
+// local shell.
+type localRunner struct {
+	read  string
+	calls int
+}
+
+func (r *localRunner) Run(cmd string) (string, string, error) {
+	r.calls++
+	if r.calls == 1 {
+		return r.read, "", nil
+	}
+	var stderr strings.Builder
+	c := exec.Command("sh", "-c", cmd)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	return string(out), stderr.String(), err
+}
+
+func newPublicKey(t *testing.T) string {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+}
+
+type fakeRunner struct {
+	responses []fakeResponse
+}
+
+type fakeResponse struct {
+	match  string
+	stdout string
+	stderr string
+	err    error
+}
+
+func (f *fakeRunner) Run(cmd string) (string, string, error) {
+	if len(f.responses) == 0 {
+		return "", "", fmt.Errorf("unexpected command: %s", cmd)
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	if !strings.Contains(cmd, resp.match) {
+		return "", "", fmt.Errorf("command %q does not contain %q", cmd, resp.match)
+	}
+	return resp.stdout, resp.stderr, resp.err
+}
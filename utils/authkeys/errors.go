@@ -0,0 +1,41 @@
+package authkeys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RunnerError indicates List was invoked without a valid runner.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "runner is required"
+}
+
+// ValidationError captures bad input values.
+type ValidationError struct {
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("authorized keys validation failed: %s", e.Reason)
+}
+
+// CommandError wraps a failed remote command.
+type CommandError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	stderr := strings.TrimSpace(e.Stderr)
+	if stderr == "" {
+		return fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("%s failed: %v (%s)", e.Step, e.Err, stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
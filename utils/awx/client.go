@@ -0,0 +1,278 @@
+// Package awx is a minimal client for the AWX / Ansible Automation Platform
+// v2 API: registering hosts in an inventory, launching job templates, and
+// following a job until it finishes.
+package awx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultPollInterval = 3 * time.Second
+)
+
+// Job statuses reported by AWX.
+const (
+	StatusNew        = "new"
+	StatusPending    = "pending"
+	StatusWaiting    = "waiting"
+	StatusRunning    = "running"
+	StatusSuccessful = "successful"
+	StatusFailed     = "failed"
+	StatusError      = "error"
+	StatusCanceled   = "canceled"
+)
+
+// Job is the subset of an AWX job record the client uses.
+type Job struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Failed bool   `json:"failed"`
+}
+
+// Finished reports whether the job reached a terminal status.
+func (j Job) Finished() bool {
+	switch j.Status {
+	case StatusSuccessful, StatusFailed, StatusError, StatusCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// JobFailedError reports a job that finished without succeeding.
+type JobFailedError struct {
+	ID     int
+	Status string
+}
+
+func (e JobFailedError) Error() string {
+	return fmt.Sprintf("awx: job %d finished with status %s", e.ID, e.Status)
+}
+
+// LaunchRequest carries the launch-time overrides for a job template. The
+// template must allow prompting for any field that is set.
+type LaunchRequest struct {
+	Limit     string         `json:"limit,omitempty"`
+	ExtraVars map[string]any `json:"extra_vars,omitempty"`
+}
+
+// Client talks to one AWX instance using an OAuth2 token.
+type Client struct {
+	baseURL      string
+	token        string
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// Option customizes a Client.
+type Option func(*Client)
+
+// WithHTTPClient overrides the HTTP client (default: 30s timeout).
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		if client != nil {
+			c.client = client
+		}
+	}
+}
+
+// WithPollInterval overrides how often Wait polls job status (default: 3s).
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		if interval > 0 {
+			c.pollInterval = interval
+		}
+	}
+}
+
+// New constructs a Client for the AWX instance at baseURL.
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(strings.TrimSpace(baseURL), "/"),
+		token:        token,
+		client:       &http.Client{Timeout: defaultTimeout},
+		pollInterval: defaultPollInterval,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	return c
+}
+
+type host struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type hostList struct {
+	Results []host `json:"results"`
+}
+
+// EnsureHost adds name to the inventory with vars, or replaces the variables
+// of an existing host of that name, and returns the host ID.
+func (c *Client) EnsureHost(ctx context.Context, inventoryID int, name string, vars map[string]any) (int, error) {
+	encoded, err := json.Marshal(vars)
+	if err != nil {
+		return 0, fmt.Errorf("awx: encode host variables: %w", err)
+	}
+	body := map[string]string{"name": name, "variables": string(encoded)}
+
+	var found hostList
+	path := fmt.Sprintf("/api/v2/inventories/%d/hosts/?name=%s", inventoryID, url.QueryEscape(name))
+	if err := c.do(ctx, http.MethodGet, path, nil, &found); err != nil {
+		return 0, err
+	}
+	for _, h := range found.Results {
+		if h.Name == name {
+			return h.ID, c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/v2/hosts/%d/", h.ID), body, nil)
+		}
+	}
+
+	var created host
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/inventories/%d/hosts/", inventoryID), body, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// Launch starts the job template and returns the new job's ID.
+func (c *Client) Launch(ctx context.Context, templateID int, req LaunchRequest) (int, error) {
+	var launched struct {
+		Job int `json:"job"`
+		ID  int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/job_templates/%d/launch/", templateID), req, &launched); err != nil {
+		return 0, err
+	}
+	if launched.Job != 0 {
+		return launched.Job, nil
+	}
+	return launched.ID, nil
+}
+
+// Job fetches the current state of a job.
+func (c *Client) Job(ctx context.Context, id int) (Job, error) {
+	var job Job
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/jobs/%d/", id), nil, &job)
+	return job, err
+}
+
+// Wait polls the job until it finishes, copying new stdout to w (when
+// non-nil) as it appears. It returns JobFailedError unless the job succeeds.
+func (c *Client) Wait(ctx context.Context, id int, w io.Writer) (Job, error) {
+	written := 0
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil {
+			return Job{}, err
+		}
+		if w != nil {
+			if written, err = c.copyStdout(ctx, id, w, written); err != nil {
+				return job, err
+			}
+		}
+		if job.Finished() {
+			if job.Status != StatusSuccessful {
+				return job, JobFailedError{ID: id, Status: job.Status}
+			}
+			return job, nil
+		}
+
+		timer := time.NewTimer(c.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return job, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// copyStdout writes the part of the job's plain-text stdout beyond offset.
+func (c *Client) copyStdout(ctx context.Context, id int, w io.Writer, offset int) (int, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("/api/v2/jobs/%d/stdout/?format=txt", id), nil)
+	if err != nil {
+		return offset, err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return offset, err
+	}
+	defer resp.Body.Close()
+	text, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return offset, fmt.Errorf("awx: read job %d stdout: %w", id, err)
+	}
+	if len(text) <= offset {
+		return offset, nil
+	}
+	if _, err := w.Write(text[offset:]); err != nil {
+		return offset, fmt.Errorf("awx: write job %d stdout: %w", id, err)
+	}
+	return len(text), nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("awx: decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("awx: encode %s %s: %w", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("awx: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// send performs req and converts non-2xx responses into errors.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("awx: %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("awx: %s %s: unexpected status %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
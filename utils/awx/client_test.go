@@ -0,0 +1,93 @@
+package awx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureHostCreatesOrUpdates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		existing string
+		method   string
+		wantID   int
+	}{
+		{name: "create", method: http.MethodPost, wantID: 42},
+		{name: "update", existing: `{"results": [{"id": 7, "name": "10.0.0.5"}]}`, method: http.MethodPatch, wantID: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var written map[string]string
+			var method string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+				switch {
+				case r.Method == http.MethodGet:
+					require.Equal(t, "/api/v2/inventories/3/hosts/", r.URL.Path)
+					require.Equal(t, "10.0.0.5", r.URL.Query().Get("name"))
+					if tt.existing == "" {
+						_, _ = w.Write([]byte(`{"results": []}`))
+						return
+					}
+					_, _ = w.Write([]byte(tt.existing))
+				default:
+					method = r.Method
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&written))
+					_, _ = w.Write([]byte(`{"id": 42}`))
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			id, err := New(server.URL+"/", "tok").EnsureHost(context.Background(), 3, "10.0.0.5", map[string]any{"ansible_port": 2222})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantID, id)
+			require.Equal(t, tt.method, method)
+			require.Equal(t, "10.0.0.5", written["name"])
+			require.JSONEq(t, `{"ansible_port": 2222}`, written["variables"])
+		})
+	}
+}
+
+func TestWaitStreamsStdoutUntilFinished(t *testing.T) {
+	t.Parallel()
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/jobs/9/":
+			polls++
+			status := StatusRunning
+			if polls == 2 {
+				status = StatusFailed
+			}
+			_ = json.NewEncoder(w).Encode(Job{ID: 9, Status: status})
+		case "/api/v2/jobs/9/stdout/":
+			require.Equal(t, "txt", r.URL.Query().Get("format"))
+			out := "PLAY [all]\n"
+			if polls == 2 {
+				out += "fatal: [10.0.0.5]\n"
+			}
+			_, _ = w.Write([]byte(out))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var stdout bytes.Buffer
+	job, err := New(server.URL, "tok", WithPollInterval(time.Millisecond)).Wait(context.Background(), 9, &stdout)
+	require.ErrorAs(t, err, &JobFailedError{})
+	require.Equal(t, StatusFailed, job.Status)
+	require.Equal(t, "PLAY [all]\nfatal: [10.0.0.5]\n", stdout.String())
+}
@@ -0,0 +1,52 @@
+package filetransfer
+
+import "fmt"
+
+// RunnerError indicates a Transfer was built without a runner or uploader.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "filetransfer: runners and uploader are required"
+}
+
+// ValidationError captures invalid destinations or options.
+type ValidationError struct {
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("filetransfer: %s", e.Reason)
+}
+
+// UploadError wraps failures copying the file to the staging directory.
+type UploadError struct {
+	Path string
+	Err  error
+}
+
+func (e UploadError) Error() string {
+	return fmt.Sprintf("filetransfer: upload %s failed: %v", e.Path, e.Err)
+}
+
+func (e UploadError) Unwrap() error {
+	return e.Err
+}
+
+// CommandError wraps failures of the commands that stage or install the
+// file on the target.
+type CommandError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("filetransfer: %s failed: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("filetransfer: %s failed: %v (%s)", e.Step, e.Err, e.Stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
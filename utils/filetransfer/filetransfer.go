@@ -0,0 +1,264 @@
+// Package filetransfer pushes files such as configs, scripts, or bundles to
+// privileged locations on a target host.
+//
+// Files are uploaded over SFTP as the SSH user into a private remotetmp
+// directory, then installed as root with the ElevatedClient, so the SSH user
+// never needs write access to the destination:
+//
+//	ft, err := filetransfer.New(client, elevated)
+//	if err != nil {
+//		return err
+//	}
+//	res, err := ft.Put([]byte(config), "/etc/chrony/chrony.conf", filetransfer.WithMode(0o644))
+package filetransfer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/remoteexec"
+	"github.com/BrianJOC/ansible-host-prep/utils/remotetmp"
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+const (
+	defaultMode os.FileMode = 0o644
+	// stagedMode keeps the staged copy private to the SSH user.
+	stagedMode os.FileMode = 0o600
+	stagedName             = "payload"
+	// newSuffix names the copy installed next to the destination before it
+	// is renamed over it, so readers never see a partly written file.
+	newSuffix = ".host-prep-new"
+)
+
+var ownerPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// Runner executes commands on the target system.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Uploader writes a file on the target system as the SSH user, like
+// remoteexec.SFTPUploader.
+type Uploader interface {
+	Upload(path string, data []byte, mode os.FileMode) error
+}
+
+// Option configures Put and PutFile.
+type Option func(*options) error
+
+type options struct {
+	mode    os.FileMode
+	owner   string
+	group   string
+	parents bool
+	base    string
+}
+
+// WithMode sets the Unix mode bits of the installed file, e.g. 0o640 or
+// 0o4755 (default 0o644).
+func WithMode(mode os.FileMode) Option {
+	return func(opts *options) error {
+		if mode&^os.FileMode(0o7777) != 0 {
+			return ValidationError{Reason: fmt.Sprintf("invalid mode %v", mode)}
+		}
+		opts.mode = mode
+		return nil
+	}
+}
+
+// WithOwner sets the owner and group of the installed file, as names or
+// numeric IDs. An empty group leaves the group alone. New files are owned
+// by root otherwise.
+func WithOwner(owner, group string) Option {
+	return func(opts *options) error {
+		owner = strings.TrimSpace(owner)
+		group = strings.TrimSpace(group)
+		if !ownerPattern.MatchString(owner) {
+			return ValidationError{Reason: fmt.Sprintf("invalid owner %q", owner)}
+		}
+		if group != "" && !ownerPattern.MatchString(group) {
+			return ValidationError{Reason: fmt.Sprintf("invalid group %q", group)}
+		}
+		opts.owner = owner
+		opts.group = group
+		return nil
+	}
+}
+
+// WithParents creates missing parent directories of the destination.
+func WithParents() Option {
+	return func(opts *options) error {
+		opts.parents = true
+		return nil
+	}
+}
+
+// WithStagingBase stages uploads under base instead of /tmp, e.g. for hosts
+// where /tmp is too small for a bundle.
+func WithStagingBase(base string) Option {
+	return func(opts *options) error {
+		base = strings.TrimSpace(base)
+		if !path.IsAbs(base) {
+			return ValidationError{Reason: fmt.Sprintf("staging base %q must be an absolute path", base)}
+		}
+		opts.base = path.Clean(base)
+		return nil
+	}
+}
+
+// Result describes an installed file.
+type Result struct {
+	Path string
+	// Changed is false when the destination already had the same content;
+	// its mode and owner are still set.
+	Changed bool
+}
+
+// Transfer installs files on one target.
+type Transfer struct {
+	runner   Runner
+	elevated Runner
+	uploader Uploader
+}
+
+// New returns a Transfer that uploads over client's SFTP subsystem and
+// installs with elevated, usually a *privilege.ElevatedClient for the same
+// connection.
+func New(client *ssh.Client, elevated Runner) (*Transfer, error) {
+	if client == nil {
+		return nil, RunnerError{}
+	}
+	return newTransfer(sessionRunner{client: client}, elevated, remoteexec.NewSFTPUploader(client))
+}
+
+func newTransfer(runner, elevated Runner, up Uploader) (*Transfer, error) {
+	if runner == nil || elevated == nil || up == nil {
+		return nil, RunnerError{}
+	}
+	return &Transfer{runner: runner, elevated: elevated, uploader: up}, nil
+}
+
+// PutFile installs the local file at localPath as dest on the target.
+func (t *Transfer) PutFile(localPath, dest string, opts ...Option) (*Result, error) {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return nil, err
+	}
+	return t.Put(data, dest, opts...)
+}
+
+// Put installs data as dest on the target. The new file is written next to
+// dest and renamed over it, so the replacement is atomic. When dest already
+// holds the same data, only its mode and owner are set and Result.Changed is false.
+func (t *Transfer) Put(data []byte, dest string, opts ...Option) (*Result, error) {
+	dest = strings.TrimSpace(dest)
+	if !path.IsAbs(dest) || path.Clean(dest) != dest || dest == "/" || strings.ContainsAny(dest, "\n\x00") {
+		return nil, ValidationError{Reason: fmt.Sprintf("destination %q must be a clean absolute file path", dest)}
+	}
+	cfg := options{mode: defaultMode}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	var tmpOpts []remotetmp.Option
+	if cfg.base != "" {
+		tmpOpts = append(tmpOpts, remotetmp.WithBase(cfg.base))
+	}
+	dir, err := remotetmp.Create(t.runner, "filetransfer", tmpOpts...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = dir.Remove()
+	}()
+
+	staged := dir.Join(stagedName)
+	if err := t.uploader.Upload(staged, data, stagedMode); err != nil {
+		return nil, UploadError{Path: staged, Err: err}
+	}
+
+	if cfg.parents {
+		if err := t.run("create "+path.Dir(dest), "mkdir -p -- "+shellquote.Quote(path.Dir(dest))); err != nil {
+			return nil, err
+		}
+	}
+	if _, _, err := t.elevated.Run(fmt.Sprintf("cmp -s -- %s %s", shellquote.Quote(staged), shellquote.Quote(dest))); err == nil {
+		if err := t.run("set mode of "+dest, attributesCommand(dest, cfg)); err != nil {
+			return nil, err
+		}
+		return &Result{Path: dest}, nil
+	}
+	if err := t.run("install "+dest, installCommand(staged, dest, cfg)); err != nil {
+		return nil, err
+	}
+	return &Result{Path: dest, Changed: true}, nil
+}
+
+func (t *Transfer) run(step, cmd string) error {
+	_, stderr, err := t.elevated.Run(cmd)
+	if err != nil {
+		return CommandError{Step: step, Err: err, Stderr: strings.TrimSpace(stderr)}
+	}
+	return nil
+}
+
+// installCommand copies staged next to dest with the final mode and owner,
+// then renames it over dest. The copy is removed if either step fails.
+func installCommand(staged, dest string, cfg options) string {
+	tmp := shellquote.Quote(dest + newSuffix)
+	install := fmt.Sprintf("install -m %04o", uint32(cfg.mode))
+	if cfg.owner != "" {
+		install += " -o " + shellquote.Quote(cfg.owner)
+	}
+	if cfg.group != "" {
+		install += " -g " + shellquote.Quote(cfg.group)
+	}
+	return fmt.Sprintf("%s -- %s %s && mv -f -- %s %s || { rm -f -- %s; exit 1; }",
+		install, shellquote.Quote(staged), tmp, tmp, shellquote.Quote(dest), tmp)
+}
+
+// attributesCommand sets the mode and owner of an unchanged dest.
+func attributesCommand(dest string, cfg options) string {
+	cmd := fmt.Sprintf("chmod %04o -- %s", uint32(cfg.mode), shellquote.Quote(dest))
+	if cfg.owner != "" {
+		owner := cfg.owner
+		if cfg.group != "" {
+			owner += ":" + cfg.group
+		}
+		cmd += " && chown -- " + shellquote.Quote(owner) + " " + shellquote.Quote(dest)
+	}
+	return cmd
+}
+
+// sessionRunner runs commands as the SSH user, for the staging directory
+// that the upload writes into.
+type sessionRunner struct {
+	client *ssh.Client
+}
+
+func (r sessionRunner) Run(cmd string) (string, string, error) {
+	session, err := r.client.NewSession()
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		_ = session.Close()
+	}()
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	err = session.Run(cmd)
+	return stdout.String(), stderr.String(), err
+}
@@ -0,0 +1,149 @@
+package filetransfer
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// shellRunner runs commands with the local shell, standing in for a target.
+type shellRunner struct {
+	cmds []string
+}
+
+func (r *shellRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	var stderr strings.Builder
+	c := exec.Command("sh", "-c", cmd)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	return string(out), stderr.String(), err
+}
+
+// localUploader writes files on the local filesystem.
+type localUploader struct {
+	err error
+}
+
+func (u localUploader) Upload(path string, data []byte, mode os.FileMode) error {
+	if u.err != nil {
+		return u.err
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+func newLocalTransfer(t *testing.T, up Uploader) (*Transfer, *shellRunner) {
+	t.Helper()
+	elevated := &shellRunner{}
+	ft, err := newTransfer(&shellRunner{}, elevated, up)
+	require.NoError(t, err)
+	return ft, elevated
+}
+
+func TestPutInstallsAndReportsChanges(t *testing.T) {
+	t.Parallel()
+
+	ft, elevated := newLocalTransfer(t, localUploader{})
+	staging := t.TempDir()
+	dest := filepath.Join(t.TempDir(), "conf.d", "it's.conf")
+
+	res, err := ft.Put([]byte("a=1\n"), dest, WithParents(), WithMode(0o640), WithStagingBase(staging))
+	require.NoError(t, err)
+	require.Equal(t, &Result{Path: dest, Changed: true}, res)
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "a=1\n", string(data))
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	_, err = os.Stat(dest + newSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.Chmod(dest, 0o600))
+	res, err = ft.Put([]byte("a=1\n"), dest, WithMode(0o640), WithStagingBase(staging))
+	require.NoError(t, err)
+	require.False(t, res.Changed)
+	info, err = os.Stat(dest)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), info.Mode().Perm(), "mode is set even when the content is unchanged")
+	require.Contains(t, elevated.cmds[len(elevated.cmds)-1], "chmod 0640")
+
+	res, err = ft.Put([]byte("a=2\n"), dest, WithStagingBase(staging))
+	require.NoError(t, err)
+	require.True(t, res.Changed)
+	data, err = os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "a=2\n", string(data))
+
+	entries, err := os.ReadDir(staging)
+	require.NoError(t, err)
+	require.Empty(t, entries, "staging directories are removed")
+}
+
+func TestPutFile(t *testing.T) {
+	t.Parallel()
+
+	ft, _ := newLocalTransfer(t, localUploader{})
+	src := filepath.Join(t.TempDir(), "setup.sh")
+	require.NoError(t, os.WriteFile(src, []byte("#!/bin/sh\n"), 0o600))
+	dest := filepath.Join(t.TempDir(), "setup.sh")
+
+	_, err := ft.PutFile(src, dest, WithMode(0o755), WithStagingBase(t.TempDir()))
+	require.NoError(t, err)
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	_, err = ft.PutFile(filepath.Join(t.TempDir(), "missing"), dest)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPutErrors(t *testing.T) {
+	t.Parallel()
+
+	uploadErr := errors.New("sftp subsystem not available")
+	ft, _ := newLocalTransfer(t, localUploader{err: uploadErr})
+	_, err := ft.Put([]byte("x"), filepath.Join(t.TempDir(), "x"), WithStagingBase(t.TempDir()))
+	var upErr UploadError
+	require.ErrorAs(t, err, &upErr)
+	require.ErrorIs(t, err, uploadErr)
+
+	ft, _ = newLocalTransfer(t, localUploader{})
+	_, err = ft.Put([]byte("x"), filepath.Join(t.TempDir(), "missing", "x"), WithStagingBase(t.TempDir()))
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.True(t, strings.HasPrefix(cmdErr.Step, "install "))
+
+	for _, dest := range []string{"relative/path", "/", "/etc/../etc/hosts", "/etc/x\ny"} {
+		_, err := ft.Put(nil, dest)
+		require.ErrorAs(t, err, new(ValidationError), dest)
+	}
+	for _, opt := range []Option{WithMode(0o10000), WithOwner("", ""), WithOwner("root", "wheel; rm"), WithStagingBase("tmp")} {
+		_, err := ft.Put(nil, "/etc/x", opt)
+		require.ErrorAs(t, err, new(ValidationError))
+	}
+
+	_, err = newTransfer(nil, &shellRunner{}, localUploader{})
+	require.ErrorAs(t, err, new(RunnerError))
+	_, err = New(nil, &shellRunner{})
+	require.ErrorAs(t, err, new(RunnerError))
+}
+
+func TestCommandsSetOwner(t *testing.T) {
+	t.Parallel()
+
+	cfg := options{mode: 0o4755, owner: "ansible", group: "wheel"}
+	require.Equal(t,
+		`install -m 4755 -o 'ansible' -g 'wheel' -- '/tmp/d/payload' '/usr/local/bin/x.host-prep-new' && mv -f -- '/usr/local/bin/x.host-prep-new' '/usr/local/bin/x' || { rm -f -- '/usr/local/bin/x.host-prep-new'; exit 1; }`,
+		installCommand("/tmp/d/payload", "/usr/local/bin/x", cfg))
+	require.Equal(t, `chmod 4755 -- '/usr/local/bin/x' && chown -- 'ansible:wheel' '/usr/local/bin/x'`, attributesCommand("/usr/local/bin/x", cfg))
+	require.Equal(t, `chmod 0644 -- '/etc/x'`, attributesCommand("/etc/x", options{mode: defaultMode}))
+}
@@ -0,0 +1,53 @@
+package firewall
+
+import "fmt"
+
+// RunnerError indicates Ensure was invoked without a runner.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "runner is required"
+}
+
+// ValidationError captures invalid port specs.
+type ValidationError struct {
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("firewall validation failed: %s", e.Reason)
+}
+
+// OptionError surfaces invalid options.
+type OptionError struct {
+	Reason string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("firewall option error: %s", e.Reason)
+}
+
+// UnsupportedError is returned for targets without ufw or firewalld support,
+// such as the BSDs.
+type UnsupportedError struct {
+	System string
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("firewall management is not supported on %s", e.System)
+}
+
+// CommandError wraps execution failures from the remote host.
+type CommandError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	return fmt.Sprintf("%s failed: %v (%s)", e.Step, e.Err, e.Stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
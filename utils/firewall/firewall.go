@@ -0,0 +1,267 @@
+// Package firewall ensures a host firewall (ufw or firewalld) is installed,
+// allows the SSH port and any extra ports, and is enabled.
+package firewall
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+)
+
+const (
+	// BackendUFW is Ubuntu/Debian's uncomplicated firewall.
+	BackendUFW = "ufw"
+	// BackendFirewalld is the RHEL/Fedora/SUSE firewall daemon.
+	BackendFirewalld = "firewalld"
+
+	defaultSSHPort = 22
+)
+
+// Runner executes commands on the target system with elevated privileges.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Installer installs a package when missing; pkginstaller.Ensure by default.
+type Installer func(r pkginstaller.Runner, packageName string, opts ...pkginstaller.Option) (*pkginstaller.Result, error)
+
+// Port is a port number and protocol opened in the firewall.
+type Port struct {
+	Number   int
+	Protocol string
+}
+
+func (p Port) String() string {
+	return fmt.Sprintf("%d/%s", p.Number, p.Protocol)
+}
+
+// ParsePort accepts "443", "443/tcp", or "53/udp"; the protocol defaults to tcp.
+func ParsePort(spec string) (Port, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	number, proto, found := strings.Cut(spec, "/")
+	if !found {
+		proto = "tcp"
+	}
+	if proto != "tcp" && proto != "udp" {
+		return Port{}, ValidationError{Reason: fmt.Sprintf("port %q: protocol must be tcp or udp", spec)}
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 || n > 65535 {
+		return Port{}, ValidationError{Reason: fmt.Sprintf("port %q: number must be between 1 and 65535", spec)}
+	}
+	return Port{Number: n, Protocol: proto}, nil
+}
+
+// Result reports what Ensure found and changed.
+type Result struct {
+	Backend string
+	// Installed is true when the firewall package had to be installed.
+	Installed bool
+	// Activated is true when the firewall was not running before.
+	Activated bool
+	// Ports lists every port allowed, SSH first.
+	Ports []string
+}
+
+// Option configures Ensure.
+type Option func(*options) error
+
+type options struct {
+	sshPort int
+	ports   []Port
+	backend string
+	install Installer
+}
+
+// WithSSHPort sets the SSH port that is always allowed (default 22).
+func WithSSHPort(port int) Option {
+	return func(opts *options) error {
+		if port < 1 || port > 65535 {
+			return OptionError{Reason: fmt.Sprintf("ssh port %d out of range", port)}
+		}
+		opts.sshPort = port
+		return nil
+	}
+}
+
+// WithAllowedPorts opens extra ports, given as ParsePort specs.
+func WithAllowedPorts(specs ...string) Option {
+	return func(opts *options) error {
+		for _, spec := range specs {
+			if strings.TrimSpace(spec) == "" {
+				continue
+			}
+			port, err := ParsePort(spec)
+			if err != nil {
+				return err
+			}
+			opts.ports = append(opts.ports, port)
+		}
+		return nil
+	}
+}
+
+// WithBackend forces ufw or firewalld instead of detecting one.
+func WithBackend(backend string) Option {
+	return func(opts *options) error {
+		if backend != BackendUFW && backend != BackendFirewalld {
+			return OptionError{Reason: fmt.Sprintf("unknown backend %q", backend)}
+		}
+		opts.backend = backend
+		return nil
+	}
+}
+
+// WithInstaller overrides how the firewall package is installed (for tests).
+func WithInstaller(fn Installer) Option {
+	return func(opts *options) error {
+		if fn == nil {
+			return OptionError{Reason: "installer must not be nil"}
+		}
+		opts.install = fn
+		return nil
+	}
+}
+
+// Ensure installs the firewall if needed, allows the SSH port before anything
+// else so the current session survives, allows the extra ports, and enables
+// the firewall. Existing rules are kept. An installed firewall is preferred;
+// otherwise apt-based hosts get ufw and the rest firewalld.
+func Ensure(r Runner, opts ...Option) (*Result, error) {
+	if r == nil {
+		return nil, RunnerError{}
+	}
+	cfg := options{sshPort: defaultSSHPort, install: pkginstaller.Ensure}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	backend, present, err := detect(r, cfg.backend)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Backend: backend}
+
+	if !present {
+		binary := "ufw"
+		if backend == BackendFirewalld {
+			binary = "firewall-cmd"
+		}
+		if _, err := cfg.install(r, backend, pkginstaller.WithCustomCheck("command -v "+binary+" >/dev/null 2>&1")); err != nil {
+			return nil, err
+		}
+		result.Installed = true
+	}
+
+	ports := dedupePorts(append([]Port{{Number: cfg.sshPort, Protocol: "tcp"}}, cfg.ports...))
+	for _, port := range ports {
+		result.Ports = append(result.Ports, port.String())
+	}
+
+	script := ufwScript(ports)
+	if backend == BackendFirewalld {
+		script = firewalldScript(ports)
+	}
+	stdout, stderr, err := r.Run(script)
+	if err != nil {
+		return nil, CommandError{Step: "configure " + backend, Err: err, Stderr: strings.TrimSpace(stderr)}
+	}
+	result.Activated = strings.TrimSpace(stdout) == "activated"
+	return result, nil
+}
+
+// detect reports the firewall to use and whether it is already installed.
+func detect(r Runner, forced string) (string, bool, error) {
+	stdout, stderr, err := r.Run(`
+case "$(uname -s)" in
+Linux) ;;
+*) echo "unsupported $(uname -s)"; exit 0 ;;
+esac
+ufw=missing; firewalld=missing
+command -v ufw >/dev/null 2>&1 && ufw=present
+command -v firewall-cmd >/dev/null 2>&1 && firewalld=present
+apt=no
+command -v apt-get >/dev/null 2>&1 && apt=yes
+echo "ufw=$ufw firewalld=$firewalld apt=$apt"
+`)
+	if err != nil {
+		return "", false, CommandError{Step: "detect firewall", Err: err, Stderr: strings.TrimSpace(stderr)}
+	}
+	out := strings.TrimSpace(stdout)
+	if system, ok := strings.CutPrefix(out, "unsupported "); ok {
+		return "", false, UnsupportedError{System: system}
+	}
+
+	fields := map[string]string{}
+	for _, field := range strings.Fields(out) {
+		key, value, _ := strings.Cut(field, "=")
+		fields[key] = value
+	}
+	switch {
+	case forced != "":
+		return forced, fields[forced] == "present", nil
+	case fields[BackendUFW] == "present":
+		return BackendUFW, true, nil
+	case fields[BackendFirewalld] == "present":
+		return BackendFirewalld, true, nil
+	case fields["apt"] == "yes":
+		return BackendUFW, false, nil
+	default:
+		return BackendFirewalld, false, nil
+	}
+}
+
+func ufwScript(ports []Port) string {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	for _, port := range ports {
+		fmt.Fprintf(&b, "ufw allow %s >/dev/null\n", port)
+	}
+	b.WriteString(`if ufw status | grep -q '^Status: active'; then
+	echo already-active
+else
+	ufw --force enable >/dev/null
+	echo activated
+fi
+`)
+	return b.String()
+}
+
+// firewalldScript adds the ports to the permanent configuration offline when
+// firewalld is not running yet, so SSH is allowed before it starts.
+func firewalldScript(ports []Port) string {
+	var online, offline strings.Builder
+	for _, port := range ports {
+		fmt.Fprintf(&online, "\tfirewall-cmd --permanent --add-port=%s >/dev/null\n", port)
+		fmt.Fprintf(&offline, "\tfirewall-offline-cmd --add-port=%s >/dev/null\n", port)
+	}
+	return fmt.Sprintf(`set -e
+if systemctl is-active --quiet firewalld; then
+%s	firewall-cmd --reload >/dev/null
+	echo already-active
+else
+%s	systemctl enable --now firewalld >/dev/null
+	echo activated
+fi
+`, online.String(), offline.String())
+}
+
+func dedupePorts(ports []Port) []Port {
+	seen := make(map[Port]bool, len(ports))
+	out := make([]Port, 0, len(ports))
+	for _, port := range ports {
+		if seen[port] {
+			continue
+		}
+		seen[port] = true
+		out = append(out, port)
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package firewall
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+)
+
+type fakeResponse struct {
+	match  string
+	stdout string
+	stderr string
+	err    error
+}
+
+type fakeRunner struct {
+	t         *testing.T
+	responses []fakeResponse
+	cmds      []string
+}
+
+func (f *fakeRunner) Run(cmd string) (string, string, error) {
+	f.t.Helper()
+	f.cmds = append(f.cmds, cmd)
+	require.NotEmpty(f.t, f.responses, "unexpected command %q", cmd)
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	require.Contains(f.t, cmd, resp.match)
+	return resp.stdout, resp.stderr, resp.err
+}
+
+func noInstall(t *testing.T) Option {
+	return WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+		t.Fatal("installer must not run")
+		return nil, nil
+	})
+}
+
+func TestParsePort(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		spec    string
+		want    Port
+		wantErr bool
+	}{
+		{spec: "443", want: Port{Number: 443, Protocol: "tcp"}},
+		{spec: " 53/UDP ", want: Port{Number: 53, Protocol: "udp"}},
+		{spec: "8080/tcp", want: Port{Number: 8080, Protocol: "tcp"}},
+		{spec: "0", wantErr: true},
+		{spec: "70000", wantErr: true},
+		{spec: "22/sctp", wantErr: true},
+		{spec: "ssh", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParsePort(tt.spec)
+			if tt.wantErr {
+				require.ErrorAs(t, err, &ValidationError{})
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnsureUFWAllowsSSHBeforeEnabling(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{t: t, responses: []fakeResponse{
+		{match: "uname -s", stdout: "ufw=present firewalld=missing apt=yes\n"},
+		{match: "ufw allow 2222/tcp", stdout: "activated\n"},
+	}}
+	res, err := Ensure(r, WithSSHPort(2222), WithAllowedPorts("443", "53/udp", "443/tcp", ""), noInstall(t))
+	require.NoError(t, err)
+	require.Equal(t, &Result{Backend: BackendUFW, Activated: true, Ports: []string{"2222/tcp", "443/tcp", "53/udp"}}, res)
+
+	script := r.cmds[1]
+	require.Less(t, strings.Index(script, "ufw allow 2222/tcp"), strings.Index(script, "ufw --force enable"))
+	require.Contains(t, script, "ufw allow 53/udp")
+}
+
+func TestEnsureInstallsFirewalldWhenMissing(t *testing.T) {
+	t.Parallel()
+
+	var installed string
+	install := WithInstaller(func(_ pkginstaller.Runner, pkg string, _ ...pkginstaller.Option) (*pkginstaller.Result, error) {
+		installed = pkg
+		return &pkginstaller.Result{Installed: true}, nil
+	})
+	r := &fakeRunner{t: t, responses: []fakeResponse{
+		{match: "uname -s", stdout: "ufw=missing firewalld=missing apt=no\n"},
+		{match: "firewall-offline-cmd --add-port=22/tcp", stdout: "already-active\n"},
+	}}
+	res, err := Ensure(r, install)
+	require.NoError(t, err)
+	require.Equal(t, BackendFirewalld, installed)
+	require.Equal(t, &Result{Backend: BackendFirewalld, Installed: true, Ports: []string{"22/tcp"}}, res)
+	require.Contains(t, r.cmds[1], "firewall-cmd --permanent --add-port=22/tcp")
+}
+
+func TestEnsureBackendSelection(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		detected    string
+		opts        []Option
+		wantBackend string
+		wantInstall bool
+	}{
+		{name: "existing firewalld wins over apt", detected: "ufw=missing firewalld=present apt=yes", wantBackend: BackendFirewalld},
+		{name: "apt gets ufw", detected: "ufw=missing firewalld=missing apt=yes", wantBackend: BackendUFW, wantInstall: true},
+		{name: "forced backend", detected: "ufw=present firewalld=missing apt=yes", opts: []Option{WithBackend(BackendFirewalld)}, wantBackend: BackendFirewalld, wantInstall: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r := &fakeRunner{t: t, responses: []fakeResponse{
+				{match: "uname -s", stdout: tt.detected},
+				{match: "22/tcp", stdout: "already-active"},
+			}}
+			opts := append([]Option{WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+				return &pkginstaller.Result{}, nil
+			})}, tt.opts...)
+			res, err := Ensure(r, opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBackend, res.Backend)
+			require.Equal(t, tt.wantInstall, res.Installed)
+			require.False(t, res.Activated)
+		})
+	}
+}
+
+func TestEnsureErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Ensure(nil)
+	require.ErrorAs(t, err, &RunnerError{})
+
+	_, err = Ensure(&fakeRunner{t: t}, WithAllowedPorts("http"))
+	require.ErrorAs(t, err, &ValidationError{})
+
+	_, err = Ensure(&fakeRunner{t: t}, WithBackend("iptables"))
+	require.ErrorAs(t, err, &OptionError{})
+
+	_, err = Ensure(&fakeRunner{t: t, responses: []fakeResponse{{match: "uname -s", stdout: "unsupported FreeBSD\n"}}})
+	var unsupported UnsupportedError
+	require.ErrorAs(t, err, &unsupported)
+	require.Equal(t, "FreeBSD", unsupported.System)
+
+	failure := errors.New("exit status 1")
+	r := &fakeRunner{t: t, responses: []fakeResponse{
+		{match: "uname -s", stdout: "ufw=present firewalld=missing apt=yes"},
+		{match: "ufw allow", stderr: "ERROR: problem running iptables", err: failure},
+	}}
+	_, err = Ensure(r, noInstall(t))
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, "configure ufw", cmdErr.Step)
+	require.ErrorIs(t, err, failure)
+}
@@ -0,0 +1,44 @@
+package k8snode
+
+import "fmt"
+
+// RunnerError indicates a function was invoked without a runner.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "runner is required"
+}
+
+// OptionError surfaces invalid options, modules, or sysctl settings.
+type OptionError struct {
+	Reason string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("k8s node option error: %s", e.Reason)
+}
+
+// UnsupportedError is returned for targets that cannot run Kubernetes
+// nodes, i.e. anything but Linux.
+type UnsupportedError struct {
+	System string
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("kubernetes node prep is not supported on %s", e.System)
+}
+
+// CommandError wraps execution failures from the remote host.
+type CommandError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	return fmt.Sprintf("%s failed: %v (%s)", e.Step, e.Err, e.Stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,278 @@
+// Package k8snode applies the host prerequisites kubeadm checks for on a
+// Kubernetes node: swap disabled, the overlay and br_netfilter modules
+// loaded, bridged traffic and IP forwarding enabled in sysctl, and
+// containerd running with the systemd cgroup driver. Every step is
+// idempotent and reports whether it changed anything.
+package k8snode
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+// Runner executes commands on the target system with elevated privileges.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Files the steps write on the target.
+const (
+	ModulesFile      = "/etc/modules-load.d/k8s.conf"
+	SysctlFile       = "/etc/sysctl.d/99-kubernetes.conf"
+	ContainerdConfig = "/etc/containerd/config.toml"
+)
+
+// Sysctl is one kernel parameter and the value it must have.
+type Sysctl struct {
+	Key   string
+	Value string
+}
+
+// DefaultModules are the kernel modules container networking needs.
+func DefaultModules() []string {
+	return []string{"overlay", "br_netfilter"}
+}
+
+// DefaultSysctls let iptables see bridged traffic and enable forwarding.
+func DefaultSysctls() []Sysctl {
+	return []Sysctl{
+		{Key: "net.bridge.bridge-nf-call-iptables", Value: "1"},
+		{Key: "net.bridge.bridge-nf-call-ip6tables", Value: "1"},
+		{Key: "net.ipv4.ip_forward", Value: "1"},
+	}
+}
+
+var (
+	moduleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	sysctlKey  = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+)
+
+// DisableSwap turns swap off now and for later boots: active systemd swap
+// units (such as zram) are masked and swap entries in /etc/fstab are
+// commented out, keeping a copy as /etc/fstab.k8s-bak.
+func DisableSwap(r Runner) (bool, error) {
+	return runStep(r, "disable swap", `
+if command -v systemctl >/dev/null 2>&1; then
+	for unit in $(systemctl list-units --type=swap --state=active --plain --no-legend 2>/dev/null | awk '{print $1}'); do
+		systemctl mask "$unit" >/dev/null 2>&1
+		changed=yes
+	done
+fi
+if [ -n "$(swapon --noheadings 2>/dev/null)" ]; then
+	swapoff -a
+	changed=yes
+fi
+if grep -Eq '^[^#].*[[:space:]]swap[[:space:]]' /etc/fstab 2>/dev/null; then
+	sed -i.k8s-bak -E '/^[^#].*[[:space:]]swap[[:space:]]/s/^/# /' /etc/fstab
+	changed=yes
+fi
+`)
+}
+
+// LoadModules loads modules (DefaultModules when none are given) and lists
+// them in ModulesFile so they load at boot.
+func LoadModules(r Runner, modules ...string) (bool, error) {
+	if len(modules) == 0 {
+		modules = DefaultModules()
+	}
+	for _, module := range modules {
+		if !moduleName.MatchString(module) {
+			return false, OptionError{Reason: fmt.Sprintf("invalid module name %q", module)}
+		}
+	}
+	return runStep(r, "load kernel modules", fmt.Sprintf(`
+want=%s
+if [ "$(cat %s 2>/dev/null)" != "$want" ]; then
+	mkdir -p /etc/modules-load.d
+	printf '%%s\n' "$want" > %s
+	changed=yes
+fi
+for module in %s; do
+	if [ ! -d "/sys/module/$module" ]; then
+		modprobe "$module"
+		changed=yes
+	fi
+done
+`, shellquote.Quote(strings.Join(modules, "\n")), ModulesFile, ModulesFile, strings.Join(modules, " ")))
+}
+
+// ApplySysctls writes settings (DefaultSysctls when none are given) to
+// SysctlFile and applies it when the file or a running value differs. The
+// bridge settings need br_netfilter, so run LoadModules first.
+func ApplySysctls(r Runner, settings ...Sysctl) (bool, error) {
+	if len(settings) == 0 {
+		settings = DefaultSysctls()
+	}
+	var lines, checks []string
+	for _, s := range settings {
+		if !sysctlKey.MatchString(s.Key) {
+			return false, OptionError{Reason: fmt.Sprintf("invalid sysctl key %q", s.Key)}
+		}
+		if strings.TrimSpace(s.Value) == "" || strings.ContainsAny(s.Value, "\n'") {
+			return false, OptionError{Reason: fmt.Sprintf("invalid value for sysctl %s", s.Key)}
+		}
+		lines = append(lines, s.Key+" = "+s.Value)
+		checks = append(checks, fmt.Sprintf(`[ "$(sysctl -n %s 2>/dev/null)" = '%s' ] || stale=yes`, s.Key, s.Value))
+	}
+	return runStep(r, "apply sysctl settings", fmt.Sprintf(`
+want=%s
+stale=no
+if [ "$(cat %s 2>/dev/null)" != "$want" ]; then
+	mkdir -p /etc/sysctl.d
+	printf '%%s\n' "$want" > %s
+	stale=yes
+fi
+%s
+if [ "$stale" = yes ]; then
+	sysctl -p %s >/dev/null
+	changed=yes
+fi
+`, shellquote.Quote(strings.Join(lines, "\n")), SysctlFile, SysctlFile, strings.Join(checks, "\n"), SysctlFile))
+}
+
+// Installer installs a package when missing; pkginstaller.Ensure by default.
+type Installer func(r pkginstaller.Runner, packageName string, opts ...pkginstaller.Option) (*pkginstaller.Result, error)
+
+// ContainerdResult reports what EnsureContainerd found and changed.
+type ContainerdResult struct {
+	// Installed is true when the package had to be installed.
+	Installed bool
+	// Configured is true when ContainerdConfig was (re)generated or switched
+	// to the systemd cgroup driver, which restarts containerd.
+	Configured bool
+	// Version is the running containerd version, e.g. "1.7.24".
+	Version string
+}
+
+// ContainerdOption configures EnsureContainerd.
+type ContainerdOption func(*containerdOptions) error
+
+type containerdOptions struct {
+	pkg     string
+	install Installer
+}
+
+// WithPackage installs containerd from another package, such as
+// "containerd.io" from Docker's repositories on RHEL (default "containerd").
+func WithPackage(name string) ContainerdOption {
+	return func(opts *containerdOptions) error {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return OptionError{Reason: "package name must not be empty"}
+		}
+		opts.pkg = name
+		return nil
+	}
+}
+
+// WithInstaller overrides how the package is installed (for tests).
+func WithInstaller(fn Installer) ContainerdOption {
+	return func(opts *containerdOptions) error {
+		if fn == nil {
+			return OptionError{Reason: "installer must not be nil"}
+		}
+		opts.install = fn
+		return nil
+	}
+}
+
+// EnsureContainerd installs containerd when missing and makes sure its CRI
+// plugin is enabled with SystemdCgroup = true, as kubeadm expects on
+// systemd hosts. A config that disables CRI, as the containerd.io package
+// ships, is replaced by `containerd config default` after being copied to
+// config.toml.k8s-bak. containerd is then enabled and started.
+func EnsureContainerd(r Runner, opts ...ContainerdOption) (*ContainerdResult, error) {
+	if r == nil {
+		return nil, RunnerError{}
+	}
+	cfg := containerdOptions{pkg: "containerd", install: pkginstaller.Ensure}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkLinux(r); err != nil {
+		return nil, err
+	}
+	installed, err := cfg.install(r, cfg.pkg, pkginstaller.WithCustomCheck("command -v containerd >/dev/null 2>&1"))
+	if err != nil {
+		return nil, err
+	}
+	result := &ContainerdResult{Installed: installed != nil && installed.Installed}
+
+	fields, err := run(r, "configure containerd", fmt.Sprintf(`
+cfg=%s
+if [ ! -s "$cfg" ] || grep -Eq '^[[:space:]]*disabled_plugins[[:space:]]*=.*"cri"' "$cfg"; then
+	mkdir -p "$(dirname "$cfg")"
+	if [ -f "$cfg" ]; then cp "$cfg" "$cfg.k8s-bak"; fi
+	containerd config default > "$cfg.tmp"
+	mv "$cfg.tmp" "$cfg"
+	changed=yes
+fi
+if grep -q 'SystemdCgroup = false' "$cfg"; then
+	sed -i 's/SystemdCgroup = false/SystemdCgroup = true/' "$cfg"
+	changed=yes
+fi
+if [ "$changed" = yes ]; then
+	systemctl restart containerd
+fi
+systemctl enable --now containerd >/dev/null 2>&1
+echo "version=$(containerd --version | awk '{print $3}' | sed 's/^v//')"
+`, ContainerdConfig))
+	if err != nil {
+		return nil, err
+	}
+	result.Configured = fields["changed"] == "yes"
+	result.Version = fields["version"]
+	return result, nil
+}
+
+// checkLinux fails with UnsupportedError on anything but Linux.
+func checkLinux(r Runner) error {
+	_, err := run(r, "detect system", "")
+	return err
+}
+
+// runStep runs script and reports whether it set changed=yes.
+func runStep(r Runner, step, script string) (bool, error) {
+	if r == nil {
+		return false, RunnerError{}
+	}
+	fields, err := run(r, step, script)
+	if err != nil {
+		return false, err
+	}
+	return fields["changed"] == "yes", nil
+}
+
+// run executes script after a Linux check, under set -e with changed=no, and
+// returns the key=value lines it printed, including the final changed value.
+func run(r Runner, step, script string) (map[string]string, error) {
+	stdout, stderr, err := r.Run(`if [ "$(uname -s)" != Linux ]; then echo "unsupported $(uname -s)"; exit 0; fi
+set -e
+changed=no
+` + strings.TrimPrefix(script, "\n") + `echo "changed=$changed"
+`)
+	if err != nil {
+		return nil, CommandError{Step: step, Err: err, Stderr: strings.TrimSpace(stderr)}
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimSpace(line)
+		if system, ok := strings.CutPrefix(line, "unsupported "); ok {
+			return nil, UnsupportedError{System: system}
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			fields[key] = value
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,142 @@
+package k8snode
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+)
+
+type recordingRunner struct {
+	stdout string
+	err    error
+	cmds   []string
+}
+
+func (r *recordingRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	return r.stdout, "boom", r.err
+}
+
+func TestStepsReportChanges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		step     func(Runner) (bool, error)
+		contains []string
+	}{
+		{
+			name:     "swap",
+			step:     DisableSwap,
+			contains: []string{"swapoff -a", "sed -i.k8s-bak", "systemctl mask"},
+		},
+		{
+			name: "modules",
+			step: func(r Runner) (bool, error) { return LoadModules(r) },
+			contains: []string{
+				"want='overlay\nbr_netfilter'",
+				"> /etc/modules-load.d/k8s.conf",
+				"for module in overlay br_netfilter; do",
+			},
+		},
+		{
+			name: "sysctls",
+			step: func(r Runner) (bool, error) { return ApplySysctls(r, Sysctl{Key: "vm.max_map_count", Value: "262144"}) },
+			contains: []string{
+				"want='vm.max_map_count = 262144'",
+				`[ "$(sysctl -n vm.max_map_count 2>/dev/null)" = '262144' ] || stale=yes`,
+				"sysctl -p /etc/sysctl.d/99-kubernetes.conf",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &recordingRunner{stdout: "changed=yes\n"}
+			changed, err := tt.step(r)
+			require.NoError(t, err)
+			require.True(t, changed)
+			require.Len(t, r.cmds, 1)
+			for _, want := range tt.contains {
+				require.Contains(t, r.cmds[0], want)
+			}
+			out, err := exec.Command("sh", "-n", "-c", r.cmds[0]).CombinedOutput()
+			require.NoError(t, err, string(out))
+
+			r = &recordingRunner{stdout: "changed=no\n"}
+			changed, err = tt.step(r)
+			require.NoError(t, err)
+			require.False(t, changed)
+
+			_, err = tt.step(&recordingRunner{stdout: "unsupported FreeBSD\n"})
+			require.Equal(t, UnsupportedError{System: "FreeBSD"}, err)
+
+			_, err = tt.step(&recordingRunner{err: errors.New("exit status 1")})
+			var cmdErr CommandError
+			require.ErrorAs(t, err, &cmdErr)
+			require.Equal(t, "boom", cmdErr.Stderr)
+
+			_, err = tt.step(nil)
+			require.IsType(t, RunnerError{}, err)
+		})
+	}
+}
+
+func TestStepsValidateInput(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	_, err := LoadModules(r, "overlay; reboot")
+	require.IsType(t, OptionError{}, err)
+	_, err = ApplySysctls(r, Sysctl{Key: "net.ipv4.ip_forward", Value: "1'"})
+	require.IsType(t, OptionError{}, err)
+	_, err = ApplySysctls(r, Sysctl{Key: "$(id)", Value: "1"})
+	require.IsType(t, OptionError{}, err)
+	require.Empty(t, r.cmds)
+}
+
+func TestEnsureContainerd(t *testing.T) {
+	t.Parallel()
+
+	var installed string
+	r := &scriptedRunner{outputs: []string{"changed=no\n", "version=1.7.24\nchanged=yes\n"}}
+	res, err := EnsureContainerd(r, WithPackage("containerd.io"), WithInstaller(func(_ pkginstaller.Runner, name string, _ ...pkginstaller.Option) (*pkginstaller.Result, error) {
+		installed = name
+		return &pkginstaller.Result{PackageName: name, Installed: true}, nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "containerd.io", installed)
+	require.Equal(t, &ContainerdResult{Installed: true, Configured: true, Version: "1.7.24"}, res)
+	require.Contains(t, r.cmds[1], "sed -i 's/SystemdCgroup = false/SystemdCgroup = true/'")
+	require.Contains(t, r.cmds[1], `disabled_plugins[[:space:]]*=.*"cri"`)
+	out, err := exec.Command("sh", "-n", "-c", r.cmds[1]).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	noInstall := WithInstaller(func(pkginstaller.Runner, string, ...pkginstaller.Option) (*pkginstaller.Result, error) {
+		t.Fatal("installer must not run")
+		return nil, nil
+	})
+	_, err = EnsureContainerd(&scriptedRunner{outputs: []string{"unsupported OpenBSD\n"}}, noInstall)
+	require.Equal(t, UnsupportedError{System: "OpenBSD"}, err)
+
+	_, err = EnsureContainerd(&recordingRunner{}, WithPackage(" "))
+	require.IsType(t, OptionError{}, err)
+}
+
+// scriptedRunner answers each command with the next output.
+type scriptedRunner struct {
+	outputs []string
+	cmds    []string
+}
+
+func (r *scriptedRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	out := r.outputs[0]
+	r.outputs = r.outputs[1:]
+	return out, "", nil
+}
@@ -0,0 +1,210 @@
+// Package mdns resolves .local host names and browses DNS-SD services with
+// multicast DNS (RFC 6762), the way Avahi and Bonjour do, so freshly flashed
+// devices that announce their DHCP hostname can be found and reached from
+// control nodes without nss-mdns.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// defaultTimeout bounds a lookup when ctx has no deadline.
+const defaultTimeout = 2 * time.Second
+
+// groupAddr is the IPv4 mDNS multicast group.
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// errDeadline ends an exchange whose responses have all been read.
+var errDeadline = errors.New("mdns: deadline reached")
+
+// NotFoundError reports a name no responder answered for.
+type NotFoundError struct {
+	Name string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("mdns: no answer for %s", e.Name)
+}
+
+// Service is one DNS-SD service instance found by Browse.
+type Service struct {
+	// Instance is the announced instance name, usually the device's
+	// hostname, e.g. "raspberrypi".
+	Instance string
+	// Host is the target host name, e.g. "raspberrypi.local".
+	Host string
+	Port int
+	// Addrs lists the host's IPv4 addresses when the responder sent them.
+	Addrs []net.IP
+}
+
+// IsLocal reports whether host is a .local name that mDNS can resolve.
+func IsLocal(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return strings.HasSuffix(host, ".local") && net.ParseIP(host) == nil
+}
+
+// Resolve looks up the IPv4 address of name, such as "raspberrypi.local", by
+// sending a one-shot query to the mDNS group and waiting for the first
+// matching answer until ctx is done (or two seconds without a deadline).
+func Resolve(ctx context.Context, name string) (net.IP, error) {
+	return resolve(ctx, name, groupAddr)
+}
+
+func resolve(ctx context.Context, name string, server *net.UDPAddr) (net.IP, error) {
+	if !IsLocal(name) {
+		return nil, fmt.Errorf("mdns: %q is not a .local name", name)
+	}
+	fqdn, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+
+	var ip net.IP
+	err = exchange(ctx, server, dnsmessage.Question{Name: fqdn, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}, func(msg *dnsmessage.Message) bool {
+		for _, rr := range records(msg) {
+			if a, ok := rr.Body.(*dnsmessage.AResource); ok && sameName(rr.Header.Name, fqdn) {
+				ip = net.IP(a.A[:])
+				return true
+			}
+		}
+		return false
+	})
+	if errors.Is(err, errDeadline) {
+		return nil, NotFoundError{Name: name}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ip, nil
+}
+
+// Browse lists the instances of service, such as "_ssh._tcp", that answer
+// within the deadline of ctx (or two seconds without one), sorted by
+// instance name. Finding nothing is not an error.
+func Browse(ctx context.Context, service string) ([]Service, error) {
+	return browse(ctx, service, groupAddr)
+}
+
+func browse(ctx context.Context, service string, server *net.UDPAddr) ([]Service, error) {
+	domain := strings.TrimSuffix(strings.TrimSuffix(service, "."), ".local") + ".local."
+	serviceName, err := dnsmessage.NewName(domain)
+	if err != nil {
+		return nil, fmt.Errorf("mdns: %w", err)
+	}
+
+	instances := map[string]bool{}
+	srvs := map[string]dnsmessage.SRVResource{}
+	addrs := map[string][]net.IP{}
+	err = exchange(ctx, server, dnsmessage.Question{Name: serviceName, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}, func(msg *dnsmessage.Message) bool {
+		for _, rr := range records(msg) {
+			name := strings.ToLower(rr.Header.Name.String())
+			switch body := rr.Body.(type) {
+			case *dnsmessage.PTRResource:
+				if sameName(rr.Header.Name, serviceName) {
+					instances[strings.ToLower(body.PTR.String())] = true
+				}
+			case *dnsmessage.SRVResource:
+				srvs[name] = *body
+			case *dnsmessage.AResource:
+				addrs[name] = appendAddr(addrs[name], net.IP(body.A[:]))
+			}
+		}
+		return false
+	})
+	if err != nil && !errors.Is(err, errDeadline) {
+		return nil, err
+	}
+
+	suffix := "." + strings.ToLower(domain)
+	services := make([]Service, 0, len(instances))
+	for instance := range instances {
+		svc := Service{Instance: strings.TrimSuffix(instance, suffix)}
+		if srv, ok := srvs[instance]; ok {
+			target := strings.ToLower(srv.Target.String())
+			svc.Host = strings.TrimSuffix(target, ".")
+			svc.Port = int(srv.Port)
+			svc.Addrs = addrs[target]
+		}
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Instance < services[j].Instance })
+	return services, nil
+}
+
+// exchange sends q from an ephemeral port and passes every response to
+// handle until it returns true. Queries from a port other than 5353 are
+// answered by unicast to that port (RFC 6762 section 6.7), so no multicast
+// membership is needed. It returns errDeadline when the deadline passes
+// first, and ctx.Err() when ctx is cancelled.
+func exchange(ctx context.Context, server *net.UDPAddr, q dnsmessage.Question, handle func(*dnsmessage.Message) bool) error {
+	query, err := (&dnsmessage.Message{Questions: []dnsmessage.Question{q}}).Pack()
+	if err != nil {
+		return fmt.Errorf("mdns: %w", err)
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return fmt.Errorf("mdns: %w", err)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("mdns: %w", err)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.WriteToUDP(query, server); err != nil {
+		return fmt.Errorf("mdns: query %s: %w", q.Name, err)
+	}
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if errors.Is(ctx.Err(), context.Canceled) {
+					return ctx.Err()
+				}
+				return errDeadline
+			}
+			return fmt.Errorf("mdns: %w", err)
+		}
+		var msg dnsmessage.Message
+		if msg.Unpack(buf[:n]) != nil || !msg.Response {
+			continue
+		}
+		if handle(&msg) {
+			return nil
+		}
+	}
+}
+
+func records(msg *dnsmessage.Message) []dnsmessage.Resource {
+	return append(append([]dnsmessage.Resource{}, msg.Answers...), msg.Additionals...)
+}
+
+func sameName(a, b dnsmessage.Name) bool {
+	return strings.EqualFold(a.String(), b.String())
+}
+
+func appendAddr(addrs []net.IP, ip net.IP) []net.IP {
+	for _, existing := range addrs {
+		if existing.Equal(ip) {
+			return addrs
+		}
+	}
+	return append(addrs, ip)
+}
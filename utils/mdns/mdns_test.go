@@ -0,0 +1,113 @@
+package mdns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// responder answers queries on a loopback socket with the records answer
+// returns; nothing is sent when it returns none.
+func responder(t *testing.T, answer func(q dnsmessage.Question) []dnsmessage.Resource) *net.UDPAddr {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) != 1 {
+				continue
+			}
+			records := answer(query.Questions[0])
+			if len(records) == 0 {
+				continue
+			}
+			reply, err := (&dnsmessage.Message{
+				Header:  dnsmessage.Header{Response: true, Authoritative: true},
+				Answers: records,
+			}).Pack()
+			if err == nil {
+				_, _ = conn.WriteToUDP(reply, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	server := responder(t, func(q dnsmessage.Question) []dnsmessage.Resource {
+		if q.Type != dnsmessage.TypeA || q.Name.String() != "pi.local." {
+			return nil
+		}
+		return []dnsmessage.Resource{record("pi.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 42}})}
+	})
+
+	ip, err := resolve(context.Background(), "pi.local", server)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.42", ip.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = resolve(ctx, "other.local", server)
+	require.Equal(t, NotFoundError{Name: "other.local"}, err)
+
+	_, err = resolve(context.Background(), "example.com", server)
+	require.Error(t, err)
+}
+
+func TestIsLocal(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, IsLocal("raspberrypi.local"))
+	require.True(t, IsLocal("Pi.LOCAL."))
+	require.False(t, IsLocal("pi.example.com"))
+	require.False(t, IsLocal("local"))
+}
+
+func TestBrowse(t *testing.T) {
+	t.Parallel()
+
+	server := responder(t, func(q dnsmessage.Question) []dnsmessage.Resource {
+		if q.Type != dnsmessage.TypePTR || q.Name.String() != "_ssh._tcp.local." {
+			return nil
+		}
+		return []dnsmessage.Resource{
+			record("_ssh._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("raspberrypi._ssh._tcp.local.")}),
+			record("_ssh._tcp.local.", &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("nas._ssh._tcp.local.")}),
+			record("raspberrypi._ssh._tcp.local.", &dnsmessage.SRVResource{Target: dnsmessage.MustNewName("raspberrypi.local."), Port: 22}),
+			record("raspberrypi.local.", &dnsmessage.AResource{A: [4]byte{192, 168, 1, 42}}),
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	services, err := browse(ctx, "_ssh._tcp", server)
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+	require.Equal(t, Service{Instance: "nas"}, services[0])
+	require.Equal(t, "raspberrypi", services[1].Instance)
+	require.Equal(t, "raspberrypi.local", services[1].Host)
+	require.Equal(t, 22, services[1].Port)
+	require.Equal(t, "192.168.1.42", services[1].Addrs[0].String())
+}
+
+func record(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: 120},
+		Body:   body,
+	}
+}
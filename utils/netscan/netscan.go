@@ -0,0 +1,232 @@
+// Package netscan finds SSH servers in a subnet by probing one TCP port on
+// every address and reading the banner, for labs full of fresh, unnamed VMs.
+// Probes are rate limited; only scan networks you are allowed to scan.
+package netscan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPort        = 22
+	defaultRate        = 100
+	defaultConcurrency = 32
+	defaultTimeout     = time.Second
+	// DefaultMaxHosts keeps an accidental /8 from turning into a long scan.
+	DefaultMaxHosts = 4096
+	// maxBannerLen is the longest identification line RFC 4253 allows.
+	maxBannerLen = 255
+)
+
+// Candidate is an address that accepted a connection on the probed port.
+type Candidate struct {
+	Addr netip.Addr
+	Port int
+	// Banner is the server's identification line, e.g.
+	// "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3", or empty when the port was
+	// open but sent no SSH banner in time.
+	Banner string
+}
+
+// IsSSH reports whether the candidate identified itself as an SSH server.
+func (c Candidate) IsSSH() bool {
+	return strings.HasPrefix(c.Banner, "SSH-")
+}
+
+// OptionError surfaces invalid scan options or networks.
+type OptionError struct {
+	Reason string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("netscan option error: %s", e.Reason)
+}
+
+// Option configures Scan.
+type Option func(*scanOptions) error
+
+type scanOptions struct {
+	port        int
+	rate        int
+	concurrency int
+	timeout     time.Duration
+	maxHosts    int
+}
+
+// WithPort probes another port instead of 22.
+func WithPort(port int) Option {
+	return func(opts *scanOptions) error {
+		if port <= 0 || port > 65535 {
+			return OptionError{Reason: fmt.Sprintf("port %d is out of range", port)}
+		}
+		opts.port = port
+		return nil
+	}
+}
+
+// WithRate limits how many probes start per second (default 100).
+func WithRate(perSecond int) Option {
+	return func(opts *scanOptions) error {
+		if perSecond <= 0 {
+			return OptionError{Reason: "rate must be positive"}
+		}
+		opts.rate = perSecond
+		return nil
+	}
+}
+
+// WithConcurrency limits how many probes are in flight at once (default 32).
+func WithConcurrency(n int) Option {
+	return func(opts *scanOptions) error {
+		if n <= 0 {
+			return OptionError{Reason: "concurrency must be positive"}
+		}
+		opts.concurrency = n
+		return nil
+	}
+}
+
+// WithTimeout bounds each connection attempt and banner read (default 1s).
+func WithTimeout(d time.Duration) Option {
+	return func(opts *scanOptions) error {
+		if d <= 0 {
+			return OptionError{Reason: "timeout must be greater than zero"}
+		}
+		opts.timeout = d
+		return nil
+	}
+}
+
+// WithMaxHosts raises or lowers the largest network Scan accepts (default
+// 4096 addresses, a /20).
+func WithMaxHosts(n int) Option {
+	return func(opts *scanOptions) error {
+		if n <= 0 {
+			return OptionError{Reason: "max hosts must be positive"}
+		}
+		opts.maxHosts = n
+		return nil
+	}
+}
+
+// Hosts lists the addresses of cidr that can hold a host: all of them for
+// /31 and /32, otherwise all but the network and broadcast addresses.
+// IPv6 networks are accepted up to max addresses.
+func Hosts(cidr string, max int) ([]netip.Addr, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, OptionError{Reason: fmt.Sprintf("invalid network %q: %v", cidr, err)}
+	}
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 31 || 1<<hostBits > max+2 {
+		return nil, OptionError{Reason: fmt.Sprintf("%s has more than %d addresses", prefix, max)}
+	}
+
+	var hosts []netip.Addr
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, addr)
+	}
+	if prefix.Addr().Is4() && hostBits >= 2 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	if len(hosts) > max {
+		return nil, OptionError{Reason: fmt.Sprintf("%s has more than %d addresses", prefix, max)}
+	}
+	return hosts, nil
+}
+
+// Scan probes every host address in cidr and returns those that accepted a
+// connection, sorted by address. Cancelling ctx stops the scan and returns
+// what was found so far along with ctx.Err().
+func Scan(ctx context.Context, cidr string, opts ...Option) ([]Candidate, error) {
+	cfg := scanOptions{
+		port:        defaultPort,
+		rate:        defaultRate,
+		concurrency: defaultConcurrency,
+		timeout:     defaultTimeout,
+		maxHosts:    DefaultMaxHosts,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	hosts, err := Hosts(cidr, cfg.maxHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mu         sync.Mutex
+		candidates []Candidate
+		wg         sync.WaitGroup
+	)
+	work := make(chan netip.Addr)
+	for range min(cfg.concurrency, len(hosts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range work {
+				if c, ok := probe(ctx, addr, cfg); ok {
+					mu.Lock()
+					candidates = append(candidates, c)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(max(time.Second/time.Duration(cfg.rate), time.Microsecond))
+	defer ticker.Stop()
+feed:
+	for i, addr := range hosts {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				break feed
+			case <-ticker.C:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			break feed
+		case work <- addr:
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Addr.Less(candidates[j].Addr) })
+	return candidates, ctx.Err()
+}
+
+// probe connects to addr and reads the first line the server sends.
+func probe(ctx context.Context, addr netip.Addr, cfg scanOptions) (Candidate, bool) {
+	dialer := net.Dialer{Timeout: cfg.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr.String(), strconv.Itoa(cfg.port)))
+	if err != nil {
+		return Candidate{}, false
+	}
+	defer conn.Close()
+
+	candidate := Candidate{Addr: addr, Port: cfg.port}
+	_ = conn.SetReadDeadline(time.Now().Add(cfg.timeout))
+	line, err := bufio.NewReaderSize(conn, maxBannerLen+2).ReadSlice('\n')
+	if err == nil || len(line) > 0 {
+		candidate.Banner = strings.TrimSpace(string(line))
+	}
+	return candidate, true
+}
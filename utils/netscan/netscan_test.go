@@ -0,0 +1,86 @@
+package netscan
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHosts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		cidr    string
+		max     int
+		want    []string
+		wantErr bool
+	}{
+		{cidr: "192.168.1.7/32", max: 10, want: []string{"192.168.1.7"}},
+		{cidr: "192.168.1.0/31", max: 10, want: []string{"192.168.1.0", "192.168.1.1"}},
+		{cidr: "192.168.1.5/30", max: 10, want: []string{"192.168.1.5", "192.168.1.6"}},
+		{cidr: "fd00::/126", max: 10, want: []string{"fd00::", "fd00::1", "fd00::2", "fd00::3"}},
+		{cidr: "10.0.0.0/24", max: 100, wantErr: true},
+		{cidr: "10.0.0.0/8", max: 1 << 20, wantErr: true},
+		{cidr: "not-a-network", max: 10, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			t.Parallel()
+			hosts, err := Hosts(tt.cidr, tt.max)
+			if tt.wantErr {
+				require.IsType(t, OptionError{}, err)
+				return
+			}
+			require.NoError(t, err)
+			var got []string
+			for _, h := range hosts {
+				got = append(got, h.String())
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanReadsBanners(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_9.2p1 Debian-2\r\n"))
+			_ = conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	candidates, err := Scan(context.Background(), "127.0.0.1/32", WithPort(port), WithTimeout(time.Second))
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	require.Equal(t, "127.0.0.1", candidates[0].Addr.String())
+	require.Equal(t, port, candidates[0].Port)
+	require.Equal(t, "SSH-2.0-OpenSSH_9.2p1 Debian-2", candidates[0].Banner)
+	require.True(t, candidates[0].IsSSH())
+
+	require.NoError(t, listener.Close())
+	candidates, err = Scan(context.Background(), "127.0.0.1/32", WithPort(port), WithTimeout(200*time.Millisecond))
+	require.NoError(t, err)
+	require.Empty(t, candidates)
+}
+
+func TestScanOptionValidation(t *testing.T) {
+	t.Parallel()
+
+	for _, opt := range []Option{WithPort(0), WithRate(0), WithConcurrency(-1), WithTimeout(0), WithMaxHosts(0)} {
+		_, err := Scan(context.Background(), "127.0.0.1/32", opt)
+		require.IsType(t, OptionError{}, err)
+	}
+}
@@ -0,0 +1,42 @@
+package osdetect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RunnerError indicates Detect was invoked without a runner.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "runner is required"
+}
+
+// ParseError reports detection output that could not be understood.
+type ParseError struct {
+	Reason string
+	Output string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("os detection output not understood: %s", e.Reason)
+}
+
+// CommandError wraps a failed remote command.
+type CommandError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	stderr := strings.TrimSpace(e.Stderr)
+	if stderr == "" {
+		return fmt.Sprintf("%s failed: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("%s failed: %v (%s)", e.Step, e.Err, stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,210 @@
+// Package osdetect identifies a target's operating system, distribution,
+// architecture, init system, and package manager over a command runner.
+package osdetect
+
+import (
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+)
+
+// Runner executes commands on the target system.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Init systems reported in Info.InitSystem.
+const (
+	InitSystemd = "systemd"
+	InitOpenRC  = "openrc"
+	InitSysV    = "sysvinit"
+	InitBSD     = "bsdrc"
+	InitUnknown = "unknown"
+)
+
+// Info describes the target system.
+type Info struct {
+	// Kernel and KernelRelease are `uname -s` and `uname -r`.
+	Kernel        string
+	KernelRelease string
+	// Machine is `uname -m`; Arch is its pkginstaller.Arch form, or empty
+	// for architectures pkginstaller does not support.
+	Machine string
+	Arch    string
+	// ID, IDLike, Version, and Codename come from /etc/os-release (ID,
+	// ID_LIKE, VERSION_ID, VERSION_CODENAME). Without os-release, as on
+	// OpenBSD, ID is the lowercased kernel name and Version its release.
+	ID       string
+	IDLike   []string
+	Name     string
+	Version  string
+	Codename string
+	// InitSystem is one of the Init constants.
+	InitSystem string
+	// PackageManager is the first of apt-get, dnf, yum, zypper, apk,
+	// pacman, pkg, or pkg_add found, or empty.
+	PackageManager string
+}
+
+// Is reports whether the distribution is, or is derived from, one of ids
+// (matched against ID and ID_LIKE), e.g. Is("debian") for Ubuntu.
+func (i *Info) Is(ids ...string) bool {
+	for _, id := range ids {
+		if i.ID == id {
+			return true
+		}
+		for _, like := range i.IDLike {
+			if like == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String renders the distribution for logs, e.g. "ubuntu 24.04 (amd64)".
+func (i *Info) String() string {
+	s := i.ID
+	if i.Version != "" {
+		s += " " + i.Version
+	}
+	if i.Machine != "" {
+		arch := i.Arch
+		if arch == "" {
+			arch = i.Machine
+		}
+		s += " (" + arch + ")"
+	}
+	return s
+}
+
+// osReleaseMarker separates the uname and probe lines from os-release.
+const osReleaseMarker = "--os-release--"
+
+const detectScript = `
+echo "kernel=$(uname -s)"
+echo "release=$(uname -r)"
+echo "machine=$(uname -m)"
+if [ -d /run/systemd/system ]; then
+	init=systemd
+elif command -v openrc >/dev/null 2>&1 || [ -x /sbin/openrc-run ]; then
+	init=openrc
+elif [ "$(uname -s)" != Linux ] && [ -f /etc/rc ]; then
+	init=bsdrc
+elif [ -f /etc/inittab ]; then
+	init=sysvinit
+else
+	init=unknown
+fi
+echo "init=$init"
+for pm in apt-get dnf yum zypper apk pacman pkg pkg_add; do
+	if command -v "$pm" >/dev/null 2>&1; then
+		echo "pm=$pm"
+		break
+	fi
+done
+echo ` + osReleaseMarker + `
+for f in /etc/os-release /usr/lib/os-release; do
+	if [ -r "$f" ]; then
+		cat "$f"
+		break
+	fi
+done
+`
+
+// Detect inspects the target with a single command. It needs no privileges.
+func Detect(r Runner) (*Info, error) {
+	if r == nil {
+		return nil, RunnerError{}
+	}
+	stdout, stderr, err := r.Run(detectScript)
+	if err != nil {
+		return nil, CommandError{Step: "detect-os", Err: err, Stderr: stderr}
+	}
+	return parse(stdout)
+}
+
+func parse(output string) (*Info, error) {
+	probe, osRelease, _ := strings.Cut(output, osReleaseMarker+"\n")
+	info := &Info{InitSystem: InitUnknown}
+	for _, line := range strings.Split(probe, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "kernel":
+			info.Kernel = value
+		case "release":
+			info.KernelRelease = value
+		case "machine":
+			info.Machine = value
+		case "init":
+			info.InitSystem = value
+		case "pm":
+			info.PackageManager = value
+		}
+	}
+	if info.Kernel == "" {
+		return nil, ParseError{Reason: "uname -s printed nothing", Output: output}
+	}
+	if arch, err := pkginstaller.NormalizeArch(info.Machine); err == nil {
+		info.Arch = arch
+	}
+
+	fields := ParseOSRelease(osRelease)
+	info.ID = strings.ToLower(fields["ID"])
+	info.IDLike = strings.Fields(strings.ToLower(fields["ID_LIKE"]))
+	info.Name = fields["PRETTY_NAME"]
+	if info.Name == "" {
+		info.Name = fields["NAME"]
+	}
+	info.Version = fields["VERSION_ID"]
+	info.Codename = fields["VERSION_CODENAME"]
+	if info.ID == "" {
+		info.ID = strings.ToLower(info.Kernel)
+		info.Version = info.KernelRelease
+		info.Name = info.Kernel + " " + info.KernelRelease
+	}
+	return info, nil
+}
+
+// ParseOSRelease parses os-release(5) content into its KEY=value pairs,
+// removing shell quoting. Comments and malformed lines are skipped.
+func ParseOSRelease(data string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key == "" {
+			continue
+		}
+		fields[key] = unquote(value)
+	}
+	return fields
+}
+
+// unquote strips one level of single or double quotes and resolves the
+// backslash escapes os-release allows inside double quotes.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return value[1 : len(value)-1]
+		case value[0] == '"' && value[len(value)-1] == '"':
+			value = value[1 : len(value)-1]
+			var b strings.Builder
+			for i := 0; i < len(value); i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+				}
+				b.WriteByte(value[i])
+			}
+			return b.String()
+		}
+	}
+	return value
+}
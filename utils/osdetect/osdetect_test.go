@@ -0,0 +1,125 @@
+package osdetect
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+)
+
+type runnerFunc func(cmd string) (string, string, error)
+
+func (f runnerFunc) Run(cmd string) (string, string, error) {
+	return f(cmd)
+}
+
+func reply(stdout string) Runner {
+	return runnerFunc(func(string) (string, string, error) {
+		return stdout, "", nil
+	})
+}
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		want   Info
+	}{
+		{
+			name: "ubuntu",
+			output: "kernel=Linux\nrelease=6.8.0-45-generic\nmachine=x86_64\ninit=systemd\npm=apt-get\n--os-release--\n" +
+				"PRETTY_NAME=\"Ubuntu 24.04.1 LTS\"\nNAME=\"Ubuntu\"\nVERSION_ID=\"24.04\"\nVERSION_CODENAME=noble\nID=ubuntu\nID_LIKE=debian\n",
+			want: Info{
+				Kernel: "Linux", KernelRelease: "6.8.0-45-generic", Machine: "x86_64", Arch: pkginstaller.ArchAMD64,
+				ID: "ubuntu", IDLike: []string{"debian"}, Name: "Ubuntu 24.04.1 LTS", Version: "24.04", Codename: "noble",
+				InitSystem: InitSystemd, PackageManager: "apt-get",
+			},
+		},
+		{
+			name: "alpine with openrc",
+			output: "kernel=Linux\nrelease=6.6.31-0-lts\nmachine=aarch64\ninit=openrc\npm=apk\n--os-release--\n" +
+				"NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.20.1\n",
+			want: Info{
+				Kernel: "Linux", KernelRelease: "6.6.31-0-lts", Machine: "aarch64", Arch: pkginstaller.ArchARM64,
+				ID: "alpine", IDLike: []string{}, Name: "Alpine Linux", Version: "3.20.1",
+				InitSystem: InitOpenRC, PackageManager: "apk",
+			},
+		},
+		{
+			name:   "openbsd without os-release",
+			output: "kernel=OpenBSD\nrelease=7.5\nmachine=sparc64\ninit=bsdrc\npm=pkg_add\n--os-release--\n",
+			want: Info{
+				Kernel: "OpenBSD", KernelRelease: "7.5", Machine: "sparc64",
+				ID: "openbsd", IDLike: []string{}, Name: "OpenBSD 7.5", Version: "7.5",
+				InitSystem: InitBSD, PackageManager: "pkg_add",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := Detect(reply(tt.output))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, *info)
+		})
+	}
+}
+
+func TestDetectErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Detect(nil)
+	require.IsType(t, RunnerError{}, err)
+
+	_, err = Detect(reply("--os-release--\nID=debian\n"))
+	require.IsType(t, ParseError{}, err)
+
+	boom := errors.New("exit status 127")
+	_, err = Detect(runnerFunc(func(string) (string, string, error) {
+		return "", "sh: uname: not found", boom
+	}))
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, "detect-os", cmdErr.Step)
+	require.ErrorIs(t, err, boom)
+}
+
+func TestParseOSRelease(t *testing.T) {
+	t.Parallel()
+
+	fields := ParseOSRelease(`# comment
+NAME="Fedora Linux"
+VERSION="40 (Server Edition)"
+ID=fedora
+VARIANT='Server Edition'
+HOME_URL="https://fedoraproject.org/"
+QUOTED="say \"hi\""
+malformed line
+`)
+	require.Equal(t, map[string]string{
+		"NAME":     "Fedora Linux",
+		"VERSION":  "40 (Server Edition)",
+		"ID":       "fedora",
+		"VARIANT":  "Server Edition",
+		"HOME_URL": "https://fedoraproject.org/",
+		"QUOTED":   `say "hi"`,
+	}, fields)
+}
+
+func TestInfoIsAndString(t *testing.T) {
+	t.Parallel()
+
+	info := &Info{ID: "rocky", IDLike: []string{"rhel", "centos", "fedora"}, Version: "9.4", Machine: "x86_64", Arch: pkginstaller.ArchAMD64}
+	require.True(t, info.Is("rhel"))
+	require.True(t, info.Is("debian", "rocky"))
+	require.False(t, info.Is("debian"))
+	require.Equal(t, "rocky 9.4 (amd64)", info.String())
+
+	info = &Info{ID: "openbsd", Version: "7.5", Machine: "sparc64"}
+	require.Equal(t, "openbsd 7.5 (sparc64)", info.String())
+}
@@ -0,0 +1,107 @@
+package pkginstaller
+
+import (
+	"strings"
+)
+
+// Architectures reported by NormalizeArch, using Debian/Go naming.
+const (
+	ArchAMD64 = "amd64"
+	ArchARM64 = "arm64"
+	ArchARM   = "arm"
+	Arch386   = "386"
+)
+
+// machineArches maps `uname -m` output to a normalized architecture.
+var machineArches = map[string]string{
+	"x86_64":  ArchAMD64,
+	"amd64":   ArchAMD64,
+	"aarch64": ArchARM64,
+	"arm64":   ArchARM64,
+	"armv6l":  ArchARM,
+	"armv7l":  ArchARM,
+	"armhf":   ArchARM,
+	"arm":     ArchARM,
+	"i386":    Arch386,
+	"i686":    Arch386,
+}
+
+// NormalizeArch converts a `uname -m` machine name into one of the Arch
+// constants, or returns UnsupportedArchError.
+func NormalizeArch(machine string) (string, error) {
+	machine = strings.ToLower(strings.TrimSpace(machine))
+	if arch, ok := machineArches[machine]; ok {
+		return arch, nil
+	}
+	return "", UnsupportedArchError{Arch: machine}
+}
+
+// DetectArch runs `uname -m` on the target and normalizes the result.
+func DetectArch(r Runner) (string, error) {
+	if r == nil {
+		return "", RunnerError{}
+	}
+	stdout, stderr, err := r.Run("uname -m")
+	if err != nil {
+		return "", CommandError{Step: "detect-arch", Err: err, Stderr: stderr}
+	}
+	return NormalizeArch(stdout)
+}
+
+// WithArch sets the target architecture instead of detecting it. It accepts
+// either a normalized name or raw `uname -m` output.
+func WithArch(arch string) Option {
+	return func(opts *options) error {
+		normalized, err := NormalizeArch(arch)
+		if err != nil {
+			return err
+		}
+		opts.arch = normalized
+		return nil
+	}
+}
+
+// WithArchPackages installs a different package per architecture, keyed by
+// the Arch constants. Ensure fails with UnsupportedArchError before touching
+// the target's package manager when the target's architecture is not listed.
+func WithArchPackages(packages map[string]string) Option {
+	return func(opts *options) error {
+		if len(packages) == 0 {
+			return OptionError{Reason: "arch packages must not be empty"}
+		}
+		opts.archPackages = make(map[string]string, len(packages))
+		for arch, name := range packages {
+			normalized, err := NormalizeArch(arch)
+			if err != nil {
+				return OptionError{Reason: "arch packages: " + err.Error()}
+			}
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return OptionError{Reason: "arch packages: package name for " + normalized + " must not be empty"}
+			}
+			opts.archPackages[normalized] = name
+		}
+		return nil
+	}
+}
+
+// resolveArchPackage picks the package to install for the target
+// architecture, detecting it when WithArch was not given.
+func resolveArchPackage(r Runner, packageName string, config options) (string, string, error) {
+	if config.archPackages == nil {
+		return packageName, config.arch, nil
+	}
+	arch := config.arch
+	if arch == "" {
+		detected, err := DetectArch(r)
+		if err != nil {
+			return "", "", err
+		}
+		arch = detected
+	}
+	name, ok := config.archPackages[arch]
+	if !ok {
+		return "", arch, UnsupportedArchError{Arch: arch, Package: packageName}
+	}
+	return name, arch, nil
+}
@@ -0,0 +1,97 @@
+package pkginstaller
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnsureAll installs every missing package with a single package-manager
+// invocation and returns one Result per package, in the order given.
+// Presence is checked for all packages with one command (`command -v`, as in
+// Ensure), so a batch costs two round-trips at most. WithCustomCheck and
+// WithArchPackages describe a single package and are rejected.
+func EnsureAll(r Runner, packageNames []string, opts ...Option) ([]Result, error) {
+	if r == nil {
+		return nil, RunnerError{}
+	}
+
+	var names []string
+	seen := make(map[string]bool, len(packageNames))
+	for _, name := range packageNames {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, ValidationError{Reason: "package names must not be empty"}
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, ValidationError{Reason: "at least one package is required"}
+	}
+
+	config, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if config.checkCmd != "" || config.archPackages != nil {
+		return nil, OptionError{Reason: "custom checks and arch packages apply to a single package; use Ensure"}
+	}
+
+	results := make([]Result, len(names))
+	for i, name := range names {
+		results[i] = Result{PackageName: name, Arch: config.arch}
+	}
+
+	missing := names
+	if !config.force {
+		present, err := checkAll(r, names)
+		if err != nil {
+			return nil, err
+		}
+		missing = nil
+		for i, name := range names {
+			if present[name] {
+				results[i].Skipped = true
+				continue
+			}
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	installCmd, err := buildInstallCommand(missing...)
+	if err != nil {
+		return nil, err
+	}
+	if err := runInstall(r, installCmd); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Installed = !results[i].Skipped
+	}
+	return results, nil
+}
+
+// checkAll reports which of names are already available on the target.
+func checkAll(r Runner, names []string) (map[string]bool, error) {
+	cmd := fmt.Sprintf(`
+for p in %s; do
+	if command -v "$p" >/dev/null 2>&1; then echo "present $p"; fi
+done
+`, quoteAll(names, nil))
+	stdout, stderr, err := r.Run(cmd)
+	if err != nil {
+		return nil, CommandError{Step: "check", Err: err, Stderr: stderr}
+	}
+	present := make(map[string]bool, len(names))
+	for _, line := range strings.Split(stdout, "\n") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "present "); ok {
+			present[name] = true
+		}
+	}
+	return present, nil
+}
@@ -0,0 +1,88 @@
+package pkginstaller
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureAllInstallsMissingInOneCall(t *testing.T) {
+	t.Parallel()
+
+	var install string
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "for p in 'git' 'curl' 'python3'; do", stdout: "present curl\n"},
+			{match: "apt-get install -y 'git' 'python3'"},
+		},
+	}
+
+	results, err := EnsureAll(recordLast(r, &install), []string{"git", "curl", " python3", "git"}, WithArch("x86_64"))
+	require.NoError(t, err)
+	require.Empty(t, r.responses)
+	require.Equal(t, []Result{
+		{PackageName: "git", Installed: true, Arch: ArchAMD64},
+		{PackageName: "curl", Skipped: true, Arch: ArchAMD64},
+		{PackageName: "python3", Installed: true, Arch: ArchAMD64},
+	}, results)
+	require.Contains(t, install, "pacman -Sy --noconfirm --needed 'git' 'python'")
+	require.NotContains(t, install, "'curl'")
+}
+
+func TestEnsureAllSkipsInstallWhenAllPresent(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{responses: []fakeResponse{{match: "command -v", stdout: "present git\npresent curl\n"}}}
+	results, err := EnsureAll(r, []string{"git", "curl"})
+	require.NoError(t, err)
+	for _, res := range results {
+		require.True(t, res.Skipped)
+	}
+
+	r = &fakeRunner{responses: []fakeResponse{{match: "apt-get install -y 'git' 'curl'"}}}
+	results, err = EnsureAll(r, []string{"git", "curl"}, WithForce())
+	require.NoError(t, err)
+	require.True(t, results[0].Installed && results[1].Installed)
+}
+
+func TestEnsureAllErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := EnsureAll(nil, []string{"git"})
+	require.IsType(t, RunnerError{}, err)
+
+	_, err = EnsureAll(&fakeRunner{}, nil)
+	require.IsType(t, ValidationError{}, err)
+
+	_, err = EnsureAll(&fakeRunner{}, []string{"git", ""})
+	require.IsType(t, ValidationError{}, err)
+
+	_, err = EnsureAll(&fakeRunner{}, []string{"git"}, WithCustomCheck("test -x /usr/bin/git"))
+	require.IsType(t, OptionError{}, err)
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "command -v"},
+			{match: "apt-get", stderr: "E: Unable to locate package gti", err: errors.New("exit status 100")},
+		},
+	}
+	_, err = EnsureAll(r, []string{"gti"})
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, "install", cmdErr.Step)
+}
+
+// recordLast stores the most recent command run through r in last.
+func recordLast(r Runner, last *string) Runner {
+	return runnerFunc(func(cmd string) (string, string, error) {
+		*last = cmd
+		return r.Run(cmd)
+	})
+}
+
+type runnerFunc func(cmd string) (string, string, error)
+
+func (f runnerFunc) Run(cmd string) (string, string, error) {
+	return f(cmd)
+}
@@ -43,3 +43,27 @@ func (e CommandError) Error() string {
 func (e CommandError) Unwrap() error {
 	return e.Err
 }
+
+// UnsupportedArchError reports a target architecture the installer has no
+// package for.
+type UnsupportedArchError struct {
+	Arch    string
+	Package string
+}
+
+func (e UnsupportedArchError) Error() string {
+	if e.Package != "" {
+		return fmt.Sprintf("unsupported architecture %q for package %s", e.Arch, e.Package)
+	}
+	return fmt.Sprintf("unsupported architecture %q", e.Arch)
+}
+
+// ElevationError reports that sudo or doas refused to run a command, e.g.
+// after a wrong password, rather than the command itself failing.
+type ElevationError struct {
+	Stderr string
+}
+
+func (e ElevationError) Error() string {
+	return fmt.Sprintf("privilege elevation was refused: %s", e.Stderr)
+}
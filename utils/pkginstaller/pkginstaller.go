@@ -3,6 +3,8 @@ package pkginstaller
 import (
 	"fmt"
 	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
 )
 
 // Runner executes commands on the target system.
@@ -10,19 +12,33 @@ type Runner interface {
 	Run(cmd string) (stdout string, stderr string, err error)
 }
 
+// Execer is implemented by runners that report exit codes, such as
+// *privilege.ElevatedClient and phases.TracedRunner. Exec returns a non-zero
+// exit status as exitCode and sets err only when the command did not run to
+// completion. With one, a check that exits non-zero means "not installed"
+// while a failed session or a refused sudo is returned as an error; a plain
+// Runner cannot tell them apart and treats all of them as "not installed".
+type Execer interface {
+	Exec(cmd string) (stdout, stderr string, exitCode int, err error)
+}
+
 // Result reports actions taken by Installer.
 type Result struct {
 	PackageName string
 	Installed   bool
 	Skipped     bool
+	// Arch is the target architecture when it was set or detected.
+	Arch string
 }
 
 // Option configures Installer behavior.
 type Option func(*options) error
 
 type options struct {
-	checkCmd string
-	force    bool
+	checkCmd     string
+	force        bool
+	arch         string
+	archPackages map[string]string
 }
 
 // WithCustomCheck overrides the command used to detect existing packages.
@@ -56,29 +72,33 @@ func Ensure(r Runner, packageName string, opts ...Option) (*Result, error) {
 		return nil, ValidationError{Reason: "package name is required"}
 	}
 
-	config := options{}
-	for _, opt := range opts {
-		if opt == nil {
-			continue
-		}
-		if err := opt(&config); err != nil {
-			return nil, err
-		}
+	config, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	installName, arch, err := resolveArchPackage(r, packageName, config)
+	if err != nil {
+		return nil, err
 	}
 
-	result := &Result{PackageName: packageName}
+	result := &Result{PackageName: installName, Arch: arch}
 	if !config.force {
 		checkCmd := config.checkCmd
 		if checkCmd == "" {
-			checkCmd = fmt.Sprintf("command -v %s >/dev/null 2>&1", shellQuote(packageName))
+			checkCmd = fmt.Sprintf("command -v %s >/dev/null 2>&1", shellquote.Quote(packageName))
 		}
-		if err := runCheck(r, checkCmd); err == nil {
+		present, err := runCheck(r, checkCmd)
+		if err != nil {
+			return nil, err
+		}
+		if present {
 			result.Skipped = true
 			return result, nil
 		}
 	}
 
-	installCmd, err := buildInstallCommand(packageName)
+	installCmd, err := buildInstallCommand(installName)
 	if err != nil {
 		return nil, err
 	}
@@ -91,15 +111,77 @@ func Ensure(r Runner, packageName string, opts ...Option) (*Result, error) {
 	return result, nil
 }
 
-func runCheck(r Runner, cmd string) error {
+func applyOptions(opts []Option) (options, error) {
+	config := options{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&config); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// runCheck reports whether the check command succeeded.
+func runCheck(r Runner, cmd string) (bool, error) {
+	if e, ok := r.(Execer); ok {
+		_, stderr, code, err := e.Exec(cmd)
+		if err != nil {
+			return false, CommandError{Step: "check", Err: err, Stderr: stderr}
+		}
+		// sudo and doas exit 1 when they refuse, like a failed check.
+		if code != 0 && elevationRefused(stderr) {
+			return false, CommandError{Step: "check", Err: ElevationError{Stderr: strings.TrimSpace(stderr)}, Stderr: stderr}
+		}
+		return code == 0, nil
+	}
 	_, _, err := r.Run(cmd)
-	return err
+	return err == nil, nil
+}
+
+// elevationMessages are what sudo and doas print when they refuse to run a
+// command, e.g. after a wrong password.
+var elevationMessages = []string{
+	"sudo: a password is required",
+	"sudo: no password was provided",
+	"incorrect password attempt",
+	"sorry, try again",
+	"is not in the sudoers file",
+	"is not allowed to execute",
+	"sudo: a terminal is required",
+	"doas: authentication failed",
+	"doas: a password is required",
+	"doas: operation not permitted",
+}
+
+func elevationRefused(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, msg := range elevationMessages {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+	return false
 }
 
-func buildInstallCommand(packageName string) (string, error) {
-	quoted := shellQuote(packageName)
+// buildInstallCommand installs every package with one package manager
+// invocation.
+func buildInstallCommand(packageNames ...string) (string, error) {
+	quoted := quoteAll(packageNames, nil)
 	cmd := fmt.Sprintf(`
 set -euo pipefail
+case "$(uname -s)" in
+FreeBSD)
+	env ASSUME_ALWAYS_YES=yes pkg install -y %s
+	exit 0
+	;;
+OpenBSD)
+	pkg_add -I %s
+	exit 0
+	;;
+esac
 if command -v apt-get >/dev/null 2>&1; then
 	export DEBIAN_FRONTEND=noninteractive
 	apt-get update -y >/dev/null 2>&1
@@ -110,14 +192,56 @@ elif command -v dnf >/dev/null 2>&1; then
 	dnf install -y %s
 elif command -v zypper >/dev/null 2>&1; then
 	zypper --non-interactive install -y %s
+elif command -v apk >/dev/null 2>&1; then
+	apk add --no-cache %s
+elif command -v pacman >/dev/null 2>&1; then
+	pacman -Sy --noconfirm --needed %s
 else
 	echo "no supported package manager found" >&2
 	exit 1
 fi
-`, quoted, quoted, quoted, quoted)
+`, quoted, quoteAll(packageNames, openBSDPackage), quoted, quoted, quoted, quoted, quoted, quoteAll(packageNames, pacmanPackage))
 	return cmd, nil
 }
 
+// quoteAll shell-quotes names, renamed by rename when it is not nil, and
+// joins them with spaces.
+func quoteAll(names []string, rename func(string) string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		if rename != nil {
+			name = rename(name)
+		}
+		quoted = append(quoted, shellquote.Quote(name))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// openBSDPackages maps package names whose pkg_add stem is ambiguous without a
+// version flavor.
+var openBSDPackages = map[string]string{
+	"python3": "python%3",
+}
+
+func openBSDPackage(name string) string {
+	if mapped, ok := openBSDPackages[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+// pacmanPackages maps package names that Arch Linux packages under another name.
+var pacmanPackages = map[string]string{
+	"python3": "python",
+}
+
+func pacmanPackage(name string) string {
+	if mapped, ok := pacmanPackages[name]; ok {
+		return mapped
+	}
+	return name
+}
+
 func runInstall(r Runner, cmd string) error {
 	_, stderr, err := r.Run(cmd)
 	if err != nil {
@@ -125,10 +249,3 @@ func runInstall(r Runner, cmd string) error {
 	}
 	return nil
 }
-
-func shellQuote(value string) string {
-	if value == "" {
-		return "''"
-	}
-	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
-}
@@ -39,6 +39,96 @@ func TestEnsureInstallsWhenMissing(t *testing.T) {
 	require.True(t, result.Installed)
 }
 
+func TestBuildInstallCommandHandlesBSD(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := buildInstallCommand("python3")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "pkg install -y 'python3'")
+	require.Contains(t, cmd, "pkg_add -I 'python%3'")
+	require.Contains(t, cmd, "apt-get install -y 'python3'")
+}
+
+func TestBuildInstallCommandHandlesApkAndPacman(t *testing.T) {
+	t.Parallel()
+
+	cmd, err := buildInstallCommand("python3")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "apk add --no-cache 'python3'")
+	require.Contains(t, cmd, "pacman -Sy --noconfirm --needed 'python'")
+
+	cmd, err = buildInstallCommand("sudo")
+	require.NoError(t, err)
+	require.Contains(t, cmd, "pacman -Sy --noconfirm --needed 'sudo'")
+}
+
+func TestEnsureChoosesArchPackage(t *testing.T) {
+	t.Parallel()
+
+	packages := map[string]string{
+		ArchAMD64: "widget-amd64",
+		"aarch64": "widget-arm64",
+	}
+
+	tests := []struct {
+		name      string
+		machine   string
+		wantPkg   string
+		wantArch  string
+		wantError bool
+	}{
+		{name: "amd64", machine: "x86_64\n", wantPkg: "widget-amd64", wantArch: ArchAMD64},
+		{name: "arm64", machine: "aarch64\n", wantPkg: "widget-arm64", wantArch: ArchARM64},
+		{name: "unlisted arch", machine: "armv7l\n", wantError: true},
+		{name: "unknown arch", machine: "riscv64\n", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &fakeRunner{
+				responses: []fakeResponse{
+					{match: "uname -m", stdout: tt.machine},
+					{match: "command -v 'widget'", err: errors.New("exit status 1")},
+					{match: "apt-get install -y '" + tt.wantPkg + "'"},
+				},
+			}
+
+			result, err := Ensure(r, "widget", WithArchPackages(packages))
+			if tt.wantError {
+				var archErr UnsupportedArchError
+				require.ErrorAs(t, err, &archErr)
+				require.Len(t, r.responses, 2, "must fail before checking or installing")
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, result.Installed)
+			require.Equal(t, tt.wantPkg, result.PackageName)
+			require.Equal(t, tt.wantArch, result.Arch)
+		})
+	}
+}
+
+func TestEnsureUsesGivenArch(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "command -v", err: errors.New("exit status 1")},
+			{match: "apt-get install -y 'widget-arm64'"},
+		},
+	}
+
+	result, err := Ensure(r, "widget", WithArch("arm64"), WithArchPackages(map[string]string{ArchARM64: "widget-arm64"}))
+	require.NoError(t, err)
+	require.Equal(t, ArchARM64, result.Arch)
+
+	_, err = Ensure(r, "widget", WithArch("sparc64"))
+	require.ErrorAs(t, err, new(UnsupportedArchError))
+	_, err = Ensure(r, "widget", WithArchPackages(map[string]string{"sparc64": "widget"}))
+	require.ErrorAs(t, err, new(OptionError))
+}
+
 func TestEnsureValidatesInputs(t *testing.T) {
 	t.Parallel()
 
@@ -96,3 +186,48 @@ func (f *fakeRunner) Run(cmd string) (string, string, error) {
 
 	return resp.stdout, resp.stderr, resp.err
 }
+
+// execRunner answers checks with exit codes like *privilege.ElevatedClient.
+type execRunner struct {
+	fakeRunner
+	code    int
+	stderr  string
+	execErr error
+}
+
+func (r *execRunner) Exec(string) (string, string, int, error) {
+	stderr := r.stderr
+	if stderr == "" {
+		stderr = "check"
+	}
+	return "", stderr, r.code, r.execErr
+}
+
+func TestEnsureTellsFailedChecksFromFailedSessions(t *testing.T) {
+	t.Parallel()
+
+	r := &execRunner{code: 1, fakeRunner: fakeRunner{responses: []fakeResponse{{match: "apt-get update"}}}}
+	result, err := Ensure(r, "python3")
+	require.NoError(t, err)
+	require.True(t, result.Installed)
+
+	r = &execRunner{code: 0}
+	result, err = Ensure(r, "python3")
+	require.NoError(t, err)
+	require.True(t, result.Skipped)
+
+	sessionErr := errors.New("ssh: unexpected packet in response to channel open")
+	r = &execRunner{execErr: sessionErr}
+	_, err = Ensure(r, "python3")
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, "check", cmdErr.Step)
+	require.ErrorIs(t, err, sessionErr)
+
+	// A refused sudo also exits 1 but must not start an install.
+	r = &execRunner{code: 1, stderr: "Sorry, try again.\nsudo: 1 incorrect password attempt\n"}
+	_, err = Ensure(r, "python3")
+	var elevationErr ElevationError
+	require.ErrorAs(t, err, &elevationErr)
+	require.Contains(t, elevationErr.Stderr, "incorrect password")
+}
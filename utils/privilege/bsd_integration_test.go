@@ -0,0 +1,58 @@
+//go:build integration
+
+package privilege_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/pkginstaller"
+	"github.com/BrianJOC/ansible-host-prep/utils/privilege"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshkeypair"
+	"github.com/BrianJOC/ansible-host-prep/utils/systemuser"
+)
+
+// TestBSDTargetPrep preps a disposable BSD target end to end. `just test-bsd`
+// boots a stock FreeBSD VM with testdata/bsd-vm.sh and points the test at it;
+// `just test-bsd-target` runs it against a jail or VM of your own, such as an
+// OpenBSD one, described by:
+//
+//	HOST_PREP_BSD_HOST=127.0.0.1 HOST_PREP_BSD_PORT=2222 \
+//	HOST_PREP_BSD_USER=admin HOST_PREP_BSD_PASSWORD=... \
+//	go test -tags integration ./utils/privilege/
+func TestBSDTargetPrep(t *testing.T) {
+	host := os.Getenv("HOST_PREP_BSD_HOST")
+	if host == "" {
+		t.Skip("HOST_PREP_BSD_HOST not set")
+	}
+	port, _ := strconv.Atoi(os.Getenv("HOST_PREP_BSD_PORT"))
+	password := os.Getenv("HOST_PREP_BSD_PASSWORD")
+
+	client, err := sshconnection.Connect(host, port, os.Getenv("HOST_PREP_BSD_USER"), sshconnection.Credential{Password: password})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	elevated, err := privilege.EnsureElevatedClient(client, privilege.Password{Value: password})
+	require.NoError(t, err)
+	stdout, _, err := elevated.Run("id -u")
+	require.NoError(t, err)
+	require.Equal(t, "0\n", stdout)
+
+	_, err = pkginstaller.Ensure(elevated, "python3", pkginstaller.WithCustomCheck("command -v python3 >/dev/null 2>&1"))
+	require.NoError(t, err)
+
+	keys, err := sshkeypair.EnsureKeyPair(t.TempDir() + "/id_ansible")
+	require.NoError(t, err)
+	pub, err := os.ReadFile(keys.PublicPath)
+	require.NoError(t, err)
+	res, err := systemuser.EnsureUser(elevated, "ansible", string(pub), systemuser.WithPasswordlessSudo())
+	require.NoError(t, err)
+	require.True(t, res.PasswordlessConfigured)
+
+	_, _, err = elevated.Run("su -m ansible -c 'sudo -n true'")
+	require.NoError(t, err)
+}
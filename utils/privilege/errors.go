@@ -3,6 +3,7 @@ package privilege
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // NilClientError indicates EnsureElevatedClient received a nil SSH client.
@@ -12,6 +13,33 @@ func (NilClientError) Error() string {
 	return "ssh client is required"
 }
 
+// OptionError reports EnsureElevatedClient options that cannot be combined.
+type OptionError struct {
+	Reason string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("privilege option error: %s", e.Reason)
+}
+
+// CommandTimeoutError reports a privileged command that was killed because
+// it ran past its timeout, e.g. a package manager waiting on a prompt.
+type CommandTimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e CommandTimeoutError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("privileged command timed out after %s", e.Timeout)
+	}
+	return "privileged command timed out"
+}
+
+func (e CommandTimeoutError) Unwrap() error {
+	return e.Err
+}
+
 // PasswordError captures validation issues with the password object.
 type PasswordError struct {
 	Reason string
@@ -106,3 +134,18 @@ func (e EnsureSudoError) Error() string {
 func (e EnsureSudoError) Unwrap() error {
 	return e.Err
 }
+
+// PasswordlessSudoError reports that sudo still asks for a password, or fails
+// outright, for a user that should have NOPASSWD access.
+type PasswordlessSudoError struct {
+	Err    error
+	Stderr string
+}
+
+func (e PasswordlessSudoError) Error() string {
+	return fmt.Sprintf("passwordless sudo check failed: %v (%s)", e.Err, strings.TrimSpace(e.Stderr))
+}
+
+func (e PasswordlessSudoError) Unwrap() error {
+	return e.Err
+}
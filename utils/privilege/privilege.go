@@ -2,12 +2,20 @@ package privilege
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/remoteexec"
+	"github.com/BrianJOC/ansible-host-prep/utils/remotetmp"
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+	"github.com/BrianJOC/ansible-host-prep/utils/sshconnection"
 )
 
 const ensureSudoScript = `
@@ -15,6 +23,16 @@ set -euo pipefail
 if command -v sudo >/dev/null 2>&1; then
 	exit 0
 fi
+case "$(uname -s)" in
+FreeBSD)
+	env ASSUME_ALWAYS_YES=yes pkg install -y sudo >/dev/null 2>&1
+	exit $?
+	;;
+OpenBSD)
+	pkg_add -I sudo-- >/dev/null 2>&1
+	exit $?
+	;;
+esac
 if command -v apt-get >/dev/null 2>&1; then
 	apt-get update -y >/dev/null 2>&1 && apt-get install -y sudo >/dev/null 2>&1
 elif command -v yum >/dev/null 2>&1; then
@@ -23,6 +41,10 @@ elif command -v dnf >/dev/null 2>&1; then
 	dnf install -y sudo >/dev/null 2>&1
 elif command -v zypper >/dev/null 2>&1; then
 	zypper --non-interactive install -y sudo >/dev/null 2>&1
+elif command -v apk >/dev/null 2>&1; then
+	apk add --no-cache sudo >/dev/null 2>&1
+elif command -v pacman >/dev/null 2>&1; then
+	pacman -Sy --noconfirm --needed sudo >/dev/null 2>&1
 else
 	echo "unable to install sudo: no supported package manager found" >&2
 	exit 1
@@ -34,18 +56,44 @@ type elevationMethod string
 const (
 	methodSudo elevationMethod = "sudo"
 	methodSu   elevationMethod = "su"
+	methodDoas elevationMethod = "doas"
+	// methodNone runs commands as they are, for an SSH user that is root.
+	methodNone elevationMethod = "none"
+)
+
+// Shells used to run privileged commands: bash on Linux, the base sh on BSD
+// where bash is not installed by default.
+const (
+	shellBash = "bash"
+	shellSh   = "sh"
 )
 
+// bsdSystems are the `uname -s` values that get the BSD elevation flow.
+var bsdSystems = map[string]bool{"FreeBSD": true, "OpenBSD": true, "NetBSD": true, "DragonFly": true}
+
 // Password wraps the credential used for privilege escalation.
 type Password struct {
 	Value string
 }
 
+// DefaultCommandTimeout bounds each privileged command, so one that hangs,
+// such as a package manager waiting on a prompt, fails instead of blocking
+// the run forever. It leaves room for slow package installs.
+const DefaultCommandTimeout = 30 * time.Minute
+
 // ElevatedClient ensures privileged commands are executed with the chosen method.
+// It is safe for concurrent use: each command gets its own SSH session, so
+// the password written to one command's stdin never reaches another, and at
+// most DefaultMaxSessions commands (see WithMaxSessions) run at once,
+// admitted in arrival order.
 type ElevatedClient struct {
 	client   *ssh.Client
 	method   elevationMethod
+	shell    string
 	password string
+	gate     *sessionGate
+	timeout  time.Duration
+	env      map[string]string
 }
 
 // Client exposes the underlying SSH client.
@@ -53,19 +101,194 @@ func (c *ElevatedClient) Client() *ssh.Client {
 	return c.client
 }
 
-// Method returns how elevation is performed ("sudo" or "su").
+// Method returns how elevation is performed ("sudo", "su", or "doas"), or
+// "none" when the SSH user is root.
 func (c *ElevatedClient) Method() string {
 	return string(c.method)
 }
 
+// WithEnv returns a client that exports env to every command it runs, e.g.
+// DEBIAN_FRONTEND=noninteractive or proxy settings, on top of any variables
+// c already sets. The variables are set inside the root shell, so sudo's
+// env_reset does not drop them, and values are quoted for the shell. The
+// copy shares c's connection and session limit; c is unchanged. Run fails
+// with an OptionError when a name is not a valid shell variable name.
+func (c *ElevatedClient) WithEnv(env map[string]string) *ElevatedClient {
+	clone := *c
+	clone.env = make(map[string]string, len(c.env)+len(env))
+	maps.Copy(clone.env, c.env)
+	maps.Copy(clone.env, env)
+	return &clone
+}
+
 // Run executes the given command with elevated privileges and returns stdout/stderr.
 func (c *ElevatedClient) Run(cmd string) (string, string, error) {
-	runner := &sshRunner{client: c.client}
-	return runPrivileged(runner, c.method, c.password, cmd)
+	return c.RunContext(context.Background(), cmd)
+}
+
+// RunContext is Run bounded by ctx and the command timeout. When either
+// ends first, the remote command is sent SIGKILL and its session closed; a
+// timeout is reported as CommandTimeoutError and a cancellation as
+// ctx.Err().
+func (c *ElevatedClient) RunContext(ctx context.Context, cmd string) (string, string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	prefix, err := exportPrefix(c.env)
+	if err != nil {
+		return "", "", err
+	}
+	cmd = prefix + cmd
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	if c.gate != nil {
+		if err := c.gate.acquire(ctx); err != nil {
+			return "", "", c.contextError(err)
+		}
+		defer c.gate.release()
+	}
+	runner := &sshRunner{client: c.client, ctx: ctx}
+	shell := c.shell
+	if shell == "" {
+		shell = shellBash
+	}
+	stdout, stderr, err := runPrivilegedWith(runner, c.method, shell, c.password, cmd)
+	if err != nil && ctx.Err() != nil {
+		return stdout, stderr, c.contextError(ctx.Err())
+	}
+	return stdout, stderr, err
+}
+
+// exportPrefix renders env as an export statement to put before a command.
+func exportPrefix(env map[string]string) (string, error) {
+	if len(env) == 0 {
+		return "", nil
+	}
+	assignments := make([]string, 0, len(env))
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if !validEnvName(name) {
+			return "", OptionError{Reason: fmt.Sprintf("%q is not a valid environment variable name", name)}
+		}
+		assignments = append(assignments, name+"="+shellquote.Quote(env[name]))
+	}
+	return "export " + strings.Join(assignments, " ") + "; ", nil
+}
+
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *ElevatedClient) contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return CommandTimeoutError{Timeout: c.timeout, Err: err}
+	}
+	return err
+}
+
+// Option adjusts how EnsureElevatedClient gains root.
+type Option func(*elevationOptions) error
+
+type elevationOptions struct {
+	preferSu    bool
+	sudoOnly    bool
+	noInstall   bool
+	maxSessions int
+	timeout     *time.Duration
+}
+
+// WithPreferSu tries su before sudo (and, on BSD, before doas), for hosts
+// where the root password is the sanctioned way in. sudo is still used when
+// su fails.
+func WithPreferSu() Option {
+	return func(opts *elevationOptions) error {
+		opts.preferSu = true
+		return nil
+	}
+}
+
+// WithSudoOnly elevates with sudo and nothing else: no doas and no su
+// fallback, so a missing or denied sudo fails with its own error.
+func WithSudoOnly() Option {
+	return func(opts *elevationOptions) error {
+		opts.sudoOnly = true
+		return nil
+	}
+}
+
+// WithoutSudoInstall never installs sudo, for operators who do not want
+// packages installed at this step. Elevation falls back to su on hosts
+// without sudo, and the ansible user's sudoers drop-in only takes effect
+// once sudo is installed some other way.
+func WithoutSudoInstall() Option {
+	return func(opts *elevationOptions) error {
+		opts.noInstall = true
+		return nil
+	}
+}
+
+// WithMaxSessions sets how many privileged commands the client runs at once
+// on the connection (default DefaultMaxSessions); 1 serializes them.
+func WithMaxSessions(n int) Option {
+	return func(opts *elevationOptions) error {
+		if n < 1 {
+			return OptionError{Reason: "max sessions must be at least 1"}
+		}
+		opts.maxSessions = n
+		return nil
+	}
+}
+
+// WithCommandTimeout sets how long each privileged command may run (default
+// DefaultCommandTimeout); 0 removes the limit.
+func WithCommandTimeout(d time.Duration) Option {
+	return func(opts *elevationOptions) error {
+		if d < 0 {
+			return OptionError{Reason: "command timeout must not be negative"}
+		}
+		opts.timeout = &d
+		return nil
+	}
+}
+
+func (o elevationOptions) ensureSudo(r runner, method elevationMethod, shell, password string) error {
+	if o.noInstall {
+		return nil
+	}
+	return ensureSudoInstalled(r, method, shell, password)
+}
+
+// Exec runs cmd like Run but reports a non-zero exit status as exitCode
+// rather than as an error, so "the check returned 1" can be told apart from
+// "the SSH session failed". err is set only when the command could not be
+// run or its exit status never arrived. exitCode is 128+n when the command
+// was killed by signal n; under sudo, an authentication failure also exits
+// with 1.
+func (c *ElevatedClient) Exec(cmd string) (stdout, stderr string, exitCode int, err error) {
+	stdout, stderr, err = c.Run(cmd)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout, stderr, exitErr.ExitStatus(), nil
+	}
+	return stdout, stderr, 0, err
 }
 
 // EnsureElevatedClient verifies privileged access and installs sudo when necessary.
-func EnsureElevatedClient(client *ssh.Client, password Password) (*ElevatedClient, error) {
+// On BSD targets it prefers doas (which needs a nopass rule for the SSH user,
+// since doas cannot read a password from stdin) and otherwise falls back to
+// the sudo/su flow; commands run under sh instead of bash there. Options
+// change which methods are tried and whether sudo may be installed.
+func EnsureElevatedClient(client *ssh.Client, password Password, opts ...Option) (*ElevatedClient, error) {
 	if client == nil {
 		return nil, NilClientError{}
 	}
@@ -75,76 +298,260 @@ func EnsureElevatedClient(client *ssh.Client, password Password) (*ElevatedClien
 		return nil, err
 	}
 
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.preferSu && cfg.sudoOnly {
+		return nil, OptionError{Reason: "preferring su and using sudo only are mutually exclusive"}
+	}
+
+	runner := &sshRunner{client: client}
+	shell := shellBash
+	var method elevationMethod
+	if isBSD(runner) {
+		shell = shellSh
+		method, err = ensureElevationBSD(runner, pass, cfg)
+	} else {
+		method, err = ensureElevation(runner, pass, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newElevatedClient(client, method, shell, pass, cfg), nil
+}
+
+// PasswordlessClient returns an ElevatedClient for a login that needs no
+// password to act as root: commands run as they are when the SSH user is
+// root, and otherwise through sudo, which must let the user in without a
+// password (PasswordlessSudoError when it does not). Nothing is installed on
+// the target, so it suits read-only tools such as audit-keys. Of the
+// options, only WithMaxSessions and WithCommandTimeout apply.
+func PasswordlessClient(client *ssh.Client, opts ...Option) (*ElevatedClient, error) {
+	if client == nil {
+		return nil, NilClientError{}
+	}
+	cfg, err := applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 	runner := &sshRunner{client: client}
-	method, err := ensureElevation(runner, pass)
+	shell := shellBash
+	if isBSD(runner) {
+		shell = shellSh
+	}
+	method, err := passwordlessMethod(runner)
 	if err != nil {
 		return nil, err
 	}
+	return newElevatedClient(client, method, shell, "", cfg), nil
+}
 
+func passwordlessMethod(r runner) (elevationMethod, error) {
+	if stdout, _, err := r.Run("id -u", ""); err == nil && strings.TrimSpace(stdout) == "0" {
+		return methodNone, nil
+	}
+	if err := verifyPasswordless(r); err != nil {
+		return "", err
+	}
+	return methodSudo, nil
+}
+
+func applyOptions(opts []Option) (elevationOptions, error) {
+	var cfg elevationOptions
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return elevationOptions{}, err
+		}
+	}
+	return cfg, nil
+}
+
+func newElevatedClient(client *ssh.Client, method elevationMethod, shell, password string, cfg elevationOptions) *ElevatedClient {
+	maxSessions := cfg.maxSessions
+	if maxSessions == 0 {
+		maxSessions = DefaultMaxSessions
+	}
+	timeout := DefaultCommandTimeout
+	if cfg.timeout != nil {
+		timeout = *cfg.timeout
+	}
 	return &ElevatedClient{
 		client:   client,
 		method:   method,
-		password: pass,
-	}, nil
+		shell:    shell,
+		password: password,
+		gate:     newSessionGate(maxSessions),
+		timeout:  timeout,
+	}
 }
 
-func ensureElevation(r runner, password string) (elevationMethod, error) {
-	if err := validateSudo(r, password); err == nil {
-		if err := ensureSudoInstalled(r, methodSudo, password); err != nil {
-			return "", err
+// VerifyPasswordlessSudo runs `sudo -n true` over client, which should be
+// logged in as the user whose sudoers drop-in is being checked. It catches
+// sudoers syntax and ordering problems (e.g. a later rule overriding NOPASSWD)
+// that writing the file alone would not reveal. When the rule is limited to
+// commands, `true` is not allowed, so `sudo -n -l` checks that each of them
+// may run without a password instead.
+func VerifyPasswordlessSudo(client *ssh.Client, commands ...string) error {
+	if client == nil {
+		return NilClientError{}
+	}
+	return verifyPasswordless(&sshRunner{client: client}, commands...)
+}
+
+func verifyPasswordless(r runner, commands ...string) error {
+	cmd := "sudo -n true"
+	if len(commands) > 0 {
+		checks := make([]string, len(commands))
+		for i, command := range commands {
+			words := strings.Fields(command)
+			for j, word := range words {
+				words[j] = shellquote.Quote(word)
+			}
+			checks[i] = "sudo -n -l -- " + strings.Join(words, " ") + " >/dev/null"
 		}
-		return methodSudo, nil
-	} else {
-		var permErr SudoPermissionError
-		var missingErr SudoNotInstalledError
-		var authErr SudoAuthenticationError
-		switch {
-		case errors.As(err, &authErr):
-			return "", err
-		case errors.As(err, &permErr):
-			if err := ensureRootViaSu(r, password); err != nil {
+		cmd = strings.Join(checks, " && ")
+	}
+	_, stderr, err := r.Run(cmd, "")
+	if err != nil {
+		return PasswordlessSudoError{Err: err, Stderr: stderr}
+	}
+	return nil
+}
+
+func isBSD(r runner) bool {
+	stdout, _, err := r.Run("uname -s", "")
+	return err == nil && bsdSystems[strings.TrimSpace(stdout)]
+}
+
+// ensureElevationBSD uses doas when it works without a password and falls
+// back to sudo/su. sudo is still installed so the ansible user's sudoers
+// drop-in takes effect.
+func ensureElevationBSD(r runner, password string, cfg elevationOptions) (elevationMethod, error) {
+	if !cfg.preferSu && !cfg.sudoOnly {
+		if _, _, err := runPrivilegedWith(r, methodDoas, shellSh, password, "true"); err == nil {
+			if err := cfg.ensureSudo(r, methodDoas, shellSh, password); err != nil {
 				return "", err
 			}
-			if err := ensureSudoInstalled(r, methodSu, password); err != nil {
+			return methodDoas, nil
+		}
+	}
+	return ensureElevationWith(r, shellSh, password, cfg)
+}
+
+func ensureElevation(r runner, password string, cfg elevationOptions) (elevationMethod, error) {
+	return ensureElevationWith(r, shellBash, password, cfg)
+}
+
+func ensureElevationWith(r runner, shell, password string, cfg elevationOptions) (elevationMethod, error) {
+	var suErr error
+	if cfg.preferSu {
+		if suErr = ensureRootViaSu(r, shell, password); suErr == nil {
+			if err := cfg.ensureSudo(r, methodSu, shell, password); err != nil {
 				return "", err
 			}
 			return methodSu, nil
-		case errors.As(err, &missingErr):
-			if err := ensureRootViaSu(r, password); err != nil {
-				return "", err
-			}
-			if err := ensureSudoInstalled(r, methodSu, password); err != nil {
+		}
+	}
+
+	err := validateSudo(r, shell, password)
+	if err == nil {
+		if err := cfg.ensureSudo(r, methodSudo, shell, password); err != nil {
+			return "", err
+		}
+		return methodSudo, nil
+	}
+	var permErr SudoPermissionError
+	var missingErr SudoNotInstalledError
+	var authErr SudoAuthenticationError
+	switch {
+	case errors.As(err, &authErr), cfg.sudoOnly:
+		return "", err
+	case !errors.As(err, &permErr) && !errors.As(err, &missingErr):
+		return "", err
+	case suErr != nil:
+		// su was preferred and already failed.
+		return "", suErr
+	}
+
+	if err := ensureRootViaSu(r, shell, password); err != nil {
+		return "", err
+	}
+	if err := cfg.ensureSudo(r, methodSu, shell, password); err != nil {
+		return "", err
+	}
+	if errors.As(err, &missingErr) && !cfg.noInstall {
+		if err := validateSudo(r, shell, password); err == nil {
+			if err := ensureSudoInstalled(r, methodSudo, shell, password); err != nil {
 				return "", err
 			}
-			if err := validateSudo(r, password); err == nil {
-				if err := ensureSudoInstalled(r, methodSudo, password); err != nil {
-					return "", err
-				}
-				return methodSudo, nil
-			}
-			return methodSu, nil
-		default:
-			return "", err
+			return methodSudo, nil
 		}
 	}
+	return methodSu, nil
 }
 
 type runner interface {
 	Run(cmd string, stdin string) (string, string, error)
+	// RunPrompted runs cmd and writes answer to its stdin only once prompt
+	// appears on stderr; the prompt is removed from the returned stderr.
+	RunPrompted(cmd, prompt, answer string) (string, string, error)
+}
+
+// uploader is implemented by runners that can copy scripts to the target.
+type uploader interface {
+	uploader() remoteexec.Uploader
 }
 
 type sshRunner struct {
 	client *ssh.Client
+	// ctx, when set, kills the remote command once it is done.
+	ctx context.Context
 }
 
-func (r *sshRunner) Run(cmd string, stdin string) (string, string, error) {
+// watch kills the command on session once r.ctx is done. SIGKILL needs
+// OpenSSH 7.9 or later; closing the session ends the wait either way.
+func (r *sshRunner) watch(session *ssh.Session) func() bool {
+	if r.ctx == nil {
+		return func() bool { return false }
+	}
+	return context.AfterFunc(r.ctx, func() {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+	})
+}
+
+// newSession opens a session with the operator's agent forwarded when the
+// client was connected with sshconnection.WithAgentForwarding.
+func (r *sshRunner) newSession() (*ssh.Session, error) {
 	session, err := r.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	if err := sshconnection.RequestAgentForwarding(r.client, session); err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *sshRunner) uploader() remoteexec.Uploader {
+	return remoteexec.NewSFTPUploader(r.client)
+}
+
+func (r *sshRunner) Run(cmd string, stdin string) (string, string, error) {
+	session, err := r.newSession()
 	if err != nil {
 		return "", "", err
 	}
 	defer func() {
 		_ = session.Close()
 	}()
+	defer r.watch(session)()
 
 	var stdout, stderr bytes.Buffer
 	session.Stdout = &stdout
@@ -158,21 +565,33 @@ func (r *sshRunner) Run(cmd string, stdin string) (string, string, error) {
 }
 
 func runPrivileged(r runner, method elevationMethod, password, cmd string) (string, string, error) {
-	quotedCmd := shellQuote(cmd)
+	return runPrivilegedWith(r, method, shellBash, password, cmd)
+}
+
+func runPrivilegedWith(r runner, method elevationMethod, shell, password, cmd string) (string, string, error) {
+	quotedCmd := shellquote.Quote(cmd)
 	switch method {
 	case methodSudo:
-		command := fmt.Sprintf("sudo -S -p '' -k bash -c %s", quotedCmd)
-		return r.Run(command, password+"\n")
+		return r.RunPrompted(sudoCommand(shell, cmd), sudoPrompt, password)
 	case methodSu:
+		if shell != shellBash {
+			// root's login shell may be csh on BSD.
+			quotedCmd = shellquote.Quote(shell + " -c " + quotedCmd)
+		}
 		command := fmt.Sprintf("su - root -c %s", quotedCmd)
 		return r.Run(command, password+"\n")
+	case methodDoas:
+		command := fmt.Sprintf("doas -n %s -c %s", shell, quotedCmd)
+		return r.Run(command, "")
+	case methodNone:
+		return r.Run(fmt.Sprintf("%s -c %s", shell, quotedCmd), "")
 	default:
 		return "", "", fmt.Errorf("unsupported elevation method %q", method)
 	}
 }
 
-func ensureRootViaSu(r runner, password string) error {
-	_, stderr, err := runPrivileged(r, methodSu, password, "true")
+func ensureRootViaSu(r runner, shell, password string) error {
+	_, stderr, err := runPrivilegedWith(r, methodSu, shell, password, "true")
 	if err != nil {
 		if isAuthenticationFailure(stderr) {
 			return SuAuthenticationError{Err: err}
@@ -182,23 +601,60 @@ func ensureRootViaSu(r runner, password string) error {
 	return nil
 }
 
-func ensureSudoInstalled(r runner, method elevationMethod, password string) error {
-	encoded := base64.StdEncoding.EncodeToString([]byte(ensureSudoScript))
-	command := fmt.Sprintf("printf %%s %s | base64 -d | bash", shellQuote(encoded))
-	_, stderr, err := runPrivileged(r, method, password, command)
+func ensureSudoInstalled(r runner, method elevationMethod, shell, password string) error {
+	_, stderr, err := runScript(r, method, shell, password, "ensure-sudo.sh", ensureSudoScript)
 	if err != nil {
 		return EnsureSudoError{Err: err, Stderr: stderr}
 	}
 	return nil
 }
 
-func validateSudo(r runner, password string) error {
-	_, stderr, err := runPrivileged(r, methodSudo, password, "true")
+// runScript uploads script over SFTP to a private temp dir and runs it with
+// elevated privileges. Runners that cannot upload, and hosts without SFTP,
+// get the script inline instead.
+func runScript(r runner, method elevationMethod, shell, password, name, script string) (string, string, error) {
+	u, ok := r.(uploader)
+	if !ok {
+		return runPrivilegedWith(r, method, shell, password, script)
+	}
+	dir, err := remotetmp.Create(runnerFunc(func(cmd string) (string, string, error) {
+		return r.Run(cmd, "")
+	}), "privilege")
+	if err != nil {
+		return runPrivilegedWith(r, method, shell, password, script)
+	}
+	defer func() {
+		_ = dir.Remove()
+	}()
+
+	privileged := runnerFunc(func(cmd string) (string, string, error) {
+		return runPrivilegedWith(r, method, shell, password, cmd)
+	})
+	res, err := remoteexec.Run(privileged, u.uploader(), dir, name, []byte(script), remoteexec.WithInterpreter(shell))
+	if errors.As(err, new(remoteexec.UploadError)) {
+		return runPrivilegedWith(r, method, shell, password, script)
+	}
+	if res == nil {
+		return "", "", err
+	}
+	return res.Stdout, res.Stderr, err
+}
+
+// runnerFunc adapts a function to the single-argument Runner interfaces of
+// remotetmp and remoteexec.
+type runnerFunc func(cmd string) (string, string, error)
+
+func (f runnerFunc) Run(cmd string) (string, string, error) {
+	return f(cmd)
+}
+
+func validateSudo(r runner, shell, password string) error {
+	_, stderr, err := runPrivilegedWith(r, methodSudo, shell, password, "true")
 	if err == nil {
 		return nil
 	}
 
-	if strings.Contains(stderr, "sudo: command not found") {
+	if strings.Contains(stderr, "sudo: command not found") || strings.Contains(stderr, "sudo: not found") {
 		return SudoNotInstalledError{Stderr: stderr}
 	}
 
@@ -225,10 +681,3 @@ func (p Password) validate() (string, error) {
 	}
 	return p.Value, nil
 }
-
-func shellQuote(value string) string {
-	if value == "" {
-		return "''"
-	}
-	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
-}
@@ -3,10 +3,14 @@ package privilege
 import (
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/remoteexec"
 )
 
 func TestEnsureElevationPrefersSudo(t *testing.T) {
@@ -19,7 +23,7 @@ func TestEnsureElevationPrefersSudo(t *testing.T) {
 		},
 	}
 
-	method, err := ensureElevation(r, "password")
+	method, err := ensureElevation(r, "password", elevationOptions{})
 	require.NoError(t, err)
 	require.Equal(t, methodSudo, method)
 }
@@ -36,7 +40,7 @@ func TestEnsureElevationFallsBackToSuOnPermissionError(t *testing.T) {
 		},
 	}
 
-	method, err := ensureElevation(r, "password")
+	method, err := ensureElevation(r, "password", elevationOptions{})
 	require.NoError(t, err)
 	require.Equal(t, methodSu, method)
 }
@@ -55,11 +59,135 @@ func TestEnsureElevationInstallsSudoWhenMissing(t *testing.T) {
 		},
 	}
 
-	method, err := ensureElevation(r, "password")
+	method, err := ensureElevation(r, "password", elevationOptions{})
 	require.NoError(t, err)
 	require.Equal(t, methodSudo, method)
 }
 
+func TestEnsureElevationOptions(t *testing.T) {
+	t.Parallel()
+
+	failure := errors.New("exit status 1")
+	tests := []struct {
+		name      string
+		cfg       elevationOptions
+		responses []fakeResponse
+		want      elevationMethod
+		wantErr   any
+	}{
+		{
+			name: "prefer su",
+			cfg:  elevationOptions{preferSu: true},
+			responses: []fakeResponse{
+				{match: "su - root -c 'true'"},
+				{match: "su - root -c"},
+			},
+			want: methodSu,
+		},
+		{
+			name: "prefer su falls back to sudo",
+			cfg:  elevationOptions{preferSu: true},
+			responses: []fakeResponse{
+				{match: "su - root -c", stderr: "su: Authentication failure", err: failure},
+				{match: "sudo -S"},
+				{match: "sudo -S"},
+			},
+			want: methodSudo,
+		},
+		{
+			name: "sudo only does not try su",
+			cfg:  elevationOptions{sudoOnly: true},
+			responses: []fakeResponse{
+				{match: "sudo -S", stderr: "deploy is not in the sudoers file", err: failure},
+			},
+			wantErr: SudoPermissionError{},
+		},
+		{
+			name: "no install uses su when sudo is missing",
+			cfg:  elevationOptions{noInstall: true},
+			responses: []fakeResponse{
+				{match: "sudo -S", stderr: "sudo: command not found", err: failure},
+				{match: "su - root -c 'true'"},
+			},
+			want: methodSu,
+		},
+		{
+			name: "no install skips the install script",
+			cfg:  elevationOptions{noInstall: true},
+			responses: []fakeResponse{
+				{match: "sudo -S -p '[host-prep] sudo password: ' -k bash -c 'exec </dev/null; true'"},
+			},
+			want: methodSudo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &fakeRunner{responses: tt.responses}
+			method, err := ensureElevation(r, "password", tt.cfg)
+			require.Empty(t, r.responses)
+			if tt.wantErr != nil {
+				require.IsType(t, tt.wantErr, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, method)
+		})
+	}
+
+	_, err := EnsureElevatedClient(&ssh.Client{}, Password{Value: "secret"}, WithPreferSu(), WithSudoOnly())
+	require.IsType(t, OptionError{}, err)
+}
+
+func TestEnsureElevationBSD(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		responses []fakeResponse
+		want      elevationMethod
+	}{
+		{
+			name: "doas without password",
+			responses: []fakeResponse{
+				{match: "doas -n sh -c 'true'"},
+				{match: "doas -n sh -c", stdout: ""},
+			},
+			want: methodDoas,
+		},
+		{
+			name: "falls back to sudo under sh",
+			responses: []fakeResponse{
+				{match: "doas -n", stderr: "doas: Authorization required", err: errors.New("exit status 1")},
+				{match: "sudo -S -p '[host-prep] sudo password: ' -k sh -c 'exec </dev/null; true'"},
+				{match: "pkg install -y sudo"},
+			},
+			want: methodSudo,
+		},
+		{
+			name: "su wraps sh for csh root shells",
+			responses: []fakeResponse{
+				{match: "doas -n", err: errors.New("exit status 127")},
+				{match: "sudo -S", stderr: "sh: sudo: not found", err: errors.New("exit status 127")},
+				{match: `su - root -c 'sh -c '"'"'true'"'"''`},
+				{match: "su - root -c 'sh -c"},
+				{match: "sudo -S", stderr: "sh: sudo: not found", err: errors.New("exit status 127")},
+			},
+			want: methodSu,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			method, err := ensureElevationBSD(&fakeRunner{responses: tt.responses}, "password", elevationOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, method)
+		})
+	}
+}
+
 func TestEnsureElevatedClientValidatesInputs(t *testing.T) {
 	t.Parallel()
 
@@ -86,6 +214,113 @@ func TestRunPrivilegedPreservesMultilineScripts(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestEnsureSudoInstalledUploadsScript(t *testing.T) {
+	t.Parallel()
+
+	up := &fakeUploader{}
+	r := &uploadingRunner{
+		fakeRunner: fakeRunner{responses: []fakeResponse{
+			{match: "mktemp -d '/tmp/host-prep-privilege.XXXXXX'", stdout: "/tmp/host-prep-privilege.abc123\n"},
+			{match: "-k bash -c 'exec </dev/null; bash '\"'\"'/tmp/host-prep-privilege.abc123/ensure-sudo.sh'\"'\"''"},
+			{match: "rm -rf -- '/tmp/host-prep-privilege.abc123'"},
+		}},
+		up: up,
+	}
+
+	require.NoError(t, ensureSudoInstalled(r, methodSudo, shellBash, "password"))
+	require.Equal(t, "/tmp/host-prep-privilege.abc123/ensure-sudo.sh", up.path)
+	require.Equal(t, ensureSudoScript, up.data)
+	require.Empty(t, r.responses)
+}
+
+func TestEnsureSudoInstalledFallsBackWithoutSFTP(t *testing.T) {
+	t.Parallel()
+
+	r := &uploadingRunner{
+		fakeRunner: fakeRunner{responses: []fakeResponse{
+			{match: "mktemp -d", stdout: "/tmp/host-prep-privilege.abc123\n"},
+			{match: "command -v sudo"},
+			{match: "rm -rf"},
+		}},
+		up: &fakeUploader{err: errors.New("subsystem request failed")},
+	}
+
+	require.NoError(t, ensureSudoInstalled(r, methodSudo, shellBash, "password"))
+	require.Empty(t, r.responses)
+}
+
+func TestVerifyPasswordless(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, verifyPasswordless(&fakeRunner{responses: []fakeResponse{{match: "sudo -n true"}}}))
+
+	failure := errors.New("exit status 1")
+	err := verifyPasswordless(&fakeRunner{responses: []fakeResponse{
+		{match: "sudo -n true", stderr: "sudo: a password is required\n", err: failure},
+	}})
+	var sudoErr PasswordlessSudoError
+	require.ErrorAs(t, err, &sudoErr)
+	require.ErrorIs(t, err, failure)
+	require.ErrorContains(t, err, "a password is required")
+
+	require.IsType(t, NilClientError{}, VerifyPasswordlessSudo(nil))
+
+	r := &fakeRunner{responses: []fakeResponse{
+		{match: "sudo -n -l -- '/bin/sh' >/dev/null && sudo -n -l -- '/usr/bin/systemctl' 'restart' 'nginx' >/dev/null"},
+	}}
+	require.NoError(t, verifyPasswordless(r, "/bin/sh", "/usr/bin/systemctl restart nginx"))
+	require.Empty(t, r.responses)
+}
+
+func TestPasswordlessMethod(t *testing.T) {
+	t.Parallel()
+
+	method, err := passwordlessMethod(&fakeRunner{responses: []fakeResponse{{match: "id -u", stdout: "0\n"}}})
+	require.NoError(t, err)
+	require.Equal(t, methodNone, method)
+
+	method, err = passwordlessMethod(&fakeRunner{responses: []fakeResponse{
+		{match: "id -u", stdout: "1000\n"},
+		{match: "sudo -n true"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, methodSudo, method)
+
+	_, err = passwordlessMethod(&fakeRunner{responses: []fakeResponse{
+		{match: "id -u", stdout: "1000\n"},
+		{match: "sudo -n true", stderr: "sudo: a password is required\n", err: errors.New("exit status 1")},
+	}})
+	require.ErrorAs(t, err, &PasswordlessSudoError{})
+
+	r := &fakeRunner{responses: []fakeResponse{{match: "bash -c 'cat /root/.ssh/authorized_keys'"}}}
+	_, _, err = runPrivilegedWith(r, methodNone, shellBash, "", "cat /root/.ssh/authorized_keys")
+	require.NoError(t, err)
+
+	_, err = PasswordlessClient(nil)
+	require.IsType(t, NilClientError{}, err)
+}
+
+type uploadingRunner struct {
+	fakeRunner
+	up *fakeUploader
+}
+
+func (r *uploadingRunner) uploader() remoteexec.Uploader {
+	return r.up
+}
+
+type fakeUploader struct {
+	path string
+	data string
+	err  error
+}
+
+func (u *fakeUploader) Upload(path string, data []byte, _ os.FileMode) error {
+	u.path = path
+	u.data = string(data)
+	return u.err
+}
+
 type fakeRunner struct {
 	responses []fakeResponse
 }
@@ -97,6 +332,10 @@ type fakeResponse struct {
 	err    error
 }
 
+func (f *fakeRunner) RunPrompted(cmd, _, answer string) (string, string, error) {
+	return f.Run(cmd, answer+"\n")
+}
+
 func (f *fakeRunner) Run(cmd string, stdin string) (string, string, error) {
 	if len(f.responses) == 0 {
 		return "", "", fmt.Errorf("unexpected command: %s", cmd)
@@ -111,3 +350,39 @@ func (f *fakeRunner) Run(cmd string, stdin string) (string, string, error) {
 
 	return resp.stdout, resp.stderr, resp.err
 }
+
+func TestExportPrefix(t *testing.T) {
+	t.Parallel()
+
+	prefix, err := exportPrefix(nil)
+	require.NoError(t, err)
+	require.Empty(t, prefix)
+
+	prefix, err = exportPrefix(map[string]string{
+		"https_proxy":     "http://proxy:3128",
+		"DEBIAN_FRONTEND": "noninteractive",
+		"MOTD":            "it's $HOME",
+	})
+	require.NoError(t, err)
+	require.Equal(t, `export DEBIAN_FRONTEND='noninteractive' MOTD='it'"'"'s $HOME' https_proxy='http://proxy:3128'; `, prefix)
+
+	for _, name := range []string{"", "1FOO", "FOO-BAR", "FOO=1", "A B"} {
+		_, err := exportPrefix(map[string]string{name: "x"})
+		var optErr OptionError
+		require.ErrorAs(t, err, &optErr, name)
+	}
+}
+
+func TestWithEnvMergesWithoutChangingTheOriginal(t *testing.T) {
+	t.Parallel()
+
+	base := (&ElevatedClient{gate: newSessionGate(1)}).WithEnv(map[string]string{"A": "1", "B": "1"})
+	derived := base.WithEnv(map[string]string{"B": "2"})
+	require.Equal(t, map[string]string{"A": "1", "B": "1"}, base.env)
+	require.Equal(t, map[string]string{"A": "1", "B": "2"}, derived.env)
+	require.Same(t, base.gate, derived.gate)
+
+	_, _, err := base.WithEnv(map[string]string{"BAD-NAME": "x"}).Run("true")
+	var optErr OptionError
+	require.ErrorAs(t, err, &optErr)
+}
@@ -0,0 +1,107 @@
+package privilege
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+// sudoPrompt is passed to sudo -p so the password is written only once sudo
+// asks for it. Writing it up front raced with commands that fail before
+// sudo reads stdin, or that run without a password under NOPASSWD: the
+// unread password line was left for whatever read stdin next.
+const sudoPrompt = "[host-prep] sudo password: "
+
+// sudoCommand runs cmd through shell with sudo, which reads the password
+// from stdin (-S) after printing sudoPrompt. -k ignores cached credentials
+// so a wrong password is always detected. The command's own stdin is
+// /dev/null, so it never sees the password.
+func sudoCommand(shell, cmd string) string {
+	return fmt.Sprintf("sudo -S -p %s -k %s -c %s", shellquote.Quote(sudoPrompt), shell, shellquote.Quote("exec </dev/null; "+cmd))
+}
+
+// RunSudo runs cmd under sh with sudo over client, answering sudo's password
+// prompt with password. It suits one-off privileged reads as the SSH user;
+// EnsureElevatedClient covers the full elevation flow.
+func RunSudo(client *ssh.Client, password, cmd string) (string, string, error) {
+	if client == nil {
+		return "", "", NilClientError{}
+	}
+	r := &sshRunner{client: client}
+	return r.RunPrompted(sudoCommand(shellSh, cmd), sudoPrompt, password)
+}
+
+func (r *sshRunner) RunPrompted(cmd, prompt, answer string) (string, string, error) {
+	session, err := r.newSession()
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		_ = session.Close()
+	}()
+	defer r.watch(session)()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return "", "", err
+	}
+	stderrPipe, err := session.StderrPipe()
+	if err != nil {
+		return "", "", err
+	}
+	if err := session.Start(cmd); err != nil {
+		return "", "", err
+	}
+	answerPrompt(stderrPipe, stdin, &stderr, prompt, answer)
+	err = session.Wait()
+	return stdout.String(), stderr.String(), err
+}
+
+// answerPrompt copies stderr to out without the prompt. The first time the
+// prompt appears, answer is written to stdin and stdin is closed, so a
+// repeated prompt (a rejected password) reads EOF instead of waiting. stdin
+// is also closed when stderr ends without a prompt.
+func answerPrompt(stderr io.Reader, stdin io.WriteCloser, out *bytes.Buffer, prompt, answer string) {
+	marker := []byte(prompt)
+	answered := false
+	defer func() {
+		if !answered {
+			_ = stdin.Close()
+		}
+	}()
+
+	var pending []byte
+	buf := make([]byte, 4096)
+	for {
+		n, err := stderr.Read(buf)
+		pending = append(pending, buf[:n]...)
+		for {
+			i := bytes.Index(pending, marker)
+			if i < 0 {
+				break
+			}
+			out.Write(pending[:i])
+			pending = pending[i+len(marker):]
+			if !answered {
+				answered = true
+				_, _ = io.WriteString(stdin, answer+"\n")
+				_ = stdin.Close()
+			}
+		}
+		if err != nil {
+			out.Write(pending)
+			return
+		}
+		// Hold back a tail that may be the start of a prompt split across reads.
+		if keep := len(marker) - 1; len(pending) > keep {
+			out.Write(pending[:len(pending)-keep])
+			pending = pending[len(pending)-keep:]
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package privilege
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stdinRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (r *stdinRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestAnswerPrompt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		stderr    string
+		wantOut   string
+		wantStdin string
+	}{
+		{
+			name:      "answers the prompt once",
+			stderr:    "warning: locale\n" + sudoPrompt + "Sorry, try again.\n" + sudoPrompt + "sudo: 1 incorrect password attempt\n",
+			wantOut:   "warning: locale\nSorry, try again.\nsudo: 1 incorrect password attempt\n",
+			wantStdin: "secret\n",
+		},
+		{
+			name:    "writes nothing without a prompt",
+			stderr:  "bash: foo: command not found\n",
+			wantOut: "bash: foo: command not found\n",
+		},
+		{
+			name:    "keeps partial prompts that never complete",
+			stderr:  "[host-prep] sudo",
+			wantOut: "[host-prep] sudo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// One byte at a time splits the prompt across reads.
+			stdin := &stdinRecorder{}
+			var out bytes.Buffer
+			answerPrompt(iotest.OneByteReader(strings.NewReader(tt.stderr)), stdin, &out, sudoPrompt, "secret")
+			require.Equal(t, tt.wantOut, out.String())
+			require.Equal(t, tt.wantStdin, stdin.String())
+			require.True(t, stdin.closed)
+		})
+	}
+}
+
+func TestSudoCommandKeepsPasswordFromCommand(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `sudo -S -p '[host-prep] sudo password: ' -k sh -c 'exec </dev/null; cat /etc/shadow'`, sudoCommand(shellSh, "cat /etc/shadow"))
+}
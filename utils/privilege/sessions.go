@@ -0,0 +1,73 @@
+package privilege
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMaxSessions is how many privileged commands an ElevatedClient runs
+// at once. OpenSSH allows ten sessions per connection (MaxSessions), and the
+// ansible user, SFTP uploads, and keepalives share that budget.
+const DefaultMaxSessions = 4
+
+// sessionGate limits how many sessions run on one SSH connection and admits
+// waiters in arrival order, so a phase issuing many short commands cannot
+// starve another phase waiting for its turn.
+type sessionGate struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters []chan struct{}
+}
+
+func newSessionGate(limit int) *sessionGate {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &sessionGate{limit: limit}
+}
+
+// acquire waits for a slot until ctx is done.
+func (g *sessionGate) acquire(ctx context.Context) error {
+	g.mu.Lock()
+	if g.active < g.limit && len(g.waiters) == 0 {
+		g.active++
+		g.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	g.waiters = append(g.waiters, ready)
+	g.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+	g.mu.Lock()
+	for i, waiter := range g.waiters {
+		if waiter == ready {
+			g.waiters = append(g.waiters[:i], g.waiters[i+1:]...)
+			g.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	g.mu.Unlock()
+	// The slot was handed over while ctx ended; pass it on.
+	g.release()
+	return ctx.Err()
+}
+
+// release frees a slot and hands it straight to the oldest waiter, if any,
+// so a caller that releases and immediately acquires again queues behind it.
+func (g *sessionGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.active--
+	if g.active < g.limit && len(g.waiters) > 0 {
+		next := g.waiters[0]
+		g.waiters = g.waiters[1:]
+		g.active++
+		close(next)
+	}
+}
@@ -0,0 +1,126 @@
+package privilege
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionGateAdmitsWaitersInOrder(t *testing.T) {
+	t.Parallel()
+
+	gate := newSessionGate(1)
+	require.NoError(t, gate.acquire(context.Background()))
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = gate.acquire(context.Background())
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			gate.release()
+		}()
+		require.Eventually(t, func() bool { return queued(gate) == i+1 }, time.Second, time.Millisecond)
+	}
+
+	gate.release()
+	wg.Wait()
+	require.Equal(t, []int{0, 1, 2}, order)
+	require.Zero(t, gate.active)
+}
+
+func TestSessionGateLimit(t *testing.T) {
+	t.Parallel()
+
+	gate := newSessionGate(2)
+	require.NoError(t, gate.acquire(context.Background()))
+	require.NoError(t, gate.acquire(context.Background()))
+
+	admitted := make(chan struct{})
+	go func() {
+		_ = gate.acquire(context.Background())
+		close(admitted)
+	}()
+	require.Eventually(t, func() bool { return queued(gate) == 1 }, time.Second, time.Millisecond)
+
+	gate.release()
+	<-admitted
+	require.Equal(t, 2, gate.active)
+
+	gate.release()
+	gate.release()
+	require.Zero(t, gate.active)
+	require.Zero(t, queued(gate))
+}
+
+func TestWithMaxSessions(t *testing.T) {
+	t.Parallel()
+
+	var opts elevationOptions
+	require.NoError(t, WithMaxSessions(1)(&opts))
+	require.Equal(t, 1, opts.maxSessions)
+
+	var optErr OptionError
+	require.ErrorAs(t, WithMaxSessions(0)(&opts), &optErr)
+}
+
+func TestWithCommandTimeout(t *testing.T) {
+	t.Parallel()
+
+	var opts elevationOptions
+	require.Nil(t, opts.timeout)
+	require.NoError(t, WithCommandTimeout(0)(&opts))
+	require.Equal(t, time.Duration(0), *opts.timeout)
+
+	var optErr OptionError
+	require.ErrorAs(t, WithCommandTimeout(-time.Second)(&opts), &optErr)
+}
+
+func queued(g *sessionGate) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.waiters)
+}
+
+func TestSessionGateAcquireHonoursContext(t *testing.T) {
+	t.Parallel()
+
+	gate := newSessionGate(1)
+	require.NoError(t, gate.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, gate.acquire(ctx), context.DeadlineExceeded)
+	require.Zero(t, queued(gate))
+
+	gate.release()
+	require.Zero(t, gate.active)
+}
+
+func TestRunContextTimesOutWaitingForASession(t *testing.T) {
+	t.Parallel()
+
+	client := &ElevatedClient{gate: newSessionGate(1), timeout: 20 * time.Millisecond}
+	require.NoError(t, client.gate.acquire(context.Background()))
+
+	_, _, err := client.Run("apt-get install -y foo")
+	var timeoutErr CommandTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	require.Equal(t, 20*time.Millisecond, timeoutErr.Timeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = client.RunContext(ctx, "true")
+	require.ErrorIs(t, err, context.Canceled)
+}
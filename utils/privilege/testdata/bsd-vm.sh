@@ -0,0 +1,89 @@
+#!/bin/sh
+# Boots a disposable FreeBSD VM for TestBSDTargetPrep (`just test-bsd`).
+#
+#   bsd-vm.sh up     download the official cloud-init image (cached), boot it
+#                    under QEMU, and wait until SSH answers
+#   bsd-vm.sh down   stop the VM and delete its overlay disk
+#
+# The VM starts from a stock image: no sudo or doas, and an `admin` user in
+# wheel whose password is also root's, so elevation goes through su and
+# installs sudo with pkg, as on a fresh host. SSH is forwarded to
+# 127.0.0.1:$HOST_PREP_BSD_PORT. Needs qemu-system-x86_64, qemu-img, curl,
+# xz, xorriso, and ssh-keyscan; KVM is used when available.
+set -eu
+
+version=${FREEBSD_VERSION:-14.3}
+port=${HOST_PREP_BSD_PORT:-2222}
+password=${HOST_PREP_BSD_PASSWORD:-host-prep}
+cache=${XDG_CACHE_HOME:-$HOME/.cache}/ansible-host-prep
+state=${TMPDIR:-/tmp}/host-prep-bsd-vm
+image=FreeBSD-$version-RELEASE-amd64-BASIC-CLOUDINIT-ufs.qcow2
+url=https://download.freebsd.org/releases/VM-IMAGES/$version-RELEASE/amd64/Latest/$image.xz
+
+up() {
+	if [ -f "$state/qemu.pid" ]; then
+		echo "bsd-vm: already running (pid $(cat "$state/qemu.pid"))" >&2
+		exit 1
+	fi
+	mkdir -p "$cache" "$state/seed"
+	if [ ! -f "$cache/$image" ]; then
+		curl -fL "$url" | xz -d >"$cache/$image.part"
+		mv "$cache/$image.part" "$cache/$image"
+	fi
+	qemu-img create -q -f qcow2 -F qcow2 -b "$cache/$image" "$state/disk.qcow2" 10G
+
+	echo "instance-id: host-prep-bsd" >"$state/seed/meta-data"
+	cat >"$state/seed/user-data" <<EOF
+#cloud-config
+runcmd:
+  - echo '$password' | pw useradd admin -m -G wheel -s /bin/sh -h 0
+  - echo '$password' | pw usermod root -h 0
+  - sed -i '' 's/^#*PasswordAuthentication .*/PasswordAuthentication yes/' /etc/ssh/sshd_config
+  - sysrc sshd_enable=YES
+  - service sshd restart
+  - echo "host-prep-bsd-\$(echo ready)" >/dev/console
+EOF
+	xorriso -as mkisofs -quiet -V cidata -J -r -o "$state/seed.iso" "$state/seed"
+
+	qemu-system-x86_64 -accel kvm -accel tcg -m 1024 -smp 2 \
+		-drive file="$state/disk.qcow2",if=virtio \
+		-drive file="$state/seed.iso",media=cdrom \
+		-netdev user,id=net0,hostfwd=tcp:127.0.0.1:"$port"-:22 \
+		-device virtio-net-pci,netdev=net0 \
+		-display none -serial file:"$state/console.log" \
+		-daemonize -pidfile "$state/qemu.pid"
+
+	# QEMU accepts connections on the forwarded port before the guest is up,
+	# so wait for the seed's marker on the console (assembled in the guest, so
+	# an echoed command line does not match) and then an SSH banner rather
+	# than an open port.
+	tries=0
+	until grep -q host-prep-bsd-ready "$state/console.log" 2>/dev/null &&
+		ssh-keyscan -p "$port" -T 5 127.0.0.1 >/dev/null 2>&1; do
+		tries=$((tries + 1))
+		if [ "$tries" -ge 120 ]; then
+			echo "bsd-vm: VM did not come up; console log:" >&2
+			tail -n 40 "$state/console.log" >&2 || true
+			down
+			exit 1
+		fi
+		sleep 5
+	done
+	echo "bsd-vm: FreeBSD $version listening on 127.0.0.1:$port (user admin)"
+}
+
+down() {
+	if [ -f "$state/qemu.pid" ]; then
+		kill "$(cat "$state/qemu.pid")" 2>/dev/null || true
+	fi
+	rm -rf "$state"
+}
+
+case "${1:-}" in
+up) up ;;
+down) down ;;
+*)
+	echo "usage: $0 up|down" >&2
+	exit 2
+	;;
+esac
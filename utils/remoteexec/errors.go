@@ -0,0 +1,71 @@
+package remoteexec
+
+import "fmt"
+
+// RunnerError indicates Run was invoked without a runner or uploader.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "remoteexec: runner and uploader are required"
+}
+
+// ValidationError captures invalid script names, directories, or options.
+type ValidationError struct {
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("remoteexec: %s", e.Reason)
+}
+
+// UploadError wraps failures copying the script to the target.
+type UploadError struct {
+	Path string
+	Err  error
+}
+
+func (e UploadError) Error() string {
+	return fmt.Sprintf("remoteexec: upload %s failed: %v", e.Path, e.Err)
+}
+
+func (e UploadError) Unwrap() error {
+	return e.Err
+}
+
+// ExitError reports a script that ran and exited non-zero.
+type ExitError struct {
+	Path   string
+	Code   int
+	Err    error
+	Stderr string
+}
+
+func (e ExitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("remoteexec: %s exited with status %d", e.Path, e.Code)
+	}
+	return fmt.Sprintf("remoteexec: %s exited with status %d (%s)", e.Path, e.Code, e.Stderr)
+}
+
+func (e ExitError) Unwrap() error {
+	return e.Err
+}
+
+// CommandError wraps failures that prevented the script from running, such
+// as a dropped connection.
+type CommandError struct {
+	Path   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("remoteexec: run %s failed: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("remoteexec: run %s failed: %v (%s)", e.Path, e.Err, e.Stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,164 @@
+// Package remoteexec uploads scripts to a target host and runs them, instead
+// of passing them inline on the command line.
+//
+// Scripts land in a remotetmp directory with mode 0700, so they can be
+// inspected while they run and are removed with the directory:
+//
+//	dir, err := remotetmp.Create(runner, meta.ID)
+//	if err != nil {
+//		return err
+//	}
+//	phases.AddCleanup(ctx, dir.Remove)
+//	res, err := remoteexec.Run(runner, remoteexec.NewSFTPUploader(client), dir, "setup.sh", script)
+package remoteexec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/remotetmp"
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+const scriptMode os.FileMode = 0o700
+
+// Runner executes commands on the target system, possibly elevated.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Uploader writes a file on the target system.
+type Uploader interface {
+	Upload(path string, data []byte, mode os.FileMode) error
+}
+
+// SFTPUploader uploads files over the SFTP subsystem of an SSH connection.
+type SFTPUploader struct {
+	client *ssh.Client
+}
+
+// NewSFTPUploader returns an Uploader using client's SFTP subsystem.
+func NewSFTPUploader(client *ssh.Client) *SFTPUploader {
+	return &SFTPUploader{client: client}
+}
+
+// Upload creates or truncates path, writes data, and sets mode.
+func (u *SFTPUploader) Upload(path string, data []byte, mode os.FileMode) error {
+	if u == nil || u.client == nil {
+		return errors.New("ssh client is required")
+	}
+	client, err := sftp.NewClient(u.client)
+	if err != nil {
+		return fmt.Errorf("start sftp: %w", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	f, err := client.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Result holds the output of a script run.
+type Result struct {
+	Path     string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Option configures Run.
+type Option func(*options) error
+
+type options struct {
+	interpreter string
+}
+
+// WithInterpreter runs the script as `<interpreter> <path>` (e.g. "bash" or
+// "sh") instead of executing it directly, so no shebang is needed.
+func WithInterpreter(interpreter string) Option {
+	return func(opts *options) error {
+		interpreter = strings.TrimSpace(interpreter)
+		if interpreter == "" || strings.ContainsAny(interpreter, " \t\n'\"") {
+			return ValidationError{Reason: fmt.Sprintf("invalid interpreter %q", interpreter)}
+		}
+		opts.interpreter = interpreter
+		return nil
+	}
+}
+
+// Run uploads script as name inside dir with mode 0700, runs it through r,
+// and reports its output and exit code. A non-zero exit returns the Result
+// together with an ExitError; upload failures return an UploadError so
+// callers can fall back to another transport.
+func Run(r Runner, up Uploader, dir *remotetmp.Dir, name string, script []byte, opts ...Option) (*Result, error) {
+	if r == nil || up == nil {
+		return nil, RunnerError{}
+	}
+	if dir == nil || dir.Path == "" {
+		return nil, ValidationError{Reason: "temp dir is required"}
+	}
+	if name == "" || name != path.Base(name) || name == "." || name == ".." {
+		return nil, ValidationError{Reason: fmt.Sprintf("invalid script name %q", name)}
+	}
+	var cfg options
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	scriptPath := dir.Join(name)
+	if err := up.Upload(scriptPath, script, scriptMode); err != nil {
+		return nil, UploadError{Path: scriptPath, Err: err}
+	}
+
+	cmd := shellquote.Quote(scriptPath)
+	if cfg.interpreter != "" {
+		cmd = cfg.interpreter + " " + cmd
+	}
+	stdout, stderr, err := r.Run(cmd)
+	res := &Result{Path: scriptPath, Stdout: stdout, Stderr: stderr}
+	if err != nil {
+		code, ok := exitCode(err)
+		if !ok {
+			return nil, CommandError{Path: scriptPath, Err: err, Stderr: strings.TrimSpace(stderr)}
+		}
+		res.ExitCode = code
+		return res, ExitError{Path: scriptPath, Code: code, Err: err, Stderr: strings.TrimSpace(stderr)}
+	}
+	return res, nil
+}
+
+// exitCode extracts the exit status from ssh and os/exec errors.
+func exitCode(err error) (int, bool) {
+	var sshErr interface{ ExitStatus() int }
+	if errors.As(err, &sshErr) {
+		return sshErr.ExitStatus(), true
+	}
+	var execErr interface{ ExitCode() int }
+	if errors.As(err, &execErr) {
+		return execErr.ExitCode(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,117 @@
+package remoteexec
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/remotetmp"
+)
+
+// shellRunner runs commands with the local shell, standing in for a target.
+type shellRunner struct {
+	cmds []string
+}
+
+func (r *shellRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	var stderr strings.Builder
+	c := exec.Command("sh", "-c", cmd)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	return string(out), stderr.String(), err
+}
+
+// localUploader writes files on the local filesystem.
+type localUploader struct {
+	err error
+}
+
+func (u localUploader) Upload(path string, data []byte, mode os.FileMode) error {
+	if u.err != nil {
+		return u.err
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+func newDir(t *testing.T, r remotetmp.Runner) *remotetmp.Dir {
+	t.Helper()
+	dir, err := remotetmp.Create(r, "remoteexec", remotetmp.WithBase(t.TempDir()))
+	require.NoError(t, err)
+	return dir
+}
+
+func TestRunUploadsAndExecutes(t *testing.T) {
+	t.Parallel()
+
+	r := &shellRunner{}
+	dir := newDir(t, r)
+	res, err := Run(r, localUploader{}, dir, "hello.sh", []byte("#!/bin/sh\necho \"it's $(basename \"$0\")\"\n"))
+	require.NoError(t, err)
+	require.Equal(t, "it's hello.sh\n", res.Stdout)
+	require.Equal(t, dir.Join("hello.sh"), res.Path)
+	require.Zero(t, res.ExitCode)
+
+	info, err := os.Stat(res.Path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+}
+
+func TestRunCapturesExitCode(t *testing.T) {
+	t.Parallel()
+
+	r := &shellRunner{}
+	dir := newDir(t, r)
+	res, err := Run(r, localUploader{}, dir, "fail.sh", []byte("echo broken >&2\nexit 3\n"), WithInterpreter("sh"))
+	var exitErr ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 3, exitErr.Code)
+	require.Equal(t, "broken", exitErr.Stderr)
+	require.Equal(t, 3, res.ExitCode)
+	require.Equal(t, "sh '"+res.Path+"'", r.cmds[len(r.cmds)-1])
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	r := &shellRunner{}
+	dir := newDir(t, r)
+
+	_, err := Run(nil, localUploader{}, dir, "x.sh", nil)
+	require.ErrorAs(t, err, &RunnerError{})
+
+	var validation ValidationError
+	_, err = Run(r, localUploader{}, nil, "x.sh", nil)
+	require.ErrorAs(t, err, &validation)
+	_, err = Run(r, localUploader{}, dir, "../x.sh", nil)
+	require.ErrorAs(t, err, &validation)
+	_, err = Run(r, localUploader{}, dir, "x.sh", nil, WithInterpreter("sh -x"))
+	require.ErrorAs(t, err, &validation)
+
+	failure := errors.New("subsystem request failed")
+	_, err = Run(r, localUploader{err: failure}, dir, "x.sh", nil)
+	var uploadErr UploadError
+	require.ErrorAs(t, err, &uploadErr)
+	require.ErrorIs(t, err, failure)
+
+	dropped := errors.New("connection lost")
+	_, err = Run(fakeRunner{err: dropped}, localUploader{}, dir, "x.sh", nil)
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.ErrorIs(t, err, dropped)
+}
+
+type fakeRunner struct {
+	err error
+}
+
+func (f fakeRunner) Run(string) (string, string, error) {
+	return "", "", f.err
+}
@@ -0,0 +1,37 @@
+package remotetmp
+
+import "fmt"
+
+// RunnerError indicates Create was invoked without a runner.
+type RunnerError struct{}
+
+func (RunnerError) Error() string {
+	return "remotetmp: runner is required"
+}
+
+// ValidationError captures invalid prefixes or options.
+type ValidationError struct {
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("remotetmp: %s", e.Reason)
+}
+
+// CommandError wraps failures running mktemp or rm on the target.
+type CommandError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("remotetmp: %s failed: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("remotetmp: %s failed: %v (%s)", e.Step, e.Err, e.Stderr)
+}
+
+func (e CommandError) Unwrap() error {
+	return e.Err
+}
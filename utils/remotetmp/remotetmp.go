@@ -0,0 +1,117 @@
+// Package remotetmp creates private temporary directories on a target host for
+// uploads and scripts, and removes them again.
+//
+// Phases pair Create with phases.AddCleanup so the directory is removed when
+// the phase ends, even on failure:
+//
+//	dir, err := remotetmp.Create(runner, meta.ID)
+//	if err != nil {
+//		return err
+//	}
+//	phases.AddCleanup(ctx, dir.Remove)
+package remotetmp
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+const (
+	defaultBase   = "/tmp"
+	defaultPrefix = "phase"
+	namePrefix    = "host-prep-"
+)
+
+var prefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Runner executes commands on the target system.
+type Runner interface {
+	Run(cmd string) (stdout string, stderr string, err error)
+}
+
+// Option configures Create.
+type Option func(*options) error
+
+type options struct {
+	base string
+}
+
+// WithBase creates the directory under base instead of /tmp, e.g. for hosts
+// that mount /tmp noexec.
+func WithBase(base string) Option {
+	return func(opts *options) error {
+		base = strings.TrimSpace(base)
+		if !path.IsAbs(base) {
+			return ValidationError{Reason: fmt.Sprintf("base %q must be an absolute path", base)}
+		}
+		opts.base = path.Clean(base)
+		return nil
+	}
+}
+
+// Dir is a temporary directory on the target. Its mode is 0700.
+type Dir struct {
+	Path string
+
+	runner Runner
+	once   sync.Once
+	err    error
+}
+
+// Create makes a unique directory named host-prep-<prefix>.XXXXXX under /tmp
+// (or WithBase). prefix is usually the phase ID and may only contain letters,
+// digits, '_', '.', and '-'.
+func Create(r Runner, prefix string, opts ...Option) (*Dir, error) {
+	if r == nil {
+		return nil, RunnerError{}
+	}
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	if !prefixPattern.MatchString(prefix) {
+		return nil, ValidationError{Reason: fmt.Sprintf("invalid prefix %q", prefix)}
+	}
+	cfg := options{base: defaultBase}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	template := path.Join(cfg.base, namePrefix+prefix+".XXXXXX")
+	stdout, stderr, err := r.Run(fmt.Sprintf("umask 077 && mktemp -d %s", shellquote.Quote(template)))
+	if err != nil {
+		return nil, CommandError{Step: "create", Err: err, Stderr: strings.TrimSpace(stderr)}
+	}
+	dirPath := strings.TrimSpace(stdout)
+	if !strings.HasPrefix(dirPath, path.Join(cfg.base, namePrefix+prefix)+".") || path.Clean(dirPath) != dirPath {
+		return nil, CommandError{Step: "create", Err: fmt.Errorf("unexpected mktemp output %q", dirPath)}
+	}
+	return &Dir{Path: dirPath, runner: r}, nil
+}
+
+// Join returns the path of name inside the directory.
+func (d *Dir) Join(name ...string) string {
+	return path.Join(append([]string{d.Path}, name...)...)
+}
+
+// Remove deletes the directory and everything in it. Only the first call
+// touches the target; later calls return its result.
+func (d *Dir) Remove() error {
+	d.once.Do(func() {
+		_, stderr, err := d.runner.Run("rm -rf -- " + shellquote.Quote(d.Path))
+		if err != nil {
+			d.err = CommandError{Step: "remove " + d.Path, Err: err, Stderr: strings.TrimSpace(stderr)}
+		}
+	})
+	return d.err
+}
@@ -0,0 +1,83 @@
+package remotetmp
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+// shellRunner runs commands with the local shell, standing in for a target.
+type shellRunner struct {
+	cmds []string
+}
+
+func (r *shellRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	var stderr strings.Builder
+	c := exec.Command("sh", "-c", cmd)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	return string(out), stderr.String(), err
+}
+
+func TestCreateAndRemove(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	r := &shellRunner{}
+	dir, err := Create(r, "python_ensure", WithBase(base))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(dir.Path, filepath.Join(base, "host-prep-python_ensure.")))
+	require.DirExists(t, dir.Path)
+	require.Equal(t, dir.Path+"/install.sh", dir.Join("install.sh"))
+
+	_, _, err = r.Run("echo hi > " + shellquote.Quote(dir.Join("install.sh")))
+	require.NoError(t, err)
+
+	require.NoError(t, dir.Remove())
+	require.NoDirExists(t, dir.Path)
+	require.NoError(t, dir.Remove())
+	require.Len(t, r.cmds, 3, "a second Remove must not run rm again")
+}
+
+func TestCreateValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := Create(nil, "x")
+	require.ErrorAs(t, err, &RunnerError{})
+
+	var validation ValidationError
+	_, err = Create(&shellRunner{}, "../etc")
+	require.ErrorAs(t, err, &validation)
+	_, err = Create(&shellRunner{}, "x", WithBase("tmp"))
+	require.ErrorAs(t, err, &validation)
+}
+
+func TestCreateRejectsUnexpectedOutput(t *testing.T) {
+	t.Parallel()
+
+	_, err := Create(fakeRunner{stdout: "/etc\n"}, "x")
+	var cmdErr CommandError
+	require.ErrorAs(t, err, &cmdErr)
+	require.Equal(t, "create", cmdErr.Step)
+
+	failure := errors.New("exit status 1")
+	_, err = Create(fakeRunner{err: failure, stderr: "mktemp: No space left on device"}, "x")
+	require.ErrorIs(t, err, failure)
+	require.ErrorContains(t, err, "No space left")
+}
+
+type fakeRunner struct {
+	stdout, stderr string
+	err            error
+}
+
+func (f fakeRunner) Run(string) (string, string, error) {
+	return f.stdout, f.stderr, f.err
+}
@@ -0,0 +1,12 @@
+// Package shellquote quotes values for POSIX shells, so file paths, package
+// names, and scripts can be spliced into commands run on a target host.
+package shellquote
+
+import "strings"
+
+// Quote wraps value in single quotes, closing and reopening the quotes
+// around any single quote it contains, so the shell reads it as one literal
+// word, even when value is empty.
+func Quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
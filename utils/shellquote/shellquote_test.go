@@ -0,0 +1,22 @@
+package shellquote
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "''", Quote(""))
+	require.Equal(t, "'/tmp/a b'", Quote("/tmp/a b"))
+	require.Equal(t, `'it'"'"'s'`, Quote("it's"))
+
+	for _, value := range []string{"", "plain", "it's", `$HOME "x" \n; rm -rf /`, "multi\nline"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+Quote(value)).Output()
+		require.NoError(t, err)
+		require.Equal(t, value, string(out))
+	}
+}
@@ -0,0 +1,59 @@
+package sshconnection
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CertificatePath returns where ssh-keygen -s writes the certificate for the
+// private key at keyPath: the key path with a -cert.pub suffix.
+func CertificatePath(keyPath string) string {
+	if strings.TrimSpace(keyPath) == "" {
+		return ""
+	}
+	return keyPath + "-cert.pub"
+}
+
+// certSigner pairs signer with the user certificate at path. The certificate
+// must certify signer's public key and be valid at now; a host would reject
+// it otherwise, with a far less helpful error.
+func certSigner(path string, signer ssh.Signer, now time.Time) (ssh.Signer, error) {
+	certBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, CertificateError{Path: path, Reason: err.Error()}
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+	if err != nil {
+		return nil, CertificateError{Path: path, Reason: err.Error()}
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, CertificateError{Path: path, Reason: "not an OpenSSH certificate"}
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, CertificateError{Path: path, Reason: "not a user certificate"}
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, CertificateError{Path: path, Reason: "certificate does not match the private key"}
+	}
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return nil, CertificateError{Path: path, Reason: "not valid until " + certTime(cert.ValidAfter)}
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return nil, CertificateError{Path: path, Reason: "expired at " + certTime(cert.ValidBefore)}
+	}
+	certified, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, CertificateError{Path: path, Reason: err.Error()}
+	}
+	return certified, nil
+}
+
+func certTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,118 @@
+package sshconnection
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// defaultKeyNames are the private key files ssh(1) tries by default, in order.
+var defaultKeyNames = []string{"id_ed25519", "id_ecdsa", "id_ed25519_sk", "id_ecdsa_sk", "id_rsa"}
+
+// LocalKey describes a private key file or ssh-agent identity available on
+// the control node.
+type LocalKey struct {
+	// Path is the private key file; empty for agent identities.
+	Path        string
+	Fingerprint string
+	Comment     string
+	FromAgent   bool
+}
+
+// DiscoverLocalKeys lists the default key files in ~/.ssh followed by the
+// identities loaded in the agent at $SSH_AUTH_SOCK. Discovery is best effort:
+// unreadable files and an unreachable agent are skipped.
+func DiscoverLocalKeys() []LocalKey {
+	var keys []LocalKey
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		keys = append(keys, discoverKeyFiles(filepath.Join(home, ".ssh"))...)
+	}
+	return append(keys, discoverAgentKeys(os.Getenv("SSH_AUTH_SOCK"))...)
+}
+
+func discoverKeyFiles(dir string) []LocalKey {
+	var keys []LocalKey
+	for _, name := range defaultKeyNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		key := LocalKey{Path: path}
+		if pub, err := os.ReadFile(path + ".pub"); err == nil {
+			if parsed, comment, _, _, err := ssh.ParseAuthorizedKey(pub); err == nil {
+				key.Fingerprint = ssh.FingerprintSHA256(parsed)
+				key.Comment = comment
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func discoverAgentKeys(socket string) []LocalKey {
+	identities, err := AgentKeys(socket)
+	if err != nil {
+		return nil
+	}
+	keys := make([]LocalKey, 0, len(identities))
+	for _, id := range identities {
+		keys = append(keys, LocalKey{
+			Fingerprint: ssh.FingerprintSHA256(id),
+			Comment:     id.Comment,
+			FromAgent:   true,
+		})
+	}
+	return keys
+}
+
+// AgentKeys lists the identities loaded in the ssh-agent at socket, with
+// their public keys. Unlike DiscoverLocalKeys it reports why the agent could
+// not be listed.
+func AgentKeys(socket string) ([]*agent.Key, error) {
+	if strings.TrimSpace(socket) == "" {
+		return nil, CredentialError{Reason: "SSH_AUTH_SOCK is not set; no ssh-agent available"}
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, AgentError{Socket: socket, Err: err}
+	}
+	defer conn.Close()
+
+	identities, err := agent.NewClient(conn).List()
+	if err != nil {
+		return nil, AgentError{Socket: socket, Err: err}
+	}
+	return identities, nil
+}
+
+// agentAuth authenticates with the agent at socket, optionally restricted to
+// the identity with the given fingerprint. The agent connection must stay
+// open while handshakes request signatures; call closeAgent once they are
+// done.
+func agentAuth(socket, fingerprint string) (auth ssh.AuthMethod, closeAgent func(), err error) {
+	if strings.TrimSpace(socket) == "" {
+		return nil, nil, CredentialError{Reason: "SSH_AUTH_SOCK is not set; no ssh-agent available"}
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, AgentError{Socket: socket, Err: err}
+	}
+	client := agent.NewClient(conn)
+	auth = ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		signers, err := client.Signers()
+		if err != nil || fingerprint == "" {
+			return signers, err
+		}
+		for _, signer := range signers {
+			if ssh.FingerprintSHA256(signer.PublicKey()) == fingerprint {
+				return []ssh.Signer{signer}, nil
+			}
+		}
+		return nil, CredentialError{Reason: "agent identity " + fingerprint + " is no longer loaded"}
+	})
+	return auth, func() { _ = conn.Close() }, nil
+}
@@ -50,6 +50,17 @@ func (e KeyParseError) Unwrap() error {
 	return e.Err
 }
 
+// CertificateError indicates an OpenSSH certificate cannot be used with the
+// credential's private key.
+type CertificateError struct {
+	Path   string
+	Reason string
+}
+
+func (e CertificateError) Error() string {
+	return fmt.Sprintf("unusable certificate %s: %s", e.Path, e.Reason)
+}
+
 // AuthenticationError represents SSH handshake failures due to invalid credentials.
 type AuthenticationError struct {
 	Username string
@@ -91,3 +102,32 @@ func (e TimeoutError) Error() string {
 func (e TimeoutError) Unwrap() error {
 	return e.Err
 }
+
+// AgentError wraps failures reaching the ssh-agent socket.
+type AgentError struct {
+	Socket string
+	Err    error
+}
+
+func (e AgentError) Error() string {
+	return fmt.Sprintf("failed to reach ssh-agent at %s: %v", e.Socket, e.Err)
+}
+
+func (e AgentError) Unwrap() error {
+	return e.Err
+}
+
+// UnhealthyError reports a connection that failed HealthCheck; Check is
+// "keepalive" or "exec".
+type UnhealthyError struct {
+	Check string
+	Err   error
+}
+
+func (e UnhealthyError) Error() string {
+	return fmt.Sprintf("ssh connection unhealthy (%s): %v", e.Check, e.Err)
+}
+
+func (e UnhealthyError) Unwrap() error {
+	return e.Err
+}
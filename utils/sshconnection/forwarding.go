@@ -0,0 +1,61 @@
+package sshconnection
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// forwardingClients holds the clients connected with WithAgentForwarding.
+var forwardingClients sync.Map
+
+// WithAgentForwarding forwards the operator's ssh-agent ($SSH_AUTH_SOCK) to
+// the target, so remote commands that reach further hosts, e.g. a git clone
+// over SSH, can use its keys without copying them. Only sessions opened
+// through RequestAgentForwarding see the agent; privileged commands do, but
+// sudo drops SSH_AUTH_SOCK unless sudoers keeps it (env_keep). Forward only
+// to targets you trust: root on the target can use the agent while the
+// connection is open.
+func WithAgentForwarding() Option {
+	return func(opts *connectOptions) error {
+		socket := strings.TrimSpace(os.Getenv("SSH_AUTH_SOCK"))
+		if socket == "" {
+			return OptionError{Reason: "agent forwarding needs SSH_AUTH_SOCK; no ssh-agent available"}
+		}
+		opts.agentSocket = socket
+		return nil
+	}
+}
+
+// forwardAgent serves agent requests from the target on client.
+func forwardAgent(client *ssh.Client, socket string) error {
+	if err := agent.ForwardToRemote(client, socket); err != nil {
+		return AgentError{Socket: socket, Err: err}
+	}
+	forwardingClients.Store(client, struct{}{})
+	go func() {
+		_ = client.Wait()
+		forwardingClients.Delete(client)
+	}()
+	return nil
+}
+
+// ForwardsAgent reports whether client was connected with
+// WithAgentForwarding.
+func ForwardsAgent(client *ssh.Client) bool {
+	_, ok := forwardingClients.Load(client)
+	return ok
+}
+
+// RequestAgentForwarding asks the server to expose the forwarded agent to
+// session, which must not have started yet. It does nothing for clients
+// connected without WithAgentForwarding.
+func RequestAgentForwarding(client *ssh.Client, session *ssh.Session) error {
+	if !ForwardsAgent(client) {
+		return nil
+	}
+	return agent.RequestAgentForwarding(session)
+}
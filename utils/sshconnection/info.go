@@ -0,0 +1,80 @@
+package sshconnection
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ConnectionInfo describes the SSH server as seen during the handshake. It
+// helps with support when a target runs an unusual server such as Dropbear
+// or Windows OpenSSH.
+type ConnectionInfo struct {
+	// ServerVersion is the server's identification string, e.g.
+	// "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3" or "SSH-2.0-dropbear_2022.83".
+	ServerVersion string
+	// Banner is the pre-authentication banner the server sent, if any.
+	Banner string
+	// AuthMethods lists the authentication methods the server offered, e.g.
+	// "publickey" and "password". It is empty when the server let the user
+	// in without authentication.
+	AuthMethods []string
+	// KeyExchange and HostKeyAlgorithm are the negotiated algorithms.
+	KeyExchange      string
+	HostKeyAlgorithm string
+}
+
+// Software returns ServerVersion without the protocol prefix, e.g.
+// "OpenSSH_9.2p1 Debian-2+deb12u3".
+func (i ConnectionInfo) Software() string {
+	_, software, ok := strings.Cut(strings.TrimPrefix(i.ServerVersion, "SSH-"), "-")
+	if !ok {
+		return i.ServerVersion
+	}
+	return software
+}
+
+// OffersAuthMethod reports whether the server offered method.
+func (i ConnectionInfo) OffersAuthMethod(method string) bool {
+	return slices.Contains(i.AuthMethods, method)
+}
+
+// WithConnectionInfo fills info with what Connect learns about the server
+// during the handshake. It is filled as far as the handshake got even when
+// Connect fails, so an authentication error can be reported with the
+// methods the server offered.
+func WithConnectionInfo(info *ConnectionInfo) Option {
+	return func(opts *connectOptions) error {
+		if info == nil {
+			return OptionError{Reason: "connection info must not be nil"}
+		}
+		opts.info = info
+		return nil
+	}
+}
+
+// recordInfo makes config fill info during each handshake. The auth
+// callback first runs after the server answered the "none" method, so
+// AllowedMethods is then the full list the server offers.
+func recordInfo(config *ssh.ClientConfig, info *ConnectionInfo) {
+	hostKeyCallback := config.HostKeyCallback
+	config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		info.HostKeyAlgorithm = key.Type()
+		return hostKeyCallback(hostname, remote, key)
+	}
+	config.BannerCallback = func(message string) error {
+		info.Banner = message
+		return nil
+	}
+	config.AuthCallback = func(ctx *ssh.ClientAuthContext) (ssh.AuthMethod, error) {
+		if info.ServerVersion == "" {
+			info.ServerVersion = string(ctx.Metadata.ServerVersion())
+			info.AuthMethods = slices.Clone(ctx.AllowedMethods)
+			info.KeyExchange = ctx.Algorithms.KeyExchange
+			info.HostKeyAlgorithm = ctx.Algorithms.HostKey
+		}
+		return nil, nil
+	}
+}
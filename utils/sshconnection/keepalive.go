@@ -0,0 +1,118 @@
+package sshconnection
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// WithKeepAlive sends an OpenSSH keepalive request every interval once
+// connected, like ssh's ServerAliveInterval and ServerAliveCountMax. NAT
+// gateways and firewalls then keep an idle connection open, and when
+// maxMissed requests in a row go unanswered the client is closed, so
+// pending commands fail instead of hanging on a dead link. KeepAliveFailure
+// tells such a close apart from others.
+func WithKeepAlive(interval time.Duration, maxMissed int) Option {
+	return func(opts *connectOptions) error {
+		if interval <= 0 {
+			return OptionError{Reason: "keepalive interval must be greater than zero"}
+		}
+		if maxMissed < 1 {
+			return OptionError{Reason: "keepalive must allow at least one missed reply"}
+		}
+		opts.keepAliveInterval = interval
+		opts.keepAliveMaxMissed = maxMissed
+		return nil
+	}
+}
+
+// KeepAliveError reports a connection closed by WithKeepAlive.
+type KeepAliveError struct {
+	Missed   int
+	Interval time.Duration
+	Err      error
+}
+
+func (e KeepAliveError) Error() string {
+	return fmt.Sprintf("ssh connection lost: %d keepalives unanswered at %s intervals: %v", e.Missed, e.Interval, e.Err)
+}
+
+func (e KeepAliveError) Unwrap() error {
+	return e.Err
+}
+
+// keepAliveConn is the connection of a client connected WithKeepAlive. It
+// carries the KeepAliveError when the keepalive closed it, so the failure
+// lives and dies with the client.
+type keepAliveConn struct {
+	ssh.Conn
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *keepAliveConn) fail(err KeepAliveError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+func (c *keepAliveConn) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// KeepAliveFailure returns the KeepAliveError of a client that WithKeepAlive
+// closed, or nil if it is open or was closed for another reason.
+func KeepAliveFailure(client *ssh.Client) error {
+	if client == nil {
+		return nil
+	}
+	if conn, ok := client.Conn.(*keepAliveConn); ok {
+		return conn.failure()
+	}
+	return nil
+}
+
+// keepAlive pings client until it closes, and closes it after maxMissed
+// consecutive pings fail. Each ping waits up to interval for its reply.
+func keepAlive(client *ssh.Client, interval time.Duration, maxMissed int) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	missed := 0
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+		err := Ping(client, interval)
+		if err == nil {
+			missed = 0
+			continue
+		}
+		missed++
+		if missed < maxMissed {
+			continue
+		}
+		select {
+		case <-closed:
+			// Closed by the caller; the ping failed because of it.
+		default:
+			if conn, ok := client.Conn.(*keepAliveConn); ok {
+				conn.fail(KeepAliveError{Missed: missed, Interval: interval, Err: err})
+			}
+			_ = client.Close()
+		}
+		return
+	}
+}
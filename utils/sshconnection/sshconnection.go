@@ -1,6 +1,8 @@
 package sshconnection
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -9,24 +11,44 @@ import (
 	"time"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/mdns"
 )
 
 const (
 	defaultPort        = 22
 	defaultDialTimeout = 10 * time.Second
+	// defaultRetryInterval is the pause between attempts under WithRetryWindow.
+	defaultRetryInterval = 5 * time.Second
 )
 
-// Credential represents either a password or private key path for SSH authentication.
+// Credential represents a password, a private key path, or ssh-agent
+// identities for SSH authentication.
 type Credential struct {
 	Password string
 	KeyPath  string
+	// Agent authenticates with the ssh-agent at $SSH_AUTH_SOCK.
+	Agent bool
+	// AgentFingerprint restricts agent authentication to one identity.
+	AgentFingerprint string
+	// CertPath is an OpenSSH certificate signed by a CA the host trusts,
+	// e.g. id_ed25519-cert.pub. It is presented with the key at KeyPath.
+	CertPath string
 }
 
 // Option configures optional behavior for Connect.
 type Option func(*connectOptions) error
 
 type connectOptions struct {
-	timeout time.Duration
+	timeout       time.Duration
+	retryWindow   time.Duration
+	retryInterval time.Duration
+	mdns          bool
+	info          *ConnectionInfo
+	agentSocket   string
+
+	keepAliveInterval  time.Duration
+	keepAliveMaxMissed int
 }
 
 // WithTimeout overrides the default dial timeout.
@@ -40,6 +62,30 @@ func WithTimeout(d time.Duration) Option {
 	}
 }
 
+// WithRetryWindow keeps retrying an unreachable target for up to d, e.g. a
+// device that is still booting: names that do not resolve yet, refused
+// connections, and timeouts are retried every few seconds. Authentication
+// failures are returned at once.
+func WithRetryWindow(d time.Duration) Option {
+	return func(opts *connectOptions) error {
+		if d < 0 {
+			return OptionError{Reason: "retry window must not be negative"}
+		}
+		opts.retryWindow = d
+		return nil
+	}
+}
+
+// WithMDNS resolves .local host names with multicast DNS when the system
+// resolver cannot, as on control nodes without nss-mdns, so devices can be
+// reached by the hostname they announce (e.g. raspberrypi.local).
+func WithMDNS() Option {
+	return func(opts *connectOptions) error {
+		opts.mdns = true
+		return nil
+	}
+}
+
 // OptionError captures invalid option state passed to Connect.
 type OptionError struct {
 	Reason string
@@ -62,17 +108,20 @@ func Connect(host string, port int, username string, cred Credential, opts ...Op
 		return nil, InvalidTargetError{Field: "username"}
 	}
 
-	authMethod, err := cred.authMethod()
+	authMethod, closeAuth, err := cred.authMethod()
 	if err != nil {
 		return nil, err
 	}
+	// Authentication ends with the handshake, including retries.
+	defer closeAuth()
 
 	if port <= 0 {
 		port = defaultPort
 	}
 
 	cfg := connectOptions{
-		timeout: defaultDialTimeout,
+		timeout:       defaultDialTimeout,
+		retryInterval: defaultRetryInterval,
 	}
 	for _, opt := range opts {
 		if opt == nil {
@@ -89,48 +138,207 @@ func Connect(host string, port int, username string, cred Credential, opts ...Op
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // callers should replace when host key management is available
 		Timeout:         cfg.timeout,
 	}
+	if cfg.info != nil {
+		recordInfo(config, cfg.info)
+	}
 
+	deadline := time.Now().Add(cfg.retryWindow)
+	for {
+		if cfg.info != nil {
+			*cfg.info = ConnectionInfo{}
+		}
+		client, err := dial(host, port, config, cfg)
+		if err == nil && cfg.info != nil && cfg.info.ServerVersion == "" {
+			// The server accepted "none", so the auth callback never ran.
+			cfg.info.ServerVersion = string(client.ServerVersion())
+		}
+		if err == nil && cfg.agentSocket != "" {
+			if err := forwardAgent(client, cfg.agentSocket); err != nil {
+				_ = client.Close()
+				return nil, err
+			}
+		}
+		if err == nil && cfg.keepAliveInterval > 0 {
+			go keepAlive(client, cfg.keepAliveInterval, cfg.keepAliveMaxMissed)
+		}
+		if err == nil || !retryable(err) || time.Now().Add(cfg.retryInterval).After(deadline) {
+			return client, err
+		}
+		time.Sleep(cfg.retryInterval)
+	}
+}
+
+func dial(host string, port int, config *ssh.ClientConfig, cfg connectOptions) (*ssh.Client, error) {
 	addr := net.JoinHostPort(host, strconv.Itoa(port))
-	client, err := ssh.Dial("tcp", addr, config)
-	if err != nil {
-		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-			return nil, TimeoutError{Addr: addr, Err: err}
+	if cfg.mdns && mdns.IsLocal(host) {
+		if _, err := net.LookupHost(host); err != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), min(cfg.timeout, defaultRetryInterval))
+			ip, mErr := mdns.Resolve(ctx, host)
+			cancel()
+			if mErr != nil {
+				return nil, DialError{Addr: addr, Err: mErr}
+			}
+			addr = net.JoinHostPort(ip.String(), strconv.Itoa(port))
 		}
+	}
+
+	// This is ssh.Dial, split up so WithKeepAlive can record why it closed
+	// the connection on the client itself.
+	conn, err := net.DialTimeout("tcp", addr, config.Timeout)
+	if err != nil {
+		return nil, dialFailure(addr, config, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, dialFailure(addr, config, err)
+	}
+	if cfg.keepAliveInterval > 0 {
+		sshConn = &keepAliveConn{Conn: sshConn}
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
 
-		if strings.Contains(err.Error(), "unable to authenticate") {
-			return nil, AuthenticationError{Username: username, Err: err}
+func dialFailure(addr string, config *ssh.ClientConfig, err error) error {
+	if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+		return TimeoutError{Addr: addr, Err: err}
+	}
+
+	if strings.Contains(err.Error(), "unable to authenticate") {
+		return AuthenticationError{Username: config.User, Err: err}
+	}
+
+	return DialError{Addr: addr, Err: err}
+}
+
+func retryable(err error) bool {
+	var dialErr DialError
+	var timeoutErr TimeoutError
+	return errors.As(err, &dialErr) || errors.As(err, &timeoutErr)
+}
+
+// Ping checks that client's connection is still alive by sending an OpenSSH
+// keepalive request. It fails when the server does not answer within timeout,
+// which is how a target that rebooted or dropped off the network shows up
+// before the TCP connection is reset.
+func Ping(client *ssh.Client, timeout time.Duration) error {
+	if client == nil {
+		return fmt.Errorf("ping: no ssh client")
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("ping: %w", err)
 		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("ping: no reply within %s", timeout)
+	}
+}
+
+// healthCheckTimeout bounds each step of HealthCheck.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck reports whether client can still be reused: the server must
+// answer a keepalive request and then run `true` in a new session, each
+// within five seconds. The keepalive alone misses servers that keep the
+// connection open but no longer start sessions, e.g. while sshd shuts down
+// for a reboot or when MaxSessions is used up.
+func HealthCheck(client *ssh.Client) error {
+	return healthCheck(client, healthCheckTimeout)
+}
 
-		return nil, DialError{Addr: addr, Err: err}
+func healthCheck(client *ssh.Client, timeout time.Duration) error {
+	if err := Ping(client, timeout); err != nil {
+		return UnhealthyError{Check: "keepalive", Err: err}
 	}
+	done := make(chan error, 1)
+	go func() {
+		session, err := client.NewSession()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer session.Close()
+		done <- session.Run("true")
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return UnhealthyError{Check: "exec", Err: err}
+		}
+		return nil
+	case <-timer.C:
+		return UnhealthyError{Check: "exec", Err: fmt.Errorf("no reply within %s", timeout)}
+	}
+}
 
-	return client, nil
+// Fingerprint returns the SHA256 fingerprint of the public key c
+// authenticates with: the key at KeyPath, or AgentFingerprint. It is empty
+// for password credentials and for agent credentials that may use any
+// identity.
+func (c Credential) Fingerprint() (string, error) {
+	if c.Agent || strings.TrimSpace(c.KeyPath) == "" {
+		return c.AgentFingerprint, nil
+	}
+	keyBytes, err := os.ReadFile(c.KeyPath)
+	if err != nil {
+		return "", KeyLoadError{Path: c.KeyPath, Err: err}
+	}
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	if err != nil {
+		return "", KeyParseError{Path: c.KeyPath, Err: err}
+	}
+	return ssh.FingerprintSHA256(signer.PublicKey()), nil
 }
 
-func (c Credential) authMethod() (ssh.AuthMethod, error) {
+// authMethod builds the auth method for c. closeAuth releases what it holds
+// open, such as an agent connection, and must be called once connecting is
+// done.
+func (c Credential) authMethod() (auth ssh.AuthMethod, closeAuth func(), err error) {
 	hasPassword := strings.TrimSpace(c.Password) != ""
 	hasKey := strings.TrimSpace(c.KeyPath) != ""
+	hasCert := strings.TrimSpace(c.CertPath) != ""
 
 	switch {
-	case hasPassword && hasKey:
-		return nil, CredentialError{Reason: "provide either password or key path, not both"}
-	case !hasPassword && !hasKey:
-		return nil, CredentialError{Reason: "password or key path required"}
+	case hasCert && !hasKey:
+		return nil, nil, CredentialError{Reason: "certificate path requires a key path"}
+	case hasPassword && hasKey, c.Agent && (hasPassword || hasKey):
+		return nil, nil, CredentialError{Reason: "provide only one of password, key path, or agent"}
+	case !hasPassword && !hasKey && !c.Agent:
+		return nil, nil, CredentialError{Reason: "password or key path required"}
 	}
 
+	if c.Agent {
+		return agentAuth(os.Getenv("SSH_AUTH_SOCK"), c.AgentFingerprint)
+	}
 	if hasPassword {
-		return ssh.Password(c.Password), nil
+		return ssh.Password(c.Password), func() {}, nil
 	}
 
 	keyBytes, err := os.ReadFile(c.KeyPath)
 	if err != nil {
-		return nil, KeyLoadError{Path: c.KeyPath, Err: err}
+		return nil, nil, KeyLoadError{Path: c.KeyPath, Err: err}
 	}
 
 	signer, err := ssh.ParsePrivateKey(keyBytes)
 	if err != nil {
-		return nil, KeyParseError{Path: c.KeyPath, Err: err}
+		return nil, nil, KeyParseError{Path: c.KeyPath, Err: err}
+	}
+	if hasCert {
+		if signer, err = certSigner(c.CertPath, signer, time.Now()); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return ssh.PublicKeys(signer), nil
+	return ssh.PublicKeys(signer), func() {}, nil
 }
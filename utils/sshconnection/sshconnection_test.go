@@ -1,12 +1,21 @@
 package sshconnection
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 func TestCredentialAuthMethodValidation(t *testing.T) {
@@ -33,6 +42,11 @@ func TestCredentialAuthMethodValidation(t *testing.T) {
 			cred:    Credential{Password: "secret", KeyPath: "/tmp/key"},
 			errType: CredentialError{},
 		},
+		{
+			name:    "agent and password",
+			cred:    Credential{Agent: true, Password: "secret"},
+			errType: CredentialError{},
+		},
 		{
 			name:    "missing key file",
 			cred:    Credential{KeyPath: missingKeyPath},
@@ -45,7 +59,7 @@ func TestCredentialAuthMethodValidation(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := tt.cred.authMethod()
+			_, _, err := tt.cred.authMethod()
 			if tt.errType == nil {
 				require.NoError(t, err)
 				return
@@ -65,7 +79,7 @@ func TestCredentialAuthMethodKeyParseError(t *testing.T) {
 	require.NoError(t, os.WriteFile(keyPath, []byte("not a key"), 0o600))
 
 	cred := Credential{KeyPath: keyPath}
-	_, err := cred.authMethod()
+	_, _, err := cred.authMethod()
 	require.Error(t, err)
 	require.IsType(t, KeyParseError{}, err)
 }
@@ -107,3 +121,570 @@ func TestConnectOptionValidation(t *testing.T) {
 
 	require.Equal(t, connTimeout, config.timeout)
 }
+
+func TestConnectRetriesUnreachableTarget(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	_, err = Connect("127.0.0.1", port, "user", Credential{Password: "secret"}, WithRetryWindow(-time.Second))
+	require.IsType(t, OptionError{}, err)
+
+	fastRetry := func(opts *connectOptions) error {
+		opts.retryInterval = 50 * time.Millisecond
+		return nil
+	}
+	start := time.Now()
+	_, err = Connect("127.0.0.1", port, "user", Credential{Password: "secret"}, WithRetryWindow(300*time.Millisecond), fastRetry)
+	require.IsType(t, DialError{}, err)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.False(t, retryable(AuthenticationError{Username: "user"}))
+}
+
+func TestDiscoverKeyFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id_ed25519"), []byte("private"), 0o600))
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " ops@laptop\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id_ed25519.pub"), []byte(authorized), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "id_rsa"), []byte("private"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte("Host *"), 0o600))
+
+	keys := discoverKeyFiles(dir)
+	require.Len(t, keys, 2)
+	require.Equal(t, filepath.Join(dir, "id_ed25519"), keys[0].Path)
+	require.Equal(t, ssh.FingerprintSHA256(sshPub), keys[0].Fingerprint)
+	require.Equal(t, "ops@laptop", keys[0].Comment)
+	require.Equal(t, filepath.Join(dir, "id_rsa"), keys[1].Path)
+	require.Empty(t, keys[1].Fingerprint)
+}
+
+func TestDiscoverAgentKeys(t *testing.T) {
+	t.Parallel()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv, Comment: "yubikey"}))
+
+	socket := serveAgent(t, keyring)
+
+	keys := discoverAgentKeys(socket)
+	require.Len(t, keys, 1)
+	require.True(t, keys[0].FromAgent)
+	require.Equal(t, "yubikey", keys[0].Comment)
+	require.Empty(t, discoverAgentKeys(filepath.Join(t.TempDir(), "missing.sock")))
+
+	identities, err := AgentKeys(socket)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	require.Equal(t, keys[0].Fingerprint, ssh.FingerprintSHA256(identities[0]))
+	_, err = AgentKeys(filepath.Join(t.TempDir(), "missing.sock"))
+	var agentErr AgentError
+	require.ErrorAs(t, err, &agentErr)
+}
+
+func TestAgentAuthClosesAgentConnection(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	served := make(chan struct{})
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = agent.ServeAgent(agent.NewKeyring(), conn)
+		close(served)
+	}()
+
+	auth, closeAgent, err := agentAuth(socket, "")
+	require.NoError(t, err)
+	require.NotNil(t, auth)
+	closeAgent()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("agent connection was left open")
+	}
+}
+
+// testServer starts an SSH server on loopback that accepts any password,
+// answers keepalives, and runs every exec request successfully unless
+// sessions is false, in which case it rejects new sessions.
+func testServer(t *testing.T, sessions bool) *ssh.Client {
+	t.Helper()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range reqs {
+				_ = req.Reply(req.Type == "keepalive@openssh.com", nil)
+			}
+		}()
+		for newCh := range chans {
+			if !sessions {
+				_ = newCh.Reject(ssh.Prohibited, "no more sessions")
+				continue
+			}
+			ch, chReqs, err := newCh.Accept()
+			if err != nil {
+				continue
+			}
+			go func() {
+				for req := range chReqs {
+					_ = req.Reply(req.Type == "exec", nil)
+					if req.Type == "exec" {
+						_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+						_ = ch.Close()
+					}
+				}
+			}()
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+		User:            "user",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, HealthCheck(testServer(t, true)))
+
+	var unhealthy UnhealthyError
+	require.ErrorAs(t, healthCheck(testServer(t, false), time.Second), &unhealthy)
+	require.Equal(t, "exec", unhealthy.Check)
+
+	closed := testServer(t, true)
+	require.NoError(t, closed.Close())
+	require.ErrorAs(t, healthCheck(closed, time.Second), &unhealthy)
+	require.Equal(t, "keepalive", unhealthy.Check)
+
+	require.ErrorAs(t, HealthCheck(nil), &unhealthy)
+}
+
+func TestConnectRecordsConnectionInfo(t *testing.T) {
+	t.Parallel()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		ServerVersion:  "SSH-2.0-dropbear_2022.83",
+		BannerCallback: func(ssh.ConnMetadata) string { return "Authorized use only\n" },
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, errors.New("wrong password")
+			}
+			return nil, nil
+		},
+		KeyboardInteractiveCallback: func(ssh.ConnMetadata, ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			return nil, errors.New("not allowed")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				go func() {
+					for ch := range chans {
+						_ = ch.Reject(ssh.Prohibited, "no sessions")
+					}
+				}()
+				_ = sconn.Wait()
+			}()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+
+	var info ConnectionInfo
+	_, err = Connect("127.0.0.1", addr.Port, "user", Credential{Password: "wrong"}, WithConnectionInfo(&info))
+	var authErr AuthenticationError
+	require.ErrorAs(t, err, &authErr)
+	require.Equal(t, "SSH-2.0-dropbear_2022.83", info.ServerVersion)
+	require.Equal(t, "dropbear_2022.83", info.Software())
+	require.Equal(t, "Authorized use only\n", info.Banner)
+	require.ElementsMatch(t, []string{"password", "keyboard-interactive"}, info.AuthMethods)
+	require.True(t, info.OffersAuthMethod("password"))
+	require.False(t, info.OffersAuthMethod("publickey"))
+	require.Equal(t, ssh.KeyAlgoED25519, info.HostKeyAlgorithm)
+	require.NotEmpty(t, info.KeyExchange)
+
+	client, err := Connect("127.0.0.1", addr.Port, "user", Credential{Password: "secret"}, WithConnectionInfo(&info))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	require.Equal(t, "dropbear_2022.83", info.Software())
+
+	_, err = Connect("127.0.0.1", addr.Port, "user", Credential{Password: "secret"}, WithConnectionInfo(nil))
+	var optErr OptionError
+	require.ErrorAs(t, err, &optErr)
+}
+
+func TestConnectionInfoSoftware(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "OpenSSH_for_Windows_9.5", ConnectionInfo{ServerVersion: "SSH-2.0-OpenSSH_for_Windows_9.5"}.Software())
+	require.Equal(t, "OpenSSH_9.2p1 Debian-2+deb12u3", ConnectionInfo{ServerVersion: "SSH-2.0-OpenSSH_9.2p1 Debian-2+deb12u3"}.Software())
+	require.Equal(t, "garbage", ConnectionInfo{ServerVersion: "garbage"}.Software())
+	require.Empty(t, ConnectionInfo{}.Software())
+}
+
+// serveAgent serves keyring on a unix socket and returns its path.
+func serveAgent(t *testing.T, keyring agent.Agent) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	return socket
+}
+
+func TestConnectWithAgentForwarding(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv, Comment: "deploy-key"}))
+	t.Setenv("SSH_AUTH_SOCK", serveAgent(t, keyring))
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	// Each exec lists the keys of the forwarded agent, the way ssh on the
+	// target would, and exits 0 only when the agent was requested and holds
+	// deploy-key.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newCh := range chans {
+					ch, chReqs, err := newCh.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						forwarded := false
+						for req := range chReqs {
+							switch req.Type {
+							case "auth-agent-req@openssh.com":
+								forwarded = true
+								_ = req.Reply(true, nil)
+							case "exec":
+								_ = req.Reply(true, nil)
+								status := uint32(1)
+								if forwarded && agentHasKey(sconn, "deploy-key") {
+									status = 0
+								}
+								_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+								_ = ch.Close()
+							default:
+								_ = req.Reply(false, nil)
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	runForwarded := func(client *ssh.Client) error {
+		session, err := client.NewSession()
+		require.NoError(t, err)
+		defer session.Close()
+		require.NoError(t, RequestAgentForwarding(client, session))
+		return session.Run("ssh-add -l")
+	}
+
+	client, err := Connect("127.0.0.1", port, "user", Credential{Password: "secret"}, WithAgentForwarding())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	require.True(t, ForwardsAgent(client))
+	require.NoError(t, runForwarded(client))
+
+	plain, err := Connect("127.0.0.1", port, "user", Credential{Password: "secret"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = plain.Close() })
+	require.False(t, ForwardsAgent(plain))
+	require.Error(t, runForwarded(plain), "clients without forwarding do not request the agent")
+
+	require.NoError(t, client.Close())
+	require.Eventually(t, func() bool { return !ForwardsAgent(client) }, time.Second, 10*time.Millisecond)
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, err = Connect("127.0.0.1", port, "user", Credential{Password: "secret"}, WithAgentForwarding())
+	require.ErrorAs(t, err, new(OptionError))
+}
+
+func agentHasKey(conn ssh.Conn, comment string) bool {
+	ch, reqs, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return false
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+	keys, err := agent.NewClient(ch).List()
+	if err != nil {
+		return false
+	}
+	for _, key := range keys {
+		if key.Comment == comment {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnectWithKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	var silent atomic.Bool
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		go func() {
+			for ch := range chans {
+				_ = ch.Reject(ssh.Prohibited, "no sessions")
+			}
+		}()
+		for req := range reqs {
+			// A silent server models a link that dropped without a reset.
+			if !silent.Load() {
+				_ = req.Reply(true, nil)
+			}
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+
+	for _, opt := range []Option{WithKeepAlive(0, 3), WithKeepAlive(time.Second, 0)} {
+		_, err := Connect("127.0.0.1", addr.Port, "user", Credential{Password: "secret"}, opt)
+		var optErr OptionError
+		require.ErrorAs(t, err, &optErr)
+	}
+
+	client, err := Connect("127.0.0.1", addr.Port, "user", Credential{Password: "secret"}, WithKeepAlive(50*time.Millisecond, 2))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, KeepAliveFailure(client), "answered keepalives keep the connection open")
+
+	silent.Store(true)
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client was not closed after missed keepalives")
+	}
+	var keepAliveErr KeepAliveError
+	require.ErrorAs(t, KeepAliveFailure(client), &keepAliveErr)
+	require.Equal(t, 2, keepAliveErr.Missed)
+	require.NoError(t, KeepAliveFailure(nil))
+}
+
+func TestConnectWithCertificate(t *testing.T) {
+	t.Parallel()
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	require.NoError(t, err)
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caSigner.PublicKey().Marshal())
+		},
+	}
+	config := &ssh.ServerConfig{PublicKeyCallback: checker.Authenticate}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				go func() {
+					for ch := range chans {
+						_ = ch.Reject(ssh.Prohibited, "no sessions")
+					}
+				}()
+				_ = sconn.Wait()
+			}()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+
+	dir := t.TempDir()
+	writeKey := func(name string) (string, ssh.PublicKey) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		block, err := ssh.MarshalPrivateKey(key, "")
+		require.NoError(t, err)
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+		signer, err := ssh.NewSignerFromKey(key)
+		require.NoError(t, err)
+		return path, signer.PublicKey()
+	}
+	writeCert := func(keyPath string, key ssh.PublicKey, validBefore uint64) string {
+		cert := &ssh.Certificate{
+			Key:             key,
+			CertType:        ssh.UserCert,
+			ValidPrincipals: []string{"deploy"},
+			ValidBefore:     validBefore,
+		}
+		require.NoError(t, cert.SignCert(rand.Reader, caSigner))
+		path := CertificatePath(keyPath)
+		require.NoError(t, os.WriteFile(path, ssh.MarshalAuthorizedKey(cert), 0o600))
+		return path
+	}
+
+	keyPath, pub := writeKey("id_ed25519")
+	certPath := writeCert(keyPath, pub, ssh.CertTimeInfinity)
+	require.Equal(t, keyPath+"-cert.pub", certPath)
+
+	_, err = Connect("127.0.0.1", addr.Port, "deploy", Credential{KeyPath: keyPath})
+	var authErr AuthenticationError
+	require.ErrorAs(t, err, &authErr, "bare key is not trusted, only certificates")
+
+	client, err := Connect("127.0.0.1", addr.Port, "deploy", Credential{KeyPath: keyPath, CertPath: certPath})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	var certErr CertificateError
+	expiredKey, expiredPub := writeKey("id_expired")
+	expiredCert := writeCert(expiredKey, expiredPub, uint64(time.Now().Add(-time.Hour).Unix()))
+	_, err = Connect("127.0.0.1", addr.Port, "deploy", Credential{KeyPath: expiredKey, CertPath: expiredCert})
+	require.ErrorAs(t, err, &certErr)
+	require.Contains(t, certErr.Reason, "expired")
+
+	otherKey, _ := writeKey("id_other")
+	_, err = Connect("127.0.0.1", addr.Port, "deploy", Credential{KeyPath: otherKey, CertPath: certPath})
+	require.ErrorAs(t, err, &certErr)
+	require.Contains(t, certErr.Reason, "does not match")
+
+	_, err = Connect("127.0.0.1", addr.Port, "deploy", Credential{KeyPath: keyPath, CertPath: keyPath + ".pub-missing"})
+	require.ErrorAs(t, err, &certErr)
+
+	_, err = Connect("127.0.0.1", addr.Port, "deploy", Credential{CertPath: certPath})
+	var credErr CredentialError
+	require.ErrorAs(t, err, &credErr)
+}
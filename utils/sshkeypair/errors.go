@@ -88,3 +88,17 @@ type OptionError struct {
 func (e OptionError) Error() string {
 	return fmt.Sprintf("option error: %s", e.Reason)
 }
+
+// KeyLockError wraps failures to take the lock guarding a key pair.
+type KeyLockError struct {
+	Path string
+	Err  error
+}
+
+func (e KeyLockError) Error() string {
+	return fmt.Sprintf("lock %s failed: %v", e.Path, e.Err)
+}
+
+func (e KeyLockError) Unwrap() error {
+	return e.Err
+}
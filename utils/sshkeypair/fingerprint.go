@@ -0,0 +1,48 @@
+package sshkeypair
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Fingerprints holds a key's fingerprints as `ssh-keygen -l` prints them.
+type Fingerprints struct {
+	// SHA256 is the modern form, e.g. "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s".
+	SHA256 string
+	// MD5 is the legacy colon-separated form, e.g. "MD5:16:27:ac:a5:76:28:2d:36:63:1b:56:4d:eb:df:a6:48",
+	// still used by some CMDBs and older cloud consoles.
+	MD5 string
+}
+
+// Fingerprint returns the fingerprints of the key at path, which may hold a
+// public key in authorized_keys format or a private key EnsureKeyPair can
+// read.
+func Fingerprint(path string) (Fingerprints, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fingerprints{}, KeyReadError{Path: path, Err: err}
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		signer, privErr := readPrivateKey(path)
+		if privErr != nil {
+			return Fingerprints{}, KeyParseError{Path: path, Err: err}
+		}
+		if pub, err = ssh.NewPublicKey(signer.Public()); err != nil {
+			return Fingerprints{}, KeyParseError{Path: path, Err: err}
+		}
+	}
+
+	return FingerprintKey(pub), nil
+}
+
+// FingerprintKey returns the fingerprints of a parsed public key, such as
+// an ssh-agent identity.
+func FingerprintKey(pub ssh.PublicKey) Fingerprints {
+	return Fingerprints{
+		SHA256: ssh.FingerprintSHA256(pub),
+		MD5:    "MD5:" + ssh.FingerprintLegacyMD5(pub),
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package sshkeypair
+
+import "sync"
+
+var keyPairLocks sync.Map
+
+// lockKeyPair serializes key pair operations on privatePath within this
+// process; platforms without flock get no cross-process guard.
+func lockKeyPair(privatePath string) (func(), error) {
+	mu, _ := keyPairLocks.LoadOrStore(privatePath, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock, nil
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package sshkeypair
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockKeyPair takes an exclusive flock on <privatePath>.lock so concurrent
+// pipelines, in this process or another, cannot generate the same key pair
+// at once. The lock file is left in place; removing it would let a waiter
+// and a newcomer lock different inodes.
+func lockKeyPair(privatePath string) (func(), error) {
+	if err := ensureDir(privatePath); err != nil {
+		return nil, err
+	}
+	lockPath := privatePath + ".lock"
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, KeyLockError{Path: lockPath, Err: err}
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, KeyLockError{Path: lockPath, Err: err}
+	}
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		_ = file.Close()
+	}, nil
+}
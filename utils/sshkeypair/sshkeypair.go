@@ -2,6 +2,8 @@ package sshkeypair
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -12,6 +14,8 @@ import (
 	"strings"
 
 	"golang.org/x/crypto/ssh"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/atomicfile"
 )
 
 const (
@@ -20,6 +24,17 @@ const (
 	defaultComment = "ansible-host-prep"
 )
 
+// KeyType selects the algorithm of a generated key pair.
+type KeyType string
+
+const (
+	// KeyTypeRSA generates an RSA key of WithKeyBits bits (the default).
+	KeyTypeRSA KeyType = "rsa"
+	// KeyTypeEd25519 generates a small, fast ed25519 key in OpenSSH format,
+	// which suits low-powered targets.
+	KeyTypeEd25519 KeyType = "ed25519"
+)
+
 // KeyPairInfo describes the ensured key pair.
 type KeyPairInfo struct {
 	PrivatePath   string
@@ -32,10 +47,25 @@ type KeyPairInfo struct {
 type Option func(*ensureOptions) error
 
 type ensureOptions struct {
+	keyType KeyType
 	bits    int
 	comment string
 }
 
+// WithKeyType selects the algorithm used when a pair has to be generated.
+// An existing private key is reused whatever its type.
+func WithKeyType(keyType KeyType) Option {
+	return func(opts *ensureOptions) error {
+		switch keyType {
+		case KeyTypeRSA, KeyTypeEd25519:
+			opts.keyType = keyType
+			return nil
+		default:
+			return OptionError{Reason: fmt.Sprintf("unsupported key type %q", keyType)}
+		}
+	}
+}
+
 // WithKeyBits overrides the RSA key size.
 func WithKeyBits(bits int) Option {
 	return func(opts *ensureOptions) error {
@@ -59,7 +89,10 @@ func WithComment(comment string) Option {
 	}
 }
 
-// EnsureKeyPair checks for an RSA SSH key pair and creates it when missing.
+// EnsureKeyPair checks for an SSH key pair and creates it when missing, as
+// RSA unless WithKeyType says otherwise.
+// Calls for the same path are serialized with a lock file beside the key, so
+// parallel host pipelines share one generated pair.
 func EnsureKeyPair(privatePath string, opts ...Option) (*KeyPairInfo, error) {
 	privatePath = strings.TrimSpace(privatePath)
 	if privatePath == "" {
@@ -68,6 +101,7 @@ func EnsureKeyPair(privatePath string, opts ...Option) (*KeyPairInfo, error) {
 
 	pubPath := privatePath + ".pub"
 	cfg := ensureOptions{
+		keyType: KeyTypeRSA,
 		bits:    defaultBits,
 		comment: defaultComment,
 	}
@@ -80,6 +114,12 @@ func EnsureKeyPair(privatePath string, opts ...Option) (*KeyPairInfo, error) {
 		}
 	}
 
+	unlock, err := lockKeyPair(privatePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	info := &KeyPairInfo{
 		PrivatePath: privatePath,
 		PublicPath:  pubPath,
@@ -102,7 +142,7 @@ func EnsureKeyPair(privatePath string, opts ...Option) (*KeyPairInfo, error) {
 		}
 
 		if !pubExists {
-			if err := writePublicKey(pubPath, privKey, cfg.comment); err != nil {
+			if err := writePublicKey(pubPath, privKey.Public(), cfg.comment); err != nil {
 				return nil, err
 			}
 			info.PublicCreated = true
@@ -111,7 +151,7 @@ func EnsureKeyPair(privatePath string, opts ...Option) (*KeyPairInfo, error) {
 		return info, nil
 	}
 
-	if err := generateAndWritePair(privatePath, pubPath, cfg.bits, cfg.comment); err != nil {
+	if err := generateAndWritePair(privatePath, pubPath, cfg); err != nil {
 		return nil, err
 	}
 
@@ -121,29 +161,46 @@ func EnsureKeyPair(privatePath string, opts ...Option) (*KeyPairInfo, error) {
 	return info, nil
 }
 
-func generateAndWritePair(privatePath, publicPath string, bits int, comment string) error {
-	key, err := rsa.GenerateKey(rand.Reader, bits)
-	if err != nil {
-		return KeyGenerateError{Err: err}
+func generateAndWritePair(privatePath, publicPath string, cfg ensureOptions) error {
+	var (
+		key   crypto.Signer
+		block *pem.Block
+	)
+	switch cfg.keyType {
+	case KeyTypeEd25519:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return KeyGenerateError{Err: err}
+		}
+		block, err = ssh.MarshalPrivateKey(edKey, cfg.comment)
+		if err != nil {
+			return KeyGenerateError{Err: err}
+		}
+		key = edKey
+	default:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, cfg.bits)
+		if err != nil {
+			return KeyGenerateError{Err: err}
+		}
+		block = &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+		}
+		key = rsaKey
 	}
 
-	if err := writePrivateKey(privatePath, key); err != nil {
+	if err := writePrivateKey(privatePath, block); err != nil {
 		return err
 	}
 
-	if err := writePublicKey(publicPath, key, comment); err != nil {
+	if err := writePublicKey(publicPath, key.Public(), cfg.comment); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func writePrivateKey(path string, key *rsa.PrivateKey) error {
-	block := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
-	}
-
+func writePrivateKey(path string, block *pem.Block) error {
 	var buf bytes.Buffer
 	if err := pem.Encode(&buf, block); err != nil {
 		return KeyWriteError{Path: path, Err: err}
@@ -153,15 +210,15 @@ func writePrivateKey(path string, key *rsa.PrivateKey) error {
 		return err
 	}
 
-	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0o600); err != nil {
 		return KeyWriteError{Path: path, Err: err}
 	}
 
 	return nil
 }
 
-func writePublicKey(path string, key *rsa.PrivateKey, comment string) error {
-	pub, err := ssh.NewPublicKey(&key.PublicKey)
+func writePublicKey(path string, key crypto.PublicKey, comment string) error {
+	pub, err := ssh.NewPublicKey(key)
 	if err != nil {
 		return KeyWriteError{Path: path, Err: err}
 	}
@@ -176,14 +233,14 @@ func writePublicKey(path string, key *rsa.PrivateKey, comment string) error {
 		return err
 	}
 
-	if err := os.WriteFile(path, []byte(line), 0o644); err != nil {
+	if err := atomicfile.WriteFile(path, []byte(line), 0o644); err != nil {
 		return KeyWriteError{Path: path, Err: err}
 	}
 
 	return nil
 }
 
-func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+func readPrivateKey(path string) (crypto.Signer, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, KeyReadError{Path: path, Err: err}
@@ -198,6 +255,8 @@ func readPrivateKey(path string) (*rsa.PrivateKey, error) {
 	switch block.Type {
 	case "RSA PRIVATE KEY":
 		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "OPENSSH PRIVATE KEY":
+		parsed, err = ssh.ParseRawPrivateKey(data)
 	default:
 		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
 	}
@@ -205,12 +264,16 @@ func readPrivateKey(path string) (*rsa.PrivateKey, error) {
 		return nil, KeyParseError{Path: path, Err: err}
 	}
 
-	rsaKey, ok := parsed.(*rsa.PrivateKey)
-	if !ok {
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ed25519.PrivateKey:
+		return *key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
 		return nil, KeyParseError{Path: path, Err: fmt.Errorf("unsupported private key type %T", parsed)}
 	}
-
-	return rsaKey, nil
 }
 
 func ensureDir(path string) error {
@@ -3,6 +3,8 @@ package sshkeypair
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -59,6 +61,45 @@ func TestEnsureKeyPairRegeneratesMissingPublic(t *testing.T) {
 	require.True(t, info.PublicCreated)
 }
 
+func TestEnsureKeyPairConcurrentCallsShareOnePair(t *testing.T) {
+	t.Parallel()
+
+	privatePath := filepath.Join(t.TempDir(), "keys", "ansible_id")
+
+	const workers = 6
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []*KeyPairInfo
+	)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := EnsureKeyPair(privatePath, WithKeyBits(2048))
+			require.NoError(t, err)
+			mu.Lock()
+			results = append(results, info)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	generated := 0
+	for _, info := range results {
+		if info.KeyGenerated {
+			generated++
+		}
+	}
+	require.Equal(t, 1, generated)
+
+	// The pair on disk must still be consistent and loadable.
+	info, err := EnsureKeyPair(privatePath)
+	require.NoError(t, err)
+	require.False(t, info.KeyGenerated)
+	require.False(t, info.PublicCreated)
+}
+
 func TestEnsureKeyPairValidatesInput(t *testing.T) {
 	t.Parallel()
 
@@ -69,4 +110,58 @@ func TestEnsureKeyPairValidatesInput(t *testing.T) {
 	_, err = EnsureKeyPair("some", WithKeyBits(1024))
 	require.Error(t, err)
 	require.IsType(t, OptionError{}, err)
+
+	_, err = EnsureKeyPair("some", WithKeyType("dsa"))
+	require.IsType(t, OptionError{}, err)
+}
+
+func TestEnsureKeyPairGeneratesEd25519(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	private := filepath.Join(dir, "id_ed25519")
+
+	info, err := EnsureKeyPair(private, WithKeyType(KeyTypeEd25519))
+	require.NoError(t, err)
+	require.True(t, info.KeyGenerated)
+
+	privBytes, err := os.ReadFile(private)
+	require.NoError(t, err)
+	require.Contains(t, string(privBytes), "BEGIN OPENSSH PRIVATE KEY")
+	pubBytes, err := os.ReadFile(info.PublicPath)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(pubBytes), "ssh-ed25519 "))
+
+	// The existing key is reused, and its public half rebuilt when missing.
+	require.NoError(t, os.Remove(info.PublicPath))
+	again, err := EnsureKeyPair(private)
+	require.NoError(t, err)
+	require.False(t, again.KeyGenerated)
+	require.True(t, again.PublicCreated)
+	rebuilt, err := os.ReadFile(info.PublicPath)
+	require.NoError(t, err)
+	require.Equal(t, string(pubBytes), string(rebuilt))
+}
+
+func TestFingerprintMatchesForPublicAndPrivateKey(t *testing.T) {
+	t.Parallel()
+
+	private := filepath.Join(t.TempDir(), "id_fp")
+	info, err := EnsureKeyPair(private, WithKeyType(KeyTypeEd25519))
+	require.NoError(t, err)
+
+	fromPublic, err := Fingerprint(info.PublicPath)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(fromPublic.SHA256, "SHA256:"))
+	require.Regexp(t, `^MD5:([0-9a-f]{2}:){15}[0-9a-f]{2}$`, fromPublic.MD5)
+
+	fromPrivate, err := Fingerprint(info.PrivatePath)
+	require.NoError(t, err)
+	require.Equal(t, fromPublic, fromPrivate)
+
+	garbage := filepath.Join(t.TempDir(), "garbage")
+	require.NoError(t, os.WriteFile(garbage, []byte("not a key\n"), 0o600))
+	_, err = Fingerprint(garbage)
+	var parseErr KeyParseError
+	require.ErrorAs(t, err, &parseErr)
 }
@@ -44,3 +44,33 @@ func (e CommandError) Error() string {
 func (e CommandError) Unwrap() error {
 	return e.Err
 }
+
+// NFSHomeError reports that root could not write authorized_keys into a home
+// directory on NFS, typically because the export uses root_squash. Retry with
+// WithUserOwnedKeys.
+type NFSHomeError struct {
+	HomeDir string
+	Err     error
+}
+
+func (e NFSHomeError) Error() string {
+	return fmt.Sprintf("cannot write authorized_keys as root in NFS home %s (root_squash?); retry writing it as the user: %v", e.HomeDir, e.Err)
+}
+
+func (e NFSHomeError) Unwrap() error {
+	return e.Err
+}
+
+// IDMismatchError reports an existing user whose UID or primary GID differs
+// from the one requested with WithUID or WithGID.
+type IDMismatchError struct {
+	Username string
+	// Kind is "uid" or "gid".
+	Kind string
+	Want int
+	Got  int
+}
+
+func (e IDMismatchError) Error() string {
+	return fmt.Sprintf("user %s exists with %s %d, not %d; renumber it by hand or choose another %s", e.Username, e.Kind, e.Got, e.Want, e.Kind)
+}
@@ -0,0 +1,84 @@
+package systemuser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+const defaultBaseDir = "/home"
+
+// homeInfo is where the user's home lives and the filesystem type under it.
+type homeInfo struct {
+	dir    string
+	fsType string
+}
+
+// onNFS reports whether the home directory is on an NFS mount, where
+// root_squash can turn root's writes into permission errors.
+func (h homeInfo) onNFS() bool {
+	return strings.HasPrefix(h.fsType, "nfs")
+}
+
+// resolveHome finds the home directory EnsureUser works with: WithHomeDir,
+// else an existing user's home from the passwd database, else WithBaseDir
+// or the HOME= base in /etc/default/useradd (default /home) plus username.
+// It also reports the filesystem type of the home (or, before it exists, its
+// parent) so NFS homes can be handled.
+func resolveHome(r Runner, username string, config ensureUserOptions) (homeInfo, error) {
+	homeLine := "home=" + shellquote.Quote(config.homeDir)
+	if config.homeDir == "" {
+		baseLine := "base=" + shellquote.Quote(config.baseDir)
+		if config.baseDir == "" {
+			baseLine = `base=$(sed -n 's/^HOME=//p' /etc/default/useradd 2>/dev/null | tail -n 1)`
+		}
+		homeLine = fmt.Sprintf(`home=$(getent passwd %s 2>/dev/null | cut -d: -f6)
+if [ -z "$home" ]; then
+	%s
+	home="${base:-%s}"/%s
+fi`, shellquote.Quote(username), baseLine, defaultBaseDir, shellquote.Quote(username))
+	}
+	cmd := fmt.Sprintf(`
+%s
+echo "$home"
+fs=$(stat -f -c %%T "$home" 2>/dev/null || stat -f -c %%T "$(dirname "$home")" 2>/dev/null || true)
+echo "$fs"
+`, homeLine)
+	stdout, stderr, err := r.Run(cmd)
+	if err != nil {
+		return homeInfo{}, CommandError{Step: "resolve-home", Err: err, Stderr: stderr}
+	}
+
+	dir, fsType, _ := strings.Cut(strings.TrimSpace(stdout), "\n")
+	info := homeInfo{dir: strings.TrimSpace(dir), fsType: strings.TrimSpace(fsType)}
+	if !path.IsAbs(info.dir) {
+		// Runners that return nothing (or junk) get the conventional layout.
+		base := config.baseDir
+		if base == "" {
+			base = defaultBaseDir
+		}
+		info.dir = path.Join(base, username)
+		if config.homeDir != "" {
+			info.dir = config.homeDir
+		}
+	}
+	return info, nil
+}
+
+// ensureAuthorizedKeyAsUser writes authorized_keys as the user instead of
+// root, for NFS homes exported with root_squash.
+func ensureAuthorizedKeyAsUser(r Runner, username, homeDir string, keys []string, replace bool) error {
+	sshDir := path.Join(homeDir, ".ssh")
+	authPath := path.Join(sshDir, "authorized_keys")
+	script := fmt.Sprintf(`set -eu
+umask 077
+mkdir -p %s
+chmod 700 %s
+%s
+chmod 600 %s
+`, shellquote.Quote(sshDir), shellquote.Quote(sshDir), writeKeyScript(authPath, keys, replace), shellquote.Quote(authPath))
+	cmd := fmt.Sprintf("su -s /bin/sh %s -c %s", shellquote.Quote(username), shellquote.Quote(script))
+	return runStep(r, "authorized_keys", cmd)
+}
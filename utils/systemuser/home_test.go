@@ -0,0 +1,128 @@
+package systemuser
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// shellRunner runs commands with the local shell, standing in for a target.
+type shellRunner struct{}
+
+func (shellRunner) Run(cmd string) (string, string, error) {
+	var stderr strings.Builder
+	c := exec.Command("sh", "-c", cmd)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	return string(out), stderr.String(), err
+}
+
+func TestResolveHome(t *testing.T) {
+	t.Parallel()
+
+	const missing = "host-prep-no-such-user"
+	tests := []struct {
+		name   string
+		config ensureUserOptions
+		want   string
+	}{
+		{name: "base dir for new user", config: ensureUserOptions{baseDir: "/srv/homes"}, want: "/srv/homes/" + missing},
+		{name: "explicit home dir wins", config: ensureUserOptions{homeDir: "/data/ansible", baseDir: "/srv/homes"}, want: "/data/ansible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			info, err := resolveHome(shellRunner{}, missing, tt.config)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, info.dir)
+			require.False(t, info.onNFS())
+		})
+	}
+}
+
+func TestEnsureUserOnNFSHome(t *testing.T) {
+	t.Parallel()
+
+	denied := errors.New("exit status 1")
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/net/home/deploy\nnfs\n"},
+			{match: "install -o", stderr: "install: cannot change permissions of '/net/home/deploy/.ssh': Operation not permitted", err: denied},
+		},
+	}
+	_, err := EnsureUser(r, "deploy", "ssh-rsa AAA")
+	var nfsErr NFSHomeError
+	require.ErrorAs(t, err, &nfsErr)
+	require.Equal(t, "/net/home/deploy", nfsErr.HomeDir)
+	require.ErrorIs(t, err, denied)
+
+	rec := &recordingRunner{}
+	r = &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/net/home/deploy\nnfs\n"},
+			{match: "su -s /bin/sh 'deploy' -c"},
+		},
+	}
+	res, err := EnsureUser(runnerChain{r, rec}, "deploy", "ssh-rsa AAA", WithUserOwnedKeys())
+	require.NoError(t, err)
+	require.True(t, res.HomeOnNFS)
+	require.Equal(t, "/net/home/deploy", res.HomeDir)
+	require.Contains(t, rec.cmds[2], "/net/home/deploy/.ssh/authorized_keys")
+	require.NotContains(t, rec.cmds[2], "install -o")
+}
+
+func TestWithBaseDirValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := EnsureUser(&fakeRunner{}, "deploy", "ssh-rsa AAA", WithBaseDir("homes"))
+	require.IsType(t, OptionError{}, err)
+}
+
+// runnerChain records every command with rec and answers it with r.
+type runnerChain struct {
+	r   *fakeRunner
+	rec *recordingRunner
+}
+
+func (c runnerChain) Run(cmd string) (string, string, error) {
+	c.rec.cmds = append(c.rec.cmds, cmd)
+	return c.r.Run(cmd)
+}
+
+func TestEnsureUserRestoresSELinuxContext(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/srv/deploy\nxfs\n"},
+			{match: "install -o"},
+		},
+	}
+	rec := &recordingRunner{}
+	_, err := EnsureUser(runnerChain{r, rec}, "deploy", "ssh-rsa AAA")
+	require.NoError(t, err)
+	script := rec.cmds[2]
+	require.Contains(t, script, "restorecon -R '/srv/deploy/.ssh'")
+	require.Contains(t, script, "semanage fcontext -a -t ssh_home_t '/srv/deploy/.ssh(/.*)?'")
+	require.Contains(t, script, "chcon -R -t ssh_home_t '/srv/deploy/.ssh'")
+	require.NoError(t, exec.Command("sh", "-n", "-c", script).Run())
+
+	// NFS homes take their label from the mount.
+	r = &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/net/home/deploy\nnfs\n"},
+			{match: "install -o"},
+		},
+	}
+	rec = &recordingRunner{}
+	_, err = EnsureUser(runnerChain{r, rec}, "deploy", "ssh-rsa AAA")
+	require.NoError(t, err)
+	require.NotContains(t, rec.cmds[2], "restorecon")
+}
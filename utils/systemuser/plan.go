@@ -0,0 +1,186 @@
+package systemuser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
+)
+
+// RenderSudoers returns the sudoers drop-in EnsureUser writes for username
+// with WithPasswordlessSudo, including any Defaults lines for logging. With
+// commands, NOPASSWD is granted for those commands only instead of ALL.
+func RenderSudoers(username string, logging SudoLogging, commands ...string) string {
+	var b strings.Builder
+	if logging.LogFile != "" {
+		fmt.Fprintf(&b, "Defaults:%s logfile=\"%s\"\n", username, logging.LogFile)
+	}
+	if logging.IOLog {
+		fmt.Fprintf(&b, "Defaults:%s log_output\n", username)
+	}
+	if len(commands) == 0 {
+		b.WriteString(username + " ALL=(ALL) NOPASSWD:ALL\n")
+		return b.String()
+	}
+	escaped := make([]string, len(commands))
+	for i, command := range commands {
+		escaped[i] = sudoersEscaper.Replace(command)
+	}
+	fmt.Fprintf(&b, "%s ALL=(ALL) NOPASSWD: %s\n", username, strings.Join(escaped, ", "))
+	return b.String()
+}
+
+// sudoersEscaper escapes the characters that are special in a sudoers
+// command specification.
+var sudoersEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ":", `\:`, "=", `\=`)
+
+// RenderAuthorizedKeys returns the authorized_keys file EnsureUser writes
+// with WithReplaceAuthorizedKeys: one line per key.
+func RenderAuthorizedKeys(keys ...string) string {
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(strings.TrimSpace(key) + "\n")
+	}
+	return b.String()
+}
+
+// AppendAuthorizedKey returns the authorized_keys file EnsureUser writes by
+// default: current with each of keys appended, unless a line already holds
+// the same key. Options and comments do not matter for the comparison.
+func AppendAuthorizedKey(current string, keys ...string) string {
+	for _, key := range keys {
+		if hasKey(current, key) {
+			continue
+		}
+		if current != "" && !strings.HasSuffix(current, "\n") {
+			current += "\n"
+		}
+		current += RenderAuthorizedKeys(key)
+	}
+	return current
+}
+
+func hasKey(authorizedKeys, key string) bool {
+	material := keyMaterial(key)
+	for _, line := range strings.Split(authorizedKeys, "\n") {
+		if strings.Contains(line, material) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyMaterial returns the key type and base64 data of an authorized_keys
+// line, which identify the key regardless of its comment.
+func keyMaterial(publicKey string) string {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 {
+		return strings.TrimSpace(publicKey)
+	}
+	return fields[0] + " " + fields[1]
+}
+
+// Plan describes what EnsureUser would change, without changing anything.
+type Plan struct {
+	Username   string
+	UserExists bool
+	Files      []FileChange
+}
+
+// Changed reports whether EnsureUser would create the user or rewrite a file.
+func (p *Plan) Changed() bool {
+	if !p.UserExists {
+		return true
+	}
+	for _, f := range p.Files {
+		if f.Changed() {
+			return true
+		}
+	}
+	return false
+}
+
+// FileChange is a file EnsureUser writes, with its current and planned content.
+type FileChange struct {
+	Path    string
+	Exists  bool
+	Current string
+	Planned string
+}
+
+// Changed reports whether writing the file would alter it.
+func (f FileChange) Changed() bool {
+	return !f.Exists || f.Current != f.Planned
+}
+
+// Diff renders the change as a unified diff, or "" when there is none.
+func (f FileChange) Diff() string {
+	if !f.Changed() {
+		return ""
+	}
+	from := f.Path
+	if !f.Exists {
+		from = "/dev/null"
+	}
+	return UnifiedDiff(from, f.Path, f.Current, f.Planned)
+}
+
+// PlanUser reads the sudoers drop-in and authorized_keys EnsureUser would
+// write with the same arguments and returns them next to the rendered
+// content. It only runs read-only commands on the target.
+func PlanUser(r Runner, username, publicKey string, opts ...Option) (*Plan, error) {
+	username, publicKey, config, err := prepare(r, username, publicKey, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Username: username, UserExists: userExists(r, username)}
+	home, err := resolveHome(r, username, config)
+	if err != nil {
+		return nil, err
+	}
+
+	authPath := filepath.Join(home.dir, ".ssh", "authorized_keys")
+	authFile, err := readFile(r, authPath)
+	if err != nil {
+		return nil, err
+	}
+	keys := authorizedKeys(publicKey, config)
+	if config.replaceKeys {
+		authFile.Planned = RenderAuthorizedKeys(keys...)
+	} else {
+		authFile.Planned = AppendAuthorizedKey(authFile.Current, keys...)
+	}
+	plan.Files = append(plan.Files, authFile)
+
+	if config.passwordlessSudo {
+		dir := config.sudoersDir
+		if dir == "" {
+			stdout, stderr, err := r.Run(`if [ "$(uname -s)" = FreeBSD ]; then echo /usr/local/etc/sudoers.d; else echo /etc/sudoers.d; fi`)
+			if err != nil {
+				return nil, CommandError{Step: "sudoers-dir", Err: err, Stderr: stderr}
+			}
+			dir = strings.TrimSpace(stdout)
+		}
+		sudoersFile, err := readFile(r, filepath.Join(dir, username))
+		if err != nil {
+			return nil, err
+		}
+		sudoersFile.Planned = RenderSudoers(username, config.sudoLogging, config.sudoCommands...)
+		plan.Files = append(plan.Files, sudoersFile)
+	}
+
+	return plan, nil
+}
+
+// readFile returns the current content of path on the target.
+func readFile(r Runner, path string) (FileChange, error) {
+	cmd := fmt.Sprintf("if [ -f %[1]s ]; then printf 'present\\n'; cat %[1]s; else printf 'missing\\n'; fi", shellquote.Quote(path))
+	stdout, stderr, err := r.Run(cmd)
+	if err != nil {
+		return FileChange{}, CommandError{Step: "read " + path, Err: err, Stderr: stderr}
+	}
+	marker, content, _ := strings.Cut(stdout, "\n")
+	return FileChange{Path: path, Exists: marker == "present", Current: content}, nil
+}
@@ -0,0 +1,143 @@
+package systemuser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanUserDiffsAgainstCurrentFiles(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u", err: nil},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "'/home/deploy/.ssh/authorized_keys'", stdout: "present\nssh-rsa OLD old@host\n"},
+			{match: "uname -s", stdout: "/etc/sudoers.d\n"},
+			{match: "'/etc/sudoers.d/deploy'", stdout: "missing\n"},
+		},
+	}
+
+	plan, err := PlanUser(r, "deploy", "ssh-ed25519 NEW deploy@ctl", WithPasswordlessSudo())
+	require.NoError(t, err)
+	require.True(t, plan.UserExists)
+	require.True(t, plan.Changed())
+	require.Len(t, plan.Files, 2)
+	require.Empty(t, r.responses)
+
+	keys := plan.Files[0]
+	require.Equal(t, "/home/deploy/.ssh/authorized_keys", keys.Path)
+	require.Equal(t, "--- /home/deploy/.ssh/authorized_keys\n"+
+		"+++ /home/deploy/.ssh/authorized_keys\n"+
+		"@@ -1,1 +1,2 @@\n"+
+		" ssh-rsa OLD old@host\n"+
+		"+ssh-ed25519 NEW deploy@ctl\n", keys.Diff())
+
+	sudoers := plan.Files[1]
+	require.False(t, sudoers.Exists)
+	require.Equal(t, "--- /dev/null\n"+
+		"+++ /etc/sudoers.d/deploy\n"+
+		"@@ -0,0 +1,1 @@\n"+
+		"+deploy ALL=(ALL) NOPASSWD:ALL\n", sudoers.Diff())
+}
+
+func TestPlanUserReplacingKeys(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u", err: nil},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "authorized_keys", stdout: "present\nssh-rsa OLD old@host\n"},
+		},
+	}
+
+	plan, err := PlanUser(r, "deploy", "ssh-ed25519 NEW deploy@ctl", WithReplaceAuthorizedKeys())
+	require.NoError(t, err)
+	require.Equal(t, "ssh-ed25519 NEW deploy@ctl\n", plan.Files[0].Planned)
+}
+
+func TestAppendAuthorizedKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		current string
+		want    string
+	}{
+		{name: "empty file", current: "", want: "ssh-ed25519 NEW deploy@ctl\n"},
+		{name: "keeps other keys", current: "ssh-rsa OLD old@host\n", want: "ssh-rsa OLD old@host\nssh-ed25519 NEW deploy@ctl\n"},
+		{name: "terminates last line", current: "ssh-rsa OLD", want: "ssh-rsa OLD\nssh-ed25519 NEW deploy@ctl\n"},
+		{name: "present with another comment", current: "from=\"10.0.0.0/8\" ssh-ed25519 NEW laptop\n", want: "from=\"10.0.0.0/8\" ssh-ed25519 NEW laptop\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, AppendAuthorizedKey(tt.current, "ssh-ed25519 NEW deploy@ctl"))
+		})
+	}
+}
+
+func TestAppendAuthorizedKeySkipsPresentKeys(t *testing.T) {
+	t.Parallel()
+
+	current := "ssh-rsa OLD old@host\n"
+	got := AppendAuthorizedKey(current, "ssh-ed25519 NEW deploy@ctl", "ssh-rsa OLD renamed", "ssh-ed25519 TEAM alice")
+	require.Equal(t, "ssh-rsa OLD old@host\nssh-ed25519 NEW deploy@ctl\nssh-ed25519 TEAM alice\n", got)
+	require.Equal(t, "a b\nc d\n", RenderAuthorizedKeys("a b", " c d "))
+}
+
+func TestPlanUserUnchanged(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u", err: nil},
+			{match: `home='/srv/deploy'`, stdout: "/srv/deploy\nxfs\n"},
+			{match: "/srv/deploy/.ssh/authorized_keys", stdout: "present\nssh-rsa AAA\n"},
+			{match: "'/usr/local/etc/sudoers.d/deploy'", stdout: "present\ndeploy ALL=(ALL) NOPASSWD:ALL\n"},
+		},
+	}
+
+	plan, err := PlanUser(r, "deploy", "ssh-rsa AAA", WithHomeDir("/srv/deploy"), WithPasswordlessSudo(), WithSudoersDir("/usr/local/etc/sudoers.d"))
+	require.NoError(t, err)
+	require.False(t, plan.Changed())
+	for _, f := range plan.Files {
+		require.Empty(t, f.Diff())
+	}
+}
+
+func TestPlanUserReportsMissingUserAndReadErrors(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u", err: errors.New("exit status 1")},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "authorized_keys", stdout: "missing\n"},
+		},
+	}
+	plan, err := PlanUser(r, "deploy", "ssh-rsa AAA")
+	require.NoError(t, err)
+	require.False(t, plan.UserExists)
+	require.Len(t, plan.Files, 1)
+
+	r = &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "authorized_keys", stderr: "Permission denied", err: errors.New("exit status 1")},
+		},
+	}
+	_, err = PlanUser(r, "deploy", "ssh-rsa AAA")
+	require.IsType(t, CommandError{}, err)
+}
+
+func TestUnifiedDiffKeepsContext(t *testing.T) {
+	t.Parallel()
+
+	diff := UnifiedDiff("a", "b", "one\ntwo\nthree\n", "one\n2\nthree\nfour\n")
+	require.Equal(t, "--- a\n+++ b\n@@ -1,3 +1,4 @@\n one\n-two\n+2\n three\n+four\n", diff)
+}
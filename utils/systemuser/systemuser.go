@@ -3,7 +3,11 @@ package systemuser
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/BrianJOC/ansible-host-prep/utils/shellquote"
 )
 
 // Runner executes commands on the target system with elevated privileges.
@@ -13,12 +17,23 @@ type Runner interface {
 
 // Result reports what EnsureUser performed.
 type Result struct {
-	Username               string
-	HomeDir                string
-	UserCreated            bool
-	AuthorizedKeyUpdated   bool
-	AddedToSudo            bool
+	Username string
+	HomeDir  string
+	// HomeOnNFS is true when the home directory is on an NFS mount.
+	HomeOnNFS            bool
+	UserCreated          bool
+	AuthorizedKeyUpdated bool
+	AddedToSudo          bool
+	// SudoGroup is the admin group the user was added to.
+	SudoGroup              string
 	PasswordlessConfigured bool
+	// SudoCommands lists the commands passwordless sudo was limited to by
+	// WithSudoCommands; empty means ALL.
+	SudoCommands []string
+	// PasswordSet is true when WithPasswordHash was applied.
+	PasswordSet bool
+	// PasswordLocked is true when WithLockedPassword was applied.
+	PasswordLocked bool
 }
 
 // Option configures EnsureUser behavior.
@@ -27,13 +42,41 @@ type Option func(*ensureUserOptions) error
 type ensureUserOptions struct {
 	shell            string
 	homeDir          string
+	baseDir          string
+	userOwnedKeys    bool
+	replaceKeys      bool
+	extraKeys        []string
+	skelDir          string
+	groups           []string
+	expiry           time.Time
+	comment          string
+	umask            *uint32
+	uid              int
+	gid              int
+	systemAccount    bool
+	passwordHash     string
+	lockPassword     bool
 	addToSudo        bool
 	passwordlessSudo bool
 	sudoGroup        string
 	sudoersDir       string
+	sudoLogging      SudoLogging
+	sudoCommands     []string
+}
+
+// SudoLogging configures how sudo logs the user's commands. It is written to
+// the sudoers drop-in, so it needs WithPasswordlessSudo.
+type SudoLogging struct {
+	// LogFile is a file sudo appends one line per command to (Defaults
+	// logfile), in addition to syslog.
+	LogFile string
+	// IOLog records the output of every sudo session (Defaults log_output),
+	// under /var/log/sudo-io by default; replay sessions with sudoreplay.
+	IOLog bool
 }
 
-// WithShell overrides the login shell assigned to the user.
+// WithShell overrides the login shell assigned to the user (default
+// /bin/bash, falling back to /bin/sh on hosts without bash such as BSD).
 func WithShell(shell string) Option {
 	return func(opts *ensureUserOptions) error {
 		shell = strings.TrimSpace(shell)
@@ -57,6 +100,189 @@ func WithHomeDir(dir string) Option {
 	}
 }
 
+// WithBaseDir sets the directory new users' homes are created under, in place
+// of the HOME= setting in /etc/default/useradd. Existing users keep their home.
+func WithBaseDir(dir string) Option {
+	return func(opts *ensureUserOptions) error {
+		dir = strings.TrimSpace(dir)
+		if !strings.HasPrefix(dir, "/") {
+			return OptionError{Reason: "base directory must be an absolute path"}
+		}
+		opts.baseDir = strings.TrimRight(dir, "/")
+		if opts.baseDir == "" {
+			opts.baseDir = "/"
+		}
+		return nil
+	}
+}
+
+// WithUserOwnedKeys writes ~/.ssh/authorized_keys as the user (via su)
+// instead of as root. Use it for NFS homes exported with root_squash.
+func WithUserOwnedKeys() Option {
+	return func(opts *ensureUserOptions) error {
+		opts.userOwnedKeys = true
+		return nil
+	}
+}
+
+// WithReplaceAuthorizedKeys makes publicKey and any WithAuthorizedKeys the
+// only entries in authorized_keys, removing other keys. By default each key
+// is appended when missing and existing entries are kept.
+func WithReplaceAuthorizedKeys() Option {
+	return func(opts *ensureUserOptions) error {
+		opts.replaceKeys = true
+		return nil
+	}
+}
+
+// WithAuthorizedKeys authorizes more public keys next to publicKey, such as
+// team members' keys. Blank lines and # comments are skipped, so the lines
+// of an authorized_keys or .pub file can be passed as they are. Keys are
+// matched by type and key data, so each ends up in the file once.
+func WithAuthorizedKeys(keys ...string) Option {
+	return func(opts *ensureUserOptions) error {
+		for _, key := range keys {
+			key = strings.TrimSpace(key)
+			if key == "" || strings.HasPrefix(key, "#") {
+				continue
+			}
+			if len(strings.Fields(key)) < 2 {
+				return OptionError{Reason: fmt.Sprintf("invalid public key %q", key)}
+			}
+			opts.extraKeys = append(opts.extraKeys, key)
+		}
+		return nil
+	}
+}
+
+// WithSkelDir sets the skeleton directory copied into a new user's home
+// (useradd -k) instead of the system default, usually /etc/skel.
+func WithSkelDir(dir string) Option {
+	return func(opts *ensureUserOptions) error {
+		dir = strings.TrimSpace(dir)
+		if !strings.HasPrefix(dir, "/") {
+			return OptionError{Reason: "skeleton directory must be an absolute path"}
+		}
+		opts.skelDir = dir
+		return nil
+	}
+}
+
+// WithGroups adds a new user to supplementary groups (useradd -G). The groups
+// must already exist on the target. The sudo group is handled separately by
+// WithSudoAccess.
+func WithGroups(groups ...string) Option {
+	return func(opts *ensureUserOptions) error {
+		for _, group := range groups {
+			group = strings.TrimSpace(group)
+			if group == "" || strings.ContainsAny(group, ", :") {
+				return OptionError{Reason: fmt.Sprintf("invalid group name %q", group)}
+			}
+			opts.groups = append(opts.groups, group)
+		}
+		return nil
+	}
+}
+
+// WithExpiry sets the date a new account expires (useradd -e).
+func WithExpiry(date time.Time) Option {
+	return func(opts *ensureUserOptions) error {
+		if date.IsZero() {
+			return OptionError{Reason: "expiry date must be set"}
+		}
+		opts.expiry = date
+		return nil
+	}
+}
+
+// WithComment sets a new user's comment (GECOS) field (useradd -c).
+func WithComment(comment string) Option {
+	return func(opts *ensureUserOptions) error {
+		comment = strings.TrimSpace(comment)
+		if comment == "" {
+			return OptionError{Reason: "comment must not be empty"}
+		}
+		if strings.ContainsAny(comment, ":\n") {
+			return OptionError{Reason: "comment must not contain colons or newlines"}
+		}
+		opts.comment = comment
+		return nil
+	}
+}
+
+// WithUmask sets the umask a new home directory is created with, e.g. 0077
+// for a home only the user can read (useradd -K UMASK, pw -M on FreeBSD).
+func WithUmask(umask uint32) Option {
+	return func(opts *ensureUserOptions) error {
+		if umask > 0o777 {
+			return OptionError{Reason: fmt.Sprintf("umask %#o is out of range", umask)}
+		}
+		opts.umask = &umask
+		return nil
+	}
+}
+
+// WithUID creates the user with a fixed UID (useradd -u), so the account has
+// the same ID on every host of a fleet, e.g. for NFS homes. An existing user
+// with another UID fails with IDMismatchError.
+func WithUID(uid int) Option {
+	return func(opts *ensureUserOptions) error {
+		if uid <= 0 {
+			return OptionError{Reason: fmt.Sprintf("uid %d must be positive", uid)}
+		}
+		opts.uid = uid
+		return nil
+	}
+}
+
+// WithGID gives a new user a fixed primary GID (useradd -g). A group with
+// that GID is created under the user's name when the target has none. An
+// existing user with another primary GID fails with IDMismatchError.
+func WithGID(gid int) Option {
+	return func(opts *ensureUserOptions) error {
+		if gid <= 0 {
+			return OptionError{Reason: fmt.Sprintf("gid %d must be positive", gid)}
+		}
+		opts.gid = gid
+		return nil
+	}
+}
+
+// WithSystemAccount creates the user as a system account (useradd -r): its
+// UID comes from the system range and its password never ages. FreeBSD's pw
+// has no system range, so there the UID must be set with WithUID.
+func WithSystemAccount() Option {
+	return func(opts *ensureUserOptions) error {
+		opts.systemAccount = true
+		return nil
+	}
+}
+
+// WithPasswordHash sets the user's password to hash, a crypt(3) string such
+// as the output of `openssl passwd -6`. It is applied to existing users too,
+// so every run leaves the same known password.
+func WithPasswordHash(hash string) Option {
+	return func(opts *ensureUserOptions) error {
+		hash = strings.TrimSpace(hash)
+		if !strings.HasPrefix(hash, "$") || strings.ContainsAny(hash, ": \t\n") {
+			return OptionError{Reason: "password hash must be a crypt(3) string such as $6$salt$hash"}
+		}
+		opts.passwordHash = hash
+		return nil
+	}
+}
+
+// WithLockedPassword locks the user's password so only key logins work, as
+// auditors often require. The password hash is set to "*", as
+// DisablePassword does, rather than using usermod -L: its "!" prefix makes
+// sshd without PAM, as on Alpine, refuse key logins as well.
+func WithLockedPassword() Option {
+	return func(opts *ensureUserOptions) error {
+		opts.lockPassword = true
+		return nil
+	}
+}
+
 // WithSudoAccess ensures the user is added to the sudo group.
 func WithSudoAccess() Option {
 	return func(opts *ensureUserOptions) error {
@@ -74,7 +300,8 @@ func WithPasswordlessSudo() Option {
 	}
 }
 
-// WithSudoGroup overrides the primary sudo-capable group (default "sudo").
+// WithSudoGroup overrides the admin group. By default it is detected on the
+// target: "wheel" on BSD, otherwise "sudo" or "wheel", whichever exists.
 func WithSudoGroup(group string) Option {
 	return func(opts *ensureUserOptions) error {
 		group = strings.TrimSpace(group)
@@ -86,7 +313,47 @@ func WithSudoGroup(group string) Option {
 	}
 }
 
-// WithSudoersDir overrides the location used for sudoers drop-ins.
+// WithSudoLogging adds sudo logging Defaults for the user to its sudoers
+// drop-in, e.g. for compliance regimes that require privileged session logs.
+func WithSudoLogging(logging SudoLogging) Option {
+	return func(opts *ensureUserOptions) error {
+		logging.LogFile = strings.TrimSpace(logging.LogFile)
+		if logging.LogFile != "" && (!strings.HasPrefix(logging.LogFile, "/") || strings.ContainsAny(logging.LogFile, " \t\n\"\\,")) {
+			return OptionError{Reason: fmt.Sprintf("sudo log file %q must be an absolute path without spaces, quotes, or commas", logging.LogFile)}
+		}
+		opts.sudoLogging = logging
+		return nil
+	}
+}
+
+// WithSudoCommands limits the NOPASSWD rule in the sudoers drop-in to
+// commands instead of ALL, for environments that prohibit blanket
+// passwordless root. Each command is an absolute path, optionally followed
+// by arguments; sudoers special characters are escaped. Ansible's become
+// runs modules through /bin/sh, so it must be listed for playbooks to work.
+func WithSudoCommands(commands []string) Option {
+	return func(opts *ensureUserOptions) error {
+		var cleaned []string
+		for _, command := range commands {
+			command = strings.TrimSpace(command)
+			if command == "" {
+				continue
+			}
+			if !strings.HasPrefix(command, "/") || strings.ContainsAny(command, "\n\r") {
+				return OptionError{Reason: fmt.Sprintf("sudo command %q must be a single line starting with an absolute path", command)}
+			}
+			cleaned = append(cleaned, command)
+		}
+		if len(cleaned) == 0 {
+			return OptionError{Reason: "at least one sudo command is required"}
+		}
+		opts.sudoCommands = cleaned
+		return nil
+	}
+}
+
+// WithSudoersDir overrides the location used for sudoers drop-ins (default
+// /etc/sudoers.d, or /usr/local/etc/sudoers.d on FreeBSD).
 func WithSudoersDir(dir string) Option {
 	return func(opts *ensureUserOptions) error {
 		dir = strings.TrimSpace(dir)
@@ -100,124 +367,452 @@ func WithSudoersDir(dir string) Option {
 
 // EnsureUser provisions a local user with SSH access and optional sudo privileges.
 func EnsureUser(r Runner, username, publicKey string, opts ...Option) (*Result, error) {
-	if r == nil {
-		return nil, RunnerError{}
-	}
-
-	username = strings.TrimSpace(username)
-	if username == "" {
-		return nil, ValidationError{Reason: "username is required"}
-	}
-	if strings.Contains(username, " ") {
-		return nil, ValidationError{Reason: "username must not contain spaces"}
-	}
-
-	publicKey = strings.TrimSpace(publicKey)
-	if publicKey == "" {
-		return nil, ValidationError{Reason: "public key is required"}
-	}
-
-	config := ensureUserOptions{
-		shell:      "/bin/bash",
-		sudoGroup:  "sudo",
-		sudoersDir: "/etc/sudoers.d",
+	username, publicKey, config, err := prepare(r, username, publicKey, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, opt := range opts {
-		if opt == nil {
-			continue
-		}
-		if err := opt(&config); err != nil {
+	exists := userExists(r, username)
+	if exists {
+		if err := checkIDs(r, username, config); err != nil {
 			return nil, err
 		}
 	}
-
-	if config.homeDir == "" {
-		config.homeDir = filepath.Join("/home", username)
+	home, err := resolveHome(r, username, config)
+	if err != nil {
+		return nil, err
 	}
 
 	result := &Result{
-		Username: username,
-		HomeDir:  config.homeDir,
+		Username:  username,
+		HomeDir:   home.dir,
+		HomeOnNFS: home.onNFS(),
 	}
 
-	exists := userExists(r, username)
 	if !exists {
-		if err := createUser(r, username, config.homeDir, config.shell); err != nil {
+		if err := createUser(r, username, home.dir, config); err != nil {
 			return nil, err
 		}
 		result.UserCreated = true
 	}
 
-	if err := ensureAuthorizedKey(r, username, config.homeDir, publicKey); err != nil {
+	if config.passwordHash != "" || config.lockPassword {
+		if err := setPassword(r, username, config); err != nil {
+			return nil, err
+		}
+		result.PasswordSet = config.passwordHash != ""
+		result.PasswordLocked = config.lockPassword
+	}
+
+	keys := authorizedKeys(publicKey, config)
+	if config.userOwnedKeys {
+		err = ensureAuthorizedKeyAsUser(r, username, home.dir, keys, config.replaceKeys)
+	} else {
+		err = ensureAuthorizedKey(r, username, home.dir, keys, config.replaceKeys, !home.onNFS())
+		if err != nil && home.onNFS() {
+			err = NFSHomeError{HomeDir: home.dir, Err: err}
+		}
+	}
+	if err != nil {
 		return nil, err
 	}
 	result.AuthorizedKeyUpdated = true
 
 	if config.addToSudo {
-		if err := addUserToSudo(r, username, config.sudoGroup); err != nil {
+		group, err := addUserToSudo(r, username, config.sudoGroup)
+		if err != nil {
 			return nil, err
 		}
 		result.AddedToSudo = true
+		result.SudoGroup = group
 	}
 
 	if config.passwordlessSudo {
-		if err := configurePasswordlessSudo(r, username, config.sudoersDir); err != nil {
+		if err := configurePasswordlessSudo(r, username, config.sudoersDir, config.sudoLogging, config.sudoCommands); err != nil {
 			return nil, err
 		}
 		result.PasswordlessConfigured = true
+		result.SudoCommands = config.sudoCommands
 	}
 
 	return result, nil
 }
 
+// prepare validates EnsureUser and PlanUser arguments and applies options.
+func prepare(r Runner, username, publicKey string, opts []Option) (string, string, ensureUserOptions, error) {
+	// Empty shell, sudoGroup, and sudoersDir pick a per-OS default on the target.
+	config := ensureUserOptions{}
+	if r == nil {
+		return "", "", config, RunnerError{}
+	}
+
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return "", "", config, ValidationError{Reason: "username is required"}
+	}
+	if strings.Contains(username, " ") {
+		return "", "", config, ValidationError{Reason: "username must not contain spaces"}
+	}
+
+	publicKey = strings.TrimSpace(publicKey)
+	if publicKey == "" {
+		return "", "", config, ValidationError{Reason: "public key is required"}
+	}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&config); err != nil {
+			return "", "", config, err
+		}
+	}
+	if config.passwordHash != "" && config.lockPassword {
+		return "", "", config, OptionError{Reason: "a password hash and a locked password are mutually exclusive"}
+	}
+	if config.sudoLogging != (SudoLogging{}) && !config.passwordlessSudo {
+		return "", "", config, OptionError{Reason: "sudo logging is written to the sudoers drop-in and requires passwordless sudo"}
+	}
+	if len(config.sudoCommands) > 0 && !config.passwordlessSudo {
+		return "", "", config, OptionError{Reason: "sudo commands are written to the sudoers drop-in and require passwordless sudo"}
+	}
+
+	return username, publicKey, config, nil
+}
+
 func userExists(r Runner, username string) bool {
-	cmd := fmt.Sprintf("id -u %s >/dev/null 2>&1", shellQuote(username))
+	cmd := fmt.Sprintf("id -u %s >/dev/null 2>&1", shellquote.Quote(username))
 	_, _, err := r.Run(cmd)
 	return err == nil
 }
 
-func createUser(r Runner, username, homeDir, shell string) error {
-	cmd := fmt.Sprintf("useradd -m -d %s -s %s %s", shellQuote(homeDir), shellQuote(shell), shellQuote(username))
+func createUser(r Runner, username, homeDir string, config ensureUserOptions) error {
+	shellLine := "shell=" + shellquote.Quote(config.shell)
+	if config.shell == "" {
+		shellLine = `shell=/bin/bash; [ -x "$shell" ] || shell=/bin/sh`
+	}
+	linuxArgs, bsdArgs := accountArgs(config)
+	linuxPre, bsdPre := accountPrelude(username, config)
+	cmd := fmt.Sprintf(`
+%s
+case "$(uname -s)" in
+FreeBSD) %spw useradd -n %s -m -d %s -s "$shell"%s ;;
+*) %suseradd -m -d %s -s "$shell"%s %s ;;
+esac
+`, shellLine, bsdPre, shellquote.Quote(username), shellquote.Quote(homeDir), bsdArgs, linuxPre, shellquote.Quote(homeDir), linuxArgs, shellquote.Quote(username))
 	return runStep(r, "useradd", cmd)
 }
 
-func ensureAuthorizedKey(r Runner, username, homeDir, publicKey string) error {
+// accountPrelude renders the commands that must run before useradd: creating
+// the primary group for WithGID, and refusing a FreeBSD system account
+// without a UID.
+func accountPrelude(username string, config ensureUserOptions) (linux, bsd string) {
+	if config.systemAccount && config.uid == 0 {
+		bsd = `echo "system accounts need an explicit UID on FreeBSD" >&2; exit 1; `
+	}
+	if config.gid != 0 {
+		linux = fmt.Sprintf("getent group %d >/dev/null 2>&1 || groupadd -g %d %s || exit 1; ", config.gid, config.gid, shellquote.Quote(username))
+		bsd += fmt.Sprintf("pw groupshow -g %d >/dev/null 2>&1 || pw groupadd -n %s -g %d || exit 1; ", config.gid, shellquote.Quote(username), config.gid)
+	}
+	return linux, bsd
+}
+
+// checkIDs verifies that an existing user has the UID and GID requested with
+// WithUID and WithGID; EnsureUser never renumbers an account.
+func checkIDs(r Runner, username string, config ensureUserOptions) error {
+	ids := []struct {
+		kind string
+		flag string
+		want int
+	}{
+		{kind: "uid", flag: "-u", want: config.uid},
+		{kind: "gid", flag: "-g", want: config.gid},
+	}
+	for _, id := range ids {
+		if id.want == 0 {
+			continue
+		}
+		cmd := fmt.Sprintf("id %s %s", id.flag, shellquote.Quote(username))
+		stdout, stderr, err := r.Run(cmd)
+		if err != nil {
+			return CommandError{Step: "id " + id.flag, Err: err, Stderr: stderr}
+		}
+		got, err := strconv.Atoi(strings.TrimSpace(stdout))
+		if err != nil {
+			return CommandError{Step: "id " + id.flag, Err: err, Stderr: stdout}
+		}
+		if got != id.want {
+			return IDMismatchError{Username: username, Kind: id.kind, Want: id.want, Got: got}
+		}
+	}
+	return nil
+}
+
+// accountArgs renders the optional account settings as useradd and pw useradd
+// flags. Both take the same letters, but pw wants day-first expiry dates and a
+// home mode instead of a umask.
+func accountArgs(config ensureUserOptions) (linux, bsd string) {
+	var common []string
+	if config.uid != 0 {
+		common = append(common, fmt.Sprintf("-u %d", config.uid))
+	}
+	if config.gid != 0 {
+		common = append(common, fmt.Sprintf("-g %d", config.gid))
+	}
+	if config.skelDir != "" {
+		common = append(common, "-k "+shellquote.Quote(config.skelDir))
+	}
+	if len(config.groups) > 0 {
+		common = append(common, "-G "+shellquote.Quote(strings.Join(config.groups, ",")))
+	}
+	if config.comment != "" {
+		common = append(common, "-c "+shellquote.Quote(config.comment))
+	}
+	linuxArgs := append([]string(nil), common...)
+	bsdArgs := append([]string(nil), common...)
+	if config.systemAccount {
+		linuxArgs = append(linuxArgs, "-r")
+	}
+	if !config.expiry.IsZero() {
+		linuxArgs = append(linuxArgs, "-e "+config.expiry.Format("2006-01-02"))
+		bsdArgs = append(bsdArgs, "-e "+config.expiry.Format("02-01-2006"))
+	}
+	if config.umask != nil {
+		linuxArgs = append(linuxArgs, fmt.Sprintf("-K UMASK=%04o", *config.umask))
+		bsdArgs = append(bsdArgs, fmt.Sprintf("-M %04o", 0o777&^*config.umask))
+	}
+	for _, arg := range linuxArgs {
+		linux += " " + arg
+	}
+	for _, arg := range bsdArgs {
+		bsd += " " + arg
+	}
+	return linux, bsd
+}
+
+// ensureAuthorizedKey writes keys as root. The files get the account's
+// primary group, which is not named after the user when WithGID picked an
+// existing group. With relabel set, the SELinux context of .ssh is restored
+// afterwards; NFS homes are left out because their label comes from the
+// mount (the use_nfs_home_dirs boolean).
+func ensureAuthorizedKey(r Runner, username, homeDir string, keys []string, replace, relabel bool) error {
 	sshDir := filepath.Join(homeDir, ".ssh")
 	authPath := filepath.Join(sshDir, "authorized_keys")
 	script := fmt.Sprintf(`
 set -euo pipefail
-install -o %s -g %s -m 700 -d %s
-cat <<'EOF' > %s
+group="$(id -g %s)"
+install -o %s -g "$group" -m 700 -d %s
 %s
-EOF
-chown %s:%s %s
+chown %s:"$group" %s
 chmod 600 %s
-`, shellQuote(username), shellQuote(username), shellQuote(sshDir),
-		shellQuote(authPath), publicKey, shellQuote(username), shellQuote(username),
-		shellQuote(authPath), shellQuote(authPath))
+`, shellquote.Quote(username), shellquote.Quote(username), shellquote.Quote(sshDir),
+		writeKeyScript(authPath, keys, replace), shellquote.Quote(username),
+		shellquote.Quote(authPath), shellquote.Quote(authPath))
+	if relabel {
+		script += relabelScript(sshDir)
+	}
 
 	return runStep(r, "authorized_keys", script)
 }
 
-func addUserToSudo(r Runner, username, group string) error {
-	cmd := fmt.Sprintf("usermod -aG %s %s", shellQuote(group), shellQuote(username))
-	return runStep(r, "add-to-sudo", cmd)
+// relabelScript gives sshDir the ssh_home_t SELinux type sshd needs to read
+// authorized_keys, on hosts where SELinux is enabled. restorecon fixes the
+// usual homes; for homes the policy does not know, such as /srv/deploy, a
+// persistent semanage fcontext rule is added (chcon when semanage is not
+// installed). Elsewhere the script does nothing.
+func relabelScript(sshDir string) string {
+	return fmt.Sprintf(`if command -v selinuxenabled >/dev/null 2>&1 && selinuxenabled; then
+	restorecon -R %[1]s
+	if ! ls -Zd %[1]s | grep -q ':ssh_home_t:'; then
+		if command -v semanage >/dev/null 2>&1; then
+			semanage fcontext -a -t ssh_home_t %[2]s 2>/dev/null || semanage fcontext -m -t ssh_home_t %[2]s
+			restorecon -R %[1]s
+		else
+			chcon -R -t ssh_home_t %[1]s
+		fi
+	fi
+fi
+`, shellquote.Quote(sshDir), shellquote.Quote(sshDir+"(/.*)?"))
+}
+
+// authorizedKeys returns publicKey followed by the WithAuthorizedKeys keys,
+// dropping repeats of the same key.
+func authorizedKeys(publicKey string, config ensureUserOptions) []string {
+	keys := []string{publicKey}
+	seen := map[string]bool{keyMaterial(publicKey): true}
+	for _, key := range config.extraKeys {
+		if material := keyMaterial(key); !seen[material] {
+			seen[material] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// writeKeyScript renders the shell that puts keys into authPath: it
+// overwrites the file when replace is set, and otherwise appends each key
+// unless grep finds it already, first terminating an unfinished last line.
+func writeKeyScript(authPath string, keys []string, replace bool) string {
+	if replace {
+		return fmt.Sprintf("cat <<'EOF' > %s\n%sEOF", shellquote.Quote(authPath), RenderAuthorizedKeys(keys...))
+	}
+	blocks := make([]string, 0, len(keys))
+	for _, key := range keys {
+		blocks = append(blocks, fmt.Sprintf(`if ! { [ -f %[1]s ] && grep -qF -- %[2]s %[1]s; }; then
+	if [ -s %[1]s ] && [ -n "$(tail -c 1 %[1]s)" ]; then echo >> %[1]s; fi
+	cat <<'EOF' >> %[1]s
+%[3]s
+EOF
+fi`, shellquote.Quote(authPath), shellquote.Quote(keyMaterial(key)), strings.TrimSpace(key)))
+	}
+	return strings.Join(blocks, "\n")
 }
 
-func configurePasswordlessSudo(r Runner, username, sudoersDir string) error {
-	file := filepath.Join(sudoersDir, username)
+// addUserToSudo adds username to the admin group and returns its name. Without
+// an explicit group, BSD hosts use wheel and Linux hosts whichever of sudo
+// (Debian family) or wheel (RHEL family, SUSE, Arch) exists.
+func addUserToSudo(r Runner, username, group string) (string, error) {
+	groupLine := "group=" + shellquote.Quote(group)
+	if group == "" {
+		groupLine = `group=
+case "$(uname -s)" in *BSD|DragonFly) group=wheel ;; esac
+if [ -z "$group" ]; then
+	for candidate in sudo wheel; do
+		if getent group "$candidate" >/dev/null 2>&1 || grep -q "^$candidate:" /etc/group 2>/dev/null; then
+			group=$candidate
+			break
+		fi
+	done
+fi
+if [ -z "$group" ]; then
+	echo "no sudo or wheel group found; set the sudo group explicitly" >&2
+	exit 1
+fi`
+	}
+	cmd := fmt.Sprintf(`
+%s
+case "$(uname -s)" in
+FreeBSD|DragonFly) pw groupmod "$group" -m %s ;;
+OpenBSD|NetBSD) usermod -G "$group" %s ;;
+*) usermod -aG "$group" %s ;;
+esac
+echo "$group"
+`, groupLine, shellquote.Quote(username), shellquote.Quote(username), shellquote.Quote(username))
+	stdout, stderr, err := r.Run(cmd)
+	if err != nil {
+		return "", CommandError{Step: "add-to-sudo", Err: err, Stderr: stderr}
+	}
+	if lines := strings.Fields(stdout); len(lines) > 0 {
+		return lines[len(lines)-1], nil
+	}
+	return group, nil
+}
+
+func configurePasswordlessSudo(r Runner, username, sudoersDir string, logging SudoLogging, commands []string) error {
+	dirLine := "dir=" + shellquote.Quote(sudoersDir)
+	if sudoersDir == "" {
+		dirLine = `dir=/etc/sudoers.d; [ "$(uname -s)" != FreeBSD ] || dir=/usr/local/etc/sudoers.d`
+	}
 	script := fmt.Sprintf(`
 set -euo pipefail
-install -o root -g root -m 755 -d %s
-cat <<'EOF' > %s
-%s ALL=(ALL) NOPASSWD:ALL
+%s
+file="$dir"/%s
+install -o root -g 0 -m 755 -d "$dir"
+tmp="$dir/.host-prep.$$"
+cat <<'EOF' > "$tmp"
+%s
 EOF
-chmod 440 %s
-`, shellQuote(sudoersDir), shellQuote(file), username, shellQuote(file))
+chmod 440 "$tmp"
+if command -v visudo >/dev/null 2>&1 && ! visudo -cf "$tmp" >&2; then
+	rm -f "$tmp"
+	exit 1
+fi
+mv "$tmp" "$file"
+`, dirLine, shellquote.Quote(username), strings.TrimSuffix(RenderSudoers(username, logging, commands...), "\n"))
 	return runStep(r, "passwordless-sudo", script)
 }
 
+// RemoveUser deletes username, its home directory, and its sudoers drop-in
+// (honouring WithSudoersDir). A user that no longer exists is not an error.
+func RemoveUser(r Runner, username string, opts ...Option) error {
+	if r == nil {
+		return RunnerError{}
+	}
+	username = strings.TrimSpace(username)
+	if username == "" || strings.Contains(username, " ") {
+		return ValidationError{Reason: "a username without spaces is required"}
+	}
+	var config ensureUserOptions
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&config); err != nil {
+			return err
+		}
+	}
+
+	dirLine := "dir=" + shellquote.Quote(config.sudoersDir)
+	if config.sudoersDir == "" {
+		dirLine = `dir=/etc/sudoers.d; [ "$(uname -s)" != FreeBSD ] || dir=/usr/local/etc/sudoers.d`
+	}
+	cmd := fmt.Sprintf(`
+set -eu
+%s
+rm -f "$dir"/%s
+if id -u %s >/dev/null 2>&1; then
+	case "$(uname -s)" in
+	FreeBSD|DragonFly) pw userdel -n %s -r ;;
+	*) userdel -r %s ;;
+	esac
+fi
+`, dirLine, shellquote.Quote(username), shellquote.Quote(username), shellquote.Quote(username), shellquote.Quote(username))
+	return runStep(r, "userdel", cmd)
+}
+
+// DisablePassword replaces username's password hash with "*" so password
+// logins (SSH, su, sudo prompts) stop working. Unlike passwd -l or pw lock it
+// does not lock the account, so key-based SSH logins keep working.
+func DisablePassword(r Runner, username string) error {
+	if r == nil {
+		return RunnerError{}
+	}
+	username = strings.TrimSpace(username)
+	if username == "" || strings.Contains(username, " ") {
+		return ValidationError{Reason: "a username without spaces is required"}
+	}
+	return runStep(r, "disable-password", disablePasswordScript(username))
+}
+
+// disablePasswordScript sets username's password hash to "*". usermod -L is
+// avoided on purpose: its "!" prefix makes sshd without PAM, as on Alpine,
+// refuse key logins for the account too.
+func disablePasswordScript(username string) string {
+	return fmt.Sprintf(`
+set -eu
+case "$(uname -s)" in
+FreeBSD|DragonFly) echo '*' | pw usermod -n %s -H 0 ;;
+*) usermod -p '*' %s ;;
+esac
+`, shellquote.Quote(username), shellquote.Quote(username))
+}
+
+// setPassword applies WithPasswordHash or WithLockedPassword.
+func setPassword(r Runner, username string, config ensureUserOptions) error {
+	user := shellquote.Quote(username)
+	if config.lockPassword {
+		return runStep(r, "lock-password", disablePasswordScript(username))
+	}
+	hash := shellquote.Quote(config.passwordHash)
+	return runStep(r, "set-password", fmt.Sprintf(`
+set -eu
+case "$(uname -s)" in
+FreeBSD|DragonFly) printf '%%s\n' %s | pw usermod -n %s -H 0 ;;
+*) usermod -p %s %s ;;
+esac
+`, hash, user, hash, user))
+}
+
 func runStep(r Runner, step, cmd string) error {
 	_, stderr, err := r.Run(cmd)
 	if err != nil {
@@ -225,10 +820,3 @@ func runStep(r Runner, step, cmd string) error {
 	}
 	return nil
 }
-
-func shellQuote(value string) string {
-	if value == "" {
-		return "''"
-	}
-	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
-}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -15,6 +16,7 @@ func TestEnsureUserCreatesAndConfigures(t *testing.T) {
 	r := &fakeRunner{
 		responses: []fakeResponse{
 			{match: "id -u", err: errors.New("exit status 1"), stderr: "no such user"},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
 			{match: "useradd -m", err: nil},
 			{match: "install -o", err: nil},
 			{match: "usermod -aG", err: nil},
@@ -37,6 +39,7 @@ func TestEnsureUserSkipsExistingUser(t *testing.T) {
 	r := &fakeRunner{
 		responses: []fakeResponse{
 			{match: "id -u", err: nil},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
 			{match: "install -o", err: nil},
 		},
 	}
@@ -47,6 +50,186 @@ func TestEnsureUserSkipsExistingUser(t *testing.T) {
 	require.True(t, res.AuthorizedKeyUpdated)
 }
 
+func TestEnsureUserScriptsHandleBSD(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "deploy", "ssh-rsa AAA...", WithPasswordlessSudo())
+	require.NoError(t, err)
+	script := strings.Join(r.cmds, "\n")
+	require.Contains(t, script, "pw useradd -n 'deploy'")
+	require.Contains(t, script, "shell=/bin/sh")
+	require.Contains(t, script, "group=wheel")
+	require.Contains(t, script, `pw groupmod "$group" -m 'deploy'`)
+	require.Contains(t, script, "dir=/usr/local/etc/sudoers.d")
+
+	r = &recordingRunner{}
+	_, err = EnsureUser(r, "deploy", "ssh-rsa AAA...", WithPasswordlessSudo(), WithSudoGroup("admins"), WithSudoersDir("/opt/sudoers.d"), WithShell("/bin/zsh"))
+	require.NoError(t, err)
+	script = strings.Join(r.cmds, "\n")
+	require.NotContains(t, script, "group=wheel")
+	require.Contains(t, script, "group='admins'")
+	require.Contains(t, script, "shell='/bin/zsh'")
+}
+
+func TestEnsureUserAppendsAuthorizedKey(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "deploy", "ssh-ed25519 AAA deploy@ctl")
+	require.NoError(t, err)
+	script := strings.Join(r.cmds, "\n")
+	require.Contains(t, script, "grep -qF -- 'ssh-ed25519 AAA' '/home/deploy/.ssh/authorized_keys'")
+	require.Contains(t, script, "cat <<'EOF' >> '/home/deploy/.ssh/authorized_keys'")
+
+	r = &recordingRunner{}
+	_, err = EnsureUser(r, "deploy", "ssh-ed25519 AAA deploy@ctl", WithReplaceAuthorizedKeys())
+	require.NoError(t, err)
+	script = strings.Join(r.cmds, "\n")
+	require.NotContains(t, script, "grep -qF")
+	require.Contains(t, script, "cat <<'EOF' > '/home/deploy/.ssh/authorized_keys'")
+}
+
+func TestEnsureUserManagesSeveralKeys(t *testing.T) {
+	t.Parallel()
+
+	team := []string{"# team", "ssh-ed25519 BBB alice", "", "ssh-ed25519 AAA other-comment", "ssh-rsa CCC bob"}
+
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "deploy", "ssh-ed25519 AAA deploy@ctl", WithAuthorizedKeys(team...))
+	require.NoError(t, err)
+	script := strings.Join(r.cmds, "\n")
+	require.Equal(t, 1, strings.Count(script, "grep -qF -- 'ssh-ed25519 AAA'"))
+	require.Contains(t, script, "grep -qF -- 'ssh-ed25519 BBB'")
+	require.Contains(t, script, "grep -qF -- 'ssh-rsa CCC'")
+	require.NotContains(t, script, "other-comment")
+
+	r = &recordingRunner{}
+	_, err = EnsureUser(r, "deploy", "ssh-ed25519 AAA deploy@ctl", WithAuthorizedKeys(team...), WithReplaceAuthorizedKeys())
+	require.NoError(t, err)
+	script = strings.Join(r.cmds, "\n")
+	require.Contains(t, script, "cat <<'EOF' > '/home/deploy/.ssh/authorized_keys'\nssh-ed25519 AAA deploy@ctl\nssh-ed25519 BBB alice\nssh-rsa CCC bob\nEOF")
+
+	_, err = EnsureUser(r, "deploy", "ssh-ed25519 AAA deploy@ctl", WithAuthorizedKeys("not-a-key"))
+	require.IsType(t, OptionError{}, err)
+}
+
+func TestEnsureUserDetectsSudoGroup(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "install -o"},
+			{match: "for candidate in sudo wheel", stdout: "wheel\n"},
+		},
+	}
+	res, err := EnsureUser(r, "deploy", "ssh-rsa AAA", WithSudoAccess())
+	require.NoError(t, err)
+	require.Equal(t, "wheel", res.SudoGroup)
+
+	r = &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "install -o"},
+			{match: "group='admins'", stdout: "admins\n"},
+		},
+	}
+	res, err = EnsureUser(r, "deploy", "ssh-rsa AAA", WithSudoAccess(), WithSudoGroup("admins"))
+	require.NoError(t, err)
+	require.True(t, res.AddedToSudo)
+	require.Equal(t, "admins", res.SudoGroup)
+
+	r = &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u"},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
+			{match: "install -o"},
+			{match: "usermod -aG", stderr: "no sudo or wheel group found", err: errors.New("exit status 1")},
+		},
+	}
+	_, err = EnsureUser(r, "deploy", "ssh-rsa AAA", WithSudoAccess())
+	require.ErrorContains(t, err, "no sudo or wheel group found")
+}
+
+func TestRemoveUser(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	require.NoError(t, RemoveUser(r, "deploy", WithSudoersDir("/opt/sudoers.d")))
+	require.Len(t, r.cmds, 1)
+	require.Contains(t, r.cmds[0], `rm -f "$dir"/'deploy'`)
+	require.Contains(t, r.cmds[0], "dir='/opt/sudoers.d'")
+	require.Contains(t, r.cmds[0], "userdel -r 'deploy'")
+	require.Contains(t, r.cmds[0], "pw userdel -n 'deploy' -r")
+
+	require.IsType(t, RunnerError{}, RemoveUser(nil, "deploy"))
+	require.IsType(t, ValidationError{}, RemoveUser(r, " "))
+
+	failing := &fakeRunner{responses: []fakeResponse{{match: "userdel", stderr: "userdel: user deploy is currently used by process 42", err: errors.New("exit status 8")}}}
+	var cmdErr CommandError
+	require.ErrorAs(t, RemoveUser(failing, "deploy"), &cmdErr)
+	require.Equal(t, "userdel", cmdErr.Step)
+}
+
+func TestDisablePassword(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	require.NoError(t, DisablePassword(r, "pi"))
+	require.Len(t, r.cmds, 1)
+	require.Contains(t, r.cmds[0], "usermod -p '*' 'pi'")
+	require.Contains(t, r.cmds[0], "pw usermod -n 'pi' -H 0")
+	require.NotContains(t, r.cmds[0], "passwd -l")
+
+	require.IsType(t, ValidationError{}, DisablePassword(r, ""))
+}
+
+func TestEnsureUserSetsOrLocksPassword(t *testing.T) {
+	t.Parallel()
+
+	const hash = "$6$salt$abc/def"
+
+	r := &recordingRunner{}
+	result, err := EnsureUser(r, "deploy", "ssh-rsa AAA", WithPasswordHash(hash))
+	require.NoError(t, err)
+	require.True(t, result.PasswordSet)
+	require.False(t, result.PasswordLocked)
+	script := strings.Join(r.cmds, "\n")
+	require.Contains(t, script, "usermod -p '$6$salt$abc/def' 'deploy'")
+	require.Contains(t, script, "printf '%s\\n' '$6$salt$abc/def' | pw usermod -n 'deploy' -H 0")
+
+	r = &recordingRunner{}
+	result, err = EnsureUser(r, "deploy", "ssh-rsa AAA", WithLockedPassword())
+	require.NoError(t, err)
+	require.True(t, result.PasswordLocked)
+	script = strings.Join(r.cmds, "\n")
+	require.Contains(t, script, "*) usermod -p '*' 'deploy' ;;")
+	require.Contains(t, script, "echo '*' | pw usermod -n 'deploy' -H 0")
+	require.NotContains(t, script, "usermod -L")
+
+	for _, bad := range []string{"", "plaintext", "$6$salt$a:b", "$6$salt $x"} {
+		_, err = EnsureUser(r, "deploy", "ssh-rsa AAA", WithPasswordHash(bad))
+		require.IsType(t, OptionError{}, err, bad)
+	}
+	_, err = EnsureUser(r, "deploy", "ssh-rsa AAA", WithPasswordHash(hash), WithLockedPassword())
+	require.IsType(t, OptionError{}, err)
+}
+
+type recordingRunner struct {
+	cmds []string
+}
+
+func (r *recordingRunner) Run(cmd string) (string, string, error) {
+	r.cmds = append(r.cmds, cmd)
+	if strings.HasPrefix(cmd, "id -u") {
+		return "", "", errors.New("exit status 1")
+	}
+	return "", "", nil
+}
+
 func TestEnsureUserValidation(t *testing.T) {
 	t.Parallel()
 
@@ -78,6 +261,7 @@ func TestEnsureUserPropagatesCommandErrors(t *testing.T) {
 	r := &fakeRunner{
 		responses: []fakeResponse{
 			{match: "id -u", err: errors.New("exit status 1"), stderr: "no such user"},
+			{match: `echo "$home"`, stdout: "/home/deploy\next2/ext3\n"},
 			{match: "useradd -m", err: errors.New("exit status 2"), stderr: "useradd failed"},
 		},
 	}
@@ -112,3 +296,136 @@ func (f *fakeRunner) Run(cmd string) (string, string, error) {
 
 	return resp.stdout, resp.stderr, resp.err
 }
+
+func TestEnsureUserAppliesAccountSettings(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "deploy", "ssh-rsa AAA...",
+		WithSkelDir("/etc/skel.ansible"),
+		WithGroups("adm", "systemd-journal"),
+		WithExpiry(time.Date(2027, time.March, 31, 0, 0, 0, 0, time.UTC)),
+		WithComment("Ansible automation, owner ops@example.com"),
+		WithUmask(0o077),
+	)
+	require.NoError(t, err)
+	script := strings.Join(r.cmds, "\n")
+	require.Contains(t, script, `useradd -m -d '/home/deploy' -s "$shell" -k '/etc/skel.ansible' -G 'adm,systemd-journal' -c 'Ansible automation, owner ops@example.com' -e 2027-03-31 -K UMASK=0077 'deploy'`)
+	require.Contains(t, script, `pw useradd -n 'deploy' -m -d '/home/deploy' -s "$shell" -k '/etc/skel.ansible' -G 'adm,systemd-journal' -c 'Ansible automation, owner ops@example.com' -e 31-03-2027 -M 0700 ;;`)
+}
+
+func TestEnsureUserWithFixedIDs(t *testing.T) {
+	t.Parallel()
+
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "ansible", "ssh-rsa AAA...", WithUID(2001), WithGID(2001), WithSystemAccount())
+	require.NoError(t, err)
+	script := strings.Join(r.cmds, "\n")
+	require.Contains(t, script, `*) getent group 2001 >/dev/null 2>&1 || groupadd -g 2001 'ansible' || exit 1; useradd -m -d '/home/ansible' -s "$shell" -u 2001 -g 2001 -r 'ansible' ;;`)
+	require.Contains(t, script, `FreeBSD) pw groupshow -g 2001 >/dev/null 2>&1 || pw groupadd -n 'ansible' -g 2001 || exit 1; pw useradd -n 'ansible' -m -d '/home/ansible' -s "$shell" -u 2001 -g 2001 ;;`)
+
+	r = &recordingRunner{}
+	_, err = EnsureUser(r, "ansible", "ssh-rsa AAA...", WithSystemAccount())
+	require.NoError(t, err)
+	require.Contains(t, strings.Join(r.cmds, "\n"), `FreeBSD) echo "system accounts need an explicit UID on FreeBSD" >&2; exit 1; pw useradd`)
+}
+
+func TestEnsureUserWithExistingGroupOwnsKeysByGID(t *testing.T) {
+	t.Parallel()
+
+	// GID 100 already exists as "users", so no group named "ansible" is
+	// created and the key files must not be chowned to one.
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "ansible", "ssh-rsa AAA...", WithGID(100))
+	require.NoError(t, err)
+	script := strings.Join(r.cmds, "\n")
+	require.Contains(t, script, `getent group 100 >/dev/null 2>&1 || groupadd -g 100 'ansible' || exit 1; useradd -m -d '/home/ansible' -s "$shell" -g 100 'ansible'`)
+	require.Contains(t, script, `group="$(id -g 'ansible')"`)
+	require.Contains(t, script, `install -o 'ansible' -g "$group" -m 700 -d '/home/ansible/.ssh'`)
+	require.Contains(t, script, `chown 'ansible':"$group" '/home/ansible/.ssh/authorized_keys'`)
+	require.NotContains(t, script, `install -o 'ansible' -g 'ansible'`)
+	require.NotContains(t, script, `'ansible':'ansible'`)
+}
+
+func TestEnsureUserRejectsExistingUserWithOtherIDs(t *testing.T) {
+	t.Parallel()
+
+	r := &fakeRunner{
+		responses: []fakeResponse{
+			{match: "id -u", stdout: "1001\n"},
+			{match: "id -u 'ansible'", stdout: "2001\n"},
+			{match: "id -g 'ansible'", stdout: "100\n"},
+		},
+	}
+	_, err := EnsureUser(r, "ansible", "ssh-rsa AAA...", WithUID(2001), WithGID(2001))
+	var mismatch IDMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, IDMismatchError{Username: "ansible", Kind: "gid", Want: 2001, Got: 100}, mismatch)
+}
+
+func TestAccountOptionValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "relative skel", opt: WithSkelDir("skel")},
+		{name: "group with comma", opt: WithGroups("adm,wheel")},
+		{name: "empty group", opt: WithGroups("adm", " ")},
+		{name: "zero expiry", opt: WithExpiry(time.Time{})},
+		{name: "comment with colon", opt: WithComment("ops:team")},
+		{name: "umask out of range", opt: WithUmask(0o1000)},
+		{name: "root uid", opt: WithUID(0)},
+		{name: "negative gid", opt: WithGID(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := EnsureUser(&fakeRunner{}, "deploy", "ssh-rsa AAA", tt.opt)
+			require.IsType(t, OptionError{}, err)
+		})
+	}
+}
+
+func TestEnsureUserWritesSudoLogging(t *testing.T) {
+	t.Parallel()
+
+	logging := SudoLogging{LogFile: "/var/log/sudo-ansible.log", IOLog: true}
+	require.Equal(t, "Defaults:deploy logfile=\"/var/log/sudo-ansible.log\"\n"+
+		"Defaults:deploy log_output\n"+
+		"deploy ALL=(ALL) NOPASSWD:ALL\n", RenderSudoers("deploy", logging))
+
+	r := &recordingRunner{}
+	_, err := EnsureUser(r, "deploy", "ssh-rsa AAA...", WithPasswordlessSudo(), WithSudoLogging(logging))
+	require.NoError(t, err)
+	script := r.cmds[len(r.cmds)-1]
+	require.Contains(t, script, "Defaults:deploy log_output\ndeploy ALL=(ALL) NOPASSWD:ALL\nEOF")
+	require.Contains(t, script, `visudo -cf "$tmp"`)
+
+	_, err = EnsureUser(&fakeRunner{}, "deploy", "ssh-rsa AAA...", WithSudoLogging(SudoLogging{IOLog: true}))
+	require.IsType(t, OptionError{}, err)
+	_, err = EnsureUser(&fakeRunner{}, "deploy", "ssh-rsa AAA...", WithPasswordlessSudo(), WithSudoLogging(SudoLogging{LogFile: "/var/log/sudo log"}))
+	require.IsType(t, OptionError{}, err)
+}
+
+func TestEnsureUserRestrictsSudoCommands(t *testing.T) {
+	t.Parallel()
+
+	commands := []string{"/bin/sh", "/usr/bin/systemctl restart nginx", "/usr/bin/env LANG=C apt-get"}
+	require.Equal(t, "deploy ALL=(ALL) NOPASSWD: /bin/sh, /usr/bin/systemctl restart nginx, /usr/bin/env LANG\\=C apt-get\n",
+		RenderSudoers("deploy", SudoLogging{}, commands...))
+
+	r := &recordingRunner{}
+	result, err := EnsureUser(r, "deploy", "ssh-rsa AAA...", WithPasswordlessSudo(), WithSudoCommands(commands))
+	require.NoError(t, err)
+	require.Equal(t, commands, result.SudoCommands)
+	require.Contains(t, r.cmds[len(r.cmds)-1], "deploy ALL=(ALL) NOPASSWD: /bin/sh, ")
+
+	_, err = EnsureUser(&fakeRunner{}, "deploy", "ssh-rsa AAA...", WithSudoCommands([]string{"/bin/sh"}))
+	require.IsType(t, OptionError{}, err)
+	for _, bad := range [][]string{nil, {" "}, {"apt-get"}, {"/bin/sh\nALL"}} {
+		_, err = EnsureUser(&fakeRunner{}, "deploy", "ssh-rsa AAA...", WithPasswordlessSudo(), WithSudoCommands(bad))
+		require.IsType(t, OptionError{}, err, "%q", bad)
+	}
+}